@@ -19,8 +19,15 @@
 __version__ = "0.0.1"  # noqa: F401
 
 from .actions import Action, ActionType  # noqa: F401
+from .compiler_cache import CompilerCacheConfig  # noqa: F401
+from .config import PartsConfig, load_config  # noqa: F401
 from .dirs import ProjectDirs  # noqa: F401
+from .export import OciLayer  # noqa: F401
 from .infos import ProjectInfo  # noqa: F401
 from .lifecycle_manager import LifecycleManager  # noqa: F401
+from .packages.lockfile import LockfileMode  # noqa: F401
 from .parts import Part  # noqa: F401
+from .proxy import ProxyConfig  # noqa: F401
+from .sbom import SbomFormat  # noqa: F401
+from .sources.mirrors import MirrorRule  # noqa: F401
 from .steps import Step  # noqa: F401
@@ -17,8 +17,8 @@
 """Definitions of lifecycle actions and action types."""
 
 import enum
-from dataclasses import dataclass
-from typing import Optional
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional
 
 from craft_parts.steps import Step
 
@@ -60,9 +60,36 @@ class Action:
     :param action_type: Action to run for this step.
     :param reason: A textual description of why this action should be
         executed.
+    :param dirty_properties: The part properties that changed since the
+        step last ran, if the step is executed again because it's dirty.
+    :param dirty_project_options: The project options that changed since
+        the step last ran, if the step is executed again because it's dirty.
     """
 
     part_name: str
     step: Step
     action_type: ActionType = ActionType.RUN
     reason: Optional[str] = None
+    dirty_properties: Optional[List[str]] = field(
+        default=None, compare=False, repr=False
+    )
+    dirty_project_options: Optional[List[str]] = field(
+        default=None, compare=False, repr=False
+    )
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the action data.
+
+        :return: The newly created dictionary.
+        """
+        data: Dict[str, Any] = {
+            "part": self.part_name,
+            "step": self.step.name.lower(),
+            "type": self.action_type.name.lower(),
+            "reason": self.reason,
+        }
+        if self.dirty_properties:
+            data["dirty-properties"] = sorted(self.dirty_properties)
+        if self.dirty_project_options:
+            data["dirty-project-options"] = sorted(self.dirty_project_options)
+        return data
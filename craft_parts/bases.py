@@ -0,0 +1,123 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Build base names and detection.
+
+A build base identifies the distribution parts are built on. Bases are named
+as ``<distribution>@<version>``, such as ``ubuntu@22.04`` or ``fedora@39``,
+using the distribution ID and version ID listed in os-release. Snap core
+bases such as ``core22`` correspond to Ubuntu releases. If an application
+doesn't set the project base, the base of the host is used.
+"""
+
+import contextlib
+import re
+from dataclasses import dataclass
+from typing import Optional, Tuple
+
+from craft_parts import errors, hosts
+from craft_parts.utils.os_utils import OsRelease
+
+_BASE_NAME_PATTERN = re.compile(
+    r"^(?P<distribution>[a-z0-9._-]+)(@(?P<version>[^\s@]+))?$"
+)
+_CORE_BASE_PATTERN = re.compile(r"^core(?P<year>[0-9]{2})?$")
+
+
+@dataclass(frozen=True)
+class Base:
+    """The distribution parts are built on.
+
+    :param distribution: The distribution ID, as in os-release ``ID``.
+    :param version: The distribution version, as in os-release ``VERSION_ID``.
+    :param like: The IDs of the distributions this distribution is derived
+        from, as in os-release ``ID_LIKE``.
+    """
+
+    distribution: str
+    version: Optional[str] = None
+    like: Tuple[str, ...] = ()
+
+    @property
+    def name(self) -> str:
+        """Return the base name, as ``<distribution>@<version>``."""
+        if self.version is None:
+            return self.distribution
+        return f"{self.distribution}@{self.version}"
+
+    @property
+    def distribution_ids(self) -> Tuple[str, ...]:
+        """Return the distribution ID followed by the IDs it's derived from."""
+        return (self.distribution, *self.like)
+
+
+def parse_base(name: str) -> Base:
+    """Obtain the base corresponding to a base name.
+
+    :param name: The base name, such as ``ubuntu@22.04`` or ``core22``.
+
+    :return: The base. If the host runs the named base, the distributions
+        the host is derived from are also set.
+
+    :raise errors.InvalidBase: If the base name is malformed.
+    """
+    core_match = _CORE_BASE_PATTERN.match(name)
+    if core_match:
+        year = core_match.group("year") or "16"
+        base = Base(distribution="ubuntu", version=f"{year}.04")
+    else:
+        match = _BASE_NAME_PATTERN.match(name)
+        if not match:
+            raise errors.InvalidBase(name)
+        base = Base(
+            distribution=match.group("distribution"), version=match.group("version")
+        )
+
+    host_base = get_host_base()
+    if host_base and host_base.name == base.name:
+        return host_base
+
+    return base
+
+
+def get_host_base(os_release: Optional[OsRelease] = None) -> Optional[Base]:
+    """Obtain the base of the host running craft-parts.
+
+    :param os_release: The host operating system release information.
+
+    :return: The host base, or None if the host is not a Linux host or
+        the distribution can't be determined.
+    """
+    if os_release is None:
+        if not hosts.get_host_platform().supports_packages:
+            return None
+        os_release = OsRelease()
+
+    try:
+        distribution = os_release.id()
+    except errors.OsReleaseIdError:
+        return None
+
+    version = None
+    with contextlib.suppress(errors.OsReleaseVersionIdError):
+        version = os_release.version_id()
+
+    return Base(
+        distribution=distribution,
+        version=version,
+        like=tuple(os_release.id_like()),
+    )
@@ -17,24 +17,45 @@
 """Register and execute callback functions."""
 
 import logging
+import os
 from collections import namedtuple
-from typing import Callable, List, Union
+from typing import Callable, Dict, List, Optional, Union
 
 from craft_parts import errors
+from craft_parts.events import Event
 from craft_parts.infos import ProjectInfo, StepInfo
 from craft_parts.parts import Part
+from craft_parts.plugins import PluginEnvironmentValidator
 from craft_parts.steps import Step
 
 CallbackHook = namedtuple("CallbackHook", ["function", "step_list"])
 
 ExecutionCallback = Callable[[ProjectInfo, List[Part]], None]
 StepCallback = Callable[[StepInfo], bool]
-Callback = Union[ExecutionCallback, StepCallback]
+StepFailureCallback = Callable[[StepInfo, Exception], None]
+ValidationCallback = Callable[[StepInfo, PluginEnvironmentValidator], None]
+CredentialsCallback = Callable[[str], Dict[str, str]]
+SecretCallback = Callable[[str], Optional[str]]
+EventCallback = Callable[[Event], None]
+Callback = Union[
+    ExecutionCallback,
+    StepCallback,
+    StepFailureCallback,
+    ValidationCallback,
+    CredentialsCallback,
+    SecretCallback,
+    EventCallback,
+]
 
 _PROLOGUE_HOOKS: List[CallbackHook] = []
 _EPILOGUE_HOOKS: List[CallbackHook] = []
 _PRE_STEP_HOOKS: List[CallbackHook] = []
 _POST_STEP_HOOKS: List[CallbackHook] = []
+_STEP_FAILURE_HOOKS: List[CallbackHook] = []
+_VALIDATION_HOOKS: List[CallbackHook] = []
+_CREDENTIALS_HOOKS: List[CallbackHook] = []
+_SECRET_HOOKS: List[CallbackHook] = []
+_EVENT_HOOKS: List[CallbackHook] = []
 
 logger = logging.getLogger(__name__)
 
@@ -60,6 +81,9 @@ def register_epilogue(func: ExecutionCallback) -> None:
 def register_pre_step(func: StepCallback, *, step_list: List[Step] = None) -> None:
     """Register a pre-step callback function.
 
+    Step callbacks run for each step executed in each part. They receive the
+    step information, including the part name and the action being executed.
+
     :param func: The callback function to run.
     :param step_list: The steps before which the callback function should run.
         If not specified, the callback function will be executed before all steps.
@@ -79,14 +103,94 @@ def register_post_step(func: StepCallback, *, step_list: List[Step] = None) -> N
     _POST_STEP_HOOKS.append(CallbackHook(func, step_list))
 
 
+def register_step_failure(
+    func: StepFailureCallback, *, step_list: List[Step] = None
+) -> None:
+    """Register a step failure callback function.
+
+    Failure callbacks receive the step information and the error raised by
+    the step. The error is propagated after all failure callbacks run.
+
+    :param func: The callback function to run.
+    :param step_list: The steps whose failure should run the callback function.
+        If not specified, the callback function will be executed if any step fails.
+    """
+    _ensure_not_defined(func, _STEP_FAILURE_HOOKS)
+    _STEP_FAILURE_HOOKS.append(CallbackHook(func, step_list))
+
+
+def register_environment_validation(func: ValidationCallback) -> None:
+    """Register a build environment validation callback function.
+
+    Validation callbacks run before a part is built, after the plugin
+    validates the build environment. They receive the step information and
+    the plugin environment validator, which can be used to run commands in
+    the build environment, and raise :class:`PluginEnvironmentValidationError`
+    if the environment is not suitable to build the part.
+
+    :param func: The callback function to run.
+    """
+    _ensure_not_defined(func, _VALIDATION_HOOKS)
+    _VALIDATION_HOOKS.append(CallbackHook(func, None))
+
+
+def register_credentials_provider(func: CredentialsCallback) -> None:
+    """Register a source credentials provider callback function.
+
+    Credentials providers receive the URL of a source file to download and
+    return the HTTP headers to send in the request, such as authorization
+    headers, or an empty dictionary if they have no credentials for the URL.
+    Header values are never logged or stored in the part state.
+
+    :param func: The callback function to run.
+    """
+    _ensure_not_defined(func, _CREDENTIALS_HOOKS)
+    _CREDENTIALS_HOOKS.append(CallbackHook(func, None))
+
+
+def register_secret_provider(func: SecretCallback) -> None:
+    """Register a build secret provider callback function.
+
+    Secret providers receive the name of a secret referenced in the build
+    environment of a part and return its value, or None if they don't
+    provide the secret. Secret values are never stored in the part state.
+
+    :param func: The callback function to run.
+    """
+    _ensure_not_defined(func, _SECRET_HOOKS)
+    _SECRET_HOOKS.append(CallbackHook(func, None))
+
+
+def register_event_handler(func: EventCallback) -> None:
+    """Register an execution event handler callback function.
+
+    Event handlers receive the :class:`Event` objects emitted when actions
+    start and finish, and the output written by commands executed by each
+    action. Handlers may be called from different threads when parts are
+    processed in parallel.
+
+    :param func: The callback function to run.
+    """
+    _ensure_not_defined(func, _EVENT_HOOKS)
+    _EVENT_HOOKS.append(CallbackHook(func, None))
+
+
 def clear() -> None:
     """Clear all existing registered callback functions."""
     global _PROLOGUE_HOOKS, _EPILOGUE_HOOKS  # pylint: disable=global-statement
     global _PRE_STEP_HOOKS, _POST_STEP_HOOKS  # pylint: disable=global-statement
+    global _STEP_FAILURE_HOOKS  # pylint: disable=global-statement
+    global _VALIDATION_HOOKS, _CREDENTIALS_HOOKS  # pylint: disable=global-statement
+    global _SECRET_HOOKS, _EVENT_HOOKS  # pylint: disable=global-statement
     _PROLOGUE_HOOKS = []
     _EPILOGUE_HOOKS = []
     _PRE_STEP_HOOKS = []
     _POST_STEP_HOOKS = []
+    _STEP_FAILURE_HOOKS = []
+    _VALIDATION_HOOKS = []
+    _CREDENTIALS_HOOKS = []
+    _SECRET_HOOKS = []
+    _EVENT_HOOKS = []
 
 
 def run_prologue(project_info: ProjectInfo, *, part_list=List[Part]) -> None:
@@ -125,6 +229,79 @@ def run_post_step(step_info: StepInfo) -> None:
     return _run_step(hook_list=_POST_STEP_HOOKS, step_info=step_info)
 
 
+def run_step_failure(step_info: StepInfo, *, error: Exception) -> None:
+    """Run all registered step failure callback functions.
+
+    :param step_info: the step information to be sent to the callback functions.
+    :param error: The error raised by the failed step.
+    """
+    for hook in _STEP_FAILURE_HOOKS:
+        if not hook.step_list or step_info.step in hook.step_list:
+            hook.function(step_info, error)
+
+
+def run_environment_validation(
+    step_info: StepInfo, *, validator: PluginEnvironmentValidator
+) -> None:
+    """Run all registered build environment validation callbacks.
+
+    :param step_info: The step information to be sent to the callback functions.
+    :param validator: The plugin environment validator for the part.
+
+    :raise PluginEnvironmentValidationError: If the environment is invalid.
+    """
+    for hook in _VALIDATION_HOOKS:
+        hook.function(step_info, validator)
+
+
+def get_source_credentials(url: str) -> Dict[str, str]:
+    """Obtain the HTTP headers to download a source from all credentials providers.
+
+    If more than one provider returns the same header, the first registered
+    provider takes precedence.
+
+    :param url: The URL of the source file to download.
+
+    :return: The headers to send in the download request.
+    """
+    headers: Dict[str, str] = {}
+    for hook in reversed(_CREDENTIALS_HOOKS):
+        headers.update(hook.function(url))
+    return headers
+
+
+def get_secret(name: str) -> Optional[str]:
+    """Obtain the value of a build secret from the secret providers.
+
+    Providers are queried in the order they were registered. If no provider
+    returns a value, the secret is read from the host environment variable
+    of the same name.
+
+    :param name: The name of the secret.
+
+    :return: The secret value, or None if the secret is not available.
+    """
+    for hook in _SECRET_HOOKS:
+        value = hook.function(name)
+        if value is not None:
+            return value
+    return os.environ.get(name)
+
+
+def has_event_handlers() -> bool:
+    """Verify whether execution event handlers are registered."""
+    return bool(_EVENT_HOOKS)
+
+
+def run_event_handlers(event: Event) -> None:
+    """Run all registered execution event handlers.
+
+    :param event: The event to be sent to the callback functions.
+    """
+    for hook in _EVENT_HOOKS:
+        hook.function(event)
+
+
 def _run_step(*, hook_list: List[CallbackHook], step_info: StepInfo):
     for hook in hook_list:
         if not hook.step_list or step_info.step in hook.step_list:
@@ -0,0 +1,134 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Compiler cache configuration used by plugins that compile native code.
+
+If enabled, plugins building C and C++ code use ccache, and the rust plugin
+uses sccache. Both keep their caches in a directory shared by all parts and
+projects of the application, so that rebuilds reuse previous compilations.
+"""
+
+import os
+from dataclasses import dataclass
+from pathlib import Path
+from typing import TYPE_CHECKING, Dict, Optional
+
+from xdg import BaseDirectory  # type: ignore
+
+if TYPE_CHECKING:
+    from craft_parts.infos import PartInfo
+
+
+@dataclass(frozen=True)
+class CompilerCacheConfig:
+    """The location and size limit of the compiler caches.
+
+    :param cache_dir: The directory containing the compiler caches. Defaults
+        to a directory specific to the application in the XDG cache directory.
+    :param max_size: The maximum size in bytes of each compiler cache.
+    """
+
+    cache_dir: Optional[Path] = None
+    max_size: int = 5 * 1024 ** 3
+
+    def get_cache_dir(self, application_name: str) -> Path:
+        """Obtain the directory containing the compiler caches.
+
+        :param application_name: The name of the application using the cache.
+
+        :return: The compiler cache directory.
+        """
+        if self.cache_dir:
+            return Path(self.cache_dir)
+        return Path(
+            BaseDirectory.xdg_cache_home,
+            application_name,
+            "craft-parts",
+            "compiler-cache",
+        )
+
+
+def is_enabled(part_info: "PartInfo") -> bool:
+    """Verify whether the compiler cache is enabled for this project."""
+    return part_info.compiler_cache is not None
+
+
+def get_ccache_environment(part_info: "PartInfo") -> Dict[str, str]:
+    """Obtain the environment variables configuring ccache.
+
+    :param part_info: The part information.
+
+    :return: The ccache environment, or an empty dictionary if the compiler
+        cache is not enabled.
+    """
+    config: Optional[CompilerCacheConfig] = part_info.compiler_cache
+    if not config:
+        return {}
+
+    cache_dir = config.get_cache_dir(part_info.application_name)
+    return {
+        "CCACHE_DIR": str(cache_dir / "ccache"),
+        "CCACHE_MAXSIZE": f"{config.max_size // 1024}Ki",
+        # The build directory differs between projects, paths relative to it
+        # allow sharing cached results.
+        "CCACHE_BASEDIR": os.fspath(part_info.parts_dir),
+    }
+
+
+def get_sccache_environment(part_info: "PartInfo") -> Dict[str, str]:
+    """Obtain the environment variables configuring sccache as rustc wrapper.
+
+    :param part_info: The part information.
+
+    :return: The sccache environment, or an empty dictionary if the compiler
+        cache is not enabled.
+    """
+    config: Optional[CompilerCacheConfig] = part_info.compiler_cache
+    if not config:
+        return {}
+
+    cache_dir = config.get_cache_dir(part_info.application_name)
+    return {
+        "RUSTC_WRAPPER": "sccache",
+        "SCCACHE_DIR": str(cache_dir / "sccache"),
+        "SCCACHE_CACHE_SIZE": f"{config.max_size // 1024}K",
+    }
+
+
+def get_compiler_environment(
+    part_info: "PartInfo", *, cc: str = "gcc", cxx: Optional[str] = "g++"
+) -> Dict[str, str]:
+    """Obtain the environment setting compilers wrapped by ccache.
+
+    When cross-compiling, the compilers for the target architecture are
+    used.
+
+    :param part_info: The part information.
+    :param cc: The C compiler to wrap.
+    :param cxx: The C++ compiler to wrap, if any.
+
+    :return: The compiler and ccache environment, or an empty dictionary if
+        the compiler cache is not enabled.
+    """
+    env = get_ccache_environment(part_info)
+    if not env:
+        return {}
+
+    prefix = part_info.cross_compiler_prefix if part_info.is_cross_compiling else ""
+    env["CC"] = f"ccache {prefix}{cc}"
+    if cxx:
+        env["CXX"] = f"ccache {prefix}{cxx}"
+    return env
@@ -0,0 +1,246 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""User and system configuration of craft-parts.
+
+Configuration files define defaults for all applications using craft-parts,
+such as cache locations, parallelism, proxies, source mirrors and feature
+flags. They're read from ``/etc/craft-parts/config.yaml``, from
+``craft-parts/config.yaml`` in the XDG configuration directory and from the
+file named in the ``CRAFT_PARTS_CONFIG`` environment variable, with values
+in later files taking precedence. Arguments passed to the lifecycle manager
+take precedence over configuration files.
+"""
+
+import logging
+import os
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Sequence, Union
+
+import yaml
+from pydantic import BaseModel, ValidationError
+from xdg import BaseDirectory  # type: ignore
+
+from craft_parts import errors
+from craft_parts.proxy import ProxyConfig
+from craft_parts.sources.mirrors import MirrorRule
+
+logger = logging.getLogger(__name__)
+
+CONFIG_ENVIRONMENT_VARIABLE = "CRAFT_PARTS_CONFIG"
+
+SYSTEM_CONFIG_FILE = Path("/etc/craft-parts/config.yaml")
+
+
+class ProxySpec(BaseModel):
+    """The proxies used to access the network."""
+
+    http_proxy: Optional[str] = None
+    https_proxy: Optional[str] = None
+    no_proxy: List[str] = []
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+
+class MirrorRuleSpec(BaseModel):
+    """A rule to rewrite source URLs, as defined in :class:`MirrorRule`."""
+
+    pattern: str
+    mirror: str
+    regex: bool = False
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+
+
+class PartsConfig(BaseModel):
+    """The craft-parts configuration.
+
+    Source mirror rules of configurations with higher precedence are tried
+    first, and feature flags are merged.
+    """
+
+    cache_dir: Optional[str] = None
+    cache_size_limit: Optional[int] = None
+    parallel_build_count: Optional[int] = None
+    max_parallel_parts: Optional[int] = None
+    proxy: Optional[ProxySpec] = None
+    source_mirrors: List[MirrorRuleSpec] = []
+    features: Dict[str, bool] = {}
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]) -> "PartsConfig":
+        """Create and populate a new ``PartsConfig`` object from dictionary data.
+
+        :param data: The dictionary data to unmarshal.
+
+        :return: The newly created object.
+
+        :raise TypeError: If data is not a dictionary.
+        """
+        if not isinstance(data, dict):
+            raise TypeError("configuration data is not a dictionary")
+
+        return cls(**data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the configuration data.
+
+        Only values that were set are included.
+
+        :return: The newly created dictionary.
+        """
+        return self.dict(by_alias=True, exclude_unset=True)
+
+    def merge(self, other: "PartsConfig") -> "PartsConfig":
+        """Create a configuration overriding values with another configuration.
+
+        :param other: The configuration with higher precedence.
+
+        :return: The merged configuration.
+        """
+        data = {**self.marshal(), **other.marshal()}
+        data["source-mirrors"] = [
+            x.dict() for x in [*other.source_mirrors, *self.source_mirrors]
+        ]
+        data["features"] = {**self.features, **other.features}
+        return PartsConfig.unmarshal(data)
+
+    def get_proxy_config(self) -> Optional[ProxyConfig]:
+        """Obtain the configured proxies.
+
+        :return: The proxy configuration, or None if proxies are not set.
+        """
+        if not self.proxy:
+            return None
+
+        return ProxyConfig(
+            http_proxy=self.proxy.http_proxy,
+            https_proxy=self.proxy.https_proxy,
+            no_proxy=tuple(self.proxy.no_proxy),
+        )
+
+    def get_mirror_rules(self) -> List[MirrorRule]:
+        """Obtain the configured source mirror rules.
+
+        :return: The list of mirror rules.
+
+        :raise InvalidMirrorRule: If a rule pattern is not valid.
+        """
+        return [
+            MirrorRule(rule.pattern, rule.mirror, regex=rule.regex)
+            for rule in self.source_mirrors
+        ]
+
+
+def get_config_files(env: Optional[Dict[str, str]] = None) -> List[Path]:
+    """Obtain the configuration files to read, in increasing precedence order.
+
+    :param env: The environment to read the configuration file override from.
+        Defaults to the current process environment.
+
+    :return: The list of configuration file paths.
+    """
+    if env is None:
+        env = dict(os.environ)
+
+    files = [
+        SYSTEM_CONFIG_FILE,
+        Path(BaseDirectory.xdg_config_home, "craft-parts", "config.yaml"),
+    ]
+    if env.get(CONFIG_ENVIRONMENT_VARIABLE):
+        files.append(Path(env[CONFIG_ENVIRONMENT_VARIABLE]))
+
+    return files
+
+
+def load_config(paths: Optional[Sequence[Union[Path, str]]] = None) -> PartsConfig:
+    """Read and merge configuration files.
+
+    Files that don't exist are ignored.
+
+    :param paths: The configuration files to read, in increasing precedence
+        order. Defaults to the files returned by :func:`get_config_files`.
+
+    :return: The merged configuration.
+
+    :raise errors.ConfigurationError: If a configuration file is not valid.
+    """
+    if paths is None:
+        paths = get_config_files()
+
+    config = PartsConfig()
+    for path in paths:
+        path = Path(path)
+        if not path.is_file():
+            continue
+
+        logger.debug("load configuration file %s", path)
+        config = config.merge(_load_config_file(path))
+
+    return config
+
+
+def _load_config_file(path: Path) -> PartsConfig:
+    """Read a configuration file.
+
+    :param path: The configuration file to read.
+
+    :return: The configuration defined in the file.
+
+    :raise errors.ConfigurationError: If the configuration file is not valid.
+    """
+    try:
+        with open(path) as config_file:
+            data = yaml.safe_load(config_file)
+    except OSError as err:
+        raise errors.ConfigurationError(str(path), message=str(err)) from err
+    except yaml.YAMLError as err:
+        mark = getattr(err, "problem_mark", None)
+        raise errors.ConfigurationError(
+            str(path), message=str(err), line=mark.line + 1 if mark else None
+        ) from err
+
+    if data is None:
+        return PartsConfig()
+
+    try:
+        return PartsConfig.unmarshal(data)
+    except TypeError as err:
+        raise errors.ConfigurationError(str(path), message=str(err)) from err
+    except ValidationError as err:
+        raise errors.ConfigurationError.from_validation_error(
+            str(path), error_list=err.errors()
+        ) from err
@@ -0,0 +1,81 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The ``craftctl`` client used by scriptlets to call the control API.
+
+Scriptlets run by craft-parts can execute the built-in handler of a step
+using ``craftctl <step>``. Project variables and values shared with scriptlets of
+other steps and parts are read using ``craftctl get <name>`` and set using
+``craftctl set <name>=<value>``.
+"""
+
+import json
+import os
+import sys
+from typing import List, Optional
+
+_USAGE = "usage: craftctl <function> [arguments]"
+
+
+def main() -> None:
+    """Run the control API client."""
+    if len(sys.argv) < 2:
+        print(_USAGE, file=sys.stderr)
+        sys.exit(2)
+
+    try:
+        result = client(sys.argv[1], sys.argv[2:])
+    except RuntimeError as err:
+        print(f"craftctl: {err}", file=sys.stderr)
+        sys.exit(1)
+
+    if result is not None:
+        print(result)
+
+
+def client(function: str, args: List[str]) -> Optional[str]:
+    """Execute a control API call in the running step handler.
+
+    :param function: The control API function to call.
+    :param args: The function arguments.
+
+    :return: The function result, if any.
+
+    :raise RuntimeError: If the call failed.
+    """
+    call_fifo = os.environ.get("PARTS_CALL_FIFO")
+    feedback_fifo = os.environ.get("PARTS_FEEDBACK_FIFO")
+    if not call_fifo or not feedback_fifo:
+        raise RuntimeError("not running in a craft-parts scriptlet")
+
+    with open(call_fifo, "w") as fifo:
+        fifo.write(json.dumps({"function": function, "args": args}))
+
+    with open(feedback_fifo, "r") as fifo:
+        feedback = fifo.readline().rstrip("\n")
+
+    # Calls returning a result are acknowledged with "OK <result>", other
+    # calls with an empty line. Anything else is an error message.
+    if feedback.startswith("OK "):
+        return feedback[3:]
+    if feedback:
+        raise RuntimeError(feedback)
+
+    return None
+
+
+if __name__ == "__main__":
+    main()
@@ -49,3 +49,8 @@ class ProjectDirs:
     def prime_dir(self) -> Path:
         """Return the primed tree containing the final artifacts to deploy."""
         return self._work_dir / "prime"
+
+    @property
+    def debug_dir(self) -> Path:
+        """Return the directory containing debug information split from binaries."""
+        return self._work_dir / "debug"
@@ -0,0 +1,27 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""ELF file handling.
+
+Primed binaries can be post-processed so that they find the libraries
+shipped with them: rpaths are rewritten to point to the directories
+containing the needed libraries, and the dynamic linker path can be
+replaced to use a dynamic linker provided by the deployment environment.
+"""
+
+from .elf_file import ElfFile, is_elf  # noqa: F401
+from .patcher import ElfPatcher  # noqa: F401
@@ -0,0 +1,101 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Read the dynamic linking information of ELF files."""
+
+import re
+import subprocess
+from pathlib import Path
+from typing import List, Optional, Union
+
+from . import errors
+
+_ELF_MAGIC = b"\x7fELF"
+
+_INTERPRETER_PATTERN = re.compile(r"\[Requesting program interpreter: (.*)\]")
+_DYNAMIC_PATTERN = re.compile(
+    r"\((NEEDED|RPATH|RUNPATH|SONAME)\)\s+[^:]+: \[(.*)\]", re.MULTILINE
+)
+
+
+def is_elf(path: Union[Path, str]) -> bool:
+    """Verify whether a file is an ELF file.
+
+    :param path: The path to the file to verify.
+
+    :return: Whether the file is a regular file starting with the ELF magic.
+    """
+    path = Path(path)
+    if path.is_symlink() or not path.is_file():
+        return False
+
+    with open(path, "rb") as file:
+        return file.read(len(_ELF_MAGIC)) == _ELF_MAGIC
+
+
+class ElfFile:
+    """The dynamic linking information of an ELF file.
+
+    :param path: The path to the ELF file.
+
+    :raise errors.ElfReadError: If the ELF file can't be read.
+    """
+
+    def __init__(self, path: Union[Path, str]) -> None:
+        self.path = Path(path)
+        self.interpreter: Optional[str] = None
+        self.soname: Optional[str] = None
+        self.needed: List[str] = []
+        self.rpath: List[str] = []
+
+        output = _readelf(self.path)
+
+        match = _INTERPRETER_PATTERN.search(output)
+        if match:
+            self.interpreter = match.group(1)
+
+        for tag, value in _DYNAMIC_PATTERN.findall(output):
+            if tag == "NEEDED":
+                self.needed.append(value)
+            elif tag == "SONAME":
+                self.soname = value
+            else:
+                self.rpath = [x for x in value.split(":") if x]
+
+    @property
+    def is_dynamic(self) -> bool:
+        """Whether the file is dynamically linked."""
+        return bool(self.interpreter or self.needed)
+
+
+def _readelf(path: Path) -> str:
+    """Obtain the program headers and dynamic section of an ELF file."""
+    try:
+        proc = subprocess.run(
+            ["readelf", "--program-headers", "--dynamic", "--wide", str(path)],
+            check=True,
+            capture_output=True,
+            text=True,
+        )
+    except FileNotFoundError as err:
+        raise errors.ElfReadError(
+            str(path), message="'readelf' is not installed"
+        ) from err
+    except subprocess.CalledProcessError as err:
+        raise errors.ElfReadError(str(path), message=err.stderr.strip()) from err
+
+    return proc.stdout
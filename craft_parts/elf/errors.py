@@ -0,0 +1,62 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Exceptions raised by the ELF handling subsystem."""
+
+from craft_parts.errors import PartsError
+
+
+class ElfError(PartsError):
+    """Base class for ELF handling errors."""
+
+    code = "elf-error"
+
+
+class ElfReadError(ElfError):
+    """Failed to read the dynamic linking information of an ELF file.
+
+    :param path: The path to the ELF file.
+    :param message: The error message.
+    """
+
+    code = "elf-read-error"
+
+    def __init__(self, path: str, *, message: str):
+        self.path = path
+        self.message = message
+        brief = f"Failed to read ELF file {path!r}: {message}."
+        resolution = "Make sure binutils is installed."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class ElfPatchError(ElfError):
+    """Failed to patch an ELF file.
+
+    :param path: The path to the ELF file.
+    :param message: The error message.
+    """
+
+    code = "elf-patch-error"
+
+    def __init__(self, path: str, *, message: str):
+        self.path = path
+        self.message = message
+        brief = f"Failed to patch ELF file {path!r}: {message}."
+        resolution = "Make sure patchelf is installed."
+
+        super().__init__(brief=brief, resolution=resolution)
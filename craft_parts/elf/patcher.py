@@ -0,0 +1,209 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Rewrite the rpaths and interpreters of ELF files in a directory."""
+
+import fnmatch
+import logging
+import os
+import shutil
+import subprocess
+from pathlib import Path
+from typing import Any, Dict, Iterable, List, Optional
+
+from craft_parts.utils import file_utils
+
+from . import errors
+from .elf_file import ElfFile, is_elf
+
+logger = logging.getLogger(__name__)
+
+
+class ElfPatcher:
+    """Make ELF files use the libraries shipped in the same directory tree.
+
+    The rpath of each dynamically linked file is rewritten to point to the
+    directories containing its needed libraries, relative to ``$ORIGIN``.
+    Existing ``$ORIGIN`` entries are preserved, and libraries that are not
+    found in the tree are left to be resolved by the dynamic linker. If an
+    interpreter is set, it replaces the dynamic linker path of executables.
+
+    :param root: The directory tree containing the ELF files and libraries,
+        usually the prime directory.
+    :param interpreter: The dynamic linker path to set in executables, or
+        None to keep the original dynamic linker.
+    :param exclude: Patterns of files that must not be patched, relative
+        to the root directory.
+    """
+
+    def __init__(
+        self,
+        root: Path,
+        *,
+        interpreter: Optional[str] = None,
+        exclude: Optional[List[str]] = None,
+    ) -> None:
+        self._root = root
+        self._interpreter = interpreter
+        self._exclude = exclude or []
+        self._library_dirs: Optional[Dict[str, str]] = None
+
+    def patch(
+        self,
+        paths: Iterable[str],
+        *,
+        previous: Optional[Dict[str, Dict[str, Any]]] = None,
+    ) -> Dict[str, Dict[str, Any]]:
+        """Patch ELF files in the directory tree.
+
+        Files that were patched in a previous run and didn't change since
+        are not inspected again.
+
+        :param paths: The files to patch, relative to the root directory.
+        :param previous: The result of a previous run.
+
+        :return: A dictionary mapping each patched file to the rpath and
+            interpreter set, and the digest of the patched file.
+
+        :raise errors.ElfReadError: If an ELF file can't be read.
+        :raise errors.ElfPatchError: If an ELF file can't be patched.
+        """
+        previous = previous or {}
+        patched: Dict[str, Dict[str, Any]] = {}
+
+        for path in sorted(paths):
+            if any(fnmatch.fnmatchcase(path, pattern) for pattern in self._exclude):
+                continue
+
+            filename = self._root / path
+            if not is_elf(filename):
+                continue
+
+            record = previous.get(path)
+            if record and self._is_unchanged(filename, record):
+                logger.debug("%s already patched", path)
+                patched[path] = record
+                continue
+
+            record = self._patch_file(filename, path=path)
+            if record:
+                patched[path] = record
+
+        return patched
+
+    def _is_unchanged(self, filename: Path, record: Dict[str, Any]) -> bool:
+        """Verify whether a file was patched by a previous run with the same options."""
+        interpreter = record.get("interpreter")
+        if self._interpreter and interpreter not in (None, self._interpreter):
+            return False
+
+        digest = file_utils.calculate_hash(str(filename), algorithm="sha256")
+        return record.get("digest") == digest
+
+    def _patch_file(self, filename: Path, *, path: str) -> Optional[Dict[str, Any]]:
+        """Patch a single ELF file, if needed."""
+        elf_file = ElfFile(filename)
+        if not elf_file.is_dynamic:
+            return None
+
+        args: List[str] = []
+
+        rpath = self._get_rpath(elf_file, path=path)
+        if rpath != elf_file.rpath:
+            args.extend(["--set-rpath", ":".join(rpath)])
+
+        interpreter = elf_file.interpreter
+        if self._interpreter and interpreter not in (None, self._interpreter):
+            interpreter = self._interpreter
+            args.extend(["--set-interpreter", interpreter])
+
+        if not args:
+            return None
+
+        logger.debug("patch %s: %s", path, args)
+
+        # Patch a copy, so that files this one was migrated from using
+        # hard links are not modified.
+        partial = filename.with_name(f".{filename.name}.partial")
+        try:
+            shutil.copy2(filename, partial)
+            _patchelf([*args, str(partial)], path=path)
+            os.replace(partial, filename)
+        finally:
+            if partial.exists():
+                partial.unlink()
+
+        return {
+            "rpath": ":".join(rpath),
+            "interpreter": interpreter,
+            "digest": file_utils.calculate_hash(str(filename), algorithm="sha256"),
+        }
+
+    def _get_rpath(self, elf_file: ElfFile, *, path: str) -> List[str]:
+        """Compute the rpath pointing to the libraries needed by an ELF file."""
+        library_dirs = self._get_library_dirs()
+        origin_dir = os.path.dirname(path)
+
+        rpath = [x for x in elf_file.rpath if x.startswith("$ORIGIN")]
+        for library in elf_file.needed:
+            library_dir = library_dirs.get(library)
+            if library_dir is None:
+                continue
+
+            relpath = os.path.relpath(library_dir, origin_dir or ".")
+            entry = "$ORIGIN" if relpath == "." else f"$ORIGIN/{relpath}"
+            if entry not in rpath:
+                rpath.append(entry)
+
+        return rpath
+
+    def _get_library_dirs(self) -> Dict[str, str]:
+        """Map the names of libraries in the tree to the directories containing them.
+
+        Libraries are indexed by file name and soname. If a library is found
+        in more than one directory, the first one in lexical order is used.
+        """
+        if self._library_dirs is not None:
+            return self._library_dirs
+
+        library_dirs: Dict[str, str] = {}
+        for root, dirs, files in os.walk(self._root):
+            dirs.sort()
+            reldir = os.path.relpath(root, self._root)
+            for name in sorted(files):
+                if ".so" not in name:
+                    continue
+
+                filename = Path(root, name)
+                library_dirs.setdefault(name, reldir)
+                if is_elf(filename.resolve()):
+                    soname = ElfFile(filename.resolve()).soname
+                    if soname:
+                        library_dirs.setdefault(soname, reldir)
+
+        self._library_dirs = library_dirs
+        return library_dirs
+
+
+def _patchelf(args: List[str], *, path: str) -> None:
+    """Execute patchelf with the given arguments."""
+    try:
+        subprocess.run(["patchelf", *args], check=True, capture_output=True, text=True)
+    except FileNotFoundError as err:
+        raise errors.ElfPatchError(path, message="'patchelf' is not installed") from err
+    except subprocess.CalledProcessError as err:
+        raise errors.ElfPatchError(path, message=err.stderr.strip()) from err
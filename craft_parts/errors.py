@@ -17,18 +17,38 @@
 """Craft parts errors."""
 
 import dataclasses
-from typing import Any, Dict, List, Optional, Set
+import json
+from pathlib import PurePath
+from typing import Any, ClassVar, Dict, Iterable, List, Optional, Set
+
+from craft_parts.utils import formatting_utils
+
+# Error attributes describing where the error happened, and the names of
+# the keys they're serialized with.
+_LOCATION_ATTRIBUTES = {
+    "part_name": "part",
+    "step_name": "step",
+    "path": "file",
+    "filename": "file",
+    "file_path": "file",
+    "line": "line",
+}
 
 
 @dataclasses.dataclass(repr=True)
 class PartsError(Exception):
     """Unexpected error.
 
+    Each error class has a stable ``code`` that applications can use to
+    identify the failure category instead of parsing error messages.
+
     :param brief: Brief description of error.
     :param details: Detailed information.
     :param resolution: Recommendation, if any.
     """
 
+    code: ClassVar[str] = "parts-error"
+
     brief: str
     details: Optional[str] = None
     resolution: Optional[str] = None
@@ -44,10 +64,62 @@ class PartsError(Exception):
 
         return "\n".join(components)
 
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary describing this error.
+
+        The dictionary contains the error code and messages, the part, step,
+        file and line the error refers to, if known, and the attributes
+        specific to the error class.
+
+        :return: The newly created dictionary.
+        """
+        data: Dict[str, Any] = {
+            "code": self.code,
+            "brief": self.brief,
+            "details": self.details,
+            "resolution": self.resolution,
+            "part": None,
+            "step": None,
+            "file": None,
+            "line": None,
+        }
+
+        attributes = {
+            name: _to_json_value(value)
+            for name, value in vars(self).items()
+            if name not in ("brief", "details", "resolution")
+            and not name.startswith("_")
+        }
+
+        for name, key in _LOCATION_ATTRIBUTES.items():
+            if data[key] is None and attributes.get(name) is not None:
+                data[key] = attributes[name]
+
+        # Scriptlets are named after the step they override.
+        scriptlet_name = attributes.get("scriptlet_name")
+        if data["step"] is None and isinstance(scriptlet_name, str):
+            if scriptlet_name.startswith("override-"):
+                data["step"] = scriptlet_name[len("override-") :]
+
+        data["attributes"] = attributes
+        return data
+
+    def to_json(self, *, indent: Optional[int] = None) -> str:
+        """Serialize this error as a JSON document.
+
+        :param indent: The JSON indentation level. If not specified, the
+            document is written in a single line.
+
+        :return: The JSON document describing the error.
+        """
+        return json.dumps(self.marshal(), indent=indent)
+
 
 class PartDependencyCycle(PartsError):
     """A dependency cycle has been detected in the parts definition."""
 
+    code = "part-dependency-cycle"
+
     def __init__(self) -> None:
         brief = "A circular dependency chain was detected."
         resolution = "Review the parts definition to remove dependency cycles."
@@ -61,6 +133,8 @@ class InvalidPartName(PartsError):
     :param part_name: The invalid part name.
     """
 
+    code = "invalid-part-name"
+
     def __init__(self, part_name: str):
         self.part_name = part_name
         brief = f"A part named {part_name!r} is not defined in the parts list."
@@ -75,6 +149,8 @@ class InvalidArchitecture(PartsError):
     :param arch_name: The unsupported architecture name.
     """
 
+    code = "invalid-architecture"
+
     def __init__(self, arch_name: str):
         self.arch_name = arch_name
         brief = f"Architecture {arch_name!r} is not supported."
@@ -90,6 +166,8 @@ class PartSpecificationError(PartsError):
     :param message: The error message.
     """
 
+    code = "part-specification-error"
+
     def __init__(self, *, part_name: str, message: str):
         self.part_name = part_name
         self.message = message
@@ -100,21 +178,88 @@ class PartSpecificationError(PartsError):
         super().__init__(brief=brief, details=details, resolution=resolution)
 
     @classmethod
-    def from_validation_error(cls, *, part_name: str, error_list: List[Dict[str, Any]]):
-        """Create a PartSpecificationError from a pydantic error list."""
-        formatted_errors: List[str] = []
+    def from_validation_error(
+        cls,
+        *,
+        part_name: str,
+        error_list: List[Dict[str, Any]],
+        valid_names: Optional[Iterable[str]] = None,
+    ):
+        """Create a PartSpecificationError from a pydantic error list.
+
+        If valid property names are given, errors caused by unknown properties
+        suggest the closest valid name.
+        """
+        message = _format_validation_errors(error_list, valid_names=valid_names)
+        return cls(part_name=part_name, message=message)
+
+
+class PartTemplateError(PartsError):
+    """A part template was not correctly specified.
+
+    :param template_name: The template name.
+    :param message: The error message.
+    """
 
-        for error in error_list:
-            loc = error.get("loc")
-            msg = error.get("msg")
+    code = "part-template-error"
 
-            if not (loc and msg) or not isinstance(loc, tuple):
-                continue
+    def __init__(self, *, template_name: str, message: str):
+        self.template_name = template_name
+        self.message = message
+        brief = f"Part template {template_name!r} validation failed."
+        details = message
+        resolution = (
+            f"Review part template {template_name!r} and make sure it's correct."
+        )
+
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+
+class PartsFileError(PartsError):
+    """The file containing the parts definition can't be loaded.
 
-            fields = ",".join([repr(entry) for entry in loc])
-            formatted_errors.append(f"{fields}: {msg}")
+    :param path: The parts file path.
+    :param message: The error message.
+    :param line: The line of the parts file containing the error.
+    """
+
+    code = "parts-file-error"
+
+    def __init__(self, path: str, *, message: str, line: Optional[int] = None):
+        self.path = path
+        self.message = message
+        self.line = line
+        brief = f"Cannot load parts file {path!r}."
+        details = message
+        resolution = "Make sure the file exists and contains a parts definition."
 
-        return cls(part_name=part_name, message="\n".join(formatted_errors))
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+
+class ConfigurationError(PartsError):
+    """A craft-parts configuration file is not valid.
+
+    :param path: The configuration file path.
+    :param message: The error message.
+    :param line: The line of the configuration file containing the error.
+    """
+
+    code = "configuration-error"
+
+    def __init__(self, path: str, *, message: str, line: Optional[int] = None):
+        self.path = path
+        self.message = message
+        self.line = line
+        brief = f"Invalid configuration file {path!r}."
+        details = message
+        resolution = "Review the configuration file and make sure it's correct."
+
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+    @classmethod
+    def from_validation_error(cls, path: str, *, error_list: List[Dict[str, Any]]):
+        """Create a ConfigurationError from a pydantic error list."""
+        return cls(path, message=_format_validation_errors(error_list))
 
 
 class CopyTreeError(PartsError):
@@ -123,6 +268,8 @@ class CopyTreeError(PartsError):
     :param message: The error message.
     """
 
+    code = "copy-tree-error"
+
     def __init__(self, message: str):
         self.message = message
         brief = f"Failed to copy or link file tree: {message}."
@@ -137,6 +284,8 @@ class CopyFileNotFound(PartsError):
     :param name: The file name.
     """
 
+    code = "copy-file-not-found"
+
     def __init__(self, name: str):
         self.name = name
         brief = f"Failed to copy {name!r}: no such file or directory."
@@ -152,6 +301,8 @@ class XAttributeError(PartsError):
     :param path: The file path.
     """
 
+    code = "x-attribute-error"
+
     def __init__(self, key: str, path: str, is_write: bool = False):
         self.key = key
         self.path = path
@@ -167,6 +318,8 @@ class XAttributeError(PartsError):
 class XAttributeTooLong(PartsError):
     """Failed to write an extended attribute because key and/or value is too long."""
 
+    code = "x-attribute-too-long"
+
     def __init__(self, key: str, value: str, path: str):
         self.key = key
         self.value = value
@@ -183,6 +336,8 @@ class UndefinedPlugin(PartsError):
     :param part_name: The name of the part with no plugin definition."
     """
 
+    code = "undefined-plugin"
+
     def __init__(self, *, part_name: str):
         self.part_name = part_name
         brief = f"Plugin not defined for part {part_name!r}."
@@ -197,6 +352,8 @@ class InvalidPlugin(PartsError):
     :param plugin_name: The invalid plugin name."
     """
 
+    code = "invalid-plugin"
+
     def __init__(self, plugin_name: str, *, part_name: str):
         self.plugin_name = plugin_name
         self.part_name = part_name
@@ -206,9 +363,29 @@ class InvalidPlugin(PartsError):
         super().__init__(brief=brief, resolution=resolution)
 
 
+class PluginLoadError(PartsError):
+    """A plugin provided by the project or an installed package cannot be loaded.
+
+    :param plugin_name: The name of the plugin.
+    :param message: The error message.
+    """
+
+    code = "plugin-load-error"
+
+    def __init__(self, plugin_name: str, *, message: str):
+        self.plugin_name = plugin_name
+        self.message = message
+        brief = f"Failed to load plugin {plugin_name!r}: {message}."
+        resolution = "Make sure the plugin is correct."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
 class OsReleaseIdError(PartsError):
     """Failed to determine the host operating system identification string."""
 
+    code = "os-release-id-error"
+
     def __init__(self):
         brief = "Unable to determine the host operating system ID."
 
@@ -218,6 +395,8 @@ class OsReleaseIdError(PartsError):
 class OsReleaseNameError(PartsError):
     """Failed to determine the host operating system name."""
 
+    code = "os-release-name-error"
+
     def __init__(self):
         brief = "Unable to determine the host operating system name."
 
@@ -227,6 +406,8 @@ class OsReleaseNameError(PartsError):
 class OsReleaseVersionIdError(PartsError):
     """Failed to determine the host operating system version."""
 
+    code = "os-release-version-id-error"
+
     def __init__(self):
         brief = "Unable to determine the host operating system version ID."
 
@@ -236,15 +417,90 @@ class OsReleaseVersionIdError(PartsError):
 class OsReleaseCodenameError(PartsError):
     """Failed to determine the host operating system version codename."""
 
+    code = "os-release-codename-error"
+
     def __init__(self):
         brief = "Unable to determine the host operating system codename."
 
         super().__init__(brief=brief)
 
 
+class InvalidBase(PartsError):
+    """The project base name is malformed.
+
+    :param base: The invalid base name.
+    """
+
+    code = "invalid-base"
+
+    def __init__(self, base: str):
+        self.base = base
+        brief = f"Invalid base {base!r}."
+        resolution = "Name the base as '<distribution>@<version>', e.g. 'ubuntu@22.04'."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class UnsupportedBase(PartsError):
+    """No package backend is available for the project base.
+
+    :param base: The name of the unsupported base.
+    """
+
+    code = "unsupported-base"
+
+    def __init__(self, base: str):
+        self.base = base
+        brief = f"Base {base!r} is not supported."
+        details = "System packages can't be installed or staged on this base."
+        resolution = (
+            "Use a base supported by craft-parts, or register a package "
+            "backend for its distribution."
+        )
+
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+
+class HostFeatureNotSupported(PartsError):
+    """A feature used by the project is not available in the host.
+
+    :param feature: The unavailable feature.
+    :param host: The name of the host operating system.
+    """
+
+    code = "host-feature-not-supported"
+
+    def __init__(self, feature: str, *, host: str):
+        self.feature = feature
+        self.host = host
+        brief = f"Cannot use {feature} on {host} hosts."
+        resolution = "Process the project on a Linux host."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class FeatureNotEnabled(PartsError):
+    """A feature used by the project is not enabled.
+
+    :param feature: The name of the feature flag.
+    :param details: Information about how the feature is used.
+    """
+
+    code = "feature-not-enabled"
+
+    def __init__(self, feature: str, *, details: Optional[str] = None):
+        self.feature = feature
+        brief = f"Feature {feature!r} is not enabled."
+        resolution = f"Enable the {feature!r} feature flag in the configuration."
+
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+
 class FilesetError(PartsError):
     """An invalid fileset operation was performed."""
 
+    code = "fileset-error"
+
     def __init__(self, *, name: str, message: str):
         self.name = name
         self.message = message
@@ -257,6 +513,8 @@ class FilesetError(PartsError):
 class FilesetConflict(PartsError):
     """Inconsistent stage to prime filtering."""
 
+    code = "fileset-conflict"
+
     def __init__(self, conflicting_files: Set[str]):
         self.conflicting_files = conflicting_files
         brief = "Failed to filter files: inconsistent 'stage' and 'prime' filesets."
@@ -278,6 +536,8 @@ class FileOrganizeError(PartsError):
     :param message: The error message.
     """
 
+    code = "file-organize-error"
+
     def __init__(self, *, part_name, message):
         self.part_name = part_name
         self.message = message
@@ -286,9 +546,56 @@ class FileOrganizeError(PartsError):
         super().__init__(brief=brief)
 
 
+class FilePermissionsError(PartsError):
+    """Failed to set the permissions of a file.
+
+    :param part_name: The name of the part being processed.
+    :param path: The path of the file, relative to the prime directory.
+    :param message: The error message.
+    """
+
+    code = "file-permissions-error"
+
+    def __init__(self, *, part_name: str, path: str, message: str):
+        self.part_name = part_name
+        self.path = path
+        self.message = message
+        brief = (
+            f"Failed to set permissions of {path!r} in part {part_name!r}: "
+            f"{message}."
+        )
+        resolution = "Make sure the permissions defined in the part are valid."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class StripError(PartsError):
+    """Failed to strip a primed binary.
+
+    :param part_name: The name of the part being processed.
+    :param path: The path of the file, relative to the prime directory.
+    :param message: The error message.
+    """
+
+    code = "strip-error"
+
+    def __init__(self, *, part_name: str, path: str, message: str):
+        self.part_name = part_name
+        self.path = path
+        self.message = message
+        brief = f"Failed to strip {path!r} in part {part_name!r}: {message}."
+        resolution = (
+            "Make sure binutils is installed, or exclude the file from stripping."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
 class PartFilesConflict(PartsError):
     """Different parts list the same files with different contents."""
 
+    code = "part-files-conflict"
+
     def __init__(
         self, *, part_name: str, other_part_name: str, conflicting_files: List[str]
     ):
@@ -314,6 +621,8 @@ class StageFilesConflict(PartsError):
     :param conflicting_files: The list of confictling files.
     """
 
+    code = "stage-files-conflict"
+
     def __init__(self, *, part_name: str, conflicting_files: List[str]):
         self.part_name = part_name
         self.conflicting_files = conflicting_files
@@ -329,14 +638,50 @@ class StageFilesConflict(PartsError):
         super().__init__(brief=brief, details=details)
 
 
+class PluginEnvironmentValidationError(PartsError):
+    """Plugin environment is not valid to process the part.
+
+    :param part_name: The name of the part being processed.
+    :param reason: A description of the environment problem.
+    """
+
+    code = "plugin-environment-validation-error"
+
+    def __init__(self, *, part_name: str, reason: str):
+        self.part_name = part_name
+        self.reason = reason
+        brief = f"Environment validation failed for part {part_name!r}: {reason}."
+
+        super().__init__(brief=brief)
+
+
+class PluginPullError(PartsError):
+    """Plugin pull script failed at runtime.
+
+    :param part_name: The name of the part being processed.
+    """
+
+    code = "plugin-pull-error"
+
+    def __init__(self, *, part_name: str):
+        self.part_name = part_name
+        self.step_name = "pull"
+        brief = f"Failed to run the pull script for part {part_name!r}."
+
+        super().__init__(brief=brief)
+
+
 class PluginBuildError(PartsError):
     """Plugin build script failed at runtime.
 
     :param part_name: The name of the part being processed.
     """
 
+    code = "plugin-build-error"
+
     def __init__(self, *, part_name: str):
         self.part_name = part_name
+        self.step_name = "build"
         brief = f"Failed to run the build script for part {part_name!r}."
 
         super().__init__(brief=brief)
@@ -350,6 +695,8 @@ class InvalidControlAPICall(PartsError):
     :param message: The error message.
     """
 
+    code = "invalid-control-api-call"
+
     def __init__(self, *, part_name: str, scriptlet_name: str, message: str):
         self.part_name = part_name
         self.scriptlet_name = scriptlet_name
@@ -371,6 +718,8 @@ class ScriptletRunError(PartsError):
     :param exit_code: The execution error code.
     """
 
+    code = "scriptlet-run-error"
+
     def __init__(self, *, part_name: str, scriptlet_name: str, exit_code: int):
         self.part_name = part_name
         self.scriptlet_name = scriptlet_name
@@ -383,14 +732,199 @@ class ScriptletRunError(PartsError):
         super().__init__(brief=brief, resolution=resolution)
 
 
+class StepTimeoutError(PartsError):
+    """A step didn't finish within its timeout.
+
+    :param part_name: The name of the part being processed.
+    :param step_name: The name of the step that timed out.
+    :param timeout: The step timeout in seconds.
+    """
+
+    code = "step-timeout-error"
+
+    def __init__(self, *, part_name: str, step_name: str, timeout: float):
+        self.part_name = part_name
+        self.step_name = step_name
+        self.timeout = timeout
+        brief = (
+            f"Step {step_name!r} of part {part_name!r} timed out after "
+            f"{timeout:g} seconds."
+        )
+        resolution = "Increase the step timeout or check for stalled commands."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class NetworkIsolationError(PartsError):
+    """Commands of a step can't be executed without network access.
+
+    :param part_name: The name of the part being processed.
+    :param step_name: The name of the step with network access disabled.
+    """
+
+    code = "network-isolation-error"
+
+    def __init__(self, *, part_name: str, step_name: str):
+        self.part_name = part_name
+        self.step_name = step_name
+        brief = (
+            f"Cannot disable network access for step {step_name!r} "
+            f"of part {part_name!r}."
+        )
+        resolution = (
+            "Make sure 'unshare' is installed and that network namespaces "
+            "can be created in this host."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class BuildIsolationError(PartsError):
+    """The build of a part can't be isolated from the host.
+
+    :param part_name: The name of the part being built.
+    :param message: The error message.
+    """
+
+    code = "build-isolation-error"
+
+    def __init__(self, *, part_name: str, message: str):
+        self.part_name = part_name
+        self.message = message
+        brief = f"Cannot isolate the build of part {part_name!r}: {message}."
+        resolution = (
+            "Make sure the base layer directory is set and that the isolation "
+            "method is supported in this host."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class EmulationError(PartsError):
+    """Binaries of the target architecture can't be executed in the host.
+
+    :param arch: The target architecture.
+    :param message: The error message.
+    """
+
+    code = "emulation-error"
+
+    def __init__(self, arch: str, *, message: str):
+        self.arch = arch
+        self.message = message
+        brief = f"Cannot emulate architecture {arch!r}: {message}."
+        resolution = (
+            "Make sure qemu-user-static and binfmt-support are installed "
+            "in this host."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class SecretNotFound(PartsError):
+    """A build secret referenced by a part is not available.
+
+    :param part_name: The name of the part referencing the secret.
+    :param secret_name: The name of the secret.
+    """
+
+    code = "secret-not-found"
+
+    def __init__(self, *, part_name: str, secret_name: str):
+        self.part_name = part_name
+        self.secret_name = secret_name
+        brief = f"Secret {secret_name!r} used by part {part_name!r} is not available."
+        resolution = (
+            "Make sure the secret is set in the host environment or provided "
+            "by the application."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class StateVersionError(PartsError):
+    """A state was written by a newer version of craft-parts.
+
+    :param part_name: The name of the part the state belongs to.
+    :param step_name: The name of the step the state belongs to.
+    :param version: The schema version of the state.
+    :param supported_version: The latest schema version supported.
+    """
+
+    code = "state-version-error"
+
+    def __init__(
+        self, *, part_name: str, step_name: str, version: Any, supported_version: int
+    ):
+        self.part_name = part_name
+        self.step_name = step_name
+        self.version = version
+        self.supported_version = supported_version
+        brief = (
+            f"State of step {step_name!r} of part {part_name!r} has schema "
+            f"version {version}, newer than the supported version "
+            f"{supported_version}."
+        )
+        resolution = "Upgrade the application, or clean the part to discard its state."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
 class CallbackRegistrationError(PartsError):
     """Error in callback function registration.
 
     :param message: the error message.
     """
 
+    code = "callback-registration-error"
+
     def __init__(self, message: str):
         self.message = message
         brief = f"Callback registration error: {message}."
 
         super().__init__(brief=brief)
+
+
+def _format_validation_errors(
+    error_list: List[Dict[str, Any]], *, valid_names: Optional[Iterable[str]] = None
+) -> str:
+    """Format a pydantic error list, one error per line."""
+    formatted_errors: List[str] = []
+    names = list(valid_names or [])
+
+    for error in error_list:
+        loc = error.get("loc")
+        msg = error.get("msg")
+
+        if not (loc and msg) or not isinstance(loc, tuple):
+            continue
+
+        fields = ",".join([repr(entry) for entry in loc])
+        if names and len(loc) == 1 and error.get("type") == "value_error.extra":
+            match = formatting_utils.get_close_match(str(loc[0]), names)
+            if match:
+                msg = f"{msg} (did you mean {match!r}?)"
+
+        formatted_errors.append(f"{fields}: {msg}")
+
+    return "\n".join(formatted_errors)
+
+
+def _to_json_value(value: Any) -> Any:
+    """Convert an error attribute to a value that can be serialized to JSON."""
+    if value is None or isinstance(value, (bool, int, float, str)):
+        return value
+
+    if isinstance(value, PurePath):
+        return str(value)
+
+    if isinstance(value, dict):
+        return {str(key): _to_json_value(item) for key, item in value.items()}
+
+    if isinstance(value, (set, frozenset)):
+        return sorted(_to_json_value(item) for item in value)
+
+    if isinstance(value, (list, tuple)):
+        return [_to_json_value(item) for item in value]
+
+    return str(value)
@@ -0,0 +1,188 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Events emitted while executing lifecycle actions.
+
+Applications can register an event handler using
+:func:`craft_parts.callbacks.register_event_handler` to receive events as
+actions are executed, and use them to present progress and output in a
+structured way.
+"""
+
+import enum
+import time
+from dataclasses import dataclass, field
+from typing import Any, Dict, Optional
+
+from craft_parts.actions import Action
+from craft_parts.executor.metrics import ActionMetrics
+from craft_parts.progress import DownloadKind
+
+
+@enum.unique
+class ActionStatus(enum.Enum):
+    """The result of an action execution."""
+
+    SUCCEEDED = "succeeded"
+    FAILED = "failed"
+    SKIPPED = "skipped"
+
+
+@enum.unique
+class OutputStream(enum.Enum):
+    """The stream an output chunk was written to."""
+
+    STDOUT = "stdout"
+    STDERR = "stderr"
+
+
+@dataclass(frozen=True)
+class Event:
+    """Base class for execution events.
+
+    :param action: The action being executed.
+    :param timestamp: The time the event was created, in seconds since
+        the epoch.
+    """
+
+    action: Action
+    timestamp: float = field(default_factory=time.time, compare=False, init=False)
+
+    name = "event"
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the event data.
+
+        :return: The newly created dictionary.
+        """
+        return {
+            "event": self.name,
+            "timestamp": self.timestamp,
+            **self.action.marshal(),
+        }
+
+
+@dataclass(frozen=True)
+class ActionStarted(Event):
+    """The execution of an action started.
+
+    :param index: The position of the action in the list being executed,
+        starting at zero.
+    :param total: The number of actions in the list being executed.
+    """
+
+    index: int = 0
+    total: int = 1
+
+    name = "action-started"
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the event data.
+
+        :return: The newly created dictionary.
+        """
+        return {**super().marshal(), "index": self.index, "total": self.total}
+
+
+@dataclass(frozen=True)
+class ActionOutput(Event):
+    """A command executed by an action produced output.
+
+    :param stream: The stream the output was written to.
+    :param data: The output text.
+    """
+
+    stream: OutputStream = OutputStream.STDOUT
+    data: str = ""
+
+    name = "action-output"
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the event data.
+
+        :return: The newly created dictionary.
+        """
+        return {**super().marshal(), "stream": self.stream.value, "data": self.data}
+
+
+@dataclass(frozen=True)
+class ActionFinished(Event):
+    """The execution of an action finished.
+
+    :param status: The result of the action execution.
+    :param error: The error message, if the action failed.
+    :param metrics: The resources used by the action, if it was executed.
+    """
+
+    status: ActionStatus = ActionStatus.SUCCEEDED
+    error: Optional[str] = None
+    metrics: Optional[ActionMetrics] = None
+
+    name = "action-finished"
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the event data.
+
+        :return: The newly created dictionary.
+        """
+        data = {**super().marshal(), "status": self.status.value}
+        if self.error is not None:
+            data["error"] = self.error
+        if self.metrics is not None:
+            data["duration"] = self.metrics.duration
+            data["cpu-time"] = self.metrics.cpu_time
+            data["peak-disk-usage"] = self.metrics.peak_disk_usage
+        return data
+
+
+@dataclass(frozen=True)
+class DownloadProgress(Event):
+    """An action is downloading a source, stage package or stage snap.
+
+    :param kind: The kind of item being downloaded.
+    :param item: The name of the item being downloaded, such as the source
+        URL or the package name.
+    :param downloaded: The number of bytes downloaded.
+    :param total: The number of bytes to download, if known.
+    """
+
+    kind: DownloadKind = DownloadKind.SOURCE
+    item: str = ""
+    downloaded: int = 0
+    total: Optional[int] = None
+
+    name = "download-progress"
+
+    @property
+    def percentage(self) -> Optional[float]:
+        """The percentage of the download completed, if the total is known."""
+        if not self.total:
+            return None
+        return min(100.0, 100.0 * self.downloaded / self.total)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the event data.
+
+        :return: The newly created dictionary.
+        """
+        return {
+            **super().marshal(),
+            "kind": self.kind.value,
+            "item": self.item,
+            "downloaded": self.downloaded,
+            "total": self.total,
+            "percentage": self.percentage,
+        }
@@ -15,3 +15,6 @@
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 """The action executor."""
+
+from .executor import ExecutionContext, Executor  # noqa: F401
+from .metrics import ActionMetrics  # noqa: F401
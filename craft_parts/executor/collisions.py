@@ -18,7 +18,7 @@
 
 import filecmp
 import os
-from typing import Any, Dict, List
+from typing import Any, Dict, List, Optional, Set
 
 from craft_parts import errors
 from craft_parts.executor import filesets
@@ -26,13 +26,28 @@ from craft_parts.executor.filesets import Fileset
 from craft_parts.parts import Part
 
 
-def check_for_stage_collisions(part_list: List[Part]) -> None:
+def check_for_stage_collisions(part_list: List[Part]) -> Dict[str, Set[str]]:
     """Verify whether parts have conflicting files to stage.
 
+    Conflicts are resolved according to the stage conflict policies of the
+    parts involved. Parts are expected to be listed in staging order, so the
+    ``first-wins`` policy keeps the file from the part listed earlier and the
+    ``last-wins`` policy keeps the file from the part listed later. With the
+    ``prefer-part`` policy the file from the part setting the policy is kept.
+    Conflicts are errors if neither part sets a policy other than ``error``,
+    or if the parts disagree about the file to keep.
+
     :param part_list: The list of parts to be tested.
-    :raises PartConflictError: If conflicts are found.
+
+    :return: A dictionary mapping part names to the conflicting files that
+        must not be staged by the part.
+
+    :raises PartFilesConflict: If conflicts can't be resolved.
     """
     all_parts_files: Dict[str, Dict[str, Any]] = {}
+    # The part providing the file that will be staged, for each file.
+    owners: Dict[str, Part] = {}
+    overridden: Dict[str, Set[str]] = {}
     for part in part_list:
         stage_files = part.spec.stage_files
         if not stage_files:
@@ -52,14 +67,25 @@ def check_for_stage_collisions(part_list: List[Part]) -> None:
             common = part_contents & all_parts_files[other_part_name]["files"]
 
             conflict_files = []
-            for file in common:
+            for file in sorted(common):
+                owner = owners[file]
+                if owner.name != other_part_name:
+                    continue
+
                 this = os.path.join(part.part_install_dir, file)
-                other = os.path.join(
-                    all_parts_files[other_part_name]["installdir"], file
-                )
+                other = os.path.join(owner.part_install_dir, file)
+
+                if not paths_collide(this, other):
+                    continue
 
-                if paths_collide(this, other):
+                winner = _resolve_conflict(file, first=owner, last=part)
+                if winner is None:
                     conflict_files.append(file)
+                    continue
+
+                loser = owner if winner is part else part
+                overridden.setdefault(loser.name, set()).add(file)
+                owners[file] = winner
 
             if conflict_files:
                 raise errors.PartFilesConflict(
@@ -73,6 +99,36 @@ def check_for_stage_collisions(part_list: List[Part]) -> None:
             "files": part_contents,
             "installdir": part.part_install_dir,
         }
+        for file in part_contents:
+            owners.setdefault(file, part)
+
+    return overridden
+
+
+def _resolve_conflict(path: str, *, first: Part, last: Part) -> Optional[Part]:
+    """Obtain the part whose file is kept when staging a conflicting path.
+
+    :param path: The conflicting path, relative to the part install directory.
+    :param first: The part staged first.
+    :param last: The part staged last.
+
+    :return: The part providing the file to stage, or None if the conflict
+        can't be resolved.
+    """
+    winners: Set[str] = set()
+    for part in (first, last):
+        policy = part.spec.get_stage_conflict_policy(path)
+        if policy == "first-wins":
+            winners.add(first.name)
+        elif policy == "last-wins":
+            winners.add(last.name)
+        elif policy == "prefer-part":
+            winners.add(part.name)
+
+    if len(winners) != 1:
+        return None
+
+    return first if first.name in winners else last
 
 
 def paths_collide(path1: str, path2: str) -> bool:
@@ -0,0 +1,100 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Describe how a step would be executed, without executing it."""
+
+from dataclasses import dataclass
+from typing import Any, Dict, List, Optional
+
+from craft_parts import plugins
+from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
+from craft_parts.parts import Part
+from craft_parts.steps import Step
+
+from . import environment
+
+
+@dataclass(frozen=True)
+class StepDescription:
+    """The properties, environment and commands used to execute a step.
+
+    :param part_name: The name of the part the step belongs to.
+    :param step: The described step.
+    :param properties: The part properties, after templates, conditional
+        blocks and variables are applied, including plugin properties.
+    :param environment: The environment script sourced before step commands.
+    :param commands: The commands run by the built-in step handler, such
+        as the plugin build commands. Built-in handlers of the stage and
+        prime steps don't run commands.
+    :param scriptlet: The scriptlet overriding the built-in handler, if any.
+        The built-in handler only runs if the scriptlet calls it.
+    """
+
+    part_name: str
+    step: Step
+    properties: Dict[str, Any]
+    environment: str
+    commands: List[str]
+    scriptlet: Optional[str] = None
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the step description data.
+
+        :return: The newly created dictionary.
+        """
+        return {
+            "part": self.part_name,
+            "step": self.step.name.lower(),
+            "properties": self.properties,
+            "environment": self.environment,
+            "commands": self.commands,
+            "scriptlet": self.scriptlet,
+        }
+
+
+def describe_step(
+    part: Part, step: Step, *, project_info: ProjectInfo
+) -> StepDescription:
+    """Obtain the description of how a step of a part would be executed.
+
+    :param part: The part the step belongs to.
+    :param step: The step to describe.
+    :param project_info: The project information.
+
+    :return: The step description.
+    """
+    part_info = PartInfo(project_info, part)
+    step_info = StepInfo(part_info, step)
+    plugin = plugins.get_plugin(
+        part=part, part_info=part_info, properties=part.plugin_properties
+    )
+
+    commands: List[str] = []
+    if step == Step.PULL:
+        commands = plugin.get_pull_commands()
+    elif step == Step.BUILD:
+        commands = plugin.get_build_commands()
+
+    return StepDescription(
+        part_name=part.name,
+        step=step,
+        properties={**part.spec.marshal(), **part.plugin_properties.marshal()},
+        environment=environment.generate_part_environment(
+            part=part, plugin=plugin, step_info=step_info
+        ),
+        commands=commands,
+        scriptlet=part.spec.get_scriptlet(step),
+    )
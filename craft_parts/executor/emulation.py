@@ -0,0 +1,133 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Execute binaries of a foreign architecture using qemu-user emulation.
+
+Foreign binaries are executed by the kernel using the qemu-user interpreter
+registered as a binfmt_misc handler. Handlers must be registered with the
+fix-binary flag, as done by the qemu-user-static package, so that the
+interpreter is available in isolated root filesystems of the target
+architecture.
+"""
+
+import logging
+import subprocess
+from pathlib import Path
+from typing import Dict, Optional
+
+from craft_parts import errors, hosts
+
+logger = logging.getLogger(__name__)
+
+BINFMT_DIR = Path("/proc/sys/fs/binfmt_misc")
+
+# Translation from the deb architectures used by craft-parts to the
+# architecture names used by qemu-user.
+_QEMU_ARCHITECTURES = {
+    "amd64": "x86_64",
+    "arm64": "aarch64",
+    "armhf": "arm",
+    "i386": "i386",
+    "powerpc": "ppc",
+    "ppc64el": "ppc64le",
+    "riscv64": "riscv64",
+    "s390x": "s390x",
+}
+
+
+def get_binfmt_handler_name(arch: str) -> str:
+    """Obtain the name of the binfmt handler executing binaries of an architecture.
+
+    :param arch: The deb architecture to emulate.
+
+    :return: The binfmt handler name, such as ``qemu-aarch64``.
+
+    :raise errors.EmulationError: If the architecture can't be emulated.
+    """
+    qemu_arch = _QEMU_ARCHITECTURES.get(arch)
+    if not qemu_arch:
+        raise errors.EmulationError(arch, message="architecture is not supported")
+
+    return f"qemu-{qemu_arch}"
+
+
+def setup_emulation(arch: str) -> None:
+    """Make sure binaries of the given architecture can be executed.
+
+    The binfmt handler of the architecture is enabled using update-binfmts
+    if it's registered but not enabled.
+
+    :param arch: The deb architecture to emulate.
+
+    :raise errors.EmulationError: If binaries of the architecture can't be
+        executed in this host.
+    """
+    name = get_binfmt_handler_name(arch)
+    handler = _read_binfmt_handler(name)
+    if handler is None:
+        raise errors.EmulationError(
+            arch, message=f"binfmt handler {name!r} is not registered"
+        )
+
+    if not handler.get("enabled"):
+        _enable_binfmt_handler(name, arch=arch)
+
+    if "F" not in handler.get("flags", ""):
+        raise errors.EmulationError(
+            arch,
+            message=f"binfmt handler {name!r} is not registered with the F flag",
+        )
+
+    logger.debug("emulate %s using %s", arch, handler.get("interpreter"))
+
+
+def _read_binfmt_handler(name: str) -> Optional[Dict[str, str]]:
+    """Read the status and properties of a binfmt handler.
+
+    :return: The handler properties, or None if the handler doesn't exist.
+        The ``enabled`` property is set only if the handler is enabled.
+    """
+    try:
+        lines = (BINFMT_DIR / name).read_text().splitlines()
+    except OSError:
+        return None
+
+    handler: Dict[str, str] = {}
+    for line in lines:
+        if line == "enabled":
+            handler["enabled"] = "1"
+            continue
+
+        key, _, value = line.partition(" ")
+        handler[key.rstrip(":")] = value.strip()
+
+    return handler
+
+
+def _enable_binfmt_handler(name: str, *, arch: str) -> None:
+    """Enable a registered binfmt handler."""
+    cmd = ["update-binfmts", "--enable", name]
+    if not hosts.get_host_platform().is_root():
+        cmd = ["sudo", "--preserve-env", *cmd]
+
+    logger.debug("Executing: %s", cmd)
+    try:
+        subprocess.run(cmd, check=True)
+    except (OSError, subprocess.CalledProcessError) as err:
+        raise errors.EmulationError(
+            arch, message=f"cannot enable binfmt handler {name!r}"
+        ) from err
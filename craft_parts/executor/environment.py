@@ -18,14 +18,19 @@
 
 import io
 import logging
+import re
+from pathlib import Path
 from typing import Dict, Iterable
 
+from craft_parts import secrets
 from craft_parts.infos import StepInfo
 from craft_parts.parts import Part
 from craft_parts.plugins import Plugin
 from craft_parts.steps import Step
 from craft_parts.utils import os_utils
 
+from .reproducible import get_source_date_epoch
+
 logger = logging.getLogger(__name__)
 
 
@@ -46,7 +51,9 @@ def generate_part_environment(
     step = step_info.step
 
     # Plugin's say.
-    if step == Step.BUILD:
+    if step == Step.PULL:
+        plugin_environment = plugin.get_pull_environment()
+    elif step == Step.BUILD:
         plugin_environment = plugin.get_build_environment()
     else:
         plugin_environment = dict()
@@ -67,13 +74,17 @@ def generate_part_environment(
         for key, val in our_build_environment.items():
             print(f'export {key}="{val}"', file=run_environment)
 
+        # Secret values are not written to the script, only the variables
+        # set in the step process environment.
         print("## Plugin Environment", file=run_environment)
         for key, val in plugin_environment.items():
+            val = secrets.replace_references(val)
             print(f'export {key}="{val}"', file=run_environment)
 
         print("## User Environment", file=run_environment)
         for env in user_build_environment:
             for key, val in env.items():
+                val = secrets.replace_references(val)
                 print(f'export {key}="{val}"', file=run_environment)
 
         # Return something suitable for Runner.
@@ -83,14 +94,30 @@ def generate_part_environment(
 def _basic_environment_for_part(part: Part, *, step_info: StepInfo) -> Dict[str, str]:
     """Return the built-in part environment.
 
+    The environment includes variables referencing the source, build, and
+    install directories of the parts this part depends on.
+
     :param part: The part to get environment information from.
     :param step_info: Information for this step.
 
     :return: A dictionary containing the built-in environment.
     """
-    part_environment: Dict[str, str] = {}
+    part_environment: Dict[str, str] = step_info.project_environment
     paths = [part.part_install_dir, part.stage_dir]
 
+    # Parts can reference the directories of the parts they depend on.
+    for name in part.dependencies:
+        part_environment.update(
+            get_part_directory_environment(name, parts_dir=part.parts_dir)
+        )
+
+    if step_info.proxy:
+        part_environment.update(step_info.proxy.environment)
+
+    source_date_epoch = get_source_date_epoch(part, step_info=step_info)
+    if source_date_epoch is not None:
+        part_environment["SOURCE_DATE_EPOCH"] = str(source_date_epoch)
+
     bin_paths = list()
     for path in paths:
         bin_paths.extend(os_utils.get_bin_paths(root=path, existing_only=True))
@@ -140,6 +167,30 @@ def _basic_environment_for_part(part: Part, *, step_info: StepInfo) -> Dict[str,
     return part_environment
 
 
+def get_part_directory_environment(
+    part_name: str, *, parts_dir: Path
+) -> Dict[str, str]:
+    """Obtain the variables referencing the work directories of a part.
+
+    Variable names are suffixed with the part name in uppercase, with
+    characters other than letters and digits replaced by underscores.
+
+    :param part_name: The name of the part to reference.
+    :param parts_dir: The directory containing the work files of each part.
+
+    :return: A dictionary mapping variable names to the part's
+        source, build, and install directories.
+    """
+    suffix = re.sub(r"[^A-Za-z0-9]", "_", part_name).upper()
+    part_dir = parts_dir / part_name
+
+    return {
+        f"CRAFT_PART_SRC_{suffix}": str(part_dir / "src"),
+        f"CRAFT_PART_BUILD_{suffix}": str(part_dir / "build"),
+        f"CRAFT_PART_INSTALL_{suffix}": str(part_dir / "install"),
+    }
+
+
 def _combine_paths(paths: Iterable[str], prepend: str, separator: str) -> str:
     """Combine list of paths into a string.
 
@@ -0,0 +1,457 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Definitions and helpers to execute lifecycle actions."""
+
+import contextlib
+import json
+import logging
+import sys
+import tempfile
+import threading
+from concurrent import futures
+from pathlib import Path
+from typing import IO, Dict, Iterator, List, Optional, Set, Tuple, Union
+
+from craft_parts import callbacks, errors, events, parts, progress, proxy, secrets
+from craft_parts.actions import Action, ActionType
+from craft_parts.infos import PartInfo, ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.steps import Step
+
+from . import emulation
+from .metrics import ActionMetrics, ActionMonitor, get_metrics_report
+from .output import ActionLog, OutputForwarder, log_file_path
+from .part_handler import PartHandler
+
+logger = logging.getLogger(__name__)
+
+
+class Executor:
+    """Execute lifecycle actions.
+
+    The executor takes the part definition and a list of actions to run for
+    a part and step. Action execution is stateless: no information is kept from
+    the execution of previous parts. On-disk state information written after
+    running each action is read by the sequencer before planning a new set of
+    actions.
+
+    Actions of parts that don't depend on each other can be executed
+    concurrently. The output of each action executed concurrently is captured
+    and written when the action finishes, so it's not interleaved with the
+    output of other parts.
+
+    :param part_list: The list of parts to process.
+    :param project_info: Information about this project.
+    :param max_parallel_parts: The maximum number of parts whose actions can
+        be executed concurrently.
+    :param prefix_output: Whether each line of output of step commands is
+        prefixed with the part name and step, as in ``[part:step]``.
+    """
+
+    def __init__(
+        self,
+        *,
+        part_list: List[Part],
+        project_info: ProjectInfo,
+        max_parallel_parts: int = 1,
+        prefix_output: bool = False,
+    ):
+        self._part_list = part_list
+        self._project_info = project_info
+        self._max_parallel_parts = max_parallel_parts
+        self._prefix_output = prefix_output
+        self._handler: Dict[str, PartHandler] = {}
+        self._output_lock = threading.Lock()
+        self._metrics: List[ActionMetrics] = []
+        self._metrics_lock = threading.Lock()
+
+    def prologue(self) -> None:
+        """Prepare the execution environment.
+
+        This method is called before executing lifecycle actions.
+
+        :raise errors.EmulationError: If the build runs under emulation and
+            binaries of the target architecture can't be executed.
+        """
+        if self._project_info.is_emulated:
+            emulation.setup_emulation(self._project_info.target_arch)
+
+        with proxy.proxy_environment(self._project_info.proxy):
+            callbacks.run_prologue(self._project_info, part_list=self._part_list)
+
+    def epilogue(self) -> None:
+        """Finish and clean the execution environment.
+
+        This method is called after executing lifecycle actions.
+        """
+        with proxy.proxy_environment(self._project_info.proxy):
+            callbacks.run_epilogue(self._project_info, part_list=self._part_list)
+
+    @property
+    def metrics(self) -> List[ActionMetrics]:
+        """Return the metrics of all actions executed, in order of completion.
+
+        Metrics of actions that failed are also included.
+        """
+        with self._metrics_lock:
+            return self._metrics.copy()
+
+    def execute(self, actions: Union[Action, List[Action]]) -> List[ActionMetrics]:
+        """Execute the specified action or list of actions.
+
+        :param actions: An :class:`Action` object or list of :class:`Action`
+           objects specifying steps to execute.
+
+        :return: The resource usage metrics of each executed action, in order
+            of completion. Skipped actions are not included.
+
+        :raises InvalidPartName: If the action refers to an invalid part.
+        """
+        if isinstance(actions, Action):
+            actions = [actions]
+
+        start = len(self.metrics)
+
+        with proxy.proxy_environment(self._project_info.proxy):
+            if self._max_parallel_parts > 1 and len(actions) > 1:
+                self._run_actions_parallel(actions)
+            else:
+                for index, act in enumerate(actions):
+                    self._run_action(act, index=index, total=len(actions))
+
+        return self.metrics[start:]
+
+    def _run_action(
+        self,
+        action: Action,
+        *,
+        index: int = 0,
+        total: int = 1,
+        stdout: Optional[IO] = None,
+        stderr: Optional[IO] = None,
+    ) -> None:
+        """Execute the given action for a part using the provided step information.
+
+        :param action: The lifecycle action to run.
+        :param index: The position of the action in the list being executed.
+        :param total: The number of actions in the list being executed.
+        :param stdout: The file to write the output of step commands to.
+        :param stderr: The file to write the error output of step commands to.
+        """
+        part = _find_part(action.part_name, self._part_list)
+        logger.debug("execute action %s:%s", part.name, action)
+
+        handler = self._create_part_handler(part)
+        callbacks.run_event_handlers(
+            events.ActionStarted(action, index=index, total=total)
+        )
+
+        if action.action_type == ActionType.SKIP:
+            handler.run_action(action, stdout=stdout, stderr=stderr)
+            callbacks.run_event_handlers(
+                events.ActionFinished(action, status=events.ActionStatus.SKIPPED)
+            )
+            return
+
+        status = events.ActionStatus.FAILED
+        error: Optional[str] = None
+        monitor = ActionMonitor(action, path=_get_output_dir(part, action.step))
+        monitor.start()
+        try:
+            part_secrets = {
+                **handler.plugin_secrets,
+                **secrets.resolve_secrets(part),
+            }
+            with ActionLog(log_file_path(part, action.step)) as log, _forward_output(
+                action,
+                stdout=stdout,
+                stderr=stderr,
+                secret_values=list(part_secrets.values()),
+                log=log,
+                prefix_output=self._prefix_output,
+            ) as output, _forward_progress(action):
+                handler.run_action(
+                    action, stdout=output[0], stderr=output[1], secrets=part_secrets
+                )
+            status = events.ActionStatus.SUCCEEDED
+        except Exception as err:
+            error = str(err)
+            raise
+        finally:
+            metrics = monitor.stop()
+            with self._metrics_lock:
+                self._metrics.append(metrics)
+            callbacks.run_event_handlers(
+                events.ActionFinished(
+                    action, status=status, error=error, metrics=metrics
+                )
+            )
+
+        logger.debug(
+            "%s:%s finished in %.3f seconds",
+            part.name,
+            action.step.name.lower(),
+            metrics.duration,
+        )
+
+    def _run_actions_parallel(self, actions: List[Action]) -> None:
+        """Execute actions concurrently, respecting their ordering constraints.
+
+        If an action fails, no new actions are started and the error is raised
+        after the actions already running finish.
+
+        :param actions: The list of actions to execute, in sequential order.
+        """
+        # Validate part names and create handlers before starting threads.
+        for act in actions:
+            self._create_part_handler(_find_part(act.part_name, self._part_list))
+
+        prerequisites = _get_prerequisites(actions, part_list=self._part_list)
+        pending = list(range(len(actions)))
+        done: Set[int] = set()
+        running: Dict[futures.Future, int] = {}
+        error: Optional[BaseException] = None
+
+        with futures.ThreadPoolExecutor(max_workers=self._max_parallel_parts) as pool:
+            while running or (pending and error is None):
+                if error is None:
+                    for index in [i for i in pending if prerequisites[i] <= done]:
+                        pending.remove(index)
+                        future = pool.submit(
+                            self._run_captured_action,
+                            actions[index],
+                            index=index,
+                            total=len(actions),
+                        )
+                        running[future] = index
+
+                finished, _ = futures.wait(running, return_when=futures.FIRST_COMPLETED)
+                for future in finished:
+                    index = running.pop(future)
+                    if future.exception() is None:
+                        done.add(index)
+                    elif error is None:
+                        error = future.exception()
+
+        if error is not None:
+            raise error
+
+    def _run_captured_action(self, action: Action, *, index: int, total: int) -> None:
+        """Execute an action, writing its output when it finishes."""
+        with tempfile.TemporaryFile(mode="w+") as output:
+            try:
+                self._run_action(
+                    action, index=index, total=total, stdout=output, stderr=output
+                )
+            finally:
+                output.seek(0)
+                with self._output_lock:
+                    sys.stdout.write(output.read())
+                    sys.stdout.flush()
+
+    def _create_part_handler(self, part: Part) -> PartHandler:
+        """Instantiate a part handler for a new part."""
+        if part.name in self._handler:
+            return self._handler[part.name]
+
+        handler = PartHandler(
+            part,
+            part_info=PartInfo(self._project_info, part),
+            part_list=self._part_list,
+        )
+        self._handler[part.name] = handler
+
+        return handler
+
+
+class ExecutionContext:
+    """A context manager to handle lifecycle action executors.
+
+    :param executor: The lifecycle action executor.
+    :param metrics_report: The file to write the metrics of actions executed
+        in this context to, in JSON format, when the context exits.
+    """
+
+    def __init__(
+        self,
+        *,
+        executor: Executor,
+        metrics_report: Optional[Path] = None,
+    ):
+        self._executor = executor
+        self._metrics_report = metrics_report
+        self._metrics_start = 0
+
+    def __enter__(self) -> "ExecutionContext":
+        self._metrics_start = len(self._executor.metrics)
+        self._executor.prologue()
+        return self
+
+    def __exit__(self, *exc):
+        try:
+            self._executor.epilogue()
+        finally:
+            if self._metrics_report:
+                self._write_metrics_report(self._metrics_report)
+
+    @property
+    def metrics(self) -> List[ActionMetrics]:
+        """Return the metrics of all actions executed in this context."""
+        return self._executor.metrics[self._metrics_start :]
+
+    def execute(self, actions: Union[Action, List[Action]]) -> List[ActionMetrics]:
+        """Execute the specified action or list of actions.
+
+        :param actions: An :class:`Action` object or list of :class:`Action`
+           objects specifying steps to execute.
+
+        :return: The resource usage metrics of each executed action.
+
+        :raises InvalidPartName: If the action refers to an invalid part.
+        """
+        return self._executor.execute(actions)
+
+    def _write_metrics_report(self, path: Path) -> None:
+        report = get_metrics_report(self.metrics)
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(json.dumps(report, indent=2) + "\n")
+
+
+def _get_prerequisites(
+    actions: List[Action], *, part_list: List[Part]
+) -> List[Set[int]]:
+    """Obtain the earlier actions each action must wait for.
+
+    Actions wait for earlier actions of the same part and of parts related
+    by dependencies. Stage and prime actions also wait for each other, since
+    all parts migrate files to the same directories, and overlay actions wait
+    for earlier overlay actions, since layers are stacked in order.
+
+    :param actions: The list of actions, in sequential order.
+    :param part_list: The list of parts.
+
+    :return: A list containing the set of indices of prerequisite actions
+        for each action.
+    """
+    dependencies = {
+        part.name: {
+            p.name
+            for p in parts.part_dependencies(
+                part.name, part_list=part_list, recursive=True
+            )
+        }
+        for part in part_list
+    }
+    shared_steps = (Step.STAGE, Step.PRIME)
+
+    prerequisites: List[Set[int]] = []
+    for index, action in enumerate(actions):
+        prerequisites.append(
+            {
+                other_index
+                for other_index, other in enumerate(actions[:index])
+                if other.part_name == action.part_name
+                or other.part_name in dependencies[action.part_name]
+                or action.part_name in dependencies[other.part_name]
+                or (other.step in shared_steps and action.step in shared_steps)
+                or (other.step == action.step == Step.OVERLAY)
+            }
+        )
+
+    return prerequisites
+
+
+@contextlib.contextmanager
+def _forward_output(
+    action: Action,
+    *,
+    stdout: Optional[IO],
+    stderr: Optional[IO],
+    secret_values: Optional[List[str]] = None,
+    log: Optional[ActionLog] = None,
+    prefix_output: bool = False,
+) -> Iterator[Tuple[Optional[IO], Optional[IO]]]:
+    """Provide the files to write the output of step commands to.
+
+    The output is added to the action log and forwarded to the execution
+    event handlers as it's written to the standard output and error files.
+    If the part uses secrets, their values are redacted from the output.
+    """
+    if not (callbacks.has_event_handlers() or secret_values or log or prefix_output):
+        yield stdout, stderr
+        return
+
+    lock = threading.Lock()
+    prefix: Optional[str] = None
+    if prefix_output:
+        prefix = f"[{action.part_name}:{action.step.name.lower()}] "
+
+    with OutputForwarder(
+        action,
+        stream=events.OutputStream.STDOUT,
+        destination=stdout or sys.stdout,
+        lock=lock,
+        secret_values=secret_values,
+        log=log,
+        prefix=prefix,
+    ) as out, OutputForwarder(
+        action,
+        stream=events.OutputStream.STDERR,
+        destination=stderr or sys.stderr,
+        lock=lock,
+        secret_values=secret_values,
+        log=log,
+        prefix=prefix,
+    ) as err:
+        yield out, err
+
+
+@contextlib.contextmanager
+def _forward_progress(action: Action) -> Iterator[None]:
+    """Send the progress of downloads performed by an action to event handlers."""
+    if not callbacks.has_event_handlers():
+        yield
+        return
+
+    def _handler(
+        kind: progress.DownloadKind, item: str, downloaded: int, total: Optional[int]
+    ) -> None:
+        callbacks.run_event_handlers(
+            events.DownloadProgress(
+                action, kind=kind, item=item, downloaded=downloaded, total=total
+            )
+        )
+
+    with progress.progress_handler(_handler):
+        yield
+
+
+def _get_output_dir(part: Part, step: Step) -> Path:
+    """Obtain the directory written to by a step of the given part."""
+    if step == Step.STAGE:
+        return part.stage_dir
+    if step == Step.PRIME:
+        return part.prime_dir
+    return part.parts_dir / part.name
+
+
+def _find_part(name: str, part_list: List[Part]) -> Part:
+    for part in part_list:
+        if part.name == name:
+            return part
+
+    raise errors.InvalidPartName(name)
@@ -16,15 +16,23 @@
 
 """Definitions and helpers to handle filesets."""
 
+import fnmatch
 import os
-from glob import iglob
+from glob import has_magic, iglob
 from typing import List, Set, Tuple
 
 from craft_parts import errors
 
 
 class Fileset:
-    """Helper class to process string lists."""
+    """Helper class to process string lists.
+
+    Entries are paths or glob patterns to include, or to exclude if prefixed
+    with ``-``. A ``**`` path component matches any number of nested
+    directories. Excludes take precedence over includes regardless of their
+    order: a file matching both is excluded, and excluding a directory also
+    excludes all of its contents.
+    """
 
     def __init__(self, entries: List[str], *, name: str = ""):
         self._name = name
@@ -137,6 +145,72 @@ def migratable_filesets(fileset: Fileset, srcdir: str) -> Tuple[Set[str], Set[st
     return resolved_files, resolved_dirs
 
 
+def glob_paths(pattern: str) -> List[str]:
+    """Obtain the paths matching a glob pattern.
+
+    A ``**`` path component matches any number of nested directories,
+    including none. Unlike :func:`glob.glob`, symbolic links to directories
+    are not traversed, so they are matched as files. As with other wildcards,
+    ``**`` doesn't match hidden files and directories. If the pattern ends
+    with a slash, only directories are matched.
+
+    :param pattern: The glob pattern to expand.
+
+    :return: The list of matching paths.
+    """
+    if "**" not in pattern:
+        return list(iglob(pattern, recursive=True))
+
+    dir_only = pattern.endswith("/")
+    components = pattern.rstrip("/").split("/")
+    prefix: List[str] = []
+    while not has_magic(components[0]):
+        prefix.append(components.pop(0))
+
+    root = "/".join(prefix) or "."
+    candidates: List[str] = []
+    if os.path.isdir(root) and _match_components([], components):
+        candidates.append(root)
+
+    for dirpath, dirnames, filenames in os.walk(root):
+        for name in dirnames + filenames:
+            path = os.path.join(dirpath, name)
+            parts = os.path.relpath(path, root).split(os.sep)
+            if _match_components(parts, components):
+                candidates.append(path)
+
+    if dir_only:
+        return [
+            x + "/" for x in candidates if os.path.isdir(x) and not os.path.islink(x)
+        ]
+
+    return candidates
+
+
+def _match_components(parts: List[str], pattern: List[str]) -> bool:
+    """Verify whether path components match glob pattern components."""
+    if not pattern:
+        return not parts
+
+    if pattern[0] == "**":
+        if _match_components(parts, pattern[1:]):
+            return True
+        if not parts or parts[0].startswith("."):
+            return False
+        return _match_components(parts[1:], pattern)
+
+    if not parts:
+        return False
+
+    if parts[0].startswith(".") and not pattern[0].startswith("."):
+        return False
+
+    if not fnmatch.fnmatchcase(parts[0], pattern[0]):
+        return False
+
+    return _match_components(parts[1:], pattern[1:])
+
+
 def _get_file_list(fileset: Fileset) -> Tuple[List[str], List[str]]:
     """Split a fileset to obtain include and exclude file filters.
 
@@ -179,8 +253,7 @@ def _generate_include_set(directory: str, includes: List[str]) -> Set[str]:
     for include in includes:
         if "*" in include:
             pattern = os.path.join(directory, include)
-            matches = iglob(pattern, recursive=True)
-            include_files |= set(matches)
+            include_files |= set(glob_paths(pattern))
         else:
             include_files |= set([os.path.join(directory, include)])
 
@@ -216,8 +289,7 @@ def _generate_exclude_set(
 
     for exclude in excludes:
         pattern = os.path.join(directory, exclude)
-        matches = iglob(pattern, recursive=True)
-        exclude_files |= set(matches)
+        exclude_files |= set(glob_paths(pattern))
 
     exclude_dirs = {
         os.path.relpath(x, directory) for x in exclude_files if os.path.isdir(x)
@@ -0,0 +1,257 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Run build commands isolated from the host in a base root filesystem.
+
+Parts can request their build step to run in the base layer root
+filesystem using chroot or bubblewrap, so that toolchains and libraries
+installed in the host can't be used by the build. The project work
+directory is bind-mounted at the same location in the isolated root, so
+paths used by plugins and scripts remain valid. The base layer itself is
+never modified: bubblewrap mounts it read-only, and chroot builds run in a
+temporary overlay on top of it.
+"""
+
+import contextlib
+import enum
+import logging
+import os
+import shutil
+import subprocess
+import tempfile
+from pathlib import Path
+from typing import Any, Iterator, List, Optional, Sequence, Set
+
+from craft_parts import errors, hosts
+
+logger = logging.getLogger(__name__)
+
+
+class IsolationMethod(enum.Enum):
+    """The mechanism used to isolate the build of a part."""
+
+    CHROOT = "chroot"
+    BUBBLEWRAP = "bubblewrap"
+
+
+@contextlib.contextmanager
+def isolated_command(
+    command: List[Any],
+    *,
+    part_name: str,
+    method: IsolationMethod,
+    root: Optional[Path],
+    cwd: Path,
+    bind_dirs: Sequence[Path],
+) -> Iterator[List[Any]]:
+    """Obtain a command that executes in an isolated root filesystem.
+
+    Chroot isolation requires root privileges, and the overlay and bind
+    mounts it needs are only kept while the context is active. Bubblewrap
+    mounts are created by bubblewrap itself when the command runs.
+
+    :param command: The command to execute.
+    :param part_name: The name of the part being built.
+    :param method: The isolation mechanism to use.
+    :param root: The root filesystem to execute the command in.
+    :param cwd: The directory to execute the command in.
+    :param bind_dirs: The host directories to make available in the root.
+
+    :return: The command to execute in the isolated root.
+
+    :raise errors.BuildIsolationError: If the command can't be isolated.
+    """
+    host = hosts.get_host_platform()
+    if not host.supports_isolation:
+        raise errors.BuildIsolationError(
+            part_name=part_name,
+            message=f"build isolation is not supported on {host.name} hosts",
+        )
+
+    if root is None:
+        raise errors.BuildIsolationError(
+            part_name=part_name, message="no base layer directory is set"
+        )
+
+    if not root.is_dir():
+        raise errors.BuildIsolationError(
+            part_name=part_name, message=f"base layer {str(root)!r} is not a directory"
+        )
+
+    if method == IsolationMethod.BUBBLEWRAP:
+        if not shutil.which("bwrap"):
+            raise errors.BuildIsolationError(
+                part_name=part_name, message="bubblewrap is not installed"
+            )
+
+        yield _get_bubblewrap_command(command, root=root, cwd=cwd, bind_dirs=bind_dirs)
+        return
+
+    if not host.is_root():
+        raise errors.BuildIsolationError(
+            part_name=part_name, message="chroot isolation requires root privileges"
+        )
+
+    with _bind_mounts(root, bind_dirs, part_name=part_name) as chroot_dir:
+        yield _get_chroot_command(command, root=chroot_dir, cwd=cwd)
+
+
+def _get_bubblewrap_command(
+    command: List[Any], *, root: Path, cwd: Path, bind_dirs: Sequence[Path]
+) -> List[Any]:
+    """Obtain the command to execute in a bubblewrap sandbox."""
+    cmd: List[Any] = ["bwrap"]
+    mountpoints = {Path("/dev"), Path("/proc"), *(d.absolute() for d in bind_dirs)}
+    cmd.extend(_get_read_only_binds(root, Path("/"), mountpoints))
+    cmd.extend(["--dev", "/dev", "--proc", "/proc"])
+    for directory in bind_dirs:
+        cmd.extend(["--bind", str(directory), str(directory)])
+    cmd.extend(["--chdir", str(cwd)])
+    return [*cmd, *command]
+
+
+def _get_read_only_binds(root: Path, path: Path, mountpoints: Set[Path]) -> List[str]:
+    """Obtain the bubblewrap arguments to mount a base layer directory read-only.
+
+    Mountpoints can't be created in read-only mounts, so directories that
+    contain mountpoints are created in the sandbox and their other entries
+    are mounted individually.
+
+    :param root: The base layer root directory.
+    :param path: The directory to mount, relative to the base layer root.
+    :param mountpoints: The sandbox paths where other directories are mounted.
+
+    :return: The bubblewrap arguments.
+    """
+    if not any(path in mountpoint.parents for mountpoint in mountpoints):
+        return ["--ro-bind", str(root / path.relative_to("/")), str(path)]
+
+    args = [] if path == Path("/") else ["--dir", str(path)]
+    for entry in sorted(os.scandir(root / path.relative_to("/")), key=lambda e: e.name):
+        entry_path = path / entry.name
+        if entry_path in mountpoints:
+            continue
+        if entry.is_symlink():
+            args.extend(["--symlink", os.readlink(entry.path), str(entry_path)])
+        elif entry.is_dir():
+            args.extend(_get_read_only_binds(root, entry_path, mountpoints))
+        else:
+            args.extend(["--ro-bind", entry.path, str(entry_path)])
+    return args
+
+
+def _get_chroot_command(command: List[Any], *, root: Path, cwd: Path) -> List[Any]:
+    """Obtain the command to execute in a chroot.
+
+    Chroot changes to the root directory of the new root, so the command
+    is executed by a shell that changes to the original working directory.
+    """
+    return [
+        "chroot",
+        str(root),
+        "/bin/sh",
+        "-c",
+        'cd "$1" && shift && exec "$@"',
+        "sh",
+        str(cwd),
+        *command,
+    ]
+
+
+@contextlib.contextmanager
+def _bind_mounts(
+    root: Path, bind_dirs: Sequence[Path], *, part_name: str
+) -> Iterator[Path]:
+    """Mount the base layer overlay and the system and host directories.
+
+    Mountpoints are created in the overlay upper layer, so that the base
+    layer is not modified.
+
+    :return: The root directory of the chroot.
+    """
+    tmpdir = Path(tempfile.mkdtemp(prefix="craft-parts-isolation-"))
+    chroot_dir = tmpdir / "root"
+    upper_dir = tmpdir / "upper"
+    work_dir = tmpdir / "work"
+    for directory in (chroot_dir, upper_dir, work_dir):
+        directory.mkdir()
+
+    mounts: List[Path] = []
+    try:
+        _mount(
+            [
+                "-t",
+                "overlay",
+                "overlay",
+                f"-olowerdir={root},upperdir={upper_dir},workdir={work_dir}",
+                str(chroot_dir),
+            ],
+            part_name=part_name,
+        )
+        mounts.append(chroot_dir)
+
+        for source, fstype in [(Path("/dev"), None), (Path("/proc"), "proc")]:
+            target = _get_mountpoint(chroot_dir, source)
+            if fstype:
+                _mount(["-t", fstype, fstype, str(target)], part_name=part_name)
+            else:
+                _mount(["--bind", str(source), str(target)], part_name=part_name)
+            mounts.append(target)
+
+        for directory in bind_dirs:
+            target = _get_mountpoint(chroot_dir, directory)
+            _mount(["--bind", str(directory), str(target)], part_name=part_name)
+            mounts.append(target)
+
+        yield chroot_dir
+    finally:
+        failed = [target for target in reversed(mounts) if not _umount(target)]
+
+        # Keep the temporary directory if unmounting fails, removing it could
+        # delete the contents of directories that are still mounted.
+        if failed:
+            raise errors.BuildIsolationError(
+                part_name=part_name,
+                message=f"cannot unmount {', '.join(repr(str(p)) for p in failed)}",
+            )
+        shutil.rmtree(tmpdir)
+
+
+def _get_mountpoint(root: Path, directory: Path) -> Path:
+    target = root / directory.absolute().relative_to("/")
+    target.mkdir(parents=True, exist_ok=True)
+    return target
+
+
+def _umount(target: Path) -> bool:
+    """Unmount a directory, returning whether it was unmounted."""
+    logger.debug("unmount %s", target)
+    try:
+        subprocess.run(["umount", str(target)], check=True)
+    except (OSError, subprocess.CalledProcessError) as err:
+        logger.warning("Cannot unmount %s: %s", target, err)
+        return False
+    return True
+
+
+def _mount(args: List[str], *, part_name: str) -> None:
+    logger.debug("mount %s", " ".join(args))
+    try:
+        subprocess.run(["mount", *args], check=True)
+    except (OSError, subprocess.CalledProcessError) as err:
+        raise errors.BuildIsolationError(
+            part_name=part_name, message=f"cannot mount {args[-1]!r}: {err}"
+        ) from err
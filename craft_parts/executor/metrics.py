@@ -0,0 +1,165 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Resource usage metrics of executed actions."""
+
+import os
+import resource
+import threading
+import time
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from craft_parts.actions import Action
+
+
+@dataclass(frozen=True)
+class ActionMetrics:
+    """Resource usage measured while executing an action.
+
+    :param action: The executed action.
+    :param duration: The wall-clock duration of the action, in seconds.
+    :param cpu_time: The CPU time used by the action and the commands it
+        ran, in seconds.
+    :param peak_disk_usage: The peak size of the directory the action
+        writes to, in bytes.
+    """
+
+    action: Action
+    duration: float
+    cpu_time: float
+    peak_disk_usage: int
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the action metrics.
+
+        :return: The newly created dictionary.
+        """
+        return {
+            **self.action.marshal(),
+            "duration": self.duration,
+            "cpu-time": self.cpu_time,
+            "peak-disk-usage": self.peak_disk_usage,
+        }
+
+
+def get_metrics_report(metrics: List[ActionMetrics]) -> Dict[str, Any]:
+    """Create a report summarizing the metrics of executed actions.
+
+    :param metrics: The metrics of each executed action.
+
+    :return: A dictionary containing the action metrics and the totals
+        of each part.
+    """
+    part_totals: Dict[str, Dict[str, Any]] = {}
+    for item in metrics:
+        totals = part_totals.setdefault(
+            item.action.part_name,
+            {"duration": 0.0, "cpu-time": 0.0, "peak-disk-usage": 0},
+        )
+        totals["duration"] += item.duration
+        totals["cpu-time"] += item.cpu_time
+        totals["peak-disk-usage"] = max(
+            totals["peak-disk-usage"], item.peak_disk_usage
+        )
+
+    return {
+        "actions": [item.marshal() for item in metrics],
+        "parts": part_totals,
+    }
+
+
+def get_disk_usage(path: Path) -> int:
+    """Obtain the disk space used by the files in a directory tree.
+
+    :param path: The directory to measure.
+
+    :return: The disk usage in bytes, or zero if the directory doesn't exist.
+    """
+    usage = 0
+    for root, _, files in os.walk(path):
+        for name in files:
+            try:
+                usage += os.lstat(os.path.join(root, name)).st_blocks * 512
+            except FileNotFoundError:
+                # The step may remove files while they're being measured.
+                continue
+    return usage
+
+
+class ActionMonitor:
+    """Measure the resources used while executing an action.
+
+    Disk usage is sampled in a background thread while the action runs.
+    The CPU time of commands executed by the action is obtained from the
+    resource usage of terminated child processes, so it also includes
+    commands run by other actions executing concurrently.
+
+    :param action: The action being executed.
+    :param path: The directory the action writes to.
+    :param interval: The disk usage sampling interval, in seconds.
+    """
+
+    def __init__(self, action: Action, *, path: Path, interval: float = 1.0):
+        self._action = action
+        self._path = path
+        self._interval = interval
+        self._peak_disk_usage = 0
+        self._stop_event = threading.Event()
+        self._thread: Optional[threading.Thread] = None
+        self._start_time = 0.0
+        self._start_cpu_time = 0.0
+
+    def start(self) -> None:
+        """Start measuring resource usage."""
+        self._start_time = time.monotonic()
+        self._start_cpu_time = _get_cpu_time()
+        self._peak_disk_usage = get_disk_usage(self._path)
+        self._stop_event.clear()
+        self._thread = threading.Thread(target=self._sample_disk_usage, daemon=True)
+        self._thread.start()
+
+    def stop(self) -> ActionMetrics:
+        """Stop measuring resource usage.
+
+        :return: The resources used since the monitor was started.
+        """
+        self._stop_event.set()
+        if self._thread:
+            self._thread.join()
+            self._thread = None
+
+        self._update_disk_usage()
+        return ActionMetrics(
+            action=self._action,
+            duration=time.monotonic() - self._start_time,
+            cpu_time=_get_cpu_time() - self._start_cpu_time,
+            peak_disk_usage=self._peak_disk_usage,
+        )
+
+    def _sample_disk_usage(self) -> None:
+        while not self._stop_event.wait(self._interval):
+            self._update_disk_usage()
+
+    def _update_disk_usage(self) -> None:
+        self._peak_disk_usage = max(self._peak_disk_usage, get_disk_usage(self._path))
+
+
+def _get_cpu_time() -> float:
+    """Obtain the CPU time used by this thread and terminated child processes."""
+    children = resource.getrusage(resource.RUSAGE_CHILDREN)
+    return time.thread_time() + children.ru_utime + children.ru_stime
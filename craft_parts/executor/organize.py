@@ -20,21 +20,37 @@ Installed part files can be reorganized according to a mapping specified
 under the `organize` entry in a part definition. In the key/value pair,
 the key represents the path of a file inside the part and the value
 represents how the file is going to be staged.
+
+Keys starting with ``^`` are regular expressions matched against the path
+of each file inside the part. Capture groups can be referenced in the value
+using ``\\1`` or ``\\g<name>``.
+
+Values can also be mappings containing the destination ``path`` and options
+to create a symbolic link to the file instead of moving it, and to set the
+owner, group, and mode of the organized file.
 """
 
 import contextlib
+import grp
 import os
+import pwd
+import re
 import shutil
-from glob import iglob
 from pathlib import Path
-from typing import Dict
+from typing import Dict, Mapping, Union
 
 from craft_parts import errors
+from craft_parts.executor import filesets
+from craft_parts.parts import OrganizeSpec
 from craft_parts.utils import file_utils
 
 
 def organize_files(
-    *, part_name: str, mapping: Dict[str, str], base_dir: Path, overwrite: bool
+    *,
+    part_name: str,
+    mapping: Mapping[str, Union[str, OrganizeSpec]],
+    base_dir: Path,
+    overwrite: bool,
 ) -> None:
     """Rearrange files for part staging.
 
@@ -44,13 +60,26 @@ def organize_files(
         only used in build updates, when a part may organize over files
         it previously organized.
     """
-    for key in sorted(mapping, key=lambda x: ["*" in x, x]):
+    for key in sorted(mapping, key=lambda x: [is_regex(x), "*" in x, x]):
+        value = mapping[key]
+        spec = OrganizeSpec(path=value) if isinstance(value, str) else value
+
+        if is_regex(key):
+            _organize_regex(
+                part_name=part_name,
+                pattern=key,
+                spec=spec,
+                base_dir=base_dir,
+                overwrite=overwrite,
+            )
+            continue
+
         src = os.path.join(base_dir, key)
         # Remove the leading slash so the path actually joins
         # Also trailing slash is significant, be careful if using pathlib!
-        dst = os.path.join(base_dir, mapping[key].lstrip("/"))
+        dst = os.path.join(base_dir, spec.path.lstrip("/"))
 
-        sources = iglob(src, recursive=True)
+        sources = filesets.glob_paths(src)
 
         # Keep track of the number of glob expansions so we can properly error if more
         # than one tries to organize to the same file
@@ -58,11 +87,12 @@ def organize_files(
         for src in sources:
             src_count += 1
 
-            if os.path.isdir(src) and "*" not in key:
+            if os.path.isdir(src) and "*" not in key and not spec.symlink:
                 file_utils.link_or_copy_tree(src, dst)
                 # TODO create alternate organization location to avoid
                 # deletions.
                 shutil.rmtree(src)
+                _set_attributes(part_name=part_name, path=dst, spec=spec)
                 continue
 
             if os.path.isfile(dst):
@@ -90,13 +120,132 @@ def organize_files(
                         ),
                     )
 
+            if os.path.islink(dst) and overwrite:
+                os.remove(dst)
+
             if os.path.isdir(dst) and overwrite:
                 real_dst = os.path.join(dst, os.path.basename(src))
-                if os.path.isdir(real_dst):
+                if os.path.isdir(real_dst) and not os.path.islink(real_dst):
                     shutil.rmtree(real_dst)
                 else:
                     with contextlib.suppress(FileNotFoundError):
                         os.remove(real_dst)
 
-            os.makedirs(os.path.dirname(dst), exist_ok=True)
-            shutil.move(src, dst)
+            _organize_file(part_name=part_name, src=src, dst=dst, spec=spec)
+
+
+def is_regex(key: str) -> bool:
+    """Verify whether an organize key is a regular expression.
+
+    :param key: The organize mapping key.
+
+    :return: Whether the key is a regular expression.
+    """
+    return key.startswith("^")
+
+
+def _organize_regex(
+    *, part_name: str, pattern: str, spec: OrganizeSpec, base_dir: Path, overwrite: bool
+) -> None:
+    """Move files matching a regular expression, expanding capture groups."""
+    regex = re.compile(pattern)
+
+    moves: Dict[str, str] = {}
+    for root, directories, files in os.walk(base_dir):
+        links = [x for x in directories if os.path.islink(os.path.join(root, x))]
+        for name in files + links:
+            src = os.path.join(root, name)
+            match = regex.match(os.path.relpath(src, base_dir))
+            if not match:
+                continue
+
+            dst = os.path.join(base_dir, match.expand(spec.path).lstrip("/"))
+            if dst.endswith("/") or (os.path.isdir(dst) and not os.path.islink(dst)):
+                dst = os.path.join(dst, name)
+
+            if dst in moves.values():
+                raise errors.FileOrganizeError(
+                    part_name=part_name,
+                    message=(
+                        f"multiple files to be organized into "
+                        f"{os.path.relpath(dst, base_dir)!r}"
+                    ),
+                )
+            moves[src] = dst
+
+    for src, dst in sorted(moves.items()):
+        if src == dst:
+            continue
+
+        if os.path.lexists(dst):
+            if not overwrite:
+                raise errors.FileOrganizeError(
+                    part_name=part_name,
+                    message=(
+                        "trying to organize file {src!r} to {dst!r}, but {dst!r} "
+                        "already exists".format(
+                            src=os.path.relpath(src, base_dir),
+                            dst=os.path.relpath(dst, base_dir),
+                        )
+                    ),
+                )
+            if os.path.isdir(dst) and not os.path.islink(dst):
+                shutil.rmtree(dst)
+            else:
+                os.remove(dst)
+
+        _organize_file(part_name=part_name, src=src, dst=dst, spec=spec)
+
+
+def _organize_file(*, part_name: str, src: str, dst: str, spec: OrganizeSpec) -> None:
+    """Move a file to its destination, or create a symlink to it."""
+    os.makedirs(os.path.dirname(dst), exist_ok=True)
+    if spec.symlink:
+        if os.path.isdir(dst) and not os.path.islink(dst):
+            dst = os.path.join(dst, os.path.basename(src))
+        os.symlink(os.path.relpath(src, os.path.dirname(dst)), dst)
+    else:
+        # The new path is not returned when moving a directory to itself.
+        dst = shutil.move(src, dst) or dst
+
+    _set_attributes(part_name=part_name, path=dst, spec=spec)
+
+
+def _set_attributes(*, part_name: str, path: str, spec: OrganizeSpec) -> None:
+    """Set the ownership and mode of an organized file."""
+    if spec.mode:
+        os.chmod(path, int(spec.mode, 8))
+
+    if not spec.owner and not spec.group:
+        return
+
+    uid = _get_id(part_name=part_name, name=spec.owner, kind="user")
+    gid = _get_id(part_name=part_name, name=spec.group, kind="group")
+    try:
+        os.chown(path, uid, gid, follow_symlinks=False)
+    except PermissionError as err:
+        raise errors.FileOrganizeError(
+            part_name=part_name,
+            message=(
+                f"cannot change ownership of {os.path.basename(path)!r}: "
+                f"{err.strerror}"
+            ),
+        ) from err
+
+
+def _get_id(*, part_name: str, name: str, kind: str) -> int:
+    """Obtain a user or group identifier from its name or numeric value."""
+    if not name:
+        return -1
+
+    if name.isdigit():
+        return int(name)
+
+    try:
+        if kind == "user":
+            return pwd.getpwnam(name).pw_uid
+        return grp.getgrnam(name).gr_gid
+    except KeyError as err:
+        raise errors.FileOrganizeError(
+            part_name=part_name, message=f"{kind} {name!r} does not exist"
+        ) from err
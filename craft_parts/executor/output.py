@@ -0,0 +1,173 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Forward the output of step commands to event handlers and log files."""
+
+import codecs
+import json
+import os
+import threading
+import time
+from pathlib import Path
+from typing import IO, List, Optional
+
+from craft_parts import callbacks, secrets
+from craft_parts.actions import Action
+from craft_parts.events import ActionOutput, OutputStream
+from craft_parts.parts import Part
+from craft_parts.steps import Step
+
+
+def log_file_path(part: Part, step: Step) -> Path:
+    """Return the path to the output log file of a step.
+
+    :param part: The part the step belongs to.
+    :param step: The step whose output is logged.
+
+    :return: The log file path.
+    """
+    return part.part_log_dir / f"{step.name.lower()}.log"
+
+
+class ActionLog:
+    """A context manager providing a log of the output of an action.
+
+    The log is written in the JSON lines format, with one entry containing
+    the timestamp, the stream and the text of each chunk of output. Previous
+    logs of the step are replaced.
+
+    :param path: The log file path.
+    """
+
+    def __init__(self, path: Path):
+        self._path = path
+        self._file: Optional[IO] = None
+        self._lock = threading.Lock()
+
+    def __enter__(self) -> "ActionLog":
+        self._path.parent.mkdir(parents=True, exist_ok=True)
+        self._file = open(  # pylint: disable=consider-using-with
+            self._path, "w", encoding="utf-8"
+        )
+        return self
+
+    def __exit__(self, *exc):
+        if self._file:
+            self._file.close()
+            self._file = None
+
+    def write(self, stream: OutputStream, text: str) -> None:
+        """Add an output chunk to the log.
+
+        :param stream: The stream the output was written to.
+        :param text: The output text.
+        """
+        entry = {"timestamp": time.time(), "stream": stream.value, "data": text}
+        with self._lock:
+            if self._file:
+                self._file.write(json.dumps(entry) + "\n")
+                self._file.flush()
+
+
+class OutputForwarder:
+    """A context manager providing a file that forwards written output.
+
+    Output written to the file by step commands is copied to the destination
+    file and the action log, and sent to the registered event handlers as
+    :class:`ActionOutput` events, in chunks as it's received.
+
+    :param action: The action producing the output.
+    :param stream: The stream the output is written to.
+    :param destination: The file to copy the output to.
+    :param lock: A lock held while writing to the destination file, if
+        it's shared with other forwarders.
+    :param secret_values: Values to redact from the output. Output is
+        forwarded in complete lines if set, so values are not split between
+        chunks.
+    :param log: The log to add the output to.
+    :param prefix: A prefix added to each line copied to the destination
+        file. Output is forwarded in complete lines if set.
+    """
+
+    def __init__(
+        self,
+        action: Action,
+        *,
+        stream: OutputStream,
+        destination: IO,
+        lock: Optional[threading.Lock] = None,
+        secret_values: Optional[List[str]] = None,
+        log: Optional[ActionLog] = None,
+        prefix: Optional[str] = None,
+    ):
+        self._action = action
+        self._stream = stream
+        self._destination = destination
+        self._lock = lock or threading.Lock()
+        self._secret_values = secret_values or []
+        self._log = log
+        self._prefix = prefix
+        self._reader = -1
+        self._writer: Optional[IO] = None
+        self._thread: Optional[threading.Thread] = None
+
+    def __enter__(self) -> IO:
+        self._reader, writer = os.pipe()
+        self._writer = os.fdopen(writer, "w")
+        self._thread = threading.Thread(target=self._forward, daemon=True)
+        self._thread.start()
+        return self._writer
+
+    def __exit__(self, *exc):
+        if self._writer:
+            self._writer.close()
+            self._writer = None
+        if self._thread:
+            self._thread.join()
+            self._thread = None
+        os.close(self._reader)
+
+    def _forward(self) -> None:
+        decoder = codecs.getincrementaldecoder("utf-8")(errors="replace")
+        pending = ""
+        while True:
+            data = os.read(self._reader, 4096)
+            text = pending + decoder.decode(data, final=not data)
+            pending = ""
+            if (self._secret_values or self._prefix) and data:
+                text, newline, pending = text.rpartition("\n")
+                text += newline
+            if text:
+                self._write(secrets.redact(text, self._secret_values))
+            if not data:
+                break
+
+    def _write(self, text: str) -> None:
+        output = text
+        if self._prefix:
+            lines = text.splitlines(keepends=True)
+            output = "".join(f"{self._prefix}{line}" for line in lines)
+
+        with self._lock:
+            self._destination.write(output)
+            self._destination.flush()
+
+        if self._log:
+            self._log.write(self._stream, text)
+
+        callbacks.run_event_handlers(
+            ActionOutput(self._action, stream=self._stream, data=text)
+        )
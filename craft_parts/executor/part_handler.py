@@ -0,0 +1,965 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Definitions and helpers for part handlers."""
+
+import json
+import logging
+import os
+import shlex
+import shutil
+import time
+from pathlib import Path
+from typing import IO, Any, Callable, Dict, List, Optional, Set
+
+from xdg import BaseDirectory  # type: ignore
+
+from craft_parts import (
+    callbacks,
+    elf,
+    errors,
+    overlays,
+    packages,
+    permissions,
+    plugins,
+    sources,
+    step_cache,
+)
+from craft_parts.actions import Action, ActionType
+from craft_parts.infos import PartInfo, StepInfo
+from craft_parts.packages import chisel, lockfile, snaps
+from craft_parts.parts import Part
+from craft_parts.sources import patches
+from craft_parts.sources.cache import FileCache
+from craft_parts.state_manager import states
+from craft_parts.steps import Step
+from craft_parts.utils import file_utils
+
+from . import collisions, environment, reproducible, snapshot, strip
+from .step_handler import FilesAndDirs, StepHandler
+
+logger = logging.getLogger(__name__)
+
+
+class PartHandler:
+    """Handle lifecycle steps for a part.
+
+    :param part: The part being processed.
+    :param part_info: Information about the part being processed.
+    :param part_list: A list containing all parts.
+    """
+
+    def __init__(
+        self,
+        part: Part,
+        *,
+        part_info: PartInfo,
+        part_list: List[Part],
+    ):
+        self._part = part
+        self._part_info = part_info
+        self._part_list = part_list
+        self._stdout: Optional[IO] = None
+        self._stderr: Optional[IO] = None
+        self._secrets: Optional[Dict[str, str]] = None
+        self._timeout: Optional[float] = None
+        self._previous_elf_patches: Dict[str, Dict[str, Any]] = {}
+
+        self._plugin = plugins.get_plugin(
+            part=part,
+            part_info=part_info,
+            properties=part.plugin_properties,
+        )
+
+        self._source_handler = sources.get_source_handler(
+            application_name=part_info.application_name,
+            part=part,
+            project_dirs=part_info.dirs,
+            cache=FileCache(
+                part_info.application_name,
+                cache_dir=part_info.cache_dir,
+                max_size=part_info.cache_size_limit,
+            ),
+            credentials_provider=callbacks.get_source_credentials,
+            mirrors=part_info.source_mirrors,
+        )
+
+        self._overlay_manager = overlays.OverlayManager(
+            part_list=part_list, base_layer_dir=part_info.base_layer_dir
+        )
+
+        self._step_cache: Optional[step_cache.StepCache] = None
+        if part_info.step_cache_backend:
+            self._step_cache = step_cache.StepCache(part_info.step_cache_backend)
+
+    @property
+    def plugin_secrets(self) -> Dict[str, str]:
+        """The secret values used by the plugin commands of this part."""
+        return self._plugin.get_secrets()
+
+    def run_action(
+        self,
+        action: Action,
+        *,
+        stdout: Optional[IO] = None,
+        stderr: Optional[IO] = None,
+        secrets: Optional[Dict[str, str]] = None,
+    ) -> None:
+        """Execute the given action for this part using a plugin.
+
+        :param action: The action to execute.
+        :param stdout: The file to write the output of step commands to.
+        :param stderr: The file to write the error output of step commands to.
+        :param secrets: A dictionary mapping the names of the secrets used by
+            the part to their values.
+        """
+        if action.action_type == ActionType.SKIP:
+            logger.debug("skip execution of %s (because %s)", action, action.reason)
+            self._restore_values(action.step)
+            return
+
+        self._stdout = stdout
+        self._stderr = stderr
+        self._secrets = secrets
+
+        if action.step == Step.PULL:
+            self._load_source_details()
+        elif action.step == Step.PRIME:
+            self._load_elf_patches()
+
+        if action.action_type == ActionType.RERUN:
+            self._clean_step(action.step)
+
+        step_info = StepInfo(self._part_info, action.step, action=action)
+
+        if action.step == Step.PULL:
+            handler = self._run_pull
+        elif action.step == Step.OVERLAY:
+            handler = self._run_overlay
+        elif action.step == Step.BUILD:
+            handler = self._run_build
+        elif action.step == Step.STAGE:
+            handler = self._run_stage
+        elif action.step == Step.PRIME:
+            handler = self._run_prime
+        else:
+            raise RuntimeError(f"cannot run action for invalid step {action.step!r}")
+
+        self._timeout = self._get_step_timeout(action.step)
+
+        # Record the failure so that the step can be resumed in a later run.
+        failed_file = states.failed_state_file_path(self._part, action.step)
+
+        callbacks.run_pre_step(step_info)
+        try:
+            state = self._run_with_retries(
+                handler, step_info, update=action.action_type == ActionType.UPDATE
+            )
+        except Exception as err:
+            failed_file.parent.mkdir(parents=True, exist_ok=True)
+            failed_file.write_text(f"{err}\n")
+            callbacks.run_step_failure(step_info, error=err)
+            raise
+
+        if failed_file.exists():
+            failed_file.unlink()
+
+        # Keep the values set by this step so they can be restored if the
+        # step is skipped in a later run.
+        state = state.copy(update={"values": step_info.values})
+        state.write(
+            states.state_file_path(self._part, action.step),
+            work_dir=self._part.work_dir,
+        )
+        callbacks.run_post_step(step_info)
+
+    def _run_with_retries(
+        self,
+        handler: Callable[..., states.StepState],
+        step_info: StepInfo,
+        *,
+        update: bool,
+    ) -> states.StepState:
+        """Execute a step handler, retrying with exponential backoff if it fails.
+
+        :param handler: The step handler to execute.
+        :param step_info: Information about the step to execute.
+        :param update: Whether to update the previous step execution.
+
+        :return: The step state.
+        """
+        retries = self._get_step_retries(step_info.step)
+        attempt = 0
+        while True:
+            try:
+                return handler(step_info, update=update)
+            except errors.PartsError as err:
+                if attempt >= retries:
+                    raise
+
+                delay = self._part_info.retry_delay * 2 ** attempt
+                attempt += 1
+                logger.warning(
+                    "%s:%s failed (%s), retrying in %g seconds (%d/%d)",
+                    self._part.name,
+                    step_info.step.name.lower(),
+                    err.brief,
+                    delay,
+                    attempt,
+                    retries,
+                )
+                time.sleep(delay)
+
+            # Don't retry pulling on top of partially pulled sources.
+            if step_info.step == Step.PULL and not update:
+                _remove(self._part.part_src_dir)
+
+    def _get_step_timeout(self, step: Step) -> Optional[float]:
+        """Obtain the step timeout set in the part or as project default."""
+        timeout = self._part.spec.step_timeouts.get(step.name.lower())
+        if timeout is None:
+            timeout = self._part_info.step_timeouts.get(step)
+        return timeout
+
+    def _get_step_retries(self, step: Step) -> int:
+        """Obtain the number of retries set in the part or as project default."""
+        retries = self._part.spec.step_retries.get(step.name.lower())
+        if retries is None:
+            retries = self._part_info.step_retries.get(step, 0)
+        return retries
+
+    @property
+    def _part_properties(self) -> Dict[str, Any]:
+        return {**self._part.spec.marshal(), **self._part.plugin_properties.marshal()}
+
+    def _run_pull(self, step_info: StepInfo, *, update: bool) -> states.StepState:
+        """Execute the pull step for this part.
+
+        :param step_info: Information about the step to execute.
+        :param update: Whether to update previously pulled sources.
+
+        :return: The pull step state.
+        """
+        self._make_dirs()
+
+        if update and self._source_handler:
+            if self._part.spec.get_scriptlet(Step.PULL) is None:
+                self._update_pull(step_info)
+            else:
+                # Plugin pull commands only run as part of the overridden step.
+                self._source_handler.update()
+            assets = self._get_pull_assets()
+        else:
+            assets = self._run_cached_step(
+                step_info,
+                source_digest=self._get_pull_source_digest(),
+                dirs=["src"],
+                handler=lambda: self._pull_and_get_assets(step_info),
+            )
+
+        return states.PullState(
+            part_properties=self._part_properties,
+            project_options=step_info.project_options,
+            assets=assets,
+        )
+
+    def _pull_and_get_assets(self, step_info: StepInfo) -> Dict[str, Any]:
+        """Execute the pull step and return the pull state assets."""
+        fetched_packages = self._fetch_stage_packages(step_info)
+        self._cut_stage_slices(step_info)
+        self._fetch_stage_snaps()
+        self._run_step(
+            step_info=step_info,
+            scriptlet_name="override-pull",
+            work_dir=self._part.part_src_dir,
+        )
+        assets = self._get_pull_assets()
+        if fetched_packages:
+            assets["stage-packages"] = fetched_packages
+        return assets
+
+    def _fetch_stage_packages(self, step_info: StepInfo) -> List[str]:
+        """Fetch the part stage packages to the part packages directory.
+
+        :param step_info: Information about the step to execute.
+
+        :return: The fetched packages and their versions.
+        """
+        _remove(self._part.part_packages_dir)
+
+        spec = self._part.spec
+        package_names = [
+            name for name in spec.stage_packages if not chisel.is_slice(name)
+        ]
+        lockfile_path = step_info.packages_lockfile
+        replay = step_info.packages_lockfile_mode == lockfile.LockfileMode.REPLAY
+        if not package_names:
+            if lockfile_path and not replay:
+                lockfile.record_part_packages(
+                    lockfile_path, part_name=self._part.name, locked=[]
+                )
+            return []
+
+        build_base = step_info.build_base
+        repository = packages.get_repository_for_base(build_base)
+        if lockfile_path and replay:
+            locked = lockfile.get_part_packages(
+                lockfile_path, part_name=self._part.name, package_names=package_names
+            )
+            return lockfile.replay_stage_packages(
+                locked,
+                repository=repository,
+                application_name=step_info.application_name,
+                stage_packages_path=self._part.part_packages_dir,
+                base=build_base.name if build_base else "",
+                target_arch=step_info.target_arch,
+            )
+
+        fetched = repository.fetch_stage_packages(
+            application_name=step_info.application_name,
+            package_names=package_names,
+            stage_packages_path=self._part.part_packages_dir,
+            base=build_base.name if build_base else "",
+            target_arch=step_info.target_arch,
+            recommends=spec.stage_packages_recommends,
+            suggests=spec.stage_packages_suggests,
+        )
+
+        if lockfile_path:
+            lockfile.record_part_packages(
+                lockfile_path,
+                part_name=self._part.name,
+                locked=lockfile.lock_stage_packages(
+                    repository=repository,
+                    stage_packages_path=self._part.part_packages_dir,
+                ),
+            )
+
+        return fetched
+
+    def _fetch_stage_snaps(self) -> None:
+        """Download the part stage snaps to the part snaps directory."""
+        _remove(self._part.part_snaps_dir)
+
+        if self._part.spec.stage_snaps:
+            snaps.download_snaps(
+                snaps_list=self._part.spec.stage_snaps,
+                directory=str(self._part.part_snaps_dir),
+            )
+
+    def _cut_stage_slices(self, step_info: StepInfo) -> None:
+        """Cut the chisel slices listed in the part stage packages.
+
+        :param step_info: Information about the step to execute.
+        """
+        _remove(self._part.part_slices_dir)
+
+        slices = chisel.get_slices(self._part.spec.stage_packages)
+        if not slices:
+            return
+
+        cache_dir = BaseDirectory.save_cache_path(
+            step_info.application_name, "craft-parts", "chisel"
+        )
+        release_dir = chisel.resolve_release(
+            self._part.spec.chisel_release,
+            project_dir=Path.cwd(),
+            cache_dir=Path(cache_dir),
+        )
+        chisel.cut_slices(
+            slices,
+            install_path=self._part.part_slices_dir,
+            release_dir=release_dir,
+            target_arch=step_info.target_arch,
+        )
+
+    def _unpack_stage_packages(self, step_info: StepInfo) -> None:
+        """Unpack the fetched stage packages to the part install directory.
+
+        The contents of cut chisel slices and downloaded stage snaps are
+        also installed.
+
+        :param step_info: Information about the step to execute.
+        """
+        if self._part.part_packages_dir.is_dir():
+            repository = packages.get_repository_for_base(step_info.build_base)
+            repository.unpack_stage_packages(
+                stage_packages_path=self._part.part_packages_dir,
+                install_path=self._part.part_install_dir,
+            )
+
+        if self._part.part_slices_dir.is_dir():
+            file_utils.link_or_copy_tree(
+                str(self._part.part_slices_dir), str(self._part.part_install_dir)
+            )
+
+        if self._part.part_snaps_dir.is_dir():
+            snaps.unpack_snaps(
+                str(self._part.part_snaps_dir),
+                install_dir=str(self._part.part_install_dir),
+            )
+
+    def _get_pull_assets(self) -> Dict[str, Any]:
+        """Obtain the assets to record in the pull state."""
+        assets = self._plugin.get_pull_assets()
+        pull_commands = self._plugin.get_pull_commands()
+        if pull_commands:
+            assets["pull-commands"] = pull_commands
+        if self._source_handler and self._source_handler.source_details:
+            assets["source-details"] = self._source_handler.source_details
+        if self._source_handler:
+            epoch = self._source_handler.get_source_date_epoch()
+            if epoch is not None:
+                assets["source-date-epoch"] = epoch
+        if self._part.spec.source_patches:
+            assets["source-patches"] = patches.get_digests(
+                self._part.spec.source_patches
+            )
+        if self._part.part_snaps_dir.is_dir():
+            # Record the downloaded revisions so channel movement can be detected.
+            assets["stage-snaps"] = snaps.get_downloaded_snap_revisions(
+                str(self._part.part_snaps_dir)
+            )
+        return assets
+
+    def _get_pull_source_digest(self) -> Optional[str]:
+        """Obtain a digest identifying the contents to be pulled.
+
+        Only sources pinned to a checksum or commit have contents known
+        before pulling. Stage packages and snaps are resolved when pulling,
+        so parts that use them are not cached.
+
+        :return: The source digest, or None if the pulled contents are not
+            known in advance.
+        """
+        spec = self._part.spec
+        if spec.stage_packages or spec.stage_snaps:
+            return None
+
+        if spec.source_checksum:
+            digest = spec.source_checksum
+        elif spec.source_commit:
+            digest = f"commit/{spec.source_commit}"
+        elif not spec.source:
+            digest = "none"
+        else:
+            return None
+
+        if spec.source_patches:
+            digest += json.dumps(patches.get_digests(spec.source_patches))
+
+        return digest
+
+    def _get_build_source_digest(self) -> str:
+        """Obtain a digest identifying the contents used to build the part.
+
+        Parts that depend on other parts are built against the contents of
+        the stage directory, so it is also part of the digest.
+        """
+        digest = step_cache.get_directory_digest(self._part.part_src_dir)
+        if self._part.dependencies:
+            digest += step_cache.get_directory_digest(self._part.stage_dir)
+        return digest
+
+    def _run_cached_step(
+        self,
+        step_info: StepInfo,
+        *,
+        source_digest: Optional[str],
+        dirs: List[str],
+        handler: Callable[[], Dict[str, Any]],
+    ) -> Dict[str, Any]:
+        """Restore the step output from the step cache, or execute the step.
+
+        :param step_info: Information about the step to execute.
+        :param source_digest: The digest of the step input, or None if the
+            step output can't be cached.
+        :param dirs: The step output directories, relative to the part
+            work directory.
+        :param handler: The function executing the step and returning the
+            step state assets.
+
+        :return: The step state assets.
+        """
+        if not self._step_cache or source_digest is None:
+            return handler()
+
+        key = step_cache.get_step_fingerprint(
+            step_name=step_info.step.name,
+            source_digest=source_digest,
+            part_properties=self._part_properties,
+            project_options=step_info.project_options,
+            environment=environment.generate_part_environment(
+                part=self._part, plugin=self._plugin, step_info=step_info
+            ),
+            package_versions=self._get_build_package_versions(),
+        )
+        part_dir = self._part.part_src_dir.parent
+
+        assets = self._step_cache.restore(key=key, root=part_dir, dirs=dirs)
+        if assets is not None:
+            logger.debug("restore %s:%s from step cache", self._part.name, step_info)
+            return assets
+
+        assets = handler()
+        self._step_cache.save(key=key, root=part_dir, dirs=dirs, assets=assets)
+        return assets
+
+    def _get_build_package_versions(
+        self, *, include_plugin_packages: bool = False
+    ) -> List[str]:
+        """Obtain the installed versions of the part build packages.
+
+        :param include_plugin_packages: Whether to also obtain the versions of
+            the build packages required by the plugin.
+        """
+        package_names = set(self._part.spec.build_packages)
+        if include_plugin_packages:
+            package_names |= self._plugin.get_build_packages()
+        if not package_names:
+            return []
+
+        repo = packages.get_repository_for_base(self._part_info.build_base)
+        names = {
+            packages.base.get_pkg_name_parts(repo.get_package_name(name))[0]
+            for name in package_names
+        }
+        return [
+            package
+            for package in repo.get_installed_packages()
+            if package.split("=", 1)[0] in names
+        ]
+
+    def _load_source_details(self) -> None:
+        """Make the source details of the previous pull available to the handler."""
+        if not self._source_handler:
+            return
+
+        state = states.load_state(self._part, Step.PULL)
+        if state:
+            self._source_handler.previous_details = state.assets.get("source-details")
+
+    def _load_elf_patches(self) -> None:
+        """Make the ELF files patched in the previous prime available to the handler."""
+        state = states.load_state(self._part, Step.PRIME)
+        if isinstance(state, states.PrimeState):
+            self._previous_elf_patches = state.elf_patches
+
+    def _restore_values(self, step: Step) -> None:
+        """Make the values set by a previous execution of a step available.
+
+        :param step: The step being skipped.
+        """
+        state = states.load_state(self._part, step)
+        if not state:
+            return
+
+        for name, value in state.values.items():
+            self._part_info.set_value(name, value)
+
+    def _update_pull(self, step_info: StepInfo) -> None:
+        """Update previously pulled sources and repeat plugin pull commands.
+
+        :param step_info: Information about the step to execute.
+        """
+        step_handler = StepHandler(
+            self._part,
+            step_info=step_info,
+            plugin=self._plugin,
+            source_handler=self._source_handler,
+            stdout=self._stdout,
+            stderr=self._stderr,
+            timeout=self._timeout,
+            secrets=self._secrets,
+        )
+        step_handler.update_pull()
+
+    def _run_overlay(self, step_info: StepInfo, *, update: bool) -> states.StepState:
+        """Execute the overlay step for this part.
+
+        Layers with the same definition, on top of the same base image and
+        lower layers, are restored from the layer cache.
+
+        :param step_info: Information about the step to execute.
+        :param update: Unused, the layer is always created from scratch.
+
+        :return: The overlay step state.
+        """
+        self._make_dirs()
+        _remove(self._part.part_layer_dir)
+
+        layer_cache = overlays.LayerCache(
+            step_info.application_name,
+            cache_dir=step_info.cache_dir,
+            max_size=step_info.cache_size_limit,
+        )
+        layer_key = self._overlay_manager.get_layer_key(self._part)
+        assets: Dict[str, Any] = {}
+        if layer_key:
+            assets["layer-key"] = layer_key
+
+        if layer_key and layer_cache.restore(
+            key=layer_key, layer_dir=self._part.part_layer_dir
+        ):
+            logger.debug("restored overlay layer %s", layer_key)
+        else:
+            self._create_layer(step_info)
+            if layer_key:
+                layer_cache.save(key=layer_key, layer_dir=self._part.part_layer_dir)
+
+        return states.OverlayState(
+            part_properties=self._part_properties,
+            project_options=step_info.project_options,
+            assets=assets,
+        )
+
+    def _create_layer(self, step_info: StepInfo) -> None:
+        """Install the overlay packages and run the overlay script in the layer.
+
+        :param step_info: Information about the step to execute.
+        """
+        spec = self._part.spec
+        self._part.part_layer_dir.mkdir(parents=True)
+        if not spec.overlay_packages and spec.overlay_script is None:
+            return
+
+        with self._overlay_manager.mount_layer(self._part) as mountpoint:
+            if spec.overlay_packages:
+                repository = packages.get_repository_for_base(step_info.build_base)
+                commands = repository.get_overlay_install_commands(
+                    spec.overlay_packages
+                )
+                overlays.run_chroot_script(
+                    "\n".join(shlex.join(command) for command in commands),
+                    root=mountpoint,
+                    part_name=self._part.name,
+                    project_info=step_info,
+                    script_name="overlay-packages",
+                )
+
+            if spec.overlay_script is not None:
+                env = "\n".join(
+                    f"export {name}={shlex.quote(value)}"
+                    for name, value in step_info.project_environment.items()
+                )
+                overlays.run_chroot_script(
+                    spec.overlay_script,
+                    root=mountpoint,
+                    part_name=self._part.name,
+                    project_info=step_info,
+                    env=env,
+                )
+
+    def _run_build(self, step_info: StepInfo, *, update: bool) -> states.StepState:
+        """Execute the build step for this part.
+
+        :param step_info: Information about the step to execute.
+        :param update: Whether to update a previous build.
+
+        :return: The build step state.
+        """
+        self._make_dirs()
+
+        if not update:
+            _remove(self._part.part_build_dir)
+            _remove(self._part.part_install_dir)
+            self._make_dirs()
+
+        self._validate_environment(step_info)
+
+        # Copy source to the build directory for in-tree builds.
+        if not self._plugin.out_of_source_build:
+            file_utils.link_or_copy_tree(
+                str(self._part.part_src_dir), str(self._part.part_build_dir)
+            )
+
+        if update:
+            assets = self._build_and_get_assets(step_info)
+        else:
+            assets = self._run_cached_step(
+                step_info,
+                source_digest=self._get_build_source_digest(),
+                dirs=["build", "install"],
+                handler=lambda: self._build_and_get_assets(step_info),
+            )
+
+        return states.BuildState(
+            part_properties=self._part_properties,
+            project_options=step_info.project_options,
+            assets=assets,
+        )
+
+    def _build_and_get_assets(self, step_info: StepInfo) -> Dict[str, Any]:
+        """Execute the build step and return the build state assets."""
+        self._unpack_stage_packages(step_info)
+        self._run_step(
+            step_info=step_info,
+            scriptlet_name="override-build",
+            work_dir=self._part.part_build_dir,
+        )
+        assets = self._plugin.get_build_assets()
+        assets["build-environment"] = snapshot.get_build_environment_snapshot(
+            self._part,
+            plugin=self._plugin,
+            step_info=step_info,
+            build_packages=self._get_build_package_versions(
+                include_plugin_packages=True
+            ),
+        )
+        return assets
+
+    def _validate_environment(self, step_info: StepInfo) -> None:
+        """Verify that the part can be built in the build environment.
+
+        :param step_info: Information about the step to execute.
+
+        :raise PluginEnvironmentValidationError: If the environment is invalid.
+        """
+        env = environment.generate_part_environment(
+            part=self._part, plugin=self._plugin, step_info=step_info
+        )
+        validator = self._plugin.validator_class(
+            part_name=self._part.name,
+            env=env,
+            properties=self._part.plugin_properties,
+        )
+        validator.validate_environment(part_dependencies=self._part.dependencies)
+        callbacks.run_environment_validation(step_info, validator=validator)
+
+    def _run_stage(self, step_info: StepInfo, *, update: bool) -> states.StepState:
+        """Execute the stage step for this part.
+
+        :param step_info: Information about the step to execute.
+        :param update: Unused, staging is always executed from scratch.
+
+        :return: The stage step state.
+        """
+        self._make_dirs()
+
+        overridden = collisions.check_for_stage_collisions(self._part_list)
+        self._overlay_manager.check_conflicts()
+
+        contents = self._run_step(
+            step_info=step_info,
+            scriptlet_name="override-stage",
+            work_dir=self._part.stage_dir,
+            stage_excludes=overridden.get(self._part.name),
+        )
+
+        return states.StageState(
+            part_properties=self._part_properties,
+            project_options=step_info.project_options,
+            files=contents.files,
+            directories=contents.dirs,
+        )
+
+    def _run_prime(self, step_info: StepInfo, *, update: bool) -> states.StepState:
+        """Execute the prime step for this part.
+
+        :param step_info: Information about the step to execute.
+        :param update: Unused, priming is always executed from scratch.
+
+        :return: The prime step state.
+        """
+        self._make_dirs()
+
+        contents = self._run_step(
+            step_info=step_info,
+            scriptlet_name="override-prime",
+            work_dir=self._part.prime_dir,
+        )
+
+        strip_spec = self._part.spec.strip
+        if strip_spec:
+            strip.strip_files(
+                part_name=self._part.name,
+                root=self._part.prime_dir,
+                paths=contents.files,
+                exclude=strip_spec.exclude,
+                debug_dir=self._part.debug_dir if strip_spec.split_debug else None,
+            )
+
+        elf_patches: Dict[str, Dict[str, Any]] = {}
+        elf_patch_spec = self._part.spec.elf_patch
+        if elf_patch_spec:
+            patcher = elf.ElfPatcher(
+                self._part.prime_dir,
+                interpreter=elf_patch_spec.interpreter or None,
+                exclude=elf_patch_spec.exclude,
+            )
+            elf_patches = patcher.patch(
+                contents.files, previous=self._previous_elf_patches
+            )
+
+        if step_info.normalize_prime:
+            reproducible.normalize_files(
+                self._part.prime_dir,
+                paths=contents.files | contents.dirs,
+                epoch=reproducible.get_source_date_epoch(
+                    self._part, step_info=step_info
+                ),
+            )
+
+        # Extended attributes are removed when normalizing, so permissions
+        # are set after normalization.
+        applied_permissions = permissions.apply_permissions(
+            part_name=self._part.name,
+            root=self._part.prime_dir,
+            paths=contents.files | contents.dirs,
+            permissions=self._part.spec.permissions,
+        )
+
+        return states.PrimeState(
+            part_properties=self._part_properties,
+            project_options=step_info.project_options,
+            files=contents.files,
+            directories=contents.dirs,
+            permissions=applied_permissions,
+            elf_patches=elf_patches,
+        )
+
+    def _run_step(
+        self,
+        *,
+        step_info: StepInfo,
+        scriptlet_name: str,
+        work_dir: Path,
+        stage_excludes: Optional[Set[str]] = None,
+    ) -> FilesAndDirs:
+        """Run the scriptlet if overriding, otherwise run the built-in handler.
+
+        :param step_info: Information about the step to execute.
+        :param scriptlet_name: The name of this step's scriptlet.
+        :param work_dir: The path to run the scriptlet on.
+        :param stage_excludes: Conflicting files provided by other parts that
+            must not be staged.
+
+        :return: If step is Stage or Prime, return a tuple of sets containing
+            the step's file and directory artifacts.
+        """
+        # Files from the overlay layer are migrated with the stage files.
+        overlay_contents: Optional[FilesAndDirs] = None
+        if step_info.step in (Step.STAGE, Step.PRIME):
+            overlay_contents = FilesAndDirs(
+                *self._overlay_manager.get_overlay_files(self._part)
+            )
+
+        step_handler = StepHandler(
+            self._part,
+            step_info=step_info,
+            plugin=self._plugin,
+            source_handler=self._source_handler,
+            stdout=self._stdout,
+            stderr=self._stderr,
+            timeout=self._timeout,
+            secrets=self._secrets,
+            stage_excludes=stage_excludes,
+            overlay_contents=overlay_contents,
+        )
+
+        scriptlet = self._part.spec.get_scriptlet(step_info.step)
+        if scriptlet is not None:
+            step_handler.run_scriptlet(
+                scriptlet, scriptlet_name=scriptlet_name, work_dir=work_dir
+            )
+            return FilesAndDirs(set(), set())
+
+        return step_handler.run_builtin()
+
+    def _make_dirs(self) -> None:
+        dirs = [
+            self._part.part_src_dir,
+            self._part.part_build_dir,
+            self._part.part_install_dir,
+            self._part.part_state_dir,
+            self._part.part_run_dir,
+            self._part.stage_dir,
+            self._part.prime_dir,
+        ]
+        for dir_name in dirs:
+            os.makedirs(dir_name, exist_ok=True)
+
+    def _clean_step(self, step: Step) -> None:
+        """Remove the state of the given step and all later steps.
+
+        Files migrated to the stage and prime directories by the cleaned
+        steps are also removed.
+
+        :param step: The first step to clean.
+        """
+        for next_step in [step] + step.next_steps(overlay=True):
+            if next_step == Step.OVERLAY:
+                _remove(self._part.part_layer_dir)
+            elif next_step == Step.STAGE:
+                self._clean_shared_area(next_step, self._part.stage_dir)
+            elif next_step == Step.PRIME:
+                self._clean_shared_area(next_step, self._part.prime_dir)
+
+            for state_file in (
+                states.state_file_path(self._part, next_step),
+                states.failed_state_file_path(self._part, next_step),
+            ):
+                if state_file.is_file():
+                    state_file.unlink()
+
+    def _clean_shared_area(self, step: Step, shared_dir: Path) -> None:
+        """Remove the files migrated by this part to a shared directory.
+
+        Files and directories also migrated by other parts are kept, and
+        directories are only removed if they are empty.
+
+        :param step: The step that migrated the files.
+        :param shared_dir: The directory the files were migrated to.
+        """
+        state = states.load_state(self._part, step)
+        if not state:
+            return
+
+        files = set(state.files)
+        directories = set(state.directories)
+        for other_part in self._part_list:
+            if other_part.name == self._part.name:
+                continue
+            other_state = states.load_state(other_part, step)
+            if other_state:
+                files -= other_state.files
+                directories -= other_state.directories
+
+        for filename in files:
+            path = shared_dir / filename
+            if path.is_symlink() or path.is_file():
+                logger.debug("remove migrated file %s", path)
+                path.unlink()
+
+        # Remove nested directories first.
+        for dirname in sorted(directories, reverse=True):
+            path = shared_dir / dirname
+            if path.is_dir() and not path.is_symlink() and not any(path.iterdir()):
+                logger.debug("remove migrated directory %s", path)
+                path.rmdir()
+
+
+def _remove(filename: Path) -> None:
+    """Remove the given directory entry.
+
+    :param filename: The path to the file or directory to remove.
+    """
+    if filename.is_symlink() or filename.is_file():
+        logger.debug("remove file %s", filename)
+        filename.unlink()
+    elif filename.is_dir():
+        logger.debug("remove directory %s", filename)
+        shutil.rmtree(filename)
@@ -0,0 +1,99 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Helpers to produce reproducible step outputs."""
+
+import logging
+import os
+from pathlib import Path
+from typing import Iterable, Optional
+
+from craft_parts.infos import StepInfo
+from craft_parts.parts import Part
+from craft_parts.state_manager import states
+from craft_parts.steps import Step
+
+logger = logging.getLogger(__name__)
+
+
+def get_source_date_epoch(part: Part, *, step_info: StepInfo) -> Optional[int]:
+    """Obtain the timestamp to use as ``SOURCE_DATE_EPOCH`` for a part.
+
+    The timestamp set in the project takes precedence over the timestamp
+    derived from the pulled source. Since the source timestamp is only
+    known after pulling, it's not available to the pull step.
+
+    :param part: The part being processed.
+    :param step_info: Information about the step being executed.
+
+    :return: The timestamp in seconds since the epoch, or None if unknown.
+    """
+    if step_info.source_date_epoch is not None:
+        return step_info.source_date_epoch
+
+    if step_info.step == Step.PULL:
+        return None
+
+    state = states.load_state(part, Step.PULL)
+    if not state:
+        return None
+
+    return state.assets.get("source-date-epoch")
+
+
+def normalize_files(
+    root: Path, *, paths: Iterable[str], epoch: Optional[int]
+) -> None:
+    """Remove nondeterministic metadata from files in a directory.
+
+    Modification times newer than the epoch are clamped to the epoch, and
+    extended attributes are removed. Files migrated using hard links share
+    metadata with the files they were migrated from, which are also
+    modified.
+
+    :param root: The directory containing the files.
+    :param paths: The files and directories to normalize, relative to root.
+    :param epoch: The timestamp to clamp modification times to, in seconds
+        since the epoch. If not set, modification times are not changed.
+    """
+    for path in sorted(paths, reverse=True):
+        # Children are processed first, so directory times aren't updated
+        # after being clamped.
+        filename = os.path.join(root, path)
+        if not os.path.lexists(filename):
+            continue
+
+        _remove_xattrs(filename)
+
+        if epoch is not None:
+            stat = os.lstat(filename)
+            if stat.st_mtime > epoch:
+                os.utime(filename, (epoch, epoch), follow_symlinks=False)
+
+
+def _remove_xattrs(filename: str) -> None:
+    """Remove the extended attributes of a file, if supported."""
+    try:
+        names = os.listxattr(filename, follow_symlinks=False)
+    except OSError as err:
+        logger.debug("cannot list extended attributes of %s: %s", filename, err)
+        return
+
+    for name in names:
+        try:
+            os.removexattr(filename, name, follow_symlinks=False)
+        except OSError as err:
+            logger.debug("cannot remove attribute %s of %s: %s", name, filename, err)
@@ -0,0 +1,139 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Snapshots of the environment a part was built in.
+
+The snapshot is recorded in the build state, so the toolchain, packages,
+base and environment used to build an artifact can be inspected after
+the build.
+"""
+
+import logging
+import subprocess
+from typing import Any, Dict, List
+
+from craft_parts import overlays
+from craft_parts.infos import StepInfo
+from craft_parts.parts import Part
+from craft_parts.plugins import Plugin, PluginEnvironmentValidator
+
+from . import environment
+
+logger = logging.getLogger(__name__)
+
+# Variables that commonly affect the output of build tools.
+_ENVIRONMENT_VARIABLES = [
+    "AR",
+    "CC",
+    "CFLAGS",
+    "CPPFLAGS",
+    "CXX",
+    "CXXFLAGS",
+    "LANG",
+    "LC_ALL",
+    "LDFLAGS",
+    "PATH",
+    "PKG_CONFIG_PATH",
+    "SOURCE_DATE_EPOCH",
+    "TZ",
+]
+
+
+def get_build_environment_snapshot(
+    part: Part,
+    *,
+    plugin: Plugin,
+    step_info: StepInfo,
+    build_packages: List[str],
+) -> Dict[str, Any]:
+    """Describe the environment a part is built in.
+
+    Toolchain versions and environment variables are obtained by running
+    commands in the part build environment.
+
+    :param part: The part being built.
+    :param plugin: The plugin used to build the part.
+    :param step_info: Information about the build step.
+    :param build_packages: The installed build packages of the part, in the
+        ``name=version`` format.
+
+    :return: The build environment snapshot.
+    """
+    env = environment.generate_part_environment(
+        part=part, plugin=plugin, step_info=step_info
+    )
+    runner = PluginEnvironmentValidator(
+        part_name=part.name, env=env, properties=part.plugin_properties
+    )
+
+    build_base = step_info.build_base
+    base_layer_dir = step_info.base_layer_dir
+
+    return {
+        "host-arch": step_info.host_arch,
+        "target-arch": step_info.target_arch,
+        "base": build_base.name if build_base else None,
+        "base-layer": str(base_layer_dir) if base_layer_dir else None,
+        "base-layer-digest": (
+            overlays.get_base_image_digest(base_layer_dir) if base_layer_dir else None
+        ),
+        "toolchain": _get_toolchain_versions(
+            plugin.get_toolchain_version_commands(), runner=runner
+        ),
+        "build-packages": dict(
+            package.split("=", 1) for package in sorted(build_packages)
+        ),
+        "environment": _get_environment_variables(runner),
+    }
+
+
+def _get_toolchain_versions(
+    commands: Dict[str, str], *, runner: PluginEnvironmentValidator
+) -> Dict[str, str]:
+    """Obtain the first line of the version output of each tool.
+
+    Tools that are not available in the build environment are not listed.
+    """
+    versions: Dict[str, str] = {}
+    for name, command in commands.items():
+        try:
+            output = runner.execute(command).strip()
+        except (OSError, subprocess.CalledProcessError) as err:
+            logger.debug("cannot obtain %s version: %s", name, err)
+            continue
+
+        if output:
+            versions[name] = output.splitlines()[0]
+
+    return versions
+
+
+def _get_environment_variables(runner: PluginEnvironmentValidator) -> Dict[str, str]:
+    """Obtain the values of relevant variables set in the build environment."""
+    try:
+        output = runner.execute("env -0")
+    except (OSError, subprocess.CalledProcessError) as err:
+        logger.debug("cannot obtain build environment: %s", err)
+        return {}
+
+    variables: Dict[str, str] = {}
+    for entry in output.split("\0"):
+        name, sep, value = entry.partition("=")
+        if sep and name in _ENVIRONMENT_VARIABLES:
+            variables[name] = value
+
+    return dict(sorted(variables.items()))
@@ -16,6 +16,7 @@
 
 """Handle the execution of built-in or user specified step commands."""
 
+import contextlib
 import fileinput
 import json
 import os
@@ -26,19 +27,21 @@ import tempfile
 import textwrap
 import time
 from collections import namedtuple
+from concurrent import futures
 from pathlib import Path
-from typing import List, Optional, Set, Union
+from typing import IO, Any, Dict, Iterator, List, Optional, Sequence, Set, Union
 
-from craft_parts import errors
+from craft_parts import errors, hosts
 from craft_parts.executor import collisions
 from craft_parts.infos import StepInfo
 from craft_parts.parts import Part
 from craft_parts.plugins import Plugin
-from craft_parts.sources import SourceHandler
+from craft_parts.secrets import get_secret_environment
+from craft_parts.sources import SourceHandler, patches
 from craft_parts.steps import Step
-from craft_parts.utils import file_utils
+from craft_parts.utils import file_utils, os_utils
 
-from . import environment, filesets
+from . import environment, filesets, isolation
 from .filesets import Fileset
 
 FilesAndDirs = namedtuple("FilesAndDirs", ["files", "dirs"])
@@ -63,14 +66,32 @@ class StepHandler:
         step_info: StepInfo,
         plugin: Plugin,
         source_handler: Optional[SourceHandler],
+        stdout: Optional[IO] = None,
+        stderr: Optional[IO] = None,
+        timeout: Optional[float] = None,
+        secrets: Optional[Dict[str, str]] = None,
+        stage_excludes: Optional[Set[str]] = None,
+        overlay_contents: Optional[FilesAndDirs] = None,
     ):
         self._part = part
         self._step_info = step_info
         self._plugin = plugin
         self._source_handler = source_handler
+        self._stdout = stdout
+        self._stderr = stderr
+        self._timeout = timeout
+        self._stage_excludes = stage_excludes or set()
+        self._overlay_contents = overlay_contents or FilesAndDirs(set(), set())
+        self._deadline = time.monotonic() + timeout if timeout else None
         self._env = environment.generate_part_environment(
             part=part, plugin=plugin, step_info=step_info
         )
+        self._process_env: Optional[Dict[str, str]] = None
+        if secrets:
+            self._process_env = {
+                **os.environ,
+                **get_secret_environment(secrets),
+            }
 
     def run_builtin(self) -> FilesAndDirs:
         """Run the built-in commands for the current step."""
@@ -90,11 +111,68 @@ class StepHandler:
 
         return handler()
 
+    def update_pull(self) -> None:
+        """Update the part source and repeat the plugin pull commands.
+
+        Plugin pull commands often depend on the source contents, such as
+        dependency prefetching driven by a lockfile, so they run again after
+        the source is updated.
+        """
+        if self._source_handler:
+            self._source_handler.update()
+
+        self._run_pull_commands()
+
     def _builtin_pull(self) -> FilesAndDirs:
         if self._source_handler:
             self._source_handler.pull()
+
+        if self._part.spec.source_patches:
+            patches.apply_patches(
+                self._part.spec.source_patches,
+                src_dir=self._part.part_src_dir,
+                strip=self._part.spec.source_patches_strip,
+            )
+
+        self._run_pull_commands()
+
         return FilesAndDirs(set(), set())
 
+    def _run_pull_commands(self) -> None:
+        # Plugin commands.
+        plugin_pull_commands = self._plugin.get_pull_commands()
+        if not plugin_pull_commands:
+            return
+
+        # Save script to execute.
+        pull_script_path = self._part.part_run_dir.absolute() / "pull.sh"
+        with pull_script_path.open("w") as run_file:
+            print(self._env, file=run_file)
+            print("set -x", file=run_file)
+
+            for pull_command in plugin_pull_commands:
+                print(pull_command, file=run_file)
+
+        pull_script_path.chmod(0o755)
+        script_command = hosts.get_host_platform().get_script_command(
+            pull_script_path
+        )
+
+        try:
+            subprocess.run(
+                self._get_command(script_command),
+                check=True,
+                cwd=self._part.part_src_subdir,
+                env=self._process_env,
+                stdout=self._stdout,
+                stderr=self._stderr,
+                timeout=self._get_remaining_time(),
+            )
+        except subprocess.TimeoutExpired as err:
+            raise self._timeout_error() from err
+        except subprocess.CalledProcessError as process_error:
+            raise errors.PluginPullError(part_name=self._part.name) from process_error
+
     def _builtin_build(self) -> FilesAndDirs:
 
         # Plugin commands.
@@ -110,21 +188,54 @@ class StepHandler:
                 print(build_command, file=run_file)
 
         build_script_path.chmod(0o755)
+        script_command = hosts.get_host_platform().get_script_command(
+            build_script_path
+        )
 
         try:
-            subprocess.run(
-                [build_script_path], check=True, cwd=self._part.part_build_subdir
-            )
+            with self._isolate(
+                script_command, cwd=self._part.part_build_subdir
+            ) as command:
+                subprocess.run(
+                    self._get_command(command),
+                    check=True,
+                    cwd=self._part.part_build_subdir,
+                    env=self._process_env,
+                    stdout=self._stdout,
+                    stderr=self._stderr,
+                    timeout=self._get_remaining_time(),
+                )
+        except subprocess.TimeoutExpired as err:
+            raise self._timeout_error() from err
         except subprocess.CalledProcessError as process_error:
             raise errors.PluginBuildError(part_name=self._part.name) from process_error
 
         return FilesAndDirs(set(), set())
 
     def _builtin_stage(self) -> FilesAndDirs:
+        # The overlay layer is migrated first, so that files installed by
+        # the part replace files from the layer.
+        _migrate_files(
+            files=self._overlay_contents.files,
+            dirs=self._overlay_contents.dirs,
+            srcdir=str(self._part.part_layer_dir),
+            destdir=str(self._part.stage_dir),
+        )
+
         stage_fileset = Fileset(self._part.spec.stage_files, name="stage")
         srcdir = str(self._part.part_install_dir)
         files, dirs = filesets.migratable_filesets(stage_fileset, srcdir)
 
+        # Conflicting files resolved in favor of another part are not staged,
+        # and files resolved in favor of this part replace staged symlinks.
+        files -= self._stage_excludes
+        for filename in files:
+            dst = os.path.join(self._part.stage_dir, filename)
+            if os.path.islink(dst) and collisions.paths_collide(
+                os.path.join(srcdir, filename), dst
+            ):
+                os.remove(dst)
+
         def pkgconfig_fixup(file_path):
             if os.path.islink(file_path):
                 return
@@ -143,7 +254,9 @@ class StepHandler:
             destdir=str(self._part.stage_dir),
             fixup_func=pkgconfig_fixup,
         )
-        return FilesAndDirs(files, dirs)
+        return FilesAndDirs(
+            files | self._overlay_contents.files, dirs | self._overlay_contents.dirs
+        )
 
     def _builtin_prime(self) -> FilesAndDirs:
         prime_fileset = Fileset(self._part.spec.prime_files, name="prime")
@@ -156,6 +269,11 @@ class StepHandler:
 
         srcdir = str(self._part.part_install_dir)
         files, dirs = filesets.migratable_filesets(prime_fileset, srcdir)
+
+        # Files staged from the overlay layer are selected by the overlay
+        # fileset only.
+        files |= self._overlay_contents.files
+        dirs |= self._overlay_contents.dirs
         _migrate_files(
             files=files,
             dirs=dirs,
@@ -173,8 +291,15 @@ class StepHandler:
 
         :param scriptlet: the scriptlet to run.
         :param work_dir: the directory where the script will be executed.
+
+        :raise errors.HostFeatureNotSupported: If scriptlets can't be executed
+            in this host.
         """
-        with tempfile.TemporaryDirectory() as tempdir:
+        host = hosts.get_host_platform()
+        if not host.supports_fifos:
+            raise errors.HostFeatureNotSupported("scriptlets", host=host.name)
+
+        with tempfile.TemporaryDirectory() as tempdir, contextlib.ExitStack() as stack:
             call_fifo = file_utils.NonBlockingRWFifo(
                 os.path.join(tempdir, "function_call")
             )
@@ -205,12 +330,24 @@ class StepHandler:
 
             # FIXME: refactor ctl protocol server
 
+            # The control FIFOs must be reachable from an isolated build.
+            command = stack.enter_context(
+                self._isolate(
+                    [host.get_shell()], cwd=work_dir, bind_dirs=[Path(tempdir)]
+                )
+            )
+
             with tempfile.TemporaryFile(mode="w+") as script_file:
                 print(script, file=script_file)
                 script_file.flush()
                 script_file.seek(0)
                 process = subprocess.Popen(  # pylint: disable=consider-using-with
-                    ["/bin/sh"], stdin=script_file, cwd=work_dir
+                    self._get_command(command),
+                    stdin=script_file,
+                    cwd=work_dir,
+                    env=self._process_env,
+                    stdout=self._stdout,
+                    stderr=self._stderr,
                 )
 
             status = None
@@ -221,11 +358,20 @@ class StepHandler:
                         # Handle the function and let caller know that function
                         # call has been handled (must contain at least a
                         # newline, anything beyond is considered an error by
-                        # snapcraftctl)
-                        self._handle_control_api(scriptlet_name, function_call.strip())
-                        feedback_fifo.write("\n")
+                        # snapcraftctl). Results are prefixed with "OK".
+                        retval = self._handle_control_api(
+                            scriptlet_name, function_call.strip()
+                        )
+                        if retval is None:
+                            feedback_fifo.write("\n")
+                        else:
+                            feedback_fifo.write(f"OK {retval}\n")
 
                     status = process.poll()
+                    if status is None and self._is_timed_out():
+                        process.kill()
+                        process.wait()
+                        raise self._timeout_error()
 
                     # Don't loop TOO busily
                     time.sleep(0.1)
@@ -243,8 +389,89 @@ class StepHandler:
                     exit_code=status,
                 )
 
-    def _handle_control_api(self, scriptlet_name, function_call) -> None:
-        """Parse the message from the client and invoke the appropriate action."""
+    def _get_command(self, command: List[Any]) -> List[Any]:
+        """Add the network isolation prefix to a command if required by the part.
+
+        :param command: The command to execute.
+
+        :return: The command to execute in this step.
+
+        :raise errors.NetworkIsolationError: If network access can't be disabled.
+        """
+        step = self._step_info.step
+        if self._part.spec.has_network_access(step):
+            return command
+
+        prefix = os_utils.get_network_isolation_prefix()
+        if prefix is None:
+            raise errors.NetworkIsolationError(
+                part_name=self._part.name, step_name=step.name.lower()
+            )
+
+        return [*prefix, *command]
+
+    @contextlib.contextmanager
+    def _isolate(
+        self, command: List[Any], *, cwd: Path, bind_dirs: Sequence[Path] = ()
+    ) -> Iterator[List[Any]]:
+        """Isolate a build step command in the base layer if required by the part.
+
+        :param command: The command to execute.
+        :param cwd: The directory to execute the command in.
+        :param bind_dirs: Additional host directories needed by the command.
+
+        :return: The command to execute in this step.
+
+        :raise errors.BuildIsolationError: If the build can't be isolated.
+        """
+        method = self._part.spec.build_isolation
+
+        # Emulated builds run in the base layer of the target architecture.
+        if not method and self._step_info.is_emulated:
+            method = isolation.IsolationMethod.CHROOT.value
+
+        if self._step_info.step != Step.BUILD or not method:
+            yield command
+            return
+
+        with isolation.isolated_command(
+            command,
+            part_name=self._part.name,
+            method=isolation.IsolationMethod(method),
+            root=self._step_info.base_layer_dir,
+            cwd=Path(cwd).absolute(),
+            bind_dirs=[self._step_info.work_dir, *bind_dirs],
+        ) as isolated:
+            yield isolated
+
+    def _is_timed_out(self) -> bool:
+        return self._deadline is not None and time.monotonic() >= self._deadline
+
+    def _get_remaining_time(self) -> Optional[float]:
+        """Obtain the time left to run step commands, in seconds.
+
+        :raise errors.StepTimeoutError: If the step timeout expired.
+        """
+        if self._deadline is None:
+            return None
+
+        if self._is_timed_out():
+            raise self._timeout_error()
+
+        return self._deadline - time.monotonic()
+
+    def _timeout_error(self) -> errors.StepTimeoutError:
+        return errors.StepTimeoutError(
+            part_name=self._part.name,
+            step_name=self._step_info.step.name.lower(),
+            timeout=self._timeout or 0,
+        )
+
+    def _handle_control_api(self, scriptlet_name, function_call) -> Optional[str]:
+        """Parse the message from the client and invoke the appropriate action.
+
+        :return: The result of the call, if any.
+        """
         try:
             function_json = json.loads(function_call)
         except json.decoder.JSONDecodeError as err:
@@ -260,6 +487,10 @@ class StepHandler:
                 )
 
         function_name = function_json["function"]
+        function_args = function_json["args"]
+
+        if function_name in ("get", "set"):
+            return self._handle_value_call(scriptlet_name, function_name, function_args)
 
         if function_name == "pull":
             self._builtin_pull()
@@ -276,6 +507,46 @@ class StepHandler:
                 message=f"invalid function {function_name!r}",
             )
 
+        return None
+
+    def _handle_value_call(
+        self, scriptlet_name: str, function_name: str, args: List[str]
+    ) -> Optional[str]:
+        """Get or set a project variable or a value shared by part scripts."""
+        if len(args) != 1:
+            raise errors.InvalidControlAPICall(
+                part_name=self._part.name,
+                scriptlet_name=scriptlet_name,
+                message=f"{function_name!r} requires exactly one argument",
+            )
+
+        if function_name == "get":
+            value = self._step_info.get_value(args[0])
+            if value is None:
+                raise errors.InvalidControlAPICall(
+                    part_name=self._part.name,
+                    scriptlet_name=scriptlet_name,
+                    message=f"{args[0]!r} is not defined",
+                )
+            return value
+
+        name, sep, value = args[0].partition("=")
+        if not sep:
+            message = f"invalid assignment {args[0]!r}"
+        elif "\n" in value:
+            message = "values cannot contain newlines"
+        else:
+            try:
+                self._step_info.set_value(name, value)
+            except ValueError as err:
+                message = str(err)
+            else:
+                return None
+
+        raise errors.InvalidControlAPICall(
+            part_name=self._part.name, scriptlet_name=scriptlet_name, message=message
+        )
+
 
 def _migrate_files(
     *,
@@ -293,25 +564,30 @@ def _migrate_files(
 
         file_utils.create_similar_directory(src, dst)
 
-    for filename in sorted(files):
+    def migrate_file(filename: str) -> None:
         src = os.path.join(srcdir, filename)
         dst = os.path.join(destdir, filename)
 
         if missing_ok and not os.path.exists(src):
-            continue
+            return
 
         # If the file is already here and it's a symlink, leave it alone.
         if os.path.islink(dst):
-            continue
+            return
 
         # Otherwise, remove and re-link it.
         if os.path.exists(dst):
             os.remove(dst)
 
-        file_utils.link_or_copy(src, dst, follow_symlinks=follow_symlinks)
+        file_utils.reflink_or_link_or_copy(src, dst, follow_symlinks=follow_symlinks)
 
         fixup_func(dst)
 
+    # Files are migrated concurrently, since migrating large trees is
+    # dominated by filesystem latency. Directories were created above.
+    with futures.ThreadPoolExecutor() as pool:
+        list(pool.map(migrate_file, sorted(files)))
+
 
 def _check_conflicts(
     part_name: str, srcdir: str, destdir: str, files: List[str]
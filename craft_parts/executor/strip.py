@@ -0,0 +1,151 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Strip primed ELF binaries and split their debug information."""
+
+import fnmatch
+import logging
+import os
+import re
+import stat
+import subprocess
+from pathlib import Path
+from typing import Iterable, List, Optional
+
+from craft_parts import elf, errors
+
+logger = logging.getLogger(__name__)
+
+_BUILD_ID_PATTERN = re.compile(r"Build ID: ([0-9a-f]+)")
+
+
+def strip_files(
+    *,
+    part_name: str,
+    root: Path,
+    paths: Iterable[str],
+    exclude: List[str],
+    debug_dir: Optional[Path] = None,
+) -> List[str]:
+    """Strip the ELF binaries in a directory.
+
+    Stripped binaries are written to new files, so the files they were
+    migrated from are not modified. If a debug directory is given, the debug
+    information of each binary is saved to ``<debug_dir>/<path>.debug`` and
+    linked from the binary. Binaries containing a build ID can also be found
+    by their ``.build-id/<xx>/<rest>.debug`` symlink in the debug directory.
+
+    :param part_name: The name of the part being processed.
+    :param root: The directory containing the files.
+    :param paths: The files to process, relative to root.
+    :param exclude: Patterns of files that must not be stripped.
+    :param debug_dir: The directory to write debug information to, or None
+        if debug information is not kept.
+
+    :return: The list of stripped files, relative to root.
+
+    :raise errors.StripError: If a binary can't be stripped.
+    """
+    stripped: List[str] = []
+    for path in sorted(paths):
+        if any(fnmatch.fnmatchcase(path, pattern) for pattern in exclude):
+            continue
+
+        filename = root / path
+        if not elf.is_elf(filename):
+            continue
+
+        logger.debug("strip %s", path)
+        _strip_file(filename, part_name=part_name, path=path, debug_dir=debug_dir)
+        stripped.append(path)
+
+    return stripped
+
+
+def _strip_file(
+    filename: Path, *, part_name: str, path: str, debug_dir: Optional[Path]
+) -> None:
+    """Replace a binary with its stripped version."""
+    partial = filename.with_name(f".{filename.name}.partial")
+    try:
+        _run(
+            ["strip", "--strip-unneeded", "-p", "-o", str(partial), str(filename)],
+            part_name=part_name,
+            path=path,
+        )
+
+        if debug_dir:
+            debug_file = debug_dir / f"{path}.debug"
+            debug_file.parent.mkdir(parents=True, exist_ok=True)
+            _run(
+                ["objcopy", "--only-keep-debug", str(filename), str(debug_file)],
+                part_name=part_name,
+                path=path,
+            )
+            _run(
+                ["objcopy", f"--add-gnu-debuglink={debug_file}", str(partial)],
+                part_name=part_name,
+                path=path,
+            )
+            build_id = _get_build_id(filename, part_name=part_name, path=path)
+            if build_id:
+                _link_build_id(debug_file, build_id=build_id, debug_dir=debug_dir)
+
+        os.chmod(partial, stat.S_IMODE(filename.stat().st_mode))
+        os.replace(partial, filename)
+    finally:
+        if partial.exists():
+            partial.unlink()
+
+
+def _get_build_id(filename: Path, *, part_name: str, path: str) -> Optional[str]:
+    """Obtain the build ID of a binary, if any."""
+    output = _run(
+        ["readelf", "--notes", "--wide", str(filename)],
+        part_name=part_name,
+        path=path,
+    )
+    match = _BUILD_ID_PATTERN.search(output)
+    if not match:
+        return None
+
+    return match.group(1)
+
+
+def _link_build_id(debug_file: Path, *, build_id: str, debug_dir: Path) -> None:
+    """Create the build ID symlink to a debug information file."""
+    link = debug_dir / ".build-id" / build_id[:2] / f"{build_id[2:]}.debug"
+    link.parent.mkdir(parents=True, exist_ok=True)
+    if link.is_symlink() or link.exists():
+        link.unlink()
+    link.symlink_to(os.path.relpath(debug_file, link.parent))
+
+
+def _run(command: List[str], *, part_name: str, path: str) -> str:
+    """Execute a binutils command and return its output."""
+    try:
+        proc = subprocess.run(command, check=True, capture_output=True, text=True)
+    except FileNotFoundError as err:
+        raise errors.StripError(
+            part_name=part_name, path=path, message=f"{command[0]!r} is not installed"
+        ) from err
+    except subprocess.CalledProcessError as err:
+        raise errors.StripError(
+            part_name=part_name, path=path, message=err.stderr.strip()
+        ) from err
+
+    return proc.stdout
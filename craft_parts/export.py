@@ -0,0 +1,228 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Package primed files as tarballs and OCI image layers.
+
+Archives are deterministic: entries are sorted, owner names are removed,
+files owned by the user running the lifecycle are owned by root, and
+modification times can be clamped to a timestamp. Overlayfs whiteouts,
+character devices with device number 0 and directories marked opaque
+using extended attributes, are converted to the OCI whiteout format.
+"""
+
+import gzip
+import hashlib
+import io
+import logging
+import os
+import tarfile
+import tempfile
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, Iterator, Optional
+
+from craft_parts.overlays.layers import OPAQUE_WHITEOUT, WHITEOUT_PREFIX
+
+logger = logging.getLogger(__name__)
+
+OCI_LAYER_MEDIA_TYPE = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+_OPAQUE_XATTRS = ("trusted.overlay.opaque", "user.overlay.opaque")
+
+
+@dataclass(frozen=True)
+class OciLayer:
+    """An OCI image layer blob.
+
+    :param digest: The digest of the compressed layer, in the
+        ``sha256:<digest>`` format.
+    :param size: The size of the compressed layer in bytes.
+    :param diff_id: The digest of the uncompressed layer, listed in the
+        ``rootfs`` section of the image configuration.
+    :param path: The path to the layer blob.
+    """
+
+    digest: str
+    size: int
+    diff_id: str
+    path: Path
+
+    @property
+    def media_type(self) -> str:
+        """The layer media type."""
+        return OCI_LAYER_MEDIA_TYPE
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create an OCI content descriptor referencing this layer.
+
+        :return: The descriptor, to be listed in an image manifest.
+        """
+        return {"mediaType": self.media_type, "digest": self.digest, "size": self.size}
+
+
+def create_tarball(
+    root: Path,
+    destination: Path,
+    *,
+    mtime: Optional[int] = None,
+    compress: bool = False,
+) -> None:
+    """Create a deterministic tarball containing the files in a directory.
+
+    :param root: The directory to archive.
+    :param destination: The tarball to create.
+    :param mtime: The maximum modification time of archived files. Newer
+        files are archived with this modification time.
+    :param compress: Whether the tarball is compressed using gzip.
+    """
+    logger.debug("create tarball %s from %s", destination, root)
+    with open(destination, "wb") as output:
+        if compress:
+            with gzip.GzipFile(
+                filename="", mode="wb", fileobj=output, mtime=0
+            ) as gzip_file:
+                _write_tar(root, gzip_file, mtime=mtime)
+        else:
+            _write_tar(root, output, mtime=mtime)
+
+
+def create_oci_layer(
+    root: Path, blobs_dir: Path, *, mtime: Optional[int] = None
+) -> OciLayer:
+    """Create a compressed OCI image layer containing the files in a directory.
+
+    The layer blob is written to the blobs directory of an OCI image layout,
+    named after its digest.
+
+    :param root: The directory to archive.
+    :param blobs_dir: The directory to write the layer blob to, such as the
+        ``blobs/sha256`` directory of an image layout.
+    :param mtime: The maximum modification time of archived files. Newer
+        files are archived with this modification time.
+
+    :return: The created layer.
+    """
+    blobs_dir.mkdir(parents=True, exist_ok=True)
+    with tempfile.NamedTemporaryFile(dir=blobs_dir, delete=False) as output:
+        try:
+            compressed = _HashingWriter(output)
+            with gzip.GzipFile(
+                filename="", mode="wb", fileobj=compressed, mtime=0
+            ) as gzip_file:
+                uncompressed = _HashingWriter(gzip_file)
+                _write_tar(root, uncompressed, mtime=mtime)
+        except BaseException:
+            os.unlink(output.name)
+            raise
+
+    blob = blobs_dir / compressed.hash.hexdigest()
+    os.replace(output.name, blob)
+    os.chmod(blob, 0o644)
+
+    return OciLayer(
+        digest=f"sha256:{compressed.hash.hexdigest()}",
+        size=compressed.size,
+        diff_id=f"sha256:{uncompressed.hash.hexdigest()}",
+        path=blob,
+    )
+
+
+class _HashingWriter(io.RawIOBase):
+    """A file object computing the digest of the data written to it."""
+
+    def __init__(self, output):
+        super().__init__()
+        self.output = output
+        self.hash = hashlib.sha256()
+        self.size = 0
+
+    def writable(self) -> bool:
+        return True
+
+    def write(self, data) -> int:
+        self.hash.update(data)
+        self.size += len(data)
+        self.output.write(data)
+        return len(data)
+
+
+def _write_tar(root: Path, fileobj, *, mtime: Optional[int]) -> None:
+    """Write the directory contents to a tar stream."""
+    with tarfile.open(fileobj=fileobj, mode="w|", format=tarfile.PAX_FORMAT) as tar:
+        for path in _walk(root):
+            arcname = str(path.relative_to(root))
+            filename = str(path)
+            info = tar.gettarinfo(filename, arcname=arcname)
+            info = _normalize(info, mtime=mtime)
+
+            if info.ischr() and info.devmajor == 0 and info.devminor == 0:
+                name = os.path.join(os.path.dirname(arcname), WHITEOUT_PREFIX)
+                tar.addfile(_whiteout(name + path.name, info))
+                continue
+
+            if info.isreg():
+                with open(filename, "rb") as file:
+                    tar.addfile(info, file)
+            else:
+                tar.addfile(info)
+
+            if info.isdir() and _is_opaque(filename):
+                tar.addfile(_whiteout(os.path.join(arcname, OPAQUE_WHITEOUT), info))
+
+
+def _walk(root: Path) -> Iterator[Path]:
+    """Iterate over the entries in a directory tree in sorted order."""
+    for entry in sorted(os.scandir(root), key=lambda x: x.name):
+        path = Path(entry.path)
+        yield path
+        if entry.is_dir(follow_symlinks=False):
+            yield from _walk(path)
+
+
+def _normalize(info: tarfile.TarInfo, *, mtime: Optional[int]) -> tarfile.TarInfo:
+    """Remove host-specific details from a tar entry."""
+    if info.uid == os.getuid():
+        info.uid = 0
+    if info.gid == os.getgid():
+        info.gid = 0
+    info.uname = ""
+    info.gname = ""
+    info.mtime = int(info.mtime)
+    if mtime is not None:
+        info.mtime = min(info.mtime, mtime)
+    return info
+
+
+def _whiteout(name: str, info: tarfile.TarInfo) -> tarfile.TarInfo:
+    """Create an OCI whiteout entry."""
+    whiteout = tarfile.TarInfo(name)
+    whiteout.mode = 0o644
+    whiteout.uid = info.uid
+    whiteout.gid = info.gid
+    whiteout.mtime = info.mtime
+    return whiteout
+
+
+def _is_opaque(path: str) -> bool:
+    """Verify whether a directory is marked opaque in an overlayfs upper layer."""
+    for key in _OPAQUE_XATTRS:
+        try:
+            if os.getxattr(path, key, follow_symlinks=False) == b"y":
+                return True
+        except OSError:
+            continue
+    return False
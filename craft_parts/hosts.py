@@ -0,0 +1,164 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Host operating system abstraction.
+
+Parts are built on Linux hosts, but part definitions can be developed on
+macOS and Windows hosts: parts that don't need system packages, overlays
+or build isolation can be planned and processed there. Operations that
+depend on the host operating system are provided by the host platform,
+so that features not available in a host are disabled consistently.
+"""
+
+import os
+import platform
+import shutil
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Type
+
+from craft_parts import errors
+
+# Machine names used by non-Linux hosts for architectures known to craft-parts.
+_MACHINE_ALIASES = {
+    "amd64": "x86_64",
+    "x64": "x86_64",
+    "arm64": "aarch64",
+}
+
+
+class HostPlatform:
+    """Operations and features of a POSIX host operating system.
+
+    Features specific to Linux are not available in the base platform.
+    """
+
+    name = sys.platform
+
+    # Whether system packages and snaps can be installed and staged.
+    supports_packages = False
+
+    # Whether overlay filesystems can be mounted and chroots created.
+    supports_overlays = False
+
+    # Whether builds and network access can be isolated from the host.
+    supports_isolation = False
+
+    # Whether file extended attributes can be read and written.
+    supports_xattrs = False
+
+    # Whether named pipes can be created to serve control calls.
+    supports_fifos = True
+
+    def get_machine(self) -> str:
+        """Obtain the host machine architecture.
+
+        :return: The architecture name, as used in Linux hosts.
+        """
+        machine = platform.machine()
+        return _MACHINE_ALIASES.get(machine.lower(), machine)
+
+    def is_root(self) -> bool:
+        """Verify whether the current process has superuser privileges."""
+        return os.geteuid() == 0
+
+    def get_shell(self) -> str:
+        """Obtain the shell used to execute step scripts.
+
+        :return: The path to the shell.
+
+        :raise errors.HostFeatureNotSupported: If no shell is available.
+        """
+        return "/bin/sh"
+
+    def get_script_command(self, script: Path) -> List[Any]:
+        """Obtain the command to execute a step script.
+
+        :param script: The path to the executable script.
+
+        :return: The command to execute the script.
+        """
+        return [script]
+
+
+class LinuxHost(HostPlatform):
+    """A Linux host, supporting all craft-parts features."""
+
+    name = "Linux"
+    supports_packages = True
+    supports_overlays = True
+    supports_isolation = True
+    supports_xattrs = True
+
+
+class MacOSHost(HostPlatform):
+    """A macOS host."""
+
+    name = "macOS"
+
+
+class WindowsHost(HostPlatform):
+    """A Windows host.
+
+    Step scripts are executed by a POSIX shell installed in the host, such
+    as the one provided by Git for Windows. Named pipes are not available,
+    so scriptlets can't be executed.
+    """
+
+    name = "Windows"
+    supports_fifos = False
+
+    def is_root(self) -> bool:
+        """Verify whether the current process has superuser privileges."""
+        return False
+
+    def get_shell(self) -> str:
+        """Obtain the shell used to execute step scripts.
+
+        :return: The path to the shell.
+
+        :raise errors.HostFeatureNotSupported: If no shell is available.
+        """
+        shell = shutil.which("sh")
+        if not shell:
+            raise errors.HostFeatureNotSupported("step scripts", host=self.name)
+        return shell
+
+    def get_script_command(self, script: Path) -> List[Any]:
+        """Obtain the command to execute a step script.
+
+        :param script: The path to the script.
+
+        :return: The command to execute the script.
+        """
+        return [self.get_shell(), script]
+
+
+_HOSTS: Dict[str, Type[HostPlatform]] = {
+    "linux": LinuxHost,
+    "darwin": MacOSHost,
+    "win32": WindowsHost,
+}
+
+
+def get_host_platform() -> HostPlatform:
+    """Obtain the platform of the host running craft-parts.
+
+    :return: The host platform. Unknown hosts are handled as generic POSIX
+        hosts without Linux-specific features.
+    """
+    return _HOSTS.get(sys.platform, HostPlatform)()
@@ -17,17 +17,26 @@
 """Project, part and step information classes."""
 
 import logging
-import platform
+import re
+import threading
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Sequence
 
-from craft_parts import errors, utils
+from craft_parts import bases, errors, hosts, utils
+from craft_parts.actions import Action
+from craft_parts.compiler_cache import CompilerCacheConfig
 from craft_parts.dirs import ProjectDirs
+from craft_parts.packages.lockfile import LockfileMode
 from craft_parts.parts import Part
+from craft_parts.proxy import ProxyConfig
+from craft_parts.sources.mirrors import MirrorRule
+from craft_parts.step_cache import StepCacheBackend
 from craft_parts.steps import Step
 
 logger = logging.getLogger(__name__)
 
+_VALUE_NAME_PATTERN = re.compile(r"^[A-Za-z_][A-Za-z0-9_-]*$")
+
 
 class ProjectInfo:
     """Project-level information containing project-specific fields.
@@ -39,6 +48,42 @@ class ProjectInfo:
     :param parallel_build_count: The maximum number of concurrent jobs to be
         used to build each part of this project.
     :param project_dirs: The project work directories.
+    :param project_name: The name of the project.
+    :param project_vars: A dictionary containing project variables, such as
+        the project version.
+    :param cache_dir: The location of the cache for downloaded files. Defaults
+        to a cache specific to the application.
+    :param cache_size_limit: The maximum size of the download cache in bytes.
+        If not specified, the cache size is not limited.
+    :param source_mirrors: The rules used to rewrite source URLs to mirrors,
+        in order of precedence.
+    :param step_cache_backend: The backend used to store step outputs. If
+        not specified, step outputs are not cached.
+    :param step_timeouts: The default timeout in seconds for each step.
+    :param step_retries: The default number of times to retry each step.
+    :param retry_delay: The delay in seconds before the first retry of a
+        failed step. The delay doubles on each subsequent retry.
+    :param source_date_epoch: The timestamp to export as ``SOURCE_DATE_EPOCH``
+        to all parts. If not set, it's derived from the source of each part.
+    :param normalize_prime: Whether to clamp modification times and remove
+        extended attributes of primed files.
+    :param proxy: The proxies used by lifecycle operations and exported to
+        the environment of all steps. If not set, proxies defined in the
+        environment of the application are used.
+    :param compiler_cache: The compiler cache used by plugins that compile
+        native code. If not set, compilations are not cached.
+    :param base_layer_dir: The root filesystem used to build parts with
+        build isolation.
+    :param emulation: Whether build commands run under qemu-user emulation
+        if the target architecture is different from the host architecture.
+    :param base: The base the project is built on, such as ``core24`` or
+        ``ubuntu@24.04``. If not set, the base of the host is used to select
+        the package backend.
+    :param features: A dictionary containing the state of feature flags.
+    :param packages_lockfile: The lockfile of the exact stage packages
+        fetched by each part. If not set, stage packages are not locked.
+    :param packages_lockfile_mode: Whether fetched stage packages are
+        recorded in the lockfile, or the locked packages are fetched.
     :param custom_args: Any additional arguments defined by the application
         when creating a :class:`LifecycleManager`.
     """
@@ -50,6 +95,25 @@ class ProjectInfo:
         arch: str = "",
         parallel_build_count: int = 1,
         project_dirs: ProjectDirs = None,
+        project_name: Optional[str] = None,
+        project_vars: Optional[Dict[str, str]] = None,
+        cache_dir: Optional[Path] = None,
+        cache_size_limit: Optional[int] = None,
+        source_mirrors: Optional[Sequence[MirrorRule]] = None,
+        step_cache_backend: Optional[StepCacheBackend] = None,
+        step_timeouts: Optional[Dict[Step, float]] = None,
+        step_retries: Optional[Dict[Step, int]] = None,
+        retry_delay: float = 1.0,
+        source_date_epoch: Optional[int] = None,
+        normalize_prime: bool = False,
+        proxy: Optional[ProxyConfig] = None,
+        compiler_cache: Optional[CompilerCacheConfig] = None,
+        base_layer_dir: Optional[Path] = None,
+        emulation: bool = False,
+        base: Optional[str] = None,
+        features: Optional[Dict[str, bool]] = None,
+        packages_lockfile: Optional[Path] = None,
+        packages_lockfile_mode: LockfileMode = LockfileMode.RECORD,
         **custom_args,  # custom passthrough args
     ):
         if not project_dirs:
@@ -59,6 +123,28 @@ class ProjectInfo:
         self._set_machine(arch)
         self._parallel_build_count = parallel_build_count
         self._dirs = project_dirs
+        self._project_name = project_name
+        self._project_vars = dict(project_vars or {})
+        self._run_values: Dict[str, str] = {}
+        self._run_values_lock = threading.Lock()
+        self._cache_dir = cache_dir
+        self._cache_size_limit = cache_size_limit
+        self._source_mirrors = list(source_mirrors or [])
+        self._step_cache_backend = step_cache_backend
+        self._step_timeouts = dict(step_timeouts or {})
+        self._step_retries = dict(step_retries or {})
+        self._retry_delay = retry_delay
+        self._source_date_epoch = source_date_epoch
+        self._normalize_prime = normalize_prime
+        self._proxy = proxy
+        self._compiler_cache = compiler_cache
+        self._base_layer_dir = base_layer_dir
+        self._emulation = emulation
+        self._base = base
+        self._build_base = bases.parse_base(base) if base else bases.get_host_base()
+        self._features = dict(features or {})
+        self._packages_lockfile = packages_lockfile
+        self._packages_lockfile_mode = packages_lockfile_mode
         self._custom_args = custom_args
 
     def __getattr__(self, name):
@@ -85,10 +171,33 @@ class ProjectInfo:
         """Return the machine-vendor-os platform triplet definition."""
         return self._machine["triplet"]
 
+    @property
+    def cross_compiler_prefix(self) -> str:
+        """Return the prefix of the cross-compiler toolchain for the target."""
+        return self._machine.get("cross-compiler-prefix", "")
+
+    @property
+    def host_arch(self) -> str:
+        """Return the host architecture used for debs, snaps and charms.
+
+        Emulated builds run on the target architecture.
+        """
+        if self.is_emulated:
+            return self.target_arch
+        return _ARCH_TRANSLATIONS[self._host_arch]["deb"]
+
     @property
     def is_cross_compiling(self) -> bool:
-        """Whether the target and host architectures are different."""
-        return self._arch != self._host_arch
+        """Whether the target and host architectures are different.
+
+        Emulated builds are not cross-compiled.
+        """
+        return self._arch != self._host_arch and not self._emulation
+
+    @property
+    def is_emulated(self) -> bool:
+        """Whether build commands run under emulation of the target architecture."""
+        return self._arch != self._host_arch and self._emulation
 
     @property
     def parallel_build_count(self) -> int:
@@ -105,6 +214,184 @@ class ProjectInfo:
         """Return the project's work directories."""
         return self._dirs
 
+    @property
+    def project_name(self) -> Optional[str]:
+        """Return the name of the project."""
+        return self._project_name
+
+    @property
+    def project_vars(self) -> Dict[str, str]:
+        """Return the project variables."""
+        return self._project_vars.copy()
+
+    def set_project_var(self, name: str, value: str) -> None:
+        """Set the value of a project variable.
+
+        :param name: The project variable name.
+        :param value: The new project variable value.
+        """
+        self._project_vars[name] = value
+
+    @property
+    def run_values(self) -> Dict[str, str]:
+        """Return the values set by part scripts during this run."""
+        with self._run_values_lock:
+            return self._run_values.copy()
+
+    def get_value(self, name: str) -> Optional[str]:
+        """Obtain a project variable or a value set by part scripts.
+
+        :param name: The project variable or value name.
+
+        :return: The value, or None if not defined.
+        """
+        if name in self._project_vars:
+            return self._project_vars[name]
+        with self._run_values_lock:
+            return self._run_values.get(name)
+
+    def set_value(self, name: str, value: str) -> None:
+        """Set a project variable or a value shared by part scripts.
+
+        If the name is not a project variable, the value can be read by later
+        steps of any part. Values are stored in the state of the step that set
+        them and restored when that step is skipped in a later run.
+
+        :param name: The project variable or value name.
+        :param value: The new value.
+
+        :raise ValueError: If the name is not valid.
+        """
+        if name in self._project_vars:
+            self._project_vars[name] = value
+            return
+
+        if not _VALUE_NAME_PATTERN.match(name):
+            raise ValueError(f"invalid name {name!r}")
+        with self._run_values_lock:
+            self._run_values[name] = value
+
+    @property
+    def cache_dir(self) -> Optional[Path]:
+        """Return the location of the download cache, if set."""
+        return self._cache_dir
+
+    @property
+    def cache_size_limit(self) -> Optional[int]:
+        """Return the maximum size of the download cache, if limited."""
+        return self._cache_size_limit
+
+    @property
+    def source_mirrors(self) -> List[MirrorRule]:
+        """Return the rules used to rewrite source URLs to mirrors."""
+        return self._source_mirrors.copy()
+
+    @property
+    def step_cache_backend(self) -> Optional[StepCacheBackend]:
+        """Return the backend used to store step outputs, if set."""
+        return self._step_cache_backend
+
+    @property
+    def step_timeouts(self) -> Dict[Step, float]:
+        """Return the default step timeouts in seconds."""
+        return self._step_timeouts.copy()
+
+    @property
+    def step_retries(self) -> Dict[Step, int]:
+        """Return the default number of retries of each step."""
+        return self._step_retries.copy()
+
+    @property
+    def retry_delay(self) -> float:
+        """Return the delay in seconds before the first retry of a step."""
+        return self._retry_delay
+
+    @property
+    def source_date_epoch(self) -> Optional[int]:
+        """Return the timestamp to export as ``SOURCE_DATE_EPOCH``, if set."""
+        return self._source_date_epoch
+
+    @property
+    def normalize_prime(self) -> bool:
+        """Whether the metadata of primed files is normalized."""
+        return self._normalize_prime
+
+    @property
+    def proxy(self) -> Optional[ProxyConfig]:
+        """Return the proxy configuration, if set."""
+        return self._proxy
+
+    @property
+    def compiler_cache(self) -> Optional[CompilerCacheConfig]:
+        """Return the compiler cache configuration, if enabled."""
+        return self._compiler_cache
+
+    @property
+    def base_layer_dir(self) -> Optional[Path]:
+        """Return the root filesystem used by isolated builds, if set."""
+        return self._base_layer_dir
+
+    @property
+    def base(self) -> Optional[str]:
+        """Return the project base, if set."""
+        return self._base
+
+    @property
+    def build_base(self) -> Optional[bases.Base]:
+        """Return the base parts are built on.
+
+        If the project base is not set, this is the base of the host, or
+        None if it can't be determined.
+        """
+        return self._build_base
+
+    @property
+    def features(self) -> Dict[str, bool]:
+        """Return the state of feature flags set for this project."""
+        return self._features.copy()
+
+    def is_feature_enabled(self, name: str) -> bool:
+        """Verify whether a feature flag is enabled.
+
+        :param name: The feature flag name.
+
+        :return: Whether the feature is enabled. Features that are not set
+            are disabled.
+        """
+        return self._features.get(name, False)
+
+    @property
+    def overlay_enabled(self) -> bool:
+        """Whether the overlay step is part of the lifecycle.
+
+        The overlay step is enabled by the ``overlay`` feature flag.
+        """
+        return self.is_feature_enabled("overlay")
+
+    @property
+    def packages_lockfile(self) -> Optional[Path]:
+        """Return the stage packages lockfile, if set."""
+        return self._packages_lockfile
+
+    @property
+    def packages_lockfile_mode(self) -> LockfileMode:
+        """Return how the stage packages lockfile is used."""
+        return self._packages_lockfile_mode
+
+    @property
+    def project_environment(self) -> Dict[str, str]:
+        """Return the environment variables defined from project information.
+
+        Variables are named ``CRAFT_PROJECT_NAME`` for the project name and
+        ``CRAFT_PROJECT_<NAME>`` for each project variable.
+        """
+        env: Dict[str, str] = {}
+        if self._project_name:
+            env["CRAFT_PROJECT_NAME"] = self._project_name
+        for name, value in self._project_vars.items():
+            env[f"CRAFT_PROJECT_{name.upper()}"] = value
+        return env
+
     @property
     def project_options(self) -> Dict[str, Any]:
         """Obtain a project-wide options dictionary."""
@@ -112,6 +399,8 @@ class ProjectInfo:
             "application_name": self.application_name,
             "arch_triplet": self.arch_triplet,
             "target_arch": self.target_arch,
+            "project_name": self.project_name,
+            "project_vars": self.project_vars,
         }
 
     def _set_machine(self, arch: Optional[str]):
@@ -153,6 +442,7 @@ class PartInfo:
         self._part_build_subdir = part.part_build_subdir
         self._part_install_dir = part.part_install_dir
         self._part_state_dir = part.part_state_dir
+        self._part_dependencies = list(part.spec.after)
 
     def __getattr__(self, name):
         # Use composition and attribute cascading to avoid setting attributes
@@ -197,17 +487,31 @@ class PartInfo:
         """Return the subdirectory containing this part's lifecycle state."""
         return self._part_state_dir
 
+    @property
+    def part_dependencies(self) -> List[str]:
+        """Return the names of the parts this part runs after."""
+        return self._part_dependencies.copy()
+
 
 class StepInfo:
-    """Step-level information containing project, part, and step fields."""
+    """Step-level information containing project, part, and step fields.
+
+    :param part_info: The part information.
+    :param step: The step to execute.
+    :param action: The action executing the step, if any.
+    """
 
     def __init__(
         self,
         part_info: PartInfo,
         step: Step,
+        *,
+        action: Optional[Action] = None,
     ):
         self._part_info = part_info
         self.step = step
+        self.action = action
+        self._values: Dict[str, str] = {}
 
     def __getattr__(self, name):
         if hasattr(self._part_info, name):
@@ -215,11 +519,30 @@ class StepInfo:
 
         raise AttributeError(f"{self.__class__.__name__!r} has no attribute {name!r}")
 
+    @property
+    def values(self) -> Dict[str, str]:
+        """Return the values set by part scripts during this step."""
+        return self._values.copy()
+
+    def set_value(self, name: str, value: str) -> None:
+        """Set a project variable or a value shared by part scripts.
+
+        Values that are not project variables are also recorded as set
+        by this step, so they can be stored in the step state.
+
+        :param name: The project variable or value name.
+        :param value: The new value.
+
+        :raise ValueError: If the name is not valid.
+        """
+        self._part_info.set_value(name, value)
+        if name not in self._part_info.project_vars:
+            self._values[name] = value
+
 
 def _get_host_architecture() -> str:
     """Obtain the host system architecture."""
-    # TODO: handle Windows architectures
-    return platform.machine()
+    return hosts.get_host_platform().get_machine()
 
 
 _ARCH_TRANSLATIONS: Dict[str, Dict[str, Any]] = {
@@ -16,16 +16,65 @@
 
 """The parts lifecycle manager."""
 
-from typing import Any, Dict, List, Sequence
+import json
+from pathlib import Path
+from typing import (
+    Any,
+    Callable,
+    Dict,
+    Iterator,
+    List,
+    Optional,
+    Sequence,
+    Set,
+    Type,
+    Union,
+)
 
 from pydantic import ValidationError
+from xdg import BaseDirectory  # type: ignore
 
-from craft_parts import errors, plugins, sequencer
+from craft_parts import (
+    errors,
+    export,
+    overlays,
+    packages,
+    plugins,
+    provenance,
+    prune,
+    sbom,
+    sequencer,
+    sources,
+)
 from craft_parts.actions import Action
+from craft_parts.compiler_cache import CompilerCacheConfig
+from craft_parts.config import PartsConfig
 from craft_parts.dirs import ProjectDirs
-from craft_parts.infos import ProjectInfo
-from craft_parts.parts import Part
+from craft_parts.executor import ExecutionContext, Executor
+from craft_parts.executor.description import StepDescription, describe_step
+from craft_parts.executor.output import log_file_path
+from craft_parts.export import OciLayer
+from craft_parts.infos import PartInfo, ProjectInfo
+from craft_parts.packages.lockfile import LockfileMode
+from craft_parts.parts import (
+    Part,
+    apply_template,
+    expand_variables,
+    part_dependencies,
+    part_list_by_name,
+    resolve_conditional_properties,
+)
+from craft_parts.plugins import PluginProperties
+from craft_parts.proxy import ProxyConfig
+from craft_parts.sbom import SbomFormat
+from craft_parts.sources import mirrors
+from craft_parts.state_manager import StateManager, state_info
+from craft_parts.state_manager.reports import DirtyReport, OutdatedReport
+from craft_parts.state_manager.state_info import PartStateInfo
+from craft_parts.step_cache import StepCacheBackend
 from craft_parts.steps import Step
+from craft_parts.utils import formatting_utils
+from craft_parts.watch import SourceWatcher
 
 
 class LifecycleManager:
@@ -37,7 +86,9 @@ class LifecycleManager:
 
     :param all_parts: A dictionary containing the parts specification according
         to the :ref:`parts schema<parts-schema>`. The format is compatible with the
-        output generated by PyYAML's ``yaml.load``.
+        output generated by PyYAML's ``yaml.load``. Reusable part templates
+        can be defined in ``templates``, and parts extend a template by
+        setting its name in the ``extends`` property.
     :param application_name: A unique identifier for the application using Craft
         Parts. This string will be used as segregated directory path when creating
         persistent data that shouldn't be shared with other applications.
@@ -46,9 +97,137 @@ class LifecycleManager:
     :param arch: The architecture to build for. Defaults to the host system
         architecture.
     :param parallel_build_count: The maximum number of concurrent jobs to be
-        used to build each part of this project.
+        used to build each part of this project. Defaults to 1.
+    :param max_parallel_parts: The maximum number of parts to process
+        concurrently. Actions of parts that don't depend on each other are
+        executed in parallel, with the output of each part written when
+        its action finishes. Defaults to 1.
+    :param project_name: The name of the project.
+    :param project_vars: A dictionary containing project variables, such as
+        the project version. Variables are available to parts as
+        ``CRAFT_PROJECT_<NAME>``.
+    :param spec_vars: A dictionary containing variables expanded in part
+        properties, such as source URLs, plugin options and organize
+        targets, when referenced as ``$NAME`` or ``${NAME}``. The project
+        name and project variables can also be referenced as
+        ``$CRAFT_PROJECT_NAME`` and ``$CRAFT_PROJECT_<NAME>``. Since parts
+        are processed with the expanded properties, changing the value of a
+        variable makes the steps of the parts using it dirty.
+    :param plugins_dir: A directory containing plugins provided by the project.
+        Each python file in this directory defines a plugin named after the
+        file, with underscores replaced by dashes. Plugins provided by the
+        project or by packages remain registered until :meth:`close` is
+        called or the lifecycle manager is used as a context manager and
+        the context exits.
+    :param cache_dir: The location of the cache for downloaded source files.
+        The cache can be shared by different applications. Defaults to a
+        cache specific to the application.
+    :param cache_size_limit: The maximum size of the download cache in bytes.
+        When exceeded, the least recently used files are removed.
+    :param source_mirrors: A list of :class:`MirrorRule` objects used to
+        rewrite source URLs to mirrors before pulling. The first matching
+        rule is used. Rules set in the ``CRAFT_SOURCE_MIRRORS`` environment
+        variable are tried after these, followed by rules set in the
+        configuration.
+    :param step_cache_backend: A :class:`StepCacheBackend` used to store the
+        outputs of pull and build steps. Steps with a matching fingerprint
+        are restored from the cache instead of executed.
+    :param step_timeouts: A dictionary containing the default timeout in
+        seconds of each step. Steps that don't finish in time fail with
+        :class:`StepTimeoutError`. Parts can override these defaults using
+        the ``step-timeouts`` property.
+    :param step_retries: A dictionary containing the default number of times
+        each failed step is retried. Parts can override these defaults using
+        the ``step-retries`` property.
+    :param retry_delay: The delay in seconds before the first retry of a
+        failed step. The delay doubles on each subsequent retry.
+    :param source_date_epoch: The timestamp exported to all parts as
+        ``SOURCE_DATE_EPOCH``. If not set, the timestamp is derived from the
+        pulled source of each part, such as the time of a git commit, and
+        exported to steps after pull. Applications honoring a
+        ``SOURCE_DATE_EPOCH`` set in their environment should pass it here.
+    :param normalize_prime: Whether files migrated to the prime directory
+        have their modification times clamped to the source timestamp of the
+        part and their extended attributes removed, so that runs of the same
+        project produce identical prime trees.
+    :param proxy: A :class:`ProxyConfig` with the proxies used to download
+        sources and packages, and exported as ``http_proxy``, ``https_proxy``
+        and ``no_proxy`` to the environment of all steps. Use
+        :meth:`ProxyConfig.from_environment` to obtain the proxies set in the
+        application environment. If not set, the application environment is
+        not changed.
+    :param compiler_cache: A :class:`CompilerCacheConfig` enabling ccache and
+        sccache in plugins that compile C, C++ and Rust code. The cache is
+        shared by all projects of the application and kept across rebuilds.
+        If not set, compilations are not cached.
+    :param base_layer_dir: The root filesystem, such as an unpacked base
+        image, used to build parts that set ``build-isolation`` to ``chroot``
+        or ``bubblewrap``. The project work directory is bind-mounted in it
+        during the build. If the ``overlay`` feature is enabled, this is also
+        the base layer the overlay layers of parts are stacked on.
+    :param base_image: A reference to an OCI image in a container registry,
+        or the path to a local oci-archive file, pinned to a digest. The
+        image is unpacked to the cache directory and used as the base layer
+        directory, unless ``base_layer_dir`` is set. Overlay layers created
+        on top of an image are cached and reused by other projects of the
+        application with the same overlay definitions.
+    :param base_image_digest: The manifest digest of the base image, if not
+        included in the image reference.
+    :param emulation: Whether the build step of parts runs under qemu-user
+        emulation if the target architecture is different from the host
+        architecture, instead of cross-compiling. Builds are isolated in the
+        base layer directory, which must contain a root filesystem of the
+        target architecture, using ``chroot`` unless the part sets another
+        ``build-isolation`` method. Plugins build as if natively.
+    :param base: The base the project is built on, such as ``core24`` or
+        ``ubuntu@24.04``. Parts can set properties for specific bases and
+        target architectures using conditional blocks listed in the ``when``
+        part property. The base selects the backend used to handle system
+        packages. If not set, the base is detected from the host os-release
+        information.
+    :param plugin_entry_point_group: The name of an entry point group used by
+        installed packages to provide plugins. Entry point names are plugin
+        names and entry point objects are plugin classes.
+    :param config: A :class:`PartsConfig` with defaults for the cache
+        location and size limit, parallelism, proxies, source mirrors and
+        feature flags. Arguments passed to the lifecycle manager take
+        precedence over the configuration. Configuration files are not read
+        unless a configuration obtained with :func:`load_config` is passed.
+        The ``overlay`` feature flag adds the overlay step to the lifecycle,
+        in which the ``overlay-packages`` of each part are installed and its
+        ``overlay-script`` runs in an overlay of the base layer. Files in the
+        layer selected by the ``overlay`` fileset are staged and primed.
+    :param prune_removed_parts: Whether the work directories and migrated
+        files of parts that are no longer defined are removed before actions
+        are executed. See :meth:`prune_removed_parts`.
+    :param prefix_output: Whether each line of output of step commands is
+        prefixed with the part name and step, as in ``[part:step]``. The
+        output of each step is also written to a log file regardless of this
+        option, see :meth:`get_step_log`.
+    :param strict_validation: Whether plugin options that are not used by
+        the plugin selected by a part are rejected. By default, options of
+        plugins that don't declare their properties are ignored.
+    :param packages_lockfile: The path of a lockfile with the name, version
+        and checksum of all stage packages fetched by each part, including
+        dependencies. If not set, stage packages are not locked.
+    :param packages_lockfile_mode: A :class:`LockfileMode`. In record mode,
+        the packages fetched when pulling a part replace the locked packages
+        of the part. In replay mode, exactly the locked packages are fetched,
+        and pulling fails if a stage package of the part is not locked, or if
+        the fetched packages don't match the lockfile.
     :param custom_args: Any additional arguments that will be passed directly
         to :ref:`callbacks<callbacks>`.
+
+    :raise PluginLoadError: If a project or package plugin cannot be loaded.
+    :raise InvalidMirrorRule: If a mirror rule in the environment or in the
+        configuration is malformed.
+    :raise ConfigurationError: If a configuration file is not valid.
+    :raise InvalidBase: If the base name is malformed.
+    :raise UnsupportedBase: If parts use system packages and there's no
+        package backend for the base.
+    :raise FeatureNotEnabled: If parts set overlay properties and the
+        ``overlay`` feature is not enabled.
+    :raise OverlayBaseImageError: If the base image is not pinned to a digest.
     """
 
     def __init__(
@@ -58,26 +237,158 @@ class LifecycleManager:
         application_name: str,
         work_dir: str = ".",
         arch: str = "",
-        parallel_build_count: int = 1,
+        parallel_build_count: Optional[int] = None,
+        max_parallel_parts: Optional[int] = None,
+        project_name: Optional[str] = None,
+        project_vars: Optional[Dict[str, str]] = None,
+        spec_vars: Optional[Dict[str, str]] = None,
+        plugins_dir: Optional[str] = None,
+        plugin_entry_point_group: Optional[str] = None,
+        cache_dir: Optional[Union[Path, str]] = None,
+        cache_size_limit: Optional[int] = None,
+        source_mirrors: Optional[Sequence[mirrors.MirrorRule]] = None,
+        step_cache_backend: Optional[StepCacheBackend] = None,
+        step_timeouts: Optional[Dict[Step, float]] = None,
+        step_retries: Optional[Dict[Step, int]] = None,
+        retry_delay: float = 1.0,
+        source_date_epoch: Optional[int] = None,
+        normalize_prime: bool = False,
+        proxy: Optional[ProxyConfig] = None,
+        compiler_cache: Optional[CompilerCacheConfig] = None,
+        base_layer_dir: Optional[Union[Path, str]] = None,
+        base_image: Optional[str] = None,
+        base_image_digest: Optional[str] = None,
+        emulation: bool = False,
+        base: Optional[str] = None,
+        config: Optional[PartsConfig] = None,
+        prune_removed_parts: bool = False,
+        strict_validation: bool = False,
+        prefix_output: bool = False,
+        packages_lockfile: Optional[Union[Path, str]] = None,
+        packages_lockfile_mode: LockfileMode = LockfileMode.RECORD,
         **custom_args,  # custom passthrough args
     ):
         # TODO: validate or slugify application name
 
+        if config is None:
+            config = PartsConfig()
+
+        if cache_dir is None:
+            cache_dir = config.cache_dir
+        if cache_size_limit is None:
+            cache_size_limit = config.cache_size_limit
+        if parallel_build_count is None:
+            parallel_build_count = config.parallel_build_count or 1
+        if max_parallel_parts is None:
+            max_parallel_parts = config.max_parallel_parts or 1
+        if proxy is None:
+            proxy = config.get_proxy_config()
+
         project_dirs = ProjectDirs(work_dir=work_dir)
 
+        if base_image and not base_layer_dir:
+            base_layer_dir = overlays.unpack_base_image(
+                base_image,
+                cache_dir=(
+                    Path(cache_dir)
+                    if cache_dir
+                    else Path(
+                        BaseDirectory.xdg_cache_home, application_name, "craft-parts"
+                    )
+                ),
+                digest=base_image_digest,
+            )
+
         project_info = ProjectInfo(
             application_name=application_name,
             arch=arch,
             parallel_build_count=parallel_build_count,
             project_dirs=project_dirs,
+            project_name=project_name,
+            project_vars=project_vars,
+            cache_dir=Path(cache_dir) if cache_dir else None,
+            cache_size_limit=cache_size_limit,
+            source_mirrors=[
+                *(source_mirrors or []),
+                *mirrors.get_environment_rules(),
+                *config.get_mirror_rules(),
+            ],
+            step_cache_backend=step_cache_backend,
+            step_timeouts=step_timeouts,
+            step_retries=step_retries,
+            retry_delay=retry_delay,
+            source_date_epoch=source_date_epoch,
+            normalize_prime=normalize_prime,
+            proxy=proxy,
+            compiler_cache=compiler_cache,
+            base_layer_dir=Path(base_layer_dir) if base_layer_dir else None,
+            emulation=emulation,
+            base=base,
+            features=config.features,
+            packages_lockfile=Path(packages_lockfile) if packages_lockfile else None,
+            packages_lockfile_mode=packages_lockfile_mode,
             **custom_args,
         )
 
         parts_data = all_parts.get("parts", {})
+        templates = all_parts.get("templates", {})
+        variables = {**(spec_vars or {}), **project_info.project_environment}
+
+        def resolve_spec(name: str, spec: Dict[str, Any]) -> Dict[str, Any]:
+            spec = apply_template(name, spec, templates)
+            spec = resolve_conditional_properties(
+                name, spec, arch=project_info.target_arch, base=project_info.build_base
+            )
+            return expand_variables(spec, variables)
+
+        # Project plugins take precedence over plugins provided by packages.
+        project_plugins: Dict[str, Type[plugins.Plugin]] = {}
+        if plugin_entry_point_group:
+            project_plugins.update(
+                plugins.load_plugins_from_entry_points(plugin_entry_point_group)
+            )
+        if plugins_dir:
+            project_plugins.update(plugins.load_plugins_from_dir(plugins_dir))
+
+        registered_plugins = plugins.get_registered_plugins()
+        self._replaced_plugins = {
+            name: registered_plugins.get(name) for name in project_plugins
+        }
+        plugins.register(project_plugins)
+
+        try:
+            for name in templates:
+                _validate_template(
+                    name,
+                    resolve_spec,
+                    project_dirs=project_dirs,
+                    strict_validation=strict_validation,
+                )
+
+            part_list = []
+            for name, spec in parts_data.items():
+                if isinstance(spec, dict):
+                    spec = resolve_spec(name, spec)
+                part_list.append(
+                    _build_part(
+                        name, spec, project_dirs, strict_validation=strict_validation
+                    )
+                )
+
+            if not project_info.overlay_enabled:
+                for part in part_list:
+                    if part.spec.has_overlay:
+                        raise errors.FeatureNotEnabled(
+                            "overlay",
+                            details=f"Part {part.name!r} sets overlay properties.",
+                        )
 
-        part_list = []
-        for name, spec in parts_data.items():
-            part_list.append(_build_part(name, spec, project_dirs))
+            # Fail early if packages can't be handled in the project base.
+            if any(p.spec.build_packages or p.spec.stage_packages for p in part_list):
+                packages.get_repository_for_base(project_info.build_base)
+        except Exception:
+            self.close()
+            raise
 
         self._part_list = part_list
         self._application_name = application_name
@@ -86,33 +397,455 @@ class LifecycleManager:
             part_list=self._part_list,
             project_info=project_info,
         )
+        self._executor = Executor(
+            part_list=self._part_list,
+            project_info=project_info,
+            max_parallel_parts=max_parallel_parts,
+            prefix_output=prefix_output,
+        )
         self._project_info = project_info
+        self._project_dirs = project_dirs
+        self._prune_removed_parts = prune_removed_parts
+
+    def __enter__(self) -> "LifecycleManager":
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def close(self) -> None:
+        """Unregister the plugins provided by the project or by packages.
+
+        Plugins registered before the lifecycle manager was created are
+        restored.
+        """
+        plugins.unregister(
+            *[name for name, plugin in self._replaced_plugins.items() if not plugin]
+        )
+        plugins.register(
+            {name: plugin for name, plugin in self._replaced_plugins.items() if plugin}
+        )
+        self._replaced_plugins = {}
 
     @property
     def project_info(self) -> ProjectInfo:
         """Obtain information about this project."""
         return self._project_info
 
-    def plan(self, target_step: Step, part_names: Sequence[str] = None) -> List[Action]:
+    def plan(
+        self,
+        target_step: Step,
+        part_names: Sequence[str] = None,
+        *,
+        resume: bool = False,
+    ) -> List[Action]:
         """Obtain the list of actions to be executed given the target step and parts.
 
+        Steps that completed successfully are not executed again, so planning
+        after a failure continues from the failed action. If resuming, a
+        failed build step continues in the existing build directory instead
+        of building the part from scratch.
+
         :param target_step: The final step we want to reach.
         :param part_names: The list of parts to process. If not specified, all
             parts will be processed.
-        :param update: Refresh the list of available packages.
+        :param resume: Continue failed build steps using their partial output.
 
         :return: The list of :class:`Action` objects that should be executed in
             order to reach the target step for the specified parts.
         """
-        actions = self._sequencer.plan(target_step, part_names)
+        actions = self._sequencer.plan(target_step, part_names, resume=resume)
         return actions
 
+    def watch(
+        self,
+        target_step: Step,
+        part_names: Sequence[str] = None,
+        *,
+        interval: float = 1.0,
+    ) -> Iterator[List[Action]]:
+        """Plan the lifecycle again each time local part sources are modified.
+
+        The first plan is obtained immediately, and each following plan once
+        files in the local sources of the processed parts or their
+        dependencies are modified. Since steps that are up to date are not
+        executed again, each plan only contains the actions affected by the
+        modifications. Plans are meant to be executed using
+        :meth:`action_executor` before the next plan is requested, and
+        watching stops when the caller stops iterating.
+
+        If none of the parts has a local source, only the first plan is
+        obtained.
+
+        :param target_step: The final step we want to reach.
+        :param part_names: The list of parts to process. If not specified, all
+            parts will be processed.
+        :param interval: The interval between checks for modified files, in
+            seconds.
+
+        :return: An iterator over the lists of actions to execute.
+
+        :raise InvalidPartName: If a part is not defined.
+        """
+        if part_names:
+            names = set(part_names)
+            for name in part_names:
+                dependencies = part_dependencies(
+                    name, part_list=self._part_list, recursive=True
+                )
+                names.update(part.name for part in dependencies)
+            part_list = [p for p in self._part_list if p.name in names]
+        else:
+            part_list = self._part_list
+
+        watcher = SourceWatcher(
+            part_list,
+            application_name=self._application_name,
+            project_dirs=self._project_dirs,
+        )
+
+        while True:
+            # Plan from the state written by the execution of the last plan.
+            self._sequencer.reload_state()
+            yield self.plan(target_step, part_names)
+
+            if not watcher.part_names:
+                return
+
+            watcher.wait(interval=interval)
+
+    def plan_json(
+        self,
+        target_step: Step,
+        part_names: Sequence[str] = None,
+        *,
+        indent: Optional[int] = None,
+    ) -> str:
+        """Obtain the planned actions as a JSON document.
+
+        The document contains the target step and the list of actions, each
+        with the part name, step, action type, the reason it's planned and,
+        for steps that are dirty, the properties and options that changed.
+        Planning doesn't execute any of the actions.
+
+        :param target_step: The final step we want to reach.
+        :param part_names: The list of parts to process. If not specified, all
+            parts will be processed.
+        :param indent: The JSON indentation level. If not specified, the
+            document is written in a single line.
+
+        :return: The JSON document describing the plan.
+        """
+        actions = self.plan(target_step, part_names)
+        plan = {
+            "target-step": target_step.name.lower(),
+            "actions": [action.marshal() for action in actions],
+        }
+        return json.dumps(plan, indent=indent)
+
+    def get_build_packages(self) -> List[str]:
+        """Obtain the system packages needed to build the project parts.
+
+        Packages listed in the ``build-packages`` part property and needed by
+        plugins are named as in Debian, and translated to the package names
+        used in the distribution of the project base. Packages needed to pull
+        the sources of parts are also included.
+
+        :return: The sorted list of build packages.
+
+        :raise UnsupportedBase: If there's no package backend for the base.
+        """
+        repository = packages.get_repository_for_base(self._project_info.build_base)
+        names: Set[str] = set()
+        for part in self._part_list:
+            plugin = plugins.get_plugin(
+                part=part,
+                part_info=PartInfo(self._project_info, part),
+                properties=part.plugin_properties,
+            )
+            names.update(
+                repository.get_package_name(name)
+                for name in [*part.spec.build_packages, *plugin.get_build_packages()]
+            )
+
+            if isinstance(part.spec.source, str):
+                source_type = part.spec.source_type or sources.get_source_type_from_uri(
+                    part.spec.source, ignore_errors=True
+                )
+                names.update(repository.get_packages_for_source_type(source_type))
+
+        return sorted(names)
+
+    def get_step_log(self, part_name: str, step: Step) -> Path:
+        """Obtain the path to the output log file of a step.
+
+        The log contains the output of the last execution of the step in the
+        JSON lines format. Each entry has the ``timestamp``, the ``stream``
+        the output was written to, and the output ``data``. Secrets used by
+        the part are redacted.
+
+        :param part_name: The name of the part the step belongs to.
+        :param step: The step whose output was logged.
+
+        :return: The log file path. The file doesn't exist if the step
+            didn't run yet.
+
+        :raise InvalidPartName: If the part is not defined.
+        """
+        part = part_list_by_name([part_name], self._part_list)[0]
+        return log_file_path(part, step)
+
+    def describe_step(self, part_name: str, step: Step) -> StepDescription:
+        """Describe how a step would be executed, without executing it.
+
+        The description contains the part properties after templates,
+        conditional blocks and variables are applied, the step environment,
+        and the commands or scriptlet the step would run.
+
+        :param part_name: The name of the part the step belongs to.
+        :param step: The step to describe.
+
+        :return: The step description.
+
+        :raise InvalidPartName: If the part is not defined.
+        """
+        part = part_list_by_name([part_name], self._part_list)[0]
+        return describe_step(part, step, project_info=self._project_info)
+
+    def explain_step(
+        self, part_name: str, step: Step
+    ) -> Optional[Union[DirtyReport, OutdatedReport]]:
+        """Explain why a step that already ran would run again.
+
+        The report is obtained from the state on disk, so it reflects changes
+        made since the step last ran regardless of previous plans. A step is
+        dirty if properties used by the step, project options or dependencies
+        changed, and outdated if an earlier step ran again or source files
+        were modified.
+
+        :param part_name: The name of the part the step belongs to.
+        :param step: The step to explain.
+
+        :return: A :class:`DirtyReport` if the step is dirty, an
+            :class:`OutdatedReport` if the step is outdated, or None if the
+            step didn't run yet or is up to date.
+
+        :raise InvalidPartName: If the part is not defined.
+        """
+        part = part_list_by_name([part_name], self._part_list)[0]
+        state_manager = StateManager(
+            project_info=self._project_info, part_list=self._part_list
+        )
+
+        dirty_report = state_manager.check_if_dirty(part, step)
+        if dirty_report:
+            return dirty_report
+
+        return state_manager.check_if_outdated(part, step)
+
+    def get_part_states(
+        self, part_names: Sequence[str] = None
+    ) -> Dict[str, PartStateInfo]:
+        """Obtain the states recorded for the steps that ran for each part.
+
+        The states include the fingerprint of the properties each step ran
+        with, the assets recorded when the step ran such as resolved source
+        details, a snapshot of the environment each part was built in, the
+        stage package versions and the files and directories migrated by the
+        stage and prime steps.
+
+        :param part_names: The list of parts to obtain states of. If not
+            specified, states of all parts are obtained.
+
+        :return: A dictionary mapping part names to their states.
+
+        :raise InvalidPartName: If a part is not defined.
+        """
+        if part_names:
+            part_list = part_list_by_name(part_names, self._part_list)
+        else:
+            part_list = self._part_list
+
+        return {part.name: state_info.get_part_state_info(part) for part in part_list}
+
+    def generate_sbom(
+        self,
+        sbom_format: SbomFormat = SbomFormat.SPDX,
+        part_names: Sequence[str] = None,
+    ) -> Dict[str, Any]:
+        """Obtain a software bill of materials describing the primed artifact.
+
+        The document lists the sources, stage packages and snaps, and the
+        dependencies resolved by plugins of each primed part, obtained from
+        the state and work directories of the parts.
+
+        :param sbom_format: The document format.
+        :param part_names: The list of parts to describe. If not specified,
+            all primed parts are described.
+
+        :return: The document, as a dictionary to be serialized to JSON.
+
+        :raise InvalidPartName: If a part is not defined.
+        """
+        if part_names:
+            part_list = part_list_by_name(part_names, self._part_list)
+        else:
+            part_list = self._part_list
+
+        components: List[sbom.Component] = []
+        for part in part_list:
+            components.extend(sbom.get_components(part))
+
+        return sbom.generate_sbom(
+            components, sbom_format=sbom_format, project_info=self._project_info
+        )
+
+    def generate_provenance(
+        self,
+        subjects: Optional[Dict[str, str]] = None,
+        *,
+        builder_id: str = provenance.DEFAULT_BUILDER_ID,
+    ) -> Dict[str, Any]:
+        """Obtain a SLSA provenance statement describing the primed artifact.
+
+        The statement describes the builder, the definition of each part,
+        the sources resolved when pulling and the fingerprint of each step
+        that ran, and can be attached to artifacts published from the prime
+        directory.
+
+        :param subjects: A dictionary mapping the names of the artifacts the
+            statement applies to, to their digests in the ``<algorithm>/<digest>``
+            format. If not specified, the subject is the prime directory.
+        :param builder_id: The identifier of the builder, such as a URI naming
+            the application and the build service running it.
+
+        :return: The statement, as a dictionary to be serialized to JSON.
+        """
+        return provenance.generate_provenance(
+            self._part_list,
+            project_info=self._project_info,
+            subjects=subjects,
+            builder_id=builder_id,
+        )
+
+    def export_prime(
+        self, destination: Union[Path, str], *, compress: bool = False
+    ) -> None:
+        """Package the prime directory in a deterministic tarball.
+
+        Modification times are clamped to the source date epoch, if set.
+        Overlay whiteouts are converted to the OCI whiteout format.
+
+        :param destination: The tarball to create.
+        :param compress: Whether the tarball is compressed using gzip.
+        """
+        export.create_tarball(
+            self._project_dirs.prime_dir,
+            Path(destination),
+            mtime=self._project_info.source_date_epoch,
+            compress=compress,
+        )
+
+    def export_prime_layer(self, blobs_dir: Union[Path, str]) -> OciLayer:
+        """Package the prime directory in a compressed OCI image layer.
+
+        The layer is created like the tarball created by :meth:`export_prime`,
+        and can be added to the image manifest using its descriptor.
+
+        :param blobs_dir: The directory to write the layer blob to, such as
+            the ``blobs/sha256`` directory of an OCI image layout.
 
-def _build_part(name: str, spec: Dict[str, Any], project_dirs: ProjectDirs) -> Part:
+        :return: The created layer.
+        """
+        return export.create_oci_layer(
+            self._project_dirs.prime_dir,
+            Path(blobs_dir),
+            mtime=self._project_info.source_date_epoch,
+        )
+
+    def get_removed_parts(self) -> List[str]:
+        """Obtain the names of parts that ran but are no longer defined.
+
+        :return: The sorted list of names of parts with work directories in
+            the parts directory that are not defined in the project.
+        """
+        return prune.get_removed_parts(self._part_list, project_dirs=self._project_dirs)
+
+    def prune_removed_parts(self) -> List[str]:
+        """Remove work directories and states of parts no longer defined.
+
+        Files staged or primed by removed parts are also removed from the
+        stage and prime directories, unless they were migrated by parts
+        that are still defined.
+
+        :return: The sorted list of names of pruned parts.
+        """
+        return prune.prune_removed_parts(
+            self._part_list, project_dirs=self._project_dirs
+        )
+
+    def action_executor(
+        self, *, metrics_report: Optional[Union[Path, str]] = None
+    ) -> ExecutionContext:
+        """Return a context manager for action execution.
+
+        If the lifecycle manager was created with ``prune_removed_parts``
+        set, parts that are no longer defined are pruned first.
+
+        :param metrics_report: A file to write the duration and resource usage
+            of the executed actions to, in JSON format, when the context exits.
+        """
+        if self._prune_removed_parts:
+            self.prune_removed_parts()
+
+        return ExecutionContext(
+            executor=self._executor,
+            metrics_report=Path(metrics_report) if metrics_report else None,
+        )
+
+
+def _validate_template(
+    name: str,
+    resolve_spec: Callable[[str, Dict[str, Any]], Dict[str, Any]],
+    *,
+    project_dirs: ProjectDirs,
+    strict_validation: bool = False,
+) -> None:
+    """Verify that a part template is valid.
+
+    Templates are validated as parts. Plugin properties are validated if
+    the template, or a template it extends, sets the plugin.
+
+    :param name: The template name.
+    :param resolve_spec: The function used to resolve part specifications.
+    :param project_dirs: The project's work directories.
+    :param strict_validation: Whether unused plugin options are rejected.
+
+    :raise errors.PartTemplateError: If the template is not valid.
+    """
+    try:
+        spec = resolve_spec(name, {"extends": name})
+        if "plugin" in spec:
+            _build_part(name, spec, project_dirs, strict_validation=strict_validation)
+        else:
+            Part(name, spec, project_dirs=project_dirs)
+    except errors.PartSpecificationError as err:
+        raise errors.PartTemplateError(template_name=name, message=err.message) from err
+
+
+def _build_part(
+    name: str,
+    spec: Dict[str, Any],
+    project_dirs: ProjectDirs,
+    *,
+    strict_validation: bool = False,
+) -> Part:
     """Create and populate a :class:`Part` object based on part specification data.
 
     :param spec: A dictionary containing the part specification.
     :param project_dirs: The project's work directories.
+    :param strict_validation: Whether plugin options not used by the plugin
+        are rejected.
 
     :return: A :class:`Part` object corresponding to the given part specification.
     """
@@ -138,18 +871,65 @@ def _build_part(name: str, spec: Dict[str, Any], project_dirs: ProjectDirs) -> P
         raise errors.InvalidPlugin(plugin_name, part_name=name) from err
 
     # validate and unmarshal plugin properties
+    properties_class = plugin_class.properties_class
     try:
-        properties = plugin_class.properties_class.unmarshal(spec)
+        properties = properties_class.unmarshal(spec)
     except ValidationError as err:
         raise errors.PartSpecificationError.from_validation_error(
-            part_name=name, error_list=err.errors()
+            part_name=name,
+            error_list=err.errors(),
+            valid_names={
+                *properties_class.get_pull_properties(),
+                *properties_class.get_build_properties(),
+            },
         ) from err
     except ValueError as err:
         raise errors.PartSpecificationError(part_name=name, message=str(err)) from err
 
+    if strict_validation:
+        _check_unused_plugin_options(
+            name, spec, plugin_name=plugin_name, properties=properties
+        )
+
     plugins.strip_plugin_properties(spec, plugin_name=plugin_name)
 
     # initialize part and unmarshal part specs
     part = Part(name, spec, project_dirs=project_dirs, plugin_properties=properties)
 
     return part
+
+
+def _check_unused_plugin_options(
+    name: str,
+    spec: Dict[str, Any],
+    *,
+    plugin_name: str,
+    properties: PluginProperties,
+) -> None:
+    """Verify that all options of the selected plugin are used by the plugin.
+
+    :param name: The part name.
+    :param spec: A dictionary containing the part specification.
+    :param plugin_name: The name of the plugin used by the part.
+    :param properties: The plugin properties unmarshaled from the part.
+
+    :raise errors.PartSpecificationError: If a plugin option is not used.
+    """
+    used_options = properties.marshal()
+    unused_options = [
+        key
+        for key in spec
+        if key.startswith(f"{plugin_name}-") and key not in used_options
+    ]
+    if not unused_options:
+        return
+
+    messages: List[str] = []
+    for option in unused_options:
+        message = f"{option!r}: option not used by plugin {plugin_name!r}"
+        match = formatting_utils.get_close_match(option, used_options)
+        if match:
+            message += f" (did you mean {match!r}?)"
+        messages.append(message)
+
+    raise errors.PartSpecificationError(part_name=name, message="\n".join(messages))
@@ -0,0 +1,381 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The craft-parts command line interface.
+
+The command line interface runs the lifecycle of parts defined in a YAML
+file, and shows the actions that would be executed and how each step would
+run without executing anything, to help debugging part definitions. Steps
+can also be executed again each time local sources change. The plugins and
+source types available to part definitions can also be listed.
+
+Partitions are not supported: the lifecycle manager always migrates files
+to a single stage and prime directory, so there is no partition to enable
+or target.
+"""
+
+import argparse
+import json
+import logging
+import sys
+from typing import Any, Dict, List, Optional, Type
+
+import yaml
+
+from craft_parts import errors, plugins, sources
+from craft_parts.actions import Action, ActionType
+from craft_parts.config import load_config
+from craft_parts.lifecycle_manager import LifecycleManager
+from craft_parts.parts import PartSpec
+from craft_parts.steps import Step
+
+_STEPS = {step.name.lower(): step for step in Step}
+
+
+def main(argv: Optional[List[str]] = None) -> None:
+    """Run the craft-parts command line interface.
+
+    :param argv: The command line arguments. Defaults to the arguments of
+        the current process.
+    """
+    parser = _get_parser()
+    args = parser.parse_args(argv)
+    if not args.command:
+        parser.print_usage(sys.stderr)
+        sys.exit(2)
+
+    logging.basicConfig(
+        level=logging.DEBUG if args.verbose else logging.WARNING,
+        format="%(message)s",
+    )
+
+    try:
+        if args.needs_parts:
+            args.func(_create_lifecycle_manager(args), args)
+        else:
+            args.func(args)
+    except errors.PartsError as err:
+        _print_error(err, as_json=args.json)
+        sys.exit(1)
+
+
+def _get_parser() -> argparse.ArgumentParser:
+    parser = argparse.ArgumentParser(
+        prog="craft_parts", description="Process the parts of a project."
+    )
+    parser.add_argument(
+        "-f",
+        "--file",
+        default="parts.yaml",
+        help="the file containing the parts definition (default: parts.yaml)",
+    )
+    parser.add_argument(
+        "--work-dir",
+        default=".",
+        help="the directory to work on (default: the current directory)",
+    )
+    parser.add_argument(
+        "--application-name",
+        default="craft_parts",
+        help="the application name used to identify caches and plugins",
+    )
+    parser.add_argument(
+        "--json", action="store_true", help="write results and errors as JSON"
+    )
+    parser.add_argument(
+        "-v", "--verbose", action="store_true", help="show debug messages"
+    )
+
+    parser.set_defaults(needs_parts=True)
+    subparsers = parser.add_subparsers(dest="command", metavar="command")
+
+    for name in _STEPS:
+        step_parser = subparsers.add_parser(
+            name, help=f"run the lifecycle of parts up to the {name} step"
+        )
+        step_parser.add_argument("parts", nargs="*", help="the parts to process")
+        mode_group = step_parser.add_mutually_exclusive_group()
+        mode_group.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="show the actions to execute instead of executing them",
+        )
+        mode_group.add_argument(
+            "--watch",
+            action="store_true",
+            help="execute the affected steps again when local sources change",
+        )
+        step_parser.set_defaults(func=_run_step, step=name)
+
+    plan_parser = subparsers.add_parser(
+        "plan", help="show the actions to execute to reach a step, and why"
+    )
+    plan_parser.add_argument("step", choices=_STEPS, help="the target step")
+    plan_parser.add_argument("parts", nargs="*", help="the parts to process")
+    plan_parser.add_argument(
+        "--show-skipped",
+        action="store_true",
+        help="also show steps that don't need to run",
+    )
+    plan_parser.set_defaults(func=_run_plan)
+
+    explain_parser = subparsers.add_parser(
+        "explain",
+        help="show the properties, environment and commands used by a step",
+    )
+    explain_parser.add_argument("part", help="the part the step belongs to")
+    explain_parser.add_argument("step", choices=_STEPS, help="the step to explain")
+    explain_parser.set_defaults(func=_run_explain)
+
+    list_plugins_parser = subparsers.add_parser(
+        "list-plugins", help="list the available plugins and their options"
+    )
+    list_plugins_parser.set_defaults(func=_run_list_plugins, needs_parts=False)
+
+    list_sources_parser = subparsers.add_parser(
+        "list-sources", help="list the available source types and their options"
+    )
+    list_sources_parser.set_defaults(func=_run_list_sources, needs_parts=False)
+
+    return parser
+
+
+def _create_lifecycle_manager(args: argparse.Namespace) -> LifecycleManager:
+    """Create a lifecycle manager for the parts defined in the parts file."""
+    try:
+        with open(args.file) as parts_file:
+            parts_data = yaml.safe_load(parts_file)
+    except OSError as err:
+        raise errors.PartsFileError(args.file, message=str(err)) from err
+    except yaml.YAMLError as err:
+        mark = getattr(err, "problem_mark", None)
+        raise errors.PartsFileError(
+            args.file, message=str(err), line=mark.line + 1 if mark else None
+        ) from err
+
+    if not isinstance(parts_data, dict):
+        raise errors.PartsFileError(
+            args.file, message="the parts definition is not a mapping"
+        )
+
+    return LifecycleManager(
+        parts_data,
+        application_name=args.application_name,
+        work_dir=args.work_dir,
+        config=load_config(),
+    )
+
+
+def _run_step(lcm: LifecycleManager, args: argparse.Namespace) -> None:
+    """Execute the actions needed to reach the selected step."""
+    target_step = _STEPS[args.step]
+    if args.watch:
+        _watch_step(lcm, args)
+        return
+
+    actions = lcm.plan(target_step, args.parts)
+    if args.dry_run:
+        _print_plan(actions, target_step=target_step, as_json=args.json)
+        return
+
+    _execute(lcm, actions)
+
+
+def _watch_step(lcm: LifecycleManager, args: argparse.Namespace) -> None:
+    """Execute the actions needed to reach the selected step as sources change.
+
+    Errors are reported without stopping, so that failed steps run again
+    once they're fixed.
+    """
+    try:
+        for actions in lcm.watch(_STEPS[args.step], args.parts):
+            try:
+                _execute(lcm, actions)
+            except errors.PartsError as err:
+                _print_error(err, as_json=args.json)
+    except KeyboardInterrupt:
+        return
+
+    print("No local sources to watch.")
+
+
+def _execute(lcm: LifecycleManager, actions: List[Action]) -> None:
+    """Execute a list of actions, showing the actions that run."""
+    with lcm.action_executor() as ctx:
+        for action in actions:
+            if action.action_type != ActionType.SKIP:
+                print(format_action(action), flush=True)
+            ctx.execute(action)
+
+
+def _run_plan(lcm: LifecycleManager, args: argparse.Namespace) -> None:
+    """Show the actions needed to reach the selected step."""
+    target_step = _STEPS[args.step]
+    actions = lcm.plan(target_step, args.parts)
+    _print_plan(
+        actions,
+        target_step=target_step,
+        as_json=args.json,
+        show_skipped=args.show_skipped,
+    )
+
+
+def _print_plan(
+    actions: List[Action],
+    *,
+    target_step: Step,
+    as_json: bool = False,
+    show_skipped: bool = False,
+) -> None:
+    """Write the list of planned actions to the standard output."""
+    if not show_skipped:
+        actions = [a for a in actions if a.action_type != ActionType.SKIP]
+
+    if as_json:
+        plan = {
+            "target-step": target_step.name.lower(),
+            "actions": [action.marshal() for action in actions],
+        }
+        print(json.dumps(plan, indent=2))
+        return
+
+    if not actions:
+        print("No actions to execute.")
+    for action in actions:
+        print(format_action(action))
+
+
+def _run_explain(lcm: LifecycleManager, args: argparse.Namespace) -> None:
+    """Show how a step would be executed."""
+    step = _STEPS[args.step]
+    description = lcm.describe_step(args.part, step)
+    report = lcm.explain_step(args.part, step)
+
+    if args.json:
+        data = description.marshal()
+        data["status"] = report.reason() if report else None
+        print(json.dumps(data, indent=2))
+        return
+
+    print(f"Part: {description.part_name}")
+    print(f"Step: {args.step}")
+    if report:
+        print(f"Status: {report.reason()}")
+
+    print("Properties:")
+    for name, value in _get_set_properties(description.properties).items():
+        print(f"  {name}: {json.dumps(value)}")
+
+    print("Environment:")
+    for line in description.environment.splitlines():
+        print(f"  {line}")
+
+    print("Commands:")
+    for command in description.commands:
+        for line in command.splitlines():
+            print(f"  {line}")
+
+    if description.scriptlet is not None:
+        print(f"Scriptlet (override-{args.step}):")
+        for line in description.scriptlet.splitlines():
+            print(f"  {line}")
+
+
+def _run_list_plugins(args: argparse.Namespace) -> None:
+    """Show the registered plugins and the options they accept."""
+    plugin_options = {
+        name: _get_plugin_options(plugin_class)
+        for name, plugin_class in sorted(plugins.get_registered_plugins().items())
+    }
+    _print_options(plugin_options, as_json=args.json)
+
+
+def _run_list_sources(args: argparse.Namespace) -> None:
+    """Show the source types and the options they accept."""
+    source_options = dict(sorted(sources.get_source_options().items()))
+    _print_options(source_options, as_json=args.json)
+
+
+def _get_plugin_options(plugin_class: Type[plugins.Plugin]) -> List[str]:
+    """Obtain the names of the part properties used by a plugin."""
+    properties_class = plugin_class.properties_class
+    fields = getattr(properties_class, "__fields__", None)
+    if fields is not None:
+        return [field.alias for field in fields.values()]
+
+    return sorted(
+        {
+            *properties_class.get_pull_properties(),
+            *properties_class.get_build_properties(),
+        }
+    )
+
+
+def _print_options(options: Dict[str, List[str]], *, as_json: bool) -> None:
+    """Write a list of names and their options to the standard output."""
+    if as_json:
+        print(json.dumps(options, indent=2))
+        return
+
+    for name, names in options.items():
+        print(name)
+        for option in names:
+            print(f"  {option}")
+
+
+def _print_error(err: errors.PartsError, *, as_json: bool) -> None:
+    """Write an error to the standard error output."""
+    if as_json:
+        print(err.to_json(), file=sys.stderr)
+    else:
+        print(f"Error: {err}", file=sys.stderr)
+
+
+def _get_set_properties(properties: Dict[str, Any]) -> Dict[str, Any]:
+    """Remove part properties set to their default values."""
+    defaults = PartSpec.unmarshal({}).marshal()
+    return {
+        name: value
+        for name, value in properties.items()
+        if name not in defaults or defaults[name] != value
+    }
+
+
+def format_action(action: Action) -> str:
+    """Obtain a human-readable description of an action.
+
+    :param action: The action to describe.
+
+    :return: The action description, including the reason it's planned.
+    """
+    step = action.step.name.lower()
+    if action.action_type == ActionType.RERUN:
+        message = f"Re{step} {action.part_name}"
+    elif action.action_type == ActionType.UPDATE:
+        message = f"Update {step} of {action.part_name}"
+    elif action.action_type == ActionType.SKIP:
+        message = f"Skip {step} {action.part_name}"
+    else:
+        message = f"{step.capitalize()} {action.part_name}"
+
+    if action.reason:
+        message += f" ({action.reason})"
+
+    return message
+
+
+if __name__ == "__main__":
+    main()
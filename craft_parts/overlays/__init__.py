@@ -0,0 +1,29 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Overlay filesystem handling."""
+
+from .base_image import get_base_image_digest, unpack_base_image  # noqa: F401
+from .chroot import run_chroot_script  # noqa: F401
+from .layer_cache import LayerCache, get_layer_key  # noqa: F401
+from .layers import check_whiteout_conflicts, migratable_overlay_files  # noqa: F401
+from .overlay_fs import (  # noqa: F401
+    OVERLAY_BACKEND_ENVIRONMENT_VARIABLE,
+    OverlayBackend,
+    OverlayFS,
+    get_overlay_backends,
+)
+from .overlay_manager import OverlayManager  # noqa: F401
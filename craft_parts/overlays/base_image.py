@@ -0,0 +1,102 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Overlay base layers obtained from OCI images."""
+
+import logging
+import os
+import re
+import tempfile
+from pathlib import Path
+from typing import Optional
+
+from craft_parts.sources.oci_source import OciSource
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+_DIGEST_PATTERN = re.compile(r"^[a-z0-9]+:[0-9a-f]{32,}$")
+
+
+def unpack_base_image(
+    image: str, *, cache_dir: Path, digest: Optional[str] = None
+) -> Path:
+    """Unpack an OCI image to be used as the overlay base layer.
+
+    The image is either a reference to an image in a container registry or
+    the path to a local oci-archive file. It must be pinned to a digest,
+    either in the image reference (``<name>@sha256:<digest>``) or using
+    ``digest``, which must match the image manifest digest. Unpacked images
+    are cached by digest and reused in later runs.
+
+    :param image: The image reference or oci-archive path.
+    :param cache_dir: The directory to cache unpacked images in.
+    :param digest: The expected image manifest digest.
+
+    :return: The root filesystem of the unpacked image.
+
+    :raise errors.OverlayBaseImageError: If the image is not pinned to a digest.
+    """
+    if digest is None and "@" in image:
+        pinned_digest = image.rpartition("@")[2]
+    else:
+        pinned_digest = digest or ""
+
+    if not _DIGEST_PATTERN.match(pinned_digest):
+        raise errors.OverlayBaseImageError(
+            image, message="image must be pinned to a digest"
+        )
+
+    algorithm, _, encoded = pinned_digest.partition(":")
+    image_dir = cache_dir / "overlay-base" / algorithm / encoded
+    rootfs = image_dir / "rootfs"
+    if rootfs.is_dir():
+        logger.debug("using cached base image %s", pinned_digest)
+        return rootfs
+
+    image_dir.parent.mkdir(parents=True, exist_ok=True)
+    with tempfile.TemporaryDirectory(dir=image_dir.parent) as tmpdir:
+        # The image layout is created next to the unpacked root filesystem.
+        unpack_dir = Path(tmpdir, "rootfs")
+        handler = OciSource(
+            image,
+            str(unpack_dir),
+            source_checksum=f"{algorithm}/{encoded}" if digest else None,
+        )
+        unpack_dir.mkdir()
+        handler.pull()
+
+        image_dir.mkdir(exist_ok=True)
+        os.rename(unpack_dir, rootfs)
+
+    return rootfs
+
+
+def get_base_image_digest(rootfs: Path) -> Optional[str]:
+    """Obtain the digest of the image a base layer was unpacked from.
+
+    :param rootfs: The root filesystem of the base layer.
+
+    :return: The image manifest digest, or None if the base layer was not
+        unpacked by :func:`unpack_base_image`.
+    """
+    image_dir = rootfs.parent
+    if rootfs.name != "rootfs" or image_dir.parent.parent.name != "overlay-base":
+        return None
+
+    digest = f"{image_dir.parent.name}:{image_dir.name}"
+    return digest if _DIGEST_PATTERN.match(digest) else None
@@ -0,0 +1,194 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Run overlay scripts in a chroot with access to control commands.
+
+The chroot may not contain a Python interpreter, so the ``craftctl`` client
+installed in the chroot is a shell script. Calls are written as single lines
+to a FIFO in the chroot and brokered by the process running the script, which
+replies with ``ok <result>`` or ``error <message>``.
+"""
+
+import logging
+import shutil
+import subprocess
+import tempfile
+import textwrap
+import time
+from pathlib import Path
+from typing import Union
+
+from craft_parts import errors, hosts
+from craft_parts.infos import ProjectInfo, StepInfo
+from craft_parts.utils import file_utils
+
+logger = logging.getLogger(__name__)
+
+# The control directory, relative to the chroot root. It's removed after the
+# script runs, so it leaves no changes in the overlay upper layer.
+_CTL_DIR = ".craftctl"
+
+_CRAFTCTL_SCRIPT = """\
+#!/bin/sh
+# Forward control calls to the craft-parts process running this script.
+
+usage() {
+    echo "usage: craftctl get <name> | craftctl set <name>=<value>" >&2
+    exit 2
+}
+
+[ $# -eq 2 ] || usage
+case "$1" in
+    get|set) ;;
+    *) usage ;;
+esac
+
+case "$2" in
+    *"
+"*)
+        echo "craftctl: values cannot contain newlines" >&2
+        exit 2
+        ;;
+esac
+
+printf '%s %s\\n' "$1" "$2" > "$PARTS_CALL_FIFO"
+IFS= read -r feedback < "$PARTS_FEEDBACK_FIFO"
+
+case "$feedback" in
+    "ok "*)
+        if [ "$1" = get ]; then
+            printf '%s\\n' "${feedback#ok }"
+        fi
+        ;;
+    *)
+        echo "craftctl: ${feedback#error }" >&2
+        exit 1
+        ;;
+esac
+"""
+
+
+def run_chroot_script(
+    script: str,
+    *,
+    root: Path,
+    part_name: str,
+    project_info: Union[ProjectInfo, StepInfo],
+    env: str = "",
+    script_name: str = "overlay-script",
+) -> None:
+    """Execute a script in a chroot, brokering ``craftctl`` calls.
+
+    Scripts can read and set project variables using ``craftctl get <name>``
+    and ``craftctl set <name>=<value>``. Names that are not project variables
+    refer to values shared with scripts of other steps and parts in the run.
+
+    :param script: The script to run.
+    :param root: The chroot root directory.
+    :param part_name: The name of the part the script belongs to.
+    :param project_info: The project information, or the information of
+        the step running the script to record the values it sets.
+    :param env: Environment variable definitions to add to the script.
+    :param script_name: The name of the script being executed.
+
+    :raise errors.HostFeatureNotSupported: If chroots can't be used in this host.
+    :raise errors.ScriptletRunError: If the script execution fails.
+    """
+    host = hosts.get_host_platform()
+    if not host.supports_overlays:
+        raise errors.HostFeatureNotSupported("overlay scripts", host=host.name)
+
+    ctl_dir = root / _CTL_DIR
+    (ctl_dir / "bin").mkdir(parents=True)
+
+    try:
+        call_fifo = file_utils.NonBlockingRWFifo(str(ctl_dir / "call"))
+        feedback_fifo = file_utils.NonBlockingRWFifo(str(ctl_dir / "feedback"))
+
+        craftctl = ctl_dir / "bin" / "craftctl"
+        craftctl.write_text(_CRAFTCTL_SCRIPT)
+        craftctl.chmod(0o755)
+
+        chroot_script = textwrap.dedent(
+            """\
+            set -e
+            export PARTS_CALL_FIFO=/{ctl_dir}/call
+            export PARTS_FEEDBACK_FIFO=/{ctl_dir}/feedback
+            export PATH=/{ctl_dir}/bin:$PATH
+
+            {env}
+
+            {script}"""
+        ).format(ctl_dir=_CTL_DIR, env=env, script=script)
+
+        with tempfile.TemporaryFile(mode="w+") as script_file:
+            print(chroot_script, file=script_file)
+            script_file.flush()
+            script_file.seek(0)
+            process = subprocess.Popen(  # pylint: disable=consider-using-with
+                ["chroot", str(root), "/bin/sh"], stdin=script_file
+            )
+
+        status = None
+        try:
+            while status is None:
+                for call in call_fifo.read().splitlines():
+                    if call:
+                        feedback = _handle_call(call, project_info=project_info)
+                        feedback_fifo.write(f"{feedback}\n")
+
+                status = process.poll()
+
+                # Don't loop TOO busily
+                time.sleep(0.1)
+        finally:
+            call_fifo.close()
+            feedback_fifo.close()
+    finally:
+        shutil.rmtree(ctl_dir)
+
+    if status != 0:
+        raise errors.ScriptletRunError(
+            part_name=part_name, scriptlet_name=script_name, exit_code=status
+        )
+
+
+def _handle_call(call: str, *, project_info: Union[ProjectInfo, StepInfo]) -> str:
+    """Execute a control call and return the feedback to send to the client."""
+    logger.debug("chroot control call: %s", call)
+    function, _, arg = call.partition(" ")
+
+    if function == "get":
+        name = arg
+    elif function == "set":
+        name, sep, value = arg.partition("=")
+        if not sep:
+            return f"error invalid assignment {arg!r}"
+    else:
+        return f"error invalid function {function!r}"
+
+    if function == "set":
+        try:
+            project_info.set_value(name, value)
+        except ValueError as err:
+            return f"error {err}"
+        return "ok "
+
+    result = project_info.get_value(name)
+    if result is None:
+        return f"error {name!r} is not defined"
+
+    return f"ok {result}"
@@ -0,0 +1,140 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Exceptions raised by the overlay handling subsystem."""
+
+from typing import List
+
+from craft_parts.errors import PartsError
+
+
+class OverlayError(PartsError):
+    """Base class for overlay handler errors."""
+
+    code = "overlay-error"
+
+
+class InvalidOverlayBackend(OverlayError):
+    """The requested overlay backend is not valid.
+
+    :param backend: The name of the invalid backend.
+    """
+
+    code = "invalid-overlay-backend"
+
+    def __init__(self, backend: str):
+        self.backend = backend
+        brief = f"Invalid overlay backend {backend!r}."
+        resolution = "Valid overlay backends are 'kernel', 'fuse' and 'copy'."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class OverlayBaseImageError(OverlayError):
+    """The overlay base image can't be used.
+
+    :param image: The image reference or oci-archive path.
+    :param message: The error message.
+    """
+
+    code = "overlay-base-image-error"
+
+    def __init__(self, image: str, *, message: str):
+        self.image = image
+        self.message = message
+        brief = f"Cannot use base image {image!r}: {message}."
+        resolution = "Use an image reference or archive pinned to a digest."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class OverlayBaseLayerNotSet(OverlayError):
+    """The overlay step requires a base layer.
+
+    :param part_name: The name of the part whose layer can't be created.
+    """
+
+    code = "overlay-base-layer-not-set"
+
+    def __init__(self, part_name: str):
+        self.part_name = part_name
+        brief = f"Cannot create the overlay layer of part {part_name!r}."
+        details = "The overlay step requires a base layer."
+        resolution = "Set the base layer directory or the base image."
+
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+
+class OverlayWhiteoutConflict(OverlayError):
+    """A part hides content provided by another part.
+
+    :param part_name: The name of the part containing the whiteouts.
+    :param other_part_name: The name of the part providing the hidden content.
+    :param conflicting_files: The list of hidden files.
+    """
+
+    code = "overlay-whiteout-conflict"
+
+    def __init__(
+        self, *, part_name: str, other_part_name: str, conflicting_files: List[str]
+    ):
+        self.part_name = part_name
+        self.other_part_name = other_part_name
+        self.conflicting_files = conflicting_files
+        indented_conflicting_files = ("    " + i for i in conflicting_files)
+        file_paths = "\n".join(sorted(indented_conflicting_files))
+        brief = (
+            f"Whiteouts in part {part_name!r} hide files provided by part "
+            f"{other_part_name!r}."
+        )
+        details = f"Hidden files:\n{file_paths}"
+        resolution = "Exclude the conflicting whiteouts or files from the overlay."
+
+        super().__init__(brief=brief, details=details, resolution=resolution)
+
+
+class OverlayMountError(OverlayError):
+    """Failed to mount an overlay filesystem.
+
+    :param mountpoint: The filesystem mount point.
+    :param message: The error message.
+    """
+
+    code = "overlay-mount-error"
+
+    def __init__(self, mountpoint: str, *, message: str):
+        self.mountpoint = mountpoint
+        self.message = message
+        brief = f"Failed to mount overlay on {mountpoint}: {message}."
+
+        super().__init__(brief=brief)
+
+
+class OverlayUnmountError(OverlayError):
+    """Failed to unmount an overlay filesystem.
+
+    :param mountpoint: The filesystem mount point.
+    :param message: The error message.
+    """
+
+    code = "overlay-unmount-error"
+
+    def __init__(self, mountpoint: str, *, message: str):
+        self.mountpoint = mountpoint
+        self.message = message
+        brief = f"Failed to unmount {mountpoint}: {message}."
+
+        super().__init__(brief=brief)
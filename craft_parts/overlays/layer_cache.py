@@ -0,0 +1,124 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Cache overlay layers shared by projects with identical overlay definitions."""
+
+import hashlib
+import json
+import logging
+import shutil
+import tarfile
+import tempfile
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Union
+
+from craft_parts.sources.cache import FileCache
+
+logger = logging.getLogger(__name__)
+
+# Layers are created by craft-parts and may contain links to absolute paths
+# and whiteout device nodes.
+_EXTRACT_ARGS: Dict[str, Any] = (
+    {"filter": "fully_trusted"} if hasattr(tarfile, "fully_trusted_filter") else {}
+)
+
+
+def get_layer_key(
+    *,
+    base_digest: str,
+    overlay_packages: List[str],
+    overlay_script: Optional[str],
+    parent_key: Optional[str] = None,
+) -> str:
+    """Compute the cache key of an overlay layer.
+
+    :param base_digest: The digest of the overlay base image.
+    :param overlay_packages: The packages installed in the layer.
+    :param overlay_script: The script used to customize the layer.
+    :param parent_key: The key of the layer below this one, if any.
+
+    :return: The layer key, in the ``sha256/<digest>`` format.
+    """
+    definition = {
+        "base": base_digest,
+        "packages": sorted(overlay_packages),
+        "script": overlay_script,
+        "parent": parent_key,
+    }
+    digest = hashlib.sha256(json.dumps(definition, sort_keys=True).encode())
+    return f"sha256/{digest.hexdigest()}"
+
+
+class LayerCache:
+    """Store and restore overlay layers by key.
+
+    Layers are archived in a file cache namespace shared by all projects
+    of the application, so that rebuilds and other projects using the same
+    overlay definition don't need to run the overlay step again.
+
+    :param name: The name of the application using the cache.
+    :param cache_dir: The cache location. Defaults to a cache specific to
+        the application in the XDG cache directory.
+    :param max_size: The maximum size of the layer cache in bytes.
+    """
+
+    def __init__(
+        self,
+        name: str,
+        *,
+        cache_dir: Optional[Union[Path, str]] = None,
+        max_size: Optional[int] = None,
+    ) -> None:
+        self._cache = FileCache(
+            name, namespace="overlay-layers", cache_dir=cache_dir, max_size=max_size
+        )
+
+    def save(self, *, key: str, layer_dir: Path) -> bool:
+        """Add the contents of a layer directory to the cache.
+
+        :param key: The layer key.
+        :param layer_dir: The directory containing the layer.
+
+        :return: Whether the layer was cached.
+        """
+        with tempfile.TemporaryDirectory() as tmpdir:
+            archive = Path(tmpdir, "layer.tar")
+            with tarfile.open(archive, "w") as tar:
+                tar.add(layer_dir, arcname=".")
+
+            return self._cache.cache(filename=str(archive), key=key) is not None
+
+    def restore(self, *, key: str, layer_dir: Path) -> bool:
+        """Replace the contents of a layer directory with a cached layer.
+
+        :param key: The layer key.
+        :param layer_dir: The directory to restore the layer to.
+
+        :return: Whether the layer was found in the cache.
+        """
+        archive = self._cache.get(key=key)
+        if not archive:
+            return False
+
+        logger.debug("restore overlay layer %s", key)
+        if layer_dir.exists():
+            shutil.rmtree(layer_dir)
+        layer_dir.mkdir(parents=True)
+
+        with tarfile.open(archive) as tar:
+            tar.extractall(layer_dir, **_EXTRACT_ARGS)
+
+        return True
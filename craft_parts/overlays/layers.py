@@ -0,0 +1,162 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Helpers to migrate overlay layer contents containing whiteouts."""
+
+import fnmatch
+import os
+from typing import Dict, List, Optional, Set, Tuple
+
+from craft_parts.executor import filesets
+from craft_parts.executor.filesets import Fileset
+
+from . import errors
+
+WHITEOUT_PREFIX = ".wh."
+OPAQUE_WHITEOUT = ".wh..wh..opq"
+
+
+def get_whiteout_target(path: str) -> Optional[str]:
+    """Obtain the path hidden by a whiteout file.
+
+    Opaque directory markers hide the contents of the directory they are in,
+    so the directory path is returned.
+
+    :param path: The path of the file, relative to the layer root.
+
+    :return: The hidden path, or None if the file is not a whiteout.
+    """
+    dirname, basename = os.path.split(path)
+    if basename == OPAQUE_WHITEOUT:
+        return dirname
+    if basename.startswith(WHITEOUT_PREFIX):
+        return os.path.join(dirname, basename[len(WHITEOUT_PREFIX) :])
+    return None
+
+
+def migratable_overlay_files(
+    fileset: Fileset, srcdir: str
+) -> Tuple[Set[str], Set[str]]:
+    """Return the overlay layer files and directories that can be migrated.
+
+    Whiteout files are selected according to the path they hide instead of
+    their own names, so excluding a path also drops its whiteout, and opaque
+    directory markers are selected with their directory.
+
+    :param fileset: The fileset to migrate.
+    :param srcdir: The overlay layer directory.
+
+    :return: A tuple containing the set of files and the set of directories
+        that can be migrated.
+    """
+    files, dirs = filesets.migratable_filesets(fileset, srcdir)
+    files = {x for x in files if get_whiteout_target(x) is None}
+
+    includes = fileset.includes or ["*"]
+    excludes = fileset.excludes
+
+    for root, _, names in os.walk(srcdir):
+        for name in names:
+            path = os.path.relpath(os.path.join(root, name), srcdir)
+            target = get_whiteout_target(path)
+            if target is None or not _is_selected(target, includes, excludes):
+                continue
+
+            files.add(path)
+            dirname = os.path.dirname(path)
+            while dirname:
+                dirs.add(dirname)
+                dirname = os.path.dirname(dirname)
+
+    return files, dirs
+
+
+def check_whiteout_conflicts(part_files: Dict[str, Set[str]]) -> None:
+    """Verify that parts don't hide content provided by other parts.
+
+    Whiteouts from different layers are applied in an arbitrary order when
+    staging, so a part hiding a path that is provided by another part yields
+    unpredictable results.
+
+    :param part_files: A dictionary mapping part names to the set of files
+        and directories to be migrated from their overlay layers.
+
+    :raise errors.OverlayWhiteoutConflict: If a part hides content provided
+        by another part.
+    """
+    for part_name, files in sorted(part_files.items()):
+        hidden: List[Tuple[str, bool]] = []
+        for path in files:
+            target = get_whiteout_target(path)
+            if target is not None:
+                opaque = os.path.basename(path) == OPAQUE_WHITEOUT
+                hidden.append((target, opaque))
+
+        for other_part_name, other_files in sorted(part_files.items()):
+            if other_part_name == part_name:
+                continue
+
+            conflicting = {
+                path
+                for path in other_files
+                if get_whiteout_target(path) is None
+                and any(_is_hidden(path, target, opaque) for target, opaque in hidden)
+            }
+            if conflicting:
+                raise errors.OverlayWhiteoutConflict(
+                    part_name=part_name,
+                    other_part_name=other_part_name,
+                    conflicting_files=sorted(conflicting),
+                )
+
+
+def _is_hidden(path: str, target: str, opaque: bool) -> bool:
+    """Verify whether a path is hidden by a whiteout of the target path."""
+    if opaque:
+        return path.startswith(target + "/")
+    return path == target or path.startswith(target + "/")
+
+
+def _is_selected(path: str, includes: List[str], excludes: List[str]) -> bool:
+    """Verify whether a path, or one of its parents, is selected by filters."""
+    candidates = [path]
+    dirname = os.path.dirname(path)
+    while dirname:
+        candidates.append(dirname)
+        dirname = os.path.dirname(dirname)
+
+    def matches(patterns: List[str]) -> bool:
+        return any(_match(pattern, x) for pattern in patterns for x in candidates)
+
+    return matches([x.lstrip("\\") for x in includes]) and not matches(excludes)
+
+
+def _match(pattern: str, path: str) -> bool:
+    """Match a path against a glob pattern, with ``**`` matching directories."""
+    return _match_parts(pattern.strip("/").split("/"), path.split("/"))
+
+
+def _match_parts(pattern: List[str], parts: List[str]) -> bool:
+    if not pattern:
+        return not parts
+
+    if pattern[0] == "**":
+        return any(_match_parts(pattern[1:], parts[i:]) for i in range(len(parts) + 1))
+
+    if not parts or not fnmatch.fnmatchcase(parts[0], pattern[0]):
+        return False
+
+    return _match_parts(pattern[1:], parts[1:])
@@ -0,0 +1,356 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Overlay filesystem mount and unmount operations.
+
+Kernel overlayfs mounts require privileges that are not available in
+unprivileged containers, and some kernels and filesystems don't support
+overlayfs at all. In these cases the overlay is mounted using fuse-overlayfs
+or, if that's not available either, emulated by copying the layers to the
+mountpoint and copying changes up to the upper layer when unmounting.
+"""
+
+import contextlib
+import enum
+import filecmp
+import logging
+import os
+import shutil
+import subprocess
+import tempfile
+from pathlib import Path
+from typing import Callable, Dict, List, Optional
+
+from craft_parts import hosts
+from craft_parts.utils import file_utils
+
+from . import errors
+from .layers import WHITEOUT_PREFIX
+
+logger = logging.getLogger(__name__)
+
+OVERLAY_BACKEND_ENVIRONMENT_VARIABLE = "CRAFT_PARTS_OVERLAY_BACKEND"
+
+
+class OverlayBackend(enum.Enum):
+    """The mechanism used to mount an overlay filesystem."""
+
+    KERNEL = "kernel"
+    FUSE = "fuse"
+    COPY = "copy"
+
+
+def get_overlay_backends(env: Optional[Dict[str, str]] = None) -> List[OverlayBackend]:
+    """Obtain the overlay backends available in this host, in order of preference.
+
+    A specific backend can be selected by setting ``CRAFT_PARTS_OVERLAY_BACKEND``
+    to ``kernel``, ``fuse`` or ``copy``.
+
+    :param env: The environment to read the backend override from. Defaults
+        to the current process environment.
+
+    :return: The list of overlay backends to try.
+
+    :raise errors.InvalidOverlayBackend: If the backend override is not valid.
+    """
+    if env is None:
+        env = dict(os.environ)
+
+    name = env.get(OVERLAY_BACKEND_ENVIRONMENT_VARIABLE)
+    if name:
+        try:
+            return [OverlayBackend(name)]
+        except ValueError as err:
+            raise errors.InvalidOverlayBackend(name) from err
+
+    # Only the copy backend is available in hosts without overlayfs.
+    host = hosts.get_host_platform()
+    backends: List[OverlayBackend] = []
+    if host.supports_overlays:
+        if host.is_root() and _is_kernel_overlay_supported():
+            backends.append(OverlayBackend.KERNEL)
+        if shutil.which("fuse-overlayfs") and os.path.exists("/dev/fuse"):
+            backends.append(OverlayBackend.FUSE)
+    backends.append(OverlayBackend.COPY)
+
+    return backends
+
+
+class OverlayFS:
+    """Mount and unmount an overlay filesystem.
+
+    :param lower_dirs: The lower layer directories, topmost first.
+    :param upper_dir: The upper layer directory.
+    :param work_dir: The overlay work directory.
+    :param backend: The backend to use. If not set, the first available
+        backend returned by :func:`get_overlay_backends` that succeeds in
+        mounting the overlay is used.
+    """
+
+    def __init__(
+        self,
+        *,
+        lower_dirs: List[Path],
+        upper_dir: Path,
+        work_dir: Path,
+        backend: Optional[OverlayBackend] = None,
+    ):
+        self._lower_dirs = lower_dirs
+        self._upper_dir = upper_dir
+        self._work_dir = work_dir
+        self._requested_backend = backend
+        self._backend: Optional[OverlayBackend] = None
+        self._mountpoint: Optional[Path] = None
+
+    @property
+    def backend(self) -> Optional[OverlayBackend]:
+        """The backend used to mount the overlay, or None if not mounted."""
+        return self._backend
+
+    def mount(self, mountpoint: Path) -> None:
+        """Mount the overlay filesystem.
+
+        :param mountpoint: The directory to mount the overlay on.
+
+        :raise errors.OverlayMountError: If the overlay can't be mounted.
+        """
+        if self._mountpoint:
+            raise errors.OverlayMountError(
+                str(mountpoint), message=f"already mounted on {self._mountpoint}"
+            )
+
+        for path in (self._upper_dir, self._work_dir, mountpoint):
+            path.mkdir(parents=True, exist_ok=True)
+
+        if self._requested_backend:
+            backends = [self._requested_backend]
+        else:
+            backends = get_overlay_backends()
+
+        mount_handlers: Dict[OverlayBackend, Callable[[Path], None]] = {
+            OverlayBackend.KERNEL: self._kernel_mount,
+            OverlayBackend.FUSE: self._fuse_mount,
+            OverlayBackend.COPY: self._copy_mount,
+        }
+
+        for backend in backends:
+            logger.debug("mount overlay on %s using %s", mountpoint, backend.value)
+            try:
+                mount_handlers[backend](mountpoint)
+            except (OSError, subprocess.CalledProcessError) as err:
+                if backend == backends[-1]:
+                    raise errors.OverlayMountError(
+                        str(mountpoint), message=str(err)
+                    ) from err
+                logger.debug("overlay %s mount failed: %s", backend.value, err)
+                continue
+
+            self._backend = backend
+            self._mountpoint = mountpoint
+            return
+
+    def unmount(self) -> None:
+        """Unmount the overlay filesystem.
+
+        When using the copy backend, changes made to the mountpoint are
+        copied up to the upper layer directory.
+
+        :raise errors.OverlayUnmountError: If the overlay can't be unmounted.
+        """
+        if not self._mountpoint or not self._backend:
+            raise errors.OverlayUnmountError("overlay", message="not mounted")
+
+        unmount_handlers: Dict[OverlayBackend, Callable[[Path], None]] = {
+            OverlayBackend.KERNEL: self._kernel_unmount,
+            OverlayBackend.FUSE: self._fuse_unmount,
+            OverlayBackend.COPY: self._copy_unmount,
+        }
+
+        logger.debug("unmount overlay from %s", self._mountpoint)
+        try:
+            unmount_handlers[self._backend](self._mountpoint)
+        except (OSError, subprocess.CalledProcessError) as err:
+            raise errors.OverlayUnmountError(
+                str(self._mountpoint), message=str(err)
+            ) from err
+
+        self._backend = None
+        self._mountpoint = None
+
+    def _get_mount_options(self) -> str:
+        lower_dirs = ":".join(str(path) for path in self._lower_dirs)
+        return (
+            f"lowerdir={lower_dirs},upperdir={self._upper_dir},"
+            f"workdir={self._work_dir}"
+        )
+
+    def _kernel_mount(self, mountpoint: Path) -> None:
+        subprocess.run(
+            [
+                "mount",
+                "-t",
+                "overlay",
+                "overlay",
+                f"-o{self._get_mount_options()}",
+                str(mountpoint),
+            ],
+            check=True,
+        )
+
+    @staticmethod
+    def _kernel_unmount(mountpoint: Path) -> None:
+        subprocess.run(["umount", str(mountpoint)], check=True)
+
+    def _fuse_mount(self, mountpoint: Path) -> None:
+        subprocess.run(
+            ["fuse-overlayfs", "-o", self._get_mount_options(), str(mountpoint)],
+            check=True,
+        )
+
+    @staticmethod
+    def _fuse_unmount(mountpoint: Path) -> None:
+        fusermount = shutil.which("fusermount3") or "fusermount"
+        subprocess.run([fusermount, "-u", str(mountpoint)], check=True)
+
+    def _copy_mount(self, mountpoint: Path) -> None:
+        for layer in [*reversed(self._lower_dirs), self._upper_dir]:
+            _apply_layer(layer, mountpoint, copy_function=file_utils.copy)
+
+    def _copy_unmount(self, mountpoint: Path) -> None:
+        with tempfile.TemporaryDirectory() as tmpdir:
+            # Hard links are safe here since the lower view is not modified.
+            lower_view = Path(tmpdir, "lower")
+            lower_view.mkdir()
+            for layer in reversed(self._lower_dirs):
+                _apply_layer(layer, lower_view, copy_function=file_utils.link_or_copy)
+
+            _clear_directory(self._upper_dir)
+            _copy_up(lower_view, mountpoint, self._upper_dir)
+
+        _clear_directory(mountpoint)
+
+
+def _is_kernel_overlay_supported() -> bool:
+    """Verify whether the running kernel supports overlayfs."""
+    try:
+        filesystems = Path("/proc/filesystems").read_text()
+    except OSError:
+        return False
+
+    return any(line.split()[-1:] == ["overlay"] for line in filesystems.splitlines())
+
+
+def _walk(top: Path):
+    """Walk a directory tree, listing symlinks to directories as files."""
+    for root, directories, files in os.walk(top):
+        for name in list(directories):
+            if os.path.islink(os.path.join(root, name)):
+                directories.remove(name)
+                files.append(name)
+
+        yield os.path.relpath(root, top), directories, files
+
+
+def _apply_layer(
+    layer: Path, destination: Path, *, copy_function: Callable[..., None]
+) -> None:
+    """Copy a layer on top of a directory, removing whited-out files."""
+    for relroot, directories, files in _walk(layer):
+        for name in directories:
+            target = destination / relroot / name
+            if os.path.lexists(target) and (
+                os.path.islink(target) or not target.is_dir()
+            ):
+                target.unlink()
+            file_utils.create_similar_directory(
+                str(layer / relroot / name), str(target)
+            )
+
+        for name in files:
+            if name.startswith(WHITEOUT_PREFIX):
+                _remove(destination / relroot / name[len(WHITEOUT_PREFIX) :])
+                continue
+
+            target = destination / relroot / name
+            if target.is_dir() and not target.is_symlink():
+                shutil.rmtree(target)
+            copy_function(str(layer / relroot / name), str(target))
+
+
+def _copy_up(lower_view: Path, merged: Path, upper_dir: Path) -> None:
+    """Write the differences between the merged and lower views to a layer."""
+    for relroot, directories, files in _walk(merged):
+        for name in directories:
+            path = Path(relroot, name)
+            if not _is_same_entry(lower_view / path, merged / path):
+                file_utils.create_similar_directory(
+                    str(merged / path), str(upper_dir / path)
+                )
+
+        for name in files:
+            path = Path(relroot, name)
+            if not _is_same_entry(lower_view / path, merged / path):
+                (upper_dir / relroot).mkdir(parents=True, exist_ok=True)
+                file_utils.copy(str(merged / path), str(upper_dir / path))
+
+    for relroot, directories, files in _walk(lower_view):
+        removed = [
+            name
+            for name in directories + files
+            if not os.path.lexists(merged / relroot / name)
+        ]
+        # Whiteouts use the OCI layer format, since overlayfs whiteout
+        # character devices can't be created without privileges.
+        for name in removed:
+            (upper_dir / relroot).mkdir(parents=True, exist_ok=True)
+            (upper_dir / relroot / f"{WHITEOUT_PREFIX}{name}").touch()
+
+        # Whiteouts of parent directories also hide their contents.
+        directories[:] = [name for name in directories if name not in removed]
+
+
+def _is_same_entry(lower: Path, merged: Path) -> bool:
+    """Verify whether a merged view entry is unchanged from the lower view."""
+    if not os.path.lexists(lower):
+        return False
+
+    lower_stat = os.lstat(lower)
+    merged_stat = os.lstat(merged)
+    if lower_stat.st_mode != merged_stat.st_mode:
+        return False
+
+    if lower.is_symlink():
+        return os.readlink(lower) == os.readlink(merged)
+
+    if lower.is_file():
+        return filecmp.cmp(lower, merged, shallow=False)
+
+    return True
+
+
+def _clear_directory(directory: Path) -> None:
+    """Remove the contents of a directory, keeping the directory itself."""
+    for entry in directory.iterdir():
+        _remove(entry)
+
+
+def _remove(path: Path) -> None:
+    """Remove a file, symlink, or directory tree if it exists."""
+    if path.is_dir() and not path.is_symlink():
+        shutil.rmtree(path)
+    else:
+        with contextlib.suppress(FileNotFoundError):
+            path.unlink()
@@ -0,0 +1,150 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Create and mount the overlay layers of parts."""
+
+import contextlib
+import logging
+import tempfile
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional, Set, Tuple
+
+from craft_parts.executor.filesets import Fileset
+from craft_parts.parts import Part, part_lower_layers
+
+from . import errors
+from .base_image import get_base_image_digest
+from .layer_cache import get_layer_key
+from .layers import check_whiteout_conflicts, migratable_overlay_files
+from .overlay_fs import OverlayFS
+
+logger = logging.getLogger(__name__)
+
+
+class OverlayManager:
+    """Stack the overlay layers of parts on top of the base layer.
+
+    Each part has its own layer, stacked on top of the layers of all parts
+    processed before it. Changes made to the mounted overlay of a part are
+    written to the part layer directory.
+
+    :param part_list: A list of all parts in the project.
+    :param base_layer_dir: The root filesystem at the bottom of the overlay.
+    """
+
+    def __init__(self, *, part_list: List[Part], base_layer_dir: Optional[Path]):
+        self._part_list = part_list
+        self._base_layer_dir = base_layer_dir
+
+    def get_layer_key(self, part: Part) -> Optional[str]:
+        """Compute the cache key of the overlay layer of a part.
+
+        The key depends on the base image and on the overlay definitions of
+        the part and of all parts with lower layers.
+
+        :param part: The part whose layer key is computed.
+
+        :return: The layer key, or None if the base layer was not unpacked
+            from an image pinned to a digest.
+        """
+        if not self._base_layer_dir:
+            return None
+
+        base_digest = get_base_image_digest(self._base_layer_dir)
+        if not base_digest:
+            return None
+
+        key: Optional[str] = None
+        for layer_part in [*self._get_lower_parts(part), part]:
+            key = get_layer_key(
+                base_digest=base_digest,
+                overlay_packages=layer_part.spec.overlay_packages,
+                overlay_script=layer_part.spec.overlay_script,
+                parent_key=key,
+            )
+
+        return key
+
+    @contextlib.contextmanager
+    def mount_layer(self, part: Part) -> Iterator[Path]:
+        """Mount the overlay of a part, with its layer on top.
+
+        :param part: The part whose layer is mounted.
+
+        :yield: The overlay mountpoint.
+
+        :raise errors.OverlayBaseLayerNotSet: If there's no base layer.
+        :raise errors.OverlayMountError: If the overlay can't be mounted.
+        :raise errors.OverlayUnmountError: If the overlay can't be unmounted.
+        """
+        if not self._base_layer_dir:
+            raise errors.OverlayBaseLayerNotSet(part.name)
+
+        lower_dirs = [
+            lower_part.part_layer_dir
+            for lower_part in reversed(self._get_lower_parts(part))
+            if lower_part.part_layer_dir.is_dir()
+        ]
+        lower_dirs.append(self._base_layer_dir)
+
+        # The work directory must be in the same filesystem as the layer.
+        layer_dir = part.part_layer_dir
+        layer_dir.mkdir(parents=True, exist_ok=True)
+        with tempfile.TemporaryDirectory(
+            prefix=".overlay-", dir=layer_dir.parent
+        ) as tmpdir:
+            overlay = OverlayFS(
+                lower_dirs=lower_dirs,
+                upper_dir=layer_dir,
+                work_dir=Path(tmpdir, "work"),
+            )
+            mountpoint = Path(tmpdir, "overlay")
+            overlay.mount(mountpoint)
+            try:
+                yield mountpoint
+            finally:
+                overlay.unmount()
+
+    def get_overlay_files(self, part: Part) -> Tuple[Set[str], Set[str]]:
+        """Obtain the layer files and directories of a part to be staged.
+
+        :param part: The part whose layer files are listed.
+
+        :return: A tuple containing the set of files and the set of
+            directories selected by the ``overlay`` fileset.
+        """
+        layer_dir = part.part_layer_dir
+        if not layer_dir.is_dir():
+            return set(), set()
+
+        fileset = Fileset(part.spec.overlay_files, name="overlay")
+        return migratable_overlay_files(fileset, str(layer_dir))
+
+    def check_conflicts(self) -> None:
+        """Verify that layers don't hide content provided by other parts.
+
+        :raise errors.OverlayWhiteoutConflict: If a part layer hides content
+            provided by another part.
+        """
+        part_files: Dict[str, Set[str]] = {}
+        for part in self._part_list:
+            files, dirs = self.get_overlay_files(part)
+            part_files[part.name] = files | dirs
+
+        check_whiteout_conflicts(part_files)
+
+    def _get_lower_parts(self, part: Part) -> List[Part]:
+        return part_lower_layers(part.name, part_list=self._part_list)
@@ -14,4 +14,138 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-"""Operations with platform-specific package repositories."""
+"""Operations with platform-specific package repositories.
+
+Package backends are selected using the distribution of the project base,
+detected from the host os-release information if not set.
+Applications can register backends for additional distributions, or to
+replace the backends provided by craft-parts, by subclassing
+:class:`BaseRepository`.
+"""
+
+from typing import Dict, Optional, Sequence
+
+from craft_parts import bases
+from craft_parts.errors import UnsupportedBase
+from craft_parts.utils.os_utils import OsRelease
+
+from .base import BaseRepository, DummyRepository, RepositoryType  # noqa: F401
+
+_DEB_DISTRIBUTIONS = {"ubuntu", "debian"}
+_RPM_DISTRIBUTIONS = {"fedora", "rhel", "centos"}
+_APK_DISTRIBUTIONS = {"alpine"}
+_PACMAN_DISTRIBUTIONS = {"arch"}
+_ZYPPER_DISTRIBUTIONS = {"suse", "opensuse", "sles"}
+
+_BACKENDS: Dict[str, RepositoryType] = {}
+
+
+def register(backends: Dict[str, RepositoryType]) -> None:
+    """Register package backends.
+
+    Registered backends take precedence over the backends provided by
+    craft-parts.
+
+    :param backends: A dictionary where the keys are distribution IDs, as
+        listed in the os-release ``ID`` or ``ID_LIKE`` fields, and values are
+        repository classes. Valid backends must subclass :class:`BaseRepository`.
+    """
+    _BACKENDS.update(backends)
+
+
+def get_registered_backends() -> Dict[str, RepositoryType]:
+    """Obtain the package backends currently registered.
+
+    :return: A dictionary where the keys are distribution IDs and values are
+        repository classes.
+    """
+    return _BACKENDS.copy()
+
+
+def unregister_all() -> None:
+    """Unregister all user-registered package backends."""
+    _BACKENDS.clear()
+
+
+def get_repository_for_platform(
+    os_release: Optional[OsRelease] = None,
+) -> RepositoryType:
+    """Obtain the repository handler for the host operating system.
+
+    Registered backends are used if the distribution or a distribution it
+    is derived from is registered. Otherwise distributions derived from
+    Debian use apt, distributions derived from Fedora or Red Hat Enterprise
+    Linux use dnf, Alpine uses apk, distributions derived from Arch Linux
+    use pacman, and openSUSE and SUSE Linux Enterprise use zypper. Other
+    distributions and non-Linux hosts use a repository that doesn't handle
+    packages.
+
+    :param os_release: The host operating system release information.
+
+    :return: The repository handler class.
+    """
+    host_base = bases.get_host_base(os_release)
+    if host_base is None:
+        return DummyRepository
+
+    return _get_backend(host_base.distribution_ids) or DummyRepository
+
+
+def get_repository_for_base(base: Optional[bases.Base]) -> RepositoryType:
+    """Obtain the repository handler for the project base.
+
+    Backends are selected as in :func:`get_repository_for_platform`, using
+    the distribution of the base instead of the host distribution.
+
+    :param base: The project base. If not set, packages are not handled.
+
+    :return: The repository handler class.
+
+    :raise errors.UnsupportedBase: If no backend handles the base distribution.
+    """
+    if base is None:
+        return DummyRepository
+
+    repository = _get_backend(base.distribution_ids)
+    if repository is None:
+        raise UnsupportedBase(base.name)
+
+    return repository
+
+
+def _get_backend(distribution_ids: Sequence[str]) -> Optional[RepositoryType]:
+    """Obtain the repository handler for the first supported distribution."""
+    # The distribution ID takes precedence over the distributions it's like.
+    for distribution_id in distribution_ids:
+        if distribution_id in _BACKENDS:
+            return _BACKENDS[distribution_id]
+
+    distributions = set(distribution_ids)
+
+    # pylint: disable=import-outside-toplevel
+    if distributions & _DEB_DISTRIBUTIONS:
+        from .deb import Ubuntu
+
+        return Ubuntu
+
+    if distributions & _RPM_DISTRIBUTIONS:
+        from .dnf import DNFRepository
+
+        return DNFRepository
+
+    if distributions & _APK_DISTRIBUTIONS:
+        from .apk import Alpine
+
+        return Alpine
+
+    if distributions & _PACMAN_DISTRIBUTIONS:
+        from .pacman import ArchLinux
+
+        return ArchLinux
+
+    if distributions & _ZYPPER_DISTRIBUTIONS:
+        from .zypper import OpenSUSE
+
+        return OpenSUSE
+
+    return None
@@ -0,0 +1,315 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Support for Alpine packages using apk."""
+
+import functools
+import logging
+import os
+import pathlib
+import re
+import subprocess
+from typing import Any, Dict, List, Set
+
+from xdg import BaseDirectory  # type: ignore
+
+from . import errors
+from .base import get_pkg_name_parts
+from .package_manager import PackageManagerRepository
+from .repositories import ApkRepository, install_apk_repository
+
+logger = logging.getLogger(__name__)
+
+# Translation from the deb architectures used by craft-parts to apk
+# architectures.
+_APK_ARCHITECTURES = {
+    "amd64": "x86_64",
+    "arm64": "aarch64",
+    "armhf": "armv7",
+    "i386": "x86",
+    "ppc64el": "ppc64le",
+    "s390x": "s390x",
+}
+
+# Lines printed by apk when simulating the installation of packages, such
+# as "(1/2) Installing musl (1.2.2-r3)".
+_SIMULATED_INSTALL_REGEX = re.compile(r"Installing (\S+) \((\S+)\)")
+
+# Package metadata stored in apk packages alongside the package files.
+_METADATA_PREFIXES = (".PKGINFO", ".SIGN.", ".pre-", ".post-", ".trigger")
+
+
+class Alpine(PackageManagerRepository):
+    """Repository management for Alpine packages.
+
+    Packages can be pinned to a tagged repository listed in the apk
+    repositories file using the ``<name>@<tag>`` syntax.
+    """
+
+    package_suffix = ".apk"
+
+    source_type_packages = {"rpm2cpio": {"rpm2cpio", "cpio"}}
+
+    package_names = {
+        "python3-venv": "python3",
+        "zlib1g-dev": "zlib-dev",
+        "libssl-dev": "openssl-dev",
+        "libyaml-dev": "yaml-dev",
+        "libgmp-dev": "gmp-dev",
+        "libpcre2-dev": "pcre2-dev",
+        "xz-utils": "xz",
+        "ninja-build": "ninja",
+        "pkg-config": "pkgconf",
+        "default-jdk-headless": "openjdk17-jdk",
+    }
+
+    @classmethod
+    def get_package_libraries(cls, package_name: str) -> Set[str]:
+        """Return a list of libraries in package_name."""
+        return _run_apk_info_list_files(package_name)
+
+    @classmethod
+    def refresh_build_packages_list(cls) -> None:
+        """Refresh the list of packages available in the repository."""
+        try:
+            cmd = ["sudo", "--preserve-env", "apk", "update", "--no-progress"]
+            logger.debug("Executing: %s", cmd)
+            subprocess.check_call(cmd)
+        except subprocess.CalledProcessError as call_error:
+            raise errors.PackageListRefreshError(
+                "failed to run apk update"
+            ) from call_error
+
+    @classmethod
+    def get_installed_packages(cls) -> List[str]:
+        """Obtain a list of the installed packages and their versions."""
+        return sorted(f"{k}={v}" for k, v in _get_installed_versions().items())
+
+    @classmethod
+    def refresh_stage_packages_list(
+        cls, *, application_name: str, target_arch: str
+    ) -> None:
+        """Refresh the list of packages available in the repository."""
+        command = ["apk", "update", *_get_stage_options(application_name, target_arch)]
+        logger.debug("Executing: %s", command)
+        try:
+            subprocess.check_call(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageListRefreshError("failed to run apk update") from err
+
+    @classmethod
+    def install_package_repositories(
+        cls,
+        repositories: Dict[str, Dict[str, Any]],
+        *,
+        keys_dir: pathlib.Path,  # pylint: disable=unused-argument
+    ) -> bool:
+        """Add package repositories to the host system.
+
+        Tagged repositories pin their packages. Repository names are not
+        used, and repository keys must be installed in ``/etc/apk/keys``.
+
+        :param repositories: A dictionary where the keys are unique repository
+            names and values are apk repository definitions.
+        :param keys_dir: The directory containing the repository keys.
+
+        :return: Whether the package lists must be refreshed.
+        """
+        changed = False
+        for data in repositories.values():
+            repository = ApkRepository.unmarshal(data)
+            changed |= install_apk_repository(repository)
+
+        return changed
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path: pathlib.Path) -> str:
+        """Obtain the name and version of a fetched stage package file."""
+        return _get_apk_name_version(package_path.stem)
+
+    @classmethod
+    def _is_version_installed(cls, package_name: str) -> bool:
+        """Verify if a package is installed, with the given version if set."""
+        return _is_version_installed(package_name, _get_installed_versions())
+
+    @classmethod
+    def _get_install_command(cls, package_names: List[str]) -> List[str]:
+        """Obtain the apk command to install build packages."""
+        return ["apk", "add", "--no-progress", *package_names]
+
+    @classmethod
+    def _list_build_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the build packages that would be installed."""
+        return _run_apk_add_simulate(package_names)
+
+    @classmethod
+    def _query_installed_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the versions of the given installed packages."""
+        installed = _get_installed_versions()
+        names = [_get_package_name(name) for name in package_names]
+        return [f"{name}={installed[name]}" for name in names if name in installed]
+
+    @classmethod
+    def _list_stage_packages(
+        cls,
+        package_names: List[str],
+        *,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the stage packages that would be fetched."""
+        command = [
+            *_get_apk_fetch_command(application_name, target_arch),
+            "--simulate",
+            *package_names,
+        ]
+        try:
+            output = subprocess.check_output(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageFetchError(str(err)) from err
+
+        # Simulated downloads are reported as "Downloading <name>-<version>".
+        fetched = [
+            line.split()[-1]
+            for line in output.decode().splitlines()
+            if line.startswith("Downloading ")
+        ]
+        return sorted({_get_apk_name_version(name) for name in fetched})
+
+    @classmethod
+    def _get_download_command(
+        cls,
+        package_names: List[str],
+        *,
+        download_dir: str,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the apk command to download stage packages."""
+        return [
+            *_get_apk_fetch_command(application_name, target_arch),
+            "--output",
+            download_dir,
+            *package_names,
+        ]
+
+    @classmethod
+    def _extract_package(cls, pkg_path: pathlib.Path, extract_dir: str) -> None:
+        """Extract the files of the given apk package.
+
+        Apk packages are concatenated gzipped tarballs containing the package
+        signature, metadata and files. The package signature and metadata are
+        removed after extraction.
+        """
+        command = [
+            "tar",
+            "--extract",
+            "--gzip",
+            "--ignore-zeros",
+            "--warning=no-unknown-keyword",
+            f"--file={pkg_path}",
+            f"--directory={extract_dir}",
+        ]
+        try:
+            subprocess.check_call(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.UnpackError(str(pkg_path)) from err
+
+        for name in os.listdir(extract_dir):
+            if name.startswith(_METADATA_PREFIXES):
+                os.remove(os.path.join(extract_dir, name))
+
+
+def _get_package_name(package_name: str) -> str:
+    """Obtain the package name without version or repository tag."""
+    name = get_pkg_name_parts(package_name)[0]
+    return name.split("@", 1)[0]
+
+
+def _is_version_installed(package_name: str, installed: Dict[str, str]) -> bool:
+    """Verify if a package is installed, with the given version if set."""
+    name = _get_package_name(package_name)
+    version = get_pkg_name_parts(package_name)[1]
+    if name not in installed:
+        return False
+
+    return version is None or installed[name] == version
+
+
+def _get_apk_name_version(name_version: str) -> str:
+    """Obtain ``name=version`` from an apk package file name.
+
+    Apk files are named ``<name>-<version>-r<release>.apk``.
+    """
+    name, version, release = name_version.rsplit("-", 2)
+    return f"{name}={version}-{release}"
+
+
+def _get_apk_fetch_command(application_name: str, target_arch: str) -> List[str]:
+    """Obtain the apk command used to fetch stage packages."""
+    return [
+        "apk",
+        "fetch",
+        "--recursive",
+        *_get_stage_options(application_name, target_arch),
+    ]
+
+
+def _get_stage_options(application_name: str, target_arch: str) -> List[str]:
+    """Obtain the apk options used to fetch stage packages."""
+    cache_dir = BaseDirectory.save_cache_path(
+        application_name, "craft-parts", "stage-packages-apk"
+    )
+    arch = _APK_ARCHITECTURES.get(target_arch, target_arch)
+    return ["--no-progress", f"--cache-dir={cache_dir}", f"--arch={arch}"]
+
+
+def _get_installed_versions() -> Dict[str, str]:
+    """Obtain the versions of the installed packages, indexed by name."""
+    output = subprocess.check_output(["apk", "info", "--verbose"])
+
+    installed: Dict[str, str] = {}
+    for line in output.decode().split():
+        name, version = _get_apk_name_version(line).split("=", 1)
+        installed[name] = version
+
+    return installed
+
+
+def _run_apk_add_simulate(package_names: List[str]) -> List[str]:
+    """Obtain ``name=version`` of the packages that would be installed."""
+    try:
+        output = subprocess.check_output(
+            ["apk", "add", "--simulate", "--no-progress", *package_names]
+        )
+    except subprocess.CalledProcessError as err:
+        raise errors.PackageFetchError(str(err)) from err
+
+    return sorted(
+        {f"{n}={v}" for n, v in _SIMULATED_INSTALL_REGEX.findall(output.decode())}
+    )
+
+
+@functools.lru_cache(maxsize=256)
+def _run_apk_info_list_files(package_name: str) -> Set[str]:
+    output = subprocess.check_output(["apk", "info", "--contents", package_name])
+
+    # Files are listed relative to the root directory, after a
+    # "<name>-<version> contains:" header line.
+    files = [os.path.join("/", i) for i in output.decode().split()[2:]]
+    return {i for i in files if ("lib" in i and os.path.isfile(i))}
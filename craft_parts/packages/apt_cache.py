@@ -32,7 +32,9 @@ import apt.package
 import apt.progress
 import apt.progress.text
 
-from craft_parts.utils import os_utils
+from craft_parts import progress
+from craft_parts.sources.cache import FileCache
+from craft_parts.utils import file_utils, os_utils
 
 from . import errors
 from .base import get_pkg_name_parts
@@ -51,9 +53,15 @@ class AptCache(ContextDecorator):
         *,
         stage_cache: Optional[Path] = None,
         stage_cache_arch: Optional[str] = None,
+        stage_cache_foreign_archs: Optional[Set[str]] = None,
+        install_recommends: bool = False,
+        install_suggests: bool = False,
     ) -> None:
         self.stage_cache = stage_cache
         self.stage_cache_arch = stage_cache_arch
+        self.stage_cache_foreign_archs = stage_cache_foreign_archs or set()
+        self.install_recommends = install_recommends
+        self.install_suggests = install_suggests
         self.progress = None
 
     # pylint: disable=attribute-defined-outside-init
@@ -74,8 +82,11 @@ class AptCache(ContextDecorator):
         self.cache.close()
 
     def _configure_apt(self):
-        # Do not install recommends.
-        apt.apt_pkg.config.set("Apt::Install-Recommends", "False")
+        # Do not install recommends or suggests unless requested.
+        apt.apt_pkg.config.set(
+            "Apt::Install-Recommends", str(self.install_recommends)
+        )
+        apt.apt_pkg.config.set("Apt::Install-Suggests", str(self.install_suggests))
 
         # Ensure repos are provided by trusted third-parties.
         apt.apt_pkg.config.set("Acquire::AllowInsecureRepositories", "False")
@@ -115,7 +126,8 @@ class AptCache(ContextDecorator):
         (2) Delete current-style (copied) tree.
         (3) Copy current host apt configuration.
         (4) Configure primary arch to target arch.
-        (5) Install dpkg into cache directory to support multi-arch.
+        (5) Configure foreign archs of the requested packages.
+        (6) Install dpkg into cache directory to support multi-arch.
         """
         if self.stage_cache is None:
             return
@@ -138,6 +150,14 @@ class AptCache(ContextDecorator):
             arch_conf_path = cache_etc_apt_path / "apt.conf.d" / "00default-arch"
             arch_conf_path.write_text(f'APT::Architecture "{self.stage_cache_arch}";\n')
 
+        # Enable foreign architectures (if any), in addition to the primary arch.
+        if self.stage_cache_foreign_archs:
+            primary_arch = self.stage_cache_arch or apt.apt_pkg.get_architectures()[0]
+            archs = [primary_arch, *sorted(self.stage_cache_foreign_archs)]
+            archs_conf_path = cache_etc_apt_path / "apt.conf.d" / "01foreign-archs"
+            archs_conf = "".join(f' "{arch}";' for arch in archs)
+            archs_conf_path.write_text(f"APT::Architectures {{{archs_conf} }};\n")
+
         # dpkg also needs to be in the rootdir in order to support multiarch
         # (apt calls dpkg --print-foreign-architectures).
         dpkg_path = shutil.which("dpkg")
@@ -196,22 +216,85 @@ class AptCache(ContextDecorator):
                 return installed.version
         return None
 
-    def fetch_archives(self, download_path: Path) -> List[Tuple[str, str, Path]]:
+    def get_version_satisfying(
+        self, package_name: str, *, relation: str, version: str
+    ) -> str:
+        """Obtain the package version that satisfies a version constraint.
+
+        The installed version is used if it satisfies the constraint, otherwise
+        the newest available version that satisfies the constraint is used.
+
+        :param package_name: The name of the package.
+        :param relation: The constraint relation, one of ``<<``, ``<=``, ``=``,
+            ``>=`` or ``>>``.
+        :param version: The version to compare with.
+
+        :return: The version of the package that satisfies the constraint.
+
+        :raise errors.PackageNotFound: If the package doesn't exist.
+        :raise errors.PackageVersionNotSatisfied: If no version of the package
+            satisfies the constraint.
+        """
+        if package_name not in self.cache:
+            raise errors.PackageNotFound(package_name)
+
+        package = self.cache[package_name]
+        installed = package.installed
+        if installed is not None and apt.apt_pkg.check_dep(
+            installed.version, relation, version
+        ):
+            return installed.version
+
+        available = sorted(package.versions, reverse=True)
+        for pkg_version in available:
+            if apt.apt_pkg.check_dep(pkg_version.version, relation, version):
+                return pkg_version.version
+
+        raise errors.PackageVersionNotSatisfied(
+            package_name,
+            constraint=f"{relation} {version}",
+            versions=[v.version for v in available],
+        )
+
+    def fetch_archives(
+        self, download_path: Path, *, file_cache: Optional[FileCache] = None
+    ) -> List[Tuple[str, str, Path]]:
         """Retrieve packages marked to be fetched.
 
         :param download_path: The directory to download files to.
+        :param file_cache: A cache of deb files shared with other projects,
+            keyed by the sha256 digest of the files.
 
         :return: A list of (<package-name>, <package-version>, <dl-path>) tuples.
         """
         downloaded = list()
         for package in self.cache.get_changes():
-            if package.candidate is None:
+            candidate = package.candidate
+            if candidate is None:
                 continue
 
+            key = f"sha256/{candidate.sha256}" if candidate.sha256 else None
+            if file_cache and key:
+                # Files already in the download directory with the expected
+                # digest are not downloaded again.
+                cached_path = file_cache.get(key=key)
+                deb_path = download_path / os.path.basename(candidate.filename)
+                if cached_path and not deb_path.exists():
+                    file_utils.link_or_copy(cached_path, str(deb_path))
+
+            reporter = progress.ProgressReporter(
+                progress.DownloadKind.PACKAGE, package.name, total=candidate.size
+            )
+            reporter.start()
             try:
-                dl_path = package.candidate.fetch_binary(str(download_path))
+                dl_path = candidate.fetch_binary(str(download_path))
             except apt.package.FetchError as err:
                 raise errors.PackageFetchError(str(err))
+            reporter.update(candidate.size)
+            reporter.finish()
+
+            if file_cache and key:
+                file_cache.cache(filename=dl_path, key=key)
 
             downloaded.append((package.name, package.candidate.version, Path(dl_path)))
         return downloaded
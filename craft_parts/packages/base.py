@@ -14,23 +14,50 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-"""Definition and helpers for the repository base class."""
+"""Definition and helpers for the repository base class.
+
+Repository classes are the package backends used by craft-parts: each one
+resolves, installs, fetches and unpacks the packages of a distribution and
+manages its package repositories. Applications can provide backends for
+distributions not supported by craft-parts by registering subclasses of
+:class:`BaseRepository`.
+"""
 
 import abc
 import contextlib
 import logging
 import os
 from pathlib import Path
-from typing import List, Optional, Set, Tuple, Type
+from typing import Any, Dict, List, Optional, Set, Tuple, Type
 
 from craft_parts import xattrs
 
+from . import errors
+
 logger = logging.getLogger(__name__)
 
 
 class BaseRepository(abc.ABC):
     """Base implementation for a platform specific repository handler."""
 
+    # Translations from the Debian package names used by plugins and part
+    # definitions to the package names of the repository distribution.
+    package_names: Dict[str, str] = {}
+
+    @classmethod
+    def get_package_name(cls, package_name: str) -> str:
+        """Obtain the name of a package in the repository distribution.
+
+        :param package_name: The Debian package name, with an optional
+            version in the ``name=version`` format.
+
+        :return: The translated package name. Names without a translation
+            are returned unchanged.
+        """
+        name, version = get_pkg_name_parts(package_name)
+        name = cls.package_names.get(name, name)
+        return f"{name}={version}" if version else name
+
     @classmethod
     @abc.abstractmethod
     def get_package_libraries(cls, package_name: str) -> Set[str]:
@@ -62,8 +89,6 @@ class BaseRepository(abc.ABC):
         should be raised.
         """
 
-    # XXX: list-only functionality can be a method called by install_build_packages
-
     @classmethod
     @abc.abstractmethod
     def install_build_packages(
@@ -87,6 +112,17 @@ class BaseRepository(abc.ABC):
         :return: A list with the packages installed and their versions.
         """
 
+    @classmethod
+    def resolve_build_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the packages that would be installed on the host system.
+
+        :param package_names: A list of package names to resolve.
+
+        :return: A list with the packages to install and their versions,
+            including dependencies.
+        """
+        return cls.install_build_packages(package_names, list_only=True)
+
     @classmethod
     @abc.abstractmethod
     def is_package_installed(cls, package_name: str) -> bool:
@@ -130,6 +166,8 @@ class BaseRepository(abc.ABC):
         base: str,
         target_arch: str,
         list_only: bool = False,
+        recommends: bool = False,
+        suggests: bool = False,
     ) -> List[str]:
         """Fetch stage packages to stage_packages_path.
 
@@ -141,9 +179,44 @@ class BaseRepository(abc.ABC):
         :param target_arch: The architecture of the packages to fetch.
         :param list_only: Whether to obtain a list of packages to be fetched
             instead of actually fetching the packages.
+        :param recommends: Whether to also fetch the packages recommended by
+            the requested packages, if supported by the package manager.
+        :param suggests: Whether to also fetch the packages suggested by
+            the requested packages, if supported by the package manager.
+
+        :return: The list of all packages to be fetched, including dependencies.
+
+        :raise StagePackagesOptionNotSupported: If recommended or suggested
+            packages are requested and the package manager can't fetch them.
+        """
+
+    @classmethod
+    def resolve_stage_packages(
+        cls,
+        *,
+        application_name: str,
+        package_names: List[str],
+        base: str,
+        target_arch: str,
+    ) -> List[str]:
+        """Obtain the packages that would be fetched as stage packages.
+
+        :param application_name: A unique identifier for the application
+            using Craft Parts.
+        :param package_names: A list with the names of the packages to resolve.
+        :param base: The base this project will run on.
+        :param target_arch: The architecture of the packages to fetch.
 
         :return: The list of all packages to be fetched, including dependencies.
         """
+        return cls.fetch_stage_packages(
+            application_name=application_name,
+            package_names=package_names,
+            stage_packages_path=Path(),
+            base=base,
+            target_arch=target_arch,
+            list_only=True,
+        )
 
     @classmethod
     @abc.abstractmethod
@@ -157,6 +230,55 @@ class BaseRepository(abc.ABC):
         :param install_path: The path stage packages will be unpacked to.
         """
 
+    @classmethod
+    def install_package_repositories(
+        cls,
+        repositories: Dict[str, Dict[str, Any]],
+        *,
+        keys_dir: Path,
+    ) -> bool:
+        """Add package repositories to the host system.
+
+        :param repositories: A dictionary where the keys are unique repository
+            names and values are the backend-specific repository definitions.
+        :param keys_dir: The directory containing the repository keys.
+
+        :return: Whether the repositories changed and the list of available
+            packages must be refreshed.
+
+        :raise PackageRepositoriesNotSupported: If the backend can't manage
+            package repositories.
+        """
+        raise errors.PackageRepositoriesNotSupported(backend=cls.__name__)
+
+    @classmethod
+    def get_overlay_install_commands(cls, package_names: List[str]) -> List[List[str]]:
+        """Obtain the commands to install packages in an overlay layer.
+
+        The commands are executed as the superuser in a chroot of the overlay.
+
+        :param package_names: The packages to install.
+
+        :return: The list of commands to execute, in order.
+
+        :raise OverlayPackagesNotSupported: If the backend can't install
+            packages in overlay layers.
+        """
+        raise errors.OverlayPackagesNotSupported(backend=cls.__name__)
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path: Path) -> str:
+        """Obtain the name and version of a fetched stage package file.
+
+        :param package_path: The path to the stage package file.
+
+        :return: The package name and version in the form package=version.
+
+        :raise PackageLockNotSupported: If the backend can't identify package
+            files, and stage packages can't be locked.
+        """
+        raise errors.PackageLockNotSupported(backend=cls.__name__)
+
 
 RepositoryType = Type[BaseRepository]
 
@@ -215,6 +337,15 @@ class DummyRepository(BaseRepository):
     ) -> None:
         """Unpack stage packages to install_path."""
 
+    @classmethod
+    def install_package_repositories(
+        cls,
+        *args,
+        **kwargs,  # pylint: disable=unused-argument
+    ) -> bool:
+        """Add package repositories to the host system."""
+        return False
+
 
 def get_pkg_name_parts(pkg_name: str) -> Tuple[str, Optional[str]]:
     """Break package name into base parts."""
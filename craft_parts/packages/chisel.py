@@ -0,0 +1,166 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Cut package slices with chisel.
+
+Stage packages named ``<package>_<slice>`` are chisel slices, since package
+names can't contain underscores. Slices are cut using the slice definitions
+of a chisel release, which can be a project-local directory containing a
+``chisel.yaml`` file, or a branch or commit of the upstream chisel-releases
+repository. If no release is specified, chisel selects the release matching
+the host system.
+"""
+
+import logging
+import re
+import subprocess
+from pathlib import Path
+from typing import List, Optional
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+CHISEL_RELEASES_URL = "https://github.com/canonical/chisel-releases"
+
+_REF_REGEX = re.compile(r"^[A-Za-z0-9][A-Za-z0-9._/-]*$")
+_COMMIT_REGEX = re.compile(r"^[0-9a-f]{40}$")
+
+
+def is_slice(package_name: str) -> bool:
+    """Verify whether the given stage package is a chisel slice.
+
+    :param package_name: The stage package name.
+    """
+    return "_" in package_name
+
+
+def get_slices(package_names: List[str]) -> List[str]:
+    """Obtain the chisel slices listed in the given stage packages.
+
+    :param package_names: The stage package names.
+
+    :return: The sorted list of slices.
+    """
+    return sorted(name for name in package_names if is_slice(name))
+
+
+def resolve_release(
+    release: str, *, project_dir: Path, cache_dir: Path
+) -> Optional[Path]:
+    """Obtain the directory containing the slice definitions to use.
+
+    :param release: A directory relative to the project directory, or a
+        branch or commit of the chisel-releases repository.
+    :param project_dir: The project directory.
+    :param cache_dir: The directory to clone chisel-releases into.
+
+    :return: The release directory, or None to use the chisel default.
+
+    :raise errors.InvalidChiselRelease: If the release is not valid.
+    """
+    if not release:
+        return None
+
+    release_dir = project_dir / release
+    if release_dir.is_dir():
+        if not (release_dir / "chisel.yaml").is_file():
+            raise errors.InvalidChiselRelease(
+                release, message="chisel.yaml not found in the release directory"
+            )
+        return release_dir
+
+    if not _REF_REGEX.match(release):
+        raise errors.InvalidChiselRelease(
+            release, message="not a directory or chisel-releases reference"
+        )
+
+    return _checkout_release(release, cache_dir=cache_dir)
+
+
+def cut_slices(
+    slices: List[str],
+    *,
+    install_path: Path,
+    release_dir: Optional[Path] = None,
+    target_arch: Optional[str] = None,
+) -> None:
+    """Cut the given slices into the install directory.
+
+    :param slices: The slices to cut.
+    :param install_path: The directory to install the slice contents into.
+    :param release_dir: The directory containing the slice definitions.
+    :param target_arch: The architecture of the packages to cut.
+
+    :raise errors.ChiselError: If chisel fails to cut the slices.
+    """
+    if not slices:
+        return
+
+    command = ["chisel", "cut", f"--root={install_path}"]
+    if release_dir:
+        command.append(f"--release={release_dir}")
+    if target_arch:
+        command.append(f"--arch={target_arch}")
+    command.extend(slices)
+
+    logger.debug("Running: %s", " ".join(command))
+    install_path.mkdir(parents=True, exist_ok=True)
+    try:
+        subprocess.run(command, check=True)
+    except subprocess.CalledProcessError as err:
+        raise errors.ChiselError(
+            slices, message=f"chisel exited with code {err.returncode}"
+        ) from err
+
+
+def _checkout_release(ref: str, *, cache_dir: Path) -> Path:
+    """Check out the given chisel-releases reference in the cache directory.
+
+    Commits that are already checked out are not fetched again.
+    """
+    release_dir = cache_dir / "chisel-releases" / ref.replace("/", "_")
+    git = ["git", "-C", str(release_dir)]
+
+    if _COMMIT_REGEX.match(ref) and (release_dir / "chisel.yaml").is_file():
+        head = subprocess.check_output([*git, "rev-parse", "HEAD"]).decode().strip()
+        if head == ref:
+            return release_dir
+
+    commands = [
+        [*git, "fetch", "--quiet", "--depth=1", "origin", ref],
+        [*git, "checkout", "--quiet", "--force", "FETCH_HEAD"],
+    ]
+    if not (release_dir / ".git").is_dir():
+        release_dir.mkdir(parents=True, exist_ok=True)
+        commands.insert(0, [*git, "init", "--quiet"])
+        commands.insert(1, [*git, "remote", "add", "origin", CHISEL_RELEASES_URL])
+
+    try:
+        for command in commands:
+            logger.debug("Running: %s", " ".join(command))
+            subprocess.run(command, check=True)
+    except subprocess.CalledProcessError as err:
+        raise errors.InvalidChiselRelease(
+            ref, message=f"failed to fetch from {CHISEL_RELEASES_URL}"
+        ) from err
+
+    if not (release_dir / "chisel.yaml").is_file():
+        raise errors.InvalidChiselRelease(
+            ref, message="chisel.yaml not found in the release"
+        )
+
+    return release_dir
@@ -25,15 +25,22 @@ import re
 import subprocess
 import sys
 import tempfile
-from typing import Dict, List, Set, Tuple
+from typing import Any, Dict, List, Optional, Set, Tuple
 
 from xdg import BaseDirectory  # type: ignore
 
+from craft_parts.sources.cache import FileCache
 from craft_parts.utils import file_utils, os_utils
 
 from . import errors
 from .base import BaseRepository, get_pkg_name_parts, mark_origin_stage_package
 from .deb_package import DebPackage
+from .repositories import (
+    AptCloudArchive,
+    AptRepository,
+    install_cloud_archive,
+    install_repository,
+)
 
 if sys.platform == "linux":
     # Ensure importing works on non-Linux.
@@ -42,7 +49,14 @@ if sys.platform == "linux":
 logger = logging.getLogger(__name__)
 
 
+# The maximum size of the deb file cache shared by all projects.
+DEB_FILE_CACHE_MAX_SIZE = 4 * 1024 ** 3
+
 _HASHSUM_MISMATCH_PATTERN = re.compile(r"(E:Failed to fetch.+Hash Sum mismatch)+")
+_VERSION_CONSTRAINT_PATTERN = re.compile(
+    r"^(?P<name>[^\s(]+)\s*"
+    r"\(\s*(?P<relation><<|<=|=|>=|>>)\s*(?P<version>[^\s)]+)\s*\)$"
+)
 _DEFAULT_FILTERED_STAGE_PACKAGES: List[str] = [
     "adduser",
     "apt",
@@ -253,6 +267,125 @@ def _get_filtered_stage_package_names(
     )
 
 
+def get_foreign_architectures(
+    package_names: List[str], *, target_arch: str
+) -> Set[str]:
+    """Obtain the foreign architectures of arch-qualified package names.
+
+    Packages named ``<package>:<arch>`` are fetched for the given architecture
+    instead of the target architecture.
+
+    :param package_names: The stage package names.
+    :param target_arch: The architecture of unqualified packages.
+
+    :return: The architectures other than the target architecture.
+    """
+    archs: Set[str] = set()
+    for name in package_names:
+        name_arch = get_pkg_name_parts(name)[0]
+        if ":" not in name_arch:
+            continue
+
+        arch = name_arch.split(":", 1)[1]
+        if arch not in ("any", "all", "native", target_arch):
+            archs.add(arch)
+
+    return archs
+
+
+def get_local_deb_files(
+    package_names: List[str],
+) -> Tuple[List[str], List[pathlib.Path]]:
+    """Separate local deb files from the package names to obtain from the archive.
+
+    Entries ending in ``.deb`` are local package files, and entries containing
+    a path separator are either local package files or directories containing
+    local package files. Relative paths are resolved from the current directory.
+
+    :param package_names: The list of package names and local paths.
+
+    :return: A tuple containing the names of the packages to obtain from the
+        archive and the local deb files.
+
+    :raise errors.LocalPackageNotFound: If a local path doesn't exist.
+    """
+    names: List[str] = []
+    deb_files: List[pathlib.Path] = []
+    for name in package_names:
+        if not name.endswith(".deb") and os.sep not in name:
+            names.append(name)
+            continue
+
+        path = pathlib.Path(name)
+        if path.is_dir():
+            deb_files.extend(sorted(path.glob("*.deb")))
+        elif path.is_file():
+            deb_files.append(path)
+        else:
+            raise errors.LocalPackageNotFound(name)
+
+    return names, deb_files
+
+
+def get_deb_dependencies(deb_files: List[pathlib.Path]) -> Set[str]:
+    """Obtain the dependencies of local deb files.
+
+    Only the first alternative of each dependency is used, version constraints
+    are ignored, and dependencies provided by the local deb files are omitted.
+
+    :param deb_files: The local deb files.
+
+    :return: The names of the packages the local deb files depend on.
+    """
+    provided: Set[str] = set()
+    depends: Set[str] = set()
+    for deb_path in deb_files:
+        try:
+            output = subprocess.check_output(
+                [
+                    "dpkg-deb",
+                    "--show",
+                    "--showformat=${Package}\n${Pre-Depends}, ${Depends}",
+                    deb_path,
+                ]
+            )
+        except subprocess.CalledProcessError as err:
+            raise errors.UnpackError(str(deb_path)) from err
+
+        package, _, relations = output.decode().partition("\n")
+        provided.add(package.strip())
+        for relation in relations.split(","):
+            dependency = relation.split("|")[0].split("(")[0].strip()
+            if dependency:
+                depends.add(dependency)
+
+    return {dep for dep in depends if dep.split(":")[0] not in provided}
+
+
+def get_version_constraint(package_name: str) -> Tuple[str, Optional[Tuple[str, str]]]:
+    """Obtain the version constraint of a package name.
+
+    Constraints are expressed as in Debian package relationships, for
+    example ``gcc (>= 4:9.3)``.
+
+    :param package_name: The package name, optionally followed by a version
+        constraint.
+
+    :return: A tuple containing the package name and, if a constraint is
+        given, a tuple with the constraint relation and version.
+
+    :raise errors.InvalidVersionConstraint: If the constraint can't be parsed.
+    """
+    if "(" not in package_name:
+        return package_name, None
+
+    match = _VERSION_CONSTRAINT_PATTERN.match(package_name.strip())
+    if not match:
+        raise errors.InvalidVersionConstraint(package_name)
+
+    return match.group("name"), (match.group("relation"), match.group("version"))
+
+
 def get_packages_in_base(*, base: str) -> List[DebPackage]:
     """Get the list of packages for the given base."""
     # We do not want to break what we already have.
@@ -354,6 +487,35 @@ class Ubuntu(BaseRepository):
 
             return apt_cache.get_packages_marked_for_installation()
 
+    @classmethod
+    def _resolve_version_constraints(cls, package_names: List[str]) -> List[str]:
+        """Replace version constraints with the versions that satisfy them."""
+        constraints = [get_version_constraint(name) for name in package_names]
+        if all(constraint is None for _, constraint in constraints):
+            return package_names
+
+        resolved: List[str] = []
+        with AptCache() as apt_cache:
+            for name, constraint in constraints:
+                if constraint is None:
+                    resolved.append(name)
+                    continue
+
+                relation, version = constraint
+                try:
+                    pkg_version = apt_cache.get_version_satisfying(
+                        name, relation=relation, version=version
+                    )
+                except errors.PackageNotFound as error:
+                    raise errors.BuildPackageNotFound(error.package_name) from error
+
+                logger.debug(
+                    "Using %s=%s to satisfy %s %s", name, pkg_version, relation, version
+                )
+                resolved.append(f"{name}={pkg_version}")
+
+        return resolved
+
     @classmethod
     def install_build_packages(
         cls, package_names: List[str], list_only: bool = False
@@ -367,23 +529,46 @@ class Ubuntu(BaseRepository):
 
         logger.debug("Requested build-packages: %s", package_names)
 
+        # Local deb files are installed directly, and their dependencies
+        # are obtained from the archive.
+        package_names, deb_files = get_local_deb_files(package_names)
+        package_names = cls._resolve_version_constraints(package_names)
+        local_packages = [cls._extract_deb_name_version(p) for p in deb_files]
+        archive_names = package_names + sorted(get_deb_dependencies(deb_files))
+
         # Ensure we have an up-to-date cache first if we will have to
         # install anything.
-        if not cls._check_if_all_packages_installed(package_names):
+        if not cls._check_if_all_packages_installed(package_names + local_packages):
             install_required = True
             # refresh the build package list before planning for consistency
             # cls.refresh_build_packages()
 
-        marked_packages = cls._get_packages_marked_for_installation(package_names)
+        marked_packages = cls._get_packages_marked_for_installation(archive_names)
         packages = [f"{name}={version}" for name, version in sorted(marked_packages)]
 
         if not list_only:
             if install_required:
-                cls._install_packages(packages)
+                cls._install_packages(packages + [str(p.absolute()) for p in deb_files])
             else:
                 logger.debug("Requested build-packages already installed: %s", packages)
 
-        return packages
+        return packages + local_packages
+
+    @classmethod
+    def get_overlay_install_commands(cls, package_names: List[str]) -> List[List[str]]:
+        """Obtain the apt commands to install packages in an overlay layer."""
+        return [
+            ["apt-get", "update"],
+            [
+                "env",
+                "DEBIAN_FRONTEND=noninteractive",
+                "apt-get",
+                "--no-install-recommends",
+                "-y",
+                "install",
+                *package_names,
+            ],
+        ]
 
     @classmethod
     def _install_packages(cls, package_names: List[str]) -> None:
@@ -414,7 +599,9 @@ class Ubuntu(BaseRepository):
         except subprocess.CalledProcessError as err:
             raise errors.BuildPackagesNotInstalled(packages=package_names) from err
 
-        versionless_names = [get_pkg_name_parts(p)[0] for p in package_names]
+        versionless_names = [
+            get_pkg_name_parts(p)[0] for p in package_names if not p.endswith(".deb")
+        ]
         try:
             subprocess.check_call(
                 ["sudo", "apt-mark", "auto"] + versionless_names, env=env
@@ -432,13 +619,24 @@ class Ubuntu(BaseRepository):
         base: str,
         target_arch: str,
         list_only: bool = False,
+        recommends: bool = False,
+        suggests: bool = False,
     ) -> List[str]:
-        """Fetch stage packages to stage_packages_path."""
+        """Fetch stage packages to stage_packages_path.
+
+        Packages can be qualified with an architecture, as in ``libc6:i386``,
+        to fetch them for an architecture other than the target architecture.
+        Local deb files are staged as they are, and their dependencies are
+        fetched from the archive. Recommended and suggested packages are only
+        fetched if requested.
+        """
         logger.debug("Requested stage-packages: %s", sorted(package_names))
 
         if not package_names:
             return []
 
+        package_names, deb_files = get_local_deb_files(package_names)
+
         filtered_names = _get_filtered_stage_package_names(
             base=base,
             package_list=[DebPackage.from_unparsed(name) for name in package_names],
@@ -448,13 +646,34 @@ class Ubuntu(BaseRepository):
             stage_packages_path.mkdir(exist_ok=True)
 
         stage_cache_dir, deb_cache_dir = get_cache_dirs(application_name)
+        foreign_archs = get_foreign_architectures(
+            package_names, target_arch=target_arch
+        )
 
         installed: Set[str] = set()
 
+        for deb_path in deb_files:
+            installed.add(cls._extract_deb_name_version(deb_path))
+            if not list_only:
+                file_utils.link_or_copy(
+                    str(deb_path), str(stage_packages_path / deb_path.name)
+                )
+
         with AptCache(
-            stage_cache=stage_cache_dir, stage_cache_arch=target_arch
+            stage_cache=stage_cache_dir,
+            stage_cache_arch=target_arch,
+            stage_cache_foreign_archs=foreign_archs,
+            install_recommends=recommends,
+            install_suggests=suggests,
         ) as apt_cache:
-            apt_cache.mark_packages(set(package_names))
+            # The package lists of foreign architectures are not fetched
+            # when refreshing the stage packages list.
+            if foreign_archs:
+                apt_cache.update()
+
+            apt_cache.mark_packages(
+                set(package_names) | get_deb_dependencies(deb_files)
+            )
             apt_cache.unmark_packages(filtered_names)
 
             if list_only:
@@ -463,14 +682,20 @@ class Ubuntu(BaseRepository):
                     f"{name}={version}" for name, version in sorted(marked_packages)
                 }
             else:
-                for pkg_name, pkg_version, dl_path in apt_cache.fetch_archives(
-                    deb_cache_dir
-                ):
-                    logger.debug("Extracting stage package: %s", pkg_name)
-                    installed.add(f"{pkg_name}={pkg_version}")
-                    file_utils.link_or_copy(
-                        str(dl_path), str(stage_packages_path / dl_path.name)
-                    )
+                # Downloaded files are kept in the shared deb file cache, which
+                # is bounded in size, instead of the download directory.
+                file_cache = get_deb_file_cache(application_name)
+                with tempfile.TemporaryDirectory(
+                    suffix="deb-download", dir=deb_cache_dir
+                ) as download_dir:
+                    for pkg_name, pkg_version, dl_path in apt_cache.fetch_archives(
+                        pathlib.Path(download_dir), file_cache=file_cache
+                    ):
+                        logger.debug("Extracting stage package: %s", pkg_name)
+                        installed.add(f"{pkg_name}={pkg_version}")
+                        file_utils.link_or_copy(
+                            str(dl_path), str(stage_packages_path / dl_path.name)
+                        )
 
         return sorted(installed)
 
@@ -518,6 +743,44 @@ class Ubuntu(BaseRepository):
                 for pkg_name, pkg_version in apt_cache.get_installed_packages().items()
             ]
 
+    @classmethod
+    def install_package_repositories(
+        cls,
+        repositories: Dict[str, Dict[str, Any]],
+        *,
+        keys_dir: pathlib.Path,
+    ) -> bool:
+        """Add package repositories to the host system.
+
+        :param repositories: A dictionary where the keys are unique repository
+            names and values are apt repository or Ubuntu Cloud Archive
+            definitions.
+        :param keys_dir: The directory containing the repository keys.
+
+        :return: Whether the package lists must be refreshed.
+        """
+        changed = False
+        for name, data in repositories.items():
+            if isinstance(data, dict) and "cloud" in data:
+                cloud_archive = AptCloudArchive.unmarshal(data)
+                changed |= install_cloud_archive(
+                    cloud_archive,
+                    name=name,
+                    keys_dir=keys_dir,
+                    series=os_utils.OsRelease().version_codename(),
+                )
+                continue
+
+            repository = AptRepository.unmarshal(data)
+            changed |= install_repository(repository, name=name, keys_dir=keys_dir)
+
+        return changed
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path: pathlib.Path) -> str:
+        """Obtain the name and version of a fetched stage package file."""
+        return cls._extract_deb_name_version(package_path)
+
     @classmethod
     def _extract_deb_name_version(cls, deb_path: pathlib.Path) -> str:
         try:
@@ -549,3 +812,26 @@ def get_cache_dirs(name: str):
     )
 
     return (stage_cache_dir, deb_cache_dir)
+
+
+def get_deb_file_cache(
+    name: str, *, max_size: Optional[int] = DEB_FILE_CACHE_MAX_SIZE
+) -> FileCache:
+    """Return the cache of downloaded deb files shared by all projects.
+
+    :param name: The name of the application using the cache.
+    :param max_size: The maximum size of the cache in bytes.
+    """
+    return FileCache(name, namespace="stage-packages-debs", max_size=max_size)
+
+
+def prune_deb_file_cache(name: str, *, max_size: Optional[int] = None) -> int:
+    """Remove the least recently used files from the shared deb file cache.
+
+    :param name: The name of the application using the cache.
+    :param max_size: The size to reduce the cache to, in bytes. Defaults to
+        the maximum size of the cache. Use zero to remove all files.
+
+    :return: The number of bytes removed from the cache.
+    """
+    return get_deb_file_cache(name).prune(max_size=max_size)
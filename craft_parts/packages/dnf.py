@@ -0,0 +1,249 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Support for rpm packages using dnf."""
+
+import logging
+import os
+import pathlib
+import subprocess
+from typing import Any, Dict, List, Set
+
+from xdg import BaseDirectory  # type: ignore
+
+from . import errors, rpm
+from .base import get_pkg_name_parts
+from .package_manager import PackageManagerRepository
+from .repositories import RpmRepository, install_rpm_repository
+
+logger = logging.getLogger(__name__)
+
+# Translation from the deb architectures used by craft-parts to rpm
+# architectures.
+_RPM_ARCHITECTURES = {
+    "amd64": "x86_64",
+    "arm64": "aarch64",
+    "armhf": "armv7hl",
+    "i386": "i686",
+    "ppc64el": "ppc64le",
+    "s390x": "s390x",
+}
+
+
+class DNFRepository(PackageManagerRepository):
+    """Repository management for Fedora and Enterprise Linux packages.
+
+    Stage packages are downloaded with ``dnf download``, provided by the
+    dnf plugins, using a package metadata cache specific to the application
+    so that fetching stage packages doesn't require superuser privileges.
+    """
+
+    package_suffix = ".rpm"
+
+    package_names = {
+        "g++": "gcc-c++",
+        "python3-dev": "python3-devel",
+        "python3-venv": "python3",
+        "zlib1g-dev": "zlib-devel",
+        "libssl-dev": "openssl-devel",
+        "libffi-dev": "libffi-devel",
+        "libyaml-dev": "libyaml-devel",
+        "libgmp-dev": "gmp-devel",
+        "libpcre2-dev": "pcre2-devel",
+        "libevent-dev": "libevent-devel",
+        "xz-utils": "xz",
+        "pkg-config": "pkgconf-pkg-config",
+        "default-jdk-headless": "java-latest-openjdk-headless",
+        "erlang-dev": "erlang",
+    }
+
+    @classmethod
+    def get_package_libraries(cls, package_name: str) -> Set[str]:
+        """Return a list of libraries in package_name."""
+        return rpm.run_rpm_query_list_files(package_name)
+
+    @classmethod
+    def refresh_build_packages_list(cls) -> None:
+        """Refresh the list of packages available in the repository."""
+        try:
+            cmd = ["sudo", "--preserve-env", "dnf", "makecache", "--refresh"]
+            logger.debug("Executing: %s", cmd)
+            subprocess.check_call(cmd)
+        except subprocess.CalledProcessError as call_error:
+            raise errors.PackageListRefreshError(
+                "failed to run dnf makecache"
+            ) from call_error
+
+    @classmethod
+    def get_installed_packages(cls) -> List[str]:
+        """Obtain a list of the installed packages and their versions."""
+        return rpm.get_installed_packages()
+
+    @classmethod
+    def refresh_stage_packages_list(
+        cls, *, application_name: str, target_arch: str
+    ) -> None:
+        """Refresh the list of packages available in the repository."""
+        command = [
+            "dnf",
+            "makecache",
+            "--refresh",
+            *_get_stage_options(application_name, target_arch),
+        ]
+        logger.debug("Executing: %s", command)
+        try:
+            subprocess.check_call(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageListRefreshError("failed to run dnf makecache") from err
+
+    @classmethod
+    def install_package_repositories(
+        cls,
+        repositories: Dict[str, Dict[str, Any]],
+        *,
+        keys_dir: pathlib.Path,
+    ) -> bool:
+        """Add package repositories to the host system.
+
+        :param repositories: A dictionary where the keys are unique repository
+            names and values are rpm repository definitions.
+        :param keys_dir: The directory containing the repository keys.
+
+        :return: Whether the package lists must be refreshed.
+        """
+        changed = False
+        for name, data in repositories.items():
+            repository = RpmRepository.unmarshal(data)
+            changed |= install_rpm_repository(repository, name=name, keys_dir=keys_dir)
+
+        return changed
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path: pathlib.Path) -> str:
+        """Obtain the name and version of a fetched stage package file."""
+        return rpm.get_rpm_name_version(package_path.name)
+
+    @classmethod
+    def _get_install_command(cls, package_names: List[str]) -> List[str]:
+        """Obtain the dnf command to install build packages."""
+        specs = [_get_dnf_spec(name) for name in package_names]
+        return ["dnf", "install", "-y", *specs]
+
+    @classmethod
+    def _list_build_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the build packages that would be installed."""
+        return _run_dnf_repoquery([_get_dnf_spec(name) for name in package_names])
+
+    @classmethod
+    def _query_installed_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the versions of the given installed packages."""
+        return rpm.run_rpm_query([get_pkg_name_parts(n)[0] for n in package_names])
+
+    @classmethod
+    def _list_stage_packages(
+        cls,
+        package_names: List[str],
+        *,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the stage packages that would be fetched."""
+        command = [
+            *_get_dnf_download_command(application_name, target_arch),
+            "--url",
+            *[_get_dnf_spec(name) for name in package_names],
+        ]
+        try:
+            output = subprocess.check_output(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageFetchError(str(err)) from err
+
+        rpm_files = [
+            os.path.basename(line)
+            for line in output.decode().splitlines()
+            if line.endswith(".rpm")
+        ]
+        return sorted({rpm.get_rpm_name_version(name) for name in rpm_files})
+
+    @classmethod
+    def _get_download_command(
+        cls,
+        package_names: List[str],
+        *,
+        download_dir: str,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the dnf command to download stage packages."""
+        return [
+            *_get_dnf_download_command(application_name, target_arch),
+            "--destdir",
+            download_dir,
+            *[_get_dnf_spec(name) for name in package_names],
+        ]
+
+    @classmethod
+    def _extract_package(cls, pkg_path: pathlib.Path, extract_dir: str) -> None:
+        """Extract the files of the given rpm package."""
+        rpm.extract_rpm(pkg_path, extract_dir)
+
+
+def _get_dnf_spec(package_name: str) -> str:
+    """Convert a ``name=version`` package name to a dnf package spec."""
+    name, version = get_pkg_name_parts(package_name)
+    return f"{name}-{version}" if version else name
+
+
+def _get_dnf_download_command(application_name: str, target_arch: str) -> List[str]:
+    """Obtain the dnf command used to download stage packages."""
+    return [
+        "dnf",
+        "download",
+        "--resolve",
+        "--alldeps",
+        *_get_stage_options(application_name, target_arch),
+    ]
+
+
+def _get_stage_options(application_name: str, target_arch: str) -> List[str]:
+    """Obtain the dnf options used to fetch stage packages."""
+    cache_dir = BaseDirectory.save_cache_path(
+        application_name, "craft-parts", "stage-packages-dnf"
+    )
+    arch = _RPM_ARCHITECTURES.get(target_arch, target_arch)
+    return ["--quiet", f"--setopt=cachedir={cache_dir}", f"--forcearch={arch}"]
+
+
+def _run_dnf_repoquery(specs: List[str]) -> List[str]:
+    """Obtain ``name=version-release`` of the latest available packages."""
+    try:
+        output = subprocess.check_output(
+            [
+                "dnf",
+                "repoquery",
+                "--quiet",
+                "--latest-limit=1",
+                "--queryformat",
+                "%{name}=%{version}-%{release}\n",
+                *specs,
+            ]
+        )
+    except subprocess.CalledProcessError as err:
+        raise errors.PackageFetchError(str(err)) from err
+
+    return sorted(set(output.decode().split()))
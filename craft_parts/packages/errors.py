@@ -24,10 +24,14 @@ from craft_parts.errors import PartsError
 class PackagesError(PartsError):
     """Base class for package handler errors."""
 
+    code = "packages-error"
+
 
 class PackageNotFound(PackagesError):
     """Requested package doesn't exist in the remote repository."""
 
+    code = "package-not-found"
+
     def __init__(self, package_name: str):
         self.package_name = package_name
         brief = f"Package not found: {package_name}."
@@ -38,6 +42,8 @@ class PackageNotFound(PackagesError):
 class PackageFetchError(PackagesError):
     """Failed to fetch package from remote repository."""
 
+    code = "package-fetch-error"
+
     def __init__(self, message: str):
         self.message = message
         brief = f"Failed to fetch package: {message}."
@@ -48,6 +54,8 @@ class PackageFetchError(PackagesError):
 class PackageListRefreshError(PackagesError):
     """Failed to refresh the list of available packages."""
 
+    code = "package-list-refresh-error"
+
     def __init__(self, message: str):
         self.message = message
         brief = f"Failed to refresh package list: {message}."
@@ -58,6 +66,8 @@ class PackageListRefreshError(PackagesError):
 class PackageBroken(PackagesError):
     """Package has unmet dependencies."""
 
+    code = "package-broken"
+
     def __init__(self, package_name: str, *, deps: Sequence[str]):
         self.package_name = package_name
         self.deps = deps
@@ -69,6 +79,8 @@ class PackageBroken(PackagesError):
 class FileProviderNotFound(PackagesError):
     """A file is not provided by any package."""
 
+    code = "file-provider-not-found"
+
     def __init__(self, *, file_path: str):
         self.file_path = file_path
         brief = f"{file_path} is not provided by any package."
@@ -79,6 +91,8 @@ class FileProviderNotFound(PackagesError):
 class BuildPackageNotFound(PackagesError):
     """A package listed in 'build-packages' was not found."""
 
+    code = "build-package-not-found"
+
     def __init__(self, package):
         self.package = package
         brief = f"Cannot find package listed in 'build-packages': {package}"
@@ -89,6 +103,8 @@ class BuildPackageNotFound(PackagesError):
 class BuildPackagesNotInstalled(PackagesError):
     """Could not install all requested build packages."""
 
+    code = "build-packages-not-installed"
+
     def __init__(self, *, packages: Sequence[str]) -> None:
         self.packages = packages
         brief = f"Cannot install all requested build packages: {', '.join(packages)}"
@@ -99,6 +115,8 @@ class BuildPackagesNotInstalled(PackagesError):
 class UnpackError(PackagesError):
     """Error unpacking stage package."""
 
+    code = "unpack-error"
+
     def __init__(self, package: str):
         self.package = package
         brief = f"Error unpacking {package!r}"
@@ -109,6 +127,8 @@ class UnpackError(PackagesError):
 class SnapUnavailable(PackagesError):
     """Failed to install or refresh a snap."""
 
+    code = "snap-unavailable"
+
     def __init__(self, *, snap_name: str, snap_channel: str):
         self.snap_name = snap_name
         self.snap_channel = snap_channel
@@ -128,6 +148,8 @@ class SnapUnavailable(PackagesError):
 class SnapInstallError(PackagesError):
     """Failed to install a snap."""
 
+    code = "snap-install-error"
+
     def __init__(self, *, snap_name, snap_channel):
         self.snap_name = snap_name
         self.snap_channel = snap_channel
@@ -139,6 +161,8 @@ class SnapInstallError(PackagesError):
 class SnapDownloadError(PackagesError):
     """Failed to download a snap."""
 
+    code = "snap-download-error"
+
     def __init__(self, *, snap_name, snap_channel):
         self.snap_name = snap_name
         self.snap_channel = snap_channel
@@ -147,9 +171,24 @@ class SnapDownloadError(PackagesError):
         super().__init__(brief=brief)
 
 
+class SnapUnpackError(PackagesError):
+    """Failed to extract a snap."""
+
+    code = "snap-unpack-error"
+
+    def __init__(self, *, snap_file: str):
+        self.snap_file = snap_file
+        brief = f"Error extracting snap file {snap_file!r}."
+        resolution = "Make sure the unsquashfs command is installed."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
 class SnapRefreshError(PackagesError):
     """Failed to refresh a snap."""
 
+    code = "snap-refresh-error"
+
     def __init__(self, *, snap_name, snap_channel):
         self.snap_name = snap_name
         self.snap_channel = snap_channel
@@ -161,6 +200,8 @@ class SnapRefreshError(PackagesError):
 class SnapGetAssertionError(PackagesError):
     """Failed to retrieve snap assertion."""
 
+    code = "snap-get-assertion-error"
+
     def __init__(self, *, assertion_params: Sequence[str]) -> None:
         self.assertion_params = assertion_params
         brief = f"Error retrieving assertion with parameters {assertion_params!r}"
@@ -172,6 +213,8 @@ class SnapGetAssertionError(PackagesError):
 class SnapdConnectionError(PackagesError):
     """Failed to connect to snapd."""
 
+    code = "snapd-connection-error"
+
     def __init__(self, *, snap_name: str, url: str) -> None:
         self.snap_name = snap_name
         self.url = url
@@ -181,3 +224,219 @@ class SnapdConnectionError(PackagesError):
         )
 
         super().__init__(brief=brief)
+
+
+class PackageRepositoryKeyNotFound(PackagesError):
+    """The signing key of a package repository was not found."""
+
+    code = "package-repository-key-not-found"
+
+    def __init__(self, *, key_id: str, keys_dir: str) -> None:
+        self.key_id = key_id
+        self.keys_dir = keys_dir
+        brief = f"Failed to install repository: key {key_id!r} not found."
+        resolution = f"Add the ASCII-armored key to {keys_dir!r} as {key_id[-8:]}.asc."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class PackageRepositoriesNotSupported(PackagesError):
+    """The package backend can't manage package repositories."""
+
+    code = "package-repositories-not-supported"
+
+    def __init__(self, *, backend: str) -> None:
+        self.backend = backend
+        brief = f"Package repositories are not supported by {backend}."
+        resolution = "Remove the package repositories from the project."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class OverlayPackagesNotSupported(PackagesError):
+    """The package backend can't install packages in overlay layers."""
+
+    code = "overlay-packages-not-supported"
+
+    def __init__(self, *, backend: str) -> None:
+        self.backend = backend
+        brief = f"Installing overlay packages is not supported by {backend}."
+        resolution = "Install the packages using an overlay script."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class InvalidChiselRelease(PackagesError):
+    """The chisel release containing the slice definitions is not valid."""
+
+    code = "invalid-chisel-release"
+
+    def __init__(self, release: str, *, message: str) -> None:
+        self.release = release
+        self.message = message
+        brief = f"Invalid chisel release {release!r}: {message}."
+        resolution = (
+            "Make sure the release is a directory containing chisel.yaml, or "
+            "a branch or commit of chisel-releases."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class ChiselError(PackagesError):
+    """Failed to cut chisel slices."""
+
+    code = "chisel-error"
+
+    def __init__(self, slices: Sequence[str], *, message: str) -> None:
+        self.slices = slices
+        self.message = message
+        brief = f"Failed to cut slices {', '.join(slices)}: {message}."
+
+        super().__init__(brief=brief)
+
+
+class InvalidPackagesLockfile(PackagesError):
+    """The stage packages lockfile can't be read."""
+
+    code = "invalid-packages-lockfile"
+
+    def __init__(self, filename: str, *, message: str) -> None:
+        self.filename = filename
+        self.message = message
+        brief = f"Failed to read lockfile {filename!r}: {message}"
+        resolution = "Make sure the lockfile is valid or record it again."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class StagePackagesLockMismatch(PackagesError):
+    """The fetched stage packages don't match the lockfile."""
+
+    code = "stage-packages-lock-mismatch"
+
+    def __init__(self, *, message: str) -> None:
+        self.message = message
+        brief = f"Stage packages don't match the lockfile: {message}."
+        resolution = "Record the lockfile again to update the locked packages."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class PackageLockNotSupported(PackagesError):
+    """The package backend can't lock stage packages."""
+
+    code = "package-lock-not-supported"
+
+    def __init__(self, *, backend: str) -> None:
+        self.backend = backend
+        brief = f"Locking stage packages is not supported by {backend}."
+
+        super().__init__(brief=brief)
+
+
+class StagePackagesOptionNotSupported(PackagesError):
+    """The package backend can't fetch stage packages with the given option."""
+
+    code = "stage-packages-option-not-supported"
+
+    def __init__(self, option: str, *, backend: str) -> None:
+        self.option = option
+        self.backend = backend
+        brief = f"Stage packages option {option!r} is not supported by {backend}."
+        resolution = f"Remove {option!r} from the part properties."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class VersionConstraintNotSupported(PackagesError):
+    """The package backend can't resolve package version constraints."""
+
+    code = "version-constraint-not-supported"
+
+    def __init__(self, package: str, *, backend: str) -> None:
+        self.package = package
+        self.backend = backend
+        brief = f"Version constraint {package!r} is not supported by {backend}."
+        resolution = "Use the package name, or pin an exact version as 'name=version'."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class LocalPackageNotFound(PackagesError):
+    """A local package file listed in the part packages doesn't exist."""
+
+    code = "local-package-not-found"
+
+    def __init__(self, path: str) -> None:
+        self.path = path
+        brief = f"Local package not found: {path}."
+        resolution = "Make sure the package file path is correct."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class InvalidVersionConstraint(PackagesError):
+    """A package version constraint can't be parsed."""
+
+    code = "invalid-version-constraint"
+
+    def __init__(self, package: str) -> None:
+        self.package = package
+        brief = f"Invalid version constraint: {package!r}."
+        resolution = (
+            "Use a constraint such as 'name (>= version)', with one of the "
+            "relations <<, <=, =, >= or >>."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class PackageVersionNotSatisfied(PackagesError):
+    """No version of a package satisfies the requested version constraint."""
+
+    code = "package-version-not-satisfied"
+
+    def __init__(
+        self, package_name: str, *, constraint: str, versions: Sequence[str]
+    ) -> None:
+        self.package_name = package_name
+        self.constraint = constraint
+        self.versions = versions
+        brief = f"No version of {package_name!r} satisfies {constraint!r}."
+        if versions:
+            details = f"Available versions: {', '.join(versions)}."
+        else:
+            details = "No versions are available."
+
+        super().__init__(brief=brief, details=details)
+
+
+class InvalidSnapRevision(PackagesError):
+    """A snap is pinned to an invalid revision."""
+
+    code = "invalid-snap-revision"
+
+    def __init__(self, snap: str) -> None:
+        self.snap = snap
+        brief = f"Invalid snap revision: {snap!r}."
+        resolution = "Use '<snap-name>=<revision>', where the revision is a number."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class SnapAssertionError(PackagesError):
+    """The assertions of a snap revision could not be verified."""
+
+    code = "snap-assertion-error"
+
+    def __init__(self, *, snap_name: str, snap_revision: str, message: str) -> None:
+        self.snap_name = snap_name
+        self.snap_revision = snap_revision
+        self.message = message
+        brief = (
+            f"Failed to verify snap {snap_name!r} revision {snap_revision}: "
+            f"{message}."
+        )
+
+        super().__init__(brief=brief)
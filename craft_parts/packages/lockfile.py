@@ -0,0 +1,284 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Record and replay the exact versions of stage packages.
+
+A lockfile lists, for each part, the name, version and SHA256 digest of all
+fetched stage packages, including dependencies. Replaying a lockfile fetches
+exactly the locked packages and fails if the package set or any package file
+differs from the lockfile.
+"""
+
+import enum
+import hashlib
+import logging
+import threading
+from pathlib import Path
+from typing import Any, Dict, List
+
+import yaml
+from pydantic import BaseModel, ValidationError
+from pydantic_yaml import YamlModel  # type: ignore
+
+from craft_parts.utils import os_utils
+
+from . import errors
+from .base import RepositoryType, get_pkg_name_parts
+
+logger = logging.getLogger(__name__)
+
+# Parts are pulled concurrently and record their packages in the same file.
+_lockfile_lock = threading.Lock()
+
+
+class LockfileMode(enum.Enum):
+    """How the stage packages lockfile is used when pulling."""
+
+    RECORD = "record"
+    REPLAY = "replay"
+
+
+class LockedPackage(BaseModel):
+    """A stage package pinned in the lockfile."""
+
+    name: str
+    version: str
+    sha256: str
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+
+    @property
+    def spec(self) -> str:
+        """The ``name=version`` specification of the package."""
+        return f"{self.name}={self.version}"
+
+
+class PackagesLockfile(YamlModel):
+    """The stage packages locked for each part."""
+
+    stage_packages: Dict[str, List[LockedPackage]] = {}
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]) -> "PackagesLockfile":
+        """Create and populate a new ``PackagesLockfile`` object from dictionary data.
+
+        :param data: The dictionary data to unmarshal.
+
+        :return: The newly created object.
+
+        :raise TypeError: If data is not a dictionary.
+        """
+        if not isinstance(data, dict):
+            raise TypeError("lockfile data is not a dictionary")
+
+        return cls(**data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the lockfile data.
+
+        :return: The newly created dictionary.
+        """
+        return self.dict(by_alias=True)
+
+    @classmethod
+    def read(cls, filepath: Path) -> "PackagesLockfile":
+        """Read the lockfile from disk.
+
+        :param filepath: The lockfile path.
+
+        :return: The lockfile contents.
+
+        :raise errors.InvalidPackagesLockfile: If the lockfile can't be parsed.
+        """
+        logger.debug("load lockfile: %s", filepath)
+        try:
+            with open(filepath) as yaml_file:
+                return cls.unmarshal(yaml.safe_load(yaml_file))
+        except (OSError, TypeError, ValidationError, yaml.YAMLError) as err:
+            raise errors.InvalidPackagesLockfile(
+                str(filepath), message=str(err)
+            ) from err
+
+    def write(self, filepath: Path) -> None:
+        """Write the lockfile to disk."""
+        filepath.parent.mkdir(parents=True, exist_ok=True)
+        yaml_data = self.yaml(by_alias=True)
+        os_utils.TimedWriter.write_text(filepath, yaml_data)
+
+
+def lock_stage_packages(
+    *, repository: RepositoryType, stage_packages_path: Path
+) -> List[LockedPackage]:
+    """Obtain the locked entries of the fetched stage packages.
+
+    :param repository: The repository used to fetch the stage packages.
+    :param stage_packages_path: The directory containing the fetched
+        stage packages.
+
+    :return: The locked packages, sorted by name.
+    """
+    locked: List[LockedPackage] = []
+    for package_path in sorted(stage_packages_path.iterdir()):
+        if not package_path.is_file():
+            continue
+
+        name_version = repository.get_package_file_name_version(package_path)
+        name, version = name_version.split("=", 1)
+        digest = hashlib.sha256(package_path.read_bytes()).hexdigest()
+        locked.append(LockedPackage(name=name, version=version, sha256=digest))
+
+    return sorted(locked, key=lambda x: x.name)
+
+
+def record_part_packages(
+    filepath: Path, *, part_name: str, locked: List[LockedPackage]
+) -> None:
+    """Update the locked stage packages of a part in the lockfile.
+
+    The lockfile is created if it doesn't exist. Entries of other parts
+    are preserved.
+
+    :param filepath: The lockfile path.
+    :param part_name: The name of the part.
+    :param locked: The locked packages of the part.
+
+    :raise errors.InvalidPackagesLockfile: If the existing lockfile can't
+        be parsed.
+    """
+    with _lockfile_lock:
+        if filepath.exists():
+            lockfile = PackagesLockfile.read(filepath)
+        else:
+            lockfile = PackagesLockfile()
+
+        stage_packages = dict(lockfile.stage_packages)
+        if locked:
+            stage_packages[part_name] = locked
+        else:
+            stage_packages.pop(part_name, None)
+        lockfile.stage_packages = stage_packages
+        lockfile.write(filepath)
+
+
+def get_part_packages(
+    filepath: Path, *, part_name: str, package_names: List[str]
+) -> List[LockedPackage]:
+    """Obtain the locked stage packages of a part from the lockfile.
+
+    :param filepath: The lockfile path.
+    :param part_name: The name of the part.
+    :param package_names: The stage packages listed in the part.
+
+    :return: The locked packages of the part.
+
+    :raise errors.InvalidPackagesLockfile: If the lockfile can't be parsed.
+    :raise errors.StagePackagesLockMismatch: If a stage package of the part
+        is not locked.
+    """
+    lockfile = PackagesLockfile.read(filepath)
+    locked = lockfile.stage_packages.get(part_name)
+    if locked is None:
+        raise errors.StagePackagesLockMismatch(
+            message=f"part {part_name!r} is not in the lockfile"
+        )
+
+    locked_names = {package.name for package in locked}
+    missing = sorted(
+        name
+        for name in package_names
+        if get_pkg_name_parts(name)[0] not in locked_names
+    )
+    if missing:
+        raise errors.StagePackagesLockMismatch(
+            message=f"packages not locked: {', '.join(missing)}"
+        )
+
+    return locked
+
+
+def replay_stage_packages(
+    locked: List[LockedPackage],
+    *,
+    repository: RepositoryType,
+    application_name: str,
+    stage_packages_path: Path,
+    base: str,
+    target_arch: str,
+) -> List[str]:
+    """Fetch exactly the locked stage packages.
+
+    :param locked: The locked packages of the part.
+    :param repository: The repository to fetch stage packages from.
+    :param application_name: A unique identifier for the application
+        using Craft Parts.
+    :param stage_packages_path: The path stage packages will be fetched to.
+    :param base: The base this project will run on.
+    :param target_arch: The architecture of the packages to fetch.
+
+    :return: The list of fetched packages.
+
+    :raise errors.StagePackagesLockMismatch: If the fetched packages differ
+        from the locked packages.
+    """
+    if not locked:
+        return []
+
+    specs = sorted(package.spec for package in locked)
+    fetched = repository.fetch_stage_packages(
+        application_name=application_name,
+        package_names=specs,
+        stage_packages_path=stage_packages_path,
+        base=base,
+        target_arch=target_arch,
+    )
+
+    unlocked = sorted(set(fetched) - set(specs))
+    if unlocked:
+        raise errors.StagePackagesLockMismatch(
+            message=f"packages not in the lockfile: {', '.join(unlocked)}"
+        )
+
+    missing = sorted(set(specs) - set(fetched))
+    if missing:
+        raise errors.StagePackagesLockMismatch(
+            message=f"locked packages not fetched: {', '.join(missing)}"
+        )
+
+    digests = {
+        package.spec: package.sha256
+        for package in lock_stage_packages(
+            repository=repository, stage_packages_path=stage_packages_path
+        )
+    }
+    for package in locked:
+        if digests.get(package.spec) != package.sha256:
+            raise errors.StagePackagesLockMismatch(
+                message=f"checksum mismatch for {package.spec}"
+            )
+
+    return sorted(fetched)
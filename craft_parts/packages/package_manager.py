@@ -0,0 +1,325 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Base class for the backends of distributions using a package manager.
+
+Backends using a package manager install build packages and fetch and
+unpack stage packages in the same way, and only differ in the package
+manager commands they run and in the format of the package files they
+handle.
+"""
+
+import abc
+import logging
+import subprocess
+import tempfile
+from pathlib import Path
+from typing import Dict, List, Set
+
+from craft_parts.utils import file_utils
+
+from . import errors
+from .base import BaseRepository, get_pkg_name_parts, mark_origin_stage_package
+
+logger = logging.getLogger(__name__)
+
+# The packages required to work with each source type.
+_SOURCE_TYPE_PACKAGES = {
+    "bzr": {"bzr"},
+    "git": {"git"},
+    "tar": {"tar"},
+    "hg": {"mercurial"},
+    "mercurial": {"mercurial"},
+    "svn": {"subversion"},
+    "subversion": {"subversion"},
+    "rpm2cpio": {"rpm", "cpio"},
+    "7zip": {"p7zip"},
+}
+
+
+class PackageManagerRepository(BaseRepository):
+    """Base implementation for repositories handled by a package manager."""
+
+    # The suffix of the package files fetched by the package manager.
+    package_suffix = ""
+
+    # The packages required to work with source types, where they differ
+    # from the package names used by most distributions.
+    source_type_packages: Dict[str, Set[str]] = {}
+
+    # Whether the package manager can fetch the packages recommended or
+    # suggested by stage packages.
+    supports_recommends = False
+    supports_suggests = False
+
+    @classmethod
+    def get_packages_for_source_type(cls, source_type: str) -> Set[str]:
+        """Return a list of packages required to to work with source_type."""
+        packages = {**_SOURCE_TYPE_PACKAGES, **cls.source_type_packages}
+        return set(packages.get(source_type, set()))
+
+    @classmethod
+    def install_build_packages(
+        cls, package_names: List[str], list_only: bool = False
+    ) -> List[str]:
+        """Install packages on the host system."""
+        if not package_names:
+            return []
+
+        cls._check_version_constraints(package_names)
+        package_names = sorted(package_names)
+        logger.debug("Requested build-packages: %s", package_names)
+
+        if list_only:
+            return cls._list_build_packages(package_names)
+
+        if all(cls._is_version_installed(name) for name in package_names):
+            logger.debug(
+                "Requested build-packages already installed: %s", package_names
+            )
+        else:
+            logger.info("Installing build dependencies: %s", " ".join(package_names))
+            try:
+                subprocess.check_call(
+                    [
+                        "sudo",
+                        "--preserve-env",
+                        *cls._get_install_command(package_names),
+                    ]
+                )
+            except subprocess.CalledProcessError as err:
+                raise errors.BuildPackagesNotInstalled(packages=package_names) from err
+
+        return cls._query_installed_packages(package_names)
+
+    @classmethod
+    def get_overlay_install_commands(cls, package_names: List[str]) -> List[List[str]]:
+        """Obtain the commands to install packages in an overlay layer."""
+        cls._check_version_constraints(package_names)
+        return [cls._get_install_command(sorted(package_names))]
+
+    @classmethod
+    def _check_version_constraints(cls, package_names: List[str]) -> None:
+        """Verify that package names don't have version constraints.
+
+        Constraints such as ``name (>= version)`` are only resolved by the
+        apt backend.
+
+        :raise errors.VersionConstraintNotSupported: If a package name has
+            a version constraint.
+        """
+        for name in package_names:
+            if "(" in name:
+                raise errors.VersionConstraintNotSupported(name, backend=cls.__name__)
+
+    @classmethod
+    def is_package_installed(cls, package_name: str) -> bool:
+        """Inform if a package is installed on the host system."""
+        return bool(cls._query_installed_packages([package_name]))
+
+    @classmethod
+    def fetch_stage_packages(
+        cls,
+        *,
+        application_name: str,
+        package_names: List[str],
+        stage_packages_path: Path,
+        base: str,
+        target_arch: str,
+        list_only: bool = False,
+        recommends: bool = False,
+        suggests: bool = False,
+    ) -> List[str]:
+        """Fetch stage packages to stage_packages_path.
+
+        All dependencies are fetched, including the ones provided by the
+        build base, since the base of the staged files is not known.
+
+        :raise errors.StagePackagesOptionNotSupported: If recommended or
+            suggested packages are requested and the package manager can't
+            fetch them.
+        :raise errors.VersionConstraintNotSupported: If a package name has
+            a version constraint.
+        """
+        logger.debug("Requested stage-packages: %s", sorted(package_names))
+
+        if recommends and not cls.supports_recommends:
+            raise errors.StagePackagesOptionNotSupported(
+                "stage-packages-recommends", backend=cls.__name__
+            )
+        if suggests and not cls.supports_suggests:
+            raise errors.StagePackagesOptionNotSupported(
+                "stage-packages-suggests", backend=cls.__name__
+            )
+
+        if not package_names:
+            return []
+
+        cls._check_version_constraints(package_names)
+        package_names = sorted(package_names)
+
+        if list_only:
+            return cls._list_stage_packages(
+                package_names,
+                application_name=application_name,
+                target_arch=target_arch,
+                recommends=recommends,
+            )
+
+        stage_packages_path.mkdir(exist_ok=True)
+        with tempfile.TemporaryDirectory() as download_dir:
+            command = cls._get_download_command(
+                package_names,
+                download_dir=download_dir,
+                application_name=application_name,
+                target_arch=target_arch,
+                recommends=recommends,
+            )
+            try:
+                subprocess.check_call(command)
+            except subprocess.CalledProcessError as err:
+                raise errors.PackageFetchError(str(err)) from err
+
+            # Some package managers download packages to a subdirectory for
+            # each repository and architecture.
+            fetched: Set[str] = set()
+            for pkg_path in Path(download_dir).glob(f"**/*{cls.package_suffix}"):
+                fetched.add(cls.get_package_file_name_version(pkg_path))
+                file_utils.link_or_copy(
+                    str(pkg_path), str(stage_packages_path / pkg_path.name)
+                )
+
+        return sorted(fetched)
+
+    @classmethod
+    def unpack_stage_packages(
+        cls, *, stage_packages_path: Path, install_path: Path
+    ) -> None:
+        """Unpack stage packages to install_path."""
+        for pkg_path in stage_packages_path.glob(f"*{cls.package_suffix}"):
+            with tempfile.TemporaryDirectory(
+                suffix="package-extract", dir=install_path.parent
+            ) as extract_dir:
+                # Extract package.
+                cls._extract_package(pkg_path, extract_dir)
+                # Mark source of files.
+                marked_name = cls.get_package_file_name_version(pkg_path)
+                mark_origin_stage_package(extract_dir, marked_name)
+                # Stage files to install_dir.
+                file_utils.link_or_copy_tree(extract_dir, install_path.as_posix())
+
+    @classmethod
+    def _is_version_installed(cls, package_name: str) -> bool:
+        """Verify if a package is installed, with the given version if set."""
+        name, version = get_pkg_name_parts(package_name)
+        installed = cls._query_installed_packages([name])
+        if not installed:
+            return False
+
+        return version is None or installed[0] == f"{name}={version}"
+
+    @classmethod
+    @abc.abstractmethod
+    def _get_install_command(cls, package_names: List[str]) -> List[str]:
+        """Obtain the command to install build packages.
+
+        The command is executed with superuser privileges.
+
+        :param package_names: The packages to install.
+
+        :return: The package manager command.
+        """
+
+    @classmethod
+    @abc.abstractmethod
+    def _list_build_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain ``name=version`` of the packages that would be installed.
+
+        :param package_names: The packages to install.
+
+        :return: The packages to install, including dependencies.
+
+        :raise errors.PackageFetchError: If the packages can't be resolved.
+        """
+
+    @classmethod
+    @abc.abstractmethod
+    def _query_installed_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain ``name=version`` for the given installed packages.
+
+        Packages that are not installed are not listed.
+
+        :param package_names: The packages to query, with an optional version.
+
+        :return: The installed packages and their versions.
+        """
+
+    @classmethod
+    @abc.abstractmethod
+    def _list_stage_packages(
+        cls,
+        package_names: List[str],
+        *,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain ``name=version`` of the stage packages that would be fetched.
+
+        :param package_names: The packages to fetch.
+        :param application_name: A unique identifier for the application
+            using Craft Parts.
+        :param target_arch: The architecture of the packages to fetch.
+        :param recommends: Whether recommended packages are also fetched.
+
+        :return: The packages to fetch, including dependencies.
+
+        :raise errors.PackageFetchError: If the packages can't be resolved.
+        """
+
+    @classmethod
+    @abc.abstractmethod
+    def _get_download_command(
+        cls,
+        package_names: List[str],
+        *,
+        download_dir: str,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the command to download stage packages and their dependencies.
+
+        :param package_names: The packages to fetch.
+        :param download_dir: The directory to download package files to.
+        :param application_name: A unique identifier for the application
+            using Craft Parts.
+        :param target_arch: The architecture of the packages to fetch.
+        :param recommends: Whether recommended packages are also fetched.
+
+        :return: The package manager command.
+        """
+
+    @classmethod
+    @abc.abstractmethod
+    def _extract_package(cls, pkg_path: Path, extract_dir: str) -> None:
+        """Extract the files of the given package, without its metadata.
+
+        :param pkg_path: The package file to extract.
+        :param extract_dir: The directory to extract the package files into.
+
+        :raise errors.UnpackError: If the package can't be extracted.
+        """
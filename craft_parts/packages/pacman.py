@@ -0,0 +1,270 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Support for Arch Linux packages using pacman."""
+
+import contextlib
+import functools
+import logging
+import os
+import pathlib
+import subprocess
+from typing import List, Optional, Set
+
+from xdg import BaseDirectory  # type: ignore
+
+from . import errors
+from .base import get_pkg_name_parts
+from .package_manager import PackageManagerRepository
+
+logger = logging.getLogger(__name__)
+
+_PRINT_FORMAT = "%n=%v"
+
+_PACKAGE_SUFFIX = ".pkg.tar.zst"
+
+# Translation from the deb architectures used by craft-parts to pacman
+# architectures.
+_PACMAN_ARCHITECTURES = {
+    "amd64": "x86_64",
+    "arm64": "aarch64",
+    "armhf": "armv7h",
+    "i386": "i686",
+}
+
+# Package metadata stored in pacman packages alongside the package files.
+_METADATA_FILES = {".BUILDINFO", ".CHANGELOG", ".INSTALL", ".MTREE", ".PKGINFO"}
+
+
+class ArchLinux(PackageManagerRepository):
+    """Repository management for Arch Linux packages.
+
+    Stage packages are downloaded using a package database specific to the
+    application, so that the packages installed on the host don't prevent
+    their dependencies from being staged.
+    """
+
+    package_suffix = _PACKAGE_SUFFIX
+
+    source_type_packages = {
+        "bzr": {"breezy"},
+        "rpm2cpio": {"rpmextract", "cpio"},
+    }
+
+    package_names = {
+        "g++": "gcc",
+        "python3": "python",
+        "python3-dev": "python",
+        "python3-venv": "python",
+        "zlib1g-dev": "zlib",
+        "libssl-dev": "openssl",
+        "libffi-dev": "libffi",
+        "libyaml-dev": "libyaml",
+        "libgmp-dev": "gmp",
+        "libpcre2-dev": "pcre2",
+        "libevent-dev": "libevent",
+        "xz-utils": "xz",
+        "ninja-build": "ninja",
+        "pkg-config": "pkgconf",
+        "default-jdk-headless": "jdk-openjdk",
+        "erlang-dev": "erlang",
+    }
+
+    @classmethod
+    def get_package_libraries(cls, package_name: str) -> Set[str]:
+        """Return a list of libraries in package_name."""
+        return _run_pacman_query_list_files(package_name)
+
+    @classmethod
+    def refresh_build_packages_list(cls) -> None:
+        """Refresh the list of packages available in the repository."""
+        try:
+            cmd = ["sudo", "--preserve-env", "pacman", "--sync", "--refresh"]
+            logger.debug("Executing: %s", cmd)
+            subprocess.check_call(cmd)
+        except subprocess.CalledProcessError as call_error:
+            raise errors.PackageListRefreshError(
+                "failed to run pacman --sync --refresh"
+            ) from call_error
+
+    @classmethod
+    def get_installed_packages(cls) -> List[str]:
+        """Obtain a list of the installed packages and their versions."""
+        return _run_pacman_query([])
+
+    @classmethod
+    def refresh_stage_packages_list(
+        cls, *, application_name: str, target_arch: str
+    ) -> None:
+        """Refresh the list of packages available in the repository."""
+        command = [
+            "pacman",
+            "--sync",
+            "--refresh",
+            *_get_stage_options(application_name, target_arch),
+        ]
+        logger.debug("Executing: %s", command)
+        try:
+            subprocess.check_call(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageListRefreshError(
+                "failed to run pacman --sync --refresh"
+            ) from err
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path: pathlib.Path) -> str:
+        """Obtain the name and version of a fetched stage package file."""
+        return _get_package_name_version(package_path.name)
+
+    @classmethod
+    def _get_install_command(cls, package_names: List[str]) -> List[str]:
+        """Obtain the pacman command to install build packages."""
+        return ["pacman", "--sync", "--needed", "--noconfirm", *package_names]
+
+    @classmethod
+    def _list_build_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the build packages that would be installed."""
+        try:
+            return _run_pacman_sync_print(package_names)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageFetchError(str(err)) from err
+
+    @classmethod
+    def _query_installed_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the versions of the given installed packages."""
+        return _run_pacman_query([get_pkg_name_parts(n)[0] for n in package_names])
+
+    @classmethod
+    def _list_stage_packages(
+        cls,
+        package_names: List[str],
+        *,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the stage packages that would be fetched."""
+        options = _get_stage_options(application_name, target_arch)
+        try:
+            return _run_pacman_sync_print(package_names, options=options)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageFetchError(str(err)) from err
+
+    @classmethod
+    def _get_download_command(
+        cls,
+        package_names: List[str],
+        *,
+        download_dir: str,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the pacman command to download stage packages."""
+        return [
+            "pacman",
+            "--sync",
+            "--downloadonly",
+            "--noconfirm",
+            *_get_stage_options(application_name, target_arch),
+            f"--cachedir={download_dir}",
+            *package_names,
+        ]
+
+    @classmethod
+    def _extract_package(cls, pkg_path: pathlib.Path, extract_dir: str) -> None:
+        """Extract the files of the given package, without its metadata."""
+        command = [
+            "tar",
+            "--extract",
+            "--zstd",
+            f"--file={pkg_path}",
+            f"--directory={extract_dir}",
+        ]
+        try:
+            subprocess.check_call(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.UnpackError(str(pkg_path)) from err
+
+        for name in _METADATA_FILES:
+            with contextlib.suppress(FileNotFoundError):
+                os.remove(os.path.join(extract_dir, name))
+
+
+def _get_package_name_version(pkg_file: str) -> str:
+    """Obtain ``name=version`` from a pacman package file name.
+
+    Package files are named ``<name>-<version>-<release>-<arch>.pkg.tar.zst``.
+    """
+    name, version, release, _ = pkg_file[: -len(_PACKAGE_SUFFIX)].rsplit("-", 3)
+    return f"{name}={version}-{release}"
+
+
+def _get_stage_options(application_name: str, target_arch: str) -> List[str]:
+    """Obtain the pacman options used to fetch stage packages."""
+    cache_dir = BaseDirectory.save_cache_path(
+        application_name, "craft-parts", "stage-packages-pacman"
+    )
+    arch = _PACMAN_ARCHITECTURES.get(target_arch, target_arch)
+    return [f"--dbpath={cache_dir}", f"--arch={arch}"]
+
+
+def _run_pacman_query(names: List[str]) -> List[str]:
+    """Obtain ``name=version`` for the given installed packages.
+
+    All installed packages are listed if no names are given, and packages
+    that are not installed are not listed.
+    """
+    proc = subprocess.run(
+        ["pacman", "--query", *names],
+        stdout=subprocess.PIPE,
+        stderr=subprocess.DEVNULL,
+        check=False,
+    )
+    # Installed packages are reported as "<name> <version>".
+    return sorted(
+        "=".join(line.split())
+        for line in proc.stdout.decode().splitlines()
+        if len(line.split()) == 2
+    )
+
+
+def _run_pacman_sync_print(
+    package_names: List[str], *, options: Optional[List[str]] = None
+) -> List[str]:
+    """Obtain ``name=version`` of the packages that would be installed.
+
+    :raise subprocess.CalledProcessError: If the packages can't be resolved.
+    """
+    output = subprocess.check_output(
+        [
+            "pacman",
+            "--sync",
+            "--print",
+            f"--print-format={_PRINT_FORMAT}",
+            *(options or []),
+            *package_names,
+        ]
+    )
+    return sorted(set(output.decode().split()))
+
+
+@functools.lru_cache(maxsize=256)
+def _run_pacman_query_list_files(package_name: str) -> Set[str]:
+    command = ["pacman", "--query", "--list", "--quiet", package_name]
+    output = subprocess.check_output(command).decode().strip().split()
+
+    return {i for i in output if ("lib" in i and os.path.isfile(i))}
@@ -0,0 +1,622 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Install additional apt, rpm and apk package repositories and pacman mirrors.
+
+Apt repositories are written as deb822 ``.sources`` files, with the
+repository signing key embedded in the ``Signed-By`` field, so that no keys
+are added to the keyrings trusted for all repositories. Legacy one-line
+``.list`` files previously written for a repository are removed, to avoid
+having the same repository defined in both formats. Repositories with a
+priority are pinned with an apt preferences file. Flat repositories, without
+a ``dists/`` structure, and Ubuntu Cloud Archive pockets are also supported.
+
+Rpm repositories are written as ``.repo`` files for dnf or zypper, with
+signature verification enabled and the repository key installed next to
+the keys of the distribution.
+
+Apk repositories are added to the apk repositories file. Tagged repositories
+are pinned: their packages are only installed when requested explicitly
+using the ``<name>@<tag>`` syntax.
+
+Pacman mirrors are written to the pacman mirrorlist, replacing the mirrors
+configured by the distribution.
+"""
+
+import logging
+import re
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+from urllib.parse import urlparse
+
+from pydantic import BaseModel, root_validator, validator
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+_KEY_ID_REGEX = re.compile(r"^[0-9A-F]{40}$")
+
+_APK_TAG_REGEX = re.compile(r"^[a-z0-9][a-z0-9_-]*$")
+
+_CLOUD_REGEX = re.compile(r"^[a-z]+$")
+
+CLOUD_ARCHIVE_URL = "http://ubuntu-cloud.archive.canonical.com/ubuntu"
+
+# The fingerprint of the Canonical Cloud Archive Signing Key.
+CLOUD_ARCHIVE_KEY_ID = "391A9AA2147192839E9DB0315EDB1B62EC4926EA"
+
+
+class AptRepository(BaseModel):
+    """An apt package repository.
+
+    The repository key is read from ``<keys_dir>/<key-id>.asc`` when the
+    repository is installed, where the key ID can be shortened to its last
+    8 characters.
+
+    Flat repositories are defined with the ``path`` of the packages index
+    relative to the repository URL, instead of suites and components.
+    """
+
+    formats: List[str] = ["deb"]
+    url: str
+    suites: List[str] = []
+    components: List[str] = []
+    path: Optional[str] = None
+    architectures: List[str] = []
+    key_id: str
+    priority: Optional[int] = None
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+
+    @validator("formats", each_item=True)
+    def validate_formats(cls, item):
+        """Make sure only binary and source package formats are listed."""
+        if item not in ("deb", "deb-src"):
+            raise ValueError(f"invalid format {item!r}, must be 'deb' or 'deb-src'")
+        return item
+
+    @validator("key_id")
+    def validate_key_id(cls, value):
+        """Make sure the key ID is a full key fingerprint."""
+        if not _KEY_ID_REGEX.match(value):
+            raise ValueError("key ID must be a 40-character uppercase fingerprint")
+        return value
+
+    @validator("priority")
+    def validate_priority(cls, value):
+        """Make sure the priority can be used to pin the repository."""
+        if value == 0:
+            raise ValueError("priority cannot be zero")
+        return value
+
+    @root_validator(skip_on_failure=True)
+    def validate_suites_or_path(cls, values):
+        """Make sure either a flat repository path or suites are given."""
+        if values.get("path") is not None:
+            if values.get("suites") or values.get("components"):
+                raise ValueError("suites and components cannot be used with path")
+        elif not values.get("suites") or not values.get("components"):
+            raise ValueError("suites and components are required without path")
+        return values
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]) -> "AptRepository":
+        """Create and populate a new ``AptRepository`` object from dictionary data.
+
+        :param data: The dictionary data to unmarshal.
+
+        :return: The newly created object.
+
+        :raise TypeError: If data is not a dictionary.
+        """
+        if not isinstance(data, dict):
+            raise TypeError("repository data is not a dictionary")
+
+        return cls(**data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the repository data.
+
+        :return: The newly created dictionary.
+        """
+        return self.dict(by_alias=True)
+
+
+class AptCloudArchive(BaseModel):
+    """An Ubuntu Cloud Archive pocket, as in ``cloud-archive:<cloud>``.
+
+    The Cloud Archive key is read from ``<keys_dir>/EC4926EA.asc`` when the
+    pocket is installed.
+    """
+
+    cloud: str
+    pocket: str = "updates"
+    priority: Optional[int] = None
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+
+    @validator("cloud")
+    def validate_cloud(cls, value):
+        """Make sure the cloud is an OpenStack release name."""
+        if not _CLOUD_REGEX.match(value):
+            raise ValueError(f"invalid cloud {value!r}")
+        return value
+
+    @validator("pocket")
+    def validate_pocket(cls, value):
+        """Make sure the pocket is provided by the Cloud Archive."""
+        if value not in ("updates", "proposed"):
+            raise ValueError(
+                f"invalid pocket {value!r}, must be 'updates' or 'proposed'"
+            )
+        return value
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]) -> "AptCloudArchive":
+        """Create and populate a new ``AptCloudArchive`` object from dictionary data.
+
+        :param data: The dictionary data to unmarshal.
+
+        :return: The newly created object.
+
+        :raise TypeError: If data is not a dictionary.
+        """
+        if not isinstance(data, dict):
+            raise TypeError("repository data is not a dictionary")
+
+        return cls(**data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the repository data.
+
+        :return: The newly created dictionary.
+        """
+        return self.dict(by_alias=True)
+
+    def get_repository(self, *, series: str) -> AptRepository:
+        """Obtain the apt repository of the pocket for the given Ubuntu series.
+
+        :param series: The codename of the Ubuntu release, such as ``jammy``.
+
+        :return: The apt repository providing the pocket.
+        """
+        return AptRepository.unmarshal(
+            {
+                "url": CLOUD_ARCHIVE_URL,
+                "suites": [f"{series}-{self.pocket}/{self.cloud}"],
+                "components": ["main"],
+                "key-id": CLOUD_ARCHIVE_KEY_ID,
+                "priority": self.priority,
+            }
+        )
+
+
+class RpmRepository(BaseModel):
+    """An rpm package repository.
+
+    The repository key is read from ``<keys_dir>/<key-id>.asc`` when the
+    repository is installed, where the key ID can be shortened to its last
+    8 characters.
+    """
+
+    url: str
+    key_id: str
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+
+    @validator("key_id")
+    def validate_key_id(cls, value):
+        """Make sure the key ID is a full key fingerprint."""
+        if not _KEY_ID_REGEX.match(value):
+            raise ValueError("key ID must be a 40-character uppercase fingerprint")
+        return value
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]) -> "RpmRepository":
+        """Create and populate a new ``RpmRepository`` object from dictionary data.
+
+        :param data: The dictionary data to unmarshal.
+
+        :return: The newly created object.
+
+        :raise TypeError: If data is not a dictionary.
+        """
+        if not isinstance(data, dict):
+            raise TypeError("repository data is not a dictionary")
+
+        return cls(**data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the repository data.
+
+        :return: The newly created dictionary.
+        """
+        return self.dict(by_alias=True)
+
+
+class ApkRepository(BaseModel):
+    """An apk package repository, optionally pinned with a tag."""
+
+    url: str
+    tag: Optional[str] = None
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+
+    @validator("tag")
+    def validate_tag(cls, value):
+        """Make sure the tag can be used to pin packages."""
+        if value is not None and not _APK_TAG_REGEX.match(value):
+            raise ValueError(f"invalid repository tag {value!r}")
+        return value
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]) -> "ApkRepository":
+        """Create and populate a new ``ApkRepository`` object from dictionary data.
+
+        :param data: The dictionary data to unmarshal.
+
+        :return: The newly created object.
+
+        :raise TypeError: If data is not a dictionary.
+        """
+        if not isinstance(data, dict):
+            raise TypeError("repository data is not a dictionary")
+
+        return cls(**data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the repository data.
+
+        :return: The newly created dictionary.
+        """
+        return self.dict(by_alias=True)
+
+    @property
+    def entry(self) -> str:
+        """The repository entry in the apk repositories file."""
+        return f"@{self.tag} {self.url}" if self.tag else self.url
+
+
+def get_sources_content(repository: AptRepository, *, key: str) -> str:
+    """Obtain the deb822 sources entry for the given repository.
+
+    :param repository: The repository to obtain the entry for.
+    :param key: The ASCII-armored public key that signs the repository.
+
+    :return: The contents of the ``.sources`` file.
+    """
+    fields = [
+        ("Types", " ".join(repository.formats)),
+        ("URIs", repository.url),
+    ]
+    if repository.path is not None:
+        # The suite of a flat repository is a path ending with a slash.
+        fields.append(("Suites", repository.path.rstrip("/") + "/"))
+    else:
+        fields.append(("Suites", " ".join(repository.suites)))
+        fields.append(("Components", " ".join(repository.components)))
+    if repository.architectures:
+        fields.append(("Architectures", " ".join(repository.architectures)))
+
+    lines = [f"{name}: {value}" for name, value in fields]
+
+    # Multi-line values are folded, with empty lines written as a single dot.
+    lines.append("Signed-By:")
+    lines.extend(f" {line.strip() or '.'}" for line in key.strip().splitlines())
+
+    return "\n".join(lines) + "\n"
+
+
+def get_preferences_content(repository: AptRepository) -> str:
+    """Obtain the apt preferences pinning the given repository.
+
+    :param repository: The repository to pin, with a priority.
+
+    :return: The contents of the preferences file.
+    """
+    # Local repositories have an empty origin.
+    origin = urlparse(repository.url).hostname or ""
+    return f'Package: *\nPin: origin "{origin}"\nPin-Priority: {repository.priority}\n'
+
+
+def install_repository(
+    repository: AptRepository,
+    *,
+    name: str,
+    keys_dir: Path,
+    sources_dir: Path = Path("/etc/apt/sources.list.d"),
+    preferences_dir: Path = Path("/etc/apt/preferences.d"),
+) -> bool:
+    """Write the sources file for the given repository.
+
+    The repository is pinned if it has a priority, and previous pinning is
+    removed otherwise.
+
+    :param repository: The repository to install.
+    :param name: A unique name for the repository, used to name its files.
+    :param keys_dir: The directory containing the repository keys.
+    :param sources_dir: The directory to write the sources file into.
+    :param preferences_dir: The directory to write the preferences file into.
+
+    :return: Whether the sources changed and the package lists must be
+        refreshed.
+
+    :raise errors.PackageRepositoryKeyNotFound: If the repository key is not
+        in the keys directory.
+    """
+    key = _read_key(repository.key_id, keys_dir=keys_dir)
+    content = get_sources_content(repository, key=key)
+
+    changed = _install_preferences(
+        repository, preferences_file=preferences_dir / f"craft-{name}.pref"
+    )
+
+    legacy_file = sources_dir / f"craft-{name}.list"
+    if legacy_file.exists():
+        logger.debug("Remove legacy sources file %s", legacy_file)
+        legacy_file.unlink()
+        changed = True
+
+    sources_file = sources_dir / f"craft-{name}.sources"
+    if sources_file.is_file() and sources_file.read_text() == content:
+        return changed
+
+    logger.debug("Write sources file %s", sources_file)
+    sources_dir.mkdir(parents=True, exist_ok=True)
+    sources_file.write_text(content)
+    return True
+
+
+def install_cloud_archive(
+    cloud_archive: AptCloudArchive,
+    *,
+    name: str,
+    keys_dir: Path,
+    series: str,
+    sources_dir: Path = Path("/etc/apt/sources.list.d"),
+    preferences_dir: Path = Path("/etc/apt/preferences.d"),
+) -> bool:
+    """Write the sources file for the given Ubuntu Cloud Archive pocket.
+
+    :param cloud_archive: The Cloud Archive pocket to install.
+    :param name: A unique name for the repository, used to name its files.
+    :param keys_dir: The directory containing the Cloud Archive key.
+    :param series: The codename of the Ubuntu release of the host.
+    :param sources_dir: The directory to write the sources file into.
+    :param preferences_dir: The directory to write the preferences file into.
+
+    :return: Whether the sources changed and the package lists must be
+        refreshed.
+
+    :raise errors.PackageRepositoryKeyNotFound: If the Cloud Archive key is
+        not in the keys directory.
+    """
+    return install_repository(
+        cloud_archive.get_repository(series=series),
+        name=name,
+        keys_dir=keys_dir,
+        sources_dir=sources_dir,
+        preferences_dir=preferences_dir,
+    )
+
+
+def get_repo_content(repository: RpmRepository, *, name: str, key_file: Path) -> str:
+    """Obtain the dnf repository definition for the given repository.
+
+    :param repository: The repository to obtain the definition for.
+    :param name: The unique name of the repository.
+    :param key_file: The installed public key that signs the repository.
+
+    :return: The contents of the ``.repo`` file.
+    """
+    lines = [
+        f"[craft-{name}]",
+        f"name=craft-{name}",
+        f"baseurl={repository.url}",
+        "enabled=1",
+        "gpgcheck=1",
+        "repo_gpgcheck=0",
+        f"gpgkey=file://{key_file}",
+    ]
+    return "\n".join(lines) + "\n"
+
+
+def install_rpm_repository(
+    repository: RpmRepository,
+    *,
+    name: str,
+    keys_dir: Path,
+    repos_dir: Path = Path("/etc/yum.repos.d"),
+    gpg_dir: Path = Path("/etc/pki/rpm-gpg"),
+) -> bool:
+    """Write the repository definition and key for the given repository.
+
+    :param repository: The repository to install.
+    :param name: A unique name for the repository, used to name its files.
+    :param keys_dir: The directory containing the repository keys.
+    :param repos_dir: The directory to write the repository definition into,
+        ``/etc/zypp/repos.d`` for zypper.
+    :param gpg_dir: The directory to install the repository key into.
+
+    :return: Whether the repository changed and the package metadata must be
+        refreshed.
+
+    :raise errors.PackageRepositoryKeyNotFound: If the repository key is not
+        in the keys directory.
+    """
+    key = _read_key(repository.key_id, keys_dir=keys_dir)
+    key_file = gpg_dir / f"RPM-GPG-KEY-craft-{name}"
+    content = get_repo_content(repository, name=name, key_file=key_file)
+
+    changed = False
+    if not key_file.is_file() or key_file.read_text() != key:
+        logger.debug("Write repository key %s", key_file)
+        gpg_dir.mkdir(parents=True, exist_ok=True)
+        key_file.write_text(key)
+        changed = True
+
+    repo_file = repos_dir / f"craft-{name}.repo"
+    if repo_file.is_file() and repo_file.read_text() == content:
+        return changed
+
+    logger.debug("Write repository file %s", repo_file)
+    repos_dir.mkdir(parents=True, exist_ok=True)
+    repo_file.write_text(content)
+    return True
+
+
+def install_apk_repository(
+    repository: ApkRepository,
+    *,
+    repositories_file: Path = Path("/etc/apk/repositories"),
+) -> bool:
+    """Add the given repository to the apk repositories file.
+
+    An existing entry with the same tag is replaced, so that a tag always
+    pins packages to a single repository.
+
+    :param repository: The repository to install.
+    :param repositories_file: The apk repositories file.
+
+    :return: Whether the repositories changed and the package indexes must
+        be refreshed.
+    """
+    lines: List[str] = []
+    if repositories_file.is_file():
+        lines = repositories_file.read_text().splitlines()
+
+    if repository.entry in lines:
+        return False
+
+    if repository.tag:
+        lines = [x for x in lines if not x.startswith(f"@{repository.tag} ")]
+
+    lines.append(repository.entry)
+
+    logger.debug("Write repositories file %s", repositories_file)
+    repositories_file.parent.mkdir(parents=True, exist_ok=True)
+    repositories_file.write_text("\n".join(lines) + "\n")
+    return True
+
+
+def get_mirrorlist_content(servers: List[str]) -> str:
+    """Obtain the pacman mirrorlist for the given servers.
+
+    :param servers: The mirror URLs, in order of preference. URLs can use
+        the ``$repo`` and ``$arch`` variables.
+
+    :return: The contents of the mirrorlist file.
+    """
+    return "".join(f"Server = {server}\n" for server in servers)
+
+
+def install_mirrorlist(
+    servers: List[str],
+    *,
+    mirrorlist_file: Path = Path("/etc/pacman.d/mirrorlist"),
+) -> bool:
+    """Write the pacman mirrorlist for the given servers.
+
+    :param servers: The mirror URLs, in order of preference.
+    :param mirrorlist_file: The pacman mirrorlist file.
+
+    :return: Whether the mirrorlist changed and the package databases must
+        be refreshed.
+
+    :raise ValueError: If no servers are given.
+    """
+    if not servers:
+        raise ValueError("at least one mirror server is required")
+
+    content = get_mirrorlist_content(servers)
+    if mirrorlist_file.is_file() and mirrorlist_file.read_text() == content:
+        return False
+
+    logger.debug("Write mirrorlist %s", mirrorlist_file)
+    mirrorlist_file.parent.mkdir(parents=True, exist_ok=True)
+    mirrorlist_file.write_text(content)
+    return True
+
+
+def _install_preferences(repository: AptRepository, *, preferences_file: Path) -> bool:
+    """Write or remove the preferences file pinning the given repository."""
+    if repository.priority is None:
+        if not preferences_file.exists():
+            return False
+
+        logger.debug("Remove preferences file %s", preferences_file)
+        preferences_file.unlink()
+        return True
+
+    content = get_preferences_content(repository)
+    if preferences_file.is_file() and preferences_file.read_text() == content:
+        return False
+
+    logger.debug("Write preferences file %s", preferences_file)
+    preferences_file.parent.mkdir(parents=True, exist_ok=True)
+    preferences_file.write_text(content)
+    return True
+
+
+def _read_key(key_id: str, *, keys_dir: Path) -> str:
+    """Read the ASCII-armored key with the given ID from the keys directory."""
+    for name in (key_id, key_id[-8:]):
+        key_file = keys_dir / f"{name}.asc"
+        if key_file.is_file():
+            return key_file.read_text()
+
+    raise errors.PackageRepositoryKeyNotFound(key_id=key_id, keys_dir=str(keys_dir))
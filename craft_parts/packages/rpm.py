@@ -0,0 +1,117 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Helpers shared by the backends of rpm-based distributions."""
+
+import functools
+import os
+import pathlib
+import subprocess
+from typing import List, Set
+
+from . import errors
+
+_QUERY_FORMAT = "%{NAME}=%{VERSION}-%{RELEASE}\n"
+
+
+def get_rpm_name_version(rpm_file: str) -> str:
+    """Obtain ``name=version-release`` from an rpm file name.
+
+    Rpm files are named ``<name>-<version>-<release>.<arch>.rpm``.
+
+    :param rpm_file: The name of the rpm file.
+
+    :return: The package name and version.
+    """
+    nvr = rpm_file[: -len(".rpm")].rsplit(".", 1)[0]
+    name, version, release = nvr.rsplit("-", 2)
+    return f"{name}={version}-{release}"
+
+
+def extract_rpm(rpm_path: pathlib.Path, extract_dir: str) -> None:
+    """Extract the payload of the given rpm package.
+
+    :param rpm_path: The rpm package to extract.
+    :param extract_dir: The directory to extract the package files into.
+
+    :raise errors.UnpackError: If the package can't be extracted.
+    """
+    with subprocess.Popen(["rpm2cpio", str(rpm_path)], stdout=subprocess.PIPE) as proc:
+        try:
+            subprocess.check_call(
+                [
+                    "cpio",
+                    "--extract",
+                    "--make-directories",
+                    "--preserve-modification-time",
+                    "--quiet",
+                ],
+                stdin=proc.stdout,
+                cwd=extract_dir,
+            )
+        except subprocess.CalledProcessError as err:
+            raise errors.UnpackError(str(rpm_path)) from err
+
+    if proc.returncode != 0:
+        raise errors.UnpackError(str(rpm_path))
+
+
+def run_rpm_query(names: List[str]) -> List[str]:
+    """Obtain ``name=version-release`` for the given installed packages.
+
+    Packages that are not installed are not listed.
+
+    :param names: The names of the packages to query.
+
+    :return: The installed packages and their versions.
+    """
+    proc = subprocess.run(
+        ["rpm", "--query", "--queryformat", _QUERY_FORMAT, *names],
+        stdout=subprocess.PIPE,
+        stderr=subprocess.DEVNULL,
+        check=False,
+    )
+    # Packages that are not installed are reported in the standard output.
+    return [line for line in proc.stdout.decode().split() if "=" in line]
+
+
+def get_installed_packages() -> List[str]:
+    """Obtain ``name=version-release`` for all installed packages.
+
+    :return: The sorted list of installed packages and their versions.
+    """
+    output = subprocess.check_output(
+        ["rpm", "--query", "--all", "--queryformat", _QUERY_FORMAT]
+    )
+    return sorted(output.decode().split())
+
+
+@functools.lru_cache(maxsize=256)
+def run_rpm_query_list_files(package_name: str) -> Set[str]:
+    """Obtain the libraries installed by the given package.
+
+    :param package_name: The name of the installed package.
+
+    :return: The paths of the library files.
+    """
+    output = (
+        subprocess.check_output(["rpm", "--query", "--list", package_name])
+        .decode()
+        .strip()
+        .split()
+    )
+
+    return {i for i in output if ("lib" in i and os.path.isfile(i))}
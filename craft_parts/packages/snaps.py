@@ -16,10 +16,14 @@
 
 """Helpers to install snap packages."""
 
+import base64
 import contextlib
+import hashlib
 import logging
 import os
+import shutil
 import sys
+import tempfile
 from subprocess import CalledProcessError, check_call, check_output
 from typing import Any, Dict, List, Optional, Sequence, Set, Tuple, Union
 from urllib import parse
@@ -27,6 +31,9 @@ from urllib import parse
 import requests_unixsocket  # type: ignore
 from requests import exceptions
 
+from craft_parts import hosts, progress
+from craft_parts.utils import file_utils
+
 from . import errors
 
 # pylint: disable=line-too-long
@@ -66,14 +73,24 @@ class SnapPackage:
     @classmethod
     def is_snap_installed(cls, snap: str) -> bool:
         """Verify whether the given snap is installed."""
-        # Snaps are not currently supported on Windows
-        if sys.platform == "win32":
+        # Snaps are only supported on Linux hosts.
+        if not hosts.get_host_platform().supports_packages:
             return False
         return cls(snap).installed
 
     def __init__(self, snap: str):
-        """Lifecycle handler for a snap of the format <snap-name>/<channel>."""
+        """Lifecycle handler for a snap of the format <snap-name>/<channel>.
+
+        The snap can be pinned to a revision using ``<snap-name>=<revision>``.
+        Pinned revisions are verified against their assertions before they
+        are installed.
+        """
+        snap, _, revision = snap.partition("=")
+        if revision and not revision.isdigit():
+            raise errors.InvalidSnapRevision(f"{snap}={revision}")
+
         self.name, self.channel = _get_parsed_snap(snap)
+        self.revision: Optional[str] = revision or None
         self._original_channel = self.channel
         if not self.channel or self.channel == "stable":
             self.channel = "latest/stable"
@@ -147,6 +164,26 @@ class SnapPackage:
 
         return snap_store_info["channels"]
 
+    def get_store_revision(self) -> str:
+        """Obtain the store revision this snap resolves to.
+
+        :raise SnapUnavailable: If the snap is not available on its channel.
+        """
+        if self.revision:
+            return self.revision
+
+        channel = self.channel
+        if channel in _CHANNEL_RISKS:
+            channel = f"latest/{channel}"
+
+        store_channels = self._get_store_channels()
+        try:
+            return str(store_channels[channel]["revision"])
+        except KeyError as err:
+            raise errors.SnapUnavailable(
+                snap_name=self.name, snap_channel=channel
+            ) from err
+
     def get_current_channel(self) -> str:
         """Obtain the current channel for this snap."""
         current_channel = ""
@@ -193,7 +230,9 @@ class SnapPackage:
         # We use the `snap download` command here on recommendation
         # of the snapd team.
         snap_download_cmd = ["snap", "download", self.name]
-        if self._original_channel:
+        if self.revision:
+            snap_download_cmd.append(f"--revision={self.revision}")
+        elif self._original_channel:
             snap_download_cmd.extend(["--channel", self._original_channel])
         try:
             check_output(snap_download_cmd, cwd=directory)
@@ -204,6 +243,10 @@ class SnapPackage:
 
     def install(self):
         """Installs the snap onto the system."""
+        if self.revision:
+            self._install_revision()
+            return
+
         snap_install_cmd = []
         if _snap_command_requires_sudo():
             snap_install_cmd = ["sudo"]
@@ -227,6 +270,68 @@ class SnapPackage:
         # Now that the snap is installed, invalidate the data we had on it.
         self._is_installed = None
 
+    def _install_revision(self) -> None:
+        """Download, verify and install the pinned revision of the snap."""
+        snap_cmd_prefix = ["sudo"] if _snap_command_requires_sudo() else []
+
+        with tempfile.TemporaryDirectory() as download_dir:
+            self.download(directory=download_dir)
+            snap_file = os.path.join(download_dir, f"{self.name}_{self.revision}.snap")
+            assert_file = os.path.splitext(snap_file)[0] + ".assert"
+            self._verify_revision(snap_file, assert_file)
+
+            # Acknowledging the assertions verifies their signatures, and
+            # snapd verifies the snap file against them on installation.
+            snap_install_cmd = [*snap_cmd_prefix, "snap", "install", snap_file]
+            try:
+                if self.is_classic():
+                    snap_install_cmd.append("--classic")
+            except (errors.SnapUnavailable, KeyError):
+                pass
+
+            try:
+                check_call([*snap_cmd_prefix, "snap", "ack", assert_file])
+            except CalledProcessError as err:
+                raise errors.SnapAssertionError(
+                    snap_name=self.name,
+                    snap_revision=str(self.revision),
+                    message="assertions not acknowledged",
+                ) from err
+
+            try:
+                check_call(snap_install_cmd)
+            except CalledProcessError as err:
+                raise errors.SnapInstallError(
+                    snap_name=self.name, snap_channel=self.channel
+                ) from err
+
+        # Now that the snap is installed, invalidate the data we had on it.
+        self._is_installed = None
+
+    def _verify_revision(self, snap_file: str, assert_file: str) -> None:
+        """Verify the downloaded snap file against its revision assertion."""
+        revision = str(self.revision)
+        try:
+            headers = _get_snap_revision_headers(assert_file)
+        except OSError as err:
+            raise errors.SnapAssertionError(
+                snap_name=self.name, snap_revision=revision, message=str(err)
+            ) from err
+
+        if headers.get("snap-revision") != revision:
+            raise errors.SnapAssertionError(
+                snap_name=self.name,
+                snap_revision=revision,
+                message="revision assertion not found",
+            )
+
+        if headers.get("snap-sha3-384") != _get_snap_digest(snap_file):
+            raise errors.SnapAssertionError(
+                snap_name=self.name,
+                snap_revision=revision,
+                message="snap file digest mismatch",
+            )
+
     def refresh(self):
         """Refresh a snap onto a channel on the system."""
         snap_refresh_cmd = []
@@ -266,11 +371,88 @@ def download_snaps(*, snaps_list: Sequence[str], directory: str) -> None:
 
         # TODO: use dependency injected echoer
         logger.info("Downloading snap %s", snap_pkg.name)
+        reporter = progress.ProgressReporter(progress.DownloadKind.SNAP, snap_pkg.name)
+        reporter.start()
+        existing_files = set(os.listdir(directory))
         snap_pkg.download(directory=directory)
+        reporter.update(_get_downloaded_size(directory, existing_files))
+        reporter.finish()
+
+
+def _get_downloaded_size(directory: str, existing_files: Set[str]) -> int:
+    """Obtain the size of snap files added to a directory."""
+    return sum(
+        os.path.getsize(os.path.join(directory, name))
+        for name in os.listdir(directory)
+        if name.endswith(".snap") and name not in existing_files
+    )
+
+
+def get_snap_revisions(snaps_list: Sequence[str]) -> Dict[str, Dict[str, str]]:
+    """Resolve snaps of the format <snap-name>/<channel> to store revisions.
+
+    :return: a dictionary mapping snap names to their "revision" and "snap-id".
+    """
+    revisions: Dict[str, Dict[str, str]] = {}
+    for snap in snaps_list:
+        snap_pkg = SnapPackage(snap)
+        revisions[snap_pkg.name] = {
+            "revision": snap_pkg.get_store_revision(),
+            "snap-id": snap_pkg.get_store_snap_info().get("id", ""),
+        }
+    return revisions
+
+
+def get_downloaded_snap_revisions(directory: str) -> Dict[str, Dict[str, str]]:
+    """Obtain the revisions of the snaps downloaded into directory.
+
+    The revision and snap-id of each snap are taken from the revision
+    assertion downloaded with the snap file.
+
+    :return: a dictionary mapping snap names to their "revision" and "snap-id".
+    """
+    revisions: Dict[str, Dict[str, str]] = {}
+    for name in sorted(os.listdir(directory)):
+        if not name.endswith(".snap"):
+            continue
+
+        snap_name = name[: -len(".snap")].rpartition("_")[0]
+        assert_file = os.path.join(directory, name[: -len(".snap")] + ".assert")
+        headers = _get_snap_revision_headers(assert_file)
+        revisions[snap_name] = {
+            "revision": headers.get("snap-revision", ""),
+            "snap-id": headers.get("snap-id", ""),
+        }
+    return revisions
+
+
+def unpack_snaps(directory: str, *, install_dir: str) -> None:
+    """Extract the snaps downloaded into directory to install_dir.
+
+    The snap metadata is not extracted.
+
+    :raise SnapUnpackError: If a snap file can't be extracted.
+    """
+    for name in sorted(os.listdir(directory)):
+        if not name.endswith(".snap"):
+            continue
+
+        snap_file = os.path.join(directory, name)
+        logger.debug("Unpacking snap %s to %s", snap_file, install_dir)
+        with tempfile.TemporaryDirectory() as unpack_dir:
+            try:
+                check_output(["unsquashfs", "-force", "-dest", unpack_dir, snap_file])
+            except CalledProcessError as err:
+                raise errors.SnapUnpackError(snap_file=snap_file) from err
+
+            for metadata_dir in ("meta", "snap"):
+                metadata_path = os.path.join(unpack_dir, metadata_dir)
+                shutil.rmtree(metadata_path, ignore_errors=True)
+            file_utils.link_or_copy_tree(unpack_dir, install_dir)
 
 
 def install_snaps(snaps_list: Union[Sequence[str], Set[str]]) -> List[str]:
-    """Install snaps of the format <snap-name>/<channel>.
+    """Install snaps of the format <snap-name>/<channel> or <snap-name>=<revision>.
 
     :return: a list of "name=revision" for the snaps installed.
     """
@@ -281,7 +463,11 @@ def install_snaps(snaps_list: Union[Sequence[str], Set[str]]) -> List[str]:
         # Allow bases to be installed from non stable channels.
         snap_pkg_channel = snap_pkg.get_store_snap_info()["channel"]
         snap_pkg_type = snap_pkg.get_store_snap_info()["type"]
-        if snap_pkg_channel != "stable" and snap_pkg_type == "base":
+        if (
+            snap_pkg.revision is None
+            and snap_pkg_channel != "stable"
+            and snap_pkg_type == "base"
+        ):
             snap_pkg = SnapPackage(
                 "{snap_name}/latest/{channel}".format(
                     snap_name=snap_pkg.name, channel=snap_pkg_channel
@@ -290,6 +476,9 @@ def install_snaps(snaps_list: Union[Sequence[str], Set[str]]) -> List[str]:
 
         if not snap_pkg.installed:
             snap_pkg.install()
+        elif snap_pkg.revision is not None:
+            if snap_pkg.get_local_snap_info()["revision"] != snap_pkg.revision:
+                snap_pkg.install()
         elif snap_pkg.get_current_channel() != snap_pkg.channel:
             snap_pkg.refresh()
 
@@ -329,6 +518,36 @@ def get_assertion(assertion_params: Sequence[str]) -> bytes:
         ) from call_error
 
 
+def _get_snap_revision_headers(assert_file: str) -> Dict[str, str]:
+    """Obtain the headers of the snap-revision assertion in an assertions file."""
+    # Assertions are separated by their signatures, and their headers are
+    # separated from the signature by an empty line.
+    with open(assert_file) as assertions:
+        content = assertions.read()
+
+    for block in content.split("\n\n"):
+        headers: Dict[str, str] = {}
+        for line in block.splitlines():
+            name, sep, value = line.partition(": ")
+            if sep and not line.startswith(" "):
+                headers[name] = value
+
+        if headers.get("type") == "snap-revision":
+            return headers
+
+    return {}
+
+
+def _get_snap_digest(snap_file: str) -> str:
+    """Obtain the sha3-384 digest of a snap file, as used in assertions."""
+    digest = hashlib.sha3_384()
+    with open(snap_file, "rb") as snap:
+        for chunk in iter(lambda: snap.read(1024 * 1024), b""):
+            digest.update(chunk)
+
+    return base64.urlsafe_b64encode(digest.digest()).decode().rstrip("=")
+
+
 def _get_parsed_snap(snap: str) -> Tuple[str, str]:
     if "/" in snap:
         sep_index = snap.find("/")
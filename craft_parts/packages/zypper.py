@@ -0,0 +1,237 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Support for rpm packages using zypper."""
+
+import logging
+import os
+import pathlib
+import subprocess
+from typing import Any, Dict, List, Set
+from xml.etree import ElementTree
+
+from xdg import BaseDirectory  # type: ignore
+
+from . import errors, rpm
+from .base import get_pkg_name_parts
+from .package_manager import PackageManagerRepository
+from .repositories import RpmRepository, install_rpm_repository
+
+logger = logging.getLogger(__name__)
+
+_ZYPPER_REPOS_DIR = "/etc/zypp/repos.d"
+
+
+class OpenSUSE(PackageManagerRepository):
+    """Repository management for openSUSE and SUSE Linux Enterprise packages.
+
+    Stage packages are resolved in an empty installation root using the
+    repositories configured on the host, so that all dependencies are
+    fetched and fetching doesn't modify the host package database.
+    Recommended stage packages are only fetched if requested, and suggested
+    packages are not supported.
+    """
+
+    package_suffix = ".rpm"
+    supports_recommends = True
+
+    package_names = {
+        "g++": "gcc-c++",
+        "python3-dev": "python3-devel",
+        "python3-venv": "python3",
+        "zlib1g-dev": "zlib-devel",
+        "libssl-dev": "libopenssl-devel",
+        "libffi-dev": "libffi-devel",
+        "libyaml-dev": "libyaml-devel",
+        "libgmp-dev": "gmp-devel",
+        "libpcre2-dev": "pcre2-devel",
+        "libevent-dev": "libevent-devel",
+        "xz-utils": "xz",
+        "ninja-build": "ninja",
+        "default-jdk-headless": "java-17-openjdk-headless",
+        "erlang-dev": "erlang",
+    }
+
+    @classmethod
+    def get_package_libraries(cls, package_name: str) -> Set[str]:
+        """Return a list of libraries in package_name."""
+        return rpm.run_rpm_query_list_files(package_name)
+
+    @classmethod
+    def refresh_build_packages_list(cls) -> None:
+        """Refresh the list of packages available in the repository."""
+        try:
+            cmd = ["sudo", "--preserve-env", "zypper", "--non-interactive", "refresh"]
+            logger.debug("Executing: %s", cmd)
+            subprocess.check_call(cmd)
+        except subprocess.CalledProcessError as call_error:
+            raise errors.PackageListRefreshError(
+                "failed to run zypper refresh"
+            ) from call_error
+
+    @classmethod
+    def get_installed_packages(cls) -> List[str]:
+        """Obtain a list of the installed packages and their versions."""
+        return rpm.get_installed_packages()
+
+    @classmethod
+    def refresh_stage_packages_list(
+        cls, *, application_name: str, target_arch: str
+    ) -> None:
+        """Refresh the list of packages available in the repository."""
+        command = ["zypper", *_get_stage_options(application_name), "refresh"]
+        logger.debug("Executing: %s", command)
+        try:
+            subprocess.check_call(command)
+        except subprocess.CalledProcessError as err:
+            raise errors.PackageListRefreshError(
+                "failed to run zypper refresh"
+            ) from err
+
+    @classmethod
+    def install_package_repositories(
+        cls,
+        repositories: Dict[str, Dict[str, Any]],
+        *,
+        keys_dir: pathlib.Path,
+    ) -> bool:
+        """Add package repositories to the host system.
+
+        :param repositories: A dictionary where the keys are unique repository
+            names and values are rpm repository definitions.
+        :param keys_dir: The directory containing the repository keys.
+
+        :return: Whether the package lists must be refreshed.
+        """
+        changed = False
+        for name, data in repositories.items():
+            repository = RpmRepository.unmarshal(data)
+            changed |= install_rpm_repository(
+                repository,
+                name=name,
+                keys_dir=keys_dir,
+                repos_dir=pathlib.Path(_ZYPPER_REPOS_DIR),
+            )
+
+        return changed
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path: pathlib.Path) -> str:
+        """Obtain the name and version of a fetched stage package file."""
+        return rpm.get_rpm_name_version(package_path.name)
+
+    @classmethod
+    def _get_install_command(cls, package_names: List[str]) -> List[str]:
+        """Obtain the zypper command to install build packages."""
+        return ["zypper", "--non-interactive", "install", *package_names]
+
+    @classmethod
+    def _list_build_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the build packages that would be installed."""
+        return _run_zypper_install_dry_run(["--non-interactive"], package_names)
+
+    @classmethod
+    def _query_installed_packages(cls, package_names: List[str]) -> List[str]:
+        """Obtain the versions of the given installed packages."""
+        return rpm.run_rpm_query([get_pkg_name_parts(n)[0] for n in package_names])
+
+    @classmethod
+    def _list_stage_packages(
+        cls,
+        package_names: List[str],
+        *,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the stage packages that would be fetched."""
+        return _run_zypper_install_dry_run(
+            _get_stage_options(application_name), package_names, recommends=recommends
+        )
+
+    @classmethod
+    def _get_download_command(
+        cls,
+        package_names: List[str],
+        *,
+        download_dir: str,
+        application_name: str,
+        target_arch: str,
+        recommends: bool,
+    ) -> List[str]:
+        """Obtain the zypper command to download stage packages."""
+        return [
+            "zypper",
+            *_get_stage_options(application_name),
+            f"--pkg-cache-dir={download_dir}",
+            "install",
+            "--download-only",
+            _get_recommends_option(recommends),
+            *package_names,
+        ]
+
+    @classmethod
+    def _extract_package(cls, pkg_path: pathlib.Path, extract_dir: str) -> None:
+        """Extract the files of the given rpm package."""
+        rpm.extract_rpm(pkg_path, extract_dir)
+
+
+def _get_stage_options(application_name: str) -> List[str]:
+    """Obtain the zypper options used to fetch stage packages."""
+    cache_dir = BaseDirectory.save_cache_path(
+        application_name, "craft-parts", "stage-packages-zypper"
+    )
+    return [
+        "--non-interactive",
+        "--gpg-auto-import-keys",
+        f"--root={os.path.join(cache_dir, 'root')}",
+        f"--reposd-dir={_ZYPPER_REPOS_DIR}",
+        f"--cache-dir={os.path.join(cache_dir, 'cache')}",
+    ]
+
+
+def _get_recommends_option(recommends: bool) -> str:
+    """Obtain the zypper option to fetch or skip recommended packages."""
+    return "--recommends" if recommends else "--no-recommends"
+
+
+def _run_zypper_install_dry_run(
+    options: List[str], package_names: List[str], *, recommends: bool = False
+) -> List[str]:
+    """Obtain ``name=version-release`` of the packages that would be installed."""
+    command = [
+        "zypper",
+        *options,
+        "--xmlout",
+        "install",
+        "--dry-run",
+        _get_recommends_option(recommends),
+        *package_names,
+    ]
+    try:
+        output = subprocess.check_output(command)
+    except subprocess.CalledProcessError as err:
+        raise errors.PackageFetchError(str(err)) from err
+
+    # Packages to install are listed as solvables in the install summary.
+    root = ElementTree.fromstring(output)
+    return sorted(
+        {
+            f"{solvable.get('name')}={solvable.get('edition')}"
+            for solvable in root.iterfind(".//to-install/solvable")
+            if solvable.get("type") == "package"
+        }
+    )
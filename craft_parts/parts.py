@@ -16,22 +16,228 @@
 
 """Definitions and helpers to handle parts."""
 
+import fnmatch
+import os
+import re
 from pathlib import Path
-from typing import Any, Dict, List, Optional, Sequence, Set
-
-from pydantic import BaseModel, Field, ValidationError
-
-from craft_parts import errors
+from typing import (
+    Any,
+    Callable,
+    Dict,
+    List,
+    Mapping,
+    Optional,
+    Sequence,
+    Set,
+    Tuple,
+    Union,
+)
+
+from pydantic import BaseModel, Field, ValidationError, root_validator, validator
+
+from craft_parts import bases, errors, steps
 from craft_parts.dirs import ProjectDirs
+from craft_parts.permissions import Permissions
 from craft_parts.plugins.properties import PluginProperties
 from craft_parts.steps import Step
 
 
+class SubmoduleSpec(BaseModel):
+    """A git submodule to fetch when pulling the part source."""
+
+    path: str
+    branch: str = ""
+    depth: int = 0
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+
+class SourceAuthSpec(BaseModel):
+    """The credentials used to download a source file.
+
+    Credentials are given as the names of environment variables containing
+    the secret values, so they never appear in the part specification.
+    """
+
+    token_env: str = ""
+    username_env: str = ""
+    password_env: str = ""
+    headers_env: Dict[str, str] = {}
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+
+class SourceSpec(BaseModel):
+    """A source in a part that combines more than one source.
+
+    Each source is pulled into the target subdirectory of the part source
+    directory, or into the part source directory itself if no target is set.
+    """
+
+    source: str
+    source_type: str = ""
+    source_checksum: str = ""
+    source_branch: str = ""
+    source_commit: str = ""
+    source_depth: int = 0
+    source_tag: str = ""
+    source_submodules: Optional[List[Union[str, SubmoduleSpec]]] = None
+    source_sparse_paths: List[str] = []
+    source_lfs: Optional[bool] = None
+    source_keyring: str = ""
+    source_allowed_signers: str = ""
+    source_checksum_signature: str = ""
+    source_auth: Optional[SourceAuthSpec] = None
+    source_region: str = ""
+    source_profile: str = ""
+    target: str = ""
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+    @validator("target")
+    def validate_target(cls, target: str) -> str:
+        """Make sure the target is a subdirectory of the part source."""
+        if not target:
+            return target
+
+        path = os.path.normpath(target)
+        if os.path.isabs(path) or path == ".." or path.startswith("../"):
+            raise ValueError("target must be a subdirectory of the part source")
+        return path
+
+    @root_validator(skip_on_failure=True)
+    def validate_checksum_signature(cls, values: Dict[str, Any]) -> Dict[str, Any]:
+        """Make sure checksum file signatures can be verified."""
+        return _validate_checksum_signature(values)
+
+    # pylint: enable=no-self-argument
+
+
+def _validate_checksum_signature(values: Dict[str, Any]) -> Dict[str, Any]:
+    """Make sure a checksum file signature is set with the keyring to verify it."""
+    if values.get("source_checksum_signature") and not values.get("source_keyring"):
+        raise ValueError("'source-checksum-signature' requires 'source-keyring'")
+    return values
+
+
+class OrganizeSpec(BaseModel):
+    """An organize destination with options applied to organized files.
+
+    If ``symlink`` is set, a symbolic link to the file is created in the
+    destination instead of moving the file. The owner and group can be
+    names or numeric identifiers, and the mode is given in octal notation.
+    """
+
+    path: str
+    symlink: bool = False
+    owner: str = ""
+    group: str = ""
+    mode: str = ""
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+    @validator("mode")
+    def validate_mode(cls, mode: str) -> str:
+        """Make sure the mode is a valid octal file mode."""
+        if mode and not re.fullmatch(r"[0-7]{3,4}", mode):
+            raise ValueError(f"mode {mode!r} must be an octal file mode")
+        return mode
+
+    @root_validator(skip_on_failure=True)
+    def validate_symlink_mode(cls, values: Dict[str, Any]) -> Dict[str, Any]:
+        """Make sure the mode is not set when creating symlinks."""
+        if values.get("symlink") and values.get("mode"):
+            raise ValueError("mode cannot be set when creating a symlink")
+        return values
+
+    # pylint: enable=no-self-argument
+
+
+class StripSpec(BaseModel):
+    """Options to strip ELF binaries when priming.
+
+    If ``split_debug`` is set, debug information is saved to files in the
+    project debug directory before binaries are stripped. Primed files
+    matching the ``exclude`` patterns are not stripped.
+    """
+
+    split_debug: bool = False
+    exclude: List[str] = []
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+
+class ElfPatchSpec(BaseModel):
+    """Options to patch primed ELF files.
+
+    The rpaths of primed ELF files are rewritten to point to the primed
+    libraries they need. If ``interpreter`` is set, it replaces the dynamic
+    linker path of primed executables. Primed files matching the ``exclude``
+    patterns are not patched.
+    """
+
+    interpreter: str = ""
+    exclude: List[str] = []
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+
+# Source options that are set in each source entry when a part has more
+# than one source.
+_SOURCE_ENTRY_OPTIONS = [n for n in SourceSpec.__fields__ if n.startswith("source_")]
+
+_CONDITIONS = {"arch", "base"}
+
+_STAGE_CONFLICT_POLICIES = ("error", "first-wins", "last-wins", "prefer-part")
+
+_VARIABLE_PATTERN = re.compile(
+    r"\$(?:(?P<name>[A-Za-z_][A-Za-z0-9_]*)|\{(?P<braced>[A-Za-z_][A-Za-z0-9_]*)\})"
+)
+
+
 class PartSpec(BaseModel):
     """The part specification data."""
 
     plugin: Optional[str] = None
-    source: Optional[str] = None
+    source: Optional[Union[str, List[SourceSpec]]] = None
     source_checksum: str = ""
     source_branch: str = ""
     source_commit: str = ""
@@ -39,21 +245,50 @@ class PartSpec(BaseModel):
     source_subdir: str = ""
     source_tag: str = ""
     source_type: str = ""
+    source_submodules: Optional[List[Union[str, SubmoduleSpec]]] = None
+    source_sparse_paths: List[str] = []
+    source_lfs: Optional[bool] = None
+    source_keyring: str = ""
+    source_allowed_signers: str = ""
+    source_checksum_signature: str = ""
+    source_auth: Optional[SourceAuthSpec] = None
+    source_region: str = ""
+    source_profile: str = ""
+    source_patches: List[str] = []
+    source_patches_strip: int = 1
     disable_parallel: bool = False
     after: List[str] = []
     stage_snaps: List[str] = []
+    stage_snaps_track_channel: bool = False
     stage_packages: List[str] = []
+    stage_packages_recommends: bool = False
+    stage_packages_suggests: bool = False
+    chisel_release: str = ""
     build_snaps: List[str] = []
     build_packages: List[str] = []
     build_environment: List[Dict[str, str]] = []
     build_attributes: List[str] = []
-    organize_files: Dict[str, str] = Field({}, alias="organize")
+    organize_files: Dict[str, Union[str, OrganizeSpec]] = Field({}, alias="organize")
+    filesets: Dict[str, List[str]] = {}
     stage_files: List[str] = Field(["*"], alias="stage")
+    stage_conflict_policy: str = "error"
+    stage_conflicts: Dict[str, str] = {}
     prime_files: List[str] = Field(["*"], alias="prime")
+    overlay_packages: List[str] = []
+    overlay_files: List[str] = Field(["*"], alias="overlay")
+    overlay_script: Optional[str] = None
+    permissions: List[Permissions] = []
+    strip: Optional[StripSpec] = None
+    elf_patch: Optional[ElfPatchSpec] = None
     override_pull: Optional[str] = None
     override_build: Optional[str] = None
     override_stage: Optional[str] = None
     override_prime: Optional[str] = None
+    step_timeouts: Dict[str, float] = {}
+    step_retries: Dict[str, int] = {}
+    build_network: bool = True
+    step_network: Dict[str, bool] = {}
+    build_isolation: str = ""
 
     class Config:
         """Pydantic model configuration."""
@@ -63,6 +298,122 @@ class PartSpec(BaseModel):
         allow_mutation = False
         alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
 
+    # pylint: disable=no-self-argument
+    @validator("step_timeouts")
+    def validate_step_timeouts(cls, timeouts: Dict[str, float]) -> Dict[str, float]:
+        """Make sure step timeouts are set for valid steps and are positive."""
+        _validate_step_names(timeouts)
+        for name, timeout in timeouts.items():
+            if timeout <= 0:
+                raise ValueError(f"timeout for step {name!r} must be positive")
+        return timeouts
+
+    @validator("step_retries")
+    def validate_step_retries(cls, retries: Dict[str, int]) -> Dict[str, int]:
+        """Make sure step retries are set for valid steps and are not negative."""
+        _validate_step_names(retries)
+        for name, count in retries.items():
+            if count < 0:
+                raise ValueError(f"retries for step {name!r} must not be negative")
+        return retries
+
+    @validator("step_network")
+    def validate_step_network(cls, network: Dict[str, bool]) -> Dict[str, bool]:
+        """Make sure network access is set for valid steps."""
+        _validate_step_names(network)
+        return network
+
+    @validator("organize_files")
+    def validate_organize_files(
+        cls, organize: Dict[str, Union[str, OrganizeSpec]]
+    ) -> Dict[str, Union[str, OrganizeSpec]]:
+        """Make sure regular expressions in organize keys are valid."""
+        for key in organize:
+            if key.startswith("^"):
+                try:
+                    re.compile(key)
+                except re.error as err:
+                    raise ValueError(f"invalid organize pattern {key!r}: {err}")
+        return organize
+
+    @validator("stage_files", "prime_files", "overlay_files")
+    def expand_filesets(cls, entries: List[str], values: Dict[str, Any]) -> List[str]:
+        """Replace references to named filesets with the fileset entries."""
+        filesets = values.get("filesets", {})
+        expanded: List[str] = []
+        for entry in entries:
+            if not entry.startswith("$"):
+                expanded.append(entry)
+                continue
+
+            name = entry[1:]
+            if name not in filesets:
+                raise ValueError(f"fileset {name!r} is not defined")
+            expanded.extend(filesets[name])
+
+        return expanded
+
+    @validator("stage_conflict_policy")
+    def validate_stage_conflict_policy(cls, policy: str) -> str:
+        """Make sure the stage conflict policy is valid."""
+        _validate_stage_conflict_policy(policy)
+        return policy
+
+    @validator("stage_conflicts")
+    def validate_stage_conflicts(cls, conflicts: Dict[str, str]) -> Dict[str, str]:
+        """Make sure stage conflict policies set for paths are valid."""
+        for policy in conflicts.values():
+            _validate_stage_conflict_policy(policy)
+        return conflicts
+
+    @validator("strip", "elf_patch", pre=True)
+    def validate_prime_processing(cls, options: Any) -> Any:
+        """Enable prime processing with default options if set to true."""
+        if options is True:
+            return {}
+        if options is False:
+            return None
+        return options
+
+    @validator("after", each_item=True)
+    def validate_after(cls, dependency: str) -> str:
+        """Make sure dependency steps are valid step names."""
+        _, sep, step_name = dependency.rpartition(":")
+        if sep:
+            _validate_step_names({step_name: None})
+        return dependency
+
+    @validator("build_isolation")
+    def validate_build_isolation(cls, isolation: str) -> str:
+        """Make sure the build isolation method is valid."""
+        if isolation and isolation not in ("chroot", "bubblewrap"):
+            raise ValueError(
+                f"build isolation {isolation!r} must be 'chroot' or 'bubblewrap'"
+            )
+        return isolation
+
+    @root_validator(skip_on_failure=True)
+    def validate_source_list(cls, values: Dict[str, Any]) -> Dict[str, Any]:
+        """Make sure source options are set in each entry of a source list."""
+        if not isinstance(values.get("source"), list):
+            return values
+
+        for name in _SOURCE_ENTRY_OPTIONS:
+            if values.get(name) != cls.__fields__[name].default:
+                option = name.replace("_", "-")
+                raise ValueError(
+                    f"{option!r} must be set in each source entry when "
+                    "'source' is a list"
+                )
+        return values
+
+    @root_validator(skip_on_failure=True)
+    def validate_checksum_signature(cls, values: Dict[str, Any]) -> Dict[str, Any]:
+        """Make sure checksum file signatures can be verified."""
+        return _validate_checksum_signature(values)
+
+    # pylint: enable=no-self-argument
+
     @classmethod
     def unmarshal(cls, data: Dict[str, Any]) -> "PartSpec":
         """Create and populate a new ``PartSpec`` object from dictionary data.
@@ -100,11 +451,53 @@ class PartSpec(BaseModel):
         """
         return {
             Step.PULL: self.override_pull,
+            Step.OVERLAY: self.overlay_script,
             Step.BUILD: self.override_build,
             Step.STAGE: self.override_stage,
             Step.PRIME: self.override_prime,
         }[step]
 
+    def get_stage_conflict_policy(self, path: str) -> str:
+        """Return the policy used to resolve stage conflicts in the given path.
+
+        Policies set for path patterns in ``stage-conflicts`` are checked in
+        order, falling back to ``stage-conflict-policy`` if none matches.
+
+        :param path: The path of the conflicting file, relative to the part
+            install directory.
+
+        :return: The stage conflict policy for the path.
+        """
+        for pattern, policy in self.stage_conflicts.items():
+            if fnmatch.fnmatchcase(path, pattern):
+                return policy
+        return self.stage_conflict_policy
+
+    def has_network_access(self, step: Step) -> bool:
+        """Verify whether commands executed in the given step can access the network.
+
+        Network access is set for individual steps using ``step-network``.
+        The ``build-network`` property sets network access for the build step
+        if it's not set in ``step-network``.
+
+        :param step: The step to verify.
+
+        :return: Whether network access is enabled for the step.
+        """
+        access = self.step_network.get(step.name.lower())
+        if access is None and step == Step.BUILD:
+            access = self.build_network
+        return access is not False
+
+    @property
+    def has_overlay(self) -> bool:
+        """Whether the part sets properties used by the overlay step."""
+        return bool(
+            self.overlay_packages
+            or self.overlay_script is not None
+            or self.overlay_files != ["*"]
+        )
+
 
 class Part:
     """Each of the components used in the project specification.
@@ -153,12 +546,22 @@ class Part:
             self.spec = PartSpec.unmarshal(data)
         except ValidationError as err:
             raise errors.PartSpecificationError.from_validation_error(
-                part_name=name, error_list=err.errors()
+                part_name=name,
+                error_list=err.errors(),
+                valid_names=[
+                    *(field.alias for field in PartSpec.__fields__.values()),
+                    *plugin_properties.marshal(),
+                ],
             )
 
     def __repr__(self):
         return f"Part({self.name!r})"
 
+    @property
+    def work_dir(self) -> Path:
+        """Return the toplevel directory containing the work directories."""
+        return self._dirs.work_dir
+
     @property
     def parts_dir(self) -> Path:
         """Return the directory containing work files for each part."""
@@ -198,6 +601,11 @@ class Part:
         """Return the subdirectory containing the part lifecycle state."""
         return self._part_dir / "state"
 
+    @property
+    def part_log_dir(self) -> Path:
+        """Return the subdirectory containing the output logs of each step."""
+        return self.part_state_dir / "logs"
+
     @property
     def part_packages_dir(self) -> Path:
         """Return the subdirectory containing the part stage packages directory."""
@@ -208,6 +616,16 @@ class Part:
         """Return the subdirectory containing the part snap packages directory."""
         return self._part_dir / "stage_snaps"
 
+    @property
+    def part_slices_dir(self) -> Path:
+        """Return the subdirectory containing the cut chisel slices."""
+        return self._part_dir / "stage_slices"
+
+    @property
+    def part_layer_dir(self) -> Path:
+        """Return the subdirectory containing the part overlay layer."""
+        return self._part_dir / "layer"
+
     @property
     def part_run_dir(self) -> Path:
         """Return the subdirectory containing the part plugin scripts."""
@@ -223,12 +641,34 @@ class Part:
         """Return the primed tree containing the artifacts to deploy."""
         return self._dirs.prime_dir
 
+    @property
+    def debug_dir(self) -> Path:
+        """Return the directory containing debug information split from binaries."""
+        return self._dirs.debug_dir
+
     @property
     def dependencies(self) -> List[str]:
         """Return the list of parts this part depends on."""
         if not self.spec.after:
             return []
-        return self.spec.after
+        return [_split_dependency(dep)[0] for dep in self.spec.after]
+
+    def get_dependency_step(self, name: str) -> Step:
+        """Return the step a dependency must reach before this part is built.
+
+        Dependencies are listed in ``after`` as ``<part>`` or ``<part>:<step>``.
+        If the step is not specified, the dependency must be staged.
+
+        :param name: The name of the part this part depends on.
+
+        :return: The step the dependency must reach.
+        """
+        for dep in self.spec.after:
+            dep_name, step = _split_dependency(dep)
+            if dep_name == name:
+                return step
+
+        return Step.STAGE
 
 
 def part_list_by_name(
@@ -321,3 +761,262 @@ def part_dependencies(
             )
 
     return dependencies
+
+
+def part_dependency_steps(
+    name: str, step: Step, *, part_list: List[Part]
+) -> Dict[str, Step]:
+    """Return the steps parts must reach before the named part runs a step.
+
+    Dependencies of dependencies are included if the step their dependent
+    must reach requires them.
+
+    :param name: The name of the dependent part.
+    :param step: The step to run in the dependent part.
+    :param part_list: The list of all known parts.
+
+    :returns: A dictionary mapping the names of all parts the given part
+        depends on to the last step they must run.
+
+    :raises InvalidPartName: if a part name is not defined.
+    """
+    part = next((p for p in part_list if p.name == name), None)
+    if not part:
+        raise errors.InvalidPartName(name)
+
+    dependency_steps: Dict[str, Step] = {}
+    for dependency_name in part.dependencies:
+        prerequisite_step = steps.dependency_prerequisite_step(
+            step, part.get_dependency_step(dependency_name)
+        )
+        if not prerequisite_step:
+            continue
+
+        nested_steps = part_dependency_steps(
+            dependency_name, prerequisite_step, part_list=part_list
+        )
+        nested_steps[dependency_name] = prerequisite_step
+        for nested_name, nested_step in nested_steps.items():
+            dependency_steps[nested_name] = max(
+                nested_step, dependency_steps.get(nested_name, nested_step)
+            )
+
+    return dependency_steps
+
+
+def part_lower_layers(name: str, *, part_list: List[Part]) -> List[Part]:
+    """Return the parts whose overlay layers are below the named part layer.
+
+    Overlay layers are stacked in the order parts are processed, so the
+    layer of each part is on top of the layers of all parts sorted before it.
+
+    :param name: The name of the part.
+    :param part_list: The list of all known parts.
+
+    :returns: The list of parts with lower layers, bottommost first.
+
+    :raises InvalidPartName: if a part name is not defined.
+    """
+    sorted_parts = sort_parts(part_list)
+    names = [p.name for p in sorted_parts]
+    if name not in names:
+        raise errors.InvalidPartName(name)
+
+    return sorted_parts[: names.index(name)]
+
+
+def apply_template(
+    name: str, data: Dict[str, Any], templates: Mapping[str, Any]
+) -> Dict[str, Any]:
+    """Merge the properties of the template a part specification extends.
+
+    Parts extend a template by setting its name in the ``extends`` property.
+    Templates can also extend other templates. Properties set in the part
+    replace the properties set in the templates it extends.
+
+    :param name: The name of the part or template being resolved.
+    :param data: The part specification data.
+    :param templates: The part templates defined in the project.
+
+    :return: A copy of the part specification with template properties.
+
+    :raise errors.PartSpecificationError: If a template is not defined or
+        templates extend each other.
+    """
+    spec = {key: value for key, value in data.items() if key != "extends"}
+    chain: List[str] = []
+    template_name = data.get("extends")
+
+    while template_name is not None:
+        if not isinstance(template_name, str) or template_name not in templates:
+            raise errors.PartSpecificationError(
+                part_name=name, message=f"template {template_name!r} is not defined"
+            )
+
+        if template_name in chain:
+            raise errors.PartSpecificationError(
+                part_name=name, message=f"template {template_name!r} extends itself"
+            )
+        chain.append(template_name)
+
+        template = templates[template_name]
+        if not isinstance(template, dict):
+            raise errors.PartSpecificationError(
+                part_name=name, message=f"template {template_name!r} is malformed"
+            )
+
+        template_spec = {k: v for k, v in template.items() if k != "extends"}
+        spec = {**template_spec, **spec}
+        template_name = template.get("extends")
+
+    return spec
+
+
+def resolve_conditional_properties(
+    name: str, data: Dict[str, Any], *, arch: str, base: Optional[bases.Base]
+) -> Dict[str, Any]:
+    """Apply the conditional blocks of a part specification.
+
+    Conditional blocks are listed in the ``when`` property. Each block sets
+    part properties if all its conditions match the project: ``arch`` lists
+    the target architectures and ``base`` lists the project bases, either as
+    a single name or a list of names. Base names are compared as parsed, so
+    ``core24`` matches ``ubuntu@24.04``. Properties set by matching blocks
+    replace the properties of the part, in the order blocks are listed.
+
+    :param name: The part name.
+    :param data: The part specification data.
+    :param arch: The project target architecture.
+    :param base: The base parts are built on, if known.
+
+    :return: A copy of the part specification with conditional blocks applied.
+
+    :raise errors.PartSpecificationError: If a conditional block is malformed.
+    :raise errors.InvalidBase: If a base name in a conditional block is
+        malformed.
+    """
+    blocks = data.get("when")
+    if blocks is None:
+        return data
+
+    if not isinstance(blocks, list):
+        raise errors.PartSpecificationError(
+            part_name=name, message="'when' must be a list of conditional blocks"
+        )
+
+    spec = {key: value for key, value in data.items() if key != "when"}
+    for block in blocks:
+        if not isinstance(block, dict) or not _CONDITIONS & block.keys():
+            raise errors.PartSpecificationError(
+                part_name=name, message="conditional blocks must set 'arch' or 'base'"
+            )
+
+        arch_matches = _condition_matches(name, block.get("arch"), arch)
+        base_matches = _condition_matches(
+            name,
+            block.get("base"),
+            base.name if base else None,
+            parse=lambda base_name: bases.parse_base(base_name).name,
+        )
+        if arch_matches and base_matches:
+            spec.update(
+                {key: value for key, value in block.items() if key not in _CONDITIONS}
+            )
+
+    return spec
+
+
+def _condition_matches(
+    name: str,
+    condition: Any,
+    value: Optional[str],
+    *,
+    parse: Callable[[str], str] = str,
+) -> bool:
+    """Verify whether a conditional block condition matches a project value.
+
+    Condition names are converted with ``parse`` before being compared.
+    """
+    if condition is None:
+        return True
+
+    names = [condition] if isinstance(condition, str) else condition
+    if not isinstance(names, list) or not all(isinstance(n, str) for n in names):
+        raise errors.PartSpecificationError(
+            part_name=name, message="conditions must be a name or a list of names"
+        )
+
+    return value in [parse(n) for n in names]
+
+
+def expand_variables(
+    data: Dict[str, Any], variables: Mapping[str, str]
+) -> Dict[str, Any]:
+    """Expand variables in the properties of a part specification.
+
+    Variables are referenced as ``$NAME`` or ``${NAME}`` in property values,
+    list items and dictionary keys. References to undefined variables are
+    left unchanged. Scriptlets are not expanded, since they are executed by
+    the shell with project variables set in the environment.
+
+    :param data: The part specification data.
+    :param variables: The variables to expand.
+
+    :return: A copy of the part specification with variables expanded.
+    """
+
+    def _expand(value: Any) -> Any:
+        if isinstance(value, str):
+            return _VARIABLE_PATTERN.sub(_replace, value)
+        if isinstance(value, list):
+            return [_expand(item) for item in value]
+        if isinstance(value, dict):
+            return {_expand(key): _expand(item) for key, item in value.items()}
+        return value
+
+    def _replace(match: "re.Match") -> str:
+        name = match.group("name") or match.group("braced")
+        return variables.get(name, match.group(0))
+
+    return {
+        key: value if _is_scriptlet(key) else _expand(value)
+        for key, value in data.items()
+    }
+
+
+def _is_scriptlet(name: str) -> bool:
+    """Verify whether a part property contains a scriptlet."""
+    return name.startswith("override-") or name == "overlay-script"
+
+
+def _split_dependency(dependency: str) -> Tuple[str, Step]:
+    """Obtain the part name and step from an ``after`` entry."""
+    name, sep, step_name = dependency.rpartition(":")
+    if not sep:
+        return dependency, Step.STAGE
+
+    return name, Step[step_name.upper()]
+
+
+def _validate_step_names(policy: Dict[str, Any]) -> None:
+    """Verify that the keys of a step policy are valid step names.
+
+    :param policy: A dictionary mapping step names to policy values.
+
+    :raise ValueError: If a step name is not valid.
+    """
+    step_names = [step.name.lower() for step in Step]
+    for name in policy:
+        if name not in step_names:
+            raise ValueError(f"{name!r} is not a valid step name")
+
+
+def _validate_stage_conflict_policy(policy: str) -> None:
+    """Verify that a stage conflict policy is valid.
+
+    :param policy: The name of the stage conflict policy.
+
+    :raise ValueError: If the policy is not valid.
+    """
+    if policy not in _STAGE_CONFLICT_POLICIES:
+        raise ValueError(f"{policy!r} is not a valid stage conflict policy")
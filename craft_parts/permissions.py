@@ -0,0 +1,182 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Set ownership, modes, capabilities and ACLs on primed files.
+
+Permissions are defined in the ``permissions`` part property as a list of
+entries, each applying to the primed files matching its path pattern. If
+more than one entry matches a file, settings from later entries take
+precedence.
+"""
+
+import fnmatch
+import logging
+import os
+import re
+import shutil
+import subprocess
+from pathlib import Path
+from typing import Any, Dict, Iterable, List, Optional
+
+from pydantic import BaseModel, validator
+
+from craft_parts import errors
+
+logger = logging.getLogger(__name__)
+
+
+class Permissions(BaseModel):
+    """The permissions to set on files matching a path pattern.
+
+    Paths are glob patterns relative to the prime directory. Capabilities
+    use the ``setcap`` text format, such as ``cap_net_bind_service=+ep``,
+    and ACL entries use the ``setfacl`` format, such as ``u:daemon:rx``.
+    """
+
+    path: str = "*"
+    owner: Optional[int] = None
+    group: Optional[int] = None
+    mode: Optional[str] = None
+    capabilities: Optional[str] = None
+    acls: List[str] = []
+
+    class Config:
+        """Pydantic model configuration."""
+
+        validate_assignment = True
+        extra = "forbid"
+        allow_mutation = False
+        alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
+
+    # pylint: disable=no-self-argument
+    @validator("mode")
+    def validate_mode(cls, mode: Optional[str]) -> Optional[str]:
+        """Make sure the mode is a valid octal file mode."""
+        if mode is not None and not re.fullmatch(r"[0-7]{3,4}", mode):
+            raise ValueError(f"mode {mode!r} must be an octal file mode")
+        return mode
+
+    # pylint: enable=no-self-argument
+
+    def applies_to(self, path: str) -> bool:
+        """Verify whether these permissions apply to a path.
+
+        :param path: The path relative to the prime directory.
+
+        :return: Whether the path matches the permissions path pattern.
+        """
+        return fnmatch.fnmatchcase(path, self.path)
+
+
+def apply_permissions(
+    *,
+    part_name: str,
+    root: Path,
+    paths: Iterable[str],
+    permissions: List[Permissions],
+) -> Dict[str, Dict[str, Any]]:
+    """Set the permissions of files in a directory.
+
+    Files migrated using hard links are replaced with copies before their
+    permissions are set, so the files they were migrated from are not
+    modified. Symbolic links are not changed.
+
+    :param part_name: The name of the part being processed.
+    :param root: The directory containing the files.
+    :param paths: The files and directories to process, relative to root.
+    :param permissions: The permissions defined in the part.
+
+    :return: A dictionary mapping each changed path to the settings applied.
+
+    :raise errors.FilePermissionsError: If permissions can't be set.
+    """
+    applied: Dict[str, Dict[str, Any]] = {}
+    if not permissions:
+        return applied
+
+    for path in sorted(paths):
+        filename = root / path
+        if filename.is_symlink() or not filename.exists():
+            continue
+
+        settings: Dict[str, Any] = {}
+        for entry in permissions:
+            if entry.applies_to(path):
+                settings.update(entry.dict(exclude={"path"}, exclude_defaults=True))
+
+        if settings:
+            logger.debug("set permissions of %s: %s", path, settings)
+            _set_permissions(
+                filename, part_name=part_name, path=path, settings=settings
+            )
+            applied[path] = settings
+
+    return applied
+
+
+def _set_permissions(
+    filename: Path, *, part_name: str, path: str, settings: Dict[str, Any]
+) -> None:
+    """Apply permission settings to a file."""
+    if filename.is_file() and filename.stat().st_nlink > 1:
+        _break_hard_link(filename)
+
+    # Changing ownership clears file capabilities, so they're set last.
+    try:
+        if "owner" in settings or "group" in settings:
+            os.chown(filename, settings.get("owner", -1), settings.get("group", -1))
+        if "mode" in settings:
+            os.chmod(filename, int(settings["mode"], 8))
+    except OSError as err:
+        raise errors.FilePermissionsError(
+            part_name=part_name, path=path, message=err.strerror
+        ) from err
+
+    if "capabilities" in settings:
+        _run(
+            ["setcap", settings["capabilities"], str(filename)],
+            part_name=part_name,
+            path=path,
+        )
+
+    if "acls" in settings:
+        _run(
+            ["setfacl", "--modify", ",".join(settings["acls"]), str(filename)],
+            part_name=part_name,
+            path=path,
+        )
+
+
+def _break_hard_link(filename: Path) -> None:
+    """Replace a file with a copy of itself."""
+    copy = filename.with_name(f".{filename.name}.partial")
+    shutil.copy2(filename, copy)
+    os.replace(copy, filename)
+
+
+def _run(command: List[str], *, part_name: str, path: str) -> None:
+    """Execute a command to set permissions of a file."""
+    try:
+        subprocess.run(command, check=True, capture_output=True, text=True)
+    except FileNotFoundError as err:
+        raise errors.FilePermissionsError(
+            part_name=part_name, path=path, message=f"{command[0]!r} is not installed"
+        ) from err
+    except subprocess.CalledProcessError as err:
+        raise errors.FilePermissionsError(
+            part_name=part_name, path=path, message=err.stderr.strip()
+        ) from err
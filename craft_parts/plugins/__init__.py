@@ -16,12 +16,19 @@
 
 """Craft Parts plugins subsystem."""
 
+from .loader import (  # noqa: F401
+    load_plugins_from_dir,
+    load_plugins_from_entry_points,
+)
 from .plugins import (  # noqa: F401
     Plugin,
     PluginProperties,
     get_plugin,
     get_plugin_class,
+    get_registered_plugins,
     register,
     strip_plugin_properties,
+    unregister,
     unregister_all,
 )
+from .validator import PluginEnvironmentValidator  # noqa: F401
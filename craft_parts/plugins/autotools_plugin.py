@@ -16,16 +16,23 @@
 
 """The autotools plugin implementation."""
 
-from typing import Any, Dict, List, Set, cast
+from typing import Any, Dict, List, Optional, Set, cast
+
+from craft_parts import compiler_cache
 
 from .base import Plugin, PluginModel, extract_plugin_properties
 from .properties import PluginProperties
 
 
-class AutotoolsPluginProperties(PluginProperties, PluginModel):
+class AutotoolsPluginProperties(PluginModel, PluginProperties):
     """The part properties used by the autotools plugin."""
 
     autotools_configure_parameters: List[str] = []
+    autotools_bootstrap: bool = True
+    autotools_autoreconf_parameters: List[str] = ["--install"]
+    autotools_build_dir: Optional[str]
+    autotools_configure_environment: List[Dict[str, str]] = []
+    autotools_make_environment: List[Dict[str, str]] = []
 
     @classmethod
     def unmarshal(cls, data: Dict[str, Any]):
@@ -61,6 +68,32 @@ class AutotoolsPlugin(Plugin):
           (list of strings)
           configure flags to pass to the build such as those shown by running
           './configure --help'
+
+        - autotools-bootstrap
+          (boolean)
+          Run the project's 'autogen.sh' or 'bootstrap' script to generate
+          the 'configure' file, if present. If false, or if no script is
+          found, 'autoreconf' is used. Defaults to true.
+
+        - autotools-autoreconf-parameters
+          (list of strings)
+          Flags to pass to 'autoreconf'. Defaults to '--install'.
+
+        - autotools-build-dir
+          (string)
+          Configure and build the project in this directory, relative to the
+          part build directory, instead of the source tree.
+
+        - autotools-configure-environment
+          (list of dicts)
+          Environment variables to set when running 'configure'.
+
+        - autotools-make-environment
+          (list of dicts)
+          Environment variables to set when running 'make'.
+
+    If the compiler cache is enabled in the project, ``CC`` and ``CXX`` are
+    set to compilers wrapped by ccache.
     """
 
     properties_class = AutotoolsPluginProperties
@@ -71,26 +104,80 @@ class AutotoolsPlugin(Plugin):
 
     def get_build_packages(self) -> Set[str]:
         """Return a set of required packages to install in the build environment."""
-        return {"autoconf", "automake", "autopoint", "gcc", "libtool"}
+        packages = {"autoconf", "automake", "autopoint", "gcc", "libtool"}
+        if compiler_cache.is_enabled(self._part_info):
+            packages.add("ccache")
+        return packages
 
     def get_build_environment(self) -> Dict[str, str]:
         """Return a dictionary with the environment to use in the build step."""
-        return dict()
+        return compiler_cache.get_compiler_environment(self._part_info)
 
     def _get_configure_command(self) -> str:
         options = cast(AutotoolsPluginProperties, self._options)
-        cmd = ["./configure"] + options.autotools_configure_parameters
+
+        configure = "./configure"
+        if options.autotools_build_dir:
+            configure = f'"{self._part_info.part_build_subdir}/configure"'
+
+        cmd = _get_environment_assignments(options.autotools_configure_environment)
+        cmd.append(configure)
+        cmd.extend(options.autotools_configure_parameters)
+        return " ".join(cmd)
+
+    def _get_make_command(self, *args: str) -> str:
+        options = cast(AutotoolsPluginProperties, self._options)
+        cmd = _get_environment_assignments(options.autotools_make_environment)
+        cmd.append("make")
+        cmd.extend(args)
         return " ".join(cmd)
 
-    # pylint: disable=line-too-long
+    def _get_bootstrap_commands(self) -> List[str]:
+        options = cast(AutotoolsPluginProperties, self._options)
+
+        commands: List[str] = []
+        if options.autotools_bootstrap:
+            commands.extend(
+                [
+                    "[ ! -f ./configure ] && [ -f ./autogen.sh ] && "
+                    "env NOCONFIGURE=1 ./autogen.sh",
+                    "[ ! -f ./configure ] && [ -f ./bootstrap ] && "
+                    "env NOCONFIGURE=1 ./bootstrap",
+                ]
+            )
+
+        autoreconf = " ".join(["autoreconf"] + options.autotools_autoreconf_parameters)
+        commands.append(f"[ ! -f ./configure ] && {autoreconf}")
+
+        return commands
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "cc": '"${CC:-cc}" --version',
+            "make": "make --version",
+        }
 
     def get_build_commands(self) -> List[str]:
         """Return a list of commands to run during the build step."""
+        options = cast(AutotoolsPluginProperties, self._options)
+
+        build_dir_commands: List[str] = []
+        if options.autotools_build_dir:
+            build_dir = options.autotools_build_dir
+            build_dir_commands = [f'mkdir -p "{build_dir}"', f'cd "{build_dir}"']
+
         return [
-            "[ ! -f ./configure ] && [ -f ./autogen.sh ] && env NOCONFIGURE=1 ./autogen.sh",
-            "[ ! -f ./configure ] && [ -f ./bootstrap ] && env NOCONFIGURE=1 ./bootstrap",
-            "[ ! -f ./configure ] && autoreconf --install",
+            *self._get_bootstrap_commands(),
+            *build_dir_commands,
             self._get_configure_command(),
-            "make -j{}".format(self._part_info.parallel_build_count),
-            'make install DESTDIR="{}"'.format(self._part_info.part_install_dir),
+            self._get_make_command(f"-j{self._part_info.parallel_build_count}"),
+            self._get_make_command(
+                "install", f'DESTDIR="{self._part_info.part_install_dir}"'
+            ),
         ]
+
+
+def _get_environment_assignments(environment: List[Dict[str, str]]) -> List[str]:
+    """Obtain the variable assignments to prefix a command with."""
+    return [f'{key}="{val}"' for env in environment for key, val in env.items()]
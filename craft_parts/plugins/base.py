@@ -17,11 +17,12 @@
 """Plugin base class and definitions."""
 
 import abc
-from typing import TYPE_CHECKING, Any, Dict, List, Set, Type
+from typing import TYPE_CHECKING, Any, Dict, List, Optional, Set, Type
 
 from pydantic import BaseModel
 
 from .properties import PluginProperties
+from .validator import PluginEnvironmentValidator
 
 if TYPE_CHECKING:
     from craft_parts.infos import PartInfo
@@ -31,18 +32,65 @@ class Plugin(abc.ABC):
     """The base class for plugins.
 
     :cvar properties_class: The plugin properties class.
+    :cvar validator_class: The class used to validate the build environment.
 
     :param part_info: The part information for the applicable part.
     :param properties: Part-defined properties.
     """
 
     properties_class: Type[PluginProperties]
+    validator_class: Type[PluginEnvironmentValidator] = PluginEnvironmentValidator
 
     def __init__(self, *, properties: PluginProperties, part_info: "PartInfo") -> None:
         self._name = part_info.part_name
         self._options = properties
         self._part_info = part_info
 
+    def get_pull_commands(self) -> List[str]:
+        """Return a list of commands to run during the pull step."""
+        return []
+
+    def get_pull_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the pull step."""
+        return {}
+
+    def get_pull_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the pull state.
+
+        This method is called after the pull step is executed, and can be
+        used to fingerprint content obtained by the plugin pull commands.
+        """
+        return {}
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state.
+
+        This method is called after the build step is executed, and can be
+        used to fingerprint tools or content used by the plugin build commands.
+        """
+        return {}
+
+    def get_secrets(self) -> Dict[str, str]:
+        """Return a dictionary of the secret values used by the plugin commands.
+
+        Secrets are referenced in the plugin environment as
+        ``$(HOST_SECRET:<name>)``, like in the build environment of parts.
+        Their values are only set in the environment of step processes, so
+        they're never written to step scripts, and are redacted from the
+        step output.
+        """
+        return {}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools.
+
+        The dictionary maps tool names to commands that run in the build
+        environment. The first line of each command output is recorded in the
+        build environment snapshot of the build state, and tools that are not
+        available are not recorded.
+        """
+        return {}
+
     @abc.abstractmethod
     def get_build_snaps(self) -> Set[str]:
         """Return a set of required snaps to install in the build environment."""
@@ -70,7 +118,9 @@ class PluginModel(BaseModel):
 
     Plugins with configuration properties can use pydantic validation to unmarshal
     data from part specs. In this case, extract plugin-specific properties using
-    the :func:`extract_plugin_properties` helper.
+    the :func:`extract_plugin_properties` helper. Plugin property classes
+    should list this model before :class:`PluginProperties` in their base
+    classes so that model marshaling takes precedence.
     """
 
     class Config:
@@ -81,6 +131,24 @@ class PluginModel(BaseModel):
         allow_mutation = False
         alias_generator = lambda s: s.replace("_", "-")  # noqa: E731
 
+    def marshal(self) -> Dict[str, Any]:
+        """Obtain a dictionary containing the plugin properties.
+
+        :return: The dictionary containing plugin properties.
+        """
+        return self.dict(by_alias=True)
+
+    @classmethod
+    def get_build_properties(cls) -> List[str]:
+        """Obtain the list of properties affecting the build stage.
+
+        All plugin properties are relevant to the build step unless a plugin
+        states otherwise.
+
+        :return: The names of plugin properties relevant to the build step.
+        """
+        return [field.alias for field in cls.__fields__.values()]
+
 
 def extract_plugin_properties(
     data: Dict[str, Any], *, plugin_name: str
@@ -100,3 +168,84 @@ def extract_plugin_properties(
             plugin_data[key] = value
 
     return plugin_data
+
+
+def get_download_command(
+    url: str,
+    destination: str,
+    *,
+    sha256: Optional[str] = None,
+    checksums_url: Optional[str] = None,
+    sha256_url: Optional[str] = None,
+    index_url: Optional[str] = None,
+) -> str:
+    """Obtain a shell command to download and verify a file.
+
+    The file is downloaded to a temporary file next to the destination and
+    only moved to the destination if its sha256 digest matches, so that
+    concurrent downloads to the same destination don't interfere. Nothing
+    is downloaded if the destination already exists.
+
+    The expected digest is either given, or read from a file listing it:
+
+    - a checksums file in the ``sha256sum`` format, where the digest is taken
+      from the line whose file name field, without a leading ``*`` and
+      directories, is the downloaded file name;
+    - a file containing only the digest of the downloaded file;
+    - a JSON index, where the digest is the first one following the first
+      string naming the downloaded file, either as a name or as the last
+      component of a path or URL.
+
+    The command fails if the digest can't be found.
+
+    :param url: The URL of the file to download.
+    :param destination: The path of the downloaded file.
+    :param sha256: The expected sha256 digest of the file.
+    :param checksums_url: The URL of the checksums file listing the digest.
+    :param sha256_url: The URL of the file containing only the digest.
+    :param index_url: The URL of the JSON index listing the digest.
+
+    :return: The download command.
+
+    :raise ValueError: If no digest or digest source is set.
+    """
+    name = url.rsplit("/", 1)[-1]
+    if sha256:
+        get_digest = f'digest="{sha256}"'
+    elif checksums_url:
+        get_digest = (
+            f'digest="$(curl --fail --location --silent --show-error '
+            f'"{checksums_url}" | '
+            f"awk -v name={name!r} '{{ file = $2; sub(/^\\*/, \"\", file); "
+            f'count = split(file, path, "/") }} '
+            f"NF >= 2 && path[count] == name {{ print $1; exit }}')\""
+        )
+    elif sha256_url:
+        get_digest = (
+            f'digest="$(curl --fail --location --silent --show-error '
+            f"\"{sha256_url}\" | awk 'NR == 1 {{ print $1 }}')\""
+        )
+    elif index_url:
+        get_digest = (
+            f'digest="$(curl --fail --location --silent --show-error '
+            f'"{index_url}" | '
+            f"awk -F '\"' -v name={name!r} '!found {{ for (i = 2; i <= NF; i += 2) "
+            f'if ($i == name || substr($i, length($i) - length(name)) == "/" name) '
+            f"found = 1 }} found' | "
+            f"grep -o -E '[0-9a-f]{{64}}' | head -n 1)\""
+        )
+    else:
+        raise ValueError("either sha256 or a digest source URL must be set")
+
+    get_digest += ' && [ -n "$digest" ]'
+
+    return (
+        f'if [ ! -f "{destination}" ]; then '
+        f'partial="$(mktemp "{destination}.XXXXXX")" && '
+        f"{{ curl --fail --location --silent --show-error "
+        f'--output "$partial" "{url}" && {get_digest} && '
+        f'echo "$digest  $partial" | sha256sum --check --status && '
+        f'mv "$partial" "{destination}"; }} || '
+        f'{{ rm -f "$partial"; echo "cannot download and verify {url}" >&2; '
+        f"exit 1; }}; fi"
+    )
@@ -0,0 +1,127 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The bazel plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class BazelPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the bazel plugin."""
+
+    bazel_targets: List[str] = ["//..."]
+    bazel_configs: List[str] = []
+    bazel_options: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate bazel properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="bazel")
+        return cls(**plugin_data)
+
+
+class BazelPlugin(Plugin):
+    """A plugin for bazel workspaces.
+
+    The bazel plugin builds the selected targets and copies their output
+    files to the part install directory, keeping their paths relative to
+    ``bazel-bin``. Bazel output, cache and sandbox directories are created
+    in the part build directory.
+
+    Bazel must be available in the build environment, e.g. by adding the
+    ``bazelisk`` snap to ``build-snaps``.
+
+    The bazel plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - bazel-targets
+          (list of strings)
+          The targets to build. Defaults to ``//...``.
+
+        - bazel-configs
+          (list of strings)
+          Configurations defined in ``.bazelrc`` to build with.
+
+        - bazel-options
+          (list of strings)
+          Additional options to pass to the bazel build command.
+    """
+
+    properties_class = BazelPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"g++", "gcc"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(BazelPluginProperties, self._options)
+        bazel_dir = self._get_bazel_dir()
+
+        startup = f'bazel --output_user_root="{bazel_dir}/root"'
+        build_options = [
+            *[f"--config={config}" for config in options.bazel_configs],
+            f'--disk_cache="{bazel_dir}/cache"',
+            f'--sandbox_base="{bazel_dir}/sandbox"',
+        ]
+        targets = " ".join(options.bazel_targets)
+
+        build_cmd = [
+            f"{startup} build",
+            *build_options,
+            f"--jobs={self._part_info.parallel_build_count}",
+            *options.bazel_options,
+            targets,
+        ]
+        query_cmd = [f"{startup} cquery", *build_options, "--output=files", targets]
+
+        install_dir = self._part_info.part_install_dir
+        return [
+            f'mkdir -p "{bazel_dir}/sandbox"',
+            # Don't let the workspace target patterns match bazel files.
+            f'grep -qsx "{bazel_dir.name}" .bazelignore || '
+            f'echo "{bazel_dir.name}" >> .bazelignore',
+            " ".join(build_cmd),
+            f"{' '.join(query_cmd)} | while read -r output; do "
+            f'dest="{install_dir}/${{output#bazel-out/*/bin/}}"; '
+            'mkdir -p "$(dirname "$dest")" && '
+            'cp --archive --dereference "$output" "$dest"; done',
+        ]
+
+    def _get_bazel_dir(self) -> Path:
+        return self._part_info.part_build_subdir / ".bazel"
@@ -0,0 +1,196 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The bun plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from xdg import BaseDirectory  # type: ignore
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .properties import PluginProperties
+
+# Map deb architectures to bun architecture names.
+_BUN_ARCH: Dict[str, str] = {
+    "amd64": "x64",
+    "arm64": "aarch64",
+}
+
+# Map deb architectures to bun compilation targets.
+_BUN_TARGET: Dict[str, str] = {
+    "amd64": "bun-linux-x64",
+    "arm64": "bun-linux-arm64",
+}
+
+_BUN_DOWNLOAD_URL = "https://github.com/oven-sh/bun/releases/download"
+
+
+class BunPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the bun plugin."""
+
+    bun_version: Optional[str]
+    bun_version_sha256: Optional[str]
+    bun_entrypoint: str = "index.ts"
+    bun_output: Optional[str]
+    bun_parameters: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate bun properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="bun")
+        return cls(**plugin_data)
+
+
+class BunPlugin(Plugin):
+    """A plugin for javascript and typescript projects built with bun.
+
+    The bun plugin installs the dependencies locked in the project lockfile,
+    failing if the lockfile is out of date, and compiles the entry point to
+    a standalone executable in the ``bin`` directory of the part install
+    directory.
+
+    If ``bun-version`` is set, bun is downloaded to a cache shared by all
+    projects. Otherwise bun must be available in the build environment.
+
+    The bun plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - bun-version
+          (string)
+          The bun version to download, e.g. ``1.1.30``.
+
+        - bun-version-sha256
+          (string)
+          The expected sha256 digest of the bun release archive. Default is
+          to verify the archive using the digests published with the release.
+
+        - bun-entrypoint
+          (string)
+          The module to compile. Defaults to ``index.ts``.
+
+        - bun-output
+          (string)
+          The name of the compiled executable. Defaults to the part name.
+
+        - bun-parameters
+          (list of strings)
+          Additional parameters to pass to ``bun build``.
+    """
+
+    properties_class = BunPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(BunPluginProperties, self._options)
+        if options.bun_version:
+            return {"curl", "unzip"}
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        bun_dir = self._get_bun_dir()
+        if not bun_dir:
+            return {}
+
+        return {"PATH": f"{bun_dir}:${{PATH}}"}
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        options = cast(BunPluginProperties, self._options)
+        if not options.bun_version:
+            return {}
+
+        return {"bun-version": options.bun_version}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(BunPluginProperties, self._options)
+        output = options.bun_output or self._part_info.part_name
+        bin_dir = self._part_info.part_install_dir / "bin"
+
+        build_cmd = ["bun build --compile", f'--outfile "{bin_dir}/{output}"']
+        if self._part_info.is_cross_compiling:
+            target_arch = self._part_info.target_arch
+            build_cmd.append(f"--target={_BUN_TARGET.get(target_arch, target_arch)}")
+        build_cmd.extend(options.bun_parameters)
+        build_cmd.append(f'"{options.bun_entrypoint}"')
+
+        return [
+            *self._get_bun_commands(),
+            "bun install --frozen-lockfile",
+            f'mkdir -p "{bin_dir}"',
+            " ".join(build_cmd),
+        ]
+
+    def _get_bun_commands(self) -> List[str]:
+        """Obtain the commands to download and unpack bun."""
+        bun_dir = self._get_bun_dir()
+        if not bun_dir:
+            return []
+
+        options = cast(BunPluginProperties, self._options)
+        host_arch = self._part_info.host_arch
+        archive = f"{bun_dir}.zip"
+        release_url = f"{_BUN_DOWNLOAD_URL}/bun-v{options.bun_version}"
+        url = f"{release_url}/bun-linux-{_BUN_ARCH.get(host_arch, host_arch)}.zip"
+
+        return [
+            get_download_command(
+                url,
+                archive,
+                sha256=options.bun_version_sha256,
+                checksums_url=f"{release_url}/SHASUMS256.txt",
+            ),
+            f'if [ ! -x "{bun_dir}/bun" ]; then '
+            f'mkdir -p "{bun_dir}" && '
+            f'unzip -q -o -j "{archive}" -d "{bun_dir}"; fi',
+        ]
+
+    def _get_bun_dir(self) -> Optional[Path]:
+        """Obtain the cached location of bun, if provisioned."""
+        options = cast(BunPluginProperties, self._options)
+        if not options.bun_version:
+            return None
+
+        host_arch = self._part_info.host_arch
+        bun_arch = _BUN_ARCH.get(host_arch, host_arch)
+        cache_dir = Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "bun"
+            )
+        )
+
+        return cache_dir / f"bun-v{options.bun_version}-linux-{bun_arch}"
@@ -0,0 +1,158 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The cmake plugin implementation."""
+
+from typing import Any, Dict, List, Optional, Set, cast
+
+from craft_parts import compiler_cache
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class CMakePluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the cmake plugin."""
+
+    cmake_parameters: List[str] = []
+    cmake_generator: str = "Unix Makefiles"
+    cmake_preset: Optional[str]
+    cmake_build_preset: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate cmake properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="cmake")
+        return cls(**plugin_data)
+
+
+class CMakePlugin(Plugin):
+    """The cmake plugin is useful for building cmake based parts.
+
+    These are projects that have a CMakeLists.txt that drives the build.
+    The plugin configures the project out of the source tree, then builds
+    and installs it.
+
+    If a configure preset is selected, the project is configured, built and
+    installed using the presets defined in ``CMakePresets.json``, and the
+    preset binary directory is created in the part build directory.
+
+    This plugin uses the common plugin keywords as well as those for "sources".
+    For more information check the 'plugins' topic for the former and the
+    'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - cmake-parameters
+          (list of strings)
+          Parameters to pass to the cmake configure command, e.g.
+          ``-DCMAKE_BUILD_TYPE=Release``.
+
+        - cmake-generator
+          (string)
+          The build system generator, ``Unix Makefiles`` or ``Ninja``. Not
+          used if a preset is selected. Defaults to ``Unix Makefiles``.
+
+        - cmake-preset
+          (string)
+          The configure preset to use.
+
+        - cmake-build-preset
+          (string)
+          The build preset to use. Defaults to the build preset with the
+          same name as the configure preset.
+
+    If the compiler cache is enabled in the project, C and C++ compilations
+    are cached using ccache.
+    """
+
+    properties_class = CMakePluginProperties
+
+    @property
+    def out_of_source_build(self) -> bool:
+        """Return whether the plugin performs out-of-source-tree builds."""
+        # Preset binary directories are relative to the source directory.
+        options = cast(CMakePluginProperties, self._options)
+        return options.cmake_preset is None
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(CMakePluginProperties, self._options)
+        packages = {"cmake", "gcc"}
+        if options.cmake_generator == "Ninja":
+            packages.add("ninja-build")
+        else:
+            packages.add("make")
+        if compiler_cache.is_enabled(self._part_info):
+            packages.add("ccache")
+        return packages
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        env = compiler_cache.get_ccache_environment(self._part_info)
+        if env:
+            env["CMAKE_C_COMPILER_LAUNCHER"] = "ccache"
+            env["CMAKE_CXX_COMPILER_LAUNCHER"] = "ccache"
+        return env
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "cmake": "cmake --version",
+            "cc": '"${CC:-cc}" --version',
+            "c++": '"${CXX:-c++}" --version',
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(CMakePluginProperties, self._options)
+        install_dir = self._part_info.part_install_dir
+        jobs = self._part_info.parallel_build_count
+
+        if options.cmake_preset:
+            build_preset = options.cmake_build_preset or options.cmake_preset
+            return [
+                " ".join(
+                    [f'cmake --preset "{options.cmake_preset}"']
+                    + options.cmake_parameters
+                ),
+                f'cmake --build --preset "{build_preset}" -j "{jobs}"',
+                f'DESTDIR="{install_dir}" '
+                f'cmake --build --preset "{build_preset}" --target install',
+            ]
+
+        return [
+            " ".join(
+                [
+                    f'cmake "{self._part_info.part_src_subdir}"',
+                    f'-G "{options.cmake_generator}"',
+                ]
+                + options.cmake_parameters
+            ),
+            f'cmake --build . -j "{jobs}"',
+            f'DESTDIR="{install_dir}" cmake --build . --target install',
+        ]
@@ -0,0 +1,119 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The composer plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class ComposerPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the composer plugin."""
+
+    composer_php_version: Optional[str]
+    composer_auth_env: Optional[str]
+    composer_app_dir: str = "app"
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate composer properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="composer")
+        return cls(**plugin_data)
+
+
+class ComposerPlugin(Plugin):
+    """A plugin for PHP projects managed with composer.
+
+    The composer plugin installs the dependencies locked in composer.lock,
+    excluding development dependencies, and copies the application with its
+    vendor tree to the part install directory.
+
+    The composer plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - composer-php-version
+          (string)
+          The PHP version to install from the archive, e.g. ``8.3``. Default
+          is to use the default PHP version of the build base.
+
+        - composer-auth-env
+          (string)
+          The name of an environment variable containing the contents of a
+          composer ``auth.json`` file, with the credentials to access private
+          repositories. The file is created outside the part source and is
+          not installed.
+
+        - composer-app-dir
+          (string)
+          The directory to install the application to, relative to the part
+          install directory. Defaults to ``app``.
+    """
+
+    properties_class = ComposerPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(ComposerPluginProperties, self._options)
+        php = f"php{options.composer_php_version or ''}"
+        return {"composer", "git", "unzip", f"{php}-cli"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {"COMPOSER_HOME": str(self._get_composer_home())}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(ComposerPluginProperties, self._options)
+        app_dir = self._part_info.part_install_dir / options.composer_app_dir
+
+        auth_commands: List[str] = []
+        if options.composer_auth_env:
+            auth_file = self._get_composer_home() / "auth.json"
+            auth_commands = [
+                f'mkdir -p "{auth_file.parent}"',
+                f'(umask 077 && printf "%s" "${{{options.composer_auth_env}}}" '
+                f'> "{auth_file}")',
+            ]
+
+        return [
+            *auth_commands,
+            "composer install --no-dev --optimize-autoloader "
+            "--no-interaction --no-progress",
+            f'mkdir -p "{app_dir}"',
+            f'cp --archive . "{app_dir}"',
+        ]
+
+    def _get_composer_home(self) -> Path:
+        # Keep composer files in the part directory, so credentials are not
+        # copied to the build directory.
+        return self._part_info.part_build_dir.parent / "composer"
@@ -0,0 +1,150 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The conda plugin implementation."""
+
+from pathlib import Path, PurePosixPath
+from typing import Any, Dict, List, Optional, Set, cast
+
+from pydantic import Field
+from xdg import BaseDirectory  # type: ignore
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class CondaPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the conda plugin."""
+
+    conda_executable: str = Field("conda", regex=r"^(conda|mamba|micromamba)$")
+    conda_environment_file: str = "environment.yml"
+    conda_lockfile: Optional[str]
+    conda_prefix: str = ""
+    conda_target_prefix: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate conda properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="conda")
+        return cls(**plugin_data)
+
+
+class CondaPlugin(Plugin):
+    """A plugin to create conda environments.
+
+    The conda plugin creates a conda environment in the part install
+    directory, from an environment file or from an explicit lockfile such
+    as those generated by conda-lock. Downloaded packages are kept in a
+    cache shared by all projects.
+
+    Conda environments are not relocatable: text files in the environment
+    refer to the location it was created at. After the environment is
+    created, these references are rewritten to the location the environment
+    will have at runtime. Binary files are not modified.
+
+    The conda executable must be available in the build environment.
+
+    The conda plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - conda-executable
+          (string)
+          The tool used to create the environment: ``conda``, ``mamba`` or
+          ``micromamba``. Defaults to ``conda``.
+
+        - conda-environment-file
+          (string)
+          The environment file describing the environment. Defaults to
+          ``environment.yml``.
+
+        - conda-lockfile
+          (string)
+          An explicit lockfile to create the environment from. If set, the
+          environment file is not used.
+
+        - conda-prefix
+          (string)
+          The location of the environment, relative to the part install
+          directory. Defaults to the part install directory.
+
+        - conda-target-prefix
+          (string)
+          The absolute location of the environment at runtime. Defaults to
+          ``conda-prefix`` relative to the root directory.
+    """
+
+    properties_class = CondaPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        cache_dir = Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "conda"
+            )
+        )
+
+        return {
+            "CONDA_PKGS_DIRS": str(cache_dir / "pkgs"),
+            "MAMBA_ROOT_PREFIX": str(cache_dir),
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(CondaPluginProperties, self._options)
+        prefix = self._part_info.part_install_dir / options.conda_prefix
+
+        if options.conda_target_prefix:
+            target_prefix = options.conda_target_prefix.rstrip("/")
+        else:
+            target_prefix = str(PurePosixPath("/", options.conda_prefix)).rstrip("/")
+
+        if options.conda_executable == "micromamba":
+            spec = options.conda_lockfile or options.conda_environment_file
+            create_cmd = f'micromamba create --yes --prefix "{prefix}" --file "{spec}"'
+        elif options.conda_lockfile:
+            create_cmd = (
+                f"{options.conda_executable} create --yes "
+                f'--prefix "{prefix}" --file "{options.conda_lockfile}"'
+            )
+        else:
+            create_cmd = (
+                f"{options.conda_executable} env create "
+                f'--prefix "{prefix}" --file "{options.conda_environment_file}"'
+            )
+
+        return [
+            create_cmd,
+            f'grep -rlIF --null "{prefix}" "{prefix}" | '
+            f'xargs -0 -r sed -i "s|{prefix}|{target_prefix}|g"',
+        ]
@@ -0,0 +1,222 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""The crystal plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from xdg import BaseDirectory  # type: ignore
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .properties import PluginProperties
+
+# Distributions providing crystal and shards packages.
+_PACKAGED_DISTRIBUTIONS = {"alpine", "arch"}
+
+# The compiler version provisioned if it's not packaged for the base.
+_DEFAULT_CRYSTAL_VERSION = "1.14.0"
+
+# Map deb architectures to crystal release architecture names.
+_CRYSTAL_ARCH: Dict[str, str] = {
+    "amd64": "x86_64",
+    "arm64": "aarch64",
+}
+
+_CRYSTAL_DOWNLOAD_URL = "https://github.com/crystal-lang/crystal/releases/download"
+
+# The release metadata lists the digests of the release assets.
+_CRYSTAL_RELEASES_URL = "https://api.github.com/repos/crystal-lang/crystal/releases"
+
+# Libraries linked by crystal programs using the standard library.
+_LINK_DEPENDENCIES = {
+    "gcc",
+    "git",
+    "libevent-dev",
+    "libpcre2-dev",
+    "libssl-dev",
+    "libyaml-dev",
+    "pkg-config",
+    "zlib1g-dev",
+}
+
+
+class CrystalPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the crystal plugin."""
+
+    crystal_version: Optional[str]
+    crystal_version_sha256: Optional[str]
+    crystal_build_options: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate crystal properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="crystal")
+        return cls(**plugin_data)
+
+
+class CrystalPlugin(Plugin):
+    """A plugin for crystal projects built with shards.
+
+    The crystal plugin installs the project dependencies with
+    ``shards install``, builds the targets declared in ``shard.yml`` with
+    ``shards build --release`` and installs the built binaries in the part
+    install directory.
+
+    The crystal compiler is installed from the distribution packages on
+    bases that provide it. Otherwise, or if ``crystal-version`` is set, the
+    compiler release is downloaded to a cache shared by all projects.
+
+    The crystal plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - crystal-version
+          (string)
+          The crystal compiler release to download, e.g. ``1.14.0``.
+
+        - crystal-version-sha256
+          (string)
+          The expected sha256 digest of the crystal compiler archive. Default
+          is to verify the archive using the digest listed in the release
+          metadata.
+
+        - crystal-build-options
+          (list of strings)
+          Additional options to pass to ``shards build``, such as the
+          names of the targets to build.
+    """
+
+    properties_class = CrystalPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        if self._get_toolchain_dir():
+            return {*_LINK_DEPENDENCIES, "curl"}
+        return {*_LINK_DEPENDENCIES, "crystal", "shards"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        env = {"SHARDS_CACHE_PATH": str(self._get_cache_dir() / "shards")}
+
+        toolchain_dir = self._get_toolchain_dir()
+        if toolchain_dir:
+            env["PATH"] = f"{toolchain_dir}/bin:${{PATH}}"
+
+        return env
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        version = self._get_crystal_version()
+        if not version:
+            return {}
+
+        return {"crystal-version": version}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "crystal": "crystal --version",
+            "shards": "shards --version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(CrystalPluginProperties, self._options)
+        bin_dir = self._part_info.part_install_dir / "bin"
+
+        build_cmd = ["shards build --without-development --release"]
+        build_cmd.extend(options.crystal_build_options)
+
+        return [
+            *self._get_toolchain_commands(),
+            "shards install --without-development",
+            " ".join(build_cmd),
+            f'mkdir -p "{bin_dir}"',
+            f'cp --archive bin/. "{bin_dir}"',
+        ]
+
+    def _get_crystal_version(self) -> Optional[str]:
+        """Obtain the compiler version to provision, if any."""
+        options = cast(CrystalPluginProperties, self._options)
+        if options.crystal_version:
+            return options.crystal_version
+
+        build_base = self._part_info.build_base
+        if build_base and _PACKAGED_DISTRIBUTIONS.intersection(
+            build_base.distribution_ids
+        ):
+            return None
+
+        return _DEFAULT_CRYSTAL_VERSION
+
+    def _get_toolchain_commands(self) -> List[str]:
+        """Obtain the commands to download and unpack the crystal compiler."""
+        toolchain_dir = self._get_toolchain_dir()
+        if not toolchain_dir:
+            return []
+
+        options = cast(CrystalPluginProperties, self._options)
+        version = self._get_crystal_version()
+        tarball = f"{toolchain_dir}.tar.gz"
+        url = f"{_CRYSTAL_DOWNLOAD_URL}/{version}/{toolchain_dir.name}.tar.gz"
+
+        return [
+            get_download_command(
+                url,
+                tarball,
+                sha256=options.crystal_version_sha256,
+                index_url=f"{_CRYSTAL_RELEASES_URL}/tags/{version}",
+            ),
+            f'if [ ! -x "{toolchain_dir}/bin/crystal" ]; then '
+            f'mkdir -p "{toolchain_dir}" && '
+            f'tar -xzf "{tarball}" -C "{toolchain_dir}" --strip-components=1; fi',
+        ]
+
+    def _get_toolchain_dir(self) -> Optional[Path]:
+        """Obtain the cached location of the crystal compiler, if provisioned."""
+        version = self._get_crystal_version()
+        if not version:
+            return None
+
+        host_arch = self._part_info.host_arch
+        host_arch = _CRYSTAL_ARCH.get(host_arch, host_arch)
+        return self._get_cache_dir() / f"crystal-{version}-1-linux-{host_arch}"
+
+    def _get_cache_dir(self) -> Path:
+        cache_dir = BaseDirectory.save_cache_path(
+            self._part_info.application_name, "craft-parts", "crystal"
+        )
+        return Path(cache_dir)
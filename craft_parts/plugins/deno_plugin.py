@@ -0,0 +1,157 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The deno plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class DenoPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the deno plugin."""
+
+    deno_entrypoint: str = "main.ts"
+    deno_output: Optional[str]
+    deno_parameters: List[str] = []
+    deno_task: Optional[str]
+    deno_lockfile: str = "deno.lock"
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate deno properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="deno")
+        return cls(**plugin_data)
+
+    @classmethod
+    def get_pull_properties(cls) -> List[str]:
+        """Obtain the list of properties affecting the pull stage.
+
+        :return: The names of plugin properties relevant to the pull step.
+        """
+        return ["deno-entrypoint", "deno-task", "deno-lockfile"]
+
+
+class DenoPlugin(Plugin):
+    """A plugin for deno projects.
+
+    The deno plugin downloads the project dependencies during the pull step,
+    verifying them against the project lockfile, and builds the project
+    offline using only the cached dependencies. By default the entry point
+    is compiled to a standalone executable in the ``bin`` directory of the
+    part install directory. If ``deno-task`` is set, that task is run
+    instead and is expected to install its output in ``$CRAFT_PART_INSTALL``.
+
+    The deno plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - deno-entrypoint
+          (string)
+          The module to compile. Defaults to ``main.ts``.
+
+        - deno-output
+          (string)
+          The name of the compiled executable. Defaults to the part name.
+
+        - deno-parameters
+          (list of strings)
+          Additional parameters to pass to ``deno compile``, such as
+          permission flags.
+
+        - deno-task
+          (string)
+          The name of a task defined in ``deno.json`` to run instead of
+          compiling the entry point, e.g. ``build``.
+
+        - deno-lockfile
+          (string)
+          The lockfile the dependencies are checked against. Defaults to
+          ``deno.lock``.
+    """
+
+    properties_class = DenoPluginProperties
+
+    def get_pull_commands(self) -> List[str]:
+        """Return a list of commands to run during the pull step."""
+        options = cast(DenoPluginProperties, self._options)
+        lock = f'--lock="{options.deno_lockfile}" --frozen'
+
+        if options.deno_task:
+            return [f"deno install {lock}"]
+
+        return [f'deno cache {lock} "{options.deno_entrypoint}"']
+
+    def get_pull_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the pull step."""
+        return self._get_deno_environment()
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return {"deno"}
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return self._get_deno_environment()
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(DenoPluginProperties, self._options)
+
+        if options.deno_task:
+            return [f'deno task "{options.deno_task}"']
+
+        output = options.deno_output or self._part_info.part_name
+        bin_dir = self._part_info.part_install_dir / "bin"
+
+        compile_cmd = [
+            "deno compile",
+            f'--lock="{options.deno_lockfile}" --frozen --cached-only',
+            f'--output "{bin_dir}/{output}"',
+            *options.deno_parameters,
+            f'"{options.deno_entrypoint}"',
+        ]
+
+        return [
+            f'mkdir -p "{bin_dir}"',
+            " ".join(compile_cmd),
+        ]
+
+    def _get_deno_environment(self) -> Dict[str, str]:
+        return {
+            "DENO_DIR": str(self._get_deno_dir()),
+            "DENO_NO_UPDATE_CHECK": "1",
+        }
+
+    def _get_deno_dir(self) -> Path:
+        # Keep the deno cache in the part directory, so dependencies obtained
+        # in the pull step are available to the build step.
+        return self._part_info.part_src_dir.parent / "deno"
@@ -0,0 +1,174 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The dotnet plugin implementation."""
+
+from typing import Any, Dict, List, Optional, Set, cast
+
+import pydantic
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+# Map deb architectures to .NET runtime identifiers.
+_DOTNET_RID: Dict[str, str] = {
+    "amd64": "linux-x64",
+    "arm64": "linux-arm64",
+    "armhf": "linux-arm",
+    "ppc64el": "linux-ppc64le",
+    "s390x": "linux-s390x",
+}
+
+
+class DotnetPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the dotnet plugin."""
+
+    dotnet_project: Optional[str]
+    dotnet_configuration: str = "Release"
+    dotnet_runtime_identifier: Optional[str]
+    dotnet_self_contained: bool = False
+    dotnet_trim: bool = False
+    dotnet_ready_to_run: bool = False
+
+    # pylint: disable=no-self-argument
+    @pydantic.validator("dotnet_trim")
+    def validate_dotnet_trim(cls, value, values):
+        """Make sure trimming is only requested for self-contained builds."""
+        if value and not values.get("dotnet_self_contained"):
+            raise ValueError("trimming requires a self-contained build")
+        return value
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate dotnet properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="dotnet")
+        return cls(**plugin_data)
+
+
+class DotnetPlugin(Plugin):
+    """A plugin for .NET projects.
+
+    The dotnet plugin publishes the project to the part install directory
+    using ``dotnet publish``. The .NET SDK must be available in the build
+    environment, e.g. by adding the ``dotnet-sdk`` snap to ``build-snaps``.
+
+    The dotnet plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - dotnet-project
+          (string)
+          The project or solution file to publish. Default is to use the
+          project file in the source root.
+
+        - dotnet-configuration
+          (string)
+          The build configuration. Defaults to ``Release``.
+
+        - dotnet-runtime-identifier
+          (string)
+          The runtime identifier to publish for, e.g. ``linux-x64``. Defaults
+          to the identifier of the target architecture if the build is
+          self-contained or ReadyToRun.
+
+        - dotnet-self-contained
+          (boolean)
+          Include the .NET runtime in the published application, so it
+          doesn't need to be staged separately. Defaults to false.
+
+        - dotnet-trim
+          (boolean)
+          Remove unused code from the self-contained application. Defaults to
+          false.
+
+        - dotnet-ready-to-run
+          (boolean)
+          Compile the application assemblies ahead of time to reduce startup
+          time. Defaults to false.
+    """
+
+    properties_class = DotnetPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {
+            "DOTNET_CLI_TELEMETRY_OPTOUT": "1",
+            "DOTNET_NOLOGO": "1",
+        }
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "dotnet": "dotnet --version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(DotnetPluginProperties, self._options)
+        runtime_identifier = self._get_runtime_identifier()
+
+        cmd = ["dotnet publish"]
+        if options.dotnet_project:
+            cmd.append(f'"{options.dotnet_project}"')
+        cmd.extend(
+            [
+                f'--configuration "{options.dotnet_configuration}"',
+                f'--output "{self._part_info.part_install_dir}"',
+            ]
+        )
+        if runtime_identifier:
+            cmd.append(f'--runtime "{runtime_identifier}"')
+            cmd.append(
+                f"--self-contained {str(options.dotnet_self_contained).lower()}"
+            )
+        if options.dotnet_trim:
+            cmd.append("-p:PublishTrimmed=true")
+        if options.dotnet_ready_to_run:
+            cmd.append("-p:PublishReadyToRun=true")
+
+        return [" ".join(cmd)]
+
+    def _get_runtime_identifier(self) -> Optional[str]:
+        """Obtain the runtime identifier to publish for, if any."""
+        options = cast(DotnetPluginProperties, self._options)
+        if options.dotnet_runtime_identifier:
+            return options.dotnet_runtime_identifier
+
+        # Self-contained and ReadyToRun builds are specific to a runtime.
+        if options.dotnet_self_contained or options.dotnet_ready_to_run:
+            target_arch = self._part_info.target_arch
+            return _DOTNET_RID.get(target_arch, f"linux-{target_arch}")
+
+        return None
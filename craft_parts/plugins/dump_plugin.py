@@ -19,15 +19,21 @@
 This plugin just dumps the content from a specified part source.
 """
 
-from typing import Any, Dict, List, Set
+from typing import Any, Dict, List, Set, cast
 
-from .base import Plugin
+from pydantic import Field
+
+from .base import Plugin, PluginModel, extract_plugin_properties
 from .properties import PluginProperties
 
 
-class DumpPluginProperties(PluginProperties):
+class DumpPluginProperties(PluginModel, PluginProperties):
     """The part properties used by the dump plugin."""
 
+    dump_include: List[str] = []
+    dump_exclude: List[str] = []
+    dump_strip_components: int = Field(0, ge=0)
+
     @classmethod
     def unmarshal(cls, data: Dict[str, Any]):
         """Populate dump properties from the part specification.
@@ -39,14 +45,36 @@ class DumpPluginProperties(PluginProperties):
         :return: The populated plugin properties data object.
 
         :raise ValueError: If a required property is not found.
+        :raise pydantic.ValidationError: If validation fails.
         """
         if "source" not in data:
             raise ValueError("'source' is required by the dump plugin")
-        return cls()
+        plugin_data = extract_plugin_properties(data, plugin_name="dump")
+        return cls(**plugin_data)
 
 
 class DumpPlugin(Plugin):
-    """Copy the content from the part source."""
+    """Copy the content from the part source.
+
+    By default the whole source tree is copied to the part install
+    directory. A subset of the source can be selected using the following
+    plugin-specific keywords:
+
+        - dump-include
+          (list of strings)
+          Paths to copy, relative to the source directory. Shell wildcards
+          are allowed. Defaults to the whole source tree.
+
+        - dump-exclude
+          (list of strings)
+          Patterns of file or directory names to skip, e.g. ``*.o``.
+
+        - dump-strip-components
+          (integer)
+          The number of leading path components to remove from the copied
+          paths, as in ``tar --strip-components``. Files not deeper than
+          this are skipped.
+    """
 
     properties_class = DumpPluginProperties
 
@@ -64,5 +92,32 @@ class DumpPlugin(Plugin):
 
     def get_build_commands(self) -> List[str]:
         """Return a list of commands to run during the build step."""
+        options = cast(DumpPluginProperties, self._options)
         install_dir = self._part_info.part_install_dir
-        return [f'cp --archive --link --no-dereference . "{install_dir}"']
+
+        filtered = options.dump_include or options.dump_exclude
+        if not filtered and not options.dump_strip_components:
+            return [f'cp --archive --link --no-dereference . "{install_dir}"']
+
+        create_cmd = ["tar --create --file=-"]
+        for pattern in options.dump_exclude:
+            create_cmd.append(f'--exclude="{pattern}"')
+        if options.dump_include:
+            # Wildcards must be left unquoted for the shell to expand them.
+            create_cmd.extend(["--", *options.dump_include])
+            strip_components = options.dump_strip_components
+        else:
+            # Archive members are prefixed with "./".
+            create_cmd.append(".")
+            strip_components = options.dump_strip_components + 1
+
+        extract_cmd = [
+            "tar --extract --file=- --preserve-permissions",
+            f'--directory="{install_dir}"',
+            f"--strip-components={strip_components}",
+        ]
+
+        return [
+            f'mkdir -p "{install_dir}"',
+            f'{" ".join(create_cmd)} | {" ".join(extract_cmd)}',
+        ]
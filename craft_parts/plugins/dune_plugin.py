@@ -0,0 +1,140 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The dune plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from xdg import BaseDirectory  # type: ignore
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class DunePluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the dune plugin."""
+
+    dune_ocaml_version: Optional[str]
+    dune_packages: List[str] = []
+    dune_profile: str = "release"
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate dune properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="dune")
+        return cls(**plugin_data)
+
+
+class DunePlugin(Plugin):
+    """A plugin for OCaml projects built with dune.
+
+    The dune plugin installs the project dependencies with opam, builds the
+    ``@install`` alias and installs the project in the part install
+    directory. The opam root is kept in a cache shared by all projects, so
+    compilers and dependencies are not rebuilt every time.
+
+    If ``dune-ocaml-version`` is set, an opam switch with that compiler
+    version is created and used to build the project. Otherwise the default
+    switch is used.
+
+    The dune plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - dune-ocaml-version
+          (string)
+          The OCaml compiler version of the opam switch, e.g. ``5.1.1``.
+
+        - dune-packages
+          (list of strings)
+          The packages to build and install. Default is to build all
+          packages in the project.
+
+        - dune-profile
+          (string)
+          The build profile. Defaults to ``release``.
+    """
+
+    properties_class = DunePluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"bubblewrap", "gcc", "git", "make", "opam", "unzip"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        options = cast(DunePluginProperties, self._options)
+
+        env = {"OPAMROOT": str(self._get_opam_root()), "OPAMYES": "1"}
+        if options.dune_ocaml_version:
+            env["OPAMSWITCH"] = self._get_switch_name()
+
+        return env
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(DunePluginProperties, self._options)
+
+        switch_commands: List[str] = []
+        if options.dune_ocaml_version:
+            switch = self._get_switch_name()
+            switch_commands = [
+                f'opam switch list --short | grep -qx "{switch}" || '
+                f'opam switch create "{switch}" "{options.dune_ocaml_version}"',
+            ]
+
+        packages = ",".join(options.dune_packages)
+        only_packages = f' --only-packages "{packages}"' if packages else ""
+
+        install_cmd = [
+            "opam exec -- dune install",
+            f'--prefix "{self._part_info.part_install_dir}"',
+            "--relocatable",
+            *[f'"{package}"' for package in options.dune_packages],
+        ]
+
+        return [
+            '[ -f "${OPAMROOT}/config" ] || opam init --bare --disable-sandboxing',
+            *switch_commands,
+            "opam install --deps-only .",
+            f'opam exec -- dune build @install --profile "{options.dune_profile}"'
+            f'{only_packages} -j "{self._part_info.parallel_build_count}"',
+            " ".join(install_cmd),
+        ]
+
+    def _get_opam_root(self) -> Path:
+        cache_dir = BaseDirectory.save_cache_path(
+            self._part_info.application_name, "craft-parts", "opam"
+        )
+        return Path(cache_dir)
+
+    def _get_switch_name(self) -> str:
+        options = cast(DunePluginProperties, self._options)
+        return f"craft-parts-{options.dune_ocaml_version}"
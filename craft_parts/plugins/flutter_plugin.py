@@ -0,0 +1,215 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The flutter plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from pydantic import Field
+from xdg import BaseDirectory  # type: ignore
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .properties import PluginProperties
+
+# Map deb architectures to flutter linux architecture names.
+_FLUTTER_ARCH: Dict[str, str] = {
+    "amd64": "x64",
+    "arm64": "arm64",
+}
+
+_FLUTTER_RELEASES_URL = "https://storage.googleapis.com/flutter_infra_release/releases"
+_FLUTTER_DOWNLOAD_URL = f"{_FLUTTER_RELEASES_URL}/stable/linux"
+
+
+class FlutterPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the flutter plugin."""
+
+    flutter_version: Optional[str]
+    flutter_version_sha256: Optional[str]
+    flutter_target: str = Field("linux", regex=r"^(linux|web)$")
+    flutter_build_mode: str = Field("release", regex=r"^(debug|profile|release)$")
+    flutter_parameters: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate flutter properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="flutter")
+        return cls(**plugin_data)
+
+
+class FlutterPlugin(Plugin):
+    """A plugin for flutter applications.
+
+    The flutter plugin fetches the dependencies locked in pubspec.lock,
+    builds the application for the selected target and installs the
+    resulting bundle in the part install directory. The pub package cache
+    is shared by all projects.
+
+    If ``flutter-version`` is set, the flutter SDK is downloaded from the
+    stable channel to a cache shared by all projects. Otherwise flutter
+    must be available in the build environment.
+
+    The flutter plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - flutter-version
+          (string)
+          The flutter SDK version to download, e.g. ``3.24.3``.
+
+        - flutter-version-sha256
+          (string)
+          The expected sha256 digest of the flutter SDK archive. Default is
+          to verify the archive using the digest listed in the flutter
+          releases index.
+
+        - flutter-target
+          (string)
+          The target to build: ``linux`` or ``web``. Defaults to ``linux``.
+
+        - flutter-build-mode
+          (string)
+          The build mode: ``debug``, ``profile`` or ``release``. Defaults to
+          ``release``.
+
+        - flutter-parameters
+          (list of strings)
+          Additional parameters to pass to ``flutter build``.
+    """
+
+    properties_class = FlutterPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(FlutterPluginProperties, self._options)
+
+        build_packages = {"git", "unzip"}
+        if options.flutter_version:
+            build_packages |= {"curl", "xz-utils"}
+        if options.flutter_target == "linux":
+            build_packages |= {
+                "clang",
+                "cmake",
+                "libgtk-3-dev",
+                "liblzma-dev",
+                "ninja-build",
+                "pkg-config",
+            }
+
+        return build_packages
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        env = {"PUB_CACHE": str(self._get_cache_dir() / "pub-cache")}
+
+        sdk_dir = self._get_sdk_dir()
+        if sdk_dir:
+            env["PATH"] = f"{sdk_dir}/bin:${{PATH}}"
+
+        return env
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        options = cast(FlutterPluginProperties, self._options)
+        if not options.flutter_version:
+            return {}
+
+        return {"flutter-version": options.flutter_version}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(FlutterPluginProperties, self._options)
+        mode = options.flutter_build_mode
+        install_dir = self._part_info.part_install_dir
+
+        build_cmd = [f"flutter build {options.flutter_target}", f"--{mode}"]
+        if options.flutter_target == "linux":
+            target_arch = self._part_info.target_arch
+            flutter_arch = _FLUTTER_ARCH.get(target_arch, target_arch)
+            if self._part_info.is_cross_compiling:
+                build_cmd.append(f"--target-platform=linux-{flutter_arch}")
+            bundle_dir = f"build/linux/{flutter_arch}/{mode}/bundle"
+        else:
+            bundle_dir = "build/web"
+        build_cmd.extend(options.flutter_parameters)
+
+        return [
+            *self._get_sdk_commands(),
+            "flutter config --no-analytics",
+            "flutter pub get --enforce-lockfile",
+            " ".join(build_cmd),
+            f'mkdir -p "{install_dir}"',
+            f'cp --archive "{bundle_dir}/." "{install_dir}"',
+        ]
+
+    def _get_sdk_commands(self) -> List[str]:
+        """Obtain the commands to download and unpack the flutter SDK."""
+        sdk_dir = self._get_sdk_dir()
+        if not sdk_dir:
+            return []
+
+        options = cast(FlutterPluginProperties, self._options)
+        tarball = f"{sdk_dir}.tar.xz"
+        url = (
+            f"{_FLUTTER_DOWNLOAD_URL}/flutter_linux_{options.flutter_version}"
+            "-stable.tar.xz"
+        )
+
+        return [
+            get_download_command(
+                url,
+                tarball,
+                sha256=options.flutter_version_sha256,
+                index_url=f"{_FLUTTER_RELEASES_URL}/releases_linux.json",
+            ),
+            f'if [ ! -x "{sdk_dir}/bin/flutter" ]; then '
+            f'mkdir -p "{sdk_dir}" && '
+            f'tar -xJf "{tarball}" -C "{sdk_dir}" --strip-components=1; fi',
+        ]
+
+    def _get_sdk_dir(self) -> Optional[Path]:
+        """Obtain the cached location of the flutter SDK, if provisioned."""
+        options = cast(FlutterPluginProperties, self._options)
+        if not options.flutter_version:
+            return None
+
+        return self._get_cache_dir() / f"flutter-{options.flutter_version}"
+
+    def _get_cache_dir(self) -> Path:
+        return Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "flutter"
+            )
+        )
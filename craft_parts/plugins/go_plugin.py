@@ -0,0 +1,664 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The go plugin implementation."""
+
+import os
+import re
+import string
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, Tuple, Union, cast
+
+import pydantic
+from xdg import BaseDirectory  # type: ignore
+
+from craft_parts import compiler_cache, errors, step_cache
+from craft_parts.utils import url_utils
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .go_use_plugin import get_go_use_dir
+from .properties import PluginProperties
+
+# Map Debian architecture names to the corresponding Go architecture names.
+_GO_ARCH: Dict[str, str] = {
+    "amd64": "amd64",
+    "arm64": "arm64",
+    "armhf": "arm",
+    "i386": "386",
+    "powerpc": "ppc",
+    "ppc64el": "ppc64le",
+    "riscv64": "riscv64",
+    "s390x": "s390x",
+}
+
+# Go toolchain downloads use different names for some architectures.
+_GO_DOWNLOAD_ARCH: Dict[str, str] = {
+    "arm": "armv6l",
+}
+
+_GO_DOWNLOAD_URL = "https://go.dev/dl"
+
+
+class GoPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the go plugin."""
+
+    go_buildtags: List[str] = []
+    go_ldflags: List[str] = []
+    go_packages: List[Union[str, Dict[str, str]]] = []
+    go_generate: List[str] = []
+    go_generate_tags: Optional[List[str]]
+    go_generate_environment: List[Dict[str, str]] = []
+    go_os: Optional[str]
+    go_arch: Optional[str]
+    go_cgo_enabled: Optional[bool]
+    go_cgo_cc: Optional[str]
+    go_vendor: bool = False
+    go_version: Optional[str]
+    go_version_sha256: Optional[str]
+    go_flags: List[str] = []
+    go_proxy: Optional[str]
+    go_private: List[str] = []
+    go_nosumdb: List[str] = []
+    go_netrc: Optional[str]
+    go_test: bool = False
+    go_test_packages: List[str] = ["./..."]
+    go_test_args: List[str] = []
+
+    # pylint: disable=no-self-argument
+    @pydantic.validator("go_packages", each_item=True)
+    def validate_go_packages(cls, item):
+        """Make sure packages with an output name have a single entry."""
+        if isinstance(item, dict) and len(item) != 1:
+            raise ValueError("package entries must map a package to an output name")
+        return item
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate go properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="go")
+        return cls(**plugin_data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Obtain a dictionary containing the plugin properties.
+
+        Credentials in the module proxy URL are redacted, so they are not
+        saved in the step state.
+
+        :return: The dictionary containing plugin properties.
+        """
+        data = super().marshal()
+        if self.go_proxy:
+            data["go-proxy"] = url_utils.redact_credentials(self.go_proxy)
+        return data
+
+    @classmethod
+    def get_pull_properties(cls) -> List[str]:
+        """Obtain the list of properties affecting the pull stage.
+
+        :return: The names of plugin properties relevant to the pull step.
+        """
+        return [
+            "go-vendor",
+            "go-flags",
+            "go-proxy",
+            "go-private",
+            "go-nosumdb",
+            "go-netrc",
+        ]
+
+
+class GoPlugin(Plugin):
+    """A plugin for go projects using go.mod.
+
+    The go plugin requires a go compiler installed on your system. This can
+    be achieved by adding the appropriate golang package to ``build-packages``,
+    or to have it installed or built in a different part.
+
+    The go plugin uses the common plugin keywords as well as those for "sources".
+    For more information check the 'plugins' topic for the former and the
+    'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - go-buildtags
+          (list of strings)
+          Tags to use during the go build. Default is not to use any build tags.
+          Project variables such as ``$CRAFT_PROJECT_VERSION`` are expanded.
+
+        - go-ldflags
+          (list of strings)
+          Flags to pass to the linker, e.g. ``-X main.version=$CRAFT_PROJECT_VERSION``.
+          Project variables are expanded. Default is not to pass any linker flags.
+
+        - go-packages
+          (list of strings or dicts)
+          Packages to build. An entry can map a package to the name of the
+          binary to install (e.g. ``./cmd/server: myserver``). Default is to
+          build all packages in the module.
+
+        - go-generate
+          (list of strings)
+          Parameters to pass to `go generate` before building. Each item on the
+          list will be a separate `go generate` call, and can specify packages
+          or patterns to run against (e.g. ``-run stringer ./internal/...``).
+          Default is not to call `go generate`.
+
+        - go-generate-tags
+          (list of strings)
+          Tags to use when running `go generate`. Defaults to the tags listed
+          in ``go-buildtags``.
+
+        - go-generate-environment
+          (list of dicts)
+          Additional environment variables to set only when running
+          `go generate`, for example to locate code generators.
+
+        - go-os
+          (string)
+          The target operating system (GOOS). Defaults to "linux" if a target
+          architecture is set.
+
+        - go-arch
+          (string)
+          The target architecture (GOARCH). Defaults to the Go equivalent of
+          the project target architecture when cross-compiling.
+
+        - go-cgo-enabled
+          (boolean)
+          Whether cgo should be enabled (CGO_ENABLED). Defaults to the go
+          toolchain default.
+
+        - go-cgo-cc
+          (string)
+          The C compiler to use with cgo (CC). Defaults to the cross-compiler
+          for the project target architecture when cross-compiling with cgo
+          enabled.
+
+        - go-vendor
+          (boolean)
+          Vendor module dependencies in the pull step and build using only
+          the vendored modules, so no network access is needed during the
+          build. An existing vendor directory is validated instead of being
+          regenerated. Defaults to false.
+
+        - go-version
+          (string)
+          The version of the Go toolchain to download and use in the build,
+          e.g. ``1.22.3``. Downloaded toolchains are kept in a cache shared
+          by all projects. If set to ``auto``, the version is taken from the
+          ``toolchain`` directive in go.mod, or from the ``go`` directive if
+          no toolchain is specified. The go command is not allowed to switch
+          to a different toolchain. Default is to use the go command
+          available in the build environment.
+
+        - go-version-sha256
+          (string)
+          The expected sha256 digest of the Go toolchain archive. Default is
+          to verify the archive using the digest published by go.dev.
+
+        - go-flags
+          (list of strings)
+          Additional flags to pass to go commands (GOFLAGS).
+
+        - go-proxy
+          (string)
+          The module proxy URLs to use (GOPROXY). Credentials in the URLs
+          are not saved in the part state or step scripts, and are redacted
+          from the step output.
+
+        - go-private
+          (list of strings)
+          Module path patterns of private modules that must be fetched
+          directly and not verified against the checksum database (GOPRIVATE).
+
+        - go-nosumdb
+          (list of strings)
+          Module path patterns that should not be verified against the
+          checksum database (GONOSUMDB).
+
+        - go-netrc
+          (string)
+          The path to a netrc file containing credentials for private module
+          servers (NETRC). Relative paths are relative to the project work
+          directory.
+
+        - go-test
+          (boolean)
+          Run `go test` after building, failing the build step if tests
+          fail. Tests are not run when cross-compiling. Defaults to false.
+
+        - go-test-packages
+          (list of strings)
+          Packages or patterns to test. Defaults to all packages in the
+          module.
+
+        - go-test-args
+          (list of strings)
+          Additional arguments to pass to `go test`, e.g. ``-race``.
+
+    Go modules provided by parts using the go-use plugin are added to a go
+    workspace before building, if the part runs after them.
+
+    If the compiler cache is enabled in the project and cgo is enabled, C
+    compilations are cached using ccache.
+    """
+
+    properties_class = GoPluginProperties
+
+    def get_pull_commands(self) -> List[str]:
+        """Return a list of commands to run during the pull step."""
+        options = cast(GoPluginProperties, self._options)
+        if not options.go_vendor:
+            return []
+
+        return [
+            "if [ -d vendor ]; then "
+            "go list -mod=vendor ./... > /dev/null; "
+            "else go mod vendor; fi"
+        ]
+
+    def get_pull_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the pull step."""
+        options = cast(GoPluginProperties, self._options)
+        env = self._get_module_environment()
+        if options.go_flags:
+            env["GOFLAGS"] = " ".join(options.go_flags)
+
+        return env
+
+    def get_pull_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the pull state."""
+        options = cast(GoPluginProperties, self._options)
+        if not options.go_vendor:
+            return {}
+
+        # The whole tree is recorded, vendored sources can be changed without
+        # changing the module list.
+        vendor_dir = self._part_info.part_src_subdir / "vendor"
+        if not vendor_dir.is_dir():
+            return {}
+
+        return {"go-vendor": step_cache.get_directory_digest(vendor_dir)}
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        version = self._get_toolchain_version()
+        if not version:
+            return {}
+
+        return {"go-toolchain": version}
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        packages = {"gcc"}
+
+        options = cast(GoPluginProperties, self._options)
+        if options.go_version:
+            packages.add("curl")
+
+        prefix = self._part_info.cross_compiler_prefix
+        if (
+            options.go_cgo_enabled
+            and not options.go_cgo_cc
+            and self._part_info.is_cross_compiling
+            and prefix
+        ):
+            packages.add(f"gcc-{prefix.rstrip('-')}")
+
+        if options.go_cgo_enabled and compiler_cache.is_enabled(self._part_info):
+            packages.add("ccache")
+
+        return packages
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        options = cast(GoPluginProperties, self._options)
+        env: Dict[str, str] = {}
+
+        goarch = self._get_goarch()
+        goos = options.go_os
+        if goarch and not goos:
+            goos = "linux"
+
+        if goos:
+            env["GOOS"] = goos
+        if goarch:
+            env["GOARCH"] = goarch
+            if not options.go_arch and self._part_info.target_arch == "armhf":
+                env["GOARM"] = "7"
+
+        if options.go_cgo_enabled is not None:
+            env["CGO_ENABLED"] = "1" if options.go_cgo_enabled else "0"
+
+        if options.go_cgo_cc:
+            env["CC"] = options.go_cgo_cc
+        elif options.go_cgo_enabled and self._part_info.is_cross_compiling:
+            prefix = self._part_info.cross_compiler_prefix
+            if prefix:
+                env["CC"] = f"{prefix}gcc"
+
+        if options.go_cgo_enabled and compiler_cache.is_enabled(self._part_info):
+            env.update(compiler_cache.get_ccache_environment(self._part_info))
+            cc = env.get("CC", "gcc")
+            env["CC"] = f"ccache {cc}"
+
+        goflags = list(options.go_flags)
+        if options.go_vendor:
+            goflags.append("-mod=vendor")
+        if goflags:
+            env["GOFLAGS"] = " ".join(goflags)
+
+        env.update(self._get_module_environment())
+
+        toolchain_dir = self._get_toolchain_dir()
+        if toolchain_dir:
+            env["PATH"] = f"{toolchain_dir}/bin:$PATH"
+            env["GOTOOLCHAIN"] = "local"
+
+        # GOBIN cannot be used when cross-compiling, binaries are written
+        # to the install directory by the build command instead.
+        if not self._is_cross_building():
+            env["GOBIN"] = f"{self._part_info.part_install_dir}/bin"
+
+        return env
+
+    def _get_module_environment(self) -> Dict[str, str]:
+        """Obtain the environment used to fetch and authenticate modules."""
+        options = cast(GoPluginProperties, self._options)
+        env: Dict[str, str] = {}
+
+        if options.go_proxy and self.get_secrets():
+            env["GOPROXY"] = "$(HOST_SECRET:go-proxy)"
+        elif options.go_proxy:
+            env["GOPROXY"] = options.go_proxy
+        if options.go_private:
+            env["GOPRIVATE"] = ",".join(options.go_private)
+        if options.go_nosumdb:
+            env["GONOSUMDB"] = ",".join(options.go_nosumdb)
+        if options.go_netrc:
+            netrc = Path(options.go_netrc).expanduser()
+            env["NETRC"] = str(self._part_info.dirs.work_dir / netrc)
+
+        return env
+
+    def get_secrets(self) -> Dict[str, str]:
+        """Return a dictionary of the secret values used by the plugin commands."""
+        options = cast(GoPluginProperties, self._options)
+
+        # Proxy URLs containing credentials are not written to step scripts.
+        if options.go_proxy and url_utils.has_credentials(options.go_proxy):
+            return {"go-proxy": options.go_proxy}
+
+        return {}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "go": "go version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(GoPluginProperties, self._options)
+
+        tags = ""
+        if options.go_buildtags:
+            tags = f"-tags={','.join(self._expand(options.go_buildtags))}"
+
+        ldflags = ""
+        if options.go_ldflags:
+            ldflags = f'-ldflags="{" ".join(self._expand(options.go_ldflags))}"'
+
+        generate_cmds: List[str] = []
+        for args in options.go_generate:
+            generate_cmds.append(self._get_generate_command(args))
+
+        bin_dir = f"{self._part_info.part_install_dir}/bin"
+        build_cmds: List[str] = []
+        if self._is_cross_building() or self._has_output_names():
+            build_cmds.append(f'mkdir -p "{bin_dir}"')
+
+        for package, output in self._get_packages():
+            if output:
+                build_cmds.append(
+                    self._get_go_command(
+                        "build", package, tags, ldflags, f'-o "{bin_dir}/{output}"'
+                    )
+                )
+            elif self._is_cross_building():
+                build_cmds.append(
+                    self._get_go_command(
+                        "build", package, tags, ldflags, f'-o "{bin_dir}/"'
+                    )
+                )
+            else:
+                build_cmds.append(
+                    self._get_go_command("install", package, tags, ldflags)
+                )
+
+        if options.go_test and not self._is_cross_building():
+            build_cmds.append(
+                self._get_go_command(
+                    "test",
+                    " ".join(options.go_test_packages),
+                    tags,
+                    *options.go_test_args,
+                )
+            )
+
+        toolchain_cmds = self._get_toolchain_commands()
+        workspace_cmds = self._get_workspace_commands()
+
+        # Vendored modules were obtained in the pull step, and modules used
+        # in a workspace are resolved by the build.
+        if options.go_vendor or workspace_cmds:
+            return [*toolchain_cmds, *workspace_cmds, *generate_cmds, *build_cmds]
+
+        return [
+            *toolchain_cmds,
+            "go mod download all",
+            *generate_cmds,
+            *build_cmds,
+        ]
+
+    def _get_toolchain_commands(self) -> List[str]:
+        """Obtain the commands to download and unpack the Go toolchain."""
+        toolchain_dir = self._get_toolchain_dir()
+        if not toolchain_dir:
+            return []
+
+        options = cast(GoPluginProperties, self._options)
+        tarball = f"{toolchain_dir}.tar.gz"
+        url = f"{_GO_DOWNLOAD_URL}/{toolchain_dir.name}.tar.gz"
+
+        return [
+            get_download_command(
+                url,
+                tarball,
+                sha256=options.go_version_sha256,
+                sha256_url=f"{url}.sha256",
+            ),
+            f'if [ ! -x "{toolchain_dir}/bin/go" ]; then '
+            f'mkdir -p "{toolchain_dir}" && '
+            f'tar -xzf "{tarball}" -C "{toolchain_dir}" --strip-components=1; fi',
+        ]
+
+    def _get_toolchain_dir(self) -> Optional[Path]:
+        """Obtain the cached location of the Go toolchain, if provisioned."""
+        version = self._get_toolchain_version()
+        if not version:
+            return None
+
+        host_arch = _GO_ARCH.get(self._part_info.host_arch, self._part_info.host_arch)
+        host_arch = _GO_DOWNLOAD_ARCH.get(host_arch, host_arch)
+        cache_dir = Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "go"
+            )
+        )
+
+        return cache_dir / f"go{version}.linux-{host_arch}"
+
+    def _get_toolchain_version(self) -> Optional[str]:
+        """Obtain the Go toolchain version to provision, if any.
+
+        :raise PluginEnvironmentValidationError: If the version can't be
+            determined from go.mod.
+        """
+        options = cast(GoPluginProperties, self._options)
+        if not options.go_version:
+            return None
+
+        if options.go_version != "auto":
+            return _strip_go_prefix(options.go_version)
+
+        go_mod = self._part_info.part_build_subdir / "go.mod"
+        content = go_mod.read_text() if go_mod.is_file() else ""
+
+        match = re.search(r"^toolchain\s+go(\S+)", content, re.MULTILINE)
+        if match:
+            return match.group(1)
+
+        match = re.search(r"^go\s+(\d+)\.(\d+)(\S*)", content, re.MULTILINE)
+        if match:
+            major, minor, rest = match.groups()
+            # Starting with Go 1.21, the first release of a version is x.y.0.
+            if not rest and (int(major), int(minor)) >= (1, 21):
+                rest = ".0"
+            return f"{major}.{minor}{rest}"
+
+        raise errors.PluginEnvironmentValidationError(
+            part_name=self._part_info.part_name,
+            reason="cannot determine the go toolchain version from go.mod",
+        )
+
+    def _get_workspace_commands(self) -> List[str]:
+        """Obtain the commands to add go-use modules to a workspace.
+
+        Only the modules of go-use parts listed in ``after`` are used.
+        """
+        modules: List[str] = []
+        for part_name in sorted(self._part_info.part_dependencies):
+            go_use_dir = get_go_use_dir(self._part_info.dirs.parts_dir, part_name)
+            modules_file = go_use_dir / "modules"
+            if not modules_file.is_file():
+                continue
+
+            for module in modules_file.read_text().splitlines():
+                if module:
+                    modules.append(os.path.normpath(go_use_dir / "src" / module))
+
+        if not modules:
+            return []
+
+        return [
+            "[ -f go.work ] || go work init",
+            "go work use .",
+            *[f'go work use "{module}"' for module in modules],
+        ]
+
+    def _get_generate_command(self, args: str) -> str:
+        options = cast(GoPluginProperties, self._options)
+
+        cmd: List[str] = []
+        for env in options.go_generate_environment:
+            for key, val in env.items():
+                cmd.append(f'{key}="{val}"')
+
+        cmd.append("go generate")
+
+        tags = options.go_generate_tags
+        if tags is None:
+            tags = options.go_buildtags
+        if tags:
+            cmd.append(f"-tags={','.join(self._expand(tags))}")
+
+        cmd.append(args)
+
+        return " ".join(cmd)
+
+    def _get_go_command(self, action: str, package: str, *args: str) -> str:
+        options = cast(GoPluginProperties, self._options)
+
+        cmd = ["go", action, f'-p "{self._part_info.parallel_build_count}"']
+        if options.go_vendor:
+            cmd.append("-mod=vendor")
+        cmd.extend(arg for arg in args if arg)
+        cmd.append(package)
+
+        return " ".join(cmd)
+
+    def _expand(self, values: List[str]) -> List[str]:
+        """Expand project variables in the given values."""
+        project_env = self._part_info.project_environment
+        return [string.Template(value).safe_substitute(project_env) for value in values]
+
+    def _get_packages(self) -> List[Tuple[str, Optional[str]]]:
+        """Obtain the packages to build and their output names, if any."""
+        options = cast(GoPluginProperties, self._options)
+        if not options.go_packages:
+            return [("./...", None)]
+
+        packages: List[Tuple[str, Optional[str]]] = []
+        for entry in options.go_packages:
+            if isinstance(entry, dict):
+                packages.extend(entry.items())
+            else:
+                packages.append((entry, None))
+
+        return packages
+
+    def _has_output_names(self) -> bool:
+        return any(output for _, output in self._get_packages())
+
+    def _get_goarch(self) -> Optional[str]:
+        """Obtain the target Go architecture, if different from the host."""
+        options = cast(GoPluginProperties, self._options)
+        if options.go_arch:
+            return options.go_arch
+
+        if self._part_info.is_cross_compiling:
+            return _GO_ARCH.get(self._part_info.target_arch)
+
+        return None
+
+    def _is_cross_building(self) -> bool:
+        options = cast(GoPluginProperties, self._options)
+        return bool(options.go_os or self._get_goarch())
+
+
+def _strip_go_prefix(version: str) -> str:
+    if version.startswith("go"):
+        return version[len("go") :]
+    return version
@@ -0,0 +1,165 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The go-use plugin implementation.
+
+This plugin makes go modules available to other parts using the go plugin,
+which will add them to a go workspace. Modules are kept in the part
+directory instead of being installed, so they're not staged or primed.
+"""
+
+import fnmatch
+import os
+from pathlib import Path
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+# Directories that are never scanned for nested modules.
+_IGNORED_DIRS = ("vendor", "testdata")
+
+
+class GoUsePluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the go-use plugin."""
+
+    go_use_include: List[str] = []
+    go_use_exclude: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate go-use properties from the part specification.
+
+        'source' is a required part property.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise ValueError: If a required property is not found.
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        if "source" not in data:
+            raise ValueError("'source' is required by the go-use plugin")
+
+        plugin_data = extract_plugin_properties(data, plugin_name="go-use")
+        return cls(**plugin_data)
+
+
+class GoUsePlugin(Plugin):
+    """A plugin to share go modules with parts using the go plugin.
+
+    The part source is scanned for go modules, and the modules found are
+    added to the go workspace created by parts using the go plugin that
+    are declared to run after this part. Nested modules in monorepos are
+    discovered automatically. The modules are not staged or primed.
+
+    This plugin uses the following plugin-specific keywords:
+
+        - go-use-include
+          (list of strings)
+          Patterns matching the paths of modules to use, relative to the
+          part source. The module at the root of the source is ".". Default
+          is to use all modules found.
+
+        - go-use-exclude
+          (list of strings)
+          Patterns matching the paths of modules that should not be used,
+          relative to the part source. Default is not to exclude any module.
+    """
+
+    properties_class = GoUsePluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        go_use_dir = get_go_use_dir(
+            self._part_info.dirs.parts_dir, self._part_info.part_name
+        )
+        src_dir = go_use_dir / "src"
+
+        modules = " ".join(f'"{module}"' for module in self._get_modules())
+
+        return [
+            f'rm -rf "{go_use_dir}"',
+            f'mkdir -p "{src_dir}"',
+            f'cp --archive --link --no-dereference . "{src_dir}"',
+            f'printf "%s\\n" {modules} > "{go_use_dir}/modules"',
+        ]
+
+    def _get_modules(self) -> List[str]:
+        """Obtain the paths of go modules to use, relative to the source."""
+        options = cast(GoUsePluginProperties, self._options)
+        modules: List[str] = []
+
+        for module in _find_modules(self._part_info.part_build_subdir):
+            if options.go_use_include and not _matches(
+                module, options.go_use_include
+            ):
+                continue
+            if _matches(module, options.go_use_exclude):
+                continue
+            modules.append(module)
+
+        return modules
+
+
+def get_go_use_dir(parts_dir: Path, part_name: str) -> Path:
+    """Obtain the directory containing the modules used by a go-use part.
+
+    The directory contains a copy of the part source in ``src``, and the
+    paths of the modules to use, relative to the source, in ``modules``.
+
+    :param parts_dir: The directory containing the work files of each part.
+    :param part_name: The name of the go-use part.
+
+    :return: The go-use directory of the part.
+    """
+    return parts_dir / part_name / "go-use"
+
+
+def _find_modules(top_dir: Path) -> List[str]:
+    """Find the go modules under the given directory.
+
+    :param top_dir: The directory to scan.
+
+    :return: A sorted list of module paths relative to the top directory.
+    """
+    modules: List[str] = []
+
+    for root, dirs, files in os.walk(top_dir):
+        dirs[:] = [
+            d for d in dirs if not d.startswith(".") and d not in _IGNORED_DIRS
+        ]
+        if "go.mod" in files:
+            modules.append(Path(root).relative_to(top_dir).as_posix())
+
+    return sorted(modules)
+
+
+def _matches(module: str, patterns: List[str]) -> bool:
+    return any(fnmatch.fnmatch(module, pattern) for pattern in patterns)
@@ -0,0 +1,230 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The gradle plugin implementation."""
+
+import re
+import shlex
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from craft_parts import errors
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+_WRAPPER_PROPERTIES = Path("gradle", "wrapper", "gradle-wrapper.properties")
+
+
+class GradlePluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the gradle plugin."""
+
+    gradle_task: str = "build"
+    gradle_parameters: List[str] = []
+    gradle_use_wrapper: bool = True
+    gradle_wrapper_sha256: Optional[str]
+    gradle_init_scripts: List[str] = []
+    gradle_properties: Dict[str, str] = {}
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate gradle properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="gradle")
+        return cls(**plugin_data)
+
+
+class GradlePlugin(Plugin):
+    """A plugin for java projects built with gradle.
+
+    The gradle plugin runs the selected gradle task and copies the jar files
+    created in the ``build/libs`` directory to the ``jar`` directory in the
+    part install directory.
+
+    If the project contains a ``gradlew`` wrapper script, it is used to run
+    the build. The wrapper downloads the gradle distribution declared in
+    ``gradle/wrapper/gradle-wrapper.properties``, and the build fails if the
+    distribution checksum is not declared or doesn't match. Otherwise gradle
+    must be available in the build environment.
+
+    The gradle plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - gradle-task
+          (string)
+          The gradle task to run. Defaults to ``build``.
+
+        - gradle-parameters
+          (list of strings)
+          Additional parameters to pass to gradle.
+
+        - gradle-use-wrapper
+          (boolean)
+          Use the project's gradlew wrapper script, if present. Defaults to
+          true.
+
+        - gradle-wrapper-sha256
+          (string)
+          The expected SHA-256 checksum of the gradle distribution downloaded
+          by the wrapper, if not declared in the wrapper properties.
+
+        - gradle-init-scripts
+          (list of strings)
+          Paths to init scripts to run before the build, relative to the part
+          source. Init scripts can be used to configure repository mirrors.
+
+        - gradle-properties
+          (dictionary of strings)
+          Gradle properties overriding those defined by the project. Proxies
+          configured in the project are passed to gradle as system properties.
+    """
+
+    properties_class = GradlePluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        packages = {"default-jdk-headless"}
+
+        options = cast(GradlePluginProperties, self._options)
+        if not options.gradle_use_wrapper:
+            packages.add("gradle")
+
+        return packages
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        options = cast(GradlePluginProperties, self._options)
+        if not options.gradle_properties:
+            return {}
+
+        return {"GRADLE_USER_HOME": str(self._get_gradle_home())}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(GradlePluginProperties, self._options)
+        jar_dir = self._part_info.part_install_dir / "jar"
+
+        cmd = [
+            self._get_gradle_executable(),
+            options.gradle_task,
+            "--no-daemon",
+            *self._get_proxy_options(),
+            *[f'--init-script "{script}"' for script in options.gradle_init_scripts],
+            *options.gradle_parameters,
+        ]
+
+        return [
+            *self._get_wrapper_commands(),
+            *self._get_properties_commands(),
+            " ".join(cmd),
+            f'mkdir -p "{jar_dir}"',
+            f'find build/libs -maxdepth 1 -name "*.jar" -exec cp --archive {{}} '
+            f'"{jar_dir}" \\;',
+        ]
+
+    def _get_proxy_options(self) -> List[str]:
+        if not self._part_info.proxy:
+            return []
+        return [shlex.quote(x) for x in self._part_info.proxy.java_options]
+
+    def _get_gradle_executable(self) -> str:
+        if self._uses_wrapper():
+            return "./gradlew"
+        return "gradle"
+
+    def _get_gradle_home(self) -> Path:
+        # Keep the gradle user home in the part directory, so the properties
+        # don't affect other projects.
+        return self._part_info.part_build_dir.parent / "gradle"
+
+    def _uses_wrapper(self) -> bool:
+        options = cast(GradlePluginProperties, self._options)
+        return (
+            options.gradle_use_wrapper
+            and (self._part_info.part_build_subdir / "gradlew").is_file()
+        )
+
+    def _get_wrapper_commands(self) -> List[str]:
+        """Obtain the commands to verify the wrapper distribution checksum.
+
+        :raise PluginEnvironmentValidationError: If the distribution checksum
+            is not declared, or doesn't match the expected checksum.
+        """
+        if not self._uses_wrapper():
+            return []
+
+        options = cast(GradlePluginProperties, self._options)
+        properties_file = self._part_info.part_build_subdir / _WRAPPER_PROPERTIES
+        content = properties_file.read_text() if properties_file.is_file() else ""
+
+        match = re.search(
+            r"^distributionSha256Sum\s*=\s*(\S+)", content, re.MULTILINE
+        )
+        checksum = match.group(1) if match else None
+
+        expected = options.gradle_wrapper_sha256
+        if checksum:
+            if expected and checksum != expected:
+                raise errors.PluginEnvironmentValidationError(
+                    part_name=self._part_info.part_name,
+                    reason=(
+                        f"gradle wrapper distribution checksum {checksum!r} "
+                        f"does not match {expected!r}"
+                    ),
+                )
+            return []
+
+        if not expected:
+            raise errors.PluginEnvironmentValidationError(
+                part_name=self._part_info.part_name,
+                reason="the gradle wrapper distribution checksum is not declared",
+            )
+
+        # Let the wrapper verify the downloaded distribution.
+        return [
+            f"printf 'distributionSha256Sum=%s\\n' "
+            f'"{expected}" >> "{_WRAPPER_PROPERTIES}"'
+        ]
+
+    def _get_properties_commands(self) -> List[str]:
+        """Obtain the commands to write the gradle properties file."""
+        options = cast(GradlePluginProperties, self._options)
+        if not options.gradle_properties:
+            return []
+
+        gradle_home = self._get_gradle_home()
+        lines = " ".join(
+            shlex.quote(f"{key}={value}")
+            for key, value in options.gradle_properties.items()
+        )
+
+        return [
+            f'mkdir -p "{gradle_home}"',
+            f'printf "%s\\n" {lines} > "{gradle_home}/gradle.properties"',
+        ]
@@ -0,0 +1,131 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The haskell plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Set, cast
+
+from pydantic import Field
+from xdg import BaseDirectory  # type: ignore
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class HaskellPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the haskell plugin."""
+
+    haskell_build_tool: str = Field("cabal", regex=r"^(cabal|stack)$")
+    haskell_targets: List[str] = []
+    haskell_parameters: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate haskell properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="haskell")
+        return cls(**plugin_data)
+
+
+class HaskellPlugin(Plugin):
+    """A plugin for haskell projects built with cabal or stack.
+
+    The haskell plugin builds the project executables and copies them to
+    the ``bin`` directory in the part install directory. Package indexes,
+    built dependencies and compilers installed by stack are kept in a cache
+    shared by all projects.
+
+    The haskell plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - haskell-build-tool
+          (string)
+          The build tool to use, ``cabal`` or ``stack``. Defaults to
+          ``cabal``.
+
+        - haskell-targets
+          (list of strings)
+          The targets to build. Default is to build all executables in the
+          project.
+
+        - haskell-parameters
+          (list of strings)
+          Additional parameters to pass to the build tool.
+    """
+
+    properties_class = HaskellPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(HaskellPluginProperties, self._options)
+        if options.haskell_build_tool == "stack":
+            return {"haskell-stack", "libgmp-dev", "zlib1g-dev"}
+        return {"cabal-install", "ghc", "libgmp-dev", "zlib1g-dev"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        cache_dir = self._get_cache_dir()
+        return {
+            "CABAL_DIR": f"{cache_dir}/cabal",
+            "STACK_ROOT": f"{cache_dir}/stack",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(HaskellPluginProperties, self._options)
+        bin_dir = self._part_info.part_install_dir / "bin"
+        jobs = self._part_info.parallel_build_count
+
+        if options.haskell_build_tool == "stack":
+            cmd = [
+                "stack build",
+                f'-j "{jobs}"',
+                "--copy-bins",
+                f'--local-bin-path "{bin_dir}"',
+            ]
+            cmd.extend(options.haskell_parameters + options.haskell_targets)
+            return [" ".join(cmd)]
+
+        cmd = [
+            "cabal install",
+            f'-j"{jobs}"',
+            f'--installdir="{bin_dir}"',
+            "--install-method=copy",
+            "--overwrite-policy=always",
+        ]
+        cmd.extend(options.haskell_parameters + options.haskell_targets)
+        return ["cabal update", f'mkdir -p "{bin_dir}"', " ".join(cmd)]
+
+    def _get_cache_dir(self) -> Path:
+        return Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "haskell"
+            )
+        )
@@ -0,0 +1,154 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The jlink plugin implementation."""
+
+from pathlib import PurePosixPath
+from typing import Any, Dict, List, Optional, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class JLinkPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the jlink plugin."""
+
+    jlink_jars: List[str] = ["jar/*.jar"]
+    jlink_extra_modules: List[str] = []
+    jlink_java_version: Optional[str]
+    jlink_jpackage_main_jar: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate jlink properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="jlink")
+        return cls(**plugin_data)
+
+
+class JLinkPlugin(Plugin):
+    """A plugin to create minimal java runtimes.
+
+    The jlink plugin uses jdeps to find the java modules needed by the
+    application jars, and jlink to create a runtime image containing only
+    those modules in the ``jre`` directory of the part install directory.
+
+    The jars are looked up in the stage directory, so the plugin is used in
+    a part built after the parts producing them, such as those using the
+    maven or gradle plugins, which install jars in the ``jar`` directory::
+
+        runtime:
+          plugin: jlink
+          source: .
+          after: [app]
+
+    If ``jlink-jpackage-main-jar`` is set, jpackage is also run to create
+    an application image bundling the runtime, named after the part, in the
+    part install directory. The runtime is not installed separately.
+
+    The jlink plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - jlink-jars
+          (list of strings)
+          The application jars, relative to the stage directory. Shell
+          wildcards are allowed. Defaults to ``jar/*.jar``.
+
+        - jlink-extra-modules
+          (list of strings)
+          Modules to add to the runtime in addition to the ones found by
+          jdeps, e.g. modules loaded by reflection such as ``jdk.crypto.ec``.
+
+        - jlink-java-version
+          (string)
+          The JDK feature version to use, e.g. ``21``. Defaults to the
+          default JDK of the build base.
+
+        - jlink-jpackage-main-jar
+          (string)
+          The jar containing the application entry point, relative to the
+          stage directory.
+    """
+
+    properties_class = JLinkPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(JLinkPluginProperties, self._options)
+        if options.jlink_java_version:
+            jdk = f"openjdk-{options.jlink_java_version}-jdk-headless"
+        else:
+            jdk = "default-jdk-headless"
+
+        # jlink uses objcopy to strip debug information.
+        return {"binutils", jdk}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(JLinkPluginProperties, self._options)
+        stage_dir = self._part_info.stage_dir
+        install_dir = self._part_info.part_install_dir
+
+        # Wildcards must be left unquoted for the shell to expand them.
+        jars = " ".join(f'"{stage_dir}"/{jar}' for jar in options.jlink_jars)
+        release = options.jlink_java_version or "base"
+        modules = ",".join(["${JLINK_MODULES}", *options.jlink_extra_modules])
+
+        if options.jlink_jpackage_main_jar:
+            runtime_dir = self._part_info.part_build_dir / "jre"
+        else:
+            runtime_dir = install_dir / "jre"
+
+        build_commands = [
+            f'JLINK_MODULES="$(jdeps --print-module-deps --ignore-missing-deps '
+            f'--multi-release {release} {jars})"',
+            f'rm -rf "{runtime_dir}"',
+            f'jlink --add-modules "{modules}" --strip-debug --no-header-files '
+            f'--no-man-pages --output "{runtime_dir}"',
+        ]
+
+        if options.jlink_jpackage_main_jar:
+            main_jar = PurePosixPath(options.jlink_jpackage_main_jar)
+            app_dir = install_dir / self._part_info.part_name
+            build_commands.extend(
+                [
+                    f'rm -rf "{app_dir}"',
+                    f"jpackage --type app-image "
+                    f'--name "{self._part_info.part_name}" '
+                    f'--input "{stage_dir / main_jar.parent}" '
+                    f'--main-jar "{main_jar.name}" '
+                    f'--runtime-image "{runtime_dir}" --dest "{install_dir}"',
+                ]
+            )
+
+        return build_commands
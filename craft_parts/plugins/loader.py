@@ -0,0 +1,176 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Discovery of plugins provided by projects and installed packages."""
+
+import importlib.util
+import inspect
+import logging
+import sys
+from importlib import metadata
+from pathlib import Path
+from typing import Any, Dict, Type, Union
+
+from craft_parts import errors
+
+from .base import Plugin
+from .properties import PluginProperties
+
+logger = logging.getLogger(__name__)
+
+_PROJECT_PLUGINS_MODULE = "craft_parts_project_plugins"
+
+
+def load_plugins_from_dir(plugins_dir: Union[Path, str]) -> Dict[str, Type[Plugin]]:
+    """Load the plugins defined in the python files of a directory.
+
+    Each file must define exactly one plugin class. The plugin name is the
+    file name without extension, with underscores replaced by dashes: the
+    plugin defined in ``my_tool.py`` is named ``my-tool``. Files with names
+    starting with an underscore are ignored.
+
+    :param plugins_dir: The directory containing the plugin files.
+
+    :return: A dictionary where the keys are plugin names and values are
+        plugin classes.
+
+    :raise PluginLoadError: If a plugin file cannot be loaded or doesn't
+        define a valid plugin.
+    """
+    plugins_dir = Path(plugins_dir)
+    if not plugins_dir.is_dir():
+        return {}
+
+    plugins: Dict[str, Type[Plugin]] = {}
+
+    for plugin_file in sorted(plugins_dir.glob("*.py")):
+        if plugin_file.name.startswith("_"):
+            continue
+
+        plugin_name = plugin_file.stem.replace("_", "-")
+        module = _import_file(plugin_file, plugin_name=plugin_name)
+
+        plugin_classes = [
+            obj
+            for _, obj in inspect.getmembers(module, inspect.isclass)
+            if issubclass(obj, Plugin)
+            and obj is not Plugin
+            and obj.__module__ == module.__name__
+        ]
+        if len(plugin_classes) != 1:
+            raise errors.PluginLoadError(
+                plugin_name,
+                message=(
+                    f"{str(plugin_file)!r} must define exactly one plugin class, "
+                    f"found {len(plugin_classes)}"
+                ),
+            )
+
+        plugins[plugin_name] = _validate_plugin_class(
+            plugin_classes[0], plugin_name=plugin_name
+        )
+        logger.debug("loaded plugin %r from %s", plugin_name, plugin_file)
+
+    return plugins
+
+
+def load_plugins_from_entry_points(group: str) -> Dict[str, Type[Plugin]]:
+    """Load the plugins advertised by installed packages.
+
+    Plugins are declared as entry points in the given group, where the entry
+    point name is the plugin name and its object is the plugin class.
+
+    :param group: The entry point group.
+
+    :return: A dictionary where the keys are plugin names and values are
+        plugin classes.
+
+    :raise PluginLoadError: If an entry point cannot be loaded or its object
+        is not a valid plugin.
+    """
+    all_entry_points = metadata.entry_points()
+    if hasattr(all_entry_points, "select"):
+        entry_points = all_entry_points.select(group=group)
+    else:
+        # Python < 3.10 returns a dictionary of entry points by group.
+        entry_points = all_entry_points.get(group, [])  # type: ignore
+
+    plugins: Dict[str, Type[Plugin]] = {}
+
+    for entry_point in entry_points:
+        try:
+            obj = entry_point.load()
+        except Exception as err:  # pylint: disable=broad-except
+            raise errors.PluginLoadError(
+                entry_point.name, message=f"cannot load entry point: {err}"
+            ) from err
+
+        plugins[entry_point.name] = _validate_plugin_class(
+            obj, plugin_name=entry_point.name
+        )
+        logger.debug("loaded plugin %r from %s", entry_point.name, entry_point.value)
+
+    return plugins
+
+
+def _import_file(plugin_file: Path, *, plugin_name: str) -> Any:
+    """Import a python file as a module."""
+    module_name = f"{_PROJECT_PLUGINS_MODULE}.{plugin_file.stem}"
+    spec = importlib.util.spec_from_file_location(module_name, plugin_file)
+    if not spec or not spec.loader:
+        raise errors.PluginLoadError(
+            plugin_name, message=f"cannot import {str(plugin_file)!r}"
+        )
+
+    module = importlib.util.module_from_spec(spec)
+    sys.modules[module_name] = module
+    try:
+        spec.loader.exec_module(module)  # type: ignore
+    except Exception as err:  # pylint: disable=broad-except
+        del sys.modules[module_name]
+        raise errors.PluginLoadError(
+            plugin_name, message=f"cannot import {str(plugin_file)!r}: {err}"
+        ) from err
+
+    return module
+
+
+def _validate_plugin_class(obj: Any, *, plugin_name: str) -> Type[Plugin]:
+    """Verify that an object can be registered as a plugin."""
+    if not inspect.isclass(obj) or not issubclass(obj, Plugin):
+        raise errors.PluginLoadError(
+            plugin_name, message=f"{obj!r} is not a subclass of Plugin"
+        )
+
+    if inspect.isabstract(obj):
+        raise errors.PluginLoadError(
+            plugin_name,
+            message=f"{obj.__name__!r} does not implement all plugin methods",
+        )
+
+    properties_class = getattr(obj, "properties_class", None)
+    if not inspect.isclass(properties_class) or not issubclass(
+        properties_class, PluginProperties
+    ):
+        raise errors.PluginLoadError(
+            plugin_name,
+            message=(
+                f"the properties class of {obj.__name__!r} is not a subclass "
+                "of PluginProperties"
+            ),
+        )
+
+    return obj
@@ -18,11 +18,13 @@
 
 from typing import Any, Dict, List, Set, cast
 
+from craft_parts import compiler_cache
+
 from .base import Plugin, PluginModel, extract_plugin_properties
 from .properties import PluginProperties
 
 
-class MakePluginProperties(PluginProperties, PluginModel):
+class MakePluginProperties(PluginModel, PluginProperties):
     """The part properties used by the make plugin."""
 
     make_parameters: List[str] = []
@@ -59,6 +61,9 @@ class MakePlugin(Plugin):
         - make-parameters
           (list of strings)
           Pass the given parameters to the make command.
+
+    If the compiler cache is enabled in the project, ``CC`` and ``CXX`` are
+    set to compilers wrapped by ccache.
     """
 
     properties_class = MakePluginProperties
@@ -69,11 +74,14 @@ class MakePlugin(Plugin):
 
     def get_build_packages(self) -> Set[str]:
         """Return a set of required packages to install in the build environment."""
-        return {"gcc", "make"}
+        packages = {"gcc", "make"}
+        if compiler_cache.is_enabled(self._part_info):
+            packages.add("ccache")
+        return packages
 
     def get_build_environment(self) -> Dict[str, str]:
         """Return a dictionary with the environment to use in the build step."""
-        return dict()
+        return compiler_cache.get_compiler_environment(self._part_info)
 
     def _get_make_command(self, target: str = "") -> str:
         cmd = ["make", f'-j"{self._part_info.parallel_build_count}"']
@@ -86,6 +94,13 @@ class MakePlugin(Plugin):
 
         return " ".join(cmd)
 
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "cc": '"${CC:-cc}" --version',
+            "make": "make --version",
+        }
+
     def get_build_commands(self) -> List[str]:
         """Return a list of commands to run during the build step."""
         return [
@@ -0,0 +1,301 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The maven plugin implementation."""
+
+import shlex
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+import pydantic
+
+from craft_parts.utils import url_utils
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+# The server id used to match mirror credentials in settings.xml.
+_MIRROR_ID = "craft-parts-mirror"
+
+
+class MavenPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the maven plugin."""
+
+    maven_parameters: List[str] = []
+    maven_projects: List[str] = []
+    maven_also_make: bool = False
+    maven_go_offline: bool = False
+    maven_mirror: Optional[str]
+    maven_mirror_username_env: Optional[str]
+    maven_mirror_password_env: Optional[str]
+
+    # pylint: disable=no-self-argument
+    @pydantic.validator("maven_projects", each_item=True)
+    def validate_maven_projects(cls, item):
+        """Make sure projects are selected by their module directories."""
+        if not item or ":" in item or Path(item).is_absolute():
+            raise ValueError(
+                "projects must be module directories relative to the source"
+            )
+        return item
+
+    # pylint: enable=no-self-argument
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate maven properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="maven")
+        return cls(**plugin_data)
+
+    def marshal(self) -> Dict[str, Any]:
+        """Obtain a dictionary containing the plugin properties.
+
+        Credentials in the mirror URL are redacted, so they are not saved
+        in the step state.
+
+        :return: The dictionary containing plugin properties.
+        """
+        data = super().marshal()
+        if self.maven_mirror:
+            data["maven-mirror"] = url_utils.redact_credentials(self.maven_mirror)
+        return data
+
+    @classmethod
+    def get_pull_properties(cls) -> List[str]:
+        """Obtain the list of properties affecting the pull stage.
+
+        :return: The names of plugin properties relevant to the pull step.
+        """
+        return [
+            "maven-go-offline",
+            "maven-mirror",
+            "maven-mirror-username-env",
+            "maven-mirror-password-env",
+        ]
+
+
+class MavenPlugin(Plugin):
+    """A plugin for java projects built with maven.
+
+    The maven plugin runs ``mvn package`` and copies the jar files created
+    in the ``target`` directory to the ``jar`` directory in the part install
+    directory. In multi-module projects, the modules to build can be
+    selected from the reactor, and only the jar files of the selected
+    modules are installed.
+
+    The maven plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - maven-parameters
+          (list of strings)
+          Additional parameters to pass to the ``mvn package`` command.
+
+        - maven-projects
+          (list of strings)
+          The directories of the reactor modules to build, relative to the
+          source directory. Default is to build all modules.
+
+        - maven-also-make
+          (boolean)
+          Also build the modules the selected modules depend on. Their jar
+          files are not installed. Defaults to false.
+
+        - maven-go-offline
+          (boolean)
+          Download the project dependencies and plugins to a local repository
+          in the part directory during the pull step, and build offline using
+          only the local repository. Defaults to false.
+
+        - maven-mirror
+          (string)
+          The URL of a repository mirror to use instead of the remote
+          repositories declared by the project.
+
+        - maven-mirror-username-env
+          (string)
+          The name of the environment variable containing the user name to
+          authenticate to the mirror.
+
+        - maven-mirror-password-env
+          (string)
+          The name of the environment variable containing the password to
+          authenticate to the mirror.
+
+    Credentials are read by maven from the environment when it runs, and are
+    not written to the generated settings.xml file. Proxies configured in the
+    project are passed to maven as system properties.
+    """
+
+    properties_class = MavenPluginProperties
+
+    def get_pull_commands(self) -> List[str]:
+        """Return a list of commands to run during the pull step."""
+        options = cast(MavenPluginProperties, self._options)
+        if not options.maven_go_offline:
+            return []
+
+        return [
+            *self._get_settings_commands(),
+            self._get_maven_command("dependency:go-offline"),
+        ]
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"default-jdk-headless", "maven"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "maven": "mvn --version",
+            "java": "java -version 2>&1",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(MavenPluginProperties, self._options)
+        jar_dir = self._part_info.part_install_dir / "jar"
+
+        target_dirs = [f"{project}/target" for project in options.maven_projects]
+
+        return [
+            *self._get_settings_commands(),
+            self._get_maven_command(
+                "package",
+                *self._get_reactor_options(),
+                *options.maven_parameters,
+                offline=options.maven_go_offline,
+            ),
+            f'mkdir -p "{jar_dir}"',
+            *[
+                f'find {shlex.quote(target_dir)} -maxdepth 1 -name "*.jar" '
+                f'-exec cp --archive {{}} "{jar_dir}" \\;'
+                for target_dir in target_dirs or ["target"]
+            ],
+        ]
+
+    def _get_reactor_options(self) -> List[str]:
+        """Obtain the options selecting modules to build from the reactor."""
+        options = cast(MavenPluginProperties, self._options)
+        if not options.maven_projects:
+            return []
+
+        projects = ",".join(options.maven_projects)
+        reactor_options = ["--projects", shlex.quote(projects)]
+        if options.maven_also_make:
+            reactor_options.append("--also-make")
+
+        return reactor_options
+
+    def _get_maven_dir(self) -> Path:
+        # Keep maven files in the part directory, so they are available to
+        # both the pull and build steps without being copied to the build
+        # directory.
+        return self._part_info.part_src_dir.parent / "maven"
+
+    def _has_settings(self) -> bool:
+        options = cast(MavenPluginProperties, self._options)
+        return options.maven_go_offline or options.maven_mirror is not None
+
+    def _get_maven_command(self, *args: str, offline: bool = False) -> str:
+        cmd = ["mvn", "--batch-mode"]
+        if self._part_info.proxy:
+            cmd.extend(shlex.quote(x) for x in self._part_info.proxy.java_options)
+        if self._has_settings():
+            cmd.append(f'--settings "{self._get_maven_dir()}/settings.xml"')
+        if offline:
+            cmd.append("--offline")
+        cmd.extend(args)
+
+        return " ".join(cmd)
+
+    def _get_settings_commands(self) -> List[str]:
+        """Obtain the commands to write the maven settings file."""
+        if not self._has_settings():
+            return []
+
+        maven_dir = self._get_maven_dir()
+        lines = " ".join(shlex.quote(line) for line in self._get_settings())
+
+        return [
+            f'mkdir -p "{maven_dir}"',
+            f'printf "%s\\n" {lines} > "{maven_dir}/settings.xml"',
+        ]
+
+    def _get_settings(self) -> List[str]:
+        """Obtain the lines of the maven settings file."""
+        options = cast(MavenPluginProperties, self._options)
+
+        settings = ["<settings>"]
+        if options.maven_go_offline:
+            repository = self._get_maven_dir() / "repository"
+            settings.append(f"  <localRepository>{repository}</localRepository>")
+
+        if options.maven_mirror:
+            settings.extend(
+                [
+                    "  <mirrors>",
+                    "    <mirror>",
+                    f"      <id>{_MIRROR_ID}</id>",
+                    "      <mirrorOf>*</mirrorOf>",
+                    f"      <url>{options.maven_mirror}</url>",
+                    "    </mirror>",
+                    "  </mirrors>",
+                ]
+            )
+
+        credentials: List[str] = []
+        if options.maven_mirror_username_env:
+            credentials.append(
+                f"      <username>${{env.{options.maven_mirror_username_env}}}"
+                "</username>"
+            )
+        if options.maven_mirror_password_env:
+            credentials.append(
+                f"      <password>${{env.{options.maven_mirror_password_env}}}"
+                "</password>"
+            )
+        if options.maven_mirror and credentials:
+            settings.extend(
+                [
+                    "  <servers>",
+                    "    <server>",
+                    f"      <id>{_MIRROR_ID}</id>",
+                    *credentials,
+                    "    </server>",
+                    "  </servers>",
+                ]
+            )
+
+        settings.append("</settings>")
+        return settings
@@ -0,0 +1,142 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The meson plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from craft_parts.utils import file_utils
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class MesonPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the meson plugin."""
+
+    meson_parameters: List[str] = []
+    meson_cross_file: Optional[str]
+    meson_native_file: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate meson properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="meson")
+        return cls(**plugin_data)
+
+
+class MesonPlugin(Plugin):
+    """A plugin for meson projects.
+
+    The plugin configures the project out of the source tree using
+    ``meson setup``, then builds and installs it with ninja.
+
+    This plugin uses the common plugin keywords as well as those for "sources".
+    For more information check the 'plugins' topic for the former and the
+    'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - meson-parameters
+          (list of strings)
+          Parameters to pass to meson setup, e.g. ``--buildtype=release``.
+
+        - meson-cross-file
+          (string)
+          Path to a meson cross file describing the target machine, relative
+          to the part source.
+
+        - meson-native-file
+          (string)
+          Path to a meson native file describing the build machine, relative
+          to the part source.
+
+    Changes to the cross and native files cause the part to be rebuilt.
+    """
+
+    properties_class = MesonPluginProperties
+
+    @property
+    def out_of_source_build(self) -> bool:
+        """Return whether the plugin performs out-of-source-tree builds."""
+        return True
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"gcc", "meson", "ninja-build"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        assets: Dict[str, Any] = {}
+        for name, path in self._get_machine_files().items():
+            if path.is_file():
+                digest = file_utils.calculate_hash(str(path), algorithm="sha256")
+                assets[f"meson-{name}"] = digest
+
+        return assets
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "meson": "meson --version",
+            "ninja": "ninja --version",
+            "cc": '"${CC:-cc}" --version',
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(MesonPluginProperties, self._options)
+
+        cmd = ["meson setup"]
+        for name, path in self._get_machine_files().items():
+            cmd.append(f'--{name} "{path}"')
+        cmd.extend(options.meson_parameters)
+        cmd.append(f'. "{self._part_info.part_src_subdir}"')
+
+        return [
+            " ".join(cmd),
+            f'ninja -j "{self._part_info.parallel_build_count}"',
+            f'DESTDIR="{self._part_info.part_install_dir}" ninja install',
+        ]
+
+    def _get_machine_files(self) -> Dict[str, Path]:
+        """Obtain the machine files to use, resolved to the part source."""
+        options = cast(MesonPluginProperties, self._options)
+        src_dir = self._part_info.part_src_subdir
+
+        files: Dict[str, Path] = {}
+        if options.meson_cross_file:
+            files["cross-file"] = src_dir / options.meson_cross_file
+        if options.meson_native_file:
+            files["native-file"] = src_dir / options.meson_native_file
+
+        return files
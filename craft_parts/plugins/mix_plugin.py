@@ -0,0 +1,100 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The mix plugin implementation."""
+
+from typing import Any, Dict, List, Optional, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class MixPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the mix plugin."""
+
+    mix_env: str = "prod"
+    mix_release: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate mix properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="mix")
+        return cls(**plugin_data)
+
+
+class MixPlugin(Plugin):
+    """A plugin for elixir applications built with mix.
+
+    The mix plugin fetches the application dependencies, compiles it and
+    assembles a release in the part install directory.
+
+    The mix plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - mix-env
+          (string)
+          The mix environment (``MIX_ENV``) to build in. Defaults to
+          ``prod``.
+
+        - mix-release
+          (string)
+          The name of the release to assemble, if the project defines more
+          than one release.
+    """
+
+    properties_class = MixPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"elixir", "erlang-dev", "git"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        options = cast(MixPluginProperties, self._options)
+        return {"MIX_ENV": options.mix_env}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(MixPluginProperties, self._options)
+
+        release_cmd = ["mix release"]
+        if options.mix_release:
+            release_cmd.append(f'"{options.mix_release}"')
+        release_cmd.extend(
+            ["--overwrite", f'--path "{self._part_info.part_install_dir}"']
+        )
+
+        return [
+            "mix local.hex --force --if-missing",
+            "mix local.rebar --force --if-missing",
+            f'mix deps.get --only "{options.mix_env}"',
+            "mix compile",
+            " ".join(release_cmd),
+        ]
@@ -0,0 +1,159 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""The nim plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Set, cast
+
+from xdg import BaseDirectory  # type: ignore
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+# Map deb architectures to nim CPU names.
+_NIM_CPU: Dict[str, str] = {
+    "amd64": "amd64",
+    "arm64": "arm64",
+    "armhf": "arm",
+    "i386": "i386",
+    "ppc64el": "powerpc64el",
+    "riscv64": "riscv64",
+    "s390x": "s390x",
+}
+
+# Extract package metadata fields from the output of ``nimble dump``.
+_NIMBLE_DUMP_BIN = "sed -n 's/^bin: \"\\(.*\\)\"$/\\1/p'"
+_NIMBLE_DUMP_BIN_DIR = "sed -n 's/^binDir: \"\\(.*\\)\"$/\\1/p'"
+
+
+class NimPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the nim plugin."""
+
+    nim_sources: List[str] = []
+    nim_flags: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate nim properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="nim")
+        return cls(**plugin_data)
+
+
+class NimPlugin(Plugin):
+    """A plugin for Nim projects.
+
+    Dependencies declared in the project ``.nimble`` file are installed with
+    nimble in a cache shared by all projects, so packages are not downloaded
+    every time. If ``nim-sources`` is set, each source is compiled with
+    ``nim c``. Otherwise the project is built with ``nimble build`` and the
+    binaries listed in the ``.nimble`` file are installed. Binaries are
+    built in release mode and installed in ``$CRAFT_PART_INSTALL/bin``.
+
+    The nim plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - nim-sources
+          (list of strings)
+          The main source files to compile with ``nim c``, relative to the
+          source directory. Default is to build the project with nimble.
+
+        - nim-flags
+          (list of strings)
+          Additional flags to pass to the compiler, e.g. ``-d:danger``.
+    """
+
+    properties_class = NimPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"gcc", "git", "nim"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {"NIMBLE_DIR": str(self._get_nimble_dir())}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "nim": "nim --version",
+            "nimble": "nimble --version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(NimPluginProperties, self._options)
+        bin_dir = self._part_info.part_install_dir / "bin"
+        flags = " ".join(["-d:release", *self._get_target_flags(), *options.nim_flags])
+
+        commands = [
+            "if ls *.nimble >/dev/null 2>&1; then nimble install --depsOnly -y; fi",
+            f'mkdir -p "{bin_dir}"',
+        ]
+
+        if options.nim_sources:
+            nimble_paths = (
+                '--nimblePath:"${NIMBLE_DIR}/pkgs2" --nimblePath:"${NIMBLE_DIR}/pkgs"'
+            )
+            for source in options.nim_sources:
+                commands.append(
+                    f'nim c {flags} {nimble_paths} --outdir:"{bin_dir}" "{source}"'
+                )
+            return commands
+
+        return [
+            *commands,
+            f"nimble build -y {flags}",
+            f'nimble_bin_dir="$(nimble dump | {_NIMBLE_DUMP_BIN_DIR})"',
+            f"for bin in $(nimble dump | {_NIMBLE_DUMP_BIN} | tr ',' ' '); do "
+            f'install -m 755 "${{nimble_bin_dir:-.}}/$(basename "$bin")" '
+            f'"{bin_dir}"; done',
+        ]
+
+    def _get_target_flags(self) -> List[str]:
+        """Obtain the compiler flags to build for the target architecture."""
+        if not self._part_info.is_cross_compiling:
+            return []
+
+        target_arch = self._part_info.target_arch
+        triplet = self._part_info.arch_triplet
+        return [
+            f"--cpu:{_NIM_CPU.get(target_arch, target_arch)}",
+            "--os:linux",
+            f"--gcc.exe:{triplet}-gcc",
+            f"--gcc.linkerexe:{triplet}-gcc",
+        ]
+
+    def _get_nimble_dir(self) -> Path:
+        cache_dir = BaseDirectory.save_cache_path(
+            self._part_info.application_name, "craft-parts", "nimble"
+        )
+        return Path(cache_dir)
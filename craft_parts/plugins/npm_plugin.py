@@ -0,0 +1,262 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The npm plugin implementation."""
+
+import json
+import logging
+import re
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from pydantic import Field
+from xdg import BaseDirectory  # type: ignore
+
+from craft_parts import errors
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .properties import PluginProperties
+
+logger = logging.getLogger(__name__)
+
+# Commands to install production dependencies from the lock file.
+_INSTALL_COMMANDS: Dict[str, str] = {
+    "npm": "npm ci --omit=dev",
+    "pnpm": "pnpm install --frozen-lockfile --prod",
+    "yarn-classic": "yarn install --frozen-lockfile --production",
+    "yarn-berry": "yarn install --immutable",
+}
+
+# Map deb architectures to the names used in Node.js downloads.
+_NODE_ARCH: Dict[str, str] = {
+    "amd64": "x64",
+    "arm64": "arm64",
+    "armhf": "armv7l",
+    "ppc64el": "ppc64le",
+    "s390x": "s390x",
+}
+
+_NODE_DOWNLOAD_URL = "https://nodejs.org/dist"
+
+
+class NpmPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the npm plugin."""
+
+    node_package_manager: str = Field("npm", regex=r"^(npm|pnpm|yarn)$")
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate npm properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="node")
+        plugin_data.update(extract_plugin_properties(data, plugin_name="npm"))
+        return cls(**plugin_data)
+
+
+class NpmPlugin(Plugin):
+    """A plugin for node projects.
+
+    The npm plugin installs the production dependencies of the project
+    from its lock file, failing if the lock file is missing or out of
+    date, and installs the project in ``lib/node_modules/<name>`` under
+    the part install directory. Executables declared in package.json are
+    linked in the install ``bin`` directory.
+
+    If the project declares an exact Node.js version in its ``.nvmrc`` file
+    or in the ``engines.node`` field of package.json, that version is
+    downloaded to a cache shared by all projects, verified using the digests
+    published by the Node.js project, used to build the part and installed
+    with the part. Version ranges are not resolved, and Node.js
+    must be available in the build environment if no exact version is
+    declared. The selected package manager must be available in the build
+    environment.
+
+    The npm plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the
+    former and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - node-package-manager
+          (string)
+          The package manager used to install dependencies: ``npm``,
+          ``pnpm`` or ``yarn``. Yarn berry is used if the project contains
+          a ``.yarnrc.yml`` file, yarn classic otherwise. Defaults to npm.
+    """
+
+    properties_class = NpmPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        # The node version is declared in the part sources, which may not
+        # have been pulled yet.
+        return {"curl", "xz-utils"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        node_dir = self._get_node_dir()
+        if not node_dir:
+            return {}
+
+        return {"PATH": f"{node_dir}/bin:${{PATH}}"}
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        version = self._get_node_version()
+        if not version:
+            return {}
+
+        return {"node-version": version}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "node": "node --version",
+            "npm": "npm --version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        package = self._read_package_json()
+        name = package.get("name", self._part_info.part_name)
+
+        install_dir = self._part_info.part_install_dir
+        dest_dir = install_dir / "lib" / "node_modules" / name
+
+        build_commands = [
+            *self._get_node_commands(),
+            _INSTALL_COMMANDS[self._get_package_manager()],
+            f'mkdir -p "{dest_dir}"',
+            f'cp --archive . "{dest_dir}"',
+        ]
+
+        bins = package.get("bin", {})
+        if isinstance(bins, str):
+            bins = {name.split("/")[-1]: bins}
+
+        if bins:
+            build_commands.append(f'mkdir -p "{install_dir}/bin"')
+        for bin_name, bin_path in bins.items():
+            target = Path("..", "lib", "node_modules", name, bin_path)
+            build_commands.append(
+                f'ln -sf "{target.as_posix()}" "{install_dir}/bin/{bin_name}"'
+            )
+
+        return build_commands
+
+    def _get_node_commands(self) -> List[str]:
+        """Obtain the commands to download, unpack and install Node.js."""
+        node_dir = self._get_node_dir()
+        if not node_dir:
+            return []
+
+        tarball = f"{node_dir}.tar.xz"
+        version = self._get_node_version()
+        url = f"{_NODE_DOWNLOAD_URL}/v{version}/{node_dir.name}.tar.xz"
+        checksums_url = f"{_NODE_DOWNLOAD_URL}/v{version}/SHASUMS256.txt"
+        install_dir = self._part_info.part_install_dir
+
+        return [
+            get_download_command(url, tarball, checksums_url=checksums_url),
+            f'if [ ! -x "{node_dir}/bin/node" ]; then '
+            f'mkdir -p "{node_dir}" && '
+            f'tar -xJf "{tarball}" -C "{node_dir}" --strip-components=1; fi',
+            f'mkdir -p "{install_dir}"',
+            f'cp --archive "{node_dir}/bin" "{node_dir}/lib" "{install_dir}"',
+        ]
+
+    def _get_node_dir(self) -> Optional[Path]:
+        """Obtain the cached location of Node.js, if provisioned."""
+        version = self._get_node_version()
+        if not version:
+            return None
+
+        host_arch = self._part_info.host_arch
+        node_arch = _NODE_ARCH.get(host_arch, host_arch)
+        cache_dir = Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "node"
+            )
+        )
+
+        return cache_dir / f"node-v{version}-linux-{node_arch}"
+
+    def _get_node_version(self) -> Optional[str]:
+        """Obtain the exact Node.js version declared in the project, if any."""
+        build_dir = self._part_info.part_build_subdir
+
+        nvmrc = build_dir / ".nvmrc"
+        if nvmrc.is_file():
+            spec = nvmrc.read_text().strip()
+            source = ".nvmrc"
+        else:
+            package_json = build_dir / "package.json"
+            try:
+                package = json.loads(package_json.read_text())
+                spec = package.get("engines", {}).get("node", "")
+            except (OSError, ValueError, AttributeError):
+                spec = ""
+            source = "package.json"
+
+        if not spec:
+            return None
+
+        match = re.match(r"^[=v]*\s*(\d+\.\d+\.\d+)$", spec)
+        if not match:
+            logger.debug("node version %r in %s is not exact, ignored", spec, source)
+            return None
+
+        return match.group(1)
+
+    def _get_package_manager(self) -> str:
+        options = cast(NpmPluginProperties, self._options)
+        if options.node_package_manager != "yarn":
+            return options.node_package_manager
+
+        if (self._part_info.part_build_subdir / ".yarnrc.yml").is_file():
+            return "yarn-berry"
+
+        return "yarn-classic"
+
+    def _read_package_json(self) -> Dict[str, Any]:
+        """Read the project's package.json file.
+
+        :raise PluginEnvironmentValidationError: If package.json is not found
+            or can't be parsed.
+        """
+        package_json = self._part_info.part_build_subdir / "package.json"
+        try:
+            return json.loads(package_json.read_text())
+        except (OSError, ValueError) as err:
+            raise errors.PluginEnvironmentValidationError(
+                part_name=self._part_info.part_name,
+                reason=f"cannot read package.json: {err}",
+            ) from err
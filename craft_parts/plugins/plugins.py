@@ -21,10 +21,40 @@ from typing import TYPE_CHECKING, Any, Dict, Type
 
 from .autotools_plugin import AutotoolsPlugin
 from .base import Plugin
+from .bazel_plugin import BazelPlugin
+from .bun_plugin import BunPlugin
+from .cmake_plugin import CMakePlugin
+from .composer_plugin import ComposerPlugin
+from .conda_plugin import CondaPlugin
+from .crystal_plugin import CrystalPlugin
+from .deno_plugin import DenoPlugin
+from .dotnet_plugin import DotnetPlugin
 from .dump_plugin import DumpPlugin
+from .dune_plugin import DunePlugin
+from .flutter_plugin import FlutterPlugin
+from .go_plugin import GoPlugin
+from .go_use_plugin import GoUsePlugin
+from .gradle_plugin import GradlePlugin
+from .haskell_plugin import HaskellPlugin
+from .jlink_plugin import JLinkPlugin
 from .make_plugin import MakePlugin
+from .maven_plugin import MavenPlugin
+from .meson_plugin import MesonPlugin
+from .mix_plugin import MixPlugin
 from .nil_plugin import NilPlugin
+from .nim_plugin import NimPlugin
+from .npm_plugin import NpmPlugin
+from .poetry_plugin import PoetryPlugin
 from .properties import PluginProperties
+from .python_plugin import PythonPlugin
+from .qmake_plugin import QmakePlugin
+from .rebar3_plugin import Rebar3Plugin
+from .ruby_plugin import RubyPlugin
+from .rust_plugin import RustPlugin
+from .scons_plugin import SConsPlugin
+from .swift_plugin import SwiftPlugin
+from .uv_plugin import UvPlugin
+from .zig_plugin import ZigPlugin
 
 if TYPE_CHECKING:
     from craft_parts.infos import PartInfo
@@ -37,9 +67,39 @@ PluginType = Type[Plugin]
 # Plugin registry by plugin API version
 _BUILTIN_PLUGINS: Dict[str, PluginType] = {
     "autotools": AutotoolsPlugin,
+    "bazel": BazelPlugin,
+    "bun": BunPlugin,
+    "cmake": CMakePlugin,
+    "composer": ComposerPlugin,
+    "conda": CondaPlugin,
+    "crystal": CrystalPlugin,
+    "deno": DenoPlugin,
+    "dotnet": DotnetPlugin,
     "dump": DumpPlugin,
+    "dune": DunePlugin,
+    "flutter": FlutterPlugin,
+    "go": GoPlugin,
+    "go-use": GoUsePlugin,
+    "gradle": GradlePlugin,
+    "haskell": HaskellPlugin,
+    "jlink": JLinkPlugin,
     "make": MakePlugin,
+    "maven": MavenPlugin,
+    "meson": MesonPlugin,
+    "mix": MixPlugin,
     "nil": NilPlugin,
+    "nim": NimPlugin,
+    "npm": NpmPlugin,
+    "poetry": PoetryPlugin,
+    "python": PythonPlugin,
+    "qmake": QmakePlugin,
+    "rebar3": Rebar3Plugin,
+    "ruby": RubyPlugin,
+    "rust": RustPlugin,
+    "scons": SConsPlugin,
+    "swift": SwiftPlugin,
+    "uv": UvPlugin,
+    "zig": ZigPlugin,
 }
 
 _PLUGINS = copy.deepcopy(_BUILTIN_PLUGINS)
@@ -80,6 +140,15 @@ def get_plugin_class(name: str) -> PluginType:
     return _PLUGINS[name]
 
 
+def get_registered_plugins() -> Dict[str, PluginType]:
+    """Obtain the plugins currently registered.
+
+    :return: A dictionary where the keys are plugin names and values are
+        plugin classes.
+    """
+    return _PLUGINS.copy()
+
+
 def register(plugins: Dict[str, PluginType]) -> None:
     """Register part handler plugins.
 
@@ -89,6 +158,20 @@ def register(plugins: Dict[str, PluginType]) -> None:
     _PLUGINS.update(plugins)
 
 
+def unregister(*plugins: str) -> None:
+    """Unregister part handler plugins.
+
+    Built-in plugins with the same names are registered again.
+
+    :param plugins: The names of the plugins to unregister.
+    """
+    for name in plugins:
+        if name in _BUILTIN_PLUGINS:
+            _PLUGINS[name] = _BUILTIN_PLUGINS[name]
+        else:
+            _PLUGINS.pop(name, None)
+
+
 def unregister_all() -> None:
     """Unregister all user-registered plugins."""
     global _PLUGINS  # pylint: disable=global-statement
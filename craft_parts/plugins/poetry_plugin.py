@@ -0,0 +1,132 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The poetry plugin implementation."""
+
+import shutil
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+from .python_plugin import get_venv_fixup_commands
+
+
+class PoetryPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the poetry plugin."""
+
+    poetry_with: List[str] = []
+    poetry_without: List[str] = []
+    poetry_strict_lock: bool = False
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate poetry properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="poetry")
+        return cls(**plugin_data)
+
+
+class PoetryPlugin(Plugin):
+    """A plugin to build python projects managed with poetry.
+
+    The poetry plugin creates a virtual environment in the part install
+    directory, installs the dependencies exported from the poetry lock file
+    and then installs the project itself.
+
+    The poetry plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - poetry-with
+          (list of strings)
+          Optional dependency groups to install, in addition to the main
+          dependencies.
+
+        - poetry-without
+          (list of strings)
+          Dependency groups that should not be installed.
+
+        - poetry-strict-lock
+          (boolean)
+          Fail the build if poetry.lock is missing or out of date with
+          pyproject.toml, instead of resolving dependencies again.
+          Defaults to false.
+    """
+
+    properties_class = PoetryPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        packages = {"findutils", "python3-dev", "python3-venv"}
+        if not shutil.which("poetry"):
+            packages.add("python3-poetry")
+        return packages
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {
+            "PATH": f"{self._part_info.part_install_dir}/bin:${{PATH}}",
+            "PARTS_PYTHON_INTERPRETER": "python3",
+            "PARTS_PYTHON_VENV_ARGS": "",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(PoetryPluginProperties, self._options)
+        install_dir = self._part_info.part_install_dir
+        requirements = self._part_info.part_build_dir / "requirements.txt"
+
+        build_commands: List[str] = []
+        if options.poetry_strict_lock:
+            build_commands.append("poetry check --lock")
+
+        export_cmd = [
+            "poetry export",
+            "--format=requirements.txt",
+            f'--output="{requirements}"',
+            "--with-credentials",
+        ]
+        if options.poetry_with:
+            export_cmd.append(f"--with={','.join(options.poetry_with)}")
+        if options.poetry_without:
+            export_cmd.append(f"--without={','.join(options.poetry_without)}")
+
+        build_commands.extend(
+            [
+                f'"${{PARTS_PYTHON_INTERPRETER}}" -m venv ${{PARTS_PYTHON_VENV_ARGS}} '
+                f'"{install_dir}"',
+                f'PARTS_PYTHON_VENV_INTERP_PATH="{install_dir}/bin/'
+                f'${{PARTS_PYTHON_INTERPRETER}}"',
+                " ".join(export_cmd),
+                f'pip install --requirement="{requirements}"',
+                "pip install --no-deps .",
+                *get_venv_fixup_commands(install_dir),
+            ]
+        )
+
+        return build_commands
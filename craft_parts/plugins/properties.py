@@ -16,7 +16,7 @@
 
 """Definitions and helpers for plugin options."""
 
-from typing import Any, Dict
+from typing import Any, Dict, List
 
 
 class PluginProperties:
@@ -37,3 +37,26 @@ class PluginProperties:
         :return: The populated plugin properties data object.
         """
         return cls()
+
+    def marshal(self) -> Dict[str, Any]:
+        """Obtain a dictionary containing the plugin properties.
+
+        :return: The dictionary containing plugin properties.
+        """
+        return {}
+
+    @classmethod
+    def get_pull_properties(cls) -> List[str]:
+        """Obtain the list of properties affecting the pull stage.
+
+        :return: The names of plugin properties relevant to the pull step.
+        """
+        return []
+
+    @classmethod
+    def get_build_properties(cls) -> List[str]:
+        """Obtain the list of properties affecting the build stage.
+
+        :return: The names of plugin properties relevant to the build step.
+        """
+        return []
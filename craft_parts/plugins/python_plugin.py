@@ -0,0 +1,161 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The python plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class PythonPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the python plugin."""
+
+    python_requirements: List[str] = []
+    python_constraints: List[str] = []
+    python_packages: List[str] = ["pip", "setuptools", "wheel"]
+    python_require_hashes: bool = False
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate python properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="python")
+        return cls(**plugin_data)
+
+
+class PythonPlugin(Plugin):
+    """A plugin to build python parts.
+
+    The python plugin creates a virtual environment in the part install
+    directory and installs the part's requirements and the project itself,
+    if it contains a setup.py or pyproject.toml file.
+
+    The python plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - python-requirements
+          (list of strings)
+          List of paths to requirements files.
+
+        - python-constraints
+          (list of strings)
+          List of paths to constraint files.
+
+        - python-packages
+          (list of strings)
+          A list of dependencies to get from PyPI. Defaults to pip, setuptools
+          and wheel, so the virtual environment uses up-to-date packaging tools.
+
+        - python-require-hashes
+          (boolean)
+          Require every package installed from the requirements files to be
+          pinned and checked against the hashes listed in the requirements.
+          The build fails if a downloaded package doesn't match its hash. The
+          project itself is installed without resolving dependencies, since
+          they must all be listed in the locked requirements. Defaults to false.
+    """
+
+    properties_class = PythonPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"findutils", "python3-dev", "python3-venv"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {
+            # Add PATH to the python interpreter we always intend to use with
+            # this plugin. It can be user overridden, but that is an explicit
+            # choice made by a user.
+            "PATH": f"{self._part_info.part_install_dir}/bin:${{PATH}}",
+            "PARTS_PYTHON_INTERPRETER": "python3",
+            "PARTS_PYTHON_VENV_ARGS": "",
+        }
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "python": '"${PARTS_PYTHON_INTERPRETER}" --version',
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(PythonPluginProperties, self._options)
+        install_dir = self._part_info.part_install_dir
+
+        build_commands = [
+            f'"${{PARTS_PYTHON_INTERPRETER}}" -m venv ${{PARTS_PYTHON_VENV_ARGS}} '
+            f'"{install_dir}"',
+            f'PARTS_PYTHON_VENV_INTERP_PATH="{install_dir}/bin/'
+            f'${{PARTS_PYTHON_INTERPRETER}}"',
+        ]
+
+        constraints = " ".join(f"-c {c!r}" for c in options.python_constraints)
+        pip = " ".join(["pip install", constraints]) if constraints else "pip install"
+
+        if options.python_packages:
+            python_packages = " ".join(f"{pkg!r}" for pkg in options.python_packages)
+            build_commands.append(f"{pip} -U {python_packages}")
+
+        if options.python_requirements:
+            requirements = " ".join(f"-r {r!r}" for r in options.python_requirements)
+            hashes = "--require-hashes " if options.python_require_hashes else ""
+            build_commands.append(f"{pip} {hashes}-U {requirements}")
+
+        # Dependencies were installed from the hash-checked requirements.
+        no_deps = "--no-deps " if options.python_require_hashes else ""
+        build_commands.append(
+            "if [ -f setup.py ] || [ -f pyproject.toml ]; then "
+            f"{pip} {no_deps}-U .; fi"
+        )
+
+        build_commands.extend(get_venv_fixup_commands(install_dir))
+
+        return build_commands
+
+
+def get_venv_fixup_commands(install_dir: Path) -> List[str]:
+    """Obtain the commands to make a virtual environment relocatable.
+
+    :param install_dir: The directory containing the virtual environment.
+
+    :return: The list of commands to run after populating the environment.
+    """
+    return [
+        # Now fix shebangs.
+        f'find "{install_dir}" -type f -executable -print0 | xargs -0 '
+        f"sed -i \"1 s|^#\\!${{PARTS_PYTHON_VENV_INTERP_PATH}}.*$|"
+        f'#!/usr/bin/env ${{PARTS_PYTHON_INTERPRETER}}|"',
+        # Lastly, fix the symlink to the "real" python3 interpreter.
+        f'ln -sf "$(readlink -f "$(which "${{PARTS_PYTHON_INTERPRETER}}")")" '
+        f'"{install_dir}/bin/${{PARTS_PYTHON_INTERPRETER}}"',
+    ]
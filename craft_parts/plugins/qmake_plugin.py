@@ -0,0 +1,146 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The qmake plugin implementation."""
+
+from typing import Any, Dict, List, Optional, Set, cast
+
+from pydantic import Field
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+_QT_BUILD_PACKAGES: Dict[str, Set[str]] = {
+    "5": {"qt5-qmake", "qtbase5-dev"},
+    "6": {"qmake6", "qt6-base-dev"},
+}
+
+
+class QmakePluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the qmake plugin."""
+
+    qmake_parameters: List[str] = []
+    qmake_project_file: Optional[str]
+    qmake_qt_version: str = Field("5", regex=r"^(5|6)$")
+    qmake_config: List[str] = []
+    qmake_defines: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate qmake properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="qmake")
+        return cls(**plugin_data)
+
+
+class QmakePlugin(Plugin):
+    """The qmake plugin is useful for building qmake based parts.
+
+    These are projects that have a .pro file that drives the build. The
+    plugin generates the Makefile out of the source tree, then builds and
+    installs the project. The compiler and linker flags set in the build
+    environment are passed to qmake.
+
+    This plugin uses the common plugin keywords as well as those for "sources".
+    For more information check the 'plugins' topic for the former and the
+    'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - qmake-parameters
+          (list of strings)
+          Additional parameters to pass to qmake invocations.
+
+        - qmake-project-file
+          (string)
+          The qmake project file to use, relative to the source directory.
+          Defaults to the project file found in the source directory.
+
+        - qmake-qt-version
+          (string)
+          The major version of Qt to build with, ``5`` or ``6``. Selects the
+          Qt development packages installed and the qmake binary used.
+          Defaults to ``5``.
+
+        - qmake-config
+          (list of strings)
+          Values to add to the qmake ``CONFIG`` variable, e.g. ``release``.
+
+        - qmake-defines
+          (list of strings)
+          Preprocessor macros to add to the qmake ``DEFINES`` variable, e.g.
+          ``APP_VERSION=1.0``.
+    """
+
+    properties_class = QmakePluginProperties
+
+    @property
+    def out_of_source_build(self) -> bool:
+        """Return whether the plugin performs out-of-source-tree builds."""
+        return True
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(QmakePluginProperties, self._options)
+        return {"g++", "make", *_QT_BUILD_PACKAGES[options.qmake_qt_version]}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        options = cast(QmakePluginProperties, self._options)
+        if options.qmake_qt_version == "5":
+            # Select Qt 5 if qtchooser is installed.
+            return {"QT_SELECT": "qt5"}
+
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(QmakePluginProperties, self._options)
+
+        qmake_cmd = [
+            "qmake6" if options.qmake_qt_version == "6" else "qmake",
+            'QMAKE_CFLAGS+="${CFLAGS:-}"',
+            'QMAKE_CXXFLAGS+="${CXXFLAGS:-}"',
+            'QMAKE_LFLAGS+="${LDFLAGS:-}"',
+            *[f'CONFIG+="{value}"' for value in options.qmake_config],
+            *[f'DEFINES+="{value}"' for value in options.qmake_defines],
+            *options.qmake_parameters,
+        ]
+
+        if options.qmake_project_file:
+            qmake_cmd.append(
+                f'"{self._part_info.part_src_subdir / options.qmake_project_file}"'
+            )
+        else:
+            qmake_cmd.append(f'"{self._part_info.part_src_subdir}"')
+
+        jobs = self._part_info.parallel_build_count
+
+        return [
+            " ".join(qmake_cmd),
+            f'env -u CFLAGS -u CXXFLAGS make -j"{jobs}"',
+            f'make install INSTALL_ROOT="{self._part_info.part_install_dir}"',
+        ]
@@ -0,0 +1,110 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The rebar3 plugin implementation."""
+
+from typing import Any, Dict, List, Optional, Set, cast
+
+from pydantic import Field
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class Rebar3PluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the rebar3 plugin."""
+
+    rebar3_target: str = Field("release", regex=r"^(release|escriptize)$")
+    rebar3_profile: str = "prod"
+    rebar3_hex_mirror: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate rebar3 properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="rebar3")
+        return cls(**plugin_data)
+
+
+class Rebar3Plugin(Plugin):
+    """A plugin for erlang projects built with rebar3.
+
+    The rebar3 plugin assembles a release, or builds an escript, using the
+    selected profile. Releases are copied to the part install directory and
+    escripts to its ``bin`` directory.
+
+    The rebar3 plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - rebar3-target
+          (string)
+          What to build, ``release`` or ``escriptize``. Defaults to
+          ``release``.
+
+        - rebar3-profile
+          (string)
+          The rebar3 profile to build with. Defaults to ``prod``.
+
+        - rebar3-hex-mirror
+          (string)
+          The URL of a hex package mirror to download dependencies from.
+    """
+
+    properties_class = Rebar3PluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"erlang", "git", "rebar3"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        options = cast(Rebar3PluginProperties, self._options)
+        if not options.rebar3_hex_mirror:
+            return {}
+
+        return {"HEX_CDN": options.rebar3_hex_mirror}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(Rebar3PluginProperties, self._options)
+        profile = options.rebar3_profile
+        install_dir = self._part_info.part_install_dir
+
+        if options.rebar3_target == "escriptize":
+            return [
+                f'rebar3 as "{profile}" escriptize',
+                f'mkdir -p "{install_dir}/bin"',
+                f'cp --archive "_build/{profile}/bin/." "{install_dir}/bin"',
+            ]
+
+        return [
+            f'rebar3 as "{profile}" release',
+            f'mkdir -p "{install_dir}"',
+            f'cp --archive "_build/{profile}/rel/." "{install_dir}"',
+        ]
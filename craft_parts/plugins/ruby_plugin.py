@@ -0,0 +1,115 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The ruby plugin implementation."""
+
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class RubyPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the ruby plugin."""
+
+    ruby_without: List[str] = ["development", "test"]
+    ruby_app_dir: str = "app"
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate ruby properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="ruby")
+        return cls(**plugin_data)
+
+
+class RubyPlugin(Plugin):
+    """A plugin for ruby applications managed with bundler.
+
+    The ruby plugin copies the application to the part install directory
+    and installs the gems locked in Gemfile.lock in its ``vendor/bundle``
+    directory, in deployment mode. The build fails if Gemfile.lock is
+    missing or out of date. Packages needed to build the most common native
+    extensions are installed in the build environment.
+
+    The ruby plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - ruby-without
+          (list of strings)
+          Gem groups that should not be installed. Defaults to
+          ``development`` and ``test``.
+
+        - ruby-app-dir
+          (string)
+          The directory to install the application to, relative to the part
+          install directory. Defaults to ``app``.
+    """
+
+    properties_class = RubyPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {
+            "bundler",
+            "g++",
+            "gcc",
+            "libffi-dev",
+            "libssl-dev",
+            "libyaml-dev",
+            "make",
+            "pkg-config",
+            "ruby",
+            "ruby-dev",
+            "zlib1g-dev",
+        }
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(RubyPluginProperties, self._options)
+        app_dir = self._part_info.part_install_dir / options.ruby_app_dir
+
+        build_commands = [
+            f'mkdir -p "{app_dir}"',
+            f'cp --archive . "{app_dir}"',
+            f'cd "{app_dir}"',
+            "bundle config set --local deployment true",
+        ]
+        if options.ruby_without:
+            without = ":".join(options.ruby_without)
+            build_commands.append(f'bundle config set --local without "{without}"')
+        build_commands.append(
+            f'bundle install -j "{self._part_info.parallel_build_count}"'
+        )
+
+        return build_commands
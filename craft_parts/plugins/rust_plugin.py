@@ -0,0 +1,333 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The rust plugin implementation."""
+
+import logging
+import os
+import re
+import subprocess
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from xdg import BaseDirectory  # type: ignore
+
+from craft_parts import compiler_cache, errors
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .properties import PluginProperties
+
+logger = logging.getLogger(__name__)
+
+_RUSTUP_DIST_URL = "https://static.rust-lang.org/rustup/dist"
+
+# Map Debian architecture names to the corresponding rust host triples.
+_RUST_HOSTS: Dict[str, str] = {
+    "amd64": "x86_64-unknown-linux-gnu",
+    "arm64": "aarch64-unknown-linux-gnu",
+    "armhf": "armv7-unknown-linux-gnueabihf",
+    "i386": "i686-unknown-linux-gnu",
+    "ppc64el": "powerpc64le-unknown-linux-gnu",
+    "riscv64": "riscv64gc-unknown-linux-gnu",
+    "s390x": "s390x-unknown-linux-gnu",
+}
+
+
+class RustPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the rust plugin."""
+
+    rust_path: List[str] = ["."]
+    rust_packages: List[str] = []
+    rust_features: List[str] = []
+    rust_no_default_features: bool = False
+    rust_channel: Optional[str]
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate rust properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="rust")
+        return cls(**plugin_data)
+
+
+class RustPlugin(Plugin):
+    """A plugin for rust projects built with cargo.
+
+    If the project pins a toolchain in a ``rust-toolchain.toml`` (or legacy
+    ``rust-toolchain``) file, or the ``rust-channel`` option is set, the exact
+    toolchain is installed using rustup in a cache shared by all projects.
+    Otherwise, if cargo is not available in the build environment, the rust
+    toolchain is installed using rustup. The rustup installer is verified
+    using the digest published by the rust project.
+
+    The rust plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - rust-path
+          (list of strings)
+          Paths to the crates to install, relative to the part source.
+          Defaults to the source root.
+
+        - rust-packages
+          (list of strings)
+          Names of workspace member packages to install. If set, the crates
+          are located in the workspace and ``rust-path`` is not used.
+
+        - rust-features
+          (list of strings)
+          Features to enable when building the crates. Default is to build
+          with the default features only.
+
+        - rust-no-default-features
+          (boolean)
+          Do not enable the default features of the crates. Defaults to false.
+
+        - rust-channel
+          (string)
+          The rust toolchain to install, e.g. ``stable`` or ``1.75.0``.
+          Overrides the toolchain pinned in the project. Default is to use
+          the toolchain pinned in the project, if any.
+
+    If the compiler cache is enabled in the project, rust compilations are
+    cached using sccache.
+    """
+
+    properties_class = RustPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        packages = {"curl", "gcc", "git", "pkg-config"}
+        if compiler_cache.is_enabled(self._part_info):
+            packages.add("sccache")
+        return packages
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        env = compiler_cache.get_sccache_environment(self._part_info)
+
+        channel = self._get_channel()
+        if not channel:
+            env["PATH"] = "${HOME}/.cargo/bin:${PATH}"
+            return env
+
+        cache_dir = self._get_cache_dir()
+        env.update(
+            {
+                "RUSTUP_HOME": f"{cache_dir}/rustup",
+                "CARGO_HOME": f"{cache_dir}/cargo",
+                "RUSTUP_TOOLCHAIN": channel,
+                "PATH": f"{cache_dir}/cargo/bin:${{PATH}}",
+            }
+        )
+        return env
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        channel = self._get_channel()
+        if not channel:
+            return {}
+
+        assets = {"rust-channel": channel}
+
+        # Resolve channels such as "stable" to the installed rustc version.
+        cache_dir = self._get_cache_dir()
+        try:
+            proc = subprocess.run(
+                [f"{cache_dir}/cargo/bin/rustc", f"+{channel}", "--version"],
+                check=True,
+                stdout=subprocess.PIPE,
+                env={**os.environ, "RUSTUP_HOME": f"{cache_dir}/rustup"},
+            )
+            assets["rust-version"] = proc.stdout.decode().strip()
+        except (OSError, subprocess.CalledProcessError) as err:
+            logger.debug("cannot obtain rustc version: %s", err)
+
+        return assets
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "rustc": "rustc --version",
+            "cargo": "cargo --version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        return [
+            *self._get_rustup_commands(),
+            *[self._get_install_command(path) for path in self._get_crate_paths()],
+        ]
+
+    def _get_rustup_commands(self) -> List[str]:
+        host_arch = self._part_info.host_arch
+        host = _RUST_HOSTS.get(host_arch, f"{host_arch}-unknown-linux-gnu")
+        url = f"{_RUSTUP_DIST_URL}/{host}/rustup-init"
+        rustup_init = f"{self._get_cache_dir()}/rustup-init-{host}"
+        download = get_download_command(
+            url, rustup_init, checksums_url=f"{url}.sha256"
+        )
+        rustup_install = (
+            f'{download} && chmod +x "{rustup_init}" && '
+            f'"{rustup_init}" -y --no-modify-path --profile=minimal'
+        )
+
+        channel = self._get_channel()
+        if not channel:
+            return [
+                "if ! command -v cargo > /dev/null 2>&1; then "
+                f"{rustup_install}; fi"
+            ]
+
+        rustup = f"{self._get_cache_dir()}/cargo/bin/rustup"
+        return [
+            f'if [ ! -x "{rustup}" ]; then '
+            f"{rustup_install} --default-toolchain none; fi",
+            f'rustup toolchain install "{channel}" --profile minimal',
+        ]
+
+    def _get_channel(self) -> Optional[str]:
+        """Obtain the pinned rust toolchain channel, if any."""
+        options = cast(RustPluginProperties, self._options)
+        if options.rust_channel:
+            return options.rust_channel
+
+        build_dir = self._part_info.part_build_subdir
+
+        toolchain_file = build_dir / "rust-toolchain.toml"
+        if toolchain_file.is_file():
+            return _get_toml_value(toolchain_file, section="toolchain", key="channel")
+
+        # The legacy toolchain file may contain only the channel name.
+        toolchain_file = build_dir / "rust-toolchain"
+        if toolchain_file.is_file():
+            content = toolchain_file.read_text().strip()
+            if not content.startswith("["):
+                return content or None
+            return _get_toml_value(toolchain_file, section="toolchain", key="channel")
+
+        return None
+
+    def _get_cache_dir(self) -> Path:
+        return Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "rust"
+            )
+        )
+
+    def _get_install_command(self, path: str) -> str:
+        options = cast(RustPluginProperties, self._options)
+
+        cmd = [
+            "cargo install",
+            "--locked",
+            f'-j "{self._part_info.parallel_build_count}"',
+            f'--path "{path}"',
+            f'--root "{self._part_info.part_install_dir}"',
+            "--force",
+        ]
+        if options.rust_no_default_features:
+            cmd.append("--no-default-features")
+        if options.rust_features:
+            cmd.append(f"--features \"{','.join(options.rust_features)}\"")
+
+        return " ".join(cmd)
+
+    def _get_crate_paths(self) -> List[str]:
+        """Obtain the paths of crates to install.
+
+        :raise PluginEnvironmentValidationError: If a package is not found in
+            the workspace.
+        """
+        options = cast(RustPluginProperties, self._options)
+        if not options.rust_packages:
+            return options.rust_path
+
+        workspace = _find_packages(self._part_info.part_build_subdir)
+
+        paths: List[str] = []
+        for package in options.rust_packages:
+            if package not in workspace:
+                raise errors.PluginEnvironmentValidationError(
+                    part_name=self._part_info.part_name,
+                    reason=f"package {package!r} not found in the cargo workspace",
+                )
+            paths.append(workspace[package])
+
+        return paths
+
+
+def _find_packages(top_dir: Path) -> Dict[str, str]:
+    """Find the cargo packages under the given directory.
+
+    :param top_dir: The directory to scan.
+
+    :return: A dictionary mapping package names to paths relative to the
+        top directory.
+    """
+    packages: Dict[str, str] = {}
+
+    for root, dirs, files in os.walk(top_dir):
+        dirs[:] = [d for d in dirs if not d.startswith(".") and d != "target"]
+        if "Cargo.toml" not in files:
+            continue
+
+        name = _get_toml_value(Path(root, "Cargo.toml"), section="package", key="name")
+        if name:
+            packages[name] = Path(root).relative_to(top_dir).as_posix()
+
+    return packages
+
+
+def _get_toml_value(path: Path, *, section: str, key: str) -> Optional[str]:
+    """Obtain a string value from a section in a TOML file, if defined.
+
+    :param path: The TOML file to read.
+    :param section: The name of the table containing the key.
+    :param key: The key to read.
+
+    :return: The string value, or None if not defined.
+    """
+    current_section = ""
+    for line in path.read_text().splitlines():
+        line = line.strip()
+        if line.startswith("["):
+            current_section = line
+            continue
+
+        if current_section == f"[{section}]":
+            match = re.match(rf"{key}\s*=\s*[\"']([^\"']+)[\"']", line)
+            if match:
+                return match.group(1)
+
+    return None
@@ -0,0 +1,120 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The scons plugin implementation."""
+
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class SConsPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the scons plugin."""
+
+    scons_parameters: List[str] = []
+    scons_variables: Dict[str, str] = {}
+    scons_targets: List[str] = []
+    scons_install_target: str = "install"
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate scons properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="scons")
+        return cls(**plugin_data)
+
+
+class SConsPlugin(Plugin):
+    """A plugin useful for building SCons-based parts.
+
+    SCons-based projects are projects that have a SConstruct file that
+    drives the build.
+
+    This plugin runs 'scons' to build the default or selected targets,
+    followed by 'scons install' with the ``DESTDIR`` build variable set to
+    the part install directory. The project's SConstruct file is expected
+    to honour ``DESTDIR`` when installing.
+
+    This plugin uses the common plugin keywords as well as those for "sources".
+    For more information check the 'plugins' topic for the former and the
+    'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - scons-parameters
+          (list of strings)
+          Pass the given parameters to the scons command.
+
+        - scons-variables
+          (dictionary of strings)
+          Build variables to pass to the scons command, e.g. ``PREFIX: /usr``.
+
+        - scons-targets
+          (list of strings)
+          The targets to build. Defaults to the project's default targets.
+
+        - scons-install-target
+          (string)
+          The target that installs the project. Defaults to ``install``. If
+          empty, the install step is skipped.
+    """
+
+    properties_class = SConsPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"gcc", "g++", "scons"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return dict()
+
+    def _get_scons_command(self, *targets: str, **variables: str) -> str:
+        options = cast(SConsPluginProperties, self._options)
+
+        cmd = ["scons", f'-j"{self._part_info.parallel_build_count}"']
+        cmd.extend(options.scons_parameters)
+        for key, value in {**options.scons_variables, **variables}.items():
+            cmd.append(f'{key}="{value}"')
+        cmd.extend(targets)
+
+        return " ".join(cmd)
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(SConsPluginProperties, self._options)
+
+        build_commands = [self._get_scons_command(*options.scons_targets)]
+        if options.scons_install_target:
+            build_commands.append(
+                self._get_scons_command(
+                    options.scons_install_target,
+                    DESTDIR=str(self._part_info.part_install_dir),
+                )
+            )
+
+        return build_commands
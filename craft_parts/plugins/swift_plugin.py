@@ -0,0 +1,130 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The swift plugin implementation."""
+
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+
+
+class SwiftPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the swift plugin."""
+
+    swift_products: List[str] = []
+    swift_swiftc_flags: List[str] = []
+    swift_linker_flags: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate swift properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="swift")
+        return cls(**plugin_data)
+
+
+class SwiftPlugin(Plugin):
+    """A plugin for Swift Package Manager projects.
+
+    The swift plugin builds the package in release mode and copies the
+    executable products and resource bundles to the ``bin`` directory in
+    the part install directory. The Swift toolchain must be available in
+    the build environment.
+
+    The swift plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - swift-products
+          (list of strings)
+          The products to build and install. Default is to build all
+          products and install all executables.
+
+        - swift-swiftc-flags
+          (list of strings)
+          Flags to pass to the swift compiler, as with ``-Xswiftc``.
+
+        - swift-linker-flags
+          (list of strings)
+          Flags to pass to the linker, as with ``-Xlinker``.
+    """
+
+    properties_class = SwiftPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"clang"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(SwiftPluginProperties, self._options)
+        bin_dir = self._part_info.part_install_dir / "bin"
+
+        if options.swift_products:
+            build_commands = [
+                self._get_build_command(f'--product "{product}"')
+                for product in options.swift_products
+            ]
+            copy_commands = [
+                f'cp --archive "${{SWIFT_BIN_PATH}}/{product}" "{bin_dir}"'
+                for product in options.swift_products
+            ]
+        else:
+            build_commands = [self._get_build_command()]
+            copy_commands = [
+                'find "${SWIFT_BIN_PATH}" -maxdepth 1 -type f -executable '
+                f'-exec cp --archive {{}} "{bin_dir}" \\;',
+            ]
+
+        return [
+            *build_commands,
+            'SWIFT_BIN_PATH="$(swift build -c release --show-bin-path)"',
+            f'mkdir -p "{bin_dir}"',
+            *copy_commands,
+            'find "${SWIFT_BIN_PATH}" -maxdepth 1 -name "*.resources" '
+            f'-exec cp --archive {{}} "{bin_dir}" \\;',
+        ]
+
+    def _get_build_command(self, *args: str) -> str:
+        options = cast(SwiftPluginProperties, self._options)
+
+        cmd = [
+            "swift build",
+            "-c release",
+            f'-j "{self._part_info.parallel_build_count}"',
+            *args,
+        ]
+        cmd.extend(f'-Xswiftc "{flag}"' for flag in options.swift_swiftc_flags)
+        cmd.extend(f'-Xlinker "{flag}"' for flag in options.swift_linker_flags)
+
+        return " ".join(cmd)
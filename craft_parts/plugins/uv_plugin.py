@@ -0,0 +1,116 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The uv plugin implementation."""
+
+from typing import Any, Dict, List, Set, cast
+
+from .base import Plugin, PluginModel, extract_plugin_properties
+from .properties import PluginProperties
+from .python_plugin import get_venv_fixup_commands
+
+
+class UvPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the uv plugin."""
+
+    uv_extras: List[str] = []
+    uv_groups: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate uv properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="uv")
+        return cls(**plugin_data)
+
+
+class UvPlugin(Plugin):
+    """A plugin to build python projects using uv.
+
+    The uv plugin creates a virtual environment in the part install
+    directory. If the project contains a uv.lock file, the environment is
+    synchronized with the locked dependencies, and the build fails if the
+    lock file is out of date. Otherwise, the project is installed with
+    ``uv pip install``.
+
+    The uv tool must be available in the build environment, e.g. by adding
+    the ``astral-uv`` snap to ``build-snaps``.
+
+    The uv plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - uv-extras
+          (list of strings)
+          Optional dependencies (extras) to install.
+
+        - uv-groups
+          (list of strings)
+          Dependency groups to install when synchronizing from uv.lock.
+          Development dependencies are not installed.
+    """
+
+    properties_class = UvPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        return {"findutils", "python3-dev", "python3-venv"}
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        return {
+            "PATH": f"{self._part_info.part_install_dir}/bin:${{PATH}}",
+            "PARTS_PYTHON_INTERPRETER": "python3",
+            "UV_PROJECT_ENVIRONMENT": str(self._part_info.part_install_dir),
+            "UV_PYTHON_DOWNLOADS": "never",
+            "UV_COMPILE_BYTECODE": "1",
+            "UV_LINK_MODE": "copy",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(UvPluginProperties, self._options)
+        install_dir = self._part_info.part_install_dir
+
+        sync_cmd = ["uv sync --locked --no-dev --no-editable"]
+        sync_cmd.extend(f"--extra {extra!r}" for extra in options.uv_extras)
+        sync_cmd.extend(f"--group {group!r}" for group in options.uv_groups)
+
+        project = "."
+        if options.uv_extras:
+            project = f"'.[{','.join(options.uv_extras)}]'"
+        pip_cmd = f'uv pip install --python "{install_dir}/bin/python" {project}'
+
+        return [
+            'uv venv --allow-existing --python "${PARTS_PYTHON_INTERPRETER}" '
+            f'"{install_dir}"',
+            f'PARTS_PYTHON_VENV_INTERP_PATH="{install_dir}/bin/'
+            f'${{PARTS_PYTHON_INTERPRETER}}"',
+            f"if [ -f uv.lock ]; then {' '.join(sync_cmd)}; else {pip_cmd}; fi",
+            *get_venv_fixup_commands(install_dir),
+        ]
@@ -0,0 +1,112 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Validation of the environment a plugin builds parts in."""
+
+import subprocess
+import tempfile
+from typing import List, Optional
+
+from craft_parts import errors
+
+from .properties import PluginProperties
+
+# Exit status of shell commands that are not found.
+_COMMAND_NOT_FOUND = 127
+
+
+class PluginEnvironmentValidator:
+    """Check the build environment before a part is built.
+
+    Plugins requiring tools that may be missing from the build environment
+    subclass the validator and set it as their ``validator_class``, so that
+    missing tools are reported before the build starts.
+
+    :param part_name: The name of the part being validated.
+    :param env: The build environment script.
+    :param properties: The plugin properties of the part.
+    """
+
+    def __init__(
+        self, *, part_name: str, env: str, properties: PluginProperties
+    ) -> None:
+        self._part_name = part_name
+        self._env = env
+        self._options = properties
+
+    @property
+    def part_name(self) -> str:
+        """Return the name of the part being validated."""
+        return self._part_name
+
+    def validate_environment(self, *, part_dependencies: Optional[List[str]] = None):
+        """Ensure the environment contains the dependencies needed by the plugin.
+
+        :param part_dependencies: The names of the parts the validated part
+            depends on.
+
+        :raise PluginEnvironmentValidationError: If the environment is invalid.
+        """
+
+    def validate_dependency(self, command: str, *, argument: str = "--version") -> str:
+        """Ensure a command is available and runs in the build environment.
+
+        :param command: The command to verify.
+        :param argument: The argument used to run the command.
+
+        :return: The command output.
+
+        :raise PluginEnvironmentValidationError: If the command is not found
+            or fails.
+        """
+        try:
+            return self.execute(f"{command} {argument}").strip()
+        except subprocess.CalledProcessError as err:
+            if err.returncode == _COMMAND_NOT_FOUND:
+                reason = (
+                    f"{command!r} not found, add it to build-packages or "
+                    "build-snaps, or build it in a part listed in 'after'"
+                )
+            else:
+                reason = f"{command!r} failed with exit status {err.returncode}"
+
+            raise errors.PluginEnvironmentValidationError(
+                part_name=self._part_name, reason=reason
+            ) from err
+
+    def execute(self, cmd: str) -> str:
+        """Run a command in the build environment.
+
+        :param cmd: The command to run.
+
+        :return: The command output.
+
+        :raise subprocess.CalledProcessError: If the command fails.
+        """
+        with tempfile.NamedTemporaryFile(mode="w+") as env_file:
+            print(self._env, file=env_file)
+            print(cmd, file=env_file)
+            env_file.flush()
+
+            proc = subprocess.run(
+                ["/bin/bash", env_file.name],
+                check=True,
+                stdout=subprocess.PIPE,
+                stderr=subprocess.DEVNULL,
+                universal_newlines=True,
+            )
+
+        return proc.stdout
@@ -0,0 +1,225 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""The zig plugin implementation."""
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set, cast
+
+from pydantic import Field
+from xdg import BaseDirectory  # type: ignore
+
+from .base import (
+    Plugin,
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
+from .properties import PluginProperties
+
+# Map deb architectures to zig architecture names.
+_ZIG_ARCH: Dict[str, str] = {
+    "amd64": "x86_64",
+    "arm64": "aarch64",
+    "armhf": "arm",
+    "i386": "x86",
+    "ppc64el": "powerpc64le",
+    "riscv64": "riscv64",
+    "s390x": "s390x",
+}
+
+# Zig toolchain downloads use different names for some architectures.
+_ZIG_DOWNLOAD_ARCH: Dict[str, str] = {
+    "arm": "armv7a",
+}
+
+_ZIG_DOWNLOAD_URL = "https://ziglang.org/download"
+
+
+class ZigPluginProperties(PluginModel, PluginProperties):
+    """The part properties used by the zig plugin."""
+
+    zig_version: Optional[str]
+    zig_version_sha256: Optional[str]
+    zig_optimize: str = Field(
+        "ReleaseSafe", regex=r"^(Debug|ReleaseSafe|ReleaseFast|ReleaseSmall)$"
+    )
+    zig_target: Optional[str]
+    zig_prefix: str = ""
+    zig_parameters: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        """Populate zig properties from the part specification.
+
+        :param data: A dictionary containing part properties.
+
+        :return: The populated plugin properties data object.
+
+        :raise pydantic.ValidationError: If validation fails.
+        """
+        plugin_data = extract_plugin_properties(data, plugin_name="zig")
+        return cls(**plugin_data)
+
+
+class ZigPlugin(Plugin):
+    """A plugin for zig projects.
+
+    The zig plugin runs ``zig build install`` to build the project and
+    install its artifacts in the part install directory.
+
+    If ``zig-version`` is set, the zig toolchain is downloaded to a cache
+    shared by all projects. Otherwise zig must be available in the build
+    environment.
+
+    The zig plugin uses the common plugin keywords as well as those for
+    "sources". For more information check the 'plugins' topic for the former
+    and the 'sources' topic for the latter.
+
+    Additionally, this plugin uses the following plugin-specific keywords:
+
+        - zig-version
+          (string)
+          The zig toolchain version to download, e.g. ``0.13.0``.
+
+        - zig-version-sha256
+          (string)
+          The expected sha256 digest of the zig toolchain archive. Default is
+          to verify the archive using the digest listed in the ziglang.org
+          download index.
+
+        - zig-optimize
+          (string)
+          The optimization mode: ``Debug``, ``ReleaseSafe``, ``ReleaseFast``
+          or ``ReleaseSmall``. Defaults to ``ReleaseSafe``.
+
+        - zig-target
+          (string)
+          The target triple, e.g. ``aarch64-linux-gnu``. Defaults to the
+          target architecture when cross-compiling.
+
+        - zig-prefix
+          (string)
+          The installation prefix, relative to the part install directory.
+          Defaults to the part install directory.
+
+        - zig-parameters
+          (list of strings)
+          Additional parameters to pass to ``zig build``.
+    """
+
+    properties_class = ZigPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        """Return a set of required snaps to install in the build environment."""
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        """Return a set of required packages to install in the build environment."""
+        options = cast(ZigPluginProperties, self._options)
+        if options.zig_version:
+            return {"curl", "xz-utils"}
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        """Return a dictionary with the environment to use in the build step."""
+        toolchain_dir = self._get_toolchain_dir()
+        if not toolchain_dir:
+            return {}
+
+        return {"PATH": f"{toolchain_dir}:${{PATH}}"}
+
+    def get_build_assets(self) -> Dict[str, Any]:
+        """Return a dictionary of assets to record in the build state."""
+        options = cast(ZigPluginProperties, self._options)
+        if not options.zig_version:
+            return {}
+
+        return {"zig-version": options.zig_version}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        """Return the commands printing the versions of the plugin build tools."""
+        return {
+            "zig": "zig version",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        """Return a list of commands to run during the build step."""
+        options = cast(ZigPluginProperties, self._options)
+        prefix = self._part_info.part_install_dir / options.zig_prefix
+
+        cmd = [
+            "zig build install",
+            f'--prefix "{prefix}"',
+            f"-Doptimize={options.zig_optimize}",
+        ]
+
+        target = self._get_target()
+        if target:
+            cmd.append(f"-Dtarget={target}")
+
+        cmd.extend(options.zig_parameters)
+
+        return [*self._get_toolchain_commands(), " ".join(cmd)]
+
+    def _get_target(self) -> Optional[str]:
+        options = cast(ZigPluginProperties, self._options)
+        if options.zig_target:
+            return options.zig_target
+
+        if self._part_info.is_cross_compiling:
+            target_arch = self._part_info.target_arch
+            return f"{_ZIG_ARCH.get(target_arch, target_arch)}-linux-gnu"
+
+        return None
+
+    def _get_toolchain_commands(self) -> List[str]:
+        """Obtain the commands to download and unpack the zig toolchain."""
+        toolchain_dir = self._get_toolchain_dir()
+        if not toolchain_dir:
+            return []
+
+        options = cast(ZigPluginProperties, self._options)
+        tarball = f"{toolchain_dir}.tar.xz"
+        url = f"{_ZIG_DOWNLOAD_URL}/{options.zig_version}/{toolchain_dir.name}.tar.xz"
+
+        return [
+            get_download_command(
+                url,
+                tarball,
+                sha256=options.zig_version_sha256,
+                index_url=f"{_ZIG_DOWNLOAD_URL}/index.json",
+            ),
+            f'if [ ! -x "{toolchain_dir}/zig" ]; then '
+            f'mkdir -p "{toolchain_dir}" && '
+            f'tar -xJf "{tarball}" -C "{toolchain_dir}" --strip-components=1; fi',
+        ]
+
+    def _get_toolchain_dir(self) -> Optional[Path]:
+        """Obtain the cached location of the zig toolchain, if provisioned."""
+        options = cast(ZigPluginProperties, self._options)
+        if not options.zig_version:
+            return None
+
+        host_arch = _ZIG_ARCH.get(self._part_info.host_arch, self._part_info.host_arch)
+        host_arch = _ZIG_DOWNLOAD_ARCH.get(host_arch, host_arch)
+        cache_dir = Path(
+            BaseDirectory.save_cache_path(
+                self._part_info.application_name, "craft-parts", "zig"
+            )
+        )
+
+        return cache_dir / f"zig-linux-{host_arch}-{options.zig_version}"
@@ -0,0 +1,131 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Report the progress of downloads performed while executing actions.
+
+Sources, stage packages and stage snaps report download progress using a
+:class:`ProgressReporter`. Reports are sent to the handler set for the
+action being executed, which forwards them to the registered event handlers
+as :class:`craft_parts.events.DownloadProgress` events.
+"""
+
+import contextlib
+import enum
+import time
+from contextvars import ContextVar
+from typing import Callable, Iterator, Optional
+
+
+@enum.unique
+class DownloadKind(enum.Enum):
+    """The kind of item being downloaded."""
+
+    SOURCE = "source"
+    PACKAGE = "package"
+    SNAP = "snap"
+
+
+ProgressHandler = Callable[[DownloadKind, str, int, Optional[int]], None]
+
+_handler: ContextVar[Optional[ProgressHandler]] = ContextVar(
+    "progress_handler", default=None
+)
+
+
+@contextlib.contextmanager
+def progress_handler(handler: ProgressHandler) -> Iterator[None]:
+    """Set the function receiving progress reports in the current context.
+
+    :param handler: A function receiving the kind and name of the item being
+        downloaded, the number of bytes downloaded and the total number of
+        bytes to download, if known.
+    """
+    token = _handler.set(handler)
+    try:
+        yield
+    finally:
+        _handler.reset(token)
+
+
+class ProgressReporter:
+    """Report the progress of a download.
+
+    Progress is reported when the download starts and finishes, and at most
+    once per interval while it's running. If no progress handler is set,
+    nothing is reported.
+
+    :param kind: The kind of item being downloaded.
+    :param name: The name of the item being downloaded.
+    :param total: The number of bytes to download, if known.
+    :param interval: The minimum time in seconds between reports.
+    """
+
+    def __init__(
+        self,
+        kind: DownloadKind,
+        name: str,
+        *,
+        total: Optional[int] = None,
+        interval: float = 0.5,
+    ):
+        self._kind = kind
+        self._name = name
+        self._total = total
+        self._interval = interval
+        self._handler = _handler.get()
+        self._downloaded = 0
+        self._last_report: Optional[float] = None
+
+    @property
+    def downloaded(self) -> int:
+        """The number of bytes downloaded."""
+        return self._downloaded
+
+    def start(self) -> None:
+        """Report that the download started."""
+        self._report(time.monotonic())
+
+    def update(self, downloaded: int, total: Optional[int] = None) -> None:
+        """Set the number of bytes downloaded.
+
+        :param downloaded: The number of bytes downloaded so far.
+        :param total: The number of bytes to download, if it became known.
+        """
+        self._downloaded = downloaded
+        if total:
+            self._total = total
+
+        now = time.monotonic()
+        if self._last_report is None or now - self._last_report >= self._interval:
+            self._report(now)
+
+    def advance(self, size: int) -> None:
+        """Add to the number of bytes downloaded.
+
+        :param size: The number of bytes downloaded since the last update.
+        """
+        self.update(self._downloaded + size)
+
+    def finish(self) -> None:
+        """Report that the download finished."""
+        if self._total is None:
+            self._total = self._downloaded
+        self._report(time.monotonic())
+
+    def _report(self, now: float) -> None:
+        self._last_report = now
+        if self._handler:
+            self._handler(self._kind, self._name, self._downloaded, self._total)
@@ -0,0 +1,193 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""SLSA provenance generation.
+
+The provenance is an in-toto statement with a SLSA v1 predicate describing
+how the primed artifact was built: the builder, the definition of each
+part, the sources resolved when pulling and the fingerprint of each step
+that ran, as recorded in the part states.
+"""
+
+import hashlib
+import json
+from typing import Any, Dict, List, Optional
+
+from craft_parts import __version__
+from craft_parts.infos import ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.state_manager import states
+from craft_parts.step_cache import get_directory_digest
+from craft_parts.steps import Step
+
+STATEMENT_TYPE = "https://in-toto.io/Statement/v1"
+PREDICATE_TYPE = "https://slsa.dev/provenance/v1"
+BUILD_TYPE = "https://github.com/canonical/craft-parts/build/v1"
+DEFAULT_BUILDER_ID = "https://github.com/canonical/craft-parts"
+
+
+def generate_provenance(
+    part_list: List[Part],
+    *,
+    project_info: ProjectInfo,
+    subjects: Optional[Dict[str, str]] = None,
+    builder_id: str = DEFAULT_BUILDER_ID,
+) -> Dict[str, Any]:
+    """Create a SLSA provenance statement for the primed artifact.
+
+    :param part_list: The parts used to build the artifact.
+    :param project_info: The project information.
+    :param subjects: A dictionary mapping the names of the artifacts the
+        statement applies to, such as a package created from the prime
+        directory, to their digests in the ``<algorithm>/<digest>`` format.
+        If not specified, the subject is the prime directory, named after
+        the project.
+    :param builder_id: The identifier of the builder running the lifecycle.
+
+    :return: The statement, as a dictionary to be serialized to JSON.
+    """
+    if subjects is None:
+        name = project_info.project_name or project_info.application_name
+        subjects = {name: get_directory_digest(project_info.prime_dir)}
+
+    parts: Dict[str, Any] = {}
+    dependencies: List[Dict[str, Any]] = []
+    byproducts: List[Dict[str, Any]] = []
+
+    for part in part_list:
+        parts[part.name] = {
+            **part.spec.marshal(),
+            **part.plugin_properties.marshal(),
+        }
+
+        pull_state = states.load_state(part, Step.PULL)
+        if pull_state:
+            dependencies.extend(_get_resolved_dependencies(part, pull_state.assets))
+
+        for step in Step:
+            state = states.load_state(part, step)
+            if state:
+                byproducts.append(
+                    {
+                        "name": f"{part.name}:{step.name.lower()}",
+                        "digest": _get_digest(get_state_fingerprint(state)),
+                    }
+                )
+
+    return {
+        "_type": STATEMENT_TYPE,
+        "subject": [
+            {"name": name, "digest": _get_digest(digest)}
+            for name, digest in sorted(subjects.items())
+        ],
+        "predicateType": PREDICATE_TYPE,
+        "predicate": {
+            "buildDefinition": {
+                "buildType": BUILD_TYPE,
+                "externalParameters": {"parts": parts},
+                "internalParameters": {
+                    "application": project_info.application_name,
+                    "arch": project_info.target_arch,
+                    "project-vars": project_info.project_vars,
+                },
+                "resolvedDependencies": dependencies,
+            },
+            "runDetails": {
+                "builder": {
+                    "id": builder_id,
+                    "version": {"craft-parts": __version__},
+                },
+                "byproducts": byproducts,
+            },
+        },
+    }
+
+
+def get_state_fingerprint(state: states.StepState) -> str:
+    """Compute the fingerprint of a step execution from its state.
+
+    The state records the part properties, project options and assets,
+    such as resolved source commits, the step ran with, and the files it
+    produced.
+
+    :param state: The step state.
+
+    :return: The fingerprint, in the ``sha256/<digest>`` format.
+    """
+    data = json.dumps(state.marshal(), sort_keys=True, default=_serialize)
+    return f"sha256/{hashlib.sha256(data.encode()).hexdigest()}"
+
+
+def _serialize(obj: Any) -> Any:
+    if isinstance(obj, (set, frozenset)):
+        return sorted(obj)
+    return str(obj)
+
+
+def _get_resolved_dependencies(
+    part: Part, assets: Dict[str, Any]
+) -> List[Dict[str, Any]]:
+    """Obtain the sources and snaps resolved when pulling a part."""
+    dependencies: List[Dict[str, Any]] = []
+    annotations = {"part": part.name}
+
+    source = part.spec.source
+    details = assets.get("source-details") or {}
+    if isinstance(source, str):
+        dependencies.append(_get_source_dependency(source, details, annotations))
+    elif source:
+        for entry in source:
+            dependencies.append(
+                _get_source_dependency(
+                    entry.source, details.get(entry.source) or {}, annotations
+                )
+            )
+
+    for name, info in sorted((assets.get("stage-snaps") or {}).items()):
+        dependencies.append(
+            {
+                "uri": f"https://snapcraft.io/{name}",
+                "annotations": {
+                    **annotations,
+                    "revision": info.get("revision"),
+                    "snap-id": info.get("snap-id"),
+                },
+            }
+        )
+
+    return dependencies
+
+
+def _get_source_dependency(
+    source: str, details: Dict[str, Any], annotations: Dict[str, str]
+) -> Dict[str, Any]:
+    """Describe a pulled source as a resolved dependency."""
+    dependency: Dict[str, Any] = {"uri": source, "annotations": annotations}
+    if details.get("commit"):
+        dependency["digest"] = {"gitCommit": details["commit"]}
+    elif details.get("digest"):
+        dependency["digest"] = _get_digest(details["digest"])
+    return dependency
+
+
+def _get_digest(digest: str) -> Dict[str, str]:
+    """Convert a digest to an in-toto digest set.
+
+    Digests are in the ``<algorithm>/<digest>`` or ``<algorithm>:<digest>``
+    formats.
+    """
+    algorithm, _, value = digest.replace(":", "/", 1).partition("/")
+    return {algorithm: value}
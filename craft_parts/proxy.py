@@ -0,0 +1,160 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Proxy configuration used by lifecycle operations.
+
+The configured proxies are set in the environment of the craft-parts
+process while actions are executed, so that source downloads, version
+control and package manager commands use them, and are exported to the
+environment of every step.
+"""
+
+import contextlib
+import logging
+import os
+from dataclasses import dataclass
+from typing import Dict, Iterator, List, Mapping, Optional, Tuple
+from urllib.parse import urlsplit
+
+from craft_parts.utils import url_utils
+
+logger = logging.getLogger(__name__)
+
+_PROXY_VARIABLES = ("http_proxy", "https_proxy", "no_proxy")
+
+
+@dataclass(frozen=True)
+class ProxyConfig:
+    """The proxies used to access the network.
+
+    :param http_proxy: The proxy URL used for HTTP requests.
+    :param https_proxy: The proxy URL used for HTTPS requests.
+    :param no_proxy: The hosts and domains accessed without a proxy.
+    """
+
+    http_proxy: Optional[str] = None
+    https_proxy: Optional[str] = None
+    no_proxy: Tuple[str, ...] = ()
+
+    @classmethod
+    def from_environment(cls, env: Optional[Mapping[str, str]] = None) -> "ProxyConfig":
+        """Create a proxy configuration from environment variables.
+
+        Lowercase variable names take precedence over uppercase names.
+
+        :param env: The environment to read. Defaults to the current process
+            environment.
+
+        :return: The proxy configuration.
+        """
+        if env is None:
+            env = os.environ
+
+        def get(name: str) -> Optional[str]:
+            return env.get(name) or env.get(name.upper()) or None
+
+        no_proxy = get("no_proxy") or ""
+        return cls(
+            http_proxy=get("http_proxy"),
+            https_proxy=get("https_proxy"),
+            no_proxy=tuple(x.strip() for x in no_proxy.split(",") if x.strip()),
+        )
+
+    @property
+    def environment(self) -> Dict[str, str]:
+        """Return the environment variables defining the proxies.
+
+        Variables are defined in lowercase and uppercase, since tools differ
+        in which names they honor.
+        """
+        values = {
+            "http_proxy": self.http_proxy,
+            "https_proxy": self.https_proxy,
+            "no_proxy": ",".join(self.no_proxy),
+        }
+
+        env: Dict[str, str] = {}
+        for name, value in values.items():
+            if value:
+                env[name] = value
+                env[name.upper()] = value
+        return env
+
+    @property
+    def java_options(self) -> List[str]:
+        """Return the Java system property definitions for the proxies.
+
+        Java tools don't use the proxy environment variables, so plugins
+        building with them pass these definitions to the tool.
+        """
+        options: List[str] = []
+        for scheme, url in (("http", self.http_proxy), ("https", self.https_proxy)):
+            if not url:
+                continue
+            parsed = urlsplit(url if "://" in url else f"http://{url}")
+            if parsed.hostname:
+                options.append(f"-D{scheme}.proxyHost={parsed.hostname}")
+            if parsed.port:
+                options.append(f"-D{scheme}.proxyPort={parsed.port}")
+
+        if options and self.no_proxy:
+            hosts = "|".join(
+                f"*{host}" if host.startswith(".") else host for host in self.no_proxy
+            )
+            options.append(f"-Dhttp.nonProxyHosts={hosts}")
+        return options
+
+    def __str__(self) -> str:
+        proxies = [
+            f"{name}={url_utils.redact_credentials(value)}"
+            for name, value in (("http", self.http_proxy), ("https", self.https_proxy))
+            if value
+        ]
+        if self.no_proxy:
+            proxies.append(f"no_proxy={','.join(self.no_proxy)}")
+        return ", ".join(proxies) or "no proxies"
+
+
+@contextlib.contextmanager
+def proxy_environment(config: Optional[ProxyConfig]) -> Iterator[None]:
+    """Set the proxy variables in the process environment.
+
+    Proxy variables set in the environment are replaced by the configured
+    proxies, and restored when the context exits.
+
+    :param config: The proxy configuration. If not set, the environment is
+        not changed.
+    """
+    if config is None:
+        yield
+        return
+
+    names = [*_PROXY_VARIABLES, *(x.upper() for x in _PROXY_VARIABLES)]
+    saved = {name: os.environ.get(name) for name in names}
+
+    logger.debug("use proxies: %s", config)
+    for name in names:
+        os.environ.pop(name, None)
+    os.environ.update(config.environment)
+
+    try:
+        yield
+    finally:
+        for name, value in saved.items():
+            if value is None:
+                os.environ.pop(name, None)
+            else:
+                os.environ[name] = value
@@ -0,0 +1,117 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Remove the work files of parts that are no longer defined in the project."""
+
+import logging
+import os
+import shutil
+from pathlib import Path
+from typing import List, Set, Tuple
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part
+from craft_parts.state_manager import states
+from craft_parts.steps import Step
+
+logger = logging.getLogger(__name__)
+
+
+def get_removed_parts(part_list: List[Part], *, project_dirs: ProjectDirs) -> List[str]:
+    """Obtain the names of parts with work directories but no definition.
+
+    Only directories containing part states are considered, so unrelated
+    files in the parts directory are ignored.
+
+    :param part_list: The list of parts defined in the project.
+    :param project_dirs: The project's work directories.
+
+    :return: The sorted list of names of removed parts.
+    """
+    parts_dir = project_dirs.parts_dir
+    if not parts_dir.is_dir():
+        return []
+
+    part_names = {part.name for part in part_list}
+    return sorted(
+        entry.name
+        for entry in parts_dir.iterdir()
+        if entry.name not in part_names
+        and not entry.is_symlink()
+        and (entry / "state").is_dir()
+    )
+
+
+def prune_removed_parts(
+    part_list: List[Part], *, project_dirs: ProjectDirs
+) -> List[str]:
+    """Remove the work directories and migrated files of removed parts.
+
+    Files and directories staged or primed by a removed part are removed
+    from the stage and prime directories unless they were also migrated by
+    a part that is still defined. Directories are only removed if empty.
+
+    :param part_list: The list of parts defined in the project.
+    :param project_dirs: The project's work directories.
+
+    :return: The sorted list of names of pruned parts.
+    """
+    removed_parts = [
+        Part(name, {}, project_dirs=project_dirs)
+        for name in get_removed_parts(part_list, project_dirs=project_dirs)
+    ]
+
+    for step, migration_dir in [
+        (Step.PRIME, project_dirs.prime_dir),
+        (Step.STAGE, project_dirs.stage_dir),
+    ]:
+        files, dirs = _get_migrated(removed_parts, step)
+        kept_files, kept_dirs = _get_migrated(part_list, step)
+        _unmigrate(files - kept_files, dirs - kept_dirs, migration_dir=migration_dir)
+
+    for part in removed_parts:
+        logger.debug("prune removed part %s", part.name)
+        shutil.rmtree(project_dirs.parts_dir / part.name)
+
+    return [part.name for part in removed_parts]
+
+
+def _get_migrated(part_list: List[Part], step: Step) -> Tuple[Set[str], Set[str]]:
+    """Obtain the files and directories migrated by parts in the given step."""
+    files: Set[str] = set()
+    dirs: Set[str] = set()
+    for part in part_list:
+        state = states.load_state(part, step)
+        if state:
+            files |= state.files
+            dirs |= state.directories
+
+    return files, dirs
+
+
+def _unmigrate(files: Set[str], dirs: Set[str], *, migration_dir: Path) -> None:
+    """Remove migrated files and empty directories from a migration directory."""
+    for name in files:
+        path = migration_dir / name
+        if path.is_symlink() or path.is_file():
+            path.unlink()
+
+    # Remove nested directories first.
+    for name in sorted(dirs, reverse=True):
+        path = migration_dir / name
+        if path.is_dir() and not path.is_symlink() and not os.listdir(path):
+            path.rmdir()
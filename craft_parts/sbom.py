@@ -0,0 +1,484 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Software bill of materials generation.
+
+Components are gathered from the information craft-parts has about each
+primed part: the pulled sources and their commits or digests, the stage
+packages and snaps, and the dependencies resolved by plugins and recorded
+in lock files, such as go modules, python wheels and rust crates.
+"""
+
+import enum
+import json
+import logging
+import re
+import time
+import uuid
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from craft_parts import __version__, errors
+from craft_parts.infos import ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.state_manager import state_info, states
+from craft_parts.steps import Step
+from craft_parts.utils import os_utils
+
+logger = logging.getLogger(__name__)
+
+
+@enum.unique
+class SbomFormat(enum.Enum):
+    """The document formats of a software bill of materials."""
+
+    SPDX = "spdx"
+    CYCLONEDX = "cyclonedx"
+
+
+@dataclass(frozen=True)
+class Component:
+    """A component of the primed artifact.
+
+    :param name: The component name.
+    :param version: The component version, commit or digest, if known.
+    :param kind: The kind of component, such as ``source``, ``deb``,
+        ``snap``, ``go-module``, ``python-package`` or ``crate``.
+    :param part_name: The name of the part providing the component.
+    :param purl: The package URL identifying the component, if any.
+    :param download_location: The location the component was obtained from.
+    """
+
+    name: str
+    version: Optional[str]
+    kind: str
+    part_name: str
+    purl: Optional[str] = None
+    download_location: Optional[str] = None
+
+    def marshal(self) -> Dict[str, Any]:
+        """Create a dictionary containing the component data."""
+        return {
+            "name": self.name,
+            "version": self.version,
+            "kind": self.kind,
+            "part-name": self.part_name,
+            "purl": self.purl,
+            "download-location": self.download_location,
+        }
+
+
+def get_components(part: Part) -> List[Component]:
+    """Obtain the components provided by a part.
+
+    Parts that were not primed don't contribute to the primed artifact and
+    have no components.
+
+    :param part: The part to obtain components from.
+
+    :return: The list of components provided by the part.
+    """
+    if states.load_state(part, Step.PRIME) is None:
+        return []
+
+    pull_state = states.load_state(part, Step.PULL)
+    assets = pull_state.assets if pull_state else {}
+
+    components: List[Component] = []
+    components.extend(_get_source_components(part, assets.get("source-details")))
+    components.extend(_get_package_components(part))
+    components.extend(_get_snap_components(part, assets.get("stage-snaps")))
+    components.extend(_get_go_module_components(part))
+    components.extend(_get_python_package_components(part))
+    components.extend(_get_crate_components(part))
+    return components
+
+
+def generate_sbom(
+    components: List[Component],
+    *,
+    sbom_format: SbomFormat,
+    project_info: ProjectInfo,
+) -> Dict[str, Any]:
+    """Create a software bill of materials document.
+
+    The document describes an artifact named after the project, or after
+    the application if the project name is not set. If the project sets
+    ``SOURCE_DATE_EPOCH``, it's used as the document creation time, and
+    the document identifier is derived from its contents so that runs of
+    the same project produce identical documents.
+
+    :param components: The artifact components.
+    :param sbom_format: The document format.
+    :param project_info: The project information.
+
+    :return: The document, as a dictionary to be serialized to JSON.
+    """
+    name = project_info.project_name or project_info.application_name
+    version = project_info.project_vars.get("version")
+
+    epoch = project_info.source_date_epoch
+    created = time.strftime(
+        "%Y-%m-%dT%H:%M:%SZ", time.gmtime(time.time() if epoch is None else epoch)
+    )
+    if epoch is None:
+        document_id = uuid.uuid4()
+    else:
+        dump = json.dumps([x.marshal() for x in components], sort_keys=True)
+        document_id = uuid.uuid5(uuid.NAMESPACE_URL, f"{name}/{version}/{dump}")
+
+    if sbom_format == SbomFormat.CYCLONEDX:
+        return _get_cyclonedx_document(
+            components,
+            name=name,
+            version=version,
+            created=created,
+            document_id=document_id,
+        )
+
+    return _get_spdx_document(
+        components,
+        name=name,
+        version=version,
+        created=created,
+        document_id=document_id,
+    )
+
+
+def _get_spdx_document(
+    components: List[Component],
+    *,
+    name: str,
+    version: Optional[str],
+    created: str,
+    document_id: uuid.UUID,
+) -> Dict[str, Any]:
+    """Create an SPDX 2.3 document in JSON format."""
+    artifact: Dict[str, Any] = {
+        "SPDXID": "SPDXRef-Artifact",
+        "name": name,
+        "downloadLocation": "NOASSERTION",
+        "filesAnalyzed": False,
+        "primaryPackagePurpose": "APPLICATION",
+    }
+    if version:
+        artifact["versionInfo"] = version
+
+    packages = [artifact]
+    relationships = [
+        {
+            "spdxElementId": "SPDXRef-DOCUMENT",
+            "relationshipType": "DESCRIBES",
+            "relatedSpdxElement": "SPDXRef-Artifact",
+        }
+    ]
+
+    for index, component in enumerate(components, start=1):
+        spdx_id = f"SPDXRef-Package-{index}"
+        package: Dict[str, Any] = {
+            "SPDXID": spdx_id,
+            "name": component.name,
+            "downloadLocation": component.download_location or "NOASSERTION",
+            "filesAnalyzed": False,
+            "comment": f"{component.kind} provided by part {component.part_name!r}",
+        }
+        if component.version:
+            package["versionInfo"] = component.version
+        if component.purl:
+            package["externalRefs"] = [
+                {
+                    "referenceCategory": "PACKAGE-MANAGER",
+                    "referenceType": "purl",
+                    "referenceLocator": component.purl,
+                }
+            ]
+        packages.append(package)
+        relationships.append(
+            {
+                "spdxElementId": "SPDXRef-Artifact",
+                "relationshipType": "CONTAINS",
+                "relatedSpdxElement": spdx_id,
+            }
+        )
+
+    return {
+        "spdxVersion": "SPDX-2.3",
+        "dataLicense": "CC0-1.0",
+        "SPDXID": "SPDXRef-DOCUMENT",
+        "name": name,
+        "documentNamespace": f"https://spdx.org/spdxdocs/{name}-{document_id}",
+        "creationInfo": {
+            "created": created,
+            "creators": [f"Tool: craft-parts-{__version__}"],
+        },
+        "packages": packages,
+        "relationships": relationships,
+    }
+
+
+def _get_cyclonedx_document(
+    components: List[Component],
+    *,
+    name: str,
+    version: Optional[str],
+    created: str,
+    document_id: uuid.UUID,
+) -> Dict[str, Any]:
+    """Create a CycloneDX 1.4 document in JSON format."""
+    artifact: Dict[str, Any] = {"type": "application", "name": name}
+    if version:
+        artifact["version"] = version
+
+    entries: List[Dict[str, Any]] = []
+    for index, component in enumerate(components, start=1):
+        entry: Dict[str, Any] = {
+            "type": "application" if component.kind == "snap" else "library",
+            "bom-ref": f"component-{index}",
+            "name": component.name,
+        }
+        if component.version:
+            entry["version"] = component.version
+        if component.purl:
+            entry["purl"] = component.purl
+        if component.download_location:
+            entry["externalReferences"] = [
+                {"type": "distribution", "url": component.download_location}
+            ]
+        entry["properties"] = [
+            {"name": "craft-parts:kind", "value": component.kind},
+            {"name": "craft-parts:part", "value": component.part_name},
+        ]
+        entries.append(entry)
+
+    return {
+        "bomFormat": "CycloneDX",
+        "specVersion": "1.4",
+        "serialNumber": f"urn:uuid:{document_id}",
+        "version": 1,
+        "metadata": {
+            "timestamp": created,
+            "tools": [
+                {"vendor": "Canonical", "name": "craft-parts", "version": __version__}
+            ],
+            "component": artifact,
+        },
+        "components": entries,
+    }
+
+
+def _get_source_components(
+    part: Part, source_details: Optional[Dict[str, Any]]
+) -> List[Component]:
+    """Obtain the components corresponding to the part sources."""
+    source = part.spec.source
+    if not source:
+        return []
+
+    if isinstance(source, str):
+        return [
+            Component(
+                name=part.name,
+                version=_get_source_version(source_details),
+                kind="source",
+                part_name=part.name,
+                download_location=source,
+            )
+        ]
+
+    details = source_details or {}
+    return [
+        Component(
+            name=f"{part.name}/{entry.target}" if entry.target else part.name,
+            version=_get_source_version(details.get(entry.source)),
+            kind="source",
+            part_name=part.name,
+            download_location=entry.source,
+        )
+        for entry in source
+    ]
+
+
+def _get_source_version(details: Optional[Dict[str, Any]]) -> Optional[str]:
+    """Obtain the commit or digest of a pulled source."""
+    if not details:
+        return None
+    return details.get("commit") or details.get("digest")
+
+
+def _get_package_components(part: Part) -> List[Component]:
+    """Obtain the components corresponding to the part stage packages.
+
+    Versions are obtained from the names of the fetched package files or,
+    if the packages were not fetched, from versions pinned in the part.
+    """
+    if not part.spec.stage_packages:
+        return []
+
+    versions = state_info.get_stage_package_versions(part)
+
+    distro = _get_distro()
+    components: List[Component] = []
+    for package in part.spec.stage_packages:
+        name, _, pinned_version = package.partition("=")
+        name = name.split(":")[0]
+        version = versions.get(name) or pinned_version or None
+        purl = f"pkg:deb/{distro}/{name}"
+        if version:
+            purl += f"@{version}"
+        components.append(
+            Component(
+                name=name,
+                version=version,
+                kind="deb",
+                part_name=part.name,
+                purl=purl,
+            )
+        )
+    return components
+
+
+def _get_distro() -> str:
+    """Obtain the identifier of the distribution providing stage packages."""
+    try:
+        return os_utils.OsRelease().id()
+    except (errors.OsReleaseIdError, OSError):
+        return "debian"
+
+
+def _get_snap_components(
+    part: Part, revisions: Optional[Dict[str, Dict[str, str]]]
+) -> List[Component]:
+    """Obtain the components corresponding to the part stage snaps."""
+    if not revisions:
+        return []
+
+    return [
+        Component(
+            name=name,
+            version=str(info.get("revision")) if info.get("revision") else None,
+            kind="snap",
+            part_name=part.name,
+            download_location=f"https://snapcraft.io/{name}",
+        )
+        for name, info in sorted(revisions.items())
+    ]
+
+
+def _get_go_module_components(part: Part) -> List[Component]:
+    """Obtain the go modules required by the part.
+
+    Modules are listed in the vendored modules file if the part vendors its
+    dependencies, or in the checksum database file otherwise.
+    """
+    modules: Dict[str, str] = {}
+
+    modules_file = part.part_src_subdir / "vendor" / "modules.txt"
+    sum_file = part.part_src_subdir / "go.sum"
+    if modules_file.is_file():
+        for line in modules_file.read_text().splitlines():
+            fields = line.split()
+            if len(fields) >= 3 and fields[0] == "#":
+                modules[fields[1]] = fields[2]
+    elif sum_file.is_file():
+        for line in sum_file.read_text().splitlines():
+            fields = line.split()
+            if len(fields) == 3 and not fields[1].endswith("/go.mod"):
+                modules[fields[0]] = fields[1]
+
+    return [
+        Component(
+            name=module,
+            version=version,
+            kind="go-module",
+            part_name=part.name,
+            purl=f"pkg:golang/{module}@{version}",
+        )
+        for module, version in sorted(modules.items())
+    ]
+
+
+def _get_python_package_components(part: Part) -> List[Component]:
+    """Obtain the python packages installed by the part."""
+    if not part.part_install_dir.is_dir():
+        return []
+
+    packages: Dict[str, str] = {}
+    for metadata_file in part.part_install_dir.glob("**/*.dist-info/METADATA"):
+        metadata = _parse_metadata(metadata_file)
+        name = metadata.get("Name")
+        version = metadata.get("Version")
+        if name and version:
+            packages[name] = version
+
+    return [
+        Component(
+            name=name,
+            version=version,
+            kind="python-package",
+            part_name=part.name,
+            purl=f"pkg:pypi/{re.sub(r'[-_.]+', '-', name).lower()}@{version}",
+        )
+        for name, version in sorted(packages.items())
+    ]
+
+
+def _parse_metadata(metadata_file: Path) -> Dict[str, str]:
+    """Read the header fields of a python package metadata file."""
+    metadata: Dict[str, str] = {}
+    with open(metadata_file, errors="replace") as metadata_data:
+        for line in metadata_data:
+            if not line.strip():
+                break
+            key, sep, value = line.partition(":")
+            if sep and not key.startswith((" ", "\t")):
+                metadata.setdefault(key, value.strip())
+    return metadata
+
+
+def _get_crate_components(part: Part) -> List[Component]:
+    """Obtain the rust crates locked by the part.
+
+    Crates that are not obtained from a registry, such as the crates built
+    by the part itself, are not listed.
+    """
+    lock_file = part.part_src_subdir / "Cargo.lock"
+    if not lock_file.is_file():
+        return []
+
+    crates: List[Dict[str, str]] = []
+    for line in lock_file.read_text().splitlines():
+        line = line.strip()
+        if line == "[[package]]":
+            crates.append({})
+            continue
+        match = re.match(r'^(name|version|source)\s*=\s*"(.*)"$', line)
+        if match and crates:
+            crates[-1][match.group(1)] = match.group(2)
+
+    return [
+        Component(
+            name=crate["name"],
+            version=crate["version"],
+            kind="crate",
+            part_name=part.name,
+            purl=f"pkg:cargo/{crate['name']}@{crate['version']}",
+        )
+        for crate in crates
+        if "name" in crate
+        and "version" in crate
+        and crate.get("source", "").startswith("registry+")
+    ]
@@ -0,0 +1,102 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""JSON Schema generation for part specifications."""
+
+import copy
+from typing import Any, Dict
+
+from pydantic import BaseModel
+
+from craft_parts import plugins
+from craft_parts.parts import PartSpec
+from craft_parts.plugins.plugins import PluginType
+
+_JSON_SCHEMA_VERSION = "http://json-schema.org/draft-07/schema#"
+
+
+def export() -> Dict[str, Any]:
+    """Obtain the JSON Schema of a part specification.
+
+    The schema validates a single part: the common part properties and the
+    properties of the plugin it uses. Each registered plugin is described
+    by a separate definition, selected by the value of the ``plugin``
+    property, so the plugin must be explicitly set in parts validated
+    against this schema. Plugins registered after the schema is exported
+    are not included.
+
+    :return: The JSON Schema as a dictionary.
+    """
+    part_schema = PartSpec.schema(by_alias=True)
+    definitions: Dict[str, Any] = part_schema.pop("definitions", {})
+
+    plugin_refs = []
+    for plugin_name, plugin_class in sorted(plugins.get_registered_plugins().items()):
+        definition_name = f"{plugin_name}-part"
+        definitions[definition_name] = _get_plugin_part_schema(
+            plugin_name,
+            plugin_class=plugin_class,
+            part_schema=part_schema,
+            definitions=definitions,
+        )
+        plugin_refs.append({"$ref": f"#/definitions/{definition_name}"})
+
+    return {
+        "$schema": _JSON_SCHEMA_VERSION,
+        "title": "Part",
+        "description": part_schema.get("description", ""),
+        "type": "object",
+        "required": ["plugin"],
+        "oneOf": plugin_refs,
+        "definitions": definitions,
+    }
+
+
+def _get_plugin_part_schema(
+    plugin_name: str,
+    *,
+    plugin_class: PluginType,
+    part_schema: Dict[str, Any],
+    definitions: Dict[str, Any],
+) -> Dict[str, Any]:
+    """Obtain the schema of a part using the given plugin.
+
+    Definitions used by the plugin properties schema are added to the
+    shared definitions.
+    """
+    properties = copy.deepcopy(part_schema["properties"])
+    properties["plugin"] = {"title": "Plugin", "const": plugin_name}
+    required = ["plugin", *part_schema.get("required", [])]
+
+    # Plugin properties that don't use pydantic validation can't be described.
+    properties_class = plugin_class.properties_class
+    if issubclass(properties_class, BaseModel):
+        plugin_schema = properties_class.schema(by_alias=True)
+        definitions.update(plugin_schema.get("definitions", {}))
+        properties.update(plugin_schema.get("properties", {}))
+        required.extend(plugin_schema.get("required", []))
+
+    schema = {
+        "title": f"{plugin_name} part",
+        "type": "object",
+        "properties": properties,
+        "required": required,
+        "additionalProperties": False,
+    }
+    if plugin_class.__doc__:
+        schema["description"] = plugin_class.__doc__.splitlines()[0]
+
+    return schema
@@ -0,0 +1,107 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Build secrets referenced in the build environment of parts.
+
+Values in the ``build-environment`` of a part can reference secrets using
+``$(HOST_SECRET:<name>)``. References are written to step scripts as
+variables set only in the environment of the step processes, so secret
+values are never written to scripts, part states or step fingerprints,
+and are redacted from the output of step commands.
+"""
+
+import re
+from typing import Dict, Iterable, List
+
+from craft_parts import callbacks, errors
+from craft_parts.parts import Part
+
+_SECRET_REFERENCE = re.compile(r"\$\(HOST_SECRET:([A-Za-z0-9_.-]+)\)")
+
+REDACTED = "*****"
+
+
+def get_secret_names(part: Part) -> List[str]:
+    """Obtain the names of the secrets referenced by a part.
+
+    :param part: The part to obtain secret names from.
+
+    :return: The sorted list of secret names.
+    """
+    names = set()
+    for env in part.spec.build_environment or []:
+        for value in env.values():
+            names.update(_SECRET_REFERENCE.findall(value))
+    return sorted(names)
+
+
+def resolve_secrets(part: Part) -> Dict[str, str]:
+    """Obtain the values of the secrets referenced by a part.
+
+    :param part: The part referencing the secrets.
+
+    :return: A dictionary mapping secret names to their values.
+
+    :raise SecretNotFound: If a secret is not available.
+    """
+    secrets: Dict[str, str] = {}
+    for name in get_secret_names(part):
+        value = callbacks.get_secret(name)
+        if value is None:
+            raise errors.SecretNotFound(part_name=part.name, secret_name=name)
+        secrets[name] = value
+    return secrets
+
+
+def get_secret_variable(name: str) -> str:
+    """Obtain the name of the environment variable holding a secret."""
+    return "CRAFT_SECRET_" + re.sub(r"[^A-Za-z0-9]", "_", name).upper()
+
+
+def get_secret_environment(secrets: Dict[str, str]) -> Dict[str, str]:
+    """Obtain the variables to set in the environment of step processes.
+
+    :param secrets: A dictionary mapping secret names to their values.
+
+    :return: A dictionary mapping variable names to secret values.
+    """
+    return {get_secret_variable(name): value for name, value in secrets.items()}
+
+
+def replace_references(value: str) -> str:
+    """Replace secret references with the variables holding the secrets.
+
+    :param value: A build environment value.
+
+    :return: The value with references replaced by variable expansions.
+    """
+    return _SECRET_REFERENCE.sub(
+        lambda match: "${" + get_secret_variable(match.group(1)) + "}", value
+    )
+
+
+def redact(text: str, values: Iterable[str]) -> str:
+    """Replace secret values in a text.
+
+    :param text: The text to redact.
+    :param values: The secret values.
+
+    :return: The text with secret values replaced.
+    """
+    for value in sorted(values, key=len, reverse=True):
+        if value:
+            text = text.replace(value, REDACTED)
+    return text
@@ -19,11 +19,12 @@
 import logging
 from typing import Dict, List, Optional, Sequence
 
-from craft_parts import parts, steps
+from craft_parts import errors, parts, steps
 from craft_parts.actions import Action, ActionType
 from craft_parts.infos import ProjectInfo
 from craft_parts.parts import Part, part_list_by_name, sort_parts
 from craft_parts.state_manager import StateManager, states
+from craft_parts.state_manager.reports import DirtyReport
 from craft_parts.steps import Step
 
 logger = logging.getLogger(__name__)
@@ -41,16 +42,34 @@ class Sequencer:
         self._project_info = project_info
         self._sm = StateManager(project_info=project_info, part_list=part_list)
         self._actions: List[Action] = []
+        self._resume = False
 
-    def plan(self, target_step: Step, part_names: Sequence[str] = None) -> List[Action]:
+    def plan(
+        self,
+        target_step: Step,
+        part_names: Sequence[str] = None,
+        *,
+        resume: bool = False,
+    ) -> List[Action]:
         """Determine the list of steps to execute for each part.
 
         :param target_step: The final step to execute for the given part names.
         :param part_names: The names of the parts to process.
+        :param resume: Continue failed build steps using the existing build
+            directory instead of building from scratch.
 
         :returns: The list of actions that should be executed.
+
+        :raises FeatureNotEnabled: If the target step is the overlay step and
+            the overlay feature is not enabled.
         """
+        if target_step == Step.OVERLAY and not self._project_info.overlay_enabled:
+            raise errors.FeatureNotEnabled(
+                "overlay", details="The overlay step is not part of the lifecycle."
+            )
+
         self._actions = []
+        self._resume = resume
         self._add_all_actions(target_step, part_names)
         return self._actions
 
@@ -68,7 +87,10 @@ class Sequencer:
     ) -> None:
         selected_parts = part_list_by_name(part_names, self._part_list)
 
-        for current_step in target_step.previous_steps() + [target_step]:
+        previous_steps = target_step.previous_steps(
+            overlay=self._project_info.overlay_enabled
+        )
+        for current_step in previous_steps + [target_step]:
             for part in selected_parts:
                 logger.debug("process %s:%s", part.name, current_step)
                 self._add_step_actions(
@@ -91,7 +113,12 @@ class Sequencer:
         """Verify if this step should be executed."""
         # check if step already ran, if not then run it
         if not self._sm.has_step_run(part, current_step):
-            self._run_step(part, current_step, reason=reason)
+            if self._can_resume(part, current_step):
+                self._run_step(
+                    part, current_step, reason="resume failed step", resume=True
+                )
+            else:
+                self._run_step(part, current_step, reason=reason)
             return
 
         # If the step has already run:
@@ -113,7 +140,12 @@ class Sequencer:
         if dirty_report:
             logger.debug("%s:%s is dirty", part.name, current_step)
 
-            self._rerun_step(part, current_step, reason=dirty_report.reason())
+            self._rerun_step(
+                part,
+                current_step,
+                reason=dirty_report.reason(),
+                dirty_report=dirty_report,
+            )
             return
 
         # 3. If the step is outdated, run it again (without cleaning if possible).
@@ -138,19 +170,38 @@ class Sequencer:
         )
 
     def _process_dependencies(self, part: Part, step: Step) -> None:
-        prerequisite_step = steps.dependency_prerequisite_step(step)
-        if not prerequisite_step:
+        if step == Step.OVERLAY:
+            self._process_lower_layers(part)
             return
 
         all_deps = parts.part_dependencies(part.name, part_list=self._part_list)
-        deps = {p for p in all_deps if self._sm.should_step_run(p, prerequisite_step)}
-        for dep in deps:
+
+        deps: Dict[Part, Step] = {}
+        for dep in all_deps:
+            prerequisite_step = steps.dependency_prerequisite_step(
+                step, part.get_dependency_step(dep.name)
+            )
+            if prerequisite_step and self._sm.should_step_run(dep, prerequisite_step):
+                deps[dep] = prerequisite_step
+
+        for dep, prerequisite_step in deps.items():
             self._add_all_actions(
                 target_step=prerequisite_step,
                 part_names=[dep.name],
                 reason=f"required to {_step_verb[step]} {part.name!r}",
             )
 
+    def _process_lower_layers(self, part: Part) -> None:
+        """Add actions to create the overlay layers below the part layer."""
+        lower_parts = parts.part_lower_layers(part.name, part_list=self._part_list)
+        for lower_part in lower_parts:
+            if self._sm.should_step_run(lower_part, Step.OVERLAY):
+                self._add_all_actions(
+                    target_step=Step.OVERLAY,
+                    part_names=[lower_part.name],
+                    reason=f"required to overlay {part.name!r}",
+                )
+
     def _run_step(
         self,
         part: Part,
@@ -158,16 +209,26 @@ class Sequencer:
         *,
         reason: Optional[str] = None,
         rerun: bool = False,
+        resume: bool = False,
+        dirty_report: Optional[DirtyReport] = None,
     ) -> None:
         self._process_dependencies(part, step)
 
-        if rerun:
-            self._add_action(part, step, action_type=ActionType.RERUN, reason=reason)
+        if resume:
+            self._add_action(part, step, action_type=ActionType.UPDATE, reason=reason)
+        elif rerun:
+            self._add_action(
+                part,
+                step,
+                action_type=ActionType.RERUN,
+                reason=reason,
+                dirty_report=dirty_report,
+            )
         else:
             self._add_action(part, step, reason=reason)
 
         state: states.StepState
-        part_properties = part.spec.marshal()
+        part_properties = {**part.spec.marshal(), **part.plugin_properties.marshal()}
 
         if step == Step.PULL:
             state = states.PullState(
@@ -176,6 +237,12 @@ class Sequencer:
                 assets={},  # TODO: obtain pull assets
             )
 
+        elif step == Step.OVERLAY:
+            state = states.OverlayState(
+                part_properties=part_properties,
+                project_options=self._project_info.project_options,
+            )
+
         elif step == Step.BUILD:
             state = states.BuildState(
                 part_properties=part_properties,
@@ -204,14 +271,43 @@ class Sequencer:
 
         self._sm.set_state(part, step, state=state)
 
+    def _can_resume(self, part: Part, step: Step) -> bool:
+        """Verify whether a failed step can continue from its previous output.
+
+        Only the build step can be resumed, and only if no earlier step of
+        the part is planned to run, since that would invalidate the output.
+        """
+        if not self._resume or step != Step.BUILD:
+            return False
+
+        if not states.has_step_failed(part, step):
+            return False
+
+        previous_steps = step.previous_steps(
+            overlay=self._project_info.overlay_enabled
+        )
+        return not any(
+            action.part_name == part.name
+            and action.step in previous_steps
+            and action.action_type != ActionType.SKIP
+            for action in self._actions
+        )
+
     def _rerun_step(
-        self, part: Part, step: Step, *, reason: Optional[str] = None
+        self,
+        part: Part,
+        step: Step,
+        *,
+        reason: Optional[str] = None,
+        dirty_report: Optional[DirtyReport] = None,
     ) -> None:
         logger.debug("rerun step %s:%s", part.name, step)
 
         # clean the step and later steps for this part, then run it again
         self._sm.clean_part(part, step)
-        self._run_step(part, step, reason=reason, rerun=True)
+        self._run_step(
+            part, step, reason=reason, rerun=True, dirty_report=dirty_report
+        )
 
     def _update_step(self, part: Part, step: Step, *, reason: Optional[str] = None):
         logger.debug("update step %s:%s", part.name, step)
@@ -225,15 +321,30 @@ class Sequencer:
         *,
         action_type: ActionType = ActionType.RUN,
         reason: Optional[str] = None,
+        dirty_report: Optional[DirtyReport] = None,
     ) -> None:
         logger.debug("add action %s:%s(%s)", part.name, step, action_type)
+        dirty_properties = None
+        dirty_project_options = None
+        if dirty_report:
+            dirty_properties = dirty_report.dirty_properties
+            dirty_project_options = dirty_report.dirty_project_options
+
         self._actions.append(
-            Action(part.name, step, action_type=action_type, reason=reason)
+            Action(
+                part.name,
+                step,
+                action_type=action_type,
+                reason=reason,
+                dirty_properties=dirty_properties,
+                dirty_project_options=dirty_project_options,
+            )
         )
 
 
 _step_verb: Dict[Step, str] = {
     Step.PULL: "pull",
+    Step.OVERLAY: "overlay",
     Step.BUILD: "build",
     Step.STAGE: "stage",
     Step.PRIME: "prime",
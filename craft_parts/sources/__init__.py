@@ -20,4 +20,5 @@ from . import errors  # noqa: F401
 from .local_source import LocalSource  # noqa: F401
 from .sources import SourceHandler  # noqa: F401
 from .sources import get_source_handler  # noqa: F401
+from .sources import get_source_options  # noqa: F401
 from .sources import get_source_type_from_uri  # noqa: F401
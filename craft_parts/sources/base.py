@@ -17,21 +17,29 @@
 """Base classes for source type handling."""
 
 import abc
+import base64
+import logging
 import os
 import shutil
+import tempfile
 from pathlib import Path
-from typing import List, Optional, Union
+from typing import TYPE_CHECKING, Any, Callable, Dict, List, Optional, Union
 
 import requests
 
-from craft_parts import utils
+from craft_parts import progress, utils
 from craft_parts.dirs import ProjectDirs
-from craft_parts.utils import url_utils
+from craft_parts.utils import file_utils, url_utils
 
-from . import errors
+from . import checksum, errors, object_storage
 from .cache import FileCache
 from .checksum import verify_checksum
 
+if TYPE_CHECKING:
+    from craft_parts.parts import SourceAuthSpec, SubmoduleSpec
+
+logger = logging.getLogger(__name__)
+
 
 class SourceHandler(abc.ABC):
     """The base class for source type handlers.
@@ -39,8 +47,22 @@ class SourceHandler(abc.ABC):
     Methods :meth:`check_if_outdated` and :meth:`update_source` can be
     overridden by subclasses to implement verification and update of
     source files.
+
+    Downloaded files are stored in the given cache. If no cache is set, a
+    cache specific to the application is used.
+
+    HTTP headers needed to download source files, such as authorization
+    headers, are obtained from the credentials provider and from the
+    environment variables in the source authentication specification.
+
+    The source details recorded in the state of a previous pull, if any,
+    are available to handlers as ``previous_details`` when pulling again.
     """
 
+    # The source options accepted by this handler, in addition to the source
+    # location and type.
+    source_options: List[str] = []
+
     # pylint: disable=too-many-arguments
 
     def __init__(
@@ -54,8 +76,19 @@ class SourceHandler(abc.ABC):
         source_branch: Optional[str] = None,
         source_depth: Optional[int] = None,
         source_checksum: Optional[str] = None,
+        source_submodules: Optional[List[Union[str, "SubmoduleSpec"]]] = None,
+        source_sparse_paths: Optional[List[str]] = None,
+        source_lfs: Optional[bool] = None,
+        source_keyring: Optional[str] = None,
+        source_allowed_signers: Optional[str] = None,
+        source_checksum_signature: Optional[str] = None,
+        source_auth: Optional["SourceAuthSpec"] = None,
+        source_region: Optional[str] = None,
+        source_profile: Optional[str] = None,
         command: Optional[str] = None,
         project_dirs: Optional[ProjectDirs] = None,
+        cache: Optional[FileCache] = None,
+        credentials_provider: Optional[Callable[[str], Dict[str, str]]] = None,
     ):
         if not application_name:
             application_name = utils.package_name()
@@ -70,12 +103,24 @@ class SourceHandler(abc.ABC):
         self.source_branch = source_branch
         self.source_depth = source_depth
         self.source_checksum = source_checksum
-        self.source_details = None
+        self.source_submodules = source_submodules
+        self.source_sparse_paths = source_sparse_paths or []
+        self.source_lfs = source_lfs
+        self.source_keyring = source_keyring
+        self.source_allowed_signers = source_allowed_signers
+        self.source_checksum_signature = source_checksum_signature
+        self.source_auth = source_auth
+        self.source_region = source_region
+        self.source_profile = source_profile
+        self.source_details: Optional[Dict[str, Any]] = None
+        self.previous_details: Optional[Dict[str, Any]] = None
 
         self.command = command
 
         self._application_name = application_name
         self._dirs = project_dirs
+        self._cache = cache
+        self._credentials_provider = credentials_provider
         self._checked = False
 
     # pylint: enable=too-many-arguments
@@ -99,6 +144,15 @@ class SourceHandler(abc.ABC):
         """
         raise errors.SourceUpdateUnsupported(self.__class__.__name__)
 
+    def get_outdated_files(self) -> List[str]:
+        """Obtain the files found to be outdated by :meth:`check_if_outdated`.
+
+        :return: The list of outdated files and directories, relative to the
+            source. Handlers that don't track individual files return an
+            empty list.
+        """
+        return []
+
     def update(self):
         """Update pulled source.
 
@@ -106,9 +160,38 @@ class SourceHandler(abc.ABC):
         """
         raise errors.SourceUpdateUnsupported(self.__class__.__name__)
 
+    def get_source_date_epoch(self) -> Optional[int]:
+        """Obtain the timestamp of the pulled source.
+
+        The timestamp is the modification time of the most recently modified
+        file in the pulled source. Handlers of sources with revision history
+        override this to use the time of the pulled revision.
+
+        :return: The timestamp in seconds since the epoch, or None if the
+            pulled source has no files.
+        """
+        latest: Optional[int] = None
+        for root, _, files in os.walk(self.part_src_dir):
+            for name in files:
+                mtime = int(os.lstat(os.path.join(root, name)).st_mtime)
+                if latest is None or mtime > latest:
+                    latest = mtime
+        return latest
+
 
 class FileSourceHandler(SourceHandler):
-    """Base class for file source types."""
+    """Base class for file source types.
+
+    The source checksum can refer to a checksum file, such as ``SHA256SUMS``,
+    listing the digest of the source file. If a keyring is set, the checksum
+    file must be signed by one of its keys, either with a detached signature
+    or by being clearsigned.
+
+    Files downloaded from HTTP URLs without a checksum are cached along with
+    the entity tag and modification time sent by the server, which are
+    recorded in the source details. When pulled again, the file is only
+    downloaded if the server reports that it was modified.
+    """
 
     # pylint: disable=too-many-arguments
     def __init__(
@@ -122,8 +205,19 @@ class FileSourceHandler(SourceHandler):
         source_branch: Optional[str] = None,
         source_depth: Optional[int] = None,
         source_checksum: Optional[str] = None,
+        source_submodules: Optional[List[Union[str, "SubmoduleSpec"]]] = None,
+        source_sparse_paths: Optional[List[str]] = None,
+        source_lfs: Optional[bool] = None,
+        source_keyring: Optional[str] = None,
+        source_allowed_signers: Optional[str] = None,
+        source_checksum_signature: Optional[str] = None,
+        source_auth: Optional["SourceAuthSpec"] = None,
+        source_region: Optional[str] = None,
+        source_profile: Optional[str] = None,
         command: Optional[str] = None,
         project_dirs: Optional[ProjectDirs] = None,
+        cache: Optional[FileCache] = None,
+        credentials_provider: Optional[Callable[[str], Dict[str, str]]] = None,
     ):
         super().__init__(
             source,
@@ -134,8 +228,19 @@ class FileSourceHandler(SourceHandler):
             source_branch=source_branch,
             source_depth=source_depth,
             source_checksum=source_checksum,
+            source_submodules=source_submodules,
+            source_sparse_paths=source_sparse_paths,
+            source_lfs=source_lfs,
+            source_keyring=source_keyring,
+            source_allowed_signers=source_allowed_signers,
+            source_checksum_signature=source_checksum_signature,
+            source_auth=source_auth,
+            source_region=source_region,
+            source_profile=source_profile,
             command=command,
             project_dirs=project_dirs,
+            cache=cache,
+            credentials_provider=credentials_provider,
         )
         self._file = ""
 
@@ -149,6 +254,8 @@ class FileSourceHandler(SourceHandler):
 
     def pull(self) -> None:
         """Retrieve this source from its origin."""
+        self._resolve_checksum_file()
+
         source_file = None
         is_source_url = url_utils.is_url(self.source)
 
@@ -179,13 +286,15 @@ class FileSourceHandler(SourceHandler):
 
         :param filepath: the destination file to download to.
         """
+        self._resolve_checksum_file()
+
         if filepath is None:
             self._file = os.path.join(self.part_src_dir, os.path.basename(self.source))
         else:
             self._file = filepath
 
         # check if we already have the source file cached
-        file_cache = FileCache(self._application_name)
+        file_cache = self._cache or FileCache(self._application_name)
         if self.source_checksum:
             cache_file = file_cache.get(key=self.source_checksum)
             if cache_file:
@@ -199,8 +308,156 @@ class FileSourceHandler(SourceHandler):
             # FIXME: handle ftp downloads
             raise NotImplementedError("ftp download not implemented")
 
+        if self.source_checksum or object_storage.is_object_storage_url(self.source):
+            self._download_file(self.source, self._file)
+        else:
+            self._download_if_modified(file_cache)
+
+        # if source_checksum is defined cache the file for future reuse
+        if self.source_checksum:
+            verify_checksum(self.source_checksum, self._file)
+            file_cache.cache(filename=self._file, key=self.source_checksum)
+        return self._file
+
+    def _resolve_checksum_file(self) -> None:
+        """Replace a checksum file reference with the listed source digest.
+
+        :raise errors.ChecksumFileError: If the checksum file signature can't
+            be verified or the source file is not listed.
+        """
+        if not checksum.is_checksum_file(self.source_checksum or ""):
+            return
+
+        algorithm, checksum_file = checksum.split_checksum(self.source_checksum)
+
+        with tempfile.TemporaryDirectory() as tmpdir:
+            sums_file = self._fetch_file(checksum_file, tmpdir)
+
+            if self.source_keyring:
+                signature = None
+                if self.source_checksum_signature:
+                    signature = self._fetch_file(
+                        self.source_checksum_signature, tmpdir
+                    )
+                sums_file = checksum.verify_checksum_file_signature(
+                    sums_file, keyring=self.source_keyring, signature=signature
+                )
+                if not sums_file:
+                    raise errors.ChecksumFileError(
+                        checksum_file, message="signature verification failed"
+                    )
+
+            digest = checksum.get_checksum_file_digest(
+                sums_file,
+                self.source,
+                relative_path=_get_relative_path(self.source, checksum_file),
+            )
+
+        if not digest:
+            name = os.path.basename(self.source)
+            raise errors.ChecksumFileError(
+                checksum_file, message=f"{name!r} is not listed"
+            )
+
+        self.source_checksum = f"{algorithm}/{digest}"
+
+    def _fetch_file(self, source: str, directory: str) -> str:
+        """Copy or download a file into the given directory.
+
+        :param source: The local path or URL of the file.
+        :param directory: The directory to place the file in.
+
+        :return: The path to the fetched file.
+        """
+        destination = os.path.join(directory, os.path.basename(source))
+        if not url_utils.is_url(source):
+            try:
+                shutil.copy2(source, destination)
+            except FileNotFoundError as err:
+                raise errors.SourceNotFound(source) from err
+        else:
+            self._download_file(source, destination)
+
+        return destination
+
+    def _download_file(self, url: str, destination: str) -> None:
+        """Download a file from an object storage, HTTP or HTTPS URL."""
+        if object_storage.is_object_storage_url(url):
+            object_storage.download_object(
+                url,
+                destination,
+                region=self.source_region,
+                profile=self.source_profile,
+            )
+        else:
+            self._download_request(url, destination)
+
+    def _download_if_modified(self, file_cache: FileCache) -> None:
+        """Download the source file unless it wasn't modified since the last pull.
+
+        :param file_cache: The cache containing previously downloaded files.
+        """
+        details = self.previous_details or {}
+        cached_file = None
+        if details.get("digest"):
+            cached_file = file_cache.get(key=details["digest"])
+
+        conditions: Dict[str, str] = {}
+        if cached_file:
+            if details.get("etag"):
+                conditions["If-None-Match"] = details["etag"]
+            if details.get("last-modified"):
+                conditions["If-Modified-Since"] = details["last-modified"]
+
+        response = self._download_request(
+            self.source, self._file, conditions=conditions
+        )
+        if cached_file and response.status_code == requests.codes.not_modified:
+            logger.debug("Source %s not modified, using cached file", self.source)
+            shutil.copy2(cached_file, self._file)
+            self.source_details = details
+            return
+
+        etag = response.headers.get("ETag")
+        last_modified = response.headers.get("Last-Modified")
+        if not (etag or last_modified):
+            return
+
+        digest = file_utils.calculate_hash(self._file, algorithm="sha384")
+        key = f"sha384/{digest}"
+        file_cache.cache(filename=self._file, key=key)
+
+        self.source_details = {"digest": key}
+        if etag:
+            self.source_details["etag"] = etag
+        if last_modified:
+            self.source_details["last-modified"] = last_modified
+
+    def _download_request(
+        self,
+        url: str,
+        destination: str,
+        *,
+        conditions: Optional[Dict[str, str]] = None,
+    ) -> requests.Response:
+        """Download a file from an HTTP or HTTPS URL.
+
+        :param url: The URL of the file to download.
+        :param destination: The file to write the downloaded contents to.
+        :param conditions: Conditional request headers. The file is not
+            written if the server reports it was not modified.
+
+        :return: The server response.
+        """
+        # Header values can contain secrets, they must not be logged.
+        headers = self._get_request_headers(url)
+        if conditions:
+            headers.update(conditions)
+
         try:
-            request = requests.get(self.source, stream=True, allow_redirects=True)
+            request = requests.get(
+                url, headers=headers, stream=True, allow_redirects=True
+            )
             request.raise_for_status()
         except requests.exceptions.RequestException as err:
             raise errors.NetworkRequestError(
@@ -208,10 +465,66 @@ class FileSourceHandler(SourceHandler):
                 "response={err.response!r}"
             )
 
-        url_utils.download_request(request, self._file)
+        if request.status_code != requests.codes.not_modified:
+            reporter = progress.ProgressReporter(
+                progress.DownloadKind.SOURCE, url_utils.redact_credentials(url)
+            )
+            reporter.start()
+            url_utils.download_request(request, destination, progress=reporter.update)
+            reporter.finish()
 
-        # if source_checksum is defined cache the file for future reuse
-        if self.source_checksum:
-            verify_checksum(self.source_checksum, self._file)
-            file_cache.cache(filename=self._file, key=self.source_checksum)
-        return self._file
+        return request
+
+    def _get_request_headers(self, url: str) -> Dict[str, str]:
+        """Obtain the HTTP headers to send when downloading from the given URL.
+
+        Credentials from the source authentication specification take
+        precedence over credentials from the credentials provider.
+
+        :raise errors.SourceCredentialsNotFound: If an environment variable
+            containing credentials is not set.
+        """
+        headers: Dict[str, str] = {}
+        if self._credentials_provider:
+            headers.update(self._credentials_provider(url))
+
+        auth = self.source_auth
+        if not auth:
+            return headers
+
+        if auth.token_env:
+            headers["Authorization"] = f"Bearer {_getenv(auth.token_env)}"
+
+        if auth.username_env or auth.password_env:
+            username = _getenv(auth.username_env) if auth.username_env else ""
+            password = _getenv(auth.password_env) if auth.password_env else ""
+            credentials = base64.b64encode(f"{username}:{password}".encode()).decode()
+            headers["Authorization"] = f"Basic {credentials}"
+
+        for name, variable in auth.headers_env.items():
+            headers[name] = _getenv(variable)
+
+        return headers
+
+
+def _getenv(variable: str) -> str:
+    value = os.getenv(variable)
+    if value is None:
+        raise errors.SourceCredentialsNotFound(variable)
+    return value
+
+
+def _get_relative_path(source: str, checksum_file: str) -> Optional[str]:
+    """Obtain the path of a source relative to its checksum file, if any."""
+    if url_utils.is_url(source) != url_utils.is_url(checksum_file):
+        return None
+
+    if not url_utils.is_url(source):
+        source = os.path.abspath(source)
+        checksum_file = os.path.abspath(checksum_file)
+
+    prefix = checksum_file.rsplit("/", 1)[0] + "/"
+    if not source.startswith(prefix):
+        return None
+
+    return source[len(prefix) :]
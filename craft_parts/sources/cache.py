@@ -19,7 +19,9 @@
 import logging
 import os
 import shutil
-from typing import Optional
+import tempfile
+from pathlib import Path
+from typing import List, Optional, Tuple, Union
 
 from xdg import BaseDirectory  # type: ignore
 
@@ -27,17 +29,36 @@ logger = logging.getLogger(__name__)
 
 
 class FileCache:
-    """Cache files based on the supplied key."""
-
-    def __init__(self, name: str, *, namespace: str = "files") -> None:
-        """Create a FileCache under namespace.
-
-        :param str namespace: The namespace for the cache (default is "files").
-        """
-        self.cache_root = os.path.join(
-            BaseDirectory.xdg_cache_home, name, "craft-parts"
-        )
+    """Cache files based on the supplied key.
+
+    Keys are content digests such as ``sha384/<digest>``, so that identical
+    files obtained by different projects or applications are stored once.
+    If a size limit is set, the least recently used files are evicted when
+    the cache grows beyond the limit.
+
+    :param name: The name of the application using the cache.
+    :param namespace: The namespace for the cache (default is "files").
+    :param cache_dir: The cache location. Defaults to a cache specific to
+        the application in the XDG cache directory.
+    :param max_size: The maximum size of the cache namespace in bytes.
+    """
+
+    def __init__(
+        self,
+        name: str,
+        *,
+        namespace: str = "files",
+        cache_dir: Optional[Union[Path, str]] = None,
+        max_size: Optional[int] = None,
+    ) -> None:
+        if cache_dir:
+            self.cache_root = str(cache_dir)
+        else:
+            self.cache_root = os.path.join(
+                BaseDirectory.xdg_cache_home, name, "craft-parts"
+            )
         self.file_cache = os.path.join(self.cache_root, namespace)
+        self.max_size = max_size
 
     def cache(self, *, filename: str, key: str) -> Optional[str]:
         """Cache a file revision with hash in XDG cache, unless it already exists.
@@ -48,14 +69,29 @@ class FileCache:
         :return: The path to the cached file, or None if the file was not cached.
         """
         cached_file_path = os.path.join(self.file_cache, key)
-        os.makedirs(os.path.dirname(cached_file_path), exist_ok=True)
+        cached_file_dir = os.path.dirname(cached_file_path)
+        os.makedirs(cached_file_dir, exist_ok=True)
 
         try:
             if not os.path.isfile(cached_file_path):
-                shutil.copyfile(filename, cached_file_path)
+                # The cache can be shared by concurrent builds, never expose
+                # partially copied files.
+                with tempfile.NamedTemporaryFile(
+                    dir=cached_file_dir, prefix=".partial-", delete=False
+                ) as partial_file:
+                    partial_path = partial_file.name
+                try:
+                    shutil.copyfile(filename, partial_path)
+                    os.replace(partial_path, cached_file_path)
+                finally:
+                    if os.path.exists(partial_path):
+                        os.remove(partial_path)
         except OSError:
             logger.warning("Unable to cache file %s.", cached_file_path)
             return None
+
+        if self.max_size is not None:
+            self._evict(self.max_size, keep=cached_file_path)
         return cached_file_path
 
     def get(self, *, key: str) -> Optional[str]:
@@ -68,6 +104,8 @@ class FileCache:
         cached_file_path = os.path.join(self.file_cache, key)
         if os.path.exists(cached_file_path):
             logger.debug("Cache hit for key %s", key)
+            # Mark the file as recently used.
+            os.utime(cached_file_path)
             return cached_file_path
 
         return None
@@ -75,3 +113,54 @@ class FileCache:
     def clean(self):
         """Remove all files from the cache namespace."""
         shutil.rmtree(self.file_cache)
+
+    def prune(self, *, max_size: Optional[int] = None) -> int:
+        """Remove least recently used files until the cache fits a size limit.
+
+        :param max_size: The maximum size of the cache namespace in bytes.
+            Defaults to the size limit of the cache, or to removing all files
+            if the cache has no size limit.
+
+        :return: The number of bytes removed from the cache.
+        """
+        if max_size is None:
+            max_size = self.max_size or 0
+
+        return self._evict(max_size)
+
+    def _evict(self, max_size: int, *, keep: Optional[str] = None) -> int:
+        """Remove least recently used files until the cache fits a size limit.
+
+        :param max_size: The maximum size of the cache namespace in bytes.
+        :param keep: A file that must not be evicted.
+
+        :return: The number of bytes removed from the cache.
+        """
+        entries: List[Tuple[float, int, str]] = []
+        for root, _, files in os.walk(self.file_cache):
+            for file_name in files:
+                path = os.path.join(root, file_name)
+                try:
+                    stat = os.stat(path)
+                except FileNotFoundError:
+                    continue
+                entries.append((stat.st_mtime, stat.st_size, path))
+
+        total_size = sum(size for _, size, _ in entries)
+        removed = 0
+        for _, size, path in sorted(entries):
+            if total_size <= max_size:
+                break
+            if path == keep:
+                continue
+
+            logger.debug("Evict cached file %s", path)
+            try:
+                os.remove(path)
+            except FileNotFoundError:
+                pass
+            else:
+                removed += size
+            total_size -= size
+
+        return removed
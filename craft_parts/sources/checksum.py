@@ -16,7 +16,12 @@
 
 """Helpers to compute and verify file checksums."""
 
-from typing import Tuple
+import os
+import re
+import subprocess
+import tempfile
+from pathlib import Path
+from typing import Optional, Tuple
 
 from craft_parts.utils import file_utils
 
@@ -60,3 +65,96 @@ def verify_checksum(source_checksum: str, checkfile: str) -> Tuple:
         raise errors.ChecksumMismatch(expected=digest, obtained=calculated_digest)
 
     return (algorithm, digest)
+
+
+_DIGEST_REGEX = re.compile(r"^[0-9a-fA-F]*$")
+
+# Lines in GNU coreutils (``<digest>  <file>``) and BSD (``ALG (<file>) = <digest>``)
+# checksum file formats.
+_GNU_LINE_REGEX = re.compile(r"^\\?(?P<digest>[0-9a-fA-F]+) [ *]?(?P<file>.+)$")
+_BSD_LINE_REGEX = re.compile(r"^\\?\w+ \((?P<file>.+)\) = (?P<digest>[0-9a-fA-F]+)$")
+
+
+def is_checksum_file(source_checksum: str) -> bool:
+    """Verify whether the given source checksum refers to a checksum file.
+
+    A source checksum in ``algorithm/checksum-file`` format refers to a
+    local or remote file listing the digests of released files, such as
+    ``SHA256SUMS``, instead of containing the digest itself.
+
+    :param source_checksum: Source checksum in algorithm/hash or
+        algorithm/checksum-file format.
+    """
+    _, sep, value = source_checksum.partition("/")
+    return bool(sep and value and not _DIGEST_REGEX.match(value))
+
+
+def get_checksum_file_digest(
+    checksum_file: str, filename: str, *, relative_path: Optional[str] = None
+) -> Optional[str]:
+    """Obtain the digest of a file listed in a checksum file.
+
+    Files listed without a directory are matched by name. Files listed with
+    a directory are matched by their path relative to the checksum file if
+    it's known, or by name otherwise.
+
+    :param checksum_file: The checksum file to read.
+    :param filename: The name of the file to obtain the digest of.
+    :param relative_path: The path of the file relative to the directory
+        containing the checksum file, if known.
+
+    :return: The file digest, or None if the file is not listed.
+    """
+    name = os.path.basename(filename)
+    if relative_path:
+        relative_path = os.path.normpath(relative_path)
+
+    for line in Path(checksum_file).read_text().splitlines():
+        match = _GNU_LINE_REGEX.match(line) or _BSD_LINE_REGEX.match(line.strip())
+        if not match:
+            continue
+
+        listed = os.path.normpath(match.group("file").strip())
+        if relative_path and os.path.dirname(listed):
+            found = listed == relative_path
+        else:
+            found = os.path.basename(listed) == name
+
+        if found:
+            return match.group("digest").lower()
+
+    return None
+
+
+def verify_checksum_file_signature(
+    checksum_file: str, *, keyring: str, signature: Optional[str] = None
+) -> Optional[str]:
+    """Verify the OpenPGP signature of a checksum file.
+
+    :param checksum_file: The checksum file to verify.
+    :param keyring: The keyring containing the trusted public keys.
+    :param signature: The detached signature of the checksum file. If not
+        set, the checksum file must be clearsigned.
+
+    :return: The path to the verified checksum file contents, written next
+        to the checksum file, or None if the signature can't be verified.
+    """
+    verified_file = f"{checksum_file}.verified"
+
+    # Use a private keyring so that only the provided keys are trusted.
+    with tempfile.TemporaryDirectory() as gnupg_home:
+        gpg = ["gpg", "--batch", "--quiet", "--homedir", gnupg_home]
+        try:
+            subprocess.run([*gpg, "--import", os.path.abspath(keyring)], check=True)
+            if signature:
+                subprocess.run([*gpg, "--verify", signature, checksum_file], check=True)
+                return checksum_file
+
+            subprocess.run(
+                [*gpg, "--output", verified_file, "--decrypt", checksum_file],
+                check=True,
+            )
+        except subprocess.CalledProcessError:
+            return None
+
+    return verified_file
@@ -25,10 +25,14 @@ from craft_parts.utils import formatting_utils
 class SourceError(errors.PartsError):
     """Base class for source handler errors."""
 
+    code = "source-error"
+
 
 class InvalidSourceType(SourceError):
     """Failed to determine a source type."""
 
+    code = "invalid-source-type"
+
     def __init__(self, source: str):
         self.source = source
         brief = f"Failed to pull source: unable to determine source type of {source!r}."
@@ -39,6 +43,8 @@ class InvalidSourceType(SourceError):
 class InvalidSourceOption(SourceError):
     """A source option is not allowed for the given source type."""
 
+    code = "invalid-source-option"
+
     def __init__(self, *, source_type: str, option: str):
         self.source_type = source_type
         self.option = option
@@ -54,6 +60,8 @@ class InvalidSourceOption(SourceError):
 class IncompatibleSourceOptions(SourceError):
     """Source specified options that cannot be used at the same time."""
 
+    code = "incompatible-source-options"
+
     def __init__(self, source_type: str, options: List[str]):
         self.source_type = source_type
         self.options = options
@@ -70,6 +78,8 @@ class IncompatibleSourceOptions(SourceError):
 class ChecksumMismatch(SourceError):
     """A checksum doesn't match the expected value."""
 
+    code = "checksum-mismatch"
+
     def __init__(self, *, expected: str, obtained: str):
         self.expected = expected
         self.obtained = obtained
@@ -81,6 +91,8 @@ class ChecksumMismatch(SourceError):
 class SourceUpdateUnsupported(SourceError):
     """The source handler doesn't support updating."""
 
+    code = "source-update-unsupported"
+
     def __init__(self, name: str):
         self.name = name
         brief = f"Failed to update source: {name!r} sources don't support updating."
@@ -91,6 +103,8 @@ class SourceUpdateUnsupported(SourceError):
 class NetworkRequestError(SourceError):
     """A network request operation failed."""
 
+    code = "network-request-error"
+
     def __init__(self, message: str):
         self.message = message
         brief = f"Network request error: {message}."
@@ -102,9 +116,131 @@ class NetworkRequestError(SourceError):
 class SourceNotFound(SourceError):
     """Failed to retrieve a source."""
 
+    code = "source-not-found"
+
     def __init__(self, source: str):
         self.source = source
         brief = f"Failed to pull source: {source!r} not found."
         resolution = "Make sure the source path is correct and accessible."
 
         super().__init__(brief=brief, resolution=resolution)
+
+
+class SourceCredentialsNotFound(SourceError):
+    """An environment variable containing source credentials is not set."""
+
+    code = "source-credentials-not-found"
+
+    def __init__(self, variable: str):
+        self.variable = variable
+        brief = f"Failed to pull source: credentials variable {variable!r} is not set."
+        resolution = "Set the environment variable to the source credentials."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class PullError(SourceError):
+    """Failed to pull source."""
+
+    code = "pull-error"
+
+    def __init__(self, *, command: List[str], exit_code: int):
+        self.command = command
+        self.exit_code = exit_code
+        brief = (
+            f"Failed to pull source: command {' '.join(command)!r} "
+            f"exited with code {exit_code}."
+        )
+        resolution = "Make sure sources are correctly specified."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class PatchError(SourceError):
+    """A patch can't be applied to the pulled source."""
+
+    code = "patch-error"
+
+    def __init__(self, patch: str, *, message: str):
+        self.patch = patch
+        self.message = message
+        brief = f"Failed to apply patch {patch!r}: {message}."
+        resolution = "Make sure the patch applies cleanly to the pulled source."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class InvalidOciImage(SourceError):
+    """An OCI image can't be unpacked."""
+
+    code = "invalid-oci-image"
+
+    def __init__(self, source: str, *, message: str):
+        self.source = source
+        self.message = message
+        brief = f"Failed to unpack OCI image {source!r}: {message}."
+        resolution = "Make sure the image reference or archive is correct."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class SignatureVerificationFailed(SourceError):
+    """The signature of a pulled revision is missing or not trusted."""
+
+    code = "signature-verification-failed"
+
+    def __init__(self, source: str, *, ref: str):
+        self.source = source
+        self.ref = ref
+        brief = f"Failed to verify the signature of {ref!r} in {source!r}."
+        resolution = (
+            "Make sure the revision is signed by a key in the provided keyring "
+            "or allowed signers file."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class InvalidMirrorRule(SourceError):
+    """A source mirror rule is malformed."""
+
+    code = "invalid-mirror-rule"
+
+    def __init__(self, rule: str, *, message: str):
+        self.rule = rule
+        self.message = message
+        brief = f"Invalid source mirror rule {rule!r}: {message}."
+        resolution = "Make sure source mirror rules are correctly specified."
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class ChecksumFileError(SourceError):
+    """The digest of a source file can't be obtained from a checksum file."""
+
+    code = "checksum-file-error"
+
+    def __init__(self, checksum_file: str, *, message: str):
+        self.checksum_file = checksum_file
+        self.message = message
+        brief = f"Failed to use checksum file {checksum_file!r}: {message}."
+        resolution = (
+            "Make sure the checksum file lists the source file and, if a keyring "
+            "is provided, is signed by one of its keys."
+        )
+
+        super().__init__(brief=brief, resolution=resolution)
+
+
+class LfsObjectMismatch(SourceError):
+    """The content of a Git LFS object doesn't match its pointer."""
+
+    code = "lfs-object-mismatch"
+
+    def __init__(self, path: str, *, oid: str):
+        self.path = path
+        self.oid = oid
+        brief = f"Git LFS object for {path!r} doesn't match digest {oid}."
+        resolution = "Make sure the LFS server provides the correct objects."
+
+        super().__init__(brief=brief, resolution=resolution)
@@ -0,0 +1,410 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Implement the git source handler."""
+
+import logging
+import os
+import shutil
+import subprocess
+import tempfile
+from pathlib import Path
+from typing import TYPE_CHECKING, Callable, Dict, List, Optional, Union
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.utils.file_utils import calculate_hash
+
+from . import errors
+from .base import SourceHandler
+from .cache import FileCache
+
+if TYPE_CHECKING:
+    from craft_parts.parts import SourceAuthSpec, SubmoduleSpec
+
+logger = logging.getLogger(__name__)
+
+
+# pylint: disable=too-many-arguments
+class GitSource(SourceHandler):
+    """The git source handler.
+
+    The repository is cloned in the part source directory, or fetched again
+    if it was previously cloned, and the requested tag, branch or commit is
+    checked out.
+
+    Submodules are fetched recursively unless ``source-submodules`` is set,
+    in which case only the listed submodules are fetched, each with its own
+    branch and depth if specified. If ``source-sparse-paths`` is set, only
+    the given directories are checked out, and file contents outside them
+    are not downloaded. Submodules are fetched regardless of the sparse
+    paths.
+
+    Files matching the ``filter=lfs`` patterns in ``.gitattributes`` are
+    replaced with their Git LFS objects after checkout, unless
+    ``source-lfs`` is set to false. Objects are verified against the digest
+    in their pointer files and stored in the download cache, so that they
+    are not downloaded again. LFS objects of submodules are not fetched.
+
+    If ``source-keyring`` or ``source-allowed-signers`` is set, the pull
+    fails unless the checked out tag, or the commit if no tag is given, is
+    signed by a trusted OpenPGP or SSH key respectively. Keys from the
+    user's own keyring are never trusted.
+    """
+
+    source_options = [
+        "source-tag",
+        "source-commit",
+        "source-branch",
+        "source-depth",
+        "source-submodules",
+        "source-sparse-paths",
+        "source-lfs",
+        "source-keyring",
+        "source-allowed-signers",
+    ]
+
+    def __init__(
+        self,
+        source,
+        part_src_dir,
+        *,
+        application_name: Optional[str] = None,
+        source_tag: Optional[str] = None,
+        source_commit: Optional[str] = None,
+        source_branch: Optional[str] = None,
+        source_depth: Optional[int] = None,
+        source_checksum: Optional[str] = None,
+        source_submodules: Optional[List[Union[str, "SubmoduleSpec"]]] = None,
+        source_sparse_paths: Optional[List[str]] = None,
+        source_lfs: Optional[bool] = None,
+        source_keyring: Optional[str] = None,
+        source_allowed_signers: Optional[str] = None,
+        source_checksum_signature: Optional[str] = None,
+        source_auth: Optional["SourceAuthSpec"] = None,
+        source_region: Optional[str] = None,
+        source_profile: Optional[str] = None,
+        project_dirs: Optional[ProjectDirs] = None,
+        cache: Optional[FileCache] = None,
+        credentials_provider: Optional[Callable[[str], Dict[str, str]]] = None,
+    ):
+        super().__init__(
+            source,
+            part_src_dir,
+            application_name=application_name,
+            source_tag=source_tag,
+            source_commit=source_commit,
+            source_branch=source_branch,
+            source_depth=source_depth,
+            source_checksum=source_checksum,
+            source_submodules=source_submodules,
+            source_sparse_paths=source_sparse_paths,
+            source_lfs=source_lfs,
+            source_keyring=source_keyring,
+            source_allowed_signers=source_allowed_signers,
+            source_checksum_signature=source_checksum_signature,
+            source_auth=source_auth,
+            source_region=source_region,
+            source_profile=source_profile,
+            command="git",
+            project_dirs=project_dirs,
+            cache=cache,
+            credentials_provider=credentials_provider,
+        )
+        if source_checksum:
+            raise errors.InvalidSourceOption(
+                source_type="git", option="source-checksum"
+            )
+
+        if source_checksum_signature:
+            raise errors.InvalidSourceOption(
+                source_type="git", option="source-checksum-signature"
+            )
+
+        if source_auth:
+            raise errors.InvalidSourceOption(source_type="git", option="source-auth")
+
+        if source_region:
+            raise errors.InvalidSourceOption(source_type="git", option="source-region")
+
+        if source_profile:
+            raise errors.InvalidSourceOption(
+                source_type="git", option="source-profile"
+            )
+
+        options = [
+            name
+            for name, value in [
+                ("source-tag", source_tag),
+                ("source-commit", source_commit),
+                ("source-branch", source_branch),
+            ]
+            if value
+        ]
+        if len(options) > 1:
+            raise errors.IncompatibleSourceOptions("git", options)
+
+    def pull(self) -> None:
+        """Clone or fetch the repository and check out the requested revision."""
+        if os.path.exists(os.path.join(self.part_src_dir, ".git")):
+            self._fetch_existing()
+        else:
+            self._clone_new()
+
+        self._verify_signature()
+        self._update_submodules()
+        self._pull_lfs_objects()
+
+        self.source_details = {"commit": self._get_current_commit()}
+
+    def _clone_new(self) -> None:
+        command = [self.command, "clone"]
+        if self.source_depth:
+            command.extend(["--depth", str(self.source_depth)])
+        if self.source_tag or self.source_branch:
+            command.extend(["--branch", self.source_tag or self.source_branch])
+        if self.source_sparse_paths:
+            # Only download the contents of files as they are checked out.
+            command.extend(["--filter=blob:none", "--sparse"])
+        command.extend([self.source, self.part_src_dir])
+        _run(command)
+
+        self._set_sparse_paths()
+
+        if self.source_commit:
+            self._fetch(self.source_commit)
+            self._git("checkout", self.source_commit)
+
+    def _fetch_existing(self) -> None:
+        if self.source_commit:
+            refspec = self.source_commit
+        elif self.source_tag:
+            refspec = f"refs/tags/{self.source_tag}:refs/tags/{self.source_tag}"
+        elif self.source_branch:
+            refspec = f"refs/heads/{self.source_branch}"
+        else:
+            refspec = "HEAD"
+
+        self._set_sparse_paths()
+        self._fetch(refspec)
+        self._git("reset", "--hard", "FETCH_HEAD")
+
+    def _fetch(self, refspec: str) -> None:
+        command = ["fetch", "--force"]
+        if self.source_depth:
+            command.extend(["--depth", str(self.source_depth)])
+        command.extend(["origin", refspec])
+        self._git(*command)
+
+    def _set_sparse_paths(self) -> None:
+        if not self.source_sparse_paths:
+            return
+
+        self._git("sparse-checkout", "init", "--cone")
+        self._git("sparse-checkout", "set", "--", *self.source_sparse_paths)
+
+    def _update_submodules(self) -> None:
+        update_command = ["submodule", "update", "--init", "--recursive"]
+
+        if self.source_submodules is None:
+            if self.source_depth:
+                update_command.extend(["--depth", str(self.source_depth)])
+            self._git(*update_command)
+            return
+
+        for submodule in self.source_submodules:
+            if isinstance(submodule, str):
+                path, branch, depth = submodule, "", 0
+            else:
+                path, branch, depth = submodule.path, submodule.branch, submodule.depth
+
+            depth_args = ["--depth", str(depth)] if depth else []
+            self._git(*update_command, *depth_args, "--", path)
+
+            if branch:
+                # Check out the tip of the branch instead of the commit
+                # recorded in the superproject.
+                submodule_dir = os.path.join(self.part_src_dir, path)
+                submodule_git = [self.command, "-C", submodule_dir]
+                _run([*submodule_git, "fetch", *depth_args, "origin", branch])
+                _run([*submodule_git, "checkout", "FETCH_HEAD"])
+                _run([*submodule_git, *update_command, *depth_args])
+
+    def _pull_lfs_objects(self) -> None:
+        if self.source_lfs is False or not self._has_lfs_patterns():
+            return
+
+        objects = self._get_lfs_objects()
+        file_cache = self._cache or FileCache(self._application_name)
+        objects_dir = Path(self.part_src_dir, ".git", "lfs", "objects")
+
+        # Restore cached objects so that git-lfs doesn't download them again.
+        for oid in set(objects.values()):
+            object_path = objects_dir / oid[0:2] / oid[2:4] / oid
+            cached_file = file_cache.get(key=f"sha256/{oid}")
+            if (
+                cached_file
+                and not object_path.exists()
+                and calculate_hash(cached_file, algorithm="sha256") == oid
+            ):
+                object_path.parent.mkdir(parents=True, exist_ok=True)
+                shutil.copyfile(cached_file, object_path)
+
+        _run(
+            [self.command, "-C", self.part_src_dir, "lfs", "pull"],
+            skip_lfs_smudge=False,
+        )
+
+        for path, oid in objects.items():
+            object_path = objects_dir / oid[0:2] / oid[2:4] / oid
+            # Objects of files outside the sparse paths are not fetched.
+            if not object_path.exists():
+                continue
+
+            if calculate_hash(str(object_path), algorithm="sha256") != oid:
+                raise errors.LfsObjectMismatch(path, oid=oid)
+
+            file_cache.cache(filename=str(object_path), key=f"sha256/{oid}")
+
+    def _has_lfs_patterns(self) -> bool:
+        for root, directories, files in os.walk(self.part_src_dir):
+            if ".git" in directories:
+                directories.remove(".git")
+
+            # Submodule working trees contain a .git file.
+            if root != self.part_src_dir and ".git" in files:
+                directories.clear()
+                continue
+
+            if ".gitattributes" not in files:
+                continue
+
+            attributes = Path(root, ".gitattributes").read_text()
+            for line in attributes.splitlines():
+                pattern, *attrs = line.split() or [""]
+                if not pattern.startswith("#") and "filter=lfs" in attrs:
+                    return True
+
+        return False
+
+    def _get_lfs_objects(self) -> Dict[str, str]:
+        """Obtain the LFS object ids of the files in the checked out revision."""
+        command = [self.command, "-C", self.part_src_dir, "lfs", "ls-files", "--long"]
+        try:
+            output = subprocess.check_output(command, universal_newlines=True)
+        except subprocess.CalledProcessError as err:
+            raise errors.PullError(command=command, exit_code=err.returncode) from err
+
+        # Each line contains the object id, a checkout marker and the path.
+        objects: Dict[str, str] = {}
+        for line in output.splitlines():
+            oid, _, path = line.split(" ", 2)
+            objects[path] = oid
+
+        return objects
+
+    def _verify_signature(self) -> None:
+        if not (self.source_keyring or self.source_allowed_signers):
+            return
+
+        if self.source_tag:
+            verify_command = ["verify-tag", self.source_tag]
+        else:
+            verify_command = ["verify-commit", "HEAD"]
+
+        ref = self.source_tag or self.source_commit or self.source_branch or "HEAD"
+
+        # Use a private keyring so that only the provided keys are trusted.
+        with tempfile.TemporaryDirectory() as gnupg_home:
+            if self.source_keyring:
+                _run(
+                    [
+                        "gpg",
+                        "--batch",
+                        "--quiet",
+                        "--homedir",
+                        gnupg_home,
+                        "--import",
+                        os.path.abspath(self.source_keyring),
+                    ]
+                )
+
+            if self.source_allowed_signers:
+                allowed_signers = os.path.abspath(self.source_allowed_signers)
+            else:
+                allowed_signers = os.path.join(gnupg_home, "allowed_signers")
+                Path(allowed_signers).touch()
+
+            command = [
+                self.command,
+                "-C",
+                self.part_src_dir,
+                "-c",
+                f"gpg.ssh.allowedSignersFile={allowed_signers}",
+                *verify_command,
+            ]
+            logger.debug("Running: %s", " ".join(command))
+            try:
+                subprocess.run(
+                    command, check=True, env={**os.environ, "GNUPGHOME": gnupg_home}
+                )
+            except subprocess.CalledProcessError as err:
+                raise errors.SignatureVerificationFailed(self.source, ref=ref) from err
+
+    def get_source_date_epoch(self) -> Optional[int]:
+        """Obtain the commit time of the pulled revision.
+
+        :return: The commit timestamp in seconds since the epoch.
+
+        :raise errors.PullError: If the commit time can't be obtained.
+        """
+        command = [
+            self.command,
+            "-C",
+            self.part_src_dir,
+            "log",
+            "-1",
+            "--format=%ct",
+            "HEAD",
+        ]
+        try:
+            output = subprocess.check_output(command, universal_newlines=True)
+        except subprocess.CalledProcessError as err:
+            raise errors.PullError(command=command, exit_code=err.returncode) from err
+
+        return int(output.strip())
+
+    def _get_current_commit(self) -> str:
+        command = [self.command, "-C", self.part_src_dir, "rev-parse", "HEAD"]
+        try:
+            output = subprocess.check_output(command, universal_newlines=True)
+        except subprocess.CalledProcessError as err:
+            raise errors.PullError(command=command, exit_code=err.returncode) from err
+
+        return output.strip()
+
+    def _git(self, *args: str) -> None:
+        _run([self.command, "-C", self.part_src_dir, *args])
+
+
+def _run(command: List[str], *, skip_lfs_smudge: bool = True) -> None:
+    logger.debug("Running: %s", " ".join(command))
+    # LFS objects are pulled after checkout to verify and cache them, don't
+    # let the git-lfs smudge filter download them.
+    env = {**os.environ, "GIT_LFS_SKIP_SMUDGE": "1"} if skip_lfs_smudge else None
+    try:
+        subprocess.run(command, check=True, env=env)
+    except subprocess.CalledProcessError as err:
+        raise errors.PullError(command=command, exit_code=err.returncode) from err
@@ -19,7 +19,7 @@
 import functools
 import glob
 import os
-from typing import List, Optional
+from typing import Dict, List, Optional
 
 from craft_parts.utils import file_utils
 
@@ -106,6 +106,34 @@ class LocalSource(SourceHandler):
 
         return len(self._updated_files) > 0 or len(self._updated_directories) > 0
 
+    def get_file_times(self) -> Dict[str, int]:
+        """Obtain the modification times of the files pulled from the source.
+
+        Entries removed while the source is being scanned are not included.
+
+        :return: A dictionary mapping the paths of files and directories,
+            relative to the source, to their modification times in nanoseconds.
+        """
+        file_times: Dict[str, int] = {}
+        for (root, directories, files) in os.walk(self.source_abspath, topdown=True):
+            ignored = set(self._ignore(root, directories + files))
+            directories[:] = [d for d in directories if d not in ignored]
+
+            for name in directories + sorted(set(files) - ignored):
+                path = os.path.join(root, name)
+                relpath = os.path.relpath(path, self.source_abspath)
+                try:
+                    file_times[relpath] = os.lstat(path).st_mtime_ns
+                except FileNotFoundError:
+                    # The entry was removed while the source was being scanned.
+                    continue
+
+        return file_times
+
+    def get_outdated_files(self) -> List[str]:
+        """Obtain the files found to be outdated by :meth:`check_if_outdated`."""
+        return sorted(self._updated_files | self._updated_directories)
+
     def update(self):
         """Update pulled source."""
         # First, copy the directories
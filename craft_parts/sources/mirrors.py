@@ -0,0 +1,128 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Rewrite source URLs to point to mirrors.
+
+Mirror rules allow builders without access to the original locations to
+download sources from internal mirrors, without changing the parts
+specification. Rules can be set when creating the lifecycle manager, or
+in the ``CRAFT_SOURCE_MIRRORS`` environment variable as a whitespace
+separated list of ``<prefix>=<mirror>`` entries, split at the last equals
+sign. Entries starting with ``re:`` are regular expressions, and the mirror
+can refer to groups in the expression.
+"""
+
+import logging
+import os
+import re
+from dataclasses import dataclass
+from typing import Dict, List, Optional, Sequence
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+MIRRORS_ENVIRONMENT_VARIABLE = "CRAFT_SOURCE_MIRRORS"
+
+
+@dataclass(frozen=True)
+class MirrorRule:
+    """A rule to rewrite source URLs.
+
+    :param pattern: The URL prefix to replace, or a regular expression
+        matching the start of the URL if ``regex`` is set.
+    :param mirror: The replacement for the matched prefix. Regular
+        expression rules can refer to groups using ``\\1`` or ``\\g<name>``.
+    :param regex: Whether the pattern is a regular expression.
+
+    :raise errors.InvalidMirrorRule: If the pattern is not a valid regular
+        expression.
+    """
+
+    pattern: str
+    mirror: str
+    regex: bool = False
+
+    def __post_init__(self):
+        if not self.pattern:
+            raise errors.InvalidMirrorRule(self.pattern, message="empty pattern")
+
+        if self.regex:
+            try:
+                re.compile(self.pattern)
+            except re.error as err:
+                raise errors.InvalidMirrorRule(self.pattern, message=str(err)) from err
+
+    def rewrite(self, url: str) -> Optional[str]:
+        """Rewrite the given URL if it matches this rule.
+
+        :param url: The URL to rewrite.
+
+        :return: The rewritten URL, or None if the rule doesn't match.
+        """
+        if self.regex:
+            match = re.match(self.pattern, url)
+            if not match:
+                return None
+            return match.expand(self.mirror) + url[match.end() :]
+
+        if not url.startswith(self.pattern):
+            return None
+        return self.mirror + url[len(self.pattern) :]
+
+
+def rewrite_source(source: str, rules: Sequence[MirrorRule]) -> str:
+    """Rewrite a source URL using the first matching mirror rule.
+
+    :param source: The source URL.
+    :param rules: The mirror rules to try, in order.
+
+    :return: The rewritten URL, or the original URL if no rule matches.
+    """
+    for rule in rules:
+        url = rule.rewrite(source)
+        if url is not None:
+            logger.debug("Using mirror %r for source %r", url, source)
+            return url
+
+    return source
+
+
+def get_environment_rules(env: Optional[Dict[str, str]] = None) -> List[MirrorRule]:
+    """Obtain the mirror rules set in the environment.
+
+    :param env: The environment to read rules from. Defaults to the current
+        process environment.
+
+    :return: The list of mirror rules.
+
+    :raise errors.InvalidMirrorRule: If an entry is malformed.
+    """
+    if env is None:
+        env = dict(os.environ)
+
+    rules: List[MirrorRule] = []
+    for entry in env.get(MIRRORS_ENVIRONMENT_VARIABLE, "").split():
+        pattern, sep, mirror = entry.rpartition("=")
+        if not sep or not mirror:
+            raise errors.InvalidMirrorRule(entry, message="missing mirror")
+
+        if pattern.startswith("re:"):
+            rules.append(MirrorRule(pattern[3:], mirror, regex=True))
+        else:
+            rules.append(MirrorRule(pattern, mirror))
+
+    return rules
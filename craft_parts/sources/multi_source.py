@@ -0,0 +1,136 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Implement the handler for parts with more than one source."""
+
+import os
+from typing import List, Optional, Union
+
+from craft_parts.dirs import ProjectDirs
+
+from . import errors
+from .base import SourceHandler
+
+
+class MultiSource(SourceHandler):
+    """The handler for a part with more than one source.
+
+    Each source is handled by its own source handler, which pulls it into
+    its own directory. Sources are pulled in the order they are listed, so
+    a source can add files on top of the ones pulled before it.
+    """
+
+    def __init__(
+        self,
+        part_src_dir,
+        *,
+        handlers: List[SourceHandler],
+        application_name: Optional[str] = None,
+        project_dirs: Optional[ProjectDirs] = None,
+    ):
+        super().__init__(
+            ", ".join(handler.source for handler in handlers),
+            part_src_dir,
+            application_name=application_name,
+            project_dirs=project_dirs,
+        )
+        self.handlers = handlers
+
+    def pull(self) -> None:
+        """Pull all sources into their target directories."""
+        previous_details = self.previous_details or {}
+        for handler in self.handlers:
+            os.makedirs(handler.part_src_dir, exist_ok=True)
+            handler.previous_details = previous_details.get(handler.source)
+            handler.pull()
+
+        source_details = {
+            handler.source: handler.source_details
+            for handler in self.handlers
+            if handler.source_details
+        }
+        self.source_details = source_details or None
+
+    def check_if_outdated(
+        self, target: str, *, ignore_files: Optional[List[str]] = None
+    ) -> bool:
+        """Check if any of the pulled sources changed since target was created.
+
+        Sources that can't check for changes are not verified.
+
+        :param target: Path to target file.
+        :param ignore_files: Files excluded from verification.
+
+        :return: Whether the sources are outdated.
+
+        :raise errors.SourceUpdateUnsupported: If none of the source handlers
+            can check if files are outdated.
+        """
+        outdated = False
+        supported = False
+        for handler in self.handlers:
+            try:
+                # Check all sources, handlers record what must be updated.
+                handler_outdated = handler.check_if_outdated(
+                    target, ignore_files=ignore_files
+                )
+            except errors.SourceUpdateUnsupported:
+                continue
+
+            supported = True
+            outdated = outdated or handler_outdated
+
+        if not supported:
+            raise errors.SourceUpdateUnsupported(self.__class__.__name__)
+
+        return outdated
+
+    def update(self):
+        """Update the pulled sources that support updates.
+
+        :raise errors.SourceUpdateUnsupported: If none of the sources can
+            update their files.
+        """
+        supported = False
+        for handler in self.handlers:
+            try:
+                handler.update()
+            except errors.SourceUpdateUnsupported:
+                continue
+            supported = True
+
+        if not supported:
+            raise errors.SourceUpdateUnsupported(self.__class__.__name__)
+
+
+    def get_source_date_epoch(self) -> Optional[int]:
+        """Obtain the timestamp of the most recent of the pulled sources.
+
+        :return: The timestamp in seconds since the epoch, or None if no
+            source has a timestamp.
+        """
+        timestamps = [handler.get_source_date_epoch() for handler in self.handlers]
+        valid_timestamps = [x for x in timestamps if x is not None]
+        return max(valid_timestamps) if valid_timestamps else None
+
+
+def get_target_dir(part_src_dir: Union[str, os.PathLike], target: str) -> str:
+    """Obtain the directory a source with the given target is pulled into.
+
+    :param part_src_dir: The part source directory.
+    :param target: The source target subdirectory.
+    """
+    return os.path.join(part_src_dir, target) if target else str(part_src_dir)
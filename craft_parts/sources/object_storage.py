@@ -0,0 +1,82 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Download source files from object storage services.
+
+Objects are copied using the command line tool of each service, ``aws``
+for ``s3://`` URLs and ``gcloud`` for ``gs://`` URLs. Credentials are
+obtained by the tools from their usual environment variables and
+configuration files, so they never appear in the part specification.
+"""
+
+import logging
+import subprocess
+from typing import Optional
+
+from craft_parts.utils import url_utils
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+OBJECT_STORAGE_SCHEMES = ["s3", "gs"]
+
+
+def is_object_storage_url(url: str) -> bool:
+    """Verify whether the given URL refers to an object in object storage.
+
+    :param url: The URL to verify.
+    """
+    return url_utils.get_url_scheme(url) in OBJECT_STORAGE_SCHEMES
+
+
+def download_object(
+    url: str,
+    destination: str,
+    *,
+    region: Optional[str] = None,
+    profile: Optional[str] = None,
+) -> None:
+    """Copy an object from object storage to a local file.
+
+    :param url: The ``s3://`` or ``gs://`` URL of the object.
+    :param destination: The file to copy the object to.
+    :param region: The region of the S3 bucket.
+    :param profile: The AWS profile or gcloud configuration to use.
+
+    :raise errors.PullError: If the object can't be copied.
+    """
+    scheme = url_utils.get_url_scheme(url)
+    if scheme == "s3":
+        command = ["aws", "s3", "cp", "--only-show-errors"]
+        if region:
+            command.extend(["--region", region])
+        if profile:
+            command.extend(["--profile", profile])
+    elif scheme == "gs":
+        command = ["gcloud", "storage", "cp"]
+        if profile:
+            command.extend(["--configuration", profile])
+    else:
+        raise ValueError(f"{url!r} is not an object storage URL")
+
+    command.extend([url, destination])
+
+    logger.debug("Running: %s", " ".join(command))
+    try:
+        subprocess.run(command, check=True)
+    except subprocess.CalledProcessError as err:
+        raise errors.PullError(command=command, exit_code=err.returncode) from err
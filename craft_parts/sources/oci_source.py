@@ -0,0 +1,327 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Implement the OCI image source handler."""
+
+import json
+import logging
+import os
+import shutil
+import subprocess
+import tarfile
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, Callable, Dict, List, Optional, Union
+
+from craft_parts.dirs import ProjectDirs
+
+from . import checksum, errors
+from .base import SourceHandler
+from .cache import FileCache
+
+if TYPE_CHECKING:
+    from craft_parts.parts import SourceAuthSpec, SubmoduleSpec
+
+logger = logging.getLogger(__name__)
+
+# The tag used to reference the pulled image in the local OCI layout.
+_IMAGE_TAG = "craft-parts"
+
+_WHITEOUT_PREFIX = ".wh."
+_OPAQUE_WHITEOUT = ".wh..wh..opq"
+
+# Entries are sanitized before extraction, links to absolute paths are
+# expected in root filesystems.
+_EXTRACT_ARGS: Dict[str, Any] = (
+    {"filter": "fully_trusted"} if hasattr(tarfile, "fully_trusted_filter") else {}
+)
+
+
+# pylint: disable=too-many-arguments
+class OciSource(SourceHandler):
+    """The OCI image source handler.
+
+    The source is either a reference to an image in a container registry,
+    such as ``docker.io/library/ubuntu:22.04``, or the path to a local
+    oci-archive file. References can also be given as any image transport
+    supported by skopeo, such as ``docker://``. The image is copied using
+    skopeo and its layers are unpacked in the part source directory.
+
+    If ``source-checksum`` is set, it must match the digest of the image
+    manifest, e.g. ``sha256/<digest>``.
+    """
+
+    source_options = ["source-checksum"]
+
+    def __init__(
+        self,
+        source,
+        part_src_dir,
+        *,
+        application_name: Optional[str] = None,
+        source_tag: Optional[str] = None,
+        source_commit: Optional[str] = None,
+        source_branch: Optional[str] = None,
+        source_depth: Optional[int] = None,
+        source_checksum: Optional[str] = None,
+        source_submodules: Optional[List[Union[str, "SubmoduleSpec"]]] = None,
+        source_sparse_paths: Optional[List[str]] = None,
+        source_lfs: Optional[bool] = None,
+        source_keyring: Optional[str] = None,
+        source_allowed_signers: Optional[str] = None,
+        source_checksum_signature: Optional[str] = None,
+        source_auth: Optional["SourceAuthSpec"] = None,
+        source_region: Optional[str] = None,
+        source_profile: Optional[str] = None,
+        project_dirs: Optional[ProjectDirs] = None,
+        cache: Optional[FileCache] = None,
+        credentials_provider: Optional[Callable[[str], Dict[str, str]]] = None,
+    ):
+        super().__init__(
+            source,
+            part_src_dir,
+            application_name=application_name,
+            source_tag=source_tag,
+            source_commit=source_commit,
+            source_branch=source_branch,
+            source_depth=source_depth,
+            source_checksum=source_checksum,
+            source_submodules=source_submodules,
+            source_sparse_paths=source_sparse_paths,
+            source_lfs=source_lfs,
+            source_keyring=source_keyring,
+            source_allowed_signers=source_allowed_signers,
+            source_checksum_signature=source_checksum_signature,
+            source_auth=source_auth,
+            source_region=source_region,
+            source_profile=source_profile,
+            project_dirs=project_dirs,
+            cache=cache,
+            credentials_provider=credentials_provider,
+        )
+        if source_tag:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-tag")
+
+        if source_commit:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-commit")
+
+        if source_branch:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-branch")
+
+        if source_depth:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-depth")
+
+        if source_submodules:
+            raise errors.InvalidSourceOption(
+                source_type="oci", option="source-submodules"
+            )
+
+        if source_sparse_paths:
+            raise errors.InvalidSourceOption(
+                source_type="oci", option="source-sparse-paths"
+            )
+
+        if source_lfs is not None:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-lfs")
+
+        if source_keyring:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-keyring")
+
+        if source_allowed_signers:
+            raise errors.InvalidSourceOption(
+                source_type="oci", option="source-allowed-signers"
+            )
+
+        # Image digests can't be obtained from checksum files.
+        if source_checksum and checksum.is_checksum_file(source_checksum):
+            raise errors.InvalidSourceOption(
+                source_type="oci", option="source-checksum"
+            )
+
+        if source_checksum_signature:
+            raise errors.InvalidSourceOption(
+                source_type="oci", option="source-checksum-signature"
+            )
+
+        if source_auth:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-auth")
+
+        if source_region:
+            raise errors.InvalidSourceOption(source_type="oci", option="source-region")
+
+        if source_profile:
+            raise errors.InvalidSourceOption(
+                source_type="oci", option="source-profile"
+            )
+
+        # The image layout is kept out of the source tree.
+        self._layout_dir = Path(self.part_src_dir).parent / "oci"
+
+    def pull(self) -> None:
+        """Copy the image and unpack its layers in the part source dir."""
+        if self._layout_dir.exists():
+            shutil.rmtree(self._layout_dir)
+        self._layout_dir.mkdir(parents=True)
+
+        command = [
+            "skopeo",
+            "copy",
+            "--quiet",
+            self._get_image_transport(),
+            f"oci:{self._layout_dir}:{_IMAGE_TAG}",
+        ]
+        try:
+            subprocess.run(command, check=True)
+        except subprocess.CalledProcessError as err:
+            raise errors.PullError(command=command, exit_code=err.returncode) from err
+
+        digest = self._get_manifest_digest()
+        if self.source_checksum:
+            expected = self.source_checksum.replace("/", ":", 1)
+            if digest != expected:
+                raise errors.ChecksumMismatch(expected=expected, obtained=digest)
+
+        manifest = _read_json(self._blob_path(digest))
+        for layer in manifest.get("layers", []):
+            self._unpack_layer(self._blob_path(layer["digest"]))
+
+        self.source_details = {"digest": digest}
+
+    def _get_image_transport(self) -> str:
+        """Obtain the skopeo image name for the source."""
+        if os.path.isfile(self.source):
+            return f"oci-archive:{os.path.abspath(self.source)}"
+
+        if "://" in self.source:
+            return self.source
+
+        return f"docker://{self.source}"
+
+    def _get_manifest_digest(self) -> str:
+        """Obtain the digest of the image manifest from the layout index."""
+        index = _read_json(self._layout_dir / "index.json")
+        for manifest in index.get("manifests", []):
+            annotations = manifest.get("annotations", {})
+            if annotations.get("org.opencontainers.image.ref.name") == _IMAGE_TAG:
+                return manifest["digest"]
+
+        raise errors.InvalidOciImage(self.source, message="image manifest not found")
+
+    def _blob_path(self, digest: str) -> Path:
+        algorithm, _, encoded = digest.partition(":")
+        return self._layout_dir / "blobs" / algorithm / encoded
+
+    def _unpack_layer(self, layer_file: Path) -> None:
+        """Apply an image layer on top of the part source dir."""
+        dst = Path(self.part_src_dir)
+
+        try:
+            with tarfile.open(layer_file) as tar:
+                members: List[tarfile.TarInfo] = []
+
+                # Whiteouts remove content from lower layers only, so they
+                # are processed before extracting the layer content.
+                for member in tar.getmembers():
+                    name = _normalize_name(member.name)
+                    if name is None:
+                        logger.debug("skip unsafe layer entry %r", member.name)
+                        continue
+
+                    dirname, basename = os.path.split(name)
+                    if basename.startswith(_WHITEOUT_PREFIX):
+                        self._check_parents(name, dst=dst)
+
+                    if basename == _OPAQUE_WHITEOUT:
+                        _clear_directory(dst / dirname)
+                    elif basename.startswith(_WHITEOUT_PREFIX):
+                        _remove(dst / dirname / basename[len(_WHITEOUT_PREFIX) :])
+                    elif member.isdev():
+                        logger.debug("skip device node %r", name)
+                    else:
+                        member.name = name
+                        members.append(member)
+
+                for member in members:
+                    self._extract_member(tar, member, dst=dst)
+        except tarfile.TarError as err:
+            raise errors.InvalidOciImage(
+                self.source, message=f"cannot unpack layer {layer_file.name!r}: {err}"
+            ) from err
+
+    def _check_parents(self, name: str, *, dst: Path) -> None:
+        """Verify that a layer entry is not under a symbolic link.
+
+        Layer content is never written or removed through symbolic links,
+        they can point outside the source directory.
+        """
+        parent = dst
+        for part in Path(name).parent.parts:
+            parent = parent / part
+            if parent.is_symlink():
+                raise errors.InvalidOciImage(
+                    self.source,
+                    message=f"layer entry {name!r} is under a symbolic link",
+                )
+
+    def _extract_member(
+        self, tar: tarfile.TarFile, member: tarfile.TarInfo, *, dst: Path
+    ) -> None:
+        self._check_parents(member.name, dst=dst)
+
+        if member.islnk():
+            linkname = _normalize_name(member.linkname)
+            if linkname is None:
+                logger.debug("skip unsafe hard link %r", member.name)
+                return
+            self._check_parents(linkname, dst=dst)
+            member.linkname = linkname
+
+        target = dst / member.name
+        if not (member.isdir() and target.is_dir() and not target.is_symlink()):
+            _remove(target)
+
+        # We mask all files to be writable to be able to easily extract
+        # on top.
+        member.mode = member.mode | 0o200
+
+        tar.extract(member, path=str(dst), **_EXTRACT_ARGS)
+
+
+def _normalize_name(name: str) -> Optional[str]:
+    """Make a layer entry name relative to the root, or None if unsafe."""
+    name = os.path.normpath(name.lstrip("/"))
+    if name == "." or name == ".." or name.startswith("../"):
+        return None
+    return name
+
+
+def _read_json(path: Path) -> Dict[str, Any]:
+    with path.open() as json_file:
+        return json.load(json_file)
+
+
+def _remove(path: Path) -> None:
+    if path.is_dir() and not path.is_symlink():
+        shutil.rmtree(path)
+    elif path.exists() or path.is_symlink():
+        path.unlink()
+
+
+def _clear_directory(path: Path) -> None:
+    if not path.is_dir() or path.is_symlink():
+        return
+
+    for entry in path.iterdir():
+        _remove(entry)
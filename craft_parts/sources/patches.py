@@ -0,0 +1,99 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+"""Apply patches to pulled sources."""
+
+import hashlib
+import logging
+import os
+import subprocess
+from pathlib import Path
+from typing import Dict, List, Union
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+
+def get_patch_files(patches: List[str]) -> List[Path]:
+    """Obtain the patch files to apply, in order.
+
+    Directories are expanded to the files they contain, sorted by name.
+
+    :param patches: The patch files or directories containing patch files.
+
+    :return: The list of patch files.
+    """
+    patch_files: List[Path] = []
+    for patch in patches:
+        path = Path(patch)
+        if path.is_dir():
+            patch_files.extend(sorted(p for p in path.iterdir() if p.is_file()))
+        else:
+            patch_files.append(path)
+
+    return patch_files
+
+
+def get_digests(patches: List[str]) -> Dict[str, str]:
+    """Obtain the digests of the patch files to apply.
+
+    Patch files that don't exist are not listed.
+
+    :param patches: The patch files or directories containing patch files.
+
+    :return: A dictionary mapping patch file paths to their SHA256 digests.
+    """
+    digests: Dict[str, str] = {}
+    for patch_file in get_patch_files(patches):
+        if patch_file.is_file():
+            data = patch_file.read_bytes()
+            digests[str(patch_file)] = hashlib.sha256(data).hexdigest()
+
+    return digests
+
+
+def apply_patches(
+    patches: List[str], *, src_dir: Union[str, Path], strip: int = 1
+) -> None:
+    """Apply patches to the pulled source tree.
+
+    :param patches: The patch files or directories containing patch files.
+    :param src_dir: The directory containing the source tree to patch.
+    :param strip: The number of leading path components to remove from
+        file names in the patches.
+
+    :raise errors.PatchError: If a patch can't be applied.
+    """
+    for patch_file in get_patch_files(patches):
+        if not patch_file.is_file():
+            raise errors.PatchError(str(patch_file), message="file not found")
+
+        command = [
+            "patch",
+            "--batch",
+            "--forward",
+            f"--strip={strip}",
+            f"--directory={src_dir}",
+            f"--input={os.path.abspath(patch_file)}",
+        ]
+        logger.debug("Running: %s", " ".join(command))
+        try:
+            subprocess.run(command, check=True)
+        except subprocess.CalledProcessError as err:
+            raise errors.PatchError(
+                str(patch_file), message=f"patch exited with code {err.returncode}"
+            ) from err
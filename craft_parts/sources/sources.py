@@ -25,13 +25,24 @@ code for that part, and how to unpack it if necessary.
     A URL or path to some source tree to build. It can be local
     ('./src/foo') or remote ('https://foo.org/...'), and can refer to a
     directory tree or a tarball or a revision control repository
-    ('git:...').
+    ('git:...'). Files can also be downloaded from object storage
+    ('s3://bucket/...' or 'gs://bucket/...').
 
-  - source-type: git, bzr, hg, svn, tar, deb, rpm, or zip
+  - source: [{source: url-or-path, target: <path>, ...}, ...]
+
+    A list of sources to combine in the part. Each entry takes the source
+    keys described below, except source-subdir, and a target subdirectory
+    of the part source directory to pull it into. Sources are pulled in
+    the order they are listed, into the part source directory itself if
+    no target is given.
+
+  - source-type: git, bzr, hg, svn, tar, deb, rpm, zip, or oci
 
     In some cases the source string is not enough to identify the version
     control system or compression algorithm. The source-type key can tell
-    Craft Parts exactly how to treat that content.
+    Craft Parts exactly how to treat that content. Container images are
+    never inferred: a registry image reference or an oci-archive file
+    requires the oci source type.
 
   - source-checksum: <algorithm>/<digest>
 
@@ -40,6 +51,18 @@ code for that part, and how to unpack it if necessary.
     the algorithm either md5, sha1, sha224, sha256, sha384, sha512, sha3_256,
     sha3_384 or sha3_512.
 
+  - source-checksum: <algorithm>/<checksum-file>
+
+    The digest can also be obtained from a checksum file published with
+    the source, such as SHA256SUMS. The checksum file can be a local path
+    or a URL, and lists digests computed with the given algorithm.
+
+  - source-checksum-signature: <path-or-url>
+
+    A detached OpenPGP signature of the checksum file. If a source-keyring
+    is given, the checksum file must be signed by one of its keys, either
+    with this signature or by being clearsigned.
+
   - source-depth: <integer>
 
     By default clones or branches with full history, specifying a depth
@@ -60,6 +83,66 @@ code for that part, and how to unpack it if necessary.
     Craft Parts will checkout the specific tag from the source tree revision
     control system.
 
+  - source-submodules: [<path> | {path: <path>, branch: <branch>, depth: <integer>}]
+
+    Git submodules to fetch. By default all submodules are fetched; an
+    empty list disables fetching submodules. Submodules can be listed by
+    path, or with a branch to track and a clone depth.
+
+  - source-sparse-paths: [<path>, ...]
+
+    Craft Parts will only check out the given directories from a git
+    repository, in addition to the files at the top of the source tree.
+
+  - source-lfs: <boolean>
+
+    Git LFS objects of files matching the LFS patterns in .gitattributes
+    are fetched by default, verified and stored in the download cache.
+    Setting it to false leaves LFS pointer files in the source tree.
+
+  - source-keyring: <path>
+
+    Craft Parts will verify that the checked out git tag or commit, or the
+    checksum file of a file source, is signed by one of the OpenPGP public
+    keys in the given keyring file.
+
+  - source-allowed-signers: <path>
+
+    Craft Parts will verify that the checked out git tag or commit is
+    signed by one of the SSH keys in the given allowed signers file, in
+    the format described in ssh-keygen(1).
+
+  - source-auth: {token-env: <var>, username-env: <var>,
+                  password-env: <var>, headers-env: {<header>: <var>}}
+
+    Credentials used to download url sources, given as the names of the
+    environment variables containing them. The token is sent as a bearer
+    token, the username and password using basic authentication, and each
+    header with the value of its variable. Credentials are never stored in
+    the part state.
+
+  - source-region: <region>
+
+    The region of the bucket containing an s3:// source file.
+
+  - source-profile: <name>
+
+    The AWS profile used to download an s3:// source file, or the gcloud
+    configuration used to download a gs:// source file. Credentials are
+    obtained by the aws and gcloud tools from their usual environment
+    variables and configuration files.
+
+  - source-patches: [<path>, ...]
+
+    Patch files to apply to the pulled source tree, in order. A directory
+    can be given to apply all files it contains, sorted by name. Changes
+    to the patches cause the part to be pulled again.
+
+  - source-patches-strip: <integer>
+
+    The number of leading path components to remove from file names in
+    the patches. Defaults to 1.
+
   - source-subdir: path
 
     When building, Snapcraft will set the working directory to be this
@@ -73,59 +156,154 @@ cases you want to refer to the documentation for the specific plugin.
 
 import os
 import re
-from typing import TYPE_CHECKING, Dict, Optional, Type
+from pathlib import Path
+from typing import (
+    TYPE_CHECKING,
+    Callable,
+    Dict,
+    List,
+    Optional,
+    Sequence,
+    Type,
+    Union,
+    cast,
+)
 
 from craft_parts.dirs import ProjectDirs
 
 from . import errors
 from .base import SourceHandler
+from .cache import FileCache
+from .git_source import GitSource
 from .local_source import LocalSource
+from .mirrors import MirrorRule, rewrite_source
+from .multi_source import MultiSource, get_target_dir
+from .oci_source import OciSource
 from .tar_source import TarSource
 
 if TYPE_CHECKING:
-    from craft_parts.parts import Part
+    from craft_parts.parts import Part, PartSpec, SourceSpec
 
 SourceHandlerType = Type[SourceHandler]
 
 
 _source_handler: Dict[str, SourceHandlerType] = {
+    "git": GitSource,
     "local": LocalSource,
+    "oci": OciSource,
     "tar": TarSource,
 }
 
 
+def get_source_options() -> Dict[str, List[str]]:
+    """Obtain the options accepted by each source type.
+
+    :return: A dictionary where the keys are source types and values are
+        the names of the source options supported by the type.
+    """
+    return {
+        source_type: list(handler.source_options)
+        for source_type, handler in _source_handler.items()
+    }
+
+
 def get_source_handler(
     application_name: str,
     part: "Part",
     project_dirs: ProjectDirs,
+    *,
+    cache: Optional[FileCache] = None,
+    credentials_provider: Optional[Callable[[str], Dict[str, str]]] = None,
+    mirrors: Sequence[MirrorRule] = (),
 ) -> Optional[SourceHandler]:
     """Return the appropriate handler for the given source.
 
     :param application_name: The name of the application using Craft Parts.
     :param part: The part to get a source handler for.
     :param project_dirs: The project's work directories.
+    :param cache: The cache for downloaded files. Defaults to a cache
+        specific to the application.
+    :param credentials_provider: A function returning the HTTP headers
+        needed to download a source file from the given URL.
+    :param mirrors: The rules used to rewrite source URLs to mirrors.
     """
-    source_handler = None
-    if part.spec.source:
-        handler_class = _get_source_handler_class(
-            part.spec.source,
-            source_type=part.spec.source_type,
-        )
-        source_handler = handler_class(
+    source_handler: Optional[SourceHandler] = None
+    if isinstance(part.spec.source, list):
+        source_handler = MultiSource(
+            part.part_src_dir,
+            handlers=[
+                _create_source_handler(
+                    spec,
+                    part_src_dir=get_target_dir(part.part_src_dir, spec.target),
+                    application_name=application_name,
+                    project_dirs=project_dirs,
+                    cache=cache,
+                    credentials_provider=credentials_provider,
+                    mirrors=mirrors,
+                )
+                for spec in part.spec.source
+            ],
             application_name=application_name,
-            source=part.spec.source,
+            project_dirs=project_dirs,
+        )
+    elif part.spec.source:
+        source_handler = _create_source_handler(
+            part.spec,
             part_src_dir=part.part_src_dir,
-            source_checksum=part.spec.source_checksum,
-            source_branch=part.spec.source_branch,
-            source_tag=part.spec.source_tag,
-            source_depth=part.spec.source_depth,
-            source_commit=part.spec.source_commit,
+            application_name=application_name,
             project_dirs=project_dirs,
+            cache=cache,
+            credentials_provider=credentials_provider,
+            mirrors=mirrors,
         )
 
     return source_handler
 
 
+def _create_source_handler(
+    spec: Union["PartSpec", "SourceSpec"],
+    *,
+    part_src_dir: Union[str, Path],
+    application_name: str,
+    project_dirs: ProjectDirs,
+    cache: Optional[FileCache],
+    credentials_provider: Optional[Callable[[str], Dict[str, str]]],
+    mirrors: Sequence[MirrorRule],
+) -> SourceHandler:
+    """Create the handler for a single source.
+
+    The source type is determined from the original source, so a mirror
+    can use a different URL layout.
+
+    :param spec: The part or source entry specifying the source.
+    :param part_src_dir: The directory to pull the source into.
+    """
+    source = cast(str, spec.source)
+    handler_class = _get_source_handler_class(source, source_type=spec.source_type)
+    return handler_class(
+        application_name=application_name,
+        source=rewrite_source(source, mirrors),
+        part_src_dir=part_src_dir,
+        source_checksum=spec.source_checksum,
+        source_branch=spec.source_branch,
+        source_tag=spec.source_tag,
+        source_depth=spec.source_depth,
+        source_commit=spec.source_commit,
+        source_submodules=spec.source_submodules,
+        source_sparse_paths=spec.source_sparse_paths,
+        source_lfs=spec.source_lfs,
+        source_keyring=spec.source_keyring,
+        source_allowed_signers=spec.source_allowed_signers,
+        source_checksum_signature=spec.source_checksum_signature,
+        source_auth=spec.source_auth,
+        source_region=spec.source_region,
+        source_profile=spec.source_profile,
+        project_dirs=project_dirs,
+        cache=cache,
+        credentials_provider=credentials_provider,
+    )
+
+
 def _get_source_handler_class(source, *, source_type: str = "") -> SourceHandlerType:
     """Return the appropriate handler class for the given source.
 
@@ -21,18 +21,32 @@ import re
 import shutil
 import tarfile
 import tempfile
-from typing import Optional
+from typing import TYPE_CHECKING, Callable, Dict, List, Optional, Union
 
 from craft_parts.dirs import ProjectDirs
+from craft_parts.utils import url_utils
 
-from . import errors
+from . import checksum, errors, object_storage
 from .base import FileSourceHandler
+from .cache import FileCache
+
+if TYPE_CHECKING:
+    from craft_parts.parts import SourceAuthSpec, SubmoduleSpec
 
 
 # pylint: disable=too-many-arguments
 class TarSource(FileSourceHandler):
     """The tar source handler."""
 
+    source_options = [
+        "source-checksum",
+        "source-keyring",
+        "source-checksum-signature",
+        "source-auth",
+        "source-region",
+        "source-profile",
+    ]
+
     def __init__(
         self,
         source,
@@ -44,7 +58,18 @@ class TarSource(FileSourceHandler):
         source_branch: Optional[str] = None,
         source_depth: Optional[int] = None,
         source_checksum: Optional[str] = None,
+        source_submodules: Optional[List[Union[str, "SubmoduleSpec"]]] = None,
+        source_sparse_paths: Optional[List[str]] = None,
+        source_lfs: Optional[bool] = None,
+        source_keyring: Optional[str] = None,
+        source_allowed_signers: Optional[str] = None,
+        source_checksum_signature: Optional[str] = None,
+        source_auth: Optional["SourceAuthSpec"] = None,
+        source_region: Optional[str] = None,
+        source_profile: Optional[str] = None,
         project_dirs: Optional[ProjectDirs] = None,
+        cache: Optional[FileCache] = None,
+        credentials_provider: Optional[Callable[[str], Dict[str, str]]] = None,
     ):
         super().__init__(
             source,
@@ -55,7 +80,18 @@ class TarSource(FileSourceHandler):
             source_branch=source_branch,
             source_depth=source_depth,
             source_checksum=source_checksum,
+            source_submodules=source_submodules,
+            source_sparse_paths=source_sparse_paths,
+            source_lfs=source_lfs,
+            source_keyring=source_keyring,
+            source_allowed_signers=source_allowed_signers,
+            source_checksum_signature=source_checksum_signature,
+            source_auth=source_auth,
+            source_region=source_region,
+            source_profile=source_profile,
             project_dirs=project_dirs,
+            cache=cache,
+            credentials_provider=credentials_provider,
         )
         if source_tag:
             raise errors.InvalidSourceOption(source_type="tar", option="source-tag")
@@ -69,6 +105,43 @@ class TarSource(FileSourceHandler):
         if source_depth:
             raise errors.InvalidSourceOption(source_type="tar", option="source-depth")
 
+        if source_submodules:
+            raise errors.InvalidSourceOption(
+                source_type="tar", option="source-submodules"
+            )
+
+        if source_sparse_paths:
+            raise errors.InvalidSourceOption(
+                source_type="tar", option="source-sparse-paths"
+            )
+
+        if source_lfs is not None:
+            raise errors.InvalidSourceOption(source_type="tar", option="source-lfs")
+
+        # The keyring verifies the signature of the checksum file.
+        is_checksum_file = checksum.is_checksum_file(source_checksum or "")
+        if source_keyring and not is_checksum_file:
+            raise errors.InvalidSourceOption(source_type="tar", option="source-keyring")
+
+        if source_checksum_signature and not source_keyring:
+            raise errors.InvalidSourceOption(
+                source_type="tar", option="source-checksum-signature"
+            )
+
+        if source_allowed_signers:
+            raise errors.InvalidSourceOption(
+                source_type="tar", option="source-allowed-signers"
+            )
+
+        scheme = url_utils.get_url_scheme(self.source)
+        if source_region and scheme != "s3":
+            raise errors.InvalidSourceOption(source_type="tar", option="source-region")
+
+        if source_profile and not object_storage.is_object_storage_url(self.source):
+            raise errors.InvalidSourceOption(
+                source_type="tar", option="source-profile"
+            )
+
     def provision(self, dst, clean_target=True, keep=False, src=None):
         """Extract tarball contents to the part source dir."""
         # TODO add unit tests.
@@ -16,4 +16,5 @@
 
 """Part state management."""
 
+from .state_info import PartStateInfo, StepStateInfo  # noqa: F401
 from .state_manager import StateManager  # noqa: F401
@@ -16,7 +16,7 @@
 
 """State definitions for the build step."""
 
-from typing import Any, Dict
+from typing import Any, Dict, List, Optional
 
 from .step_state import StepState
 
@@ -44,10 +44,16 @@ class BuildState(StepState):
 
         return cls(**data)
 
-    def properties_of_interest(self, part_properties: Dict[str, Any]) -> Dict[str, Any]:
+    def properties_of_interest(
+        self,
+        part_properties: Dict[str, Any],
+        *,
+        extra_properties: Optional[List[str]] = None,
+    ) -> Dict[str, Any]:
         """Return relevant properties concerning this step.
 
         :param part_properties: A dictionary containing all part properties.
+        :param extra_properties: Additional relevant properties to return.
 
         :return: A dictionary containing properties of interest.
         """
@@ -55,11 +61,15 @@ class BuildState(StepState):
             "after",
             "build-attributes",
             "build-packages",
+            "build-snaps",
             "disable-parallel",
             "organize",
             "override-build",
         ]
 
+        if extra_properties:
+            relevant_properties.extend(extra_properties)
+
         properties: Dict[str, Any] = {}
         for name in relevant_properties:
             properties[name] = part_properties.get(name)
@@ -75,4 +85,7 @@ class BuildState(StepState):
 
         :return: A dictionary containing project options of interest.
         """
-        return {}
+        return {
+            "target_arch": project_options.get("target_arch"),
+            "project_vars": project_options.get("project_vars"),
+        }
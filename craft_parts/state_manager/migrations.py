@@ -0,0 +1,93 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Migrations of state data written by earlier versions of craft-parts.
+
+Each migration converts state data from a schema version to the next one.
+States written before schema versioning was introduced have version 0.
+"""
+
+import logging
+from typing import Any, Callable, Dict
+
+from craft_parts import errors
+from craft_parts.parts import PartSpec
+
+logger = logging.getLogger(__name__)
+
+STATE_SCHEMA_VERSION = 2
+"""The schema version of states written by this version of craft-parts."""
+
+
+def migrate_state(
+    data: Dict[str, Any], *, part_name: str, step_name: str
+) -> Dict[str, Any]:
+    """Convert state data to the current schema version.
+
+    :param data: The state data to migrate.
+    :param part_name: The name of the part the state belongs to.
+    :param step_name: The name of the step the state belongs to.
+
+    :return: The migrated state data.
+
+    :raise errors.StateVersionError: If the state was written using a newer
+        schema version.
+    """
+    version = data.get("schema-version", 0)
+    if not isinstance(version, int) or version > STATE_SCHEMA_VERSION:
+        raise errors.StateVersionError(
+            part_name=part_name,
+            step_name=step_name,
+            version=version,
+            supported_version=STATE_SCHEMA_VERSION,
+        )
+
+    while version < STATE_SCHEMA_VERSION:
+        logger.debug(
+            "migrate %s:%s state from version %d", part_name, step_name, version
+        )
+        data = _MIGRATIONS[version](data)
+        version += 1
+        data["schema-version"] = version
+
+    return data
+
+
+def _migrate_0_to_1(data: Dict[str, Any]) -> Dict[str, Any]:
+    """Add default values of part properties missing in unversioned states.
+
+    Properties introduced after a state was written would otherwise differ
+    from the values in the current specification and make the step dirty.
+    """
+    defaults = PartSpec.unmarshal({}).marshal()
+    part_properties = data.get("part-properties") or {}
+    return {**data, "part-properties": {**defaults, **part_properties}}
+
+
+def _migrate_1_to_2(data: Dict[str, Any]) -> Dict[str, Any]:
+    """Keep the absolute paths stored in states written before relocation.
+
+    Paths in the work directory are stored relative to it since version 2.
+    Absolute paths are still valid if the work directory wasn't moved.
+    """
+    return data
+
+
+_MIGRATIONS: Dict[int, Callable[[Dict[str, Any]], Dict[str, Any]]] = {
+    0: _migrate_0_to_1,
+    1: _migrate_1_to_2,
+}
@@ -16,7 +16,7 @@
 
 """State definitions for the prime state."""
 
-from typing import Any, Dict, Set
+from typing import Any, Dict, List, Optional, Set
 
 from .step_state import StepState
 
@@ -26,6 +26,8 @@ class PrimeState(StepState):
 
     dependency_paths: Set[str] = set()
     primed_stage_packages: Set[str] = set()
+    permissions: Dict[str, Dict[str, Any]] = {}
+    elf_patches: Dict[str, Dict[str, Any]] = {}
 
     @classmethod
     def unmarshal(cls, data: Dict[str, Any]) -> "PrimeState":
@@ -45,18 +47,33 @@ class PrimeState(StepState):
 
         return cls(**data)
 
-    def properties_of_interest(self, part_properties: Dict[str, Any]) -> Dict[str, Any]:
+    def properties_of_interest(
+        self,
+        part_properties: Dict[str, Any],
+        *,
+        extra_properties: Optional[List[str]] = None,
+    ) -> Dict[str, Any]:
         """Return relevant properties concerning this step.
 
         :param part_properties: A dictionary containing all part properties.
+        :param extra_properties: Additional relevant properties to return.
 
         :return: A dictionary containing properties of interest.
         """
-        return {
+        properties: Dict[str, Any] = {
+            "overlay": part_properties.get("overlay", ["*"]) or ["*"],
             "override-prime": part_properties.get("override-prime"),
             "prime": part_properties.get("prime", ["*"]) or ["*"],
+            "permissions": part_properties.get("permissions", []) or [],
+            "strip": part_properties.get("strip"),
+            "elf-patch": part_properties.get("elf-patch"),
         }
 
+        for name in extra_properties or []:
+            properties[name] = part_properties.get(name)
+
+        return properties
+
     def project_options_of_interest(
         self, project_options: Dict[str, Any]
     ) -> Dict[str, Any]:
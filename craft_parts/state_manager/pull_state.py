@@ -16,7 +16,7 @@
 
 """State definitions for the pull step."""
 
-from typing import Any, Dict
+from typing import Any, Dict, List, Optional
 
 from .step_state import StepState
 
@@ -44,10 +44,16 @@ class PullState(StepState):
 
         return cls(**data)
 
-    def properties_of_interest(self, part_properties: Dict[str, Any]) -> Dict[str, Any]:
+    def properties_of_interest(
+        self,
+        part_properties: Dict[str, Any],
+        *,
+        extra_properties: Optional[List[str]] = None,
+    ) -> Dict[str, Any]:
         """Return relevant properties concerning this step.
 
         :param part_properties: A dictionary containing all part properties.
+        :param extra_properties: Additional relevant properties to return.
 
         :return: A dictionary containing properties of interest.
         """
@@ -60,10 +66,17 @@ class PullState(StepState):
             "source-type",
             "source-branch",
             "source-subdir",
+            "source-patches",
+            "source-patches-strip",
             "override-pull",
             "stage-packages",
+            "stage-snaps",
+            "stage-snaps-track-channel",
         ]
 
+        if extra_properties:
+            relevant_properties.extend(extra_properties)
+
         properties: Dict[str, Any] = {}
         for name in relevant_properties:
             properties[name] = part_properties.get(name)
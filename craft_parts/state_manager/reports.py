@@ -17,7 +17,7 @@
 """Provide a report on why a step is outdated."""
 
 from dataclasses import dataclass
-from typing import List
+from typing import List, Optional
 
 from craft_parts.steps import Step
 from craft_parts.utils import formatting_utils
@@ -42,15 +42,22 @@ class OutdatedReport:
     """
 
     def __init__(
-        self, *, previous_step_modified: Step = None, source_modified: bool = False
+        self,
+        *,
+        previous_step_modified: Step = None,
+        source_modified: bool = False,
+        modified_source_files: Optional[List[str]] = None,
     ) -> None:
         """Create a new OutdatedReport.
 
         :param previous_step_modified: Step earlier in the lifecycle that has changed.
         :param source_modified: Whether the source changed on disk.
+        :param modified_source_files: The source files and directories that
+            changed on disk, if known.
         """
         self.previous_step_modified = previous_step_modified
         self.source_modified = source_modified
+        self.modified_source_files = modified_source_files
 
     def reason(self) -> str:
         """Get summarized report.
@@ -70,6 +77,28 @@ class OutdatedReport:
 
         return "{} changed".format(formatting_utils.humanize_list(reasons, "and", "{}"))
 
+    def details(self) -> List[str]:
+        """Get a detailed report.
+
+        :return: A list of sentences describing each change that made the
+            step outdated.
+        """
+        details = []
+
+        if self.previous_step_modified:
+            details.append(
+                f"The {self.previous_step_modified.name!r} step ran more recently."
+            )
+
+        if self.source_modified:
+            if self.modified_source_files:
+                for name in sorted(self.modified_source_files):
+                    details.append(f"Source file {name!r} was modified.")
+            else:
+                details.append("The source was modified.")
+
+        return details
+
 
 class DirtyReport:
     """The DirtyReport class explains why a given step is dirty.
@@ -142,3 +171,25 @@ class DirtyReport:
         return "{} changed".format(formatting_utils.humanize_list(reasons, "and", "{}"))
 
     # pylint: enable=too-many-branches
+
+    def details(self) -> List[str]:
+        """Get a detailed report.
+
+        :return: A list of sentences describing each change that made the
+            step dirty.
+        """
+        details = []
+
+        for name in sorted(self.dirty_properties or []):
+            details.append(f"Property {name!r} changed.")
+
+        for name in sorted(self.dirty_project_options or []):
+            details.append(f"Project option {name!r} changed.")
+
+        for dependency in self.changed_dependencies or []:
+            details.append(
+                f"The {dependency.step.name!r} step of dependency "
+                f"{dependency.part_name!r} changed."
+            )
+
+        return details
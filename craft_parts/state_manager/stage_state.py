@@ -16,7 +16,7 @@
 
 """State definitions for the stage step."""
 
-from typing import Any, Dict
+from typing import Any, Dict, List, Optional
 
 from .step_state import StepState
 
@@ -42,19 +42,31 @@ class StageState(StepState):
 
         return cls(**data)
 
-    def properties_of_interest(self, part_properties: Dict[str, Any]) -> Dict[str, Any]:
+    def properties_of_interest(
+        self,
+        part_properties: Dict[str, Any],
+        *,
+        extra_properties: Optional[List[str]] = None,
+    ) -> Dict[str, Any]:
         """Return relevant properties concerning this step.
 
         :param part_properties: A dictionary containing all part properties.
+        :param extra_properties: Additional relevant properties to return.
 
         :return: A dictionary containing properties of interest.
         """
-        return {
+        properties: Dict[str, Any] = {
             "filesets": part_properties.get("filesets", {}) or {},
+            "overlay": part_properties.get("overlay", ["*"]) or ["*"],
             "override-stage": part_properties.get("override-stage"),
             "stage": part_properties.get("stage", ["*"]) or ["*"],
         }
 
+        for name in extra_properties or []:
+            properties[name] = part_properties.get(name)
+
+        return properties
+
     def project_options_of_interest(
         self, project_options: Dict[str, Any]
     ) -> Dict[str, Any]:
@@ -0,0 +1,141 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Structured information about the recorded states of parts."""
+
+import hashlib
+import json
+from dataclasses import dataclass
+from typing import Any, Dict, Optional, Set
+from urllib.parse import unquote
+
+from craft_parts.parts import Part
+from craft_parts.steps import Step
+
+from . import states
+from .step_state import StepState
+
+
+@dataclass(frozen=True)
+class StepStateInfo:
+    """The state recorded when a step ran for a part.
+
+    The properties fingerprint identifies the part properties and project
+    options the step depends on, so steps that ran with the same relevant
+    configuration have the same fingerprint.
+    """
+
+    part_name: str
+    step: Step
+    properties_fingerprint: str
+    part_properties: Dict[str, Any]
+    project_options: Dict[str, Any]
+    assets: Dict[str, Any]
+    files: Set[str]
+    directories: Set[str]
+
+
+@dataclass(frozen=True)
+class PartStateInfo:
+    """The states recorded for the steps that ran for a part.
+
+    Stage package versions are obtained from the packages fetched when the
+    part was pulled.
+    """
+
+    part_name: str
+    steps: Dict[Step, StepStateInfo]
+    stage_packages: Dict[str, str]
+
+    @property
+    def source_details(self) -> Optional[Dict[str, Any]]:
+        """Return the source details resolved when the part was pulled, if any."""
+        pull_state = self.steps.get(Step.PULL)
+        if not pull_state:
+            return None
+        return pull_state.assets.get("source-details")
+
+    @property
+    def build_environment(self) -> Optional[Dict[str, Any]]:
+        """Return the snapshot of the environment the part was built in, if any.
+
+        The snapshot contains the host and target architectures, the build
+        base and base layer, the toolchain versions, the installed versions
+        of build packages and relevant build environment variables.
+        """
+        build_state = self.steps.get(Step.BUILD)
+        if not build_state:
+            return None
+        return build_state.assets.get("build-environment")
+
+
+def get_part_state_info(part: Part) -> PartStateInfo:
+    """Obtain the recorded states of a part.
+
+    :param part: The part to obtain the states of.
+
+    :return: The states of the steps that ran for the part.
+    """
+    steps: Dict[Step, StepStateInfo] = {}
+    for step in Step:
+        state = states.load_state(part, step)
+        if state:
+            steps[step] = _get_step_state_info(part.name, step, state)
+
+    return PartStateInfo(
+        part_name=part.name,
+        steps=steps,
+        stage_packages=get_stage_package_versions(part) if steps else {},
+    )
+
+
+def get_stage_package_versions(part: Part) -> Dict[str, str]:
+    """Obtain the versions of the stage packages fetched for a part.
+
+    :param part: The part to obtain stage package versions of.
+
+    :return: A dictionary mapping package names to their versions.
+    """
+    versions: Dict[str, str] = {}
+    if part.part_packages_dir.is_dir():
+        for deb in part.part_packages_dir.glob("*.deb"):
+            fields = deb.stem.split("_")
+            if len(fields) == 3:
+                versions[fields[0]] = unquote(fields[1])
+
+    return versions
+
+
+def _get_step_state_info(part_name: str, step: Step, state: StepState) -> StepStateInfo:
+    """Create the state information of a step from its state."""
+    relevant = {
+        "properties": state.properties_of_interest(state.part_properties),
+        "options": state.project_options_of_interest(state.project_options),
+    }
+    data = json.dumps(relevant, sort_keys=True, default=str)
+    fingerprint = f"sha256/{hashlib.sha256(data.encode()).hexdigest()}"
+
+    return StepStateInfo(
+        part_name=part_name,
+        step=step,
+        properties_fingerprint=fingerprint,
+        part_properties=state.part_properties,
+        project_options=state.project_options,
+        assets=getattr(state, "assets", {}),
+        files=state.files,
+        directories=state.directories,
+    )
@@ -20,12 +20,13 @@ import contextlib
 import itertools
 import logging
 from dataclasses import dataclass
-from typing import Dict, List, Optional, Tuple
+from typing import Any, Dict, List, Optional, Tuple
 
-from craft_parts import parts, sources, steps
-from craft_parts.infos import ProjectInfo
+from craft_parts import parts, plugins, sources
+from craft_parts.infos import PartInfo, ProjectInfo
+from craft_parts.packages import snaps
 from craft_parts.parts import Part
-from craft_parts.sources import SourceHandler
+from craft_parts.sources import SourceHandler, patches
 from craft_parts.state_manager import states
 from craft_parts.steps import Step
 
@@ -205,7 +206,7 @@ class StateManager:
         :param part: The part corresponding to the state to remove.
         :param step: The step corresponding to the state to remove.
         """
-        for next_step in [step] + step.next_steps():
+        for next_step in [step] + step.next_steps(overlay=True):
             self._state_db.remove(part_name=part.name, step=next_step)
 
     def has_step_run(self, part: Part, step: Step) -> bool:
@@ -240,7 +241,9 @@ class StateManager:
         ):
             return True
 
-        previous_steps = step.previous_steps()
+        previous_steps = step.previous_steps(
+            overlay=self._project_info.overlay_enabled
+        )
         if previous_steps:
             return self.should_step_run(part, previous_steps[-1])
 
@@ -286,11 +289,17 @@ class StateManager:
                 # Not all sources support checking for updates
                 with contextlib.suppress(sources.errors.SourceUpdateUnsupported):
                     if source_handler.check_if_outdated(str(state_file)):
-                        return OutdatedReport(source_modified=True)
+                        return OutdatedReport(
+                            source_modified=True,
+                            modified_source_files=source_handler.get_outdated_files(),
+                        )
 
             return None
 
-        for previous_step in reversed(step.previous_steps()):
+        previous_steps = step.previous_steps(
+            overlay=self._project_info.overlay_enabled
+        )
+        for previous_step in reversed(previous_steps):
             # Has a previous step run since this one ran? Then this
             # step needs to be updated.
             previous_stw = self._state_db.get(part_name=part.name, step=previous_step)
@@ -328,30 +337,60 @@ class StateManager:
         # comparing it to those same properties and options in the current
         # state. If they've changed, then this step is dirty and needs to
         # run again.
-        part_properties = part.spec.marshal()
-        properties = state.diff_properties_of_interest(part_properties)
+        part_properties = {**part.spec.marshal(), **part.plugin_properties.marshal()}
+
+        also_compare: Optional[List[str]] = None
+        if step == Step.PULL:
+            also_compare = part.plugin_properties.get_pull_properties()
+        elif step == Step.BUILD:
+            also_compare = part.plugin_properties.get_build_properties()
+
+        properties = state.diff_properties_of_interest(part_properties, also_compare)
         options = state.diff_project_options_of_interest(
             self._project_info.project_options
         )
 
+        # Patch contents can change without changing the part properties.
+        if step == Step.PULL and "source-patches" not in properties:
+            digests = patches.get_digests(part.spec.source_patches)
+            if digests != state.assets.get("source-patches", {}):
+                properties.add("source-patches")
+
+        # Plugin assets such as vendored dependencies, and the plugin pull
+        # commands, can change without changing the part properties.
+        if step == Step.PULL:
+            for name, value in self._get_plugin_pull_assets(part).items():
+                if name not in properties and value != state.assets.get(name):
+                    properties.add(name)
+
+        # Stage snap channels can move to a new revision between runs.
+        if (
+            step == Step.PULL
+            and part.spec.stage_snaps_track_channel
+            and "stage-snaps" not in properties
+        ):
+            revisions = snaps.get_snap_revisions(part.spec.stage_snaps)
+            if revisions != state.assets.get("stage-snaps", {}):
+                properties.add("stage-snaps")
+
         if properties or options:
             return DirtyReport(
                 dirty_properties=list(properties),
                 dirty_project_options=list(options),
             )
 
-        prerequisite_step = steps.dependency_prerequisite_step(step)
-        if not prerequisite_step:
-            return None
-
         # The part is clean, check its dependencies
 
-        dependencies = parts.part_dependencies(
-            part.name, part_list=self._part_list, recursive=True
+        dependency_steps = parts.part_dependency_steps(
+            part.name, step, part_list=self._part_list
         )
 
         changed_dependencies: List[Dependency] = []
-        for dependency in dependencies:
+        for dependency in self._part_list:
+            prerequisite_step = dependency_steps.get(dependency.name)
+            if not prerequisite_step:
+                continue
+
             prerequisite_stw = self._state_db.get(
                 part_name=dependency.name, step=prerequisite_step
             )
@@ -367,6 +406,23 @@ class StateManager:
                     Dependency(part_name=dependency.name, step=prerequisite_step)
                 )
 
+        # Layers are stacked on top of the layers of earlier parts.
+        if step == Step.OVERLAY:
+            for lower_part in parts.part_lower_layers(
+                part.name, part_list=self._part_list
+            ):
+                lower_stw = self._state_db.get(
+                    part_name=lower_part.name, step=Step.OVERLAY
+                )
+                if (
+                    not lower_stw
+                    or lower_stw.is_newer_than(stw)
+                    or self.should_step_run(lower_part, Step.OVERLAY)
+                ):
+                    changed_dependencies.append(
+                        Dependency(part_name=lower_part.name, step=Step.OVERLAY)
+                    )
+
         if changed_dependencies:
             return DirtyReport(changed_dependencies=changed_dependencies)
 
@@ -376,6 +432,28 @@ class StateManager:
         """Mark the given part and step as updated."""
         self._state_db.rewrap(part_name=part.name, step=step, step_updated=True)
 
+    def _get_plugin_pull_assets(self, part: Part) -> Dict[str, Any]:
+        """Obtain the pull assets currently reported by the part plugin.
+
+        The plugin pull commands are included, as recorded when pulling.
+        Parts using plugins that are not registered have no plugin assets.
+        """
+        try:
+            plugin = plugins.get_plugin(
+                part=part,
+                part_info=PartInfo(self._project_info, part),
+                properties=part.plugin_properties,
+            )
+        except ValueError as err:
+            logger.debug("cannot obtain plugin assets: %s", err)
+            return {}
+
+        assets = plugin.get_pull_assets()
+        pull_commands = plugin.get_pull_commands()
+        if pull_commands:
+            assets["pull-commands"] = pull_commands
+        return assets
+
 
 def _sort_steps_by_state_timestamp(
     part_list: List[Part],
@@ -26,10 +26,12 @@ from craft_parts.parts import Part
 from craft_parts.steps import Step
 
 from .build_state import BuildState
+from .migrations import migrate_state
+from .overlay_state import OverlayState
 from .prime_state import PrimeState
 from .pull_state import PullState
 from .stage_state import StageState
-from .step_state import StepState
+from .step_state import WORK_DIR_VARIABLE, StepState, relocate_paths
 
 logger = logging.getLogger(__name__)
 
@@ -37,12 +39,18 @@ logger = logging.getLogger(__name__)
 def load_state(part: Part, step: Step) -> Optional[StepState]:
     """Retrieve the persistent state for the given part and step.
 
+    States written by earlier versions of craft-parts are migrated to the
+    current schema version, and paths stored relative to the work directory
+    are expanded using the current location of the work directory.
+
     :param part: The part corresponding to the state to load.
     :param step: The step corresponding to the state to load.
 
     :return: The step state.
 
     :raise RuntimeError: If step is invalid.
+    :raise errors.StateVersionError: If the state was written by a newer
+        version of craft-parts.
     """
     filename = state_file_path(part, step)
     if not filename.is_file():
@@ -54,6 +62,8 @@ def load_state(part: Part, step: Step) -> Optional[StepState]:
 
     if step == Step.PULL:
         state_class = PullState
+    elif step == Step.OVERLAY:
+        state_class = OverlayState
     elif step == Step.BUILD:
         state_class = BuildState
     elif step == Step.STAGE:
@@ -63,9 +73,33 @@ def load_state(part: Part, step: Step) -> Optional[StepState]:
     else:
         raise RuntimeError(f"invalid step {step!r}")
 
+    if isinstance(state_data, dict):
+        state_data = migrate_state(
+            state_data, part_name=part.name, step_name=step.name.lower()
+        )
+        state_data = relocate_paths(
+            state_data, old=WORK_DIR_VARIABLE, new=str(part.work_dir)
+        )
+
     return state_class.unmarshal(state_data)
 
 
 def state_file_path(part: Part, step: Step) -> Path:
     """Return the path to the state file for the give part and step."""
     return part.part_state_dir / step.name.lower()
+
+
+def failed_state_file_path(part: Part, step: Step) -> Path:
+    """Return the path to the file marking a failed run of the given part and step."""
+    return part.part_state_dir / f"{step.name.lower()}.failed"
+
+
+def has_step_failed(part: Part, step: Step) -> bool:
+    """Verify whether the last execution of the given part and step failed.
+
+    :param part: The part corresponding to the step to verify.
+    :param step: The step to verify.
+
+    :return: Whether the step failed and didn't run successfully since.
+    """
+    return failed_state_file_path(part, step).is_file()
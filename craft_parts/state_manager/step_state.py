@@ -16,27 +16,37 @@
 
 """The step state preserves step execution context information."""
 
+import re
 from abc import ABC, abstractmethod
 from pathlib import Path
-from typing import Any, Dict, Set
+from typing import Any, Dict, List, Optional, Set
 
 from pydantic_yaml import YamlModel  # type: ignore
 
 from craft_parts.utils import os_utils
 
+from .migrations import STATE_SCHEMA_VERSION
+
+WORK_DIR_VARIABLE = "${CRAFT_WORK_DIR}"
+"""The placeholder for the work directory in paths stored in state files."""
+
 
 class StepState(YamlModel, ABC):
     """Contextual information collected when a step is executed.
 
     The step state contains environmental and project-specific configuration
     data collected at step run time. Those properties are used to decide whether
-    the step should run again on a new lifecycle execution.
+    the step should run again on a new lifecycle execution. Values set by part
+    scripts during the step are also kept, so that they are available to later
+    steps when this step is skipped.
     """
 
+    schema_version: int = STATE_SCHEMA_VERSION
     part_properties: Dict[str, Any] = {}
     project_options: Dict[str, Any] = {}
     files: Set[str] = set()
     directories: Set[str] = set()
+    values: Dict[str, str] = {}
 
     class Config:
         """Pydantic model configuration."""
@@ -48,7 +58,12 @@ class StepState(YamlModel, ABC):
         allow_population_by_field_name = True
 
     @abstractmethod
-    def properties_of_interest(self, part_properties: Dict[str, Any]) -> Dict[str, Any]:
+    def properties_of_interest(
+        self,
+        part_properties: Dict[str, Any],
+        *,
+        extra_properties: Optional[List[str]] = None,
+    ) -> Dict[str, Any]:
         """Return relevant properties concerning this step."""
 
     @abstractmethod
@@ -57,7 +72,11 @@ class StepState(YamlModel, ABC):
     ) -> Dict[str, Any]:
         """Return relevant project options concerning this step."""
 
-    def diff_properties_of_interest(self, other_properties: Dict[str, Any]) -> Set[str]:
+    def diff_properties_of_interest(
+        self,
+        other_properties: Dict[str, Any],
+        also_compare: Optional[List[str]] = None,
+    ) -> Set[str]:
         """Return properties of interest that differ.
 
         Take a dictionary of properties and compare to our own, returning
@@ -67,10 +86,16 @@ class StepState(YamlModel, ABC):
 
         :param other_properties: The properties to compare to the
             project options stored in this state.
+        :param also_compare: Additional properties to compare, such as
+            plugin-specific properties relevant to this step.
         """
         return _get_differing_keys(
-            self.properties_of_interest(self.part_properties),
-            self.properties_of_interest(other_properties),
+            self.properties_of_interest(
+                self.part_properties, extra_properties=also_compare
+            ),
+            self.properties_of_interest(
+                other_properties, extra_properties=also_compare
+            ),
         )
 
     def diff_project_options_of_interest(
@@ -98,13 +123,54 @@ class StepState(YamlModel, ABC):
         """
         return self.dict(by_alias=True)
 
-    def write(self, filepath: Path) -> None:
-        """Write this state to disk."""
+    def write(self, filepath: Path, *, work_dir: Optional[Path] = None) -> None:
+        """Write this state to disk.
+
+        :param filepath: The state file to write.
+        :param work_dir: The project work directory. If set, paths in the work
+            directory are stored relative to it, so that the work directory
+            can be moved without invalidating the state.
+        """
         filepath.parent.mkdir(parents=True, exist_ok=True)
-        yaml_data = self.yaml(by_alias=True)
+        state = self
+        if work_dir:
+            data = relocate_paths(
+                self.marshal(), old=str(work_dir), new=WORK_DIR_VARIABLE
+            )
+            state = type(self)(**data)
+        yaml_data = state.yaml(by_alias=True)
         os_utils.TimedWriter.write_text(filepath, yaml_data)
 
 
+def relocate_paths(data: Any, *, old: str, new: str) -> Any:
+    """Replace a path prefix in the strings contained in state data.
+
+    Only complete paths are replaced, so that relocating ``/work`` doesn't
+    change ``/workspace`` or ``/other/work``.
+
+    :param data: The state data, or a value contained in it.
+    :param old: The path prefix to replace.
+    :param new: The replacement path prefix.
+
+    :return: The state data with the path prefix replaced.
+    """
+
+    def relocate(value: Any) -> Any:
+        return relocate_paths(value, old=old, new=new)
+
+    if isinstance(data, str):
+        pattern = r"(?<![\w./-])" + re.escape(old) + r"(?![\w.-])"
+        return re.sub(pattern, lambda _: new, data)
+
+    if isinstance(data, dict):
+        return {relocate(key): relocate(value) for key, value in data.items()}
+
+    if isinstance(data, (list, set)):
+        return type(data)(relocate(item) for item in data)
+
+    return data
+
+
 def _get_differing_keys(dict1: Dict[str, Any], dict2: Dict[str, Any]) -> Set[str]:
     """Return the keys of dictionary entries with different values.
 
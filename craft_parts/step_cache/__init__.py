@@ -0,0 +1,36 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Cache step outputs keyed by the step fingerprint.
+
+A step fingerprint covers everything the step output depends on: the
+contents of the step input, the part and plugin properties, the step
+environment and the versions of packages used to run the step. Steps with
+a cached output are restored instead of executed.
+"""
+
+from .backends import (  # noqa: F401
+    HttpCacheBackend,
+    LocalCacheBackend,
+    ObjectStorageCacheBackend,
+    StepCacheBackend,
+)
+from .step_cache import (  # noqa: F401
+    StepCache,
+    get_directory_digest,
+    get_step_fingerprint,
+)
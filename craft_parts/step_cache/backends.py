@@ -0,0 +1,225 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Storage backends for the step cache.
+
+Backends store opaque archive files by key. Applications can share step
+outputs between machines by using a remote backend, such as a HTTP server
+accepting ``PUT`` requests or an object storage bucket.
+"""
+
+import abc
+import logging
+import shutil
+import subprocess
+from pathlib import Path
+from typing import Dict, List, Optional, Union
+
+import requests
+
+from craft_parts.sources.cache import FileCache
+from craft_parts.utils import url_utils
+
+from . import errors
+
+logger = logging.getLogger(__name__)
+
+
+class StepCacheBackend(abc.ABC):
+    """The interface of step cache storage backends."""
+
+    @abc.abstractmethod
+    def get(self, *, key: str, destination: Path) -> bool:
+        """Copy a cached file to the given destination.
+
+        :param key: The key the file was cached under.
+        :param destination: The file to write the cached contents to.
+
+        :return: Whether the key was found in the cache.
+
+        :raise errors.StepCacheBackendError: If the cache can't be accessed.
+        """
+
+    @abc.abstractmethod
+    def put(self, *, key: str, filename: Path) -> None:
+        """Store a file in the cache.
+
+        :param key: The key to cache the file under.
+        :param filename: The file to cache.
+
+        :raise errors.StepCacheBackendError: If the cache can't be accessed.
+        """
+
+
+class LocalCacheBackend(StepCacheBackend):
+    """Store step outputs in the local file cache.
+
+    :param name: The name of the application using the cache.
+    :param cache_dir: The cache location. Defaults to a cache specific to
+        the application in the XDG cache directory.
+    :param max_size: The maximum size of the step cache in bytes.
+    """
+
+    def __init__(
+        self,
+        name: str,
+        *,
+        cache_dir: Optional[Union[Path, str]] = None,
+        max_size: Optional[int] = None,
+    ) -> None:
+        self._cache = FileCache(
+            name, namespace="steps", cache_dir=cache_dir, max_size=max_size
+        )
+
+    def get(self, *, key: str, destination: Path) -> bool:
+        """Copy a cached file to the given destination."""
+        cached_file = self._cache.get(key=key)
+        if not cached_file:
+            return False
+
+        shutil.copyfile(cached_file, destination)
+        return True
+
+    def put(self, *, key: str, filename: Path) -> None:
+        """Store a file in the cache."""
+        self._cache.cache(filename=str(filename), key=key)
+
+
+class HttpCacheBackend(StepCacheBackend):
+    """Store step outputs in a HTTP server.
+
+    Files are retrieved with ``GET`` and stored with ``PUT`` requests to
+    ``<url>/<key>``. A missing key must be reported with a 404 status code.
+
+    :param url: The base URL of the cache.
+    :param headers: Additional headers to send in each request, such as
+        authorization headers.
+    :param timeout: The request timeout in seconds.
+    """
+
+    def __init__(
+        self,
+        url: str,
+        *,
+        headers: Optional[Dict[str, str]] = None,
+        timeout: float = 60.0,
+    ) -> None:
+        self._url = url.rstrip("/")
+        self._headers = dict(headers or {})
+        self._timeout = timeout
+
+    def get(self, *, key: str, destination: Path) -> bool:
+        """Copy a cached file to the given destination."""
+        try:
+            response = requests.get(
+                f"{self._url}/{key}",
+                headers=self._headers,
+                stream=True,
+                timeout=self._timeout,
+            )
+            if response.status_code == requests.codes.not_found:
+                return False
+            response.raise_for_status()
+            url_utils.download_request(response, str(destination))
+        except requests.exceptions.RequestException as err:
+            raise errors.StepCacheBackendError(self._url, message=str(err)) from err
+
+        return True
+
+    def put(self, *, key: str, filename: Path) -> None:
+        """Store a file in the cache."""
+        try:
+            with filename.open("rb") as data:
+                response = requests.put(
+                    f"{self._url}/{key}",
+                    data=data,
+                    headers=self._headers,
+                    timeout=self._timeout,
+                )
+            response.raise_for_status()
+        except requests.exceptions.RequestException as err:
+            raise errors.StepCacheBackendError(self._url, message=str(err)) from err
+
+
+class ObjectStorageCacheBackend(StepCacheBackend):
+    """Store step outputs in an object storage bucket.
+
+    Objects are copied using ``aws`` for ``s3://`` URLs and ``gcloud`` for
+    ``gs://`` URLs, which obtain credentials from their usual environment
+    variables and configuration files.
+
+    :param url: The ``s3://`` or ``gs://`` URL of the cache prefix.
+    :param region: The region of the S3 bucket.
+    :param profile: The AWS profile or gcloud configuration to use.
+    """
+
+    def __init__(
+        self,
+        url: str,
+        *,
+        region: Optional[str] = None,
+        profile: Optional[str] = None,
+    ) -> None:
+        scheme = url_utils.get_url_scheme(url)
+        if scheme not in ("s3", "gs"):
+            raise ValueError(f"{url!r} is not an object storage URL")
+
+        self._url = url.rstrip("/")
+        self._scheme = scheme
+        self._region = region
+        self._profile = profile
+
+    def get(self, *, key: str, destination: Path) -> bool:
+        """Copy a cached file to the given destination.
+
+        Object storage tools don't report missing objects separately from
+        other errors, so any failure to copy the object is a cache miss.
+        """
+        try:
+            self._copy(f"{self._url}/{key}", str(destination))
+        except subprocess.CalledProcessError as err:
+            logger.debug("step cache object %s not copied: %s", key, err)
+            return False
+
+        return True
+
+    def put(self, *, key: str, filename: Path) -> None:
+        """Store a file in the cache."""
+        try:
+            self._copy(str(filename), f"{self._url}/{key}")
+        except subprocess.CalledProcessError as err:
+            raise errors.StepCacheBackendError(
+                self._url, message=f"command exited with code {err.returncode}"
+            ) from err
+
+    def _copy(self, source: str, destination: str) -> None:
+        command: List[str]
+        if self._scheme == "s3":
+            command = ["aws", "s3", "cp", "--only-show-errors"]
+            if self._region:
+                command.extend(["--region", self._region])
+            if self._profile:
+                command.extend(["--profile", self._profile])
+        else:
+            command = ["gcloud", "storage", "cp"]
+            if self._profile:
+                command.extend(["--configuration", self._profile])
+
+        command.extend([source, destination])
+
+        logger.debug("Running: %s", " ".join(command))
+        subprocess.run(command, check=True, stdout=subprocess.DEVNULL)
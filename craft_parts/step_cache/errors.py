@@ -0,0 +1,60 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Exceptions raised by the step cache subsystem."""
+
+from craft_parts.errors import PartsError
+
+
+class StepCacheError(PartsError):
+    """Base class for step cache errors."""
+
+    code = "step-cache-error"
+
+
+class StepCacheBackendError(StepCacheError):
+    """Failed to access the step cache storage.
+
+    :param location: The location of the cache storage.
+    :param message: The error message.
+    """
+
+    code = "step-cache-backend-error"
+
+    def __init__(self, location: str, *, message: str):
+        self.location = location
+        self.message = message
+        brief = f"Failed to access step cache at {location}: {message}."
+
+        super().__init__(brief=brief)
+
+
+class InvalidStepArchive(StepCacheError):
+    """A cached step output archive is malformed or unsafe to extract.
+
+    :param key: The step fingerprint.
+    :param message: The error message.
+    """
+
+    code = "invalid-step-archive"
+
+    def __init__(self, key: str, *, message: str):
+        self.key = key
+        self.message = message
+        brief = f"Invalid cached step output {key}: {message}."
+
+        super().__init__(brief=brief)
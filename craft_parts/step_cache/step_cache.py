@@ -0,0 +1,281 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Store and restore step outputs keyed by the step fingerprint."""
+
+import hashlib
+import io
+import json
+import logging
+import os
+import shutil
+import tarfile
+import tempfile
+from concurrent import futures
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from . import errors
+from .backends import StepCacheBackend
+
+logger = logging.getLogger(__name__)
+
+_ASSETS_FILE = "assets.json"
+
+# Step outputs are created by craft-parts and may contain links to absolute
+# paths. Archive members are validated before extraction.
+_EXTRACT_ARGS: Dict[str, Any] = (
+    {"filter": "fully_trusted"} if hasattr(tarfile, "fully_trusted_filter") else {}
+)
+
+
+def get_step_fingerprint(
+    *,
+    step_name: str,
+    source_digest: str,
+    part_properties: Dict[str, Any],
+    project_options: Dict[str, Any],
+    environment: str,
+    package_versions: List[str],
+) -> str:
+    """Compute the cache key of a step execution.
+
+    :param step_name: The name of the step.
+    :param source_digest: The digest of the step input contents.
+    :param part_properties: The part and plugin properties.
+    :param project_options: The project-wide options.
+    :param environment: The step execution environment.
+    :param package_versions: The versions of packages installed to run the
+        step, in the ``package=version`` format.
+
+    :return: The step key, in the ``sha256/<digest>`` format.
+    """
+    definition = {
+        "step": step_name,
+        "source": source_digest,
+        "properties": part_properties,
+        "options": project_options,
+        "environment": environment,
+        "packages": sorted(package_versions),
+    }
+    digest = hashlib.sha256(
+        json.dumps(definition, sort_keys=True, default=str).encode()
+    )
+    return f"sha256/{digest.hexdigest()}"
+
+
+def get_directory_digest(directory: Path) -> str:
+    """Compute the digest of the contents of a directory tree.
+
+    The digest covers file names, modes, symlink targets and file contents,
+    but not timestamps or ownership. File contents are read concurrently.
+
+    :param directory: The directory to compute the digest of.
+
+    :return: The directory digest, in the ``sha256/<digest>`` format.
+    """
+    paths: List[str] = []
+    for root, dirs, files in os.walk(directory):
+        dirs.sort()
+        paths.extend(os.path.join(root, name) for name in sorted(dirs + files))
+
+    digest = hashlib.sha256()
+    with futures.ThreadPoolExecutor() as pool:
+        for path, content_digest in zip(paths, pool.map(_get_entry_digest, paths)):
+            stat = os.lstat(path)
+            relpath = os.path.relpath(path, directory)
+            digest.update(f"{relpath}\0{stat.st_mode:o}\0".encode())
+            digest.update(content_digest)
+            digest.update(b"\0")
+
+    return f"sha256/{digest.hexdigest()}"
+
+
+def _get_entry_digest(path: str) -> bytes:
+    """Compute the digest of a symlink target or file contents.
+
+    :param path: The path to the directory tree entry.
+
+    :return: The entry digest, empty for directories and special files.
+    """
+    if os.path.islink(path):
+        return hashlib.sha256(os.readlink(path).encode()).digest()
+
+    if os.path.isfile(path):
+        digest = hashlib.sha256()
+        with open(path, "rb") as data:
+            for chunk in iter(lambda: data.read(65536), b""):
+                digest.update(chunk)
+        return digest.digest()
+
+    return b""
+
+
+class StepCache:
+    """Store and restore the output directories of steps.
+
+    Outputs are archived together with the step state assets, so that a
+    restored step is indistinguishable from an executed one. Failures to
+    access the cache backend are logged and treated as cache misses, so an
+    unavailable remote cache doesn't prevent the step from running.
+
+    :param backend: The backend used to store step outputs.
+    """
+
+    def __init__(self, backend: StepCacheBackend) -> None:
+        self._backend = backend
+
+    @property
+    def backend(self) -> StepCacheBackend:
+        """Return the backend used to store step outputs."""
+        return self._backend
+
+    def save(
+        self, *, key: str, root: Path, dirs: List[str], assets: Dict[str, Any]
+    ) -> bool:
+        """Add the output of a step to the cache.
+
+        :param key: The step fingerprint.
+        :param root: The directory the output directories are relative to.
+        :param dirs: The output directories of the step.
+        :param assets: The step state assets.
+
+        :return: Whether the step output was cached.
+        """
+        with tempfile.TemporaryDirectory() as tmpdir:
+            archive = Path(tmpdir, "step.tar.gz")
+            with tarfile.open(archive, "w:gz") as tar:
+                data = json.dumps(assets).encode()
+                info = tarfile.TarInfo(_ASSETS_FILE)
+                info.size = len(data)
+                tar.addfile(info, io.BytesIO(data))
+                for name in dirs:
+                    tar.add(root / name, arcname=f"dirs/{name}")
+
+            try:
+                self._backend.put(key=key, filename=archive)
+            except errors.StepCacheBackendError as err:
+                logger.warning("Cannot cache step output: %s", err.brief)
+                return False
+
+        return True
+
+    def restore(
+        self, *, key: str, root: Path, dirs: List[str]
+    ) -> Optional[Dict[str, Any]]:
+        """Replace the contents of step output directories with cached contents.
+
+        :param key: The step fingerprint.
+        :param root: The directory the output directories are relative to.
+        :param dirs: The output directories of the step.
+
+        :return: The step state assets, or None if the step output was not
+            found in the cache.
+        """
+        with tempfile.TemporaryDirectory() as tmpdir:
+            archive = Path(tmpdir, "step.tar.gz")
+            try:
+                if not self._backend.get(key=key, destination=archive):
+                    return None
+            except errors.StepCacheBackendError as err:
+                logger.warning("Cannot restore step output: %s", err.brief)
+                return None
+
+            logger.debug("restore step output %s", key)
+            extract_dir = Path(tmpdir, "extract")
+            try:
+                with tarfile.open(archive) as tar:
+                    members = _get_safe_members(tar, key=key, dirs=dirs)
+                    tar.extractall(extract_dir, members=members, **_EXTRACT_ARGS)
+
+                assets: Dict[str, Any] = json.loads(
+                    (extract_dir / _ASSETS_FILE).read_text()
+                )
+            except (tarfile.TarError, EOFError, OSError, ValueError) as err:
+                logger.warning(
+                    "Cannot restore step output: invalid cached step output %s: %s",
+                    key,
+                    err,
+                )
+                return None
+            except errors.InvalidStepArchive as err:
+                logger.warning("Cannot restore step output: %s", err.brief)
+                return None
+
+            for name in dirs:
+                target = root / name
+                if target.exists():
+                    shutil.rmtree(target)
+                shutil.move(str(extract_dir / "dirs" / name), str(target))
+
+        return assets
+
+
+def _get_safe_members(
+    tar: tarfile.TarFile, *, key: str, dirs: List[str]
+) -> List[tarfile.TarInfo]:
+    """Verify the layout of a step output archive before extracting it.
+
+    Only the assets file and the step output directories can be extracted,
+    entries can't be written through symbolic links and hard links must
+    point to other entries in the archive. Symbolic link targets are not
+    restricted.
+
+    :param tar: The step output archive.
+    :param key: The step fingerprint.
+    :param dirs: The output directories of the step.
+
+    :return: The archive members to extract.
+
+    :raise InvalidStepArchive: If the archive is malformed or unsafe.
+    """
+    members = tar.getmembers()
+    prefixes = tuple(f"dirs/{name}/" for name in dirs)
+    links = {member.name for member in members if member.issym()}
+
+    def check_name(name: str) -> None:
+        path = Path(name)
+        if path.is_absolute() or ".." in path.parts or os.path.normpath(name) != name:
+            raise errors.InvalidStepArchive(key, message=f"unsafe entry {name!r}")
+        if name != _ASSETS_FILE and not f"{name}/".startswith(prefixes):
+            raise errors.InvalidStepArchive(key, message=f"unexpected entry {name!r}")
+        for parent in path.parents:
+            if str(parent) in links:
+                raise errors.InvalidStepArchive(
+                    key, message=f"entry {name!r} is under a symbolic link"
+                )
+
+    for member in members:
+        if not (member.isfile() or member.isdir() or member.issym() or member.islnk()):
+            raise errors.InvalidStepArchive(
+                key, message=f"unsupported entry type {member.name!r}"
+            )
+        check_name(member.name)
+        if member.islnk():
+            check_name(member.linkname)
+
+    names = {member.name: member for member in members}
+    if _ASSETS_FILE not in names or not names[_ASSETS_FILE].isfile():
+        raise errors.InvalidStepArchive(key, message="missing step state assets")
+    for name in dirs:
+        entry = names.get(f"dirs/{name}")
+        if entry is None or not entry.isdir():
+            raise errors.InvalidStepArchive(
+                key, message=f"missing output directory {name!r}"
+            )
+
+    return members
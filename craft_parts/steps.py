@@ -31,25 +31,34 @@ class Step(enum.IntEnum):
     ``STAGE`` step installed build artifacts from all parts are added
     to a staging area, and further processed in the ``PRIME`` step to
     obtain the final tree with files ready for deployment.
+
+    If the ``overlay`` feature is enabled, the ``OVERLAY`` step runs
+    between the ``PULL`` and ``BUILD`` steps to create the overlay layer
+    of each part on top of the base layer.
     """
 
     PULL = 1
-    BUILD = 2
-    STAGE = 3
-    PRIME = 4
+    OVERLAY = 2
+    BUILD = 3
+    STAGE = 4
+    PRIME = 5
 
     def __repr__(self):
         return f"{self.__class__.__name__}.{self.name}"
 
-    def previous_steps(self) -> List["Step"]:
+    def previous_steps(self, *, overlay: bool = False) -> List["Step"]:
         """List the steps that should happen before the current step.
 
+        :param overlay: Whether the overlay step is part of the lifecycle.
+
         :returns: The list of previous steps.
         """
         steps = []
 
-        if self >= Step.BUILD:
+        if self >= Step.OVERLAY:
             steps.append(Step.PULL)
+        if self >= Step.BUILD and overlay:
+            steps.append(Step.OVERLAY)
         if self >= Step.STAGE:
             steps.append(Step.BUILD)
         if self >= Step.PRIME:
@@ -57,14 +66,18 @@ class Step(enum.IntEnum):
 
         return steps
 
-    def next_steps(self) -> List["Step"]:
+    def next_steps(self, *, overlay: bool = False) -> List["Step"]:
         """List the steps that should happen after the current step.
 
+        :param overlay: Whether the overlay step is part of the lifecycle.
+
         :returns: The list of next steps.
         """
         steps = []
 
-        if self == Step.PULL:
+        if self == Step.PULL and overlay:
+            steps.append(Step.OVERLAY)
+        if self <= Step.OVERLAY:
             steps.append(Step.BUILD)
         if self <= Step.BUILD:
             steps.append(Step.STAGE)
@@ -74,13 +87,29 @@ class Step(enum.IntEnum):
         return steps
 
 
-def dependency_prerequisite_step(step: Step) -> Optional[Step]:
+def dependency_prerequisite_step(
+    step: Step, dependency_step: Step = Step.STAGE
+) -> Optional[Step]:
     """Obtain the step a given step may depend on.
 
+    Dependencies that must be staged or primed are also primed before the
+    dependent part is primed.
+
+    :param step: The step to run in the dependent part.
+    :param dependency_step: The step the dependency must reach before the
+        dependent part is built.
+
     :returns: The prerequisite step.
     """
     #  With V2 plugins we don't need to repull if dependency is restaged
     if step == Step.PULL:
         return None
 
-    return Step.STAGE if step <= Step.STAGE else step
+    # Overlay layers are stacked in part order, regardless of dependencies.
+    if step == Step.OVERLAY:
+        return None
+
+    if dependency_step < Step.STAGE:
+        return dependency_step
+
+    return max(dependency_step, step)
@@ -23,12 +23,26 @@ import logging
 import os
 import shutil
 import sys
-from typing import Callable, Generator, List, Set
+from typing import Callable, Generator, List, Optional, Set, Tuple
 
 from craft_parts import errors
 
 logger = logging.getLogger(__name__)
 
+# The FICLONE ioctl request number, from linux/fs.h.
+_FICLONE = 0x40049409
+
+# Errors indicating that files can't be cloned between two filesystems.
+_REFLINK_UNSUPPORTED_ERRORS = {
+    errno.EOPNOTSUPP,
+    errno.EXDEV,
+    errno.EINVAL,
+    errno.ENOTTY,
+}
+
+# Pairs of source and destination devices that don't support cloning files.
+_reflink_unsupported: Set[Tuple[int, int]] = set()
+
 
 class NonBlockingRWFifo:
     """A non-blocking FIFO for reading and writing."""
@@ -95,6 +109,109 @@ def link_or_copy(source: str, destination: str, follow_symlinks: bool = False) -
             copy(source, destination, follow_symlinks=follow_symlinks)
 
 
+def reflink_or_link_or_copy(
+    source: str, destination: str, follow_symlinks: bool = False
+) -> None:
+    """Clone source and destination files. Hard-link or copy if it fails to clone.
+
+    Cloned files share data blocks until modified, but unlike hard links they
+    are independent files. Cloning is only supported in some filesystems (e.g.
+    btrfs or XFS), so hard-linking or copying is used otherwise. Symlinks are
+    never cloned.
+
+    :param source: The source to be cloned to destination.
+    :param destination: The destination to be cloned from source.
+    :param follow_symlinks: Whether or not symlinks should be followed.
+    """
+    if follow_symlinks or not os.path.islink(source):
+        devices = _get_devices(source, destination)
+        if devices not in _reflink_unsupported:
+            try:
+                reflink(source, destination, follow_symlinks=follow_symlinks)
+                return
+            except OSError as err:
+                logger.debug("Unable to clone %s: %s", source, err)
+                if err.errno in _REFLINK_UNSUPPORTED_ERRORS:
+                    devices = _get_devices(source, destination)
+                    if devices:
+                        _reflink_unsupported.add(devices)
+
+    link_or_copy(source, destination, follow_symlinks=follow_symlinks)
+
+
+def reflink(source: str, destination: str, *, follow_symlinks: bool = False) -> None:
+    """Clone source and destination files.
+
+    The destination file is created with the same contents, permission bits
+    and owner information as the source file, sharing data blocks with it.
+
+    :param source: The source to be cloned to destination.
+    :param destination: The destination to be cloned from source.
+    :param follow_symlinks: Whether or not symlinks should be followed.
+
+    :raises CopyFileNotFound: If source doesn't exist.
+    :raises OSError: If the file can't be cloned.
+    """
+    if sys.platform != "linux":
+        raise OSError(errno.EOPNOTSUPP, "file cloning is not supported", source)
+
+    import fcntl  # pylint: disable=import-outside-toplevel
+
+    source_path = source
+    if follow_symlinks:
+        source_path = os.path.realpath(source)
+
+    if os.path.islink(source_path):
+        raise OSError(errno.EINVAL, "cannot clone a symlink", source)
+
+    destination_dir = os.path.dirname(destination)
+    if destination_dir and not os.path.exists(destination_dir):
+        create_similar_directory(os.path.dirname(source_path), destination_dir)
+
+    try:
+        src_fd = os.open(source_path, os.O_RDONLY)
+    except FileNotFoundError as err:
+        raise errors.CopyFileNotFound(source) from err
+
+    try:
+        dst_fd = os.open(destination, os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o600)
+        try:
+            fcntl.ioctl(dst_fd, _FICLONE, src_fd)
+        except OSError:
+            os.close(dst_fd)
+            os.unlink(destination)
+            raise
+        os.close(dst_fd)
+    finally:
+        os.close(src_fd)
+
+    shutil.copystat(source_path, destination)
+
+    stat = os.stat(source_path)
+    try:
+        os.chown(destination, stat.st_uid, stat.st_gid)
+    except PermissionError as err:
+        logger.debug("Unable to chown %s: %s", destination, err)
+
+
+def _get_devices(source: str, destination: str) -> Optional[Tuple[int, int]]:
+    """Obtain the devices containing the source and destination files.
+
+    :param source: The source file.
+    :param destination: The destination file, which may not exist yet.
+
+    :return: The source and destination devices, or None if they can't
+        be determined.
+    """
+    try:
+        source_dev = os.stat(source).st_dev
+        destination_dev = os.stat(os.path.dirname(destination) or ".").st_dev
+    except OSError:
+        return None
+
+    return source_dev, destination_dev
+
+
 def link(source: str, destination: str, *, follow_symlinks: bool = False) -> None:
     """Hard-link source and destination files.
 
@@ -16,7 +16,8 @@
 
 """Text formatting utilities."""
 
-from typing import Iterable
+import difflib
+from typing import Iterable, Optional
 
 
 def humanize_list(
@@ -42,3 +43,15 @@ def humanize_list(
         humanized += ","
 
     return "{} {} {}".format(humanized, conjunction, quoted_items[-1])
+
+
+def get_close_match(name: str, candidates: Iterable[str]) -> Optional[str]:
+    """Find the candidate most similar to a misspelled name.
+
+    :param name: The name to find a match for.
+    :param candidates: The valid names.
+
+    :return: The closest valid name, or None if no candidate is similar enough.
+    """
+    matches = difflib.get_close_matches(name, list(candidates), n=1, cutoff=0.75)
+    return matches[0] if matches else None
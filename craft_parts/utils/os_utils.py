@@ -17,14 +17,16 @@
 """Utilities related to the operating system."""
 
 import contextlib
+import functools
 import logging
 import os
+import shutil
 import subprocess
 import time
 from pathlib import Path
-from typing import Dict, List, Optional, Union
+from typing import Dict, List, Optional, Tuple, Union
 
-from craft_parts import errors
+from craft_parts import errors, hosts
 
 logger = logging.getLogger(__name__)
 
@@ -253,6 +255,10 @@ class OsRelease:
 
         raise errors.OsReleaseIdError()
 
+    def id_like(self) -> List[str]:
+        """Return the IDs of the operating systems this OS is derived from."""
+        return self._os_release.get("ID_LIKE", "").split()
+
     def name(self) -> str:
         """Return the OS name.
 
@@ -289,3 +295,37 @@ class OsRelease:
             return _ID_TO_UBUNTU_CODENAME[self._os_release["VERSION_ID"]]
 
         raise errors.OsReleaseCodenameError()
+
+
+@functools.lru_cache(maxsize=None)
+def get_network_isolation_prefix() -> Optional[Tuple[str, ...]]:
+    """Obtain the command prefix to run a command without network access.
+
+    Commands are executed in a new network namespace containing only an
+    unconfigured loopback interface. Unprivileged users also need a user
+    namespace to create the network namespace, with the current user mapped
+    to root.
+
+    :return: The command prefix, or None if network namespaces can't be
+        created in this host.
+    """
+    host = hosts.get_host_platform()
+    if not host.supports_isolation or not shutil.which("unshare"):
+        return None
+
+    prefix: Tuple[str, ...] = ("unshare", "--net")
+    if not host.is_root():
+        prefix += ("--map-root-user",)
+
+    try:
+        subprocess.run(
+            [*prefix, "true"],
+            check=True,
+            stdout=subprocess.DEVNULL,
+            stderr=subprocess.DEVNULL,
+        )
+    except (OSError, subprocess.CalledProcessError) as err:
+        logger.debug("cannot create network namespace: %s", err)
+        return None
+
+    return prefix
@@ -17,14 +17,20 @@
 """URL parsing and downloading helpers."""
 
 import os
+import re
 import urllib.parse
 import urllib.request
+from typing import Callable, Optional
 
 # TODO:stdmsg: refactor to use the standard message library once available
 import progressbar  # type: ignore
 
 from craft_parts.utils import os_utils
 
+# The user information of URLs. Passwords may contain "@", so the host starts
+# after the last "@" in the URL authority.
+_CREDENTIALS = re.compile(r"(?<=://)[^/\s,|]+@")
+
 
 def get_url_scheme(url: str) -> str:
     """Return the given URL's scheme."""
@@ -36,14 +42,41 @@ def is_url(url: str) -> bool:
     return get_url_scheme(url) != ""
 
 
+def redact_credentials(text: str) -> str:
+    """Remove user credentials from URLs in the given string.
+
+    :param text: A string containing one or more URLs.
+
+    :return: The string with URL credentials replaced by asterisks.
+    """
+    return _CREDENTIALS.sub("***@", text)
+
+
+def has_credentials(text: str) -> bool:
+    """Verify whether URLs in the given string contain user credentials.
+
+    :param text: A string containing one or more URLs.
+
+    :return: Whether any of the URLs contains credentials.
+    """
+    return _CREDENTIALS.search(text) is not None
+
+
 def download_request(
-    request, destination: str, message: str = None, total_read: int = 0
+    request,
+    destination: str,
+    message: str = None,
+    total_read: int = 0,
+    *,
+    progress: Optional[Callable[[int, Optional[int]], None]] = None,
 ):
     """Download a request with nice progress bars.
 
     :param request: The URL download request.
     :param destination: The destination file name.
     :param message: The message shown in the progress bar.
+    :param progress: A function called as data is written, receiving the
+        number of bytes downloaded and the download size, if known.
     """
     # Doing len(request.content) may defeat the purpose of a
     # progress bar
@@ -66,9 +99,11 @@ def download_request(
     with open(destination, mode) as destination_file:
         for buf in request.iter_content(1024):
             destination_file.write(buf)
+            total_read += len(buf)
             if not os_utils.is_dumb_terminal():
-                total_read += len(buf)
                 progress_bar.update(total_read)
+            if progress:
+                progress(total_read, total_length or None)
     progress_bar.finish()
 
 
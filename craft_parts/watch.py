@@ -0,0 +1,135 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+"""Detect modifications of the local sources of parts.
+
+Local source directories are polled for changes to the modification times
+of their files, so that no file notification service is needed. Files that
+are not pulled, such as the project work directories, are not watched.
+"""
+
+import logging
+import time
+from typing import Dict, List, Optional
+
+from craft_parts import sources
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part
+from craft_parts.sources import LocalSource
+from craft_parts.sources.multi_source import MultiSource
+
+logger = logging.getLogger(__name__)
+
+# The modification times of the local source files of each part.
+_Snapshot = Dict[str, List[Dict[str, int]]]
+
+
+class SourceWatcher:
+    """Wait for files in the local sources of parts to be modified.
+
+    Modifications are reported relative to the files present when the
+    watcher was created or when it last reported modifications.
+
+    :param part_list: The parts to watch.
+    :param application_name: The name of the application using Craft Parts.
+    :param project_dirs: The project's work directories.
+    """
+
+    def __init__(
+        self,
+        part_list: List[Part],
+        *,
+        application_name: str,
+        project_dirs: ProjectDirs,
+    ):
+        self._handlers: Dict[str, List[LocalSource]] = {}
+        for part in part_list:
+            handlers = _get_local_sources(
+                part, application_name=application_name, project_dirs=project_dirs
+            )
+            if handlers:
+                self._handlers[part.name] = handlers
+
+        self._snapshot = self._get_snapshot()
+
+    @property
+    def part_names(self) -> List[str]:
+        """The sorted names of the watched parts with local sources."""
+        return sorted(self._handlers)
+
+    def wait(
+        self, *, interval: float = 1.0, timeout: Optional[float] = None
+    ) -> List[str]:
+        """Wait until files in the local sources are modified.
+
+        Once a modification is found, sources are polled until they don't
+        change for an interval, so that a set of files written together is
+        reported at once.
+
+        :param interval: The interval between checks, in seconds.
+        :param timeout: The maximum time to wait, in seconds. If not set,
+            wait until files are modified.
+
+        :return: The sorted names of the parts whose sources were modified,
+            or an empty list if the timeout expired.
+        """
+        deadline = time.monotonic() + timeout if timeout is not None else None
+
+        snapshot = self._get_snapshot()
+        while snapshot == self._snapshot:
+            if deadline is not None and time.monotonic() >= deadline:
+                return []
+            time.sleep(interval)
+            snapshot = self._get_snapshot()
+
+        while True:
+            time.sleep(interval)
+            settled = self._get_snapshot()
+            if settled == snapshot:
+                break
+            snapshot = settled
+
+        modified = sorted(
+            name for name in snapshot if snapshot[name] != self._snapshot[name]
+        )
+        logger.debug("sources of parts %s modified", ", ".join(modified))
+        self._snapshot = snapshot
+        return modified
+
+    def _get_snapshot(self) -> _Snapshot:
+        return {
+            name: [handler.get_file_times() for handler in handlers]
+            for name, handlers in self._handlers.items()
+        }
+
+
+def _get_local_sources(
+    part: Part, *, application_name: str, project_dirs: ProjectDirs
+) -> List[LocalSource]:
+    """Obtain the handlers of the local sources of a part."""
+    handler = sources.get_source_handler(
+        application_name=application_name, part=part, project_dirs=project_dirs
+    )
+
+    if isinstance(handler, MultiSource):
+        handlers = handler.handlers
+    elif handler:
+        handlers = [handler]
+    else:
+        handlers = []
+
+    return [h for h in handlers if isinstance(h, LocalSource)]
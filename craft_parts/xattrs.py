@@ -17,10 +17,9 @@
 """Helpers to read and write filesystem extended attributes."""
 
 import os
-import sys
 from typing import Optional
 
-from craft_parts import errors
+from craft_parts import errors, hosts
 
 # TODO: this might be a separations of concern leak, improve this handling.
 _STAGE_PACKAGE_KEY = "origin_stage_package"
@@ -31,7 +30,7 @@ def _get_xattr_key(key: str) -> str:
 
 
 def _read_xattr(path: str, key: str) -> Optional[str]:
-    if sys.platform != "linux":
+    if not hosts.get_host_platform().supports_xattrs:
         raise RuntimeError("xattr support only available for Linux")
 
     # Extended attributes do not apply to symlinks.
@@ -54,7 +53,7 @@ def _read_xattr(path: str, key: str) -> Optional[str]:
 
 
 def _write_xattr(path: str, key: str, value: str) -> None:
-    if sys.platform != "linux":
+    if not hosts.get_host_platform().supports_xattrs:
         raise RuntimeError("xattr support only available for Linux")
 
     # Extended attributes do not apply to symlinks.
@@ -49,6 +49,7 @@ setup(
     entry_points={
         "console_scripts": [
             "craft_parts=craft_parts.main:main",
+            "craftctl=craft_parts.ctl:main",
         ],
     },
     install_requires=requirements,
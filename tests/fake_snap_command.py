@@ -34,6 +34,8 @@ class FakeSnapCommand:
         self.refresh_success = True
         self.download_side_effect = None
         self.fake_download = None
+        self.fake_assertion = None
+        self.ack_success = True
         self._email = "-"
 
         original_check_call = craft_parts.packages.snaps.check_call
@@ -77,7 +79,7 @@ class FakeSnapCommand:
 
     def _is_snap_command(self, cmd):
         snap_cmd, _ = self._get_snap_cmd(cmd)
-        return snap_cmd in ["install", "refresh", "whoami", "download"]
+        return snap_cmd in ["install", "refresh", "whoami", "download", "ack"]
 
     def _fake_snap_command(self, cmd, *args, **kwargs):
         cmd, params = self._get_snap_cmd(cmd)
@@ -88,6 +90,9 @@ class FakeSnapCommand:
         if cmd == "refresh" and not self.refresh_success:
             raise subprocess.CalledProcessError(returncode=1, cmd=cmd)
 
+        if cmd == "ack" and not self.ack_success:
+            raise subprocess.CalledProcessError(returncode=1, cmd=cmd)
+
         if cmd == "whoami":
             return "email: {}".format(self._email).encode()
 
@@ -99,7 +104,16 @@ class FakeSnapCommand:
             raise subprocess.CalledProcessError(returncode=1, cmd=cmd)
 
         if cmd == "download":
-            if self.fake_download:
+            revisions = [p[11:] for p in params if p.startswith("--revision=")]
+            if revisions:
+                download_dir = kwargs.get("cwd") or os.getcwd()
+                stem = os.path.join(download_dir, f"{params[0]}_{revisions[0]}")
+                if self.fake_download:
+                    shutil.copyfile(self.fake_download, stem + ".snap")
+                if self.fake_assertion:
+                    with open(stem + ".assert", "w") as assert_file:
+                        assert_file.write(self.fake_assertion)
+            elif self.fake_download:
                 dest = os.path.join(kwargs["cwd"], params[0] + ".snap")
                 shutil.copyfile(self.fake_download, dest)
             return "Downloaded  ".encode()
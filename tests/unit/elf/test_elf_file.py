@@ -0,0 +1,102 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import shutil
+import subprocess
+from pathlib import Path
+from typing import List
+
+import pytest
+
+from craft_parts.elf import ElfFile, errors, is_elf
+
+needs_toolchain = pytest.mark.skipif(
+    not (shutil.which("gcc") and shutil.which("readelf")),
+    reason="a C toolchain is required",
+)
+
+
+def compile_elf(path: str, *, args: List[str]) -> None:
+    """Compile a trivial ELF file."""
+    Path("elf.c").write_text("int main() { return 0; }\n")
+    Path(path).parent.mkdir(parents=True, exist_ok=True)
+    subprocess.run(["gcc", *args, "-o", path, "elf.c"], check=True)
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestIsElf:
+    """Verify ELF file detection."""
+
+    def test_is_elf(self):
+        Path("foo").write_bytes(b"\x7fELF\x02\x01")
+        assert is_elf("foo")
+
+    def test_is_not_elf(self):
+        Path("foo").write_text("#!/bin/sh\n")
+        assert not is_elf("foo")
+
+    def test_symlink(self):
+        Path("foo").write_bytes(b"\x7fELF\x02\x01")
+        Path("bar").symlink_to("foo")
+        assert not is_elf("bar")
+
+    def test_directory(self):
+        Path("foo").mkdir()
+        assert not is_elf("foo")
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestElfFile:
+    """Verify reading dynamic linking information."""
+
+    @needs_toolchain
+    def test_executable(self):
+        compile_elf("foo", args=["-Wl,-rpath,/usr/local/lib:$ORIGIN/../lib"])
+
+        elf_file = ElfFile("foo")
+
+        assert elf_file.interpreter is not None
+        assert elf_file.soname is None
+        assert "libc.so.6" in elf_file.needed
+        assert elf_file.rpath == ["/usr/local/lib", "$ORIGIN/../lib"]
+        assert elf_file.is_dynamic
+
+    @needs_toolchain
+    def test_shared_library(self):
+        compile_elf("libfoo.so", args=["-shared", "-fPIC", "-Wl,-soname,libfoo.so.1"])
+
+        elf_file = ElfFile("libfoo.so")
+
+        assert elf_file.interpreter is None
+        assert elf_file.soname == "libfoo.so.1"
+        assert elf_file.rpath == []
+
+    def test_readelf_error(self):
+        Path("foo").write_bytes(b"\x7fELF invalid")
+
+        with pytest.raises(errors.ElfReadError) as raised:
+            ElfFile("foo")
+
+        assert raised.value.path == "foo"
+
+    def test_readelf_not_installed(self, mocker):
+        mocker.patch("subprocess.run", side_effect=FileNotFoundError())
+
+        with pytest.raises(errors.ElfReadError) as raised:
+            ElfFile("foo")
+
+        assert raised.value.message == "'readelf' is not installed"
@@ -0,0 +1,36 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from craft_parts.elf import errors
+
+
+def test_elf_read_error():
+    err = errors.ElfReadError("bin/foo", message="something is wrong")
+    assert err.path == "bin/foo"
+    assert err.message == "something is wrong"
+    assert err.brief == "Failed to read ELF file 'bin/foo': something is wrong."
+    assert err.details is None
+    assert err.resolution == "Make sure binutils is installed."
+
+
+def test_elf_patch_error():
+    err = errors.ElfPatchError("bin/foo", message="something is wrong")
+    assert err.path == "bin/foo"
+    assert err.message == "something is wrong"
+    assert err.brief == "Failed to patch ELF file 'bin/foo': something is wrong."
+    assert err.details is None
+    assert err.resolution == "Make sure patchelf is installed."
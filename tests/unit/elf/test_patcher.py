@@ -0,0 +1,139 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import os
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.elf import ElfPatcher, errors
+from craft_parts.utils import file_utils
+
+from .test_elf_file import compile_elf, needs_toolchain
+
+
+@pytest.fixture
+def prime_tree(new_dir):  # pylint: disable=unused-argument
+    compile_elf(
+        "prime/usr/lib/libfoo.so.1",
+        args=["-shared", "-fPIC", "-Wl,-soname,libfoo.so.1"],
+    )
+    Path("prime/usr/lib/libfoo.so").symlink_to("libfoo.so.1")
+    compile_elf(
+        "prime/usr/bin/foo",
+        args=["-Lprime/usr/lib", "-lfoo", "-Wl,-rpath,/build/lib:$ORIGIN"],
+    )
+    Path("prime/usr/bin/script").write_text("#!/bin/sh\n")
+
+    return Path("prime")
+
+
+@needs_toolchain
+class TestElfPatcher:
+    """Verify patching of ELF files."""
+
+    def test_patch_rpath(self, mocker, prime_tree):
+        mock_patchelf = mocker.patch("craft_parts.elf.patcher._patchelf")
+
+        patcher = ElfPatcher(prime_tree)
+        patched = patcher.patch(
+            ["usr/bin/foo", "usr/bin/script", "usr/lib/libfoo.so.1"]
+        )
+
+        mock_patchelf.assert_called_once_with(
+            ["--set-rpath", "$ORIGIN:$ORIGIN/../lib", mocker.ANY], path="usr/bin/foo"
+        )
+        assert list(patched) == ["usr/bin/foo"]
+        assert patched["usr/bin/foo"]["rpath"] == "$ORIGIN:$ORIGIN/../lib"
+        assert patched["usr/bin/foo"]["digest"] == file_utils.calculate_hash(
+            "prime/usr/bin/foo", algorithm="sha256"
+        )
+
+    def test_patch_interpreter(self, mocker, prime_tree):
+        mock_patchelf = mocker.patch("craft_parts.elf.patcher._patchelf")
+
+        patcher = ElfPatcher(prime_tree, interpreter="/opt/lib/ld.so")
+        patched = patcher.patch(["usr/bin/foo", "usr/lib/libfoo.so.1"])
+
+        mock_patchelf.assert_called_once_with(
+            [
+                "--set-rpath",
+                "$ORIGIN:$ORIGIN/../lib",
+                "--set-interpreter",
+                "/opt/lib/ld.so",
+                mocker.ANY,
+            ],
+            path="usr/bin/foo",
+        )
+        assert patched["usr/bin/foo"]["interpreter"] == "/opt/lib/ld.so"
+
+    def test_patch_exclude(self, mocker, prime_tree):
+        mock_patchelf = mocker.patch("craft_parts.elf.patcher._patchelf")
+
+        patcher = ElfPatcher(prime_tree, exclude=["usr/bin/*"])
+        patched = patcher.patch(["usr/bin/foo"])
+
+        mock_patchelf.assert_not_called()
+        assert patched == {}
+
+    def test_patch_incremental(self, mocker, prime_tree):
+        mock_patchelf = mocker.patch("craft_parts.elf.patcher._patchelf")
+        previous = ElfPatcher(prime_tree).patch(["usr/bin/foo"])
+        mock_patchelf.reset_mock()
+
+        patched = ElfPatcher(prime_tree).patch(["usr/bin/foo"], previous=previous)
+
+        mock_patchelf.assert_not_called()
+        assert patched == previous
+
+    def test_patch_incremental_changed(self, mocker, prime_tree):
+        mock_patchelf = mocker.patch("craft_parts.elf.patcher._patchelf")
+        previous = {"usr/bin/foo": {"rpath": "", "interpreter": None, "digest": "0"}}
+
+        ElfPatcher(prime_tree).patch(["usr/bin/foo"], previous=previous)
+
+        mock_patchelf.assert_called_once()
+
+    def test_patch_original_not_modified(self, mocker, prime_tree):
+        def fake_patchelf(args, *, path):  # pylint: disable=unused-argument
+            Path(args[-1]).write_bytes(b"\x7fELF patched")
+
+        mocker.patch("craft_parts.elf.patcher._patchelf", side_effect=fake_patchelf)
+        os.link("prime/usr/bin/foo", "foo.orig")
+
+        ElfPatcher(prime_tree).patch(["usr/bin/foo"])
+
+        assert Path("prime/usr/bin/foo").read_bytes() == b"\x7fELF patched"
+        assert Path("foo.orig").read_bytes() != b"\x7fELF patched"
+
+    def test_patchelf_not_installed(self, mocker, prime_tree):
+        run = subprocess.run
+
+        def fake_run(command, **kwargs):
+            if command[0] == "patchelf":
+                raise FileNotFoundError()
+            return run(command, **kwargs)
+
+        mocker.patch("subprocess.run", side_effect=fake_run)
+
+        with pytest.raises(errors.ElfPatchError) as raised:
+            ElfPatcher(prime_tree).patch(["usr/bin/foo"])
+
+        assert raised.value.path == "usr/bin/foo"
+        assert raised.value.message == "'patchelf' is not installed"
+        assert not Path("prime/usr/bin/.foo.partial").exists()
@@ -169,3 +169,84 @@ class TestCollisions:
 
         # a part not built doesn't have the stage file in the installdir.
         check_for_stage_collisions([part_built, part_not_built])
+
+    def test_no_collisions_overridden_files(self, part1, part2):
+        assert check_for_stage_collisions([part1, part2]) == {}
+
+
+def _conflicting_part(tmpdir, name: str, content: str, **properties) -> Part:
+    part = Part(name, properties, project_dirs=ProjectDirs(work_dir=tmpdir))
+    p = part.part_install_dir
+    (p / "bin").mkdir(parents=True)
+    (p / "bin" / "foo").write_text(content)
+    (p / "bin" / name).write_text(content)
+    return part
+
+
+class TestCollisionPolicies:
+    """Check the resolution of collisions using stage conflict policies."""
+
+    @pytest.mark.parametrize(
+        "policy1,policy2,loser",
+        [
+            ("first-wins", "error", "p2"),
+            ("error", "first-wins", "p2"),
+            ("last-wins", "error", "p1"),
+            ("error", "last-wins", "p1"),
+            ("prefer-part", "error", "p2"),
+            ("error", "prefer-part", "p1"),
+            ("first-wins", "first-wins", "p2"),
+            ("prefer-part", "first-wins", "p2"),
+            ("last-wins", "prefer-part", "p1"),
+        ],
+    )
+    def test_policy_resolves_conflict(self, tmpdir, policy1, policy2, loser):
+        p1 = _conflicting_part(tmpdir, "p1", "1", **{"stage-conflict-policy": policy1})
+        p2 = _conflicting_part(tmpdir, "p2", "2", **{"stage-conflict-policy": policy2})
+
+        assert check_for_stage_collisions([p1, p2]) == {loser: {"bin/foo"}}
+
+    @pytest.mark.parametrize(
+        "policy1,policy2",
+        [
+            ("error", "error"),
+            ("first-wins", "last-wins"),
+            ("prefer-part", "prefer-part"),
+            ("prefer-part", "last-wins"),
+        ],
+    )
+    def test_policy_unresolved_conflict(self, tmpdir, policy1, policy2):
+        p1 = _conflicting_part(tmpdir, "p1", "1", **{"stage-conflict-policy": policy1})
+        p2 = _conflicting_part(tmpdir, "p2", "2", **{"stage-conflict-policy": policy2})
+
+        with pytest.raises(errors.PartFilesConflict) as raised:
+            check_for_stage_collisions([p1, p2])
+
+        assert raised.value.other_part_name == "p1"
+        assert raised.value.part_name == "p2"
+        assert raised.value.conflicting_files == ["bin/foo"]
+
+    def test_policy_per_path(self, tmpdir):
+        p1 = _conflicting_part(tmpdir, "p1", "1")
+        (p1.part_install_dir / "bin" / "bar").write_text("1")
+        p2 = _conflicting_part(
+            tmpdir, "p2", "2", **{"stage-conflicts": {"bin/foo": "last-wins"}}
+        )
+        (p2.part_install_dir / "bin" / "bar").write_text("2")
+
+        with pytest.raises(errors.PartFilesConflict) as raised:
+            check_for_stage_collisions([p1, p2])
+
+        assert raised.value.conflicting_files == ["bin/bar"]
+
+    def test_policy_resolves_against_staged_file(self, tmpdir):
+        p1 = _conflicting_part(tmpdir, "p1", "1")
+        policy = {"stage-conflict-policy": "last-wins"}
+        p2 = _conflicting_part(tmpdir, "p2", "2", **policy)
+        p3 = _conflicting_part(tmpdir, "p3", "1", **policy)
+
+        # The file from p3 only conflicts with the file from p2 being staged.
+        assert check_for_stage_collisions([p1, p2, p3]) == {
+            "p1": {"bin/foo"},
+            "p2": {"bin/foo"},
+        }
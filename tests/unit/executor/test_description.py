@@ -0,0 +1,68 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+
+from craft_parts.executor.description import describe_step
+from craft_parts.infos import ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.plugins.make_plugin import MakePluginProperties
+from craft_parts.steps import Step
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestDescribeStep:
+    """Verify the description of steps."""
+
+    def test_describe_build(self):
+        properties = MakePluginProperties.unmarshal(
+            {"source": ".", "make-parameters": ["FOO=1"]}
+        )
+        part = Part(
+            "foo",
+            {
+                "plugin": "make",
+                "source": ".",
+                "build-environment": [{"BAR": "baz"}],
+            },
+            plugin_properties=properties,
+        )
+
+        description = describe_step(part, Step.BUILD, project_info=ProjectInfo())
+
+        assert description.part_name == "foo"
+        assert description.step == Step.BUILD
+        assert description.properties["plugin"] == "make"
+        assert description.properties["make-parameters"] == ["FOO=1"]
+        assert 'export BAR="baz"' in description.environment
+        assert description.commands[0] == 'make -j"1" FOO=1'
+        assert description.scriptlet is None
+
+    def test_describe_scriptlet(self):
+        part = Part("foo", {"plugin": "nil", "override-stage": "craftctl default"})
+
+        description = describe_step(part, Step.STAGE, project_info=ProjectInfo())
+
+        assert description.commands == []
+        assert description.scriptlet == "craftctl default"
+        assert description.marshal() == {
+            "part": "foo",
+            "step": "stage",
+            "properties": description.properties,
+            "environment": description.environment,
+            "commands": [],
+            "scriptlet": "craftctl default",
+        }
@@ -0,0 +1,128 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts import errors
+from craft_parts.executor import emulation
+
+_HANDLER = """\
+{status}
+interpreter /usr/libexec/qemu-binfmt/aarch64-binfmt-P
+flags: {flags}
+offset 0
+magic 7f454c460201010000000000000000000200b700
+mask ffffffffffffff00fffffffffffffffffeffffff
+"""
+
+
+@pytest.fixture
+def binfmt_dir(mocker, new_dir):
+    mocker.patch.object(emulation, "BINFMT_DIR", Path(new_dir))
+    return Path(new_dir)
+
+
+@pytest.mark.parametrize(
+    "arch,name",
+    [
+        ("arm64", "qemu-aarch64"),
+        ("armhf", "qemu-arm"),
+        ("ppc64el", "qemu-ppc64le"),
+        ("riscv64", "qemu-riscv64"),
+        ("amd64", "qemu-x86_64"),
+    ],
+)
+def test_get_binfmt_handler_name(arch, name):
+    assert emulation.get_binfmt_handler_name(arch) == name
+
+
+def test_get_binfmt_handler_name_unsupported():
+    with pytest.raises(errors.EmulationError) as raised:
+        emulation.get_binfmt_handler_name("m68k")
+    assert raised.value.arch == "m68k"
+    assert raised.value.message == "architecture is not supported"
+
+
+class TestSetupEmulation:
+    """Binfmt handlers are verified and enabled."""
+
+    def test_enabled(self, mocker, binfmt_dir):
+        mock_run = mocker.patch("subprocess.run")
+        (binfmt_dir / "qemu-aarch64").write_text(
+            _HANDLER.format(status="enabled", flags="POCF")
+        )
+
+        emulation.setup_emulation("arm64")
+        mock_run.assert_not_called()
+
+    @pytest.mark.parametrize(
+        "euid,cmd",
+        [
+            (0, ["update-binfmts", "--enable", "qemu-aarch64"]),
+            (
+                1000,
+                [
+                    "sudo",
+                    "--preserve-env",
+                    "update-binfmts",
+                    "--enable",
+                    "qemu-aarch64",
+                ],
+            ),
+        ],
+    )
+    def test_disabled(self, mocker, binfmt_dir, euid, cmd):
+        mocker.patch("os.geteuid", return_value=euid)
+        mock_run = mocker.patch("subprocess.run")
+        (binfmt_dir / "qemu-aarch64").write_text(
+            _HANDLER.format(status="disabled", flags="OCF")
+        )
+
+        emulation.setup_emulation("arm64")
+        mock_run.assert_called_once_with(cmd, check=True)
+
+    def test_enable_error(self, mocker, binfmt_dir):
+        mocker.patch(
+            "subprocess.run",
+            side_effect=subprocess.CalledProcessError(2, ["update-binfmts"]),
+        )
+        (binfmt_dir / "qemu-aarch64").write_text(
+            _HANDLER.format(status="disabled", flags="OCF")
+        )
+
+        with pytest.raises(errors.EmulationError) as raised:
+            emulation.setup_emulation("arm64")
+        assert raised.value.message == "cannot enable binfmt handler 'qemu-aarch64'"
+
+    def test_not_registered(self, binfmt_dir):
+        with pytest.raises(errors.EmulationError) as raised:
+            emulation.setup_emulation("arm64")
+        assert raised.value.message == "binfmt handler 'qemu-aarch64' is not registered"
+
+    def test_no_fix_binary_flag(self, binfmt_dir):
+        (binfmt_dir / "qemu-aarch64").write_text(
+            _HANDLER.format(status="enabled", flags="OC")
+        )
+
+        with pytest.raises(errors.EmulationError) as raised:
+            emulation.setup_emulation("arm64")
+        assert raised.value.message == (
+            "binfmt handler 'qemu-aarch64' is not registered with the F flag"
+        )
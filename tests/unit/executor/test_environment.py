@@ -24,6 +24,8 @@ from craft_parts import plugins
 from craft_parts.executor import environment
 from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
 from craft_parts.parts import Part
+from craft_parts.proxy import ProxyConfig
+from craft_parts.state_manager import states
 from craft_parts.steps import Step
 
 
@@ -38,6 +40,9 @@ class FooPlugin(plugins.Plugin):
     def get_build_packages(self) -> Set[str]:
         return set()
 
+    def get_pull_environment(self) -> Dict[str, str]:
+        return {"PLUGIN_PULL_ENVVAR": "from_plugin"}
+
     def get_build_environment(self) -> Dict[str, str]:
         return {"PLUGIN_ENVVAR": "from_plugin"}
 
@@ -106,6 +111,153 @@ def test_generate_part_environment_build(new_dir):
     )
 
 
+def test_generate_part_environment_project_vars(new_dir):
+    p1 = Part("p1", {})
+    info = ProjectInfo(
+        arch="aarch64", project_name="hello", project_vars={"version": "1.0"}
+    )
+    part_info = PartInfo(project_info=info, part=p1)
+    step_info = StepInfo(part_info=part_info, step=Step.BUILD)
+    props = plugins.PluginProperties()
+    plugin = FooPlugin(properties=props, part_info=part_info)
+
+    env = environment.generate_part_environment(
+        part=p1, plugin=plugin, step_info=step_info
+    )
+
+    assert env.splitlines()[4:6] == [
+        'export CRAFT_PROJECT_NAME="hello"',
+        'export CRAFT_PROJECT_VERSION="1.0"',
+    ]
+
+
+def test_generate_part_environment_dependency_dirs(new_dir):
+    p1 = Part("p1", {"after": ["my-lib"]})
+    info = ProjectInfo(arch="aarch64")
+    part_info = PartInfo(project_info=info, part=p1)
+    step_info = StepInfo(part_info=part_info, step=Step.BUILD)
+    props = plugins.PluginProperties()
+    plugin = FooPlugin(properties=props, part_info=part_info)
+
+    env = environment.generate_part_environment(
+        part=p1, plugin=plugin, step_info=step_info
+    )
+
+    assert env.splitlines()[4:7] == [
+        f'export CRAFT_PART_SRC_MY_LIB="{new_dir}/parts/my-lib/src"',
+        f'export CRAFT_PART_BUILD_MY_LIB="{new_dir}/parts/my-lib/build"',
+        f'export CRAFT_PART_INSTALL_MY_LIB="{new_dir}/parts/my-lib/install"',
+    ]
+
+
+def test_get_part_directory_environment():
+    env = environment.get_part_directory_environment(
+        "foo.bar", parts_dir=Path("/work/parts")
+    )
+
+    assert env == {
+        "CRAFT_PART_SRC_FOO_BAR": "/work/parts/foo.bar/src",
+        "CRAFT_PART_BUILD_FOO_BAR": "/work/parts/foo.bar/build",
+        "CRAFT_PART_INSTALL_FOO_BAR": "/work/parts/foo.bar/install",
+    }
+
+
+@pytest.mark.parametrize(
+    "step,project_epoch,result",
+    [
+        (Step.BUILD, None, ['export SOURCE_DATE_EPOCH="1000"']),
+        (Step.BUILD, 2000, ['export SOURCE_DATE_EPOCH="2000"']),
+        (Step.PULL, None, []),
+        (Step.PULL, 2000, ['export SOURCE_DATE_EPOCH="2000"']),
+    ],
+)
+def test_generate_part_environment_source_date_epoch(step, project_epoch, result):
+    p1 = Part("p1", {})
+    states.PullState(assets={"source-date-epoch": 1000}).write(
+        states.state_file_path(p1, Step.PULL)
+    )
+    info = ProjectInfo(arch="aarch64", source_date_epoch=project_epoch)
+    part_info = PartInfo(project_info=info, part=p1)
+    step_info = StepInfo(part_info=part_info, step=step)
+    props = plugins.PluginProperties()
+    plugin = FooPlugin(properties=props, part_info=part_info)
+
+    env = environment.generate_part_environment(
+        part=p1, plugin=plugin, step_info=step_info
+    )
+
+    assert [x for x in env.splitlines() if "SOURCE_DATE_EPOCH" in x] == result
+
+
+def test_generate_part_environment_secrets(new_dir):
+    p1 = Part(
+        "p1",
+        {"build-environment": [{"AUTH": "user:$(HOST_SECRET:npm-token)"}]},
+    )
+    info = ProjectInfo(arch="aarch64")
+    part_info = PartInfo(project_info=info, part=p1)
+    step_info = StepInfo(part_info=part_info, step=Step.BUILD)
+    props = plugins.PluginProperties()
+    plugin = FooPlugin(properties=props, part_info=part_info)
+
+    env = environment.generate_part_environment(
+        part=p1, plugin=plugin, step_info=step_info
+    )
+
+    assert env.endswith('export AUTH="user:${CRAFT_SECRET_NPM_TOKEN}"\n')
+
+
+def test_generate_part_environment_proxy(new_dir):
+    p1 = Part("p1", {})
+    proxy = ProxyConfig(https_proxy="http://proxy:3128")
+    info = ProjectInfo(arch="aarch64", proxy=proxy)
+    part_info = PartInfo(project_info=info, part=p1)
+    step_info = StepInfo(part_info=part_info, step=Step.PULL)
+    props = plugins.PluginProperties()
+    plugin = FooPlugin(properties=props, part_info=part_info)
+
+    env = environment.generate_part_environment(
+        part=p1, plugin=plugin, step_info=step_info
+    )
+
+    assert 'export https_proxy="http://proxy:3128"' in env.splitlines()
+    assert 'export HTTPS_PROXY="http://proxy:3128"' in env.splitlines()
+
+
+def test_generate_part_environment_pull(new_dir):
+    p1 = Part("p1", {"build-environment": [{"PART_ENVVAR": "from_part"}]})
+    info = ProjectInfo(arch="aarch64")
+    part_info = PartInfo(project_info=info, part=p1)
+    step_info = StepInfo(part_info=part_info, step=Step.PULL)
+    props = plugins.PluginProperties()
+    plugin = FooPlugin(properties=props, part_info=part_info)
+
+    env = environment.generate_part_environment(
+        part=p1, plugin=plugin, step_info=step_info
+    )
+
+    assert env == textwrap.dedent(
+        """\
+        #!/bin/sh
+        set -e
+        # Environment
+        ## Part Environment
+        export PATH="{0}/parts/p1/install/usr/sbin:{0}/parts/p1/install/usr/bin:{0}/parts/p1/install/sbin:{0}/parts/p1/install/bin:$PATH"
+        export CPPFLAGS="-isystem {0}/parts/p1/install/usr/include"
+        export CFLAGS="-isystem {0}/parts/p1/install/usr/include"
+        export CXXFLAGS="-isystem {0}/parts/p1/install/usr/include"
+        export LDFLAGS="-L{0}/stage/lib -L{0}/stage/usr/lib -L{0}/stage/lib/aarch64-linux-gnu"
+        export PKG_CONFIG_PATH="{0}/stage/usr/share/pkgconfig"
+        ## Plugin Environment
+        export PLUGIN_PULL_ENVVAR="from_plugin"
+        ## User Environment
+        export PART_ENVVAR="from_part"
+        """.format(
+            new_dir
+        )
+    )
+
+
 def test_generate_part_environment_no_build(new_dir):
     p1 = Part("p1", {"build-environment": [{"PART_ENVVAR": "from_part"}]})
     info = ProjectInfo(arch="aarch64")
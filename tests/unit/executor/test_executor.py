@@ -0,0 +1,657 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import json
+import os
+from pathlib import Path
+from typing import Dict, List, Set
+
+import pytest
+
+from craft_parts import callbacks, errors, events, plugins, progress
+from craft_parts.actions import Action, ActionType
+from craft_parts.executor import ExecutionContext, Executor
+from craft_parts.executor.executor import _get_prerequisites
+from craft_parts.infos import ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.proxy import ProxyConfig
+from craft_parts.steps import Step
+
+
+@pytest.fixture(autouse=True)
+def clear_callbacks():
+    yield
+    callbacks.clear()
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestExecutor:
+    """Verify action execution."""
+
+    def test_execute_action(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1], project_info=info)
+        e.execute(Action("p1", Step.PULL))
+
+        assert Path("parts/p1/state/pull").is_file()
+
+    def test_execute_action_list(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        p2 = Part("p2", {"plugin": "nil"})
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1, p2], project_info=info)
+        e.execute([Action("p1", Step.PULL), Action("p2", Step.PULL)])
+
+        assert Path("parts/p1/state/pull").is_file()
+        assert Path("parts/p2/state/pull").is_file()
+
+    def test_execute_invalid_part(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1], project_info=info)
+        with pytest.raises(errors.InvalidPartName) as raised:
+            e.execute(Action("p2", Step.PULL))
+        assert raised.value.part_name == "p2"
+
+    def test_execute_metrics(self):
+        p1 = Part(
+            "p1", {"plugin": "nil", "override-pull": "head -c 8192 /dev/urandom > a"}
+        )
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1], project_info=info)
+        metrics = e.execute(
+            [
+                Action("p1", Step.PULL),
+                Action("p1", Step.BUILD, action_type=ActionType.SKIP),
+            ]
+        )
+
+        assert len(metrics) == 1
+        assert metrics[0].action == Action("p1", Step.PULL)
+        assert metrics[0].duration > 0
+        assert metrics[0].cpu_time >= 0
+        assert metrics[0].peak_disk_usage >= 8192
+
+    def test_prologue_emulation(self, mocker):
+        mocker.patch("platform.machine", return_value="aarch64")
+        mock_setup = mocker.patch("craft_parts.executor.emulation.setup_emulation")
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo(arch="x86_64", emulation=True)
+
+        e = Executor(part_list=[p1], project_info=info)
+        e.prologue()
+        mock_setup.assert_called_once_with("amd64")
+
+    def test_prologue_no_emulation(self, mocker):
+        mocker.patch("platform.machine", return_value="aarch64")
+        mock_setup = mocker.patch("craft_parts.executor.emulation.setup_emulation")
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo(arch="x86_64")
+
+        e = Executor(part_list=[p1], project_info=info)
+        e.prologue()
+        mock_setup.assert_not_called()
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestExecutionLogs:
+    """Verify the logs and output of executed actions."""
+
+    def test_step_log(self, capfd):
+        p1 = Part(
+            "p1",
+            {"plugin": "nil", "override-pull": "echo pulling; echo oops >&2"},
+        )
+        e = Executor(part_list=[p1], project_info=ProjectInfo())
+        e.execute(
+            [
+                Action("p1", Step.PULL),
+                Action("p1", Step.BUILD, action_type=ActionType.SKIP),
+            ]
+        )
+
+        log = Path("parts/p1/state/logs/pull.log")
+        entries = [json.loads(line) for line in log.read_text().splitlines()]
+        assert all(isinstance(x["timestamp"], float) for x in entries)
+        stdout = [x["data"] for x in entries if x["stream"] == "stdout"]
+        stderr = [x["data"] for x in entries if x["stream"] == "stderr"]
+        assert "".join(stdout) == "pulling\n"
+        assert "oops\n" in "".join(stderr)
+        assert Path("parts/p1/state/logs/build.log").exists() is False
+
+        out, _ = capfd.readouterr()
+        assert "pulling\n" in out
+
+    def test_step_log_replaced(self):
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "echo first"})
+        e = Executor(part_list=[p1], project_info=ProjectInfo())
+        e.execute(Action("p1", Step.PULL))
+
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "echo second"})
+        e = Executor(part_list=[p1], project_info=ProjectInfo())
+        e.execute(Action("p1", Step.PULL, action_type=ActionType.RERUN))
+
+        log = Path("parts/p1/state/logs/pull.log").read_text()
+        assert "second" in log
+        assert "first" not in log
+
+    def test_step_log_failed(self):
+        p1 = Part("p1", {"plugin": "nil", "override-build": "echo failing; false"})
+        e = Executor(part_list=[p1], project_info=ProjectInfo())
+        with pytest.raises(errors.ScriptletRunError):
+            e.execute([Action("p1", Step.PULL), Action("p1", Step.BUILD)])
+
+        log = Path("parts/p1/state/logs/build.log").read_text()
+        assert "failing" in log
+
+    def test_step_log_secrets(self):
+        callbacks.register_secret_provider({"token": "s3cr3t"}.get)
+        p1 = Part(
+            "p1",
+            {
+                "plugin": "nil",
+                "build-environment": [{"TOKEN": "$(HOST_SECRET:token)"}],
+                "override-pull": 'echo "token is $TOKEN"',
+            },
+        )
+        e = Executor(part_list=[p1], project_info=ProjectInfo())
+        e.execute(Action("p1", Step.PULL))
+
+        log = Path("parts/p1/state/logs/pull.log").read_text()
+        assert "token is *****" in log
+        assert "s3cr3t" not in log
+
+    def test_prefix_output(self, capfd):
+        received = []
+        callbacks.register_event_handler(received.append)
+
+        p1 = Part(
+            "p1",
+            {"plugin": "nil", "override-pull": "echo one; echo two; echo oops >&2"},
+        )
+        e = Executor(part_list=[p1], project_info=ProjectInfo(), prefix_output=True)
+        e.execute(Action("p1", Step.PULL))
+
+        out, err = capfd.readouterr()
+        assert "[p1:pull] one\n[p1:pull] two\n" in out
+        assert "[p1:pull] oops\n" in err
+
+        output = [x.data for x in received if isinstance(x, events.ActionOutput)]
+        assert "[p1:pull]" not in "".join(output)
+        assert "[p1:pull]" not in Path("parts/p1/state/logs/pull.log").read_text()
+
+    def test_prefix_output_parallel(self, capfd):
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "echo p1"})
+        p2 = Part("p2", {"plugin": "nil", "override-pull": "echo p2"})
+        e = Executor(
+            part_list=[p1, p2],
+            project_info=ProjectInfo(),
+            max_parallel_parts=2,
+            prefix_output=True,
+        )
+        e.execute([Action("p1", Step.PULL), Action("p2", Step.PULL)])
+
+        out, _ = capfd.readouterr()
+        assert "[p1:pull] p1\n" in out
+        assert "[p2:pull] p2\n" in out
+        assert "p2" in Path("parts/p2/state/logs/pull.log").read_text()
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestExecutionEvents:
+    """Verify the events emitted while executing actions."""
+
+    def test_events(self, capfd):
+        received = []
+        callbacks.register_event_handler(received.append)
+
+        p1 = Part(
+            "p1",
+            {"plugin": "nil", "override-pull": "echo pulling; echo oops >&2"},
+        )
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+        e.execute(
+            [
+                Action("p1", Step.PULL),
+                Action("p1", Step.BUILD, action_type=ActionType.SKIP),
+            ]
+        )
+
+        started = [x for x in received if isinstance(x, events.ActionStarted)]
+        assert [(x.action.step, x.index, x.total) for x in started] == [
+            (Step.PULL, 0, 2),
+            (Step.BUILD, 1, 2),
+        ]
+
+        output = [x for x in received if isinstance(x, events.ActionOutput)]
+        stdout = [x.data for x in output if x.stream == events.OutputStream.STDOUT]
+        stderr = [x.data for x in output if x.stream == events.OutputStream.STDERR]
+        assert "".join(stdout) == "pulling\n"
+        assert "oops\n" in "".join(stderr)
+
+        finished = [x for x in received if isinstance(x, events.ActionFinished)]
+        assert [(x.action.step, x.status) for x in finished] == [
+            (Step.PULL, events.ActionStatus.SUCCEEDED),
+            (Step.BUILD, events.ActionStatus.SKIPPED),
+        ]
+        assert finished[0].metrics == e.metrics[0]
+        assert finished[1].metrics is None
+
+        # Output is still written to the standard output
+        out, _ = capfd.readouterr()
+        assert "pulling\n" in out
+
+    def test_events_failed(self):
+        received = []
+        callbacks.register_event_handler(received.append)
+
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "false"})
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+        with pytest.raises(errors.ScriptletRunError):
+            e.execute(Action("p1", Step.PULL))
+
+        finished = received[-1]
+        assert isinstance(finished, events.ActionFinished)
+        assert finished.status == events.ActionStatus.FAILED
+        assert finished.error == (
+            "'override-pull' in part 'p1' failed with code 1.\n"
+            "Review the scriptlet and make sure it's correct."
+        )
+
+    def test_events_download_progress(self, mocker):
+        received = []
+        callbacks.register_event_handler(received.append)
+
+        def run_action(*args, **kwargs):
+            reporter = progress.ProgressReporter(
+                progress.DownloadKind.SNAP, "hello", total=10
+            )
+            reporter.start()
+            reporter.update(10)
+            reporter.finish()
+
+        mocker.patch(
+            "craft_parts.executor.part_handler.PartHandler.run_action",
+            side_effect=run_action,
+        )
+
+        p1 = Part("p1", {"plugin": "nil"})
+        e = Executor(part_list=[p1], project_info=ProjectInfo())
+        e.execute(Action("p1", Step.PULL))
+
+        reports = [x for x in received if isinstance(x, events.DownloadProgress)]
+        assert [(x.action.part_name, x.kind, x.item) for x in reports] == [
+            ("p1", progress.DownloadKind.SNAP, "hello"),
+            ("p1", progress.DownloadKind.SNAP, "hello"),
+        ]
+        assert [(x.downloaded, x.total) for x in reports] == [(0, 10), (10, 10)]
+
+    def test_events_parallel(self, capfd):
+        received = []
+        callbacks.register_event_handler(received.append)
+
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "echo p1"})
+        p2 = Part("p2", {"plugin": "nil", "override-pull": "echo p2"})
+        info = ProjectInfo()
+        e = Executor(part_list=[p1, p2], project_info=info, max_parallel_parts=2)
+        e.execute([Action("p1", Step.PULL), Action("p2", Step.PULL)])
+
+        output = {
+            x.action.part_name: x.data
+            for x in received
+            if isinstance(x, events.ActionOutput)
+        }
+        assert output == {"p1": "p1\n", "p2": "p2\n"}
+
+        out, _ = capfd.readouterr()
+        assert "p1\n" in out
+        assert "p2\n" in out
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestExecutionSecrets:
+    """Verify the handling of secrets used by parts."""
+
+    def test_secrets_redacted(self, capfd):
+        received = []
+        callbacks.register_event_handler(received.append)
+        callbacks.register_secret_provider({"token": "s3cr3t"}.get)
+
+        p1 = Part(
+            "p1",
+            {
+                "plugin": "nil",
+                "build-environment": [{"TOKEN": "$(HOST_SECRET:token)"}],
+                "override-pull": 'echo "token is $TOKEN"; echo "$TOKEN" >&2',
+            },
+        )
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+        e.execute(Action("p1", Step.PULL))
+
+        output = [x.data for x in received if isinstance(x, events.ActionOutput)]
+        assert "".join(output).count("*****") == 2
+        assert "s3cr3t" not in "".join(output)
+
+        out, err = capfd.readouterr()
+        assert "token is *****\n" in out
+        assert "s3cr3t" not in out + err
+
+        state = Path("parts/p1/state/pull").read_text()
+        assert "$(HOST_SECRET:token)" in state
+        assert "s3cr3t" not in state
+
+    def test_plugin_secrets_redacted(self, capfd):
+        class ProxyPlugin(plugins.Plugin):
+            """A plugin using a proxy with credentials."""
+
+            properties_class = plugins.PluginProperties
+
+            def get_build_snaps(self) -> Set[str]:
+                return set()
+
+            def get_build_packages(self) -> Set[str]:
+                return set()
+
+            def get_secrets(self) -> Dict[str, str]:
+                return {"proxy": "https://user:p@ss@proxy.example.com"}
+
+            def get_build_environment(self) -> Dict[str, str]:
+                return {"PROXY": "$(HOST_SECRET:proxy)"}
+
+            def get_build_commands(self) -> List[str]:
+                return ['echo "proxy is $PROXY"']
+
+        plugins.register({"proxy": ProxyPlugin})
+        try:
+            p1 = Part("p1", {"plugin": "proxy"})
+            e = Executor(part_list=[p1], project_info=ProjectInfo())
+            e.execute([Action("p1", Step.PULL), Action("p1", Step.BUILD)])
+        finally:
+            plugins.unregister_all()
+
+        out, err = capfd.readouterr()
+        assert "proxy is *****\n" in out
+        assert "p@ss" not in out + err
+
+        script = Path("parts/p1/run/build.sh").read_text()
+        assert "p@ss" not in script
+        assert "p@ss" not in Path("parts/p1/state/build").read_text()
+
+    def test_secret_not_found(self, monkeypatch):
+        monkeypatch.delenv("missing", raising=False)
+        received = []
+        callbacks.register_event_handler(received.append)
+
+        p1 = Part(
+            "p1",
+            {
+                "plugin": "nil",
+                "build-environment": [{"TOKEN": "$(HOST_SECRET:missing)"}],
+            },
+        )
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+        with pytest.raises(errors.SecretNotFound):
+            e.execute(Action("p1", Step.PULL))
+
+        assert received[-1].status == events.ActionStatus.FAILED
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestExecutionProxy:
+    """Verify the proxy configuration used when executing actions."""
+
+    def test_proxy(self, monkeypatch):
+        monkeypatch.setenv("http_proxy", "http://host:3128")
+        monkeypatch.delenv("HTTP_PROXY", raising=False)
+
+        p1 = Part(
+            "p1",
+            {"plugin": "nil", "override-pull": 'echo "$http_proxy" > proxy'},
+        )
+        proxy = ProxyConfig(http_proxy="http://proxy:3128")
+        pulled = []
+
+        def _pre_step(step_info):
+            pulled.append(os.environ.get("HTTP_PROXY"))
+
+        callbacks.register_pre_step(_pre_step)
+        info = ProjectInfo(proxy=proxy)
+        e = Executor(part_list=[p1], project_info=info)
+        e.execute(Action("p1", Step.PULL))
+
+        assert pulled == ["http://proxy:3128"]
+        assert Path("parts/p1/src/proxy").read_text() == "http://proxy:3128\n"
+        assert os.environ["http_proxy"] == "http://host:3128"
+        assert "HTTP_PROXY" not in os.environ
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestParallelExecution:
+    """Verify concurrent execution of actions."""
+
+    def test_execute_parallel(self, capfd):
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "echo p1 output"})
+        p2 = Part("p2", {"plugin": "nil", "override-pull": "echo p2 output"})
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1, p2], project_info=info, max_parallel_parts=2)
+        e.execute([Action("p1", Step.PULL), Action("p2", Step.PULL)])
+
+        assert Path("parts/p1/state/pull").is_file()
+        assert Path("parts/p2/state/pull").is_file()
+
+        out, _ = capfd.readouterr()
+        assert "p1 output\n" in out
+        assert "p2 output\n" in out
+
+    def test_execute_parallel_order(self, new_dir):
+        marker = Path(new_dir, "marker")
+        p1 = Part("p1", {"plugin": "nil", "override-pull": f"sleep 1; touch {marker}"})
+        p2 = Part(
+            "p2",
+            {"plugin": "nil", "after": ["p1"], "override-pull": f"test -f {marker}"},
+        )
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1, p2], project_info=info, max_parallel_parts=2)
+        e.execute([Action("p1", Step.PULL), Action("p2", Step.PULL)])
+
+        assert Path("parts/p2/state/pull").is_file()
+
+    def test_execute_parallel_error(self):
+        p1 = Part("p1", {"plugin": "nil", "override-pull": "exit 1"})
+        p2 = Part("p2", {"plugin": "nil"})
+        p3 = Part("p3", {"plugin": "nil", "after": ["p1"]})
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1, p2, p3], project_info=info, max_parallel_parts=2)
+        with pytest.raises(errors.ScriptletRunError) as raised:
+            e.execute(
+                [
+                    Action("p1", Step.PULL),
+                    Action("p2", Step.PULL),
+                    Action("p3", Step.PULL),
+                ]
+            )
+        assert raised.value.part_name == "p1"
+
+        assert Path("parts/p2/state/pull").is_file()
+        assert Path("parts/p3/state/pull").exists() is False
+
+    def test_execute_parallel_invalid_part(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo()
+
+        e = Executor(part_list=[p1], project_info=info, max_parallel_parts=2)
+        with pytest.raises(errors.InvalidPartName) as raised:
+            e.execute([Action("p1", Step.PULL), Action("p2", Step.PULL)])
+        assert raised.value.part_name == "p2"
+
+        assert Path("parts/p1/state/pull").exists() is False
+
+
+class TestPrerequisites:
+    """Verify the ordering constraints of concurrent actions."""
+
+    def test_independent_parts(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        p2 = Part("p2", {"plugin": "nil"})
+        actions = [
+            Action("p1", Step.PULL),
+            Action("p2", Step.PULL),
+            Action("p1", Step.BUILD),
+            Action("p2", Step.BUILD),
+        ]
+
+        assert _get_prerequisites(actions, part_list=[p1, p2]) == [
+            set(),
+            set(),
+            {0},
+            {1},
+        ]
+
+    def test_dependencies(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        p2 = Part("p2", {"plugin": "nil", "after": ["p1"]})
+        p3 = Part("p3", {"plugin": "nil", "after": ["p2"]})
+        p4 = Part("p4", {"plugin": "nil"})
+        actions = [
+            Action("p1", Step.PULL),
+            Action("p4", Step.PULL),
+            Action("p2", Step.PULL),
+            Action("p3", Step.PULL),
+        ]
+
+        assert _get_prerequisites(actions, part_list=[p1, p2, p3, p4]) == [
+            set(),
+            set(),
+            {0},
+            {0, 2},
+        ]
+
+    def test_shared_steps(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        p2 = Part("p2", {"plugin": "nil"})
+        actions = [
+            Action("p1", Step.STAGE),
+            Action("p2", Step.BUILD),
+            Action("p2", Step.STAGE),
+            Action("p1", Step.PRIME),
+        ]
+
+        assert _get_prerequisites(actions, part_list=[p1, p2]) == [
+            set(),
+            set(),
+            {0, 1},
+            {0, 2},
+        ]
+
+    def test_overlay_steps(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        p2 = Part("p2", {"plugin": "nil"})
+        actions = [
+            Action("p1", Step.OVERLAY),
+            Action("p2", Step.PULL),
+            Action("p2", Step.OVERLAY),
+            Action("p1", Step.STAGE),
+        ]
+
+        assert _get_prerequisites(actions, part_list=[p1, p2]) == [
+            set(),
+            set(),
+            {0, 1},
+            {0},
+        ]
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestExecutionContext:
+    """Verify the execution context manager."""
+
+    def test_prologue_epilogue(self):
+        calls = []
+
+        def _prologue(info, part_list):
+            calls.append("prologue")
+
+        def _epilogue(info, part_list):
+            calls.append("epilogue")
+
+        callbacks.register_prologue(_prologue)
+        callbacks.register_epilogue(_epilogue)
+
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+
+        with ExecutionContext(executor=e) as ctx:
+            calls.append("execute")
+            ctx.execute(Action("p1", Step.PULL))
+
+        assert calls == ["prologue", "execute", "epilogue"]
+
+    def test_metrics(self):
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+
+        with ExecutionContext(executor=e) as ctx:
+            pull_metrics = ctx.execute(Action("p1", Step.PULL))
+            build_metrics = ctx.execute(Action("p1", Step.BUILD))
+
+        assert ctx.metrics == pull_metrics + build_metrics
+        assert [x.action.step for x in ctx.metrics] == [Step.PULL, Step.BUILD]
+
+    def test_metrics_report(self, new_dir):
+        p1 = Part("p1", {"plugin": "nil"})
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+
+        report = Path(new_dir, "report/metrics.json")
+        with ExecutionContext(executor=e, metrics_report=report) as ctx:
+            ctx.execute([Action("p1", Step.PULL), Action("p1", Step.BUILD)])
+
+        data = json.loads(report.read_text())
+        assert [(x["part"], x["step"]) for x in data["actions"]] == [
+            ("p1", "pull"),
+            ("p1", "build"),
+        ]
+        assert list(data["parts"]) == ["p1"]
+        assert data["parts"]["p1"]["duration"] == pytest.approx(
+            sum(x["duration"] for x in data["actions"])
+        )
+
+    def test_metrics_report_on_error(self, new_dir):
+        p1 = Part("p1", {"plugin": "nil", "override-build": "false"})
+        info = ProjectInfo()
+        e = Executor(part_list=[p1], project_info=info)
+
+        report = Path(new_dir, "metrics.json")
+        with pytest.raises(errors.ScriptletRunError):
+            with ExecutionContext(executor=e, metrics_report=report) as ctx:
+                ctx.execute([Action("p1", Step.PULL), Action("p1", Step.BUILD)])
+
+        data = json.loads(report.read_text())
+        assert [x["step"] for x in data["actions"]] == ["pull", "build"]
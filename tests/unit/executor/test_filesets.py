@@ -14,6 +14,8 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+from pathlib import Path
+
 import pytest
 
 from craft_parts import errors
@@ -108,4 +110,66 @@ def test_filesets_excludes_without_relative_paths():
     assert raised.value.message == "path '/abs/exclude' must be relative."
 
 
+@pytest.fixture
+def glob_tree(new_dir):
+    for name in [
+        "usr/lib/libfoo.so",
+        "usr/lib/x86_64-linux-gnu/libbar.so",
+        "usr/lib/x86_64-linux-gnu/libbar.a",
+        "usr/.hidden/libhidden.so",
+        "usr/libtop.so",
+        "lib.so",
+    ]:
+        Path(name).parent.mkdir(parents=True, exist_ok=True)
+        Path(name).touch()
+    Path("usr/linked").symlink_to("lib")
+
+
+@pytest.mark.parametrize(
+    "pattern,result",
+    [
+        (
+            "usr/**/*.so",
+            [
+                "usr/lib/libfoo.so",
+                "usr/lib/x86_64-linux-gnu/libbar.so",
+                "usr/libtop.so",
+            ],
+        ),
+        (
+            "**/libbar.*",
+            [
+                "./usr/lib/x86_64-linux-gnu/libbar.a",
+                "./usr/lib/x86_64-linux-gnu/libbar.so",
+            ],
+        ),
+        ("usr/**/", ["usr/", "usr/lib/", "usr/lib/x86_64-linux-gnu/"]),
+        ("usr/**/linked", ["usr/linked"]),
+        ("usr/.hidden/**", ["usr/.hidden", "usr/.hidden/libhidden.so"]),
+        ("usr/*.so", ["usr/libtop.so"]),
+        ("nothing/**", []),
+    ],
+)
+def test_glob_paths(glob_tree, pattern, result):
+    assert sorted(filesets.glob_paths(pattern)) == result
+
+
+def test_migratable_filesets_globstar(glob_tree):
+    files, dirs = filesets.migratable_filesets(
+        Fileset(["usr/**/*.so", "-usr/lib/x86_64-linux-gnu"]), "."
+    )
+
+    assert files == {"usr/lib/libfoo.so", "usr/libtop.so"}
+    assert dirs == {"usr", "usr/lib"}
+
+
+def test_migratable_filesets_exclude_precedence(glob_tree):
+    files, dirs = filesets.migratable_filesets(
+        Fileset(["-usr/**/*.a", "usr/lib/x86_64-linux-gnu/libbar.a", "usr/lib"]), "."
+    )
+
+    assert files == {"usr/lib/libfoo.so", "usr/lib/x86_64-linux-gnu/libbar.so"}
+    assert dirs == {"usr", "usr/lib", "usr/lib/x86_64-linux-gnu"}
+
+
 # migratable_filesets tested in tests/unit/executor/test_step_handler.py
@@ -0,0 +1,299 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+from unittest.mock import call
+
+import pytest
+
+from craft_parts import errors
+from craft_parts.executor import isolation
+from craft_parts.executor.isolation import IsolationMethod
+
+
+@pytest.fixture
+def root(new_dir) -> Path:
+    path = Path(new_dir, "root")
+    path.mkdir()
+    return path
+
+
+class TestIsolatedCommand:
+    """Verify the commands used to isolate builds."""
+
+    def test_bubblewrap(self, mocker, root):
+        mocker.patch("shutil.which", return_value="/usr/bin/bwrap")
+        Path(root, "usr/bin").mkdir(parents=True)
+        Path(root, "bin").symlink_to("usr/bin")
+        Path(root, "dev").mkdir()
+        Path(root, "hello").write_text("hello")
+
+        with isolation.isolated_command(
+            ["build.sh"],
+            part_name="foo",
+            method=IsolationMethod.BUBBLEWRAP,
+            root=root,
+            cwd=Path("/work/parts/foo/build"),
+            bind_dirs=[Path("/work")],
+        ) as command:
+            assert command == [
+                "bwrap",
+                "--symlink",
+                "usr/bin",
+                "/bin",
+                "--ro-bind",
+                f"{root}/hello",
+                "/hello",
+                "--ro-bind",
+                f"{root}/usr",
+                "/usr",
+                "--dev",
+                "/dev",
+                "--proc",
+                "/proc",
+                "--bind",
+                "/work",
+                "/work",
+                "--chdir",
+                "/work/parts/foo/build",
+                "build.sh",
+            ]
+
+    def test_bubblewrap_mountpoint_parents(self, mocker, root):
+        mocker.patch("shutil.which", return_value="/usr/bin/bwrap")
+        Path(root, "home/other").mkdir(parents=True)
+        Path(root, "home/user/project").mkdir(parents=True)
+        Path(root, "home/user/.profile").write_text("")
+
+        with isolation.isolated_command(
+            ["build.sh"],
+            part_name="foo",
+            method=IsolationMethod.BUBBLEWRAP,
+            root=root,
+            cwd=Path("/home/user/project"),
+            bind_dirs=[Path("/home/user/project")],
+        ) as command:
+            assert command == [
+                "bwrap",
+                "--dir",
+                "/home",
+                "--ro-bind",
+                f"{root}/home/other",
+                "/home/other",
+                "--dir",
+                "/home/user",
+                "--ro-bind",
+                f"{root}/home/user/.profile",
+                "/home/user/.profile",
+                "--dev",
+                "/dev",
+                "--proc",
+                "/proc",
+                "--bind",
+                "/home/user/project",
+                "/home/user/project",
+                "--chdir",
+                "/home/user/project",
+                "build.sh",
+            ]
+
+    def test_bubblewrap_not_installed(self, mocker, root):
+        mocker.patch("shutil.which", return_value=None)
+
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.BUBBLEWRAP,
+                root=root,
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.message == "bubblewrap is not installed"
+
+    def test_chroot(self, mocker, new_dir, root):
+        mocker.patch("os.geteuid", return_value=0)
+        mock_run = mocker.patch("subprocess.run")
+        tmpdir = Path(new_dir, "tmp")
+        tmpdir.mkdir()
+        mocker.patch("tempfile.mkdtemp", return_value=str(tmpdir))
+        chroot_dir = tmpdir / "root"
+        work_dir = Path(new_dir, "work")
+
+        with isolation.isolated_command(
+            ["build.sh"],
+            part_name="foo",
+            method=IsolationMethod.CHROOT,
+            root=root,
+            cwd=work_dir / "parts",
+            bind_dirs=[work_dir],
+        ) as command:
+            assert command == [
+                "chroot",
+                str(chroot_dir),
+                "/bin/sh",
+                "-c",
+                'cd "$1" && shift && exec "$@"',
+                "sh",
+                str(work_dir / "parts"),
+                "build.sh",
+            ]
+            assert mock_run.mock_calls == [
+                call(
+                    [
+                        "mount",
+                        "-t",
+                        "overlay",
+                        "overlay",
+                        f"-olowerdir={root},upperdir={tmpdir}/upper,"
+                        f"workdir={tmpdir}/work",
+                        str(chroot_dir),
+                    ],
+                    check=True,
+                ),
+                call(["mount", "--bind", "/dev", f"{chroot_dir}/dev"], check=True),
+                call(
+                    ["mount", "-t", "proc", "proc", f"{chroot_dir}/proc"], check=True
+                ),
+                call(
+                    ["mount", "--bind", str(work_dir), f"{chroot_dir}{work_dir}"],
+                    check=True,
+                ),
+            ]
+            mock_run.reset_mock()
+
+        assert mock_run.mock_calls == [
+            call(["umount", f"{chroot_dir}{work_dir}"], check=True),
+            call(["umount", f"{chroot_dir}/proc"], check=True),
+            call(["umount", f"{chroot_dir}/dev"], check=True),
+            call(["umount", str(chroot_dir)], check=True),
+        ]
+
+        # Mountpoints are not created in the base layer.
+        assert list(root.iterdir()) == []
+        assert tmpdir.exists() is False
+
+    def test_chroot_mount_error(self, mocker, new_dir, root):
+        mocker.patch("os.geteuid", return_value=0)
+        mock_run = mocker.patch(
+            "subprocess.run", side_effect=[None, None, OSError("boom"), None, None]
+        )
+        tmpdir = Path(new_dir, "tmp")
+        tmpdir.mkdir()
+        mocker.patch("tempfile.mkdtemp", return_value=str(tmpdir))
+
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.CHROOT,
+                root=root,
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.message == f"cannot mount '{tmpdir}/root/proc': boom"
+
+        # Successful mounts are undone.
+        assert mock_run.mock_calls[-2:] == [
+            call(["umount", f"{tmpdir}/root/dev"], check=True),
+            call(["umount", f"{tmpdir}/root"], check=True),
+        ]
+        assert tmpdir.exists() is False
+
+    def test_chroot_unmount_error(self, mocker, new_dir, root):
+        mocker.patch("os.geteuid", return_value=0)
+        mocker.patch(
+            "subprocess.run",
+            side_effect=[None, None, None, OSError("busy"), None, None],
+        )
+        tmpdir = Path(new_dir, "tmp")
+        tmpdir.mkdir()
+        mocker.patch("tempfile.mkdtemp", return_value=str(tmpdir))
+
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.CHROOT,
+                root=root,
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.message == f"cannot unmount '{tmpdir}/root/proc'"
+
+        # Directories that may still be mounted are not removed.
+        assert tmpdir.exists()
+
+    def test_chroot_unprivileged(self, mocker, root):
+        mocker.patch("os.geteuid", return_value=1000)
+
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.CHROOT,
+                root=root,
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.message == "chroot isolation requires root privileges"
+
+    def test_unsupported_host(self, mocker, root):
+        mocker.patch("sys.platform", "darwin")
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.BUBBLEWRAP,
+                root=root,
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.message == "build isolation is not supported on macOS hosts"
+
+    def test_no_root(self):
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.CHROOT,
+                root=None,
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.part_name == "foo"
+        assert raised.value.message == "no base layer directory is set"
+
+    def test_root_not_directory(self, new_dir):
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            with isolation.isolated_command(
+                ["build.sh"],
+                part_name="foo",
+                method=IsolationMethod.BUBBLEWRAP,
+                root=Path(new_dir, "missing"),
+                cwd=Path("/work"),
+                bind_dirs=[],
+            ):
+                pass
+        assert raised.value.message == (
+            f"base layer '{new_dir}/missing' is not a directory"
+        )
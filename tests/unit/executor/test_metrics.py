@@ -0,0 +1,110 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import time
+from pathlib import Path
+
+import pytest
+
+from craft_parts.actions import Action, ActionType
+from craft_parts.executor import metrics
+from craft_parts.executor.metrics import ActionMetrics, ActionMonitor
+from craft_parts.steps import Step
+
+
+class TestActionMetrics:
+    """Verify action metrics serialization."""
+
+    def test_marshal(self):
+        m = ActionMetrics(
+            action=Action("foo", Step.BUILD, ActionType.RERUN, reason="dirty"),
+            duration=1.5,
+            cpu_time=0.5,
+            peak_disk_usage=4096,
+        )
+
+        assert m.marshal() == {
+            "part": "foo",
+            "step": "build",
+            "type": "rerun",
+            "reason": "dirty",
+            "duration": 1.5,
+            "cpu-time": 0.5,
+            "peak-disk-usage": 4096,
+        }
+
+    def test_metrics_report(self):
+        items = [
+            ActionMetrics(Action("foo", Step.PULL), 1.0, 0.5, 100),
+            ActionMetrics(Action("bar", Step.PULL), 2.0, 1.0, 50),
+            ActionMetrics(Action("foo", Step.BUILD), 3.0, 2.5, 300),
+        ]
+
+        report = metrics.get_metrics_report(items)
+
+        assert report["actions"] == [x.marshal() for x in items]
+        assert report["parts"] == {
+            "foo": {"duration": 4.0, "cpu-time": 3.0, "peak-disk-usage": 300},
+            "bar": {"duration": 2.0, "cpu-time": 1.0, "peak-disk-usage": 50},
+        }
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestDiskUsage:
+    """Verify disk usage measurement."""
+
+    def test_get_disk_usage(self):
+        Path("dir/subdir").mkdir(parents=True)
+        Path("dir/subdir/foo").write_bytes(b"\1" * 8192)
+        Path("dir/bar").write_bytes(b"\1" * 8192)
+
+        assert metrics.get_disk_usage(Path("dir")) >= 16384
+
+    def test_get_disk_usage_missing(self):
+        assert metrics.get_disk_usage(Path("missing")) == 0
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestActionMonitor:
+    """Verify resource usage monitoring."""
+
+    def test_metrics(self):
+        Path("dir").mkdir()
+        action = Action("foo", Step.BUILD)
+
+        monitor = ActionMonitor(action, path=Path("dir"))
+        monitor.start()
+        Path("dir/foo").write_bytes(b"\1" * 8192)
+        result = monitor.stop()
+
+        assert result.action == action
+        assert result.duration > 0
+        assert result.cpu_time >= 0
+        assert result.peak_disk_usage >= 8192
+
+    def test_peak_disk_usage(self):
+        Path("dir").mkdir()
+
+        monitor = ActionMonitor(
+            Action("foo", Step.BUILD), path=Path("dir"), interval=0.01
+        )
+        monitor.start()
+        Path("dir/foo").write_bytes(b"\1" * 8192)
+        time.sleep(0.2)
+        Path("dir/foo").unlink()
+        result = monitor.stop()
+
+        assert result.peak_disk_usage >= 8192
@@ -16,6 +16,7 @@
 
 import os
 import re
+import stat
 from pathlib import Path
 from typing import Any, List
 
@@ -23,6 +24,7 @@ import pytest
 
 from craft_parts import errors
 from craft_parts.executor.organize import organize_files
+from craft_parts.parts import OrganizeSpec
 
 
 @pytest.mark.parametrize(
@@ -153,6 +155,78 @@ from craft_parts.executor.organize import organize_files
             expected_message=None,
             expected_overwrite=None,
         ),
+        # globstar_into_dir
+        dict(
+            setup_dirs=["usr", os.path.join("usr", "lib")],
+            setup_files=[
+                os.path.join("usr", "foo.so"),
+                os.path.join("usr", "lib", "bar.so"),
+                os.path.join("usr", "lib", "bar.a"),
+            ],
+            organize_map={"usr/**/*.so": "lib/"},
+            expected=[
+                (["lib", "usr"], ""),
+                (["bar.so", "foo.so"], "lib"),
+                (["bar.a"], os.path.join("usr", "lib")),
+            ],
+            expected_message=None,
+            expected_overwrite=None,
+        ),
+        # regex_with_capture_groups
+        dict(
+            setup_dirs=["lib"],
+            setup_files=[
+                os.path.join("lib", "libfoo.so.1"),
+                os.path.join("lib", "libbar.so.2"),
+                os.path.join("lib", "README"),
+            ],
+            organize_map={r"^lib/(.*)\.so\.\d+$": r"usr/lib/\1.so"},
+            expected=[
+                (["lib", "usr"], ""),
+                (["README"], "lib"),
+                (["libbar.so", "libfoo.so"], os.path.join("usr", "lib")),
+            ],
+            expected_message=None,
+            expected_overwrite=None,
+        ),
+        # regex_into_dir
+        dict(
+            setup_dirs=["bin", "sbin"],
+            setup_files=[os.path.join("bin", "foo"), os.path.join("sbin", "bar")],
+            organize_map={r"^(bin|sbin)/": "usr/bin/"},
+            expected=[
+                (["bin", "sbin", "usr"], ""),
+                (["bar", "foo"], os.path.join("usr", "bin")),
+            ],
+            expected_message=None,
+            expected_overwrite=None,
+        ),
+        # regex_for_files_with_non_dir_dst
+        dict(
+            setup_dirs=["lib"],
+            setup_files=[
+                os.path.join("lib", "libfoo.so.1"),
+                os.path.join("lib", "libfoo.so.2"),
+            ],
+            organize_map={r"^lib/libfoo\.so\.\d+$": "lib/libfoo.so"},
+            expected=errors.FileOrganizeError,
+            expected_message=(
+                r".*multiple files to be organized into 'lib/libfoo.so'.*"
+            ),
+            expected_overwrite=None,
+        ),
+        # regex_overwrite_existing_file
+        dict(
+            setup_dirs=[],
+            setup_files=["foo.1", "bar"],
+            organize_map={r"^foo\.\d$": "bar"},
+            expected=errors.FileOrganizeError,
+            expected_message=(
+                r".*trying to organize file 'foo.1' to 'bar', but 'bar' already "
+                r"exists.*"
+            ),
+            expected_overwrite=[(["bar"], "")],
+        ),
     ],
 )
 def test_organize(new_dir, data):
@@ -225,3 +299,131 @@ def _organize_and_assert(
             dir_contents = os.listdir(dir_path)
             dir_contents.sort()
             assert dir_contents == expect[0]
+
+
+def test_organize_symlink(new_dir):
+    base_dir = Path("install")
+    Path("install/lib").mkdir(parents=True)
+    Path("install/lib/libfoo.so.1").write_text("content")
+    Path("install/share").mkdir()
+
+    organize_files(
+        part_name="part-name",
+        mapping={
+            "lib/libfoo.so.1": OrganizeSpec(path="lib/libfoo.so", symlink=True),
+            "share": OrganizeSpec(path="usr/share", symlink=True),
+        },
+        base_dir=base_dir,
+        overwrite=False,
+    )
+
+    assert os.readlink("install/lib/libfoo.so") == "libfoo.so.1"
+    assert Path("install/lib/libfoo.so.1").read_text() == "content"
+    assert os.readlink("install/usr/share") == "../share"
+    assert Path("install/share").is_dir()
+
+    # organize again by overwriting
+    organize_files(
+        part_name="part-name",
+        mapping={"lib/libfoo.so.1": OrganizeSpec(path="lib/libfoo.so", symlink=True)},
+        base_dir=base_dir,
+        overwrite=True,
+    )
+
+    assert os.readlink("install/lib/libfoo.so") == "libfoo.so.1"
+
+
+def test_organize_regex_symlink(new_dir):
+    base_dir = Path("install")
+    Path("install/lib").mkdir(parents=True)
+    Path("install/lib/libfoo.so.1").touch()
+    Path("install/lib/libbar.so.2").touch()
+
+    organize_files(
+        part_name="part-name",
+        mapping={r"^lib/(.*)\.so\.\d+$": OrganizeSpec(path=r"lib/\1.so", symlink=True)},
+        base_dir=base_dir,
+        overwrite=False,
+    )
+
+    assert os.readlink("install/lib/libfoo.so") == "libfoo.so.1"
+    assert os.readlink("install/lib/libbar.so") == "libbar.so.2"
+
+
+def test_organize_mode_and_ownership(new_dir, mocker):
+    mock_chown = mocker.patch("os.chown")
+    base_dir = Path("install")
+    base_dir.mkdir()
+    Path("install/foo").touch()
+    Path("install/bar").touch()
+
+    organize_files(
+        part_name="part-name",
+        mapping={
+            "foo": OrganizeSpec(path="bin/foo", mode="0755", owner="0", group="0"),
+            "bar": OrganizeSpec(path="etc/bar", mode="600"),
+        },
+        base_dir=base_dir,
+        overwrite=False,
+    )
+
+    assert stat.S_IMODE(os.stat("install/bin/foo").st_mode) == 0o755
+    assert stat.S_IMODE(os.stat("install/etc/bar").st_mode) == 0o600
+    mock_chown.assert_called_once_with(
+        os.path.join("install", "bin/foo"), 0, 0, follow_symlinks=False
+    )
+
+
+def test_organize_ownership_by_name(new_dir, mocker):
+    mock_chown = mocker.patch("os.chown")
+    mocker.patch("pwd.getpwnam", return_value=mocker.Mock(pw_uid=1000))
+    mocker.patch("grp.getgrnam", return_value=mocker.Mock(gr_gid=2000))
+    base_dir = Path("install")
+    base_dir.mkdir()
+    Path("install/foo").touch()
+
+    organize_files(
+        part_name="part-name",
+        mapping={"foo": OrganizeSpec(path="bin/foo", owner="user", group="group")},
+        base_dir=base_dir,
+        overwrite=False,
+    )
+
+    mock_chown.assert_called_once_with(
+        os.path.join("install", "bin/foo"), 1000, 2000, follow_symlinks=False
+    )
+
+
+def test_organize_ownership_invalid_user(new_dir, mocker):
+    mocker.patch("os.chown")
+    mocker.patch("pwd.getpwnam", side_effect=KeyError("invalid"))
+    base_dir = Path("install")
+    base_dir.mkdir()
+    Path("install/foo").touch()
+
+    with pytest.raises(errors.FileOrganizeError) as raised:
+        organize_files(
+            part_name="part-name",
+            mapping={"foo": OrganizeSpec(path="bin/foo", owner="invalid")},
+            base_dir=base_dir,
+            overwrite=False,
+        )
+    assert raised.value.message == "user 'invalid' does not exist"
+
+
+def test_organize_ownership_not_permitted(new_dir, mocker):
+    mocker.patch("os.chown", side_effect=PermissionError(1, "Operation not permitted"))
+    base_dir = Path("install")
+    base_dir.mkdir()
+    Path("install/foo").touch()
+
+    with pytest.raises(errors.FileOrganizeError) as raised:
+        organize_files(
+            part_name="part-name",
+            mapping={"foo": OrganizeSpec(path="bin/foo", group="0")},
+            base_dir=base_dir,
+            overwrite=False,
+        )
+    assert raised.value.message == (
+        "cannot change ownership of 'foo': Operation not permitted"
+    )
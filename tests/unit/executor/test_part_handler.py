@@ -0,0 +1,1083 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import hashlib
+import os
+import stat
+import sys
+from pathlib import Path
+
+import pytest
+
+from craft_parts import callbacks, ctl, errors
+from craft_parts.actions import Action, ActionType
+from craft_parts.executor.part_handler import PartHandler
+from craft_parts.executor.step_handler import StepHandler
+from craft_parts.infos import PartInfo, ProjectInfo
+from craft_parts.packages import errors as packages_errors
+from craft_parts.packages.deb import Ubuntu
+from craft_parts.packages.lockfile import LockedPackage, LockfileMode, PackagesLockfile
+from craft_parts.parts import Part
+from craft_parts.plugins import PluginEnvironmentValidator
+from craft_parts.plugins.dump_plugin import DumpPlugin, DumpPluginProperties
+from craft_parts.state_manager import states
+from craft_parts.step_cache import LocalCacheBackend
+from craft_parts.steps import Step
+
+_CRAFTCTL = f"{sys.executable} {ctl.__file__}"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPartHandling:
+    """Verify the part handler step processing."""
+
+    def setup_method(self):
+        # pylint: disable=attribute-defined-outside-init
+        Path("foo").mkdir()
+        Path("foo/bar").write_text("content")
+
+        part_data = {"plugin": "dump", "source": "foo"}
+        self._part = Part(
+            "p1",
+            part_data,
+            plugin_properties=DumpPluginProperties.unmarshal(part_data),
+        )
+        info = ProjectInfo()
+        part_info = PartInfo(project_info=info, part=self._part)
+        self._handler = PartHandler(
+            self._part, part_info=part_info, part_list=[self._part]
+        )
+        # pylint: enable=attribute-defined-outside-init
+
+    def test_run_pull(self):
+        self._handler.run_action(Action("p1", Step.PULL))
+
+        assert Path("parts/p1/src/bar").read_text() == "content"
+
+        state = states.load_state(self._part, Step.PULL)
+        assert isinstance(state, states.PullState)
+        assert state.part_properties["source"] == "foo"
+        assert state.assets == {
+            "source-date-epoch": int(Path("foo/bar").stat().st_mtime)
+        }
+
+    def test_run_pull_plugin_commands(self, mocker):
+        mocker.patch.object(DumpPlugin, "get_pull_commands", return_value=["true"])
+        self._handler.run_action(Action("p1", Step.PULL))
+
+        state = states.load_state(self._part, Step.PULL)
+        assert state is not None
+        assert state.assets["pull-commands"] == ["true"]
+
+    def test_run_pull_source_details(self, mocker):
+        def fake_pull(source):
+            source.source_details = {"digest": "sha256:1234"}
+
+        mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.pull",
+            autospec=True,
+            side_effect=fake_pull,
+        )
+        self._handler.run_action(Action("p1", Step.PULL))
+
+        state = states.load_state(self._part, Step.PULL)
+        assert state is not None
+        assert state.assets == {"source-details": {"digest": "sha256:1234"}}
+
+    def test_run_pull_stage_snaps(self, mocker):
+        def fake_download(self, *, directory):
+            Path(directory, "snap1_10.snap").write_text("snap1")
+            Path(directory, "snap1_10.assert").write_text(
+                "type: snap-revision\nsnap-id: snap1-id\nsnap-revision: 10\n"
+            )
+
+        mocker.patch("craft_parts.packages.snaps.SnapPackage.download", fake_download)
+        mock_revisions = mocker.patch("craft_parts.packages.snaps.get_snap_revisions")
+        part_data = {"plugin": "dump", "source": "foo", "stage-snaps": ["snap1"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+
+        mock_revisions.assert_not_called()
+        assert Path(part.part_snaps_dir, "snap1_10.snap").read_text() == "snap1"
+        state = states.load_state(part, Step.PULL)
+        assert state is not None
+        assert state.assets["stage-snaps"] == {
+            "snap1": {"revision": "10", "snap-id": "snap1-id"}
+        }
+
+    def test_run_build_stage_snaps(self, mocker):
+        mocker.patch("craft_parts.packages.snaps.download_snaps")
+        mocker.patch(
+            "craft_parts.packages.snaps.get_downloaded_snap_revisions",
+            return_value={},
+        )
+        mock_unpack = mocker.patch("craft_parts.packages.snaps.unpack_snaps")
+        part_data = {"plugin": "dump", "source": "foo", "stage-snaps": ["snap1"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+        part.part_snaps_dir.mkdir()
+        handler.run_action(Action("p1", Step.BUILD))
+
+        mock_unpack.assert_called_once_with(
+            str(part.part_snaps_dir), install_dir=str(part.part_install_dir)
+        )
+
+    def test_run_rerun_pull_previous_details(self, mocker):
+        previous = []
+
+        def fake_pull(source):
+            previous.append(source.previous_details)
+            source.source_details = {"digest": "sha256:1234"}
+
+        mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.pull",
+            autospec=True,
+            side_effect=fake_pull,
+        )
+        self._handler.run_action(Action("p1", Step.PULL))
+        self._handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert previous == [None, {"digest": "sha256:1234"}]
+
+    def test_run_all_steps(self):
+        for step in list(Step):
+            self._handler.run_action(Action("p1", step))
+
+        assert Path("stage/bar").read_text() == "content"
+        assert Path("prime/bar").read_text() == "content"
+
+        state = states.load_state(self._part, Step.STAGE)
+        assert state is not None
+        assert state.files == {"bar"}
+
+        state = states.load_state(self._part, Step.PRIME)
+        assert state is not None
+        assert state.files == {"bar"}
+
+    def test_run_skip(self):
+        self._handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.SKIP, reason="test")
+        )
+
+        assert Path("parts/p1/src/bar").exists() is False
+        assert states.load_state(self._part, Step.PULL) is None
+
+    def test_run_pull_values(self):
+        part_data = {
+            "plugin": "dump",
+            "source": "foo",
+            "override-pull": f"{_CRAFTCTL} set version=1.0",
+        }
+        part = Part(
+            "p1",
+            part_data,
+            plugin_properties=DumpPluginProperties.unmarshal(part_data),
+        )
+        info = ProjectInfo()
+        info.set_value("revision", "41")
+        part_info = PartInfo(project_info=info, part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+
+        def _pre_step(step_info):
+            # A value set by another part running at the same time.
+            info.set_value("other", "value")
+
+        callbacks.register_pre_step(_pre_step)
+        try:
+            handler.run_action(Action("p1", Step.PULL))
+        finally:
+            callbacks.clear()
+
+        state = states.load_state(part, Step.PULL)
+        assert state is not None
+        assert state.values == {"version": "1.0"}
+        assert info.get_value("version") == "1.0"
+
+    def test_run_skip_values(self):
+        states.PullState(values={"version": "1.0"}).write(
+            states.state_file_path(self._part, Step.PULL)
+        )
+        info = ProjectInfo()
+        part_info = PartInfo(project_info=info, part=self._part)
+        handler = PartHandler(self._part, part_info=part_info, part_list=[self._part])
+        handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.SKIP, reason="test")
+        )
+
+        assert info.get_value("version") == "1.0"
+
+    def test_run_rerun(self):
+        for step in [Step.PULL, Step.BUILD]:
+            self._handler.run_action(Action("p1", step))
+
+        self._handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert states.load_state(self._part, Step.PULL) is not None
+        assert states.load_state(self._part, Step.BUILD) is None
+
+    def test_run_rerun_removes_migrated_files(self):
+        for step in [Step.PULL, Step.BUILD, Step.STAGE, Step.PRIME]:
+            self._handler.run_action(Action("p1", step))
+        assert Path("stage/bar").is_file()
+        assert Path("prime/bar").is_file()
+
+        self._handler.run_action(
+            Action("p1", Step.BUILD, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert Path("stage/bar").exists() is False
+        assert Path("prime/bar").exists() is False
+        assert states.load_state(self._part, Step.STAGE) is None
+
+    def test_run_rerun_keeps_shared_files(self):
+        part_data = {"plugin": "dump", "source": "foo"}
+        other = Part(
+            "p2",
+            part_data,
+            plugin_properties=DumpPluginProperties.unmarshal(part_data),
+        )
+        info = ProjectInfo()
+        other_handler = PartHandler(
+            other,
+            part_info=PartInfo(project_info=info, part=other),
+            part_list=[self._part, other],
+        )
+        handler = PartHandler(
+            self._part,
+            part_info=PartInfo(project_info=info, part=self._part),
+            part_list=[self._part, other],
+        )
+        for step in [Step.PULL, Step.BUILD, Step.STAGE]:
+            handler.run_action(Action("p1", step))
+            other_handler.run_action(Action("p2", step))
+
+        handler.run_action(
+            Action("p1", Step.BUILD, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert Path("stage/bar").is_file()
+        assert states.load_state(self._part, Step.STAGE) is None
+
+    def test_run_failed(self, mocker):
+        mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=RuntimeError("transient error"),
+        )
+
+        with pytest.raises(RuntimeError):
+            self._handler.run_action(Action("p1", Step.PULL))
+
+        assert states.has_step_failed(self._part, Step.PULL)
+        assert Path("parts/p1/state/pull.failed").read_text() == "transient error\n"
+        assert states.load_state(self._part, Step.PULL) is None
+
+        mocker.stopall()
+        self._handler.run_action(Action("p1", Step.PULL))
+
+        assert states.has_step_failed(self._part, Step.PULL) is False
+        assert states.load_state(self._part, Step.PULL) is not None
+
+    def test_run_step_callbacks(self):
+        called = []
+
+        def _pre_step(step_info):
+            called.append(("pre", step_info.part_name, step_info.action))
+
+        def _post_step(step_info):
+            called.append(("post", step_info.part_name, step_info.action))
+
+        callbacks.register_pre_step(_pre_step)
+        callbacks.register_post_step(_post_step)
+        action = Action("p1", Step.PULL, reason="test")
+        try:
+            self._handler.run_action(action)
+        finally:
+            callbacks.clear()
+
+        assert called == [("pre", "p1", action), ("post", "p1", action)]
+
+    def test_run_failed_callback(self, mocker):
+        mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=RuntimeError("transient error"),
+        )
+        failures = []
+
+        def _step_failure(step_info, error):
+            failures.append((step_info.part_name, step_info.step, str(error)))
+
+        callbacks.register_step_failure(_step_failure)
+        try:
+            with pytest.raises(RuntimeError):
+                self._handler.run_action(Action("p1", Step.PULL))
+        finally:
+            callbacks.clear()
+
+        assert failures == [("p1", Step.PULL, "transient error")]
+
+    def test_run_rerun_failed(self):
+        Path("parts/p1/state").mkdir(parents=True)
+        Path("parts/p1/state/build.failed").write_text("error\n")
+        self._handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert states.has_step_failed(self._part, Step.BUILD) is False
+
+    def test_run_build_validate_environment(self, mocker):
+        mock_validate = mocker.patch.object(
+            PluginEnvironmentValidator, "validate_environment"
+        )
+        self._handler.run_action(Action("p1", Step.PULL))
+        self._handler.run_action(Action("p1", Step.BUILD))
+
+        mock_validate.assert_called_once_with(part_dependencies=[])
+
+    def test_run_build_invalid_environment(self, mocker):
+        class InvalidValidator(PluginEnvironmentValidator):
+            """A validator rejecting all environments."""
+
+            def validate_environment(self, *, part_dependencies=None):
+                raise errors.PluginEnvironmentValidationError(
+                    part_name=self.part_name, reason="missing tool"
+                )
+
+        mocker.patch.object(DumpPlugin, "validator_class", InvalidValidator)
+        self._handler.run_action(Action("p1", Step.PULL))
+
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            self._handler.run_action(Action("p1", Step.BUILD))
+
+        assert raised.value.reason == "missing tool"
+        assert Path("parts/p1/install/bar").exists() is False
+        assert states.load_state(self._part, Step.BUILD) is None
+
+    def test_run_build_validation_callback(self):
+        validated = []
+
+        def _validate(step_info, validator):
+            validated.append((step_info.step, validator.part_name))
+            validator.validate_dependency("true", argument="")
+
+        callbacks.register_environment_validation(_validate)
+        try:
+            self._handler.run_action(Action("p1", Step.PULL))
+            self._handler.run_action(Action("p1", Step.BUILD))
+        finally:
+            callbacks.clear()
+
+        assert validated == [(Step.BUILD, "p1")]
+
+    def test_run_build_environment_snapshot(self, mocker):
+        mock_repo = mocker.patch("craft_parts.packages.get_repository_for_base")
+        mock_repo.return_value.get_package_name.side_effect = lambda name: name
+        mock_repo.return_value.get_installed_packages.return_value = [
+            "hello=2.10-2",
+            "make=4.3-4",
+        ]
+        part_data = {"plugin": "dump", "source": "foo", "build-packages": ["make"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+        handler.run_action(Action("p1", Step.BUILD))
+
+        state = states.load_state(part, Step.BUILD)
+        assert state is not None
+        snapshot = state.assets["build-environment"]
+        assert snapshot["target-arch"] == part_info.target_arch
+        assert snapshot["toolchain"] == {}
+        assert snapshot["build-packages"] == {"make": "4.3-4"}
+        assert "PATH" in snapshot["environment"]
+
+    def test_run_pull_stage_packages(self, mocker):
+        mock_repo = mocker.patch("craft_parts.packages.get_repository_for_base")
+        mock_fetch = mock_repo.return_value.fetch_stage_packages
+        mock_fetch.return_value = ["hello=2.10-2"]
+        part_data = {
+            "plugin": "dump",
+            "source": "foo",
+            "stage-packages": ["hello"],
+            "stage-packages-recommends": True,
+            "stage-packages-suggests": True,
+        }
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+
+        mock_fetch.assert_called_once_with(
+            application_name=part_info.application_name,
+            package_names=["hello"],
+            stage_packages_path=part.part_packages_dir,
+            base=part_info.build_base.name,
+            target_arch=part_info.target_arch,
+            recommends=True,
+            suggests=True,
+        )
+        state = states.load_state(part, Step.PULL)
+        assert state is not None
+        assert state.assets["stage-packages"] == ["hello=2.10-2"]
+
+    @pytest.fixture
+    def fake_repository(self, mocker):
+        def fake_fetch(**kwargs):
+            Path(kwargs["stage_packages_path"]).mkdir(parents=True, exist_ok=True)
+            Path(kwargs["stage_packages_path"], "hello=2.10-2.deb").write_text("hello")
+            return ["hello=2.10-2"]
+
+        mock_repo = mocker.patch("craft_parts.packages.get_repository_for_base")
+        mock_repo.return_value.fetch_stage_packages.side_effect = fake_fetch
+        mock_repo.return_value.get_package_file_name_version.side_effect = (
+            lambda path: path.name[: -len(".deb")]
+        )
+        return mock_repo.return_value
+
+    def test_run_pull_record_lockfile(self, fake_repository):
+        part_data = {"plugin": "dump", "source": "foo", "stage-packages": ["hello"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        project_info = ProjectInfo(packages_lockfile=Path("packages.lock"))
+        part_info = PartInfo(project_info=project_info, part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+
+        locked = PackagesLockfile.read(Path("packages.lock")).stage_packages
+        assert locked == {
+            "p1": [
+                LockedPackage(
+                    name="hello",
+                    version="2.10-2",
+                    sha256=hashlib.sha256(b"hello").hexdigest(),
+                )
+            ]
+        }
+
+    def test_run_pull_replay_lockfile(self, fake_repository):
+        PackagesLockfile.unmarshal(
+            {
+                "stage-packages": {
+                    "p1": [
+                        {
+                            "name": "hello",
+                            "version": "2.10-2",
+                            "sha256": hashlib.sha256(b"hello").hexdigest(),
+                        }
+                    ]
+                }
+            }
+        ).write(Path("packages.lock"))
+        part_data = {"plugin": "dump", "source": "foo", "stage-packages": ["hello"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        project_info = ProjectInfo(
+            packages_lockfile=Path("packages.lock"),
+            packages_lockfile_mode=LockfileMode.REPLAY,
+        )
+        part_info = PartInfo(project_info=project_info, part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+
+        fake_repository.fetch_stage_packages.assert_called_once_with(
+            application_name=part_info.application_name,
+            package_names=["hello=2.10-2"],
+            stage_packages_path=part.part_packages_dir,
+            base=part_info.build_base.name,
+            target_arch=part_info.target_arch,
+        )
+        state = states.load_state(part, Step.PULL)
+        assert state is not None
+        assert state.assets["stage-packages"] == ["hello=2.10-2"]
+
+    def test_run_pull_replay_lockfile_not_locked(self, fake_repository):
+        PackagesLockfile().write(Path("packages.lock"))
+        part_data = {"plugin": "dump", "source": "foo", "stage-packages": ["hello"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        project_info = ProjectInfo(
+            packages_lockfile=Path("packages.lock"),
+            packages_lockfile_mode=LockfileMode.REPLAY,
+        )
+        part_info = PartInfo(project_info=project_info, part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+
+        with pytest.raises(packages_errors.StagePackagesLockMismatch):
+            handler.run_action(Action("p1", Step.PULL))
+
+        fake_repository.fetch_stage_packages.assert_not_called()
+
+    def test_run_build_stage_packages(self, mocker):
+        mock_repo = mocker.patch("craft_parts.packages.get_repository_for_base")
+        mock_repo.return_value.fetch_stage_packages.side_effect = (
+            lambda **kwargs: kwargs["stage_packages_path"].mkdir() or []
+        )
+        part_data = {"plugin": "dump", "source": "foo", "stage-packages": ["hello"]}
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+        handler.run_action(Action("p1", Step.BUILD))
+
+        mock_repo.return_value.unpack_stage_packages.assert_called_once_with(
+            stage_packages_path=part.part_packages_dir,
+            install_path=part.part_install_dir,
+        )
+
+    def test_run_stage_slices(self, mocker):
+        mock_repo = mocker.patch("craft_parts.packages.get_repository_for_base")
+        mock_fetch = mock_repo.return_value.fetch_stage_packages
+        mock_fetch.return_value = ["hello=2.10-2"]
+        mock_resolve = mocker.patch(
+            "craft_parts.packages.chisel.resolve_release", return_value=Path("slices")
+        )
+
+        def fake_cut(slices, *, install_path, release_dir, target_arch):
+            Path(install_path, "lib").mkdir(parents=True)
+            Path(install_path, "lib/libc.so.6").write_text("libc")
+
+        mock_cut = mocker.patch(
+            "craft_parts.packages.chisel.cut_slices", side_effect=fake_cut
+        )
+        part_data = {
+            "plugin": "dump",
+            "source": "foo",
+            "stage-packages": ["hello", "libc6_libs"],
+            "chisel-release": "slices",
+        }
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        handler.run_action(Action("p1", Step.PULL))
+
+        assert mock_fetch.mock_calls[0].kwargs["package_names"] == ["hello"]
+        assert mock_resolve.mock_calls[0].args == ("slices",)
+        mock_cut.assert_called_once_with(
+            ["libc6_libs"],
+            install_path=part.part_slices_dir,
+            release_dir=Path("slices"),
+            target_arch=part_info.target_arch,
+        )
+
+        handler.run_action(Action("p1", Step.BUILD))
+        assert Path(part.part_install_dir, "lib/libc.so.6").read_text() == "libc"
+
+    def test_run_update_pull(self, mocker):
+        mock_update_pull = mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.update_pull"
+        )
+        self._handler.run_action(Action("p1", Step.PULL))
+        self._handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.UPDATE, reason="test")
+        )
+
+        mock_update_pull.assert_called_once_with()
+        assert states.load_state(self._part, Step.PULL) is not None
+
+    def test_run_update_pull_overridden(self, mocker):
+        part_data = {"plugin": "dump", "source": "foo", "override-pull": "true"}
+        part = Part(
+            "p1",
+            part_data,
+            plugin_properties=DumpPluginProperties.unmarshal(part_data),
+        )
+        info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=info, part_list=[part])
+        mock_update_pull = mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.update_pull"
+        )
+        mock_source_update = mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.update"
+        )
+
+        handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.UPDATE, reason="test")
+        )
+
+        mock_source_update.assert_called_once_with()
+        mock_update_pull.assert_not_called()
+
+    @pytest.mark.parametrize("normalize", [True, False])
+    def test_run_prime_normalize(self, normalize):
+        os.utime("foo/bar", (1000, 1000))
+        part_info = PartInfo(
+            project_info=ProjectInfo(normalize_prime=normalize), part=self._part
+        )
+        handler = PartHandler(self._part, part_info=part_info, part_list=[self._part])
+        for step in [Step.PULL, Step.BUILD, Step.STAGE]:
+            handler.run_action(Action("p1", step))
+
+        # The primed file is modified after the source was pulled.
+        os.utime("parts/p1/install/bar", (2000, 2000))
+        handler.run_action(Action("p1", Step.PRIME))
+
+        mtime = os.lstat("prime/bar").st_mtime
+        assert mtime == (1000 if normalize else 2000)
+
+    def test_run_prime_permissions(self):
+        part_data = {
+            "plugin": "dump",
+            "source": "foo",
+            "permissions": [{"path": "bar", "mode": "600"}],
+        }
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        for step in Step:
+            handler.run_action(Action("p1", step))
+
+        assert stat.S_IMODE(os.stat("prime/bar").st_mode) == 0o600
+        assert stat.S_IMODE(os.stat("stage/bar").st_mode) != 0o600
+
+        state = states.load_state(part, Step.PRIME)
+        assert isinstance(state, states.PrimeState)
+        assert state.permissions == {"bar": {"mode": "600"}}
+
+    @pytest.mark.parametrize(
+        "policy,content", [("first-wins", "content"), ("last-wins", "other")]
+    )
+    def test_run_stage_conflict_policy(self, policy, content):
+        Path("baz").mkdir()
+        Path("baz/bar").write_text("other")
+        part_data = {"plugin": "dump", "source": "baz", "stage-conflict-policy": policy}
+        part = Part(
+            "p2", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_list = [self._part, part]
+        for p in part_list:
+            part_info = PartInfo(project_info=ProjectInfo(), part=p)
+            handler = PartHandler(p, part_info=part_info, part_list=part_list)
+            for step in [Step.PULL, Step.BUILD, Step.STAGE]:
+                handler.run_action(Action(p.name, step))
+
+        assert Path("stage/bar").read_text() == content
+
+    def test_run_stage_conflict(self):
+        Path("baz").mkdir()
+        Path("baz/bar").write_text("other")
+        part_data = {"plugin": "dump", "source": "baz"}
+        part = Part(
+            "p2", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_list = [self._part, part]
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=part_list)
+        for step in [Step.PULL, Step.BUILD]:
+            self._handler.run_action(Action("p1", step))
+            handler.run_action(Action("p2", step))
+
+        with pytest.raises(errors.PartFilesConflict):
+            handler.run_action(Action("p2", Step.STAGE))
+
+    @pytest.mark.parametrize("split_debug", [True, False])
+    def test_run_prime_strip(self, mocker, split_debug):
+        mock_strip = mocker.patch("craft_parts.executor.strip.strip_files")
+        part_data = {
+            "plugin": "dump",
+            "source": "foo",
+            "strip": {"split-debug": split_debug, "exclude": ["lib/*"]},
+        }
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        for step in Step:
+            handler.run_action(Action("p1", step))
+
+        mock_strip.assert_called_once_with(
+            part_name="p1",
+            root=part.prime_dir,
+            paths={"bar"},
+            exclude=["lib/*"],
+            debug_dir=part.debug_dir if split_debug else None,
+        )
+
+    def test_run_prime_elf_patch(self, mocker):
+        patches = {"bar": {"rpath": "$ORIGIN", "interpreter": None, "digest": "1"}}
+        mock_patch = mocker.patch(
+            "craft_parts.elf.ElfPatcher.patch", return_value=patches
+        )
+        part_data = {
+            "plugin": "dump",
+            "source": "foo",
+            "elf-patch": {"interpreter": "/lib/ld.so"},
+        }
+        part = Part(
+            "p1", part_data, plugin_properties=DumpPluginProperties.unmarshal(part_data)
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=part)
+        handler = PartHandler(part, part_info=part_info, part_list=[part])
+        for step in Step:
+            handler.run_action(Action("p1", step))
+
+        mock_patch.assert_called_once_with({"bar"}, previous={})
+        state = states.load_state(part, Step.PRIME)
+        assert isinstance(state, states.PrimeState)
+        assert state.elf_patches == patches
+
+        # The previous results are used when priming again.
+        handler.run_action(
+            Action("p1", Step.PRIME, action_type=ActionType.RERUN, reason="test")
+        )
+        assert mock_patch.call_args[1] == {"previous": patches}
+
+    def test_run_prime_no_strip(self, mocker):
+        mock_strip = mocker.patch("craft_parts.executor.strip.strip_files")
+        for step in Step:
+            self._handler.run_action(Action("p1", step))
+
+        mock_strip.assert_not_called()
+
+    def test_run_invalid_step(self):
+        with pytest.raises(RuntimeError) as raised:
+            self._handler.run_action(Action("p1", 999))  # type: ignore
+        assert str(raised.value) == "cannot run action for invalid step 999"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestStepRetries:
+    """Verify step timeouts and retries."""
+
+    def _make_handler(self, part_data, **kwargs):
+        Path("foo").mkdir(exist_ok=True)
+        Path("foo/bar").write_text("content")
+        part = Part(
+            "p1",
+            part_data,
+            plugin_properties=DumpPluginProperties.unmarshal(part_data),
+        )
+        info = PartInfo(project_info=ProjectInfo(**kwargs), part=part)
+        return part, PartHandler(part, part_info=info, part_list=[part])
+
+    def test_retry_with_backoff(self, mocker):
+        part, handler = self._make_handler(
+            {"plugin": "dump", "source": "foo", "step-retries": {"pull": 3}},
+            retry_delay=2,
+        )
+        error = errors.PluginPullError(part_name="p1")
+        mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=[error, error, (set(), set())],
+        )
+        mock_time = mocker.patch("craft_parts.executor.part_handler.time")
+
+        handler.run_action(Action("p1", Step.PULL))
+
+        assert mock_time.sleep.mock_calls == [mocker.call(2), mocker.call(4)]
+        assert states.load_state(part, Step.PULL) is not None
+        assert states.has_step_failed(part, Step.PULL) is False
+
+    def test_retry_exhausted(self, mocker):
+        part, handler = self._make_handler(
+            {"plugin": "dump", "source": "foo"}, step_retries={Step.PULL: 1}
+        )
+        mock_run = mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=errors.PluginPullError(part_name="p1"),
+        )
+        mock_time = mocker.patch("craft_parts.executor.part_handler.time")
+
+        with pytest.raises(errors.PluginPullError):
+            handler.run_action(Action("p1", Step.PULL))
+
+        assert mock_run.call_count == 2
+        assert mock_time.sleep.mock_calls == [mocker.call(1.0)]
+        assert states.has_step_failed(part, Step.PULL)
+
+    def test_retry_part_overrides_project(self, mocker):
+        _, handler = self._make_handler(
+            {"plugin": "dump", "source": "foo", "step-retries": {"pull": 0}},
+            step_retries={Step.PULL: 3},
+        )
+        mock_run = mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=errors.PluginPullError(part_name="p1"),
+        )
+        mocker.patch("craft_parts.executor.part_handler.time")
+
+        with pytest.raises(errors.PluginPullError):
+            handler.run_action(Action("p1", Step.PULL))
+
+        assert mock_run.call_count == 1
+
+    def test_no_retry_other_errors(self, mocker):
+        _, handler = self._make_handler(
+            {"plugin": "dump", "source": "foo"}, step_retries={Step.PULL: 3}
+        )
+        mock_run = mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=RuntimeError("bug"),
+        )
+
+        with pytest.raises(RuntimeError):
+            handler.run_action(Action("p1", Step.PULL))
+
+        assert mock_run.call_count == 1
+
+    def test_retry_pull_removes_partial_sources(self, mocker):
+        _, handler = self._make_handler(
+            {"plugin": "dump", "source": "foo"}, step_retries={Step.PULL: 1}
+        )
+        found = []
+
+        def _pull():
+            found.append(Path("parts/p1/src/partial").exists())
+            if len(found) == 1:
+                Path("parts/p1/src/partial").touch()
+                raise errors.PluginPullError(part_name="p1")
+            return (set(), set())
+
+        mocker.patch(
+            "craft_parts.executor.step_handler.StepHandler.run_builtin",
+            side_effect=_pull,
+        )
+        mocker.patch("craft_parts.executor.part_handler.time")
+
+        handler.run_action(Action("p1", Step.PULL))
+
+        assert found == [False, False]
+
+    @pytest.mark.parametrize(
+        "part_timeouts,project_timeouts,expected",
+        [
+            ({}, {}, None),
+            ({}, {Step.BUILD: 60}, 60),
+            ({"build": 30}, {Step.BUILD: 60}, 30),
+            ({"pull": 30}, {}, None),
+        ],
+    )
+    def test_step_timeout(self, mocker, part_timeouts, project_timeouts, expected):
+        _, handler = self._make_handler(
+            {"plugin": "dump", "source": "foo", "step-timeouts": part_timeouts},
+            step_timeouts=project_timeouts,
+        )
+        handler.run_action(Action("p1", Step.PULL))
+        spy = mocker.patch(
+            "craft_parts.executor.part_handler.StepHandler", wraps=StepHandler
+        )
+
+        handler.run_action(Action("p1", Step.BUILD))
+
+        assert spy.call_args[1]["timeout"] == expected
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestStepCache:
+    """Verify restoring step outputs from the step cache."""
+
+    def _make_handler(self, new_dir, part_data):
+        part = Part("p1", part_data)
+        backend = LocalCacheBackend("test", cache_dir=new_dir / "cache")
+        info = ProjectInfo(step_cache_backend=backend)
+        part_info = PartInfo(project_info=info, part=part)
+        return part, PartHandler(part, part_info=part_info, part_list=[part])
+
+    def test_build_cached(self, new_dir):
+        part, handler = self._make_handler(
+            new_dir,
+            {
+                "plugin": "nil",
+                "override-build": (
+                    f"echo run >> {new_dir}/count\n"
+                    f"echo built > {new_dir}/parts/p1/install/file"
+                ),
+            },
+        )
+        handler.run_action(Action("p1", Step.PULL))
+        handler.run_action(Action("p1", Step.BUILD))
+        handler.run_action(
+            Action("p1", Step.BUILD, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert Path("count").read_text() == "run\n"
+        assert Path("parts/p1/install/file").read_text() == "built\n"
+        assert states.load_state(part, Step.BUILD) is not None
+
+    def test_build_source_changed(self, new_dir):
+        Path("foo").mkdir()
+        Path("foo/bar").write_text("content")
+        _, handler = self._make_handler(
+            new_dir,
+            {
+                "plugin": "nil",
+                "source": "foo",
+                "override-build": f"echo run >> {new_dir}/count",
+            },
+        )
+        handler.run_action(Action("p1", Step.PULL))
+        handler.run_action(Action("p1", Step.BUILD))
+
+        Path("foo/bar").write_text("changed")
+        handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.RERUN, reason="test")
+        )
+        handler.run_action(
+            Action("p1", Step.BUILD, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert Path("count").read_text() == "run\nrun\n"
+
+    def test_pull_cached(self, new_dir):
+        part, handler = self._make_handler(
+            new_dir,
+            {"plugin": "nil", "override-pull": f"echo run >> {new_dir}/count"},
+        )
+        handler.run_action(Action("p1", Step.PULL))
+        handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert Path("count").read_text() == "run\n"
+        assert states.load_state(part, Step.PULL) is not None
+
+    def test_pull_unpinned_source(self, new_dir):
+        Path("foo").mkdir()
+        _, handler = self._make_handler(new_dir, {"plugin": "nil", "source": "foo"})
+        handler.run_action(Action("p1", Step.PULL))
+
+        assert Path("cache/steps").exists() is False
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestOverlay:
+    """Verify the creation and migration of overlay layers."""
+
+    @pytest.fixture(autouse=True)
+    def setup_method_fixture(self, new_dir, monkeypatch):
+        # pylint: disable=attribute-defined-outside-init
+        monkeypatch.setenv("CRAFT_PARTS_OVERLAY_BACKEND", "copy")
+        self._base_layer_dir = Path("cache/overlay-base/sha256", "a" * 64, "rootfs")
+        (self._base_layer_dir / "etc").mkdir(parents=True)
+        self._info = ProjectInfo(
+            cache_dir=new_dir / "cache",
+            base_layer_dir=self._base_layer_dir,
+            features={"overlay": True},
+        )
+        # pylint: enable=attribute-defined-outside-init
+
+    def _make_handler(self, part_data):
+        part = Part("p1", {"plugin": "nil", **part_data})
+        part_info = PartInfo(project_info=self._info, part=part)
+        return part, PartHandler(part, part_info=part_info, part_list=[part])
+
+    def test_run_overlay_empty(self, mocker):
+        mock_chroot = mocker.patch("craft_parts.overlays.run_chroot_script")
+        part, handler = self._make_handler({})
+        handler.run_action(Action("p1", Step.OVERLAY))
+
+        mock_chroot.assert_not_called()
+        assert list(part.part_layer_dir.iterdir()) == []
+
+        state = states.load_state(part, Step.OVERLAY)
+        assert isinstance(state, states.OverlayState)
+        assert state.assets["layer-key"].startswith("sha256/")
+
+    def test_run_overlay(self, mocker):
+        def fake_chroot(script, *, root, script_name="overlay-script", **kwargs):
+            Path(root, "etc", script_name).write_text(script)
+
+        mock_chroot = mocker.patch(
+            "craft_parts.overlays.run_chroot_script", side_effect=fake_chroot
+        )
+        mocker.patch(
+            "craft_parts.packages.get_repository_for_base", return_value=Ubuntu
+        )
+        part, handler = self._make_handler(
+            {"overlay-packages": ["hello"], "overlay-script": "echo hello"}
+        )
+        handler.run_action(Action("p1", Step.OVERLAY))
+
+        assert Path(part.part_layer_dir, "etc/overlay-packages").read_text() == (
+            "apt-get update\n"
+            "env DEBIAN_FRONTEND=noninteractive apt-get --no-install-recommends "
+            "-y install hello"
+        )
+        assert Path(part.part_layer_dir, "etc/overlay-script").read_text() == (
+            "echo hello"
+        )
+        assert mock_chroot.call_count == 2
+
+        # the layer is restored from the cache if the definition didn't change
+        mock_chroot.reset_mock()
+        handler.run_action(
+            Action("p1", Step.OVERLAY, action_type=ActionType.RERUN, reason="test")
+        )
+
+        mock_chroot.assert_not_called()
+        assert Path(part.part_layer_dir, "etc/overlay-script").is_file()
+
+    def test_run_stage_overlay_files(self):
+        part, handler = self._make_handler({"overlay": ["-usr/share"]})
+        for step in [Step.PULL, Step.OVERLAY, Step.BUILD]:
+            handler.run_action(Action("p1", step))
+
+        Path(part.part_layer_dir, "usr/share").mkdir(parents=True)
+        Path(part.part_layer_dir, "usr/share/doc").touch()
+        Path(part.part_layer_dir, "usr/bin").mkdir()
+        Path(part.part_layer_dir, "usr/bin/hello").write_text("layer")
+        Path(part.part_install_dir, "usr/bin").mkdir(parents=True)
+        Path(part.part_install_dir, "usr/bin/hello").write_text("install")
+
+        for step in [Step.STAGE, Step.PRIME]:
+            handler.run_action(Action("p1", step))
+
+        # files installed by the part replace files from the layer
+        assert Path("stage/usr/bin/hello").read_text() == "install"
+        assert Path("prime/usr/bin/hello").read_text() == "install"
+        assert Path("stage/usr/share").exists() is False
+
+        state = states.load_state(part, Step.STAGE)
+        assert state is not None
+        assert state.files == {"usr/bin/hello"}
+        assert state.directories == {"usr", "usr/bin"}
+
+    def test_run_rerun_pull_removes_layer(self):
+        part, handler = self._make_handler({})
+        for step in [Step.PULL, Step.OVERLAY]:
+            handler.run_action(Action("p1", step))
+        assert part.part_layer_dir.is_dir()
+
+        handler.run_action(
+            Action("p1", Step.PULL, action_type=ActionType.RERUN, reason="test")
+        )
+
+        assert part.part_layer_dir.exists() is False
+        assert states.load_state(part, Step.OVERLAY) is None
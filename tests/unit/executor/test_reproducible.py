@@ -0,0 +1,134 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import os
+from pathlib import Path
+
+import pytest
+
+from craft_parts.executor import reproducible
+from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
+from craft_parts.parts import Part
+from craft_parts.state_manager import states
+from craft_parts.steps import Step
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestSourceDateEpoch:
+    """Verify the source timestamp used in part steps."""
+
+    def _step_info(self, part, step, **kwargs):
+        info = ProjectInfo(**kwargs)
+        return StepInfo(PartInfo(project_info=info, part=part), step)
+
+    def test_from_pull_state(self):
+        p1 = Part("p1", {})
+        states.PullState(assets={"source-date-epoch": 1000}).write(
+            states.state_file_path(p1, Step.PULL)
+        )
+
+        epoch = reproducible.get_source_date_epoch(
+            p1, step_info=self._step_info(p1, Step.BUILD)
+        )
+        assert epoch == 1000
+
+    def test_project_epoch(self):
+        p1 = Part("p1", {})
+        states.PullState(assets={"source-date-epoch": 1000}).write(
+            states.state_file_path(p1, Step.PULL)
+        )
+
+        epoch = reproducible.get_source_date_epoch(
+            p1, step_info=self._step_info(p1, Step.BUILD, source_date_epoch=2000)
+        )
+        assert epoch == 2000
+
+    def test_pull_step(self):
+        p1 = Part("p1", {})
+        states.PullState(assets={"source-date-epoch": 1000}).write(
+            states.state_file_path(p1, Step.PULL)
+        )
+
+        epoch = reproducible.get_source_date_epoch(
+            p1, step_info=self._step_info(p1, Step.PULL)
+        )
+        assert epoch is None
+
+    def test_not_pulled(self):
+        p1 = Part("p1", {})
+
+        epoch = reproducible.get_source_date_epoch(
+            p1, step_info=self._step_info(p1, Step.BUILD)
+        )
+        assert epoch is None
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestNormalizeFiles:
+    """Verify the normalization of file metadata."""
+
+    def test_clamp_mtimes(self):
+        Path("prime/dir").mkdir(parents=True)
+        Path("prime/dir/new").touch()
+        Path("prime/old").touch()
+        Path("prime/link").symlink_to("old")
+        os.utime("prime/old", (500, 500))
+
+        reproducible.normalize_files(
+            Path("prime"), paths={"dir", "dir/new", "old", "link"}, epoch=1000
+        )
+
+        assert os.lstat("prime/dir").st_mtime == 1000
+        assert os.lstat("prime/dir/new").st_mtime == 1000
+        assert os.lstat("prime/link").st_mtime == 1000
+        assert os.lstat("prime/old").st_mtime == 500
+
+    def test_no_epoch(self):
+        Path("prime").mkdir()
+        Path("prime/new").touch()
+        mtime = os.lstat("prime/new").st_mtime
+
+        reproducible.normalize_files(Path("prime"), paths={"new"}, epoch=None)
+
+        assert os.lstat("prime/new").st_mtime == mtime
+
+    def test_missing_file(self):
+        Path("prime").mkdir()
+
+        reproducible.normalize_files(Path("prime"), paths={"missing"}, epoch=1000)
+
+    def test_remove_xattrs(self, mocker):
+        Path("prime").mkdir()
+        Path("prime/file").touch()
+        mocker.patch("os.listxattr", return_value=["user.origin"])
+        mock_remove = mocker.patch("os.removexattr")
+
+        reproducible.normalize_files(Path("prime"), paths={"file"}, epoch=None)
+
+        mock_remove.assert_called_once_with(
+            "prime/file", "user.origin", follow_symlinks=False
+        )
+
+    def test_xattrs_unsupported(self, mocker):
+        Path("prime").mkdir()
+        Path("prime/file").touch()
+        mocker.patch("os.listxattr", side_effect=OSError("not supported"))
+        mock_remove = mocker.patch("os.removexattr")
+
+        reproducible.normalize_files(Path("prime"), paths={"file"}, epoch=1000)
+
+        mock_remove.assert_not_called()
+        assert os.lstat("prime/file").st_mtime == 1000
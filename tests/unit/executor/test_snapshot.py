@@ -0,0 +1,112 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Set
+
+import pytest
+
+from craft_parts import bases, plugins
+from craft_parts.executor import snapshot
+from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
+from craft_parts.parts import Part
+from craft_parts.steps import Step
+
+
+class FooPlugin(plugins.Plugin):
+    """A test plugin with toolchain version commands."""
+
+    properties_class = plugins.PluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        return {"CC": "foo-gcc", "FOO_UNRELATED": "bar"}
+
+    def get_toolchain_version_commands(self) -> Dict[str, str]:
+        return {
+            "cc": 'echo "$CC 1.2.3"; echo "Copyright"',
+            "missing": "not-a-command --version",
+            "failing": "echo 4.5.6; false",
+        }
+
+    def get_build_commands(self) -> List[str]:
+        return []
+
+
+def _get_snapshot(
+    *,
+    build_packages: List[str],
+    base_layer_dir: Optional[Path] = None,
+    base: Optional[str] = None,
+) -> Dict[str, Any]:
+    part = Part("p1", {"build-environment": [{"CFLAGS": "-O2"}]})
+    info = ProjectInfo(arch="x86_64", base_layer_dir=base_layer_dir, base=base)
+    part_info = PartInfo(project_info=info, part=part)
+    step_info = StepInfo(part_info=part_info, step=Step.BUILD)
+    plugin = FooPlugin(properties=plugins.PluginProperties(), part_info=part_info)
+
+    return snapshot.get_build_environment_snapshot(
+        part, plugin=plugin, step_info=step_info, build_packages=build_packages
+    )
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestBuildEnvironmentSnapshot:
+    """Verify the build environment snapshot contents."""
+
+    @pytest.fixture(autouse=True)
+    def setup_host(self, mocker, monkeypatch):
+        mocker.patch("platform.machine", return_value="x86_64")
+        mocker.patch("craft_parts.bases.get_host_base", return_value=None)
+        monkeypatch.setenv("LANG", "C.UTF-8")
+        monkeypatch.setenv("PATH", "/usr/bin:/bin")
+
+    def test_snapshot(self):
+        data = _get_snapshot(build_packages=["make=4.3-4", "gcc=4:11.2.0-1"])
+
+        assert data["host-arch"] == "amd64"
+        assert data["target-arch"] == "amd64"
+        assert data["base"] is None
+        assert data["base-layer"] is None
+        assert data["base-layer-digest"] is None
+        assert data["toolchain"] == {"cc": "foo-gcc 1.2.3"}
+        assert data["build-packages"] == {"gcc": "4:11.2.0-1", "make": "4.3-4"}
+
+        env = data["environment"]
+        assert env["CC"] == "foo-gcc"
+        assert env["CFLAGS"] == "-O2"
+        assert env["LANG"] == "C.UTF-8"
+        assert "FOO_UNRELATED" not in env
+        assert "CRAFT_PART_NAME" not in env
+        assert list(env) == sorted(env)
+
+    def test_snapshot_base(self, new_dir):
+        digest = "sha256:" + "a" * 64
+        base_layer_dir = Path(new_dir, "overlay-base/sha256", "a" * 64, "rootfs")
+        data = _get_snapshot(
+            build_packages=[], base_layer_dir=base_layer_dir, base="ubuntu@22.04"
+        )
+
+        assert data["base"] == bases.Base("ubuntu", "22.04").name
+        assert data["base-layer"] == str(base_layer_dir)
+        assert data["base-layer-digest"] == digest
+        assert data["build-packages"] == {}
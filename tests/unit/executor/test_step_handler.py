@@ -16,18 +16,21 @@
 
 import os
 import stat
+import subprocess
+import sys
 from pathlib import Path
-from typing import Dict, List, Set
+from typing import Any, Dict, List, Optional, Set, Type
 
 import pytest
 
-from craft_parts import plugins, sources
+from craft_parts import ctl, errors, plugins, sources
 from craft_parts.dirs import ProjectDirs
 from craft_parts.executor import filesets, step_handler
 from craft_parts.executor.filesets import Fileset
 from craft_parts.executor.step_handler import FilesAndDirs, StepHandler
 from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
 from craft_parts.parts import Part
+from craft_parts.plugins.go_plugin import GoPluginProperties
 from craft_parts.steps import Step
 
 
@@ -49,14 +52,40 @@ class FooPlugin(plugins.Plugin):
         return ["hello"]
 
 
-def _step_handler_for_step(step: Step) -> StepHandler:
-    p1 = Part("p1", {"source": "."})
+class FooPullPlugin(FooPlugin):
+    """A test plugin with pull commands."""
+
+    def get_pull_commands(self) -> List[str]:
+        return ["fetch"]
+
+
+_CRAFTCTL = f"{sys.executable} {ctl.__file__}"
+
+
+def _step_handler_for_step(
+    step: Step,
+    *,
+    plugin_class: Type[plugins.Plugin] = FooPlugin,
+    part_data: Optional[Dict[str, Any]] = None,
+    timeout: Optional[float] = None,
+    secrets: Optional[Dict[str, str]] = None,
+    base_layer_dir: Optional[Path] = None,
+    stage_excludes: Optional[Set[str]] = None,
+    arch: str = "",
+    emulation: bool = False,
+) -> StepHandler:
+    p1 = Part("p1", part_data or {"source": "."})
     dirs = ProjectDirs()
-    info = ProjectInfo(project_dirs=dirs)
+    info = ProjectInfo(
+        arch=arch,
+        project_dirs=dirs,
+        base_layer_dir=base_layer_dir,
+        emulation=emulation,
+    )
     part_info = PartInfo(project_info=info, part=p1)
     step_info = StepInfo(part_info=part_info, step=step)
     props = plugins.PluginProperties()
-    plugin = FooPlugin(properties=props, part_info=part_info)
+    plugin = plugin_class(properties=props, part_info=part_info)
     source_handler = sources.get_source_handler(
         application_name="test",
         part=p1,
@@ -68,6 +97,9 @@ def _step_handler_for_step(step: Step) -> StepHandler:
         step_info=step_info,
         plugin=plugin,
         source_handler=source_handler,
+        timeout=timeout,
+        secrets=secrets,
+        stage_excludes=stage_excludes,
     )
 
 
@@ -86,6 +118,84 @@ class TestStepHandlerBuiltins:
         mock_source_pull.assert_called_once_with()
         assert result == (set(), set())
 
+    def test_run_builtin_pull_commands(self, new_dir, mocker):
+        mock_source_pull = mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.pull"
+        )
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(Step.PULL, plugin_class=FooPullPlugin)
+        result = sh.run_builtin()
+
+        mock_source_pull.assert_called_once_with()
+        mock_run.assert_called_once_with(
+            [Path(new_dir / "parts/p1/run/pull.sh")],
+            check=True,
+            cwd=Path(new_dir / "parts/p1/src"),
+            env=None,
+            stdout=None,
+            stderr=None,
+            timeout=None,
+        )
+        assert Path("parts/p1/run/pull.sh").read_text().endswith("fetch\n")
+        assert result == (set(), set())
+
+    def test_run_builtin_pull_patches(self, mocker):
+        mock_source_pull = mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.pull"
+        )
+        mock_apply = mocker.patch("craft_parts.sources.patches.apply_patches")
+
+        sh = _step_handler_for_step(
+            Step.PULL,
+            part_data={
+                "source": ".",
+                "source-patches": ["patches"],
+                "source-patches-strip": 2,
+            },
+        )
+        sh.run_builtin()
+
+        mock_source_pull.assert_called_once_with()
+        mock_apply.assert_called_once_with(
+            ["patches"], src_dir=Path("parts/p1/src").absolute(), strip=2
+        )
+
+    def test_update_pull(self, mocker):
+        mock_source_update = mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.update"
+        )
+        mock_run = mocker.patch("subprocess.run")
+
+        sh = _step_handler_for_step(Step.PULL)
+        sh.update_pull()
+
+        mock_source_update.assert_called_once_with()
+        mock_run.assert_not_called()
+
+    def test_update_pull_commands(self, new_dir, mocker):
+        mock_source_update = mocker.patch(
+            "craft_parts.sources.local_source.LocalSource.update"
+        )
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(Step.PULL, plugin_class=FooPullPlugin)
+        sh.update_pull()
+
+        mock_source_update.assert_called_once_with()
+        mock_run.assert_called_once_with(
+            [Path(new_dir / "parts/p1/run/pull.sh")],
+            check=True,
+            cwd=Path(new_dir / "parts/p1/src"),
+            env=None,
+            stdout=None,
+            stderr=None,
+            timeout=None,
+        )
+        assert Path("parts/p1/run/pull.sh").read_text().endswith("fetch\n")
+
     def test_run_builtin_build(self, new_dir, mocker):
         mock_run = mocker.patch("subprocess.run")
 
@@ -97,9 +207,237 @@ class TestStepHandlerBuiltins:
             [Path(new_dir / "parts/p1/run/build.sh")],
             check=True,
             cwd=Path(new_dir / "parts/p1/build"),
+            env=None,
+            stdout=None,
+            stderr=None,
+            timeout=None,
         )
         assert result == (set(), set())
 
+    def test_run_builtin_build_windows(self, new_dir, mocker):
+        mocker.patch("sys.platform", "win32")
+        mocker.patch("shutil.which", return_value="C:/Git/bin/sh.exe")
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(Step.BUILD)
+        sh.run_builtin()
+
+        assert mock_run.call_args[0][0] == [
+            "C:/Git/bin/sh.exe",
+            Path(new_dir / "parts/p1/run/build.sh"),
+        ]
+
+    def test_run_builtin_build_secrets(self, new_dir, mocker):
+        mocker.patch.dict("os.environ", {"HOST_VAR": "value"}, clear=True)
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.BUILD,
+            part_data={
+                "source": ".",
+                "build-environment": [{"TOKEN": "$(HOST_SECRET:token)"}],
+            },
+            secrets={"token": "s3cr3t"},
+        )
+        sh.run_builtin()
+
+        env = mock_run.call_args[1]["env"]
+        assert env == {"HOST_VAR": "value", "CRAFT_SECRET_TOKEN": "s3cr3t"}
+        script = Path("parts/p1/run/build.sh").read_text()
+        assert 'export TOKEN="${CRAFT_SECRET_TOKEN}"' in script
+        assert "s3cr3t" not in script
+
+    def test_run_builtin_plugin_secrets(self, new_dir, mocker):
+        mocker.patch.dict("os.environ", {"HOST_VAR": "value"}, clear=True)
+        mocker.patch("craft_parts.sources.local_source.LocalSource.pull")
+        mock_run = mocker.patch("subprocess.run")
+
+        proxy = "https://user:p@ss@proxy.example.com,direct"
+        part_data = {"go-proxy": proxy, "go-vendor": True}
+        p1 = Part(
+            "p1",
+            {"source": ".", "plugin": "go"},
+            plugin_properties=GoPluginProperties.unmarshal(part_data),
+        )
+        part_info = PartInfo(project_info=ProjectInfo(), part=p1)
+        plugin = plugins.get_plugin(
+            part=p1, part_info=part_info, properties=p1.plugin_properties
+        )
+        Path("parts/p1/run").mkdir(parents=True)
+
+        for step in [Step.PULL, Step.BUILD]:
+            sh = StepHandler(
+                part=p1,
+                step_info=StepInfo(part_info=part_info, step=step),
+                plugin=plugin,
+                source_handler=None,
+                secrets=plugin.get_secrets(),
+            )
+            sh.run_builtin()
+
+            env = mock_run.call_args[1]["env"]
+            assert env == {"HOST_VAR": "value", "CRAFT_SECRET_GO_PROXY": proxy}
+
+        for script_name in ["pull.sh", "build.sh"]:
+            script = Path("parts/p1/run", script_name).read_text()
+            assert 'export GOPROXY="${CRAFT_SECRET_GO_PROXY}"' in script
+            assert "p@ss" not in script
+            assert "proxy.example.com" not in script
+
+    def test_run_builtin_build_timeout(self, new_dir, mocker):
+        mock_run = mocker.patch(
+            "subprocess.run", side_effect=subprocess.TimeoutExpired("build.sh", 10)
+        )
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(Step.BUILD, timeout=10)
+        with pytest.raises(errors.StepTimeoutError) as raised:
+            sh.run_builtin()
+        assert raised.value.part_name == "p1"
+        assert raised.value.step_name == "build"
+        assert raised.value.timeout == 10
+
+        timeout = mock_run.call_args[1]["timeout"]
+        assert 0 < timeout <= 10
+
+    def test_run_builtin_build_no_network(self, new_dir, mocker):
+        mocker.patch(
+            "craft_parts.utils.os_utils.get_network_isolation_prefix",
+            return_value=("unshare", "--net"),
+        )
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.BUILD, part_data={"source": ".", "build-network": False}
+        )
+        sh.run_builtin()
+
+        mock_run.assert_called_once_with(
+            ["unshare", "--net", Path(new_dir / "parts/p1/run/build.sh")],
+            check=True,
+            cwd=Path(new_dir / "parts/p1/build"),
+            env=None,
+            stdout=None,
+            stderr=None,
+            timeout=None,
+        )
+
+    def test_run_builtin_build_no_network_unsupported(self, mocker):
+        mocker.patch(
+            "craft_parts.utils.os_utils.get_network_isolation_prefix",
+            return_value=None,
+        )
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.BUILD, part_data={"source": ".", "build-network": False}
+        )
+        with pytest.raises(errors.NetworkIsolationError) as raised:
+            sh.run_builtin()
+        assert raised.value.part_name == "p1"
+        assert raised.value.step_name == "build"
+        mock_run.assert_not_called()
+
+    def test_run_builtin_build_isolated(self, new_dir, mocker):
+        mocker.patch("shutil.which", return_value="/usr/bin/bwrap")
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        Path("base/usr").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.BUILD,
+            part_data={"source": ".", "build-isolation": "bubblewrap"},
+            base_layer_dir=Path(new_dir, "base"),
+        )
+        sh.run_builtin()
+
+        mock_run.assert_called_once_with(
+            [
+                "bwrap",
+                "--ro-bind",
+                f"{new_dir}/base/usr",
+                "/usr",
+                "--dev",
+                "/dev",
+                "--proc",
+                "/proc",
+                "--bind",
+                str(new_dir),
+                str(new_dir),
+                "--chdir",
+                f"{new_dir}/parts/p1/build",
+                Path(new_dir / "parts/p1/run/build.sh"),
+            ],
+            check=True,
+            cwd=Path(new_dir / "parts/p1/build"),
+            env=None,
+            stdout=None,
+            stderr=None,
+            timeout=None,
+        )
+
+    def test_run_builtin_build_isolated_no_base_layer(self, mocker):
+        mock_run = mocker.patch("subprocess.run")
+
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.BUILD, part_data={"source": ".", "build-isolation": "chroot"}
+        )
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            sh.run_builtin()
+        assert raised.value.part_name == "p1"
+        mock_run.assert_not_called()
+
+    def test_run_builtin_build_emulated(self, mocker):
+        mocker.patch("platform.machine", return_value="aarch64")
+        mock_run = mocker.patch("subprocess.run")
+
+        # Emulated builds are isolated in the base layer by default.
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(Step.BUILD, arch="x86_64", emulation=True)
+        with pytest.raises(errors.BuildIsolationError) as raised:
+            sh.run_builtin()
+        assert raised.value.part_name == "p1"
+        mock_run.assert_not_called()
+
+    def test_run_builtin_pull_commands_not_isolated(self, new_dir, mocker):
+        mocker.patch("craft_parts.sources.local_source.LocalSource.pull")
+        mock_run = mocker.patch("subprocess.run")
+
+        # Build isolation doesn't apply to the pull step.
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.PULL,
+            plugin_class=FooPullPlugin,
+            part_data={"source": ".", "build-isolation": "chroot"},
+        )
+        sh.run_builtin()
+
+        assert mock_run.call_args[0][0] == [Path(new_dir / "parts/p1/run/pull.sh")]
+
+    def test_run_builtin_pull_commands_network(self, new_dir, mocker):
+        mocker.patch("craft_parts.sources.local_source.LocalSource.pull")
+        mock_prefix = mocker.patch(
+            "craft_parts.utils.os_utils.get_network_isolation_prefix"
+        )
+        mock_run = mocker.patch("subprocess.run")
+
+        # Build network settings don't apply to the pull step.
+        Path("parts/p1/run").mkdir(parents=True)
+        sh = _step_handler_for_step(
+            Step.PULL,
+            plugin_class=FooPullPlugin,
+            part_data={"source": ".", "build-network": False},
+        )
+        sh.run_builtin()
+
+        mock_prefix.assert_not_called()
+        assert mock_run.call_args[0][0] == [Path(new_dir / "parts/p1/run/pull.sh")]
+
     def test_run_builtin_stage(self, mocker):
         Path("parts/p1/install").mkdir(parents=True)
         Path("parts/p1/install/subdir").mkdir(parents=True)
@@ -111,6 +449,28 @@ class TestStepHandlerBuiltins:
 
         assert result == FilesAndDirs(files={"subdir/bar", "foo"}, dirs={"subdir"})
 
+    def test_run_builtin_stage_excludes(self):
+        Path("parts/p1/install/subdir").mkdir(parents=True)
+        Path("parts/p1/install/foo").write_text("content")
+        Path("parts/p1/install/subdir/bar").write_text("content")
+        Path("stage").mkdir()
+        Path("stage/foo").write_text("other content")
+        sh = _step_handler_for_step(Step.STAGE, stage_excludes={"foo"})
+        result = sh.run_builtin()
+
+        assert result == FilesAndDirs(files={"subdir/bar"}, dirs={"subdir"})
+        assert Path("stage/foo").read_text() == "other content"
+
+    def test_run_builtin_stage_replaces_symlink(self):
+        Path("parts/p1/install").mkdir(parents=True)
+        Path("parts/p1/install/foo").symlink_to("bar")
+        Path("stage").mkdir()
+        Path("stage/foo").symlink_to("baz")
+        sh = _step_handler_for_step(Step.STAGE)
+        sh.run_builtin()
+
+        assert os.readlink("stage/foo") == "bar"
+
     def test_run_builtin_prime(self, mocker):
         Path("parts/p1/install").mkdir(parents=True)
         Path("parts/p1/install/subdir").mkdir(parents=True)
@@ -143,7 +503,62 @@ class TestStepHandlerRunScriptlet:
         captured = capfd.readouterr()
         assert captured.out == "hello world\n"
 
-    # TODO: test ctl api server
+    def test_run_scriptlet_secrets(self, new_dir, capfd):
+        sh = _step_handler_for_step(
+            Step.PULL,
+            part_data={"build-environment": [{"TOKEN": "$(HOST_SECRET:token)"}]},
+            secrets={"token": "s3cr3t"},
+        )
+        sh.run_scriptlet('echo "$TOKEN"', scriptlet_name="name", work_dir=new_dir)
+        captured = capfd.readouterr()
+        assert captured.out == "s3cr3t\n"
+
+    def test_run_scriptlet_timeout(self, new_dir):
+        sh = _step_handler_for_step(Step.PULL, timeout=0.2)
+        with pytest.raises(errors.StepTimeoutError) as raised:
+            sh.run_scriptlet("sleep 10", scriptlet_name="name", work_dir=new_dir)
+        assert str(raised.value) == (
+            "Step 'pull' of part 'p1' timed out after 0.2 seconds.\n"
+            "Increase the step timeout or check for stalled commands."
+        )
+
+    def test_run_scriptlet_ctl_get_set(self, new_dir, capfd):
+        sh = _step_handler_for_step(Step.PULL)
+        sh._step_info.set_value("revision", "42")
+        sh.run_scriptlet(
+            f"{_CRAFTCTL} get revision\n{_CRAFTCTL} set version=1.0",
+            scriptlet_name="name",
+            work_dir=new_dir,
+        )
+        captured = capfd.readouterr()
+        assert captured.out == "42\n"
+        assert sh._step_info.run_values == {"revision": "42", "version": "1.0"}
+
+    @pytest.mark.parametrize(
+        "args,message",
+        [
+            ("get other", "'other' is not defined"),
+            ("set other", "invalid assignment 'other'"),
+            ("set other.key=1", "invalid name 'other.key'"),
+            ("get", "'get' requires exactly one argument"),
+        ],
+    )
+    def test_run_scriptlet_ctl_value_error(self, new_dir, args, message):
+        sh = _step_handler_for_step(Step.PULL)
+        with pytest.raises(errors.InvalidControlAPICall) as raised:
+            sh.run_scriptlet(
+                f"{_CRAFTCTL} {args}", scriptlet_name="name", work_dir=new_dir
+            )
+        assert raised.value.part_name == "p1"
+        assert raised.value.message == message
+
+    def test_run_scriptlet_unsupported_host(self, new_dir, mocker):
+        mocker.patch("sys.platform", "win32")
+        sh = _step_handler_for_step(Step.PULL)
+        with pytest.raises(errors.HostFeatureNotSupported) as raised:
+            sh.run_scriptlet("echo hello", scriptlet_name="name", work_dir=new_dir)
+        assert raised.value.feature == "scriptlets"
+        assert raised.value.host == "Windows"
 
 
 @pytest.mark.usefixtures("new_dir")
@@ -173,6 +588,29 @@ class TestFileMigration:
                 f.read() == "installed"
             ), "Expected staging to allow overwriting of already-staged files"
 
+    def test_migrate_files_many(self):
+        for i in range(100):
+            Path(f"install/dir{i % 10}").mkdir(parents=True, exist_ok=True)
+            Path(f"install/dir{i % 10}/file{i}").write_text(str(i))
+        os.makedirs("stage")
+
+        files, dirs = filesets.migratable_filesets(Fileset(["*"]), "install")
+        step_handler._migrate_files(
+            files=files, dirs=dirs, srcdir="install", destdir="stage"
+        )
+
+        for i in range(100):
+            assert Path(f"stage/dir{i % 10}/file{i}").read_text() == str(i)
+
+    def test_migrate_files_missing_source(self):
+        os.makedirs("install")
+        os.makedirs("stage")
+
+        with pytest.raises(errors.CopyFileNotFound):
+            step_handler._migrate_files(
+                files={"foo"}, dirs=set(), srcdir="install", destdir="stage"
+            )
+
     def test_migrate_files_supports_no_follow_symlinks(self):
         os.makedirs("install")
         os.makedirs("stage")
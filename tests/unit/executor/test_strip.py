@@ -0,0 +1,152 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import os
+import shutil
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts import errors
+from craft_parts.executor import strip
+
+_needs_toolchain = pytest.mark.skipif(
+    not (shutil.which("gcc") and shutil.which("strip")),
+    reason="a C toolchain is required",
+)
+
+
+def _compile(path: str) -> None:
+    Path("hello.c").write_text("int main() { return 0; }\n")
+    Path(path).parent.mkdir(parents=True, exist_ok=True)
+    subprocess.run(["gcc", "-g", "-Wl,--build-id", "-o", path, "hello.c"], check=True)
+
+
+def _has_debug_info(path: str) -> bool:
+    proc = subprocess.run(
+        ["readelf", "--sections", "--wide", path],
+        check=True,
+        capture_output=True,
+        text=True,
+    )
+    return ".debug_info" in proc.stdout
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestStripFiles:
+    """Verify stripping of primed binaries."""
+
+    @_needs_toolchain
+    def test_strip(self):
+        _compile("prime/bin/hello")
+        Path("prime/README").write_text("not a binary")
+        os.link("prime/bin/hello", "hello.orig")
+
+        stripped = strip.strip_files(
+            part_name="p1",
+            root=Path("prime"),
+            paths=["README", "bin/hello"],
+            exclude=[],
+        )
+
+        assert stripped == ["bin/hello"]
+        assert not _has_debug_info("prime/bin/hello")
+        assert os.access("prime/bin/hello", os.X_OK)
+        assert Path("prime/README").read_text() == "not a binary"
+
+        # The hard-linked file is not modified.
+        assert _has_debug_info("hello.orig")
+
+    @_needs_toolchain
+    def test_strip_exclude(self):
+        _compile("prime/bin/hello")
+        _compile("prime/lib/debug/hello")
+
+        stripped = strip.strip_files(
+            part_name="p1",
+            root=Path("prime"),
+            paths=["bin/hello", "lib/debug/hello"],
+            exclude=["lib/debug/*"],
+        )
+
+        assert stripped == ["bin/hello"]
+        assert _has_debug_info("prime/lib/debug/hello")
+
+    @_needs_toolchain
+    def test_strip_split_debug(self):
+        _compile("prime/bin/hello")
+
+        strip.strip_files(
+            part_name="p1",
+            root=Path("prime"),
+            paths=["bin/hello"],
+            exclude=[],
+            debug_dir=Path("debug").absolute(),
+        )
+
+        assert not _has_debug_info("prime/bin/hello")
+        assert _has_debug_info("debug/bin/hello.debug")
+
+        links = list(Path("debug/.build-id").glob("*/*.debug"))
+        assert len(links) == 1
+        assert links[0].resolve() == Path("debug/bin/hello.debug").resolve()
+
+        proc = subprocess.run(
+            ["readelf", "--string-dump=.gnu_debuglink", "prime/bin/hello"],
+            check=True,
+            capture_output=True,
+        )
+        assert b"hello.debug" in proc.stdout
+
+    def test_strip_symlink(self):
+        Path("prime").mkdir()
+        Path("prime/hello").symlink_to("/bin/true")
+
+        stripped = strip.strip_files(
+            part_name="p1", root=Path("prime"), paths=["hello"], exclude=[]
+        )
+
+        assert stripped == []
+
+    def test_strip_not_installed(self, mocker):
+        Path("prime").mkdir()
+        Path("prime/hello").write_bytes(b"\x7fELF")
+        mocker.patch("subprocess.run", side_effect=FileNotFoundError())
+
+        with pytest.raises(errors.StripError) as raised:
+            strip.strip_files(
+                part_name="p1", root=Path("prime"), paths=["hello"], exclude=[]
+            )
+
+        assert raised.value.part_name == "p1"
+        assert raised.value.path == "hello"
+        assert raised.value.message == "'strip' is not installed"
+
+    @_needs_toolchain
+    def test_strip_error(self):
+        Path("prime").mkdir()
+        Path("prime/hello").write_bytes(b"\x7fELF invalid")
+
+        with pytest.raises(errors.StripError) as raised:
+            strip.strip_files(
+                part_name="p1", root=Path("prime"), paths=["hello"], exclude=[]
+            )
+
+        assert raised.value.path == "hello"
+        assert Path("prime/hello").read_bytes() == b"\x7fELF invalid"
+        assert not Path("prime/.hello.partial").exists()
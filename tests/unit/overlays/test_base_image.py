@@ -0,0 +1,136 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import hashlib
+import io
+import json
+import tarfile
+from pathlib import Path
+
+import pytest
+
+from craft_parts.overlays import errors, get_base_image_digest, unpack_base_image
+from craft_parts.sources import errors as source_errors
+
+_PINNED_DIGEST = "sha256:" + "a" * 64
+
+
+def _add_blob(layout_dir: Path, data: bytes) -> str:
+    digest = hashlib.sha256(data).hexdigest()
+    blob_dir = layout_dir / "blobs" / "sha256"
+    blob_dir.mkdir(parents=True, exist_ok=True)
+    (blob_dir / digest).write_bytes(data)
+    return f"sha256:{digest}"
+
+
+def _make_layer(name: str, content: str) -> bytes:
+    data = io.BytesIO()
+    with tarfile.open(fileobj=data, mode="w:gz") as tar:
+        info = tarfile.TarInfo(name)
+        info.size = len(content)
+        info.mode = 0o644
+        tar.addfile(info, io.BytesIO(content.encode()))
+    return data.getvalue()
+
+
+@pytest.fixture
+def fake_skopeo(mocker):
+    """Make skopeo create an OCI layout containing a single layer."""
+    digests = {}
+
+    def fake_run(cmd, **_):
+        layout_dir = Path(cmd[-1].split(":")[1])
+        layer_digest = _add_blob(layout_dir, _make_layer("etc/os-release", "ID=fake"))
+        manifest = {"schemaVersion": 2, "layers": [{"digest": layer_digest}]}
+        digests["manifest"] = _add_blob(layout_dir, json.dumps(manifest).encode())
+        index = {
+            "schemaVersion": 2,
+            "manifests": [
+                {
+                    "digest": digests["manifest"],
+                    "annotations": {"org.opencontainers.image.ref.name": "craft-parts"},
+                }
+            ],
+        }
+        (layout_dir / "index.json").write_text(json.dumps(index))
+
+    mock_run = mocker.patch("subprocess.run", side_effect=fake_run)
+    # The manifest digest is deterministic, obtain it from a first run.
+    fake_run(["skopeo", f"oci:{Path('layout').absolute()}:craft-parts"])
+    mock_run.digest = digests["manifest"]
+    return mock_run
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestUnpackBaseImage:
+    """Verify unpacking of OCI images as overlay base layers."""
+
+    def test_unpack_pinned_reference(self, fake_skopeo):
+        rootfs = unpack_base_image(f"ubuntu@{_PINNED_DIGEST}", cache_dir=Path("cache"))
+
+        assert rootfs == Path("cache/overlay-base/sha256", "a" * 64, "rootfs")
+        assert (rootfs / "etc/os-release").read_text() == "ID=fake"
+        assert fake_skopeo.call_args[0][0][3] == f"docker://ubuntu@{_PINNED_DIGEST}"
+        assert list(rootfs.parent.iterdir()) == [rootfs]
+
+    def test_unpack_digest(self, fake_skopeo):
+        digest = fake_skopeo.digest
+        rootfs = unpack_base_image(
+            "ubuntu:22.04", cache_dir=Path("cache"), digest=digest
+        )
+
+        algorithm, _, encoded = digest.partition(":")
+        assert rootfs == Path("cache/overlay-base", algorithm, encoded, "rootfs")
+        assert (rootfs / "etc/os-release").read_text() == "ID=fake"
+
+    def test_unpack_digest_mismatch(self, fake_skopeo):
+        with pytest.raises(source_errors.ChecksumMismatch) as raised:
+            unpack_base_image(
+                "ubuntu:22.04", cache_dir=Path("cache"), digest=_PINNED_DIGEST
+            )
+        assert raised.value.expected == _PINNED_DIGEST
+
+        assert list(Path("cache/overlay-base/sha256").iterdir()) == []
+
+    def test_unpack_cached(self, fake_skopeo):
+        unpack_base_image(f"ubuntu@{_PINNED_DIGEST}", cache_dir=Path("cache"))
+        fake_skopeo.reset_mock()
+
+        rootfs = unpack_base_image(f"ubuntu@{_PINNED_DIGEST}", cache_dir=Path("cache"))
+
+        assert (rootfs / "etc/os-release").read_text() == "ID=fake"
+        fake_skopeo.assert_not_called()
+
+    @pytest.mark.parametrize("image", ["ubuntu:22.04", "ubuntu@latest"])
+    def test_unpack_not_pinned(self, image):
+        with pytest.raises(errors.OverlayBaseImageError) as raised:
+            unpack_base_image(image, cache_dir=Path("cache"))
+        assert raised.value.image == image
+        assert raised.value.message == "image must be pinned to a digest"
+
+
+@pytest.mark.parametrize(
+    "rootfs,digest",
+    [
+        (Path("cache/overlay-base/sha256", "a" * 64, "rootfs"), _PINNED_DIGEST),
+        (Path("cache/overlay-base/sha256", "a" * 64, "other"), None),
+        (Path("cache/other/sha256", "a" * 64, "rootfs"), None),
+        (Path("cache/overlay-base/sha256/invalid/rootfs"), None),
+        (Path("/base"), None),
+    ],
+)
+def test_get_base_image_digest(rootfs, digest):
+    assert get_base_image_digest(rootfs) == digest
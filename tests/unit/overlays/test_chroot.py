@@ -0,0 +1,127 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts import errors
+from craft_parts.infos import ProjectInfo
+from craft_parts.overlays import chroot
+
+
+@pytest.fixture
+def fake_chroot(mocker):
+    """Run chroot scripts in the host, relocating the control directory."""
+    real_popen = subprocess.Popen
+
+    def fake_popen(cmd, *, stdin, **kwargs):
+        assert cmd[0] == "chroot"
+        root = Path(cmd[1]).absolute()
+        script = stdin.read().replace("/.craftctl", f"{root}/.craftctl")
+        return real_popen([cmd[2], "-c", script], cwd=root, **kwargs)
+
+    return mocker.patch("subprocess.Popen", side_effect=fake_popen)
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestRunChrootScript:
+    """Verify script execution and control call brokering."""
+
+    def setup_method(self):
+        # pylint: disable=attribute-defined-outside-init
+        Path("root").mkdir()
+        self._info = ProjectInfo(project_vars={"version": "1.0", "grade": ""})
+        # pylint: enable=attribute-defined-outside-init
+
+    def test_run_script(self, fake_chroot):
+        chroot.run_chroot_script(
+            "echo $FOO > out",
+            root=Path("root"),
+            part_name="p1",
+            project_info=self._info,
+            env="export FOO=bar",
+        )
+
+        assert fake_chroot.call_args[0][0] == ["chroot", "root", "/bin/sh"]
+        assert Path("root/out").read_text() == "bar\n"
+        assert list(Path("root").iterdir()) == [Path("root/out")]
+
+    def test_craftctl_get_set(self, fake_chroot):
+        chroot.run_chroot_script(
+            "craftctl get version > out\ncraftctl set grade=stable",
+            root=Path("root"),
+            part_name="p1",
+            project_info=self._info,
+        )
+
+        assert Path("root/out").read_text() == "1.0\n"
+        assert self._info.project_vars == {"version": "1.0", "grade": "stable"}
+
+    def test_craftctl_run_values(self, fake_chroot):
+        self._info.set_value("revision", "42")
+        chroot.run_chroot_script(
+            "craftctl get revision > out\ncraftctl set arch-tag=amd64",
+            root=Path("root"),
+            part_name="p1",
+            project_info=self._info,
+        )
+
+        assert Path("root/out").read_text() == "42\n"
+        assert self._info.run_values == {"revision": "42", "arch-tag": "amd64"}
+        assert self._info.project_vars == {"version": "1.0", "grade": ""}
+
+    @pytest.mark.parametrize(
+        "script,message",
+        [
+            ("craftctl get other", "'other' is not defined"),
+            ("craftctl set other.key=1", "invalid name 'other.key'"),
+            ("craftctl set version", "invalid assignment 'version'"),
+        ],
+    )
+    def test_craftctl_error(self, fake_chroot, capfd, script, message):
+        with pytest.raises(errors.ScriptletRunError) as raised:
+            chroot.run_chroot_script(
+                script, root=Path("root"), part_name="p1", project_info=self._info
+            )
+
+        assert raised.value.part_name == "p1"
+        assert raised.value.scriptlet_name == "overlay-script"
+        assert raised.value.exit_code == 1
+        assert f"craftctl: {message}" in capfd.readouterr().err
+        assert self._info.project_vars == {"version": "1.0", "grade": ""}
+
+    def test_craftctl_usage(self, fake_chroot, capfd):
+        with pytest.raises(errors.ScriptletRunError) as raised:
+            chroot.run_chroot_script(
+                "craftctl default",
+                root=Path("root"),
+                part_name="p1",
+                project_info=self._info,
+            )
+
+        assert raised.value.exit_code == 2
+        assert "usage: craftctl get <name>" in capfd.readouterr().err
+
+    def test_script_error(self, fake_chroot):
+        with pytest.raises(errors.ScriptletRunError) as raised:
+            chroot.run_chroot_script(
+                "exit 42", root=Path("root"), part_name="p1", project_info=self._info
+            )
+
+        assert raised.value.exit_code == 42
+        assert list(Path("root").iterdir()) == []
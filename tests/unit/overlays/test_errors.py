@@ -0,0 +1,74 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from craft_parts.overlays import errors
+
+
+def test_invalid_overlay_backend():
+    err = errors.InvalidOverlayBackend("aufs")
+    assert err.backend == "aufs"
+    assert err.brief == "Invalid overlay backend 'aufs'."
+    assert err.details is None
+    assert err.resolution == "Valid overlay backends are 'kernel', 'fuse' and 'copy'."
+
+
+def test_overlay_base_image_error():
+    err = errors.OverlayBaseImageError("ubuntu:22.04", message="something is wrong")
+    assert err.image == "ubuntu:22.04"
+    assert err.message == "something is wrong"
+    assert err.brief == "Cannot use base image 'ubuntu:22.04': something is wrong."
+    assert err.details is None
+    assert err.resolution == "Use an image reference or archive pinned to a digest."
+
+
+def test_overlay_base_layer_not_set():
+    err = errors.OverlayBaseLayerNotSet("foo")
+    assert err.part_name == "foo"
+    assert err.brief == "Cannot create the overlay layer of part 'foo'."
+    assert err.details == "The overlay step requires a base layer."
+    assert err.resolution == "Set the base layer directory or the base image."
+
+
+def test_overlay_whiteout_conflict():
+    err = errors.OverlayWhiteoutConflict(
+        part_name="foo", other_part_name="bar", conflicting_files=["file2", "file1"]
+    )
+    assert err.part_name == "foo"
+    assert err.other_part_name == "bar"
+    assert err.conflicting_files == ["file2", "file1"]
+    assert err.brief == "Whiteouts in part 'foo' hide files provided by part 'bar'."
+    assert err.details == "Hidden files:\n    file1\n    file2"
+    assert err.resolution == (
+        "Exclude the conflicting whiteouts or files from the overlay."
+    )
+
+
+def test_overlay_mount_error():
+    err = errors.OverlayMountError("/mountpoint", message="something is wrong")
+    assert err.mountpoint == "/mountpoint"
+    assert err.message == "something is wrong"
+    assert err.brief == "Failed to mount overlay on /mountpoint: something is wrong."
+    assert err.details is None
+    assert err.resolution is None
+
+
+def test_overlay_unmount_error():
+    err = errors.OverlayUnmountError("/mountpoint", message="something is wrong")
+    assert err.mountpoint == "/mountpoint"
+    assert err.message == "something is wrong"
+    assert err.brief == "Failed to unmount /mountpoint: something is wrong."
+    assert err.details is None
+    assert err.resolution is None
@@ -0,0 +1,102 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import os
+from pathlib import Path
+
+import pytest
+
+from craft_parts.overlays import LayerCache, get_layer_key
+
+_BASE_DIGEST = "sha256:" + "a" * 64
+
+
+def _key(**kwargs) -> str:
+    args = {
+        "base_digest": _BASE_DIGEST,
+        "overlay_packages": ["hello", "curl"],
+        "overlay_script": "rm -rf /var/cache",
+        **kwargs,
+    }
+    return get_layer_key(**args)
+
+
+class TestGetLayerKey:
+    """Verify overlay layer key computation."""
+
+    def test_key_format(self):
+        algorithm, _, digest = _key().partition("/")
+        assert algorithm == "sha256"
+        assert len(digest) == 64
+
+    def test_package_order(self):
+        assert _key(overlay_packages=["curl", "hello"]) == _key()
+
+    @pytest.mark.parametrize(
+        "changes",
+        [
+            {"base_digest": "sha256:" + "b" * 64},
+            {"overlay_packages": ["hello"]},
+            {"overlay_script": None},
+            {"parent_key": "sha256/" + "c" * 64},
+        ],
+    )
+    def test_key_changes(self, changes):
+        assert _key(**changes) != _key()
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestLayerCache:
+    """Verify storing and restoring overlay layers."""
+
+    def setup_method(self):
+        Path("layer/etc").mkdir(parents=True)
+        Path("layer/etc/hello.conf").write_text("hello")
+        Path("layer/etc/.wh.motd").touch()
+        Path("layer/hello").symlink_to("/etc/hello.conf")
+
+    def test_save_restore(self):
+        cache = LayerCache("test", cache_dir=Path("cache"))
+        assert cache.save(key=_key(), layer_dir=Path("layer")) is True
+        assert Path("cache/overlay-layers", _key()).is_file()
+
+        Path("restored/stale").mkdir(parents=True)
+        assert cache.restore(key=_key(), layer_dir=Path("restored")) is True
+
+        assert sorted(str(p) for p in Path("restored").rglob("*")) == [
+            "restored/etc",
+            "restored/etc/.wh.motd",
+            "restored/etc/hello.conf",
+            "restored/hello",
+        ]
+        assert Path("restored/etc/hello.conf").read_text() == "hello"
+        assert os.readlink("restored/hello") == "/etc/hello.conf"
+
+    def test_restore_missing(self):
+        cache = LayerCache("test", cache_dir=Path("cache"))
+        assert cache.restore(key=_key(), layer_dir=Path("restored")) is False
+        assert Path("restored").exists() is False
+
+    def test_shared_cache(self):
+        LayerCache("test", cache_dir=Path("cache")).save(
+            key=_key(), layer_dir=Path("layer")
+        )
+
+        cache = LayerCache("test", cache_dir=Path("cache"))
+        assert cache.restore(key=_key(), layer_dir=Path("restored")) is True
+        assert (
+            cache.restore(key=_key(overlay_script=None), layer_dir=Path("x")) is False
+        )
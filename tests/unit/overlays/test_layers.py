@@ -0,0 +1,115 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts.executor.filesets import Fileset
+from craft_parts.overlays import check_whiteout_conflicts, errors, layers
+
+
+@pytest.mark.parametrize(
+    "path,target",
+    [
+        ("usr/bin/.wh.foo", "usr/bin/foo"),
+        (".wh.foo", "foo"),
+        ("usr/share/doc/.wh..wh..opq", "usr/share/doc"),
+        ("usr/bin/foo", None),
+    ],
+)
+def test_get_whiteout_target(path, target):
+    assert layers.get_whiteout_target(path) == target
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestMigratableOverlayFiles:
+    """Verify whiteout handling when selecting layer files to migrate."""
+
+    def setup_method(self):
+        Path("layer/usr/bin").mkdir(parents=True)
+        Path("layer/usr/share/doc").mkdir(parents=True)
+        Path("layer/usr/bin/foo").write_text("foo")
+        Path("layer/usr/bin/.wh.bar").touch()
+        Path("layer/usr/share/doc/.wh..wh..opq").touch()
+        Path("layer/.wh.etc").touch()
+
+    def test_all_files(self):
+        files, dirs = layers.migratable_overlay_files(Fileset(["*"]), "layer")
+        assert files == {
+            ".wh.etc",
+            "usr/bin/foo",
+            "usr/bin/.wh.bar",
+            "usr/share/doc/.wh..wh..opq",
+        }
+        assert dirs == {"usr", "usr/bin", "usr/share", "usr/share/doc"}
+
+    def test_exclude_hidden_path(self):
+        fileset = Fileset(["-usr/bin/bar", "-etc"])
+        files, dirs = layers.migratable_overlay_files(fileset, "layer")
+        assert files == {"usr/bin/foo", "usr/share/doc/.wh..wh..opq"}
+        assert dirs == {"usr", "usr/bin", "usr/share", "usr/share/doc"}
+
+    def test_exclude_opaque_directory(self):
+        fileset = Fileset(["-usr/share"])
+        files, dirs = layers.migratable_overlay_files(fileset, "layer")
+        assert files == {".wh.etc", "usr/bin/foo", "usr/bin/.wh.bar"}
+        assert dirs == {"usr", "usr/bin"}
+
+    def test_include_hidden_paths(self):
+        fileset = Fileset(["usr/bin/*", "usr/**/doc"])
+        files, dirs = layers.migratable_overlay_files(fileset, "layer")
+        assert files == {
+            "usr/bin/foo",
+            "usr/bin/.wh.bar",
+            "usr/share/doc/.wh..wh..opq",
+        }
+        assert dirs == {"usr", "usr/bin", "usr/share", "usr/share/doc"}
+
+
+class TestCheckWhiteoutConflicts:
+    """Verify detection of whiteouts hiding files from other parts."""
+
+    def test_no_conflicts(self):
+        check_whiteout_conflicts(
+            {
+                "p1": {"usr", "usr/bin", "usr/bin/.wh.foo"},
+                "p2": {"usr", "usr/bin", "usr/bin/bar"},
+            }
+        )
+
+    def test_whiteout_conflict(self):
+        with pytest.raises(errors.OverlayWhiteoutConflict) as raised:
+            check_whiteout_conflicts(
+                {
+                    "p1": {"usr", "usr/bin", "usr/bin/foo"},
+                    "p2": {".wh.usr"},
+                }
+            )
+        assert raised.value.part_name == "p2"
+        assert raised.value.other_part_name == "p1"
+        assert raised.value.conflicting_files == ["usr", "usr/bin", "usr/bin/foo"]
+
+    def test_opaque_conflict(self):
+        with pytest.raises(errors.OverlayWhiteoutConflict) as raised:
+            check_whiteout_conflicts(
+                {
+                    "p1": {"usr", "usr/share", "usr/share/doc", "usr/share/doc/a"},
+                    "p2": {"usr", "usr/share", "usr/share/.wh..wh..opq"},
+                }
+            )
+        assert raised.value.part_name == "p2"
+        assert raised.value.conflicting_files == ["usr/share/doc", "usr/share/doc/a"]
@@ -0,0 +1,251 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+from unittest.mock import call
+
+import pytest
+
+from craft_parts.overlays import OverlayBackend, OverlayFS, errors, overlay_fs
+
+_OPTIONS = "lowerdir=lower1:lower2,upperdir=upper,workdir=work"
+
+
+@pytest.fixture
+def overlay(new_dir):
+    return OverlayFS(
+        lower_dirs=[Path("lower1"), Path("lower2")],
+        upper_dir=Path("upper"),
+        work_dir=Path("work"),
+    )
+
+
+class TestGetOverlayBackends:
+    """Verify overlay backend selection."""
+
+    @pytest.mark.parametrize("backend", ["kernel", "fuse", "copy"])
+    def test_backend_override(self, backend):
+        env = {"CRAFT_PARTS_OVERLAY_BACKEND": backend}
+        assert overlay_fs.get_overlay_backends(env) == [OverlayBackend(backend)]
+
+    def test_backend_override_invalid(self):
+        env = {"CRAFT_PARTS_OVERLAY_BACKEND": "aufs"}
+        with pytest.raises(errors.InvalidOverlayBackend) as raised:
+            overlay_fs.get_overlay_backends(env)
+        assert raised.value.backend == "aufs"
+
+    @pytest.mark.parametrize(
+        "euid,kernel,fuse,backends",
+        [
+            (0, True, True, ["kernel", "fuse", "copy"]),
+            (0, False, True, ["fuse", "copy"]),
+            (1000, True, True, ["fuse", "copy"]),
+            (1000, True, False, ["copy"]),
+        ],
+    )
+    def test_backend_auto(self, mocker, euid, kernel, fuse, backends):
+        mocker.patch("os.geteuid", return_value=euid)
+        mocker.patch(
+            "craft_parts.overlays.overlay_fs._is_kernel_overlay_supported",
+            return_value=kernel,
+        )
+        mocker.patch("shutil.which", return_value="/usr/bin/fuse-overlayfs")
+        mocker.patch("os.path.exists", return_value=fuse)
+
+        assert overlay_fs.get_overlay_backends({}) == [
+            OverlayBackend(name) for name in backends
+        ]
+
+    def test_backend_auto_non_linux(self, mocker):
+        mocker.patch("sys.platform", "darwin")
+        mocker.patch("shutil.which", return_value="/usr/bin/fuse-overlayfs")
+
+        assert overlay_fs.get_overlay_backends({}) == [OverlayBackend.COPY]
+
+
+class TestOverlayFSMount:
+    """Verify overlay mounts using kernel and fuse overlayfs."""
+
+    def test_mount_kernel(self, mocker, overlay):
+        mock_run = mocker.patch("subprocess.run")
+        mocker.patch(
+            "craft_parts.overlays.overlay_fs.get_overlay_backends",
+            return_value=[OverlayBackend.KERNEL, OverlayBackend.COPY],
+        )
+
+        overlay.mount(Path("mountpoint"))
+        assert overlay.backend == OverlayBackend.KERNEL
+        overlay.unmount()
+        assert overlay.backend is None
+
+        assert mock_run.mock_calls == [
+            call(
+                ["mount", "-t", "overlay", "overlay", f"-o{_OPTIONS}", "mountpoint"],
+                check=True,
+            ),
+            call(["umount", "mountpoint"], check=True),
+        ]
+
+    def test_mount_fuse(self, mocker, new_dir):
+        mock_run = mocker.patch("subprocess.run")
+        mocker.patch("shutil.which", return_value=None)
+        overlay = OverlayFS(
+            lower_dirs=[Path("lower1"), Path("lower2")],
+            upper_dir=Path("upper"),
+            work_dir=Path("work"),
+            backend=OverlayBackend.FUSE,
+        )
+
+        overlay.mount(Path("mountpoint"))
+        assert overlay.backend == OverlayBackend.FUSE
+        overlay.unmount()
+
+        assert mock_run.mock_calls == [
+            call(["fuse-overlayfs", "-o", _OPTIONS, "mountpoint"], check=True),
+            call(["fusermount", "-u", "mountpoint"], check=True),
+        ]
+
+    def test_mount_fallback(self, mocker, overlay):
+        mock_run = mocker.patch(
+            "subprocess.run",
+            side_effect=[subprocess.CalledProcessError(32, ["mount"]), None],
+        )
+        mocker.patch(
+            "craft_parts.overlays.overlay_fs.get_overlay_backends",
+            return_value=[OverlayBackend.KERNEL, OverlayBackend.FUSE],
+        )
+
+        overlay.mount(Path("mountpoint"))
+        assert overlay.backend == OverlayBackend.FUSE
+        assert mock_run.call_count == 2
+
+    def test_mount_error(self, mocker, overlay):
+        mocker.patch(
+            "subprocess.run", side_effect=subprocess.CalledProcessError(32, ["mount"])
+        )
+        mocker.patch(
+            "craft_parts.overlays.overlay_fs.get_overlay_backends",
+            return_value=[OverlayBackend.KERNEL],
+        )
+
+        with pytest.raises(errors.OverlayMountError) as raised:
+            overlay.mount(Path("mountpoint"))
+        assert raised.value.mountpoint == "mountpoint"
+        assert overlay.backend is None
+
+    def test_mount_already_mounted(self, mocker, overlay):
+        mocker.patch("subprocess.run")
+        overlay.mount(Path("mountpoint"))
+
+        with pytest.raises(errors.OverlayMountError) as raised:
+            overlay.mount(Path("other"))
+        assert raised.value.message == "already mounted on mountpoint"
+
+    def test_unmount_not_mounted(self, overlay):
+        with pytest.raises(errors.OverlayUnmountError) as raised:
+            overlay.unmount()
+        assert raised.value.message == "not mounted"
+
+    def test_unmount_error(self, mocker, overlay):
+        mocker.patch(
+            "subprocess.run",
+            side_effect=[None, subprocess.CalledProcessError(32, ["umount"])],
+        )
+        mocker.patch(
+            "craft_parts.overlays.overlay_fs.get_overlay_backends",
+            return_value=[OverlayBackend.KERNEL],
+        )
+        overlay.mount(Path("mountpoint"))
+
+        with pytest.raises(errors.OverlayUnmountError) as raised:
+            overlay.unmount()
+        assert raised.value.mountpoint == "mountpoint"
+
+
+class TestOverlayFSCopy:
+    """Verify the overlay emulation using copies."""
+
+    @pytest.fixture
+    def copy_overlay(self, new_dir):
+        for name in ("lower1/dir", "lower2/dir", "lower2/other"):
+            Path(name).mkdir(parents=True)
+        Path("lower1/dir/file1").write_text("lower1")
+        Path("lower2/dir/file1").write_text("lower2")
+        Path("lower2/dir/file2").write_text("lower2")
+        Path("lower2/other/file3").write_text("lower2")
+        Path("lower2/link").symlink_to("dir")
+
+        return OverlayFS(
+            lower_dirs=[Path("lower1"), Path("lower2")],
+            upper_dir=Path("upper"),
+            work_dir=Path("work"),
+            backend=OverlayBackend.COPY,
+        )
+
+    def test_mount(self, copy_overlay):
+        copy_overlay.mount(Path("mountpoint"))
+
+        assert Path("mountpoint/dir/file1").read_text() == "lower1"
+        assert Path("mountpoint/dir/file2").read_text() == "lower2"
+        assert Path("mountpoint/other/file3").read_text() == "lower2"
+        assert Path("mountpoint/link").is_symlink()
+
+    def test_unmount_changes(self, copy_overlay):
+        copy_overlay.mount(Path("mountpoint"))
+
+        Path("mountpoint/dir/file2").write_text("changed")
+        Path("mountpoint/dir/file4").write_text("new")
+        Path("mountpoint/dir/file1").unlink()
+        Path("mountpoint/other/file3").unlink()
+        Path("mountpoint/other").rmdir()
+
+        copy_overlay.unmount()
+
+        # the lower layers are not modified
+        assert Path("lower1/dir/file1").read_text() == "lower1"
+        assert Path("lower2/dir/file2").read_text() == "lower2"
+        assert Path("lower2/other/file3").read_text() == "lower2"
+
+        assert list(Path("mountpoint").iterdir()) == []
+        assert sorted(str(p) for p in Path("upper").rglob("*")) == [
+            "upper/.wh.other",
+            "upper/dir",
+            "upper/dir/.wh.file1",
+            "upper/dir/file2",
+            "upper/dir/file4",
+        ]
+        assert Path("upper/dir/file2").read_text() == "changed"
+
+    def test_remount(self, copy_overlay):
+        copy_overlay.mount(Path("mountpoint"))
+        Path("mountpoint/dir/file2").write_text("changed")
+        Path("mountpoint/dir/file1").unlink()
+        copy_overlay.unmount()
+
+        copy_overlay.mount(Path("mountpoint"))
+
+        assert Path("mountpoint/dir/file1").exists() is False
+        assert Path("mountpoint/dir/file2").read_text() == "changed"
+        assert Path("mountpoint/other/file3").read_text() == "lower2"
+
+        copy_overlay.unmount()
+
+        assert sorted(str(p) for p in Path("upper").rglob("*")) == [
+            "upper/dir",
+            "upper/dir/.wh.file1",
+            "upper/dir/file2",
+        ]
@@ -0,0 +1,159 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts.overlays import OverlayManager, errors, get_layer_key
+from craft_parts.parts import Part
+
+_BASE_DIGEST = "sha256:" + "a" * 64
+
+
+@pytest.fixture
+def base_layer_dir(new_dir):
+    rootfs = Path("cache/overlay-base/sha256", "a" * 64, "rootfs")
+    (rootfs / "etc").mkdir(parents=True)
+    (rootfs / "etc/os-release").write_text("base")
+    return rootfs
+
+
+@pytest.fixture
+def part_list():
+    return [
+        Part("p1", {"overlay-packages": ["hello"]}),
+        Part("p2", {"after": ["p1"], "overlay-script": "rm /etc/os-release"}),
+    ]
+
+
+class TestLayerKey:
+    """Verify the computation of layer keys."""
+
+    def test_layer_key(self, base_layer_dir, part_list):
+        manager = OverlayManager(part_list=part_list, base_layer_dir=base_layer_dir)
+
+        p1_key = get_layer_key(
+            base_digest=_BASE_DIGEST, overlay_packages=["hello"], overlay_script=None
+        )
+        p2_key = get_layer_key(
+            base_digest=_BASE_DIGEST,
+            overlay_packages=[],
+            overlay_script="rm /etc/os-release",
+            parent_key=p1_key,
+        )
+        assert manager.get_layer_key(part_list[0]) == p1_key
+        assert manager.get_layer_key(part_list[1]) == p2_key
+
+    def test_layer_key_no_base_layer(self, part_list):
+        manager = OverlayManager(part_list=part_list, base_layer_dir=None)
+        assert manager.get_layer_key(part_list[0]) is None
+
+    @pytest.mark.usefixtures("new_dir")
+    def test_layer_key_no_base_digest(self, part_list):
+        Path("rootfs").mkdir()
+        manager = OverlayManager(part_list=part_list, base_layer_dir=Path("rootfs"))
+        assert manager.get_layer_key(part_list[0]) is None
+
+
+class TestMountLayer:
+    """Verify mounting the overlay of each part."""
+
+    @pytest.fixture(autouse=True)
+    def copy_backend(self, monkeypatch):
+        monkeypatch.setenv("CRAFT_PARTS_OVERLAY_BACKEND", "copy")
+
+    def test_mount_layer(self, base_layer_dir, part_list):
+        manager = OverlayManager(part_list=part_list, base_layer_dir=base_layer_dir)
+        p1, p2 = part_list
+
+        with manager.mount_layer(p1) as mountpoint:
+            assert Path(mountpoint, "etc/os-release").read_text() == "base"
+            Path(mountpoint, "etc/hello.conf").write_text("hello")
+
+        assert sorted(str(p) for p in Path("parts/p1/layer").rglob("*")) == [
+            "parts/p1/layer/etc",
+            "parts/p1/layer/etc/hello.conf",
+        ]
+
+        # the p2 layer is on top of the p1 layer
+        with manager.mount_layer(p2) as mountpoint:
+            assert Path(mountpoint, "etc/hello.conf").read_text() == "hello"
+            Path(mountpoint, "etc/os-release").unlink()
+
+        assert sorted(str(p) for p in Path("parts/p2/layer").rglob("*")) == [
+            "parts/p2/layer/etc",
+            "parts/p2/layer/etc/.wh.os-release",
+        ]
+
+        # work directories are removed
+        assert sorted(p.name for p in Path("parts/p2").iterdir()) == ["layer"]
+
+    def test_mount_layer_no_base_layer(self, new_dir, part_list):
+        manager = OverlayManager(part_list=part_list, base_layer_dir=None)
+
+        with pytest.raises(errors.OverlayBaseLayerNotSet) as raised:
+            with manager.mount_layer(part_list[0]):
+                pass
+        assert raised.value.part_name == "p1"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestOverlayFiles:
+    """Verify the selection of layer files to stage."""
+
+    def test_overlay_files(self):
+        p1 = Part("p1", {"overlay": ["-usr/share"]})
+        Path("parts/p1/layer/usr/share").mkdir(parents=True)
+        Path("parts/p1/layer/usr/share/doc").touch()
+        Path("parts/p1/layer/usr/bin").mkdir()
+        Path("parts/p1/layer/usr/bin/hello").touch()
+        Path("parts/p1/layer/usr/.wh.lib").touch()
+
+        manager = OverlayManager(part_list=[p1], base_layer_dir=None)
+        files, dirs = manager.get_overlay_files(p1)
+        assert files == {"usr/bin/hello", "usr/.wh.lib"}
+        assert dirs == {"usr", "usr/bin"}
+
+    def test_overlay_files_no_layer(self):
+        p1 = Part("p1", {})
+        manager = OverlayManager(part_list=[p1], base_layer_dir=None)
+        assert manager.get_overlay_files(p1) == (set(), set())
+
+    def test_check_conflicts(self):
+        p1 = Part("p1", {})
+        p2 = Part("p2", {})
+        Path("parts/p1/layer/etc").mkdir(parents=True)
+        Path("parts/p1/layer/etc/motd").touch()
+        Path("parts/p2/layer/etc").mkdir(parents=True)
+        Path("parts/p2/layer/etc/.wh.motd").touch()
+
+        manager = OverlayManager(part_list=[p1, p2], base_layer_dir=None)
+        with pytest.raises(errors.OverlayWhiteoutConflict) as raised:
+            manager.check_conflicts()
+        assert raised.value.part_name == "p2"
+        assert raised.value.other_part_name == "p1"
+
+    def test_check_conflicts_excluded(self):
+        p1 = Part("p1", {})
+        p2 = Part("p2", {"overlay": ["-etc/motd"]})
+        Path("parts/p1/layer/etc").mkdir(parents=True)
+        Path("parts/p1/layer/etc/motd").touch()
+        Path("parts/p2/layer/etc").mkdir(parents=True)
+        Path("parts/p2/layer/etc/.wh.motd").touch()
+
+        manager = OverlayManager(part_list=[p1, p2], base_layer_dir=None)
+        manager.check_conflicts()
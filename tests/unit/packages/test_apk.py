@@ -0,0 +1,252 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import apk, errors
+
+# pylint: disable=missing-class-docstring
+
+_STAGE_OPTIONS = ["--no-progress", "--cache-dir=/cache", "--arch=aarch64"]
+
+_INSTALLED = b"musl-1.2.2-r3\nbusybox-1.33.1-r3\nca-certificates-bundle-20191127-r5\n"
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch(
+        "craft_parts.packages.apk.BaseDirectory.save_cache_path",
+        return_value="/cache",
+    )
+
+
+@pytest.fixture
+def fake_check_call(mocker):
+    return mocker.patch("subprocess.check_call")
+
+
+@pytest.fixture
+def fake_installed(mocker):
+    return mocker.patch("subprocess.check_output", return_value=_INSTALLED)
+
+
+class TestBuildPackages:
+    def test_refresh_build_packages_list(self, fake_check_call):
+        apk.Alpine.refresh_build_packages_list()
+
+        fake_check_call.assert_called_once_with(
+            ["sudo", "--preserve-env", "apk", "update", "--no-progress"]
+        )
+
+    def test_refresh_build_packages_list_error(self, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageListRefreshError):
+            apk.Alpine.refresh_build_packages_list()
+
+    def test_install_build_packages(self, fake_check_call, fake_installed):
+        installed = apk.Alpine.install_build_packages(
+            ["musl=1.2.2-r3", "make", "gcc@edge"]
+        )
+
+        fake_check_call.assert_called_once_with(
+            [
+                "sudo",
+                "--preserve-env",
+                "apk",
+                "add",
+                "--no-progress",
+                "gcc@edge",
+                "make",
+                "musl=1.2.2-r3",
+            ]
+        )
+        assert installed == ["musl=1.2.2-r3"]
+
+    def test_install_build_packages_already_installed(
+        self, fake_check_call, fake_installed
+    ):
+        installed = apk.Alpine.install_build_packages(["busybox", "musl=1.2.2-r3"])
+
+        fake_check_call.assert_not_called()
+        assert installed == ["busybox=1.33.1-r3", "musl=1.2.2-r3"]
+
+    def test_install_build_packages_other_version(
+        self, fake_check_call, fake_installed
+    ):
+        apk.Alpine.install_build_packages(["musl=1.2.3-r0"])
+
+        fake_check_call.assert_called_once()
+
+    def test_install_build_packages_list_only(self, mocker, fake_check_call):
+        mocker.patch(
+            "subprocess.check_output",
+            return_value=(
+                b"(1/2) Installing libgcc (10.3.1_git20210424-r2)\n"
+                b"(2/2) Installing gcc (10.3.1_git20210424-r2)\n"
+                b"OK: 120 MiB in 16 packages\n"
+            ),
+        )
+
+        installed = apk.Alpine.install_build_packages(["gcc"], list_only=True)
+
+        fake_check_call.assert_not_called()
+        assert installed == [
+            "gcc=10.3.1_git20210424-r2",
+            "libgcc=10.3.1_git20210424-r2",
+        ]
+
+    def test_install_build_packages_error(self, fake_check_call, fake_installed):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.BuildPackagesNotInstalled) as raised:
+            apk.Alpine.install_build_packages(["make"])
+        assert raised.value.packages == ["make"]
+
+    def test_is_package_installed(self, fake_installed):
+        assert apk.Alpine.is_package_installed("busybox") is True
+        assert apk.Alpine.is_package_installed("musl@edge") is True
+        assert apk.Alpine.is_package_installed("make") is False
+
+    def test_get_installed_packages(self, fake_installed):
+        assert apk.Alpine.get_installed_packages() == [
+            "busybox=1.33.1-r3",
+            "ca-certificates-bundle=20191127-r5",
+            "musl=1.2.2-r3",
+        ]
+
+
+class TestStagePackages:
+    def test_refresh_stage_packages_list(self, fake_check_call):
+        apk.Alpine.refresh_stage_packages_list(
+            application_name="test", target_arch="arm64"
+        )
+
+        fake_check_call.assert_called_once_with(["apk", "update", *_STAGE_OPTIONS])
+
+    def test_fetch_stage_packages(self, new_dir, mocker):
+        def fake_fetch(cmd):
+            output = cmd[cmd.index("--output") + 1]
+            Path(output, "busybox-1.33.1-r3.apk").touch()
+            Path(output, "musl-1.2.2-r3.apk").touch()
+
+        fake_check_call = mocker.patch("subprocess.check_call")
+        fake_check_call.side_effect = fake_fetch
+
+        fetched = apk.Alpine.fetch_stage_packages(
+            application_name="test",
+            package_names=["busybox@edge"],
+            stage_packages_path=Path("stage"),
+            base="alpine3.14",
+            target_arch="arm64",
+        )
+
+        assert fetched == ["busybox=1.33.1-r3", "musl=1.2.2-r3"]
+        assert sorted(p.name for p in Path("stage").iterdir()) == [
+            "busybox-1.33.1-r3.apk",
+            "musl-1.2.2-r3.apk",
+        ]
+        cmd = fake_check_call.mock_calls[0].args[0]
+        assert cmd[:3] == ["apk", "fetch", "--recursive"]
+        assert cmd[3:6] == _STAGE_OPTIONS
+        assert cmd[-1] == "busybox@edge"
+
+    def test_fetch_stage_packages_list_only(self, new_dir, mocker):
+        fake_output = mocker.patch(
+            "subprocess.check_output",
+            return_value=b"Downloading busybox-1.33.1-r3\nDownloading musl-1.2.2-r3\n",
+        )
+
+        fetched = apk.Alpine.fetch_stage_packages(
+            application_name="test",
+            package_names=["busybox"],
+            stage_packages_path=Path("stage"),
+            base="alpine3.14",
+            target_arch="arm64",
+            list_only=True,
+        )
+
+        assert fetched == ["busybox=1.33.1-r3", "musl=1.2.2-r3"]
+        assert fake_output.mock_calls[0].args[0][-2:] == ["--simulate", "busybox"]
+        assert Path("stage").exists() is False
+
+    def test_fetch_stage_packages_error(self, new_dir, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageFetchError):
+            apk.Alpine.fetch_stage_packages(
+                application_name="test",
+                package_names=["busybox"],
+                stage_packages_path=Path("stage"),
+                base="alpine3.14",
+                target_arch="arm64",
+            )
+
+    def test_unpack_stage_packages(self, new_dir, mocker):
+        def fake_tar(cmd):
+            directory = cmd[-1][len("--directory=") :]
+            Path(directory, "bin").mkdir()
+            Path(directory, "bin/busybox").write_text("busybox")
+            Path(directory, ".PKGINFO").write_text("pkgname = busybox")
+            Path(directory, ".SIGN.RSA.alpine-devel.rsa.pub").write_text("sig")
+
+        mocker.patch("subprocess.check_call", side_effect=fake_tar)
+        fake_mark = mocker.patch(
+            "craft_parts.packages.package_manager.mark_origin_stage_package"
+        )
+        Path("stage").mkdir()
+        Path("stage/busybox-1.33.1-r3.apk").touch()
+        Path("install").mkdir()
+
+        apk.Alpine.unpack_stage_packages(
+            stage_packages_path=Path("stage"), install_path=Path("install")
+        )
+
+        assert [p.name for p in Path("install").iterdir()] == ["bin"]
+        assert Path("install/bin/busybox").read_text() == "busybox"
+        assert fake_mark.mock_calls[0].args[1] == "busybox=1.33.1-r3"
+
+    def test_unpack_stage_packages_error(self, new_dir, mocker):
+        mocker.patch(
+            "subprocess.check_call", side_effect=subprocess.CalledProcessError(2, [])
+        )
+        Path("stage").mkdir()
+        Path("stage/busybox-1.33.1-r3.apk").touch()
+        Path("install").mkdir()
+
+        with pytest.raises(errors.UnpackError):
+            apk.Alpine.unpack_stage_packages(
+                stage_packages_path=Path("stage"), install_path=Path("install")
+            )
+
+
+def test_install_package_repositories(mocker):
+    fake_install = mocker.patch(
+        "craft_parts.packages.apk.install_apk_repository", return_value=False
+    )
+
+    changed = apk.Alpine.install_package_repositories(
+        {"edge": {"url": "https://example.com/edge/main", "tag": "edge"}},
+        keys_dir=Path("keys"),
+    )
+
+    assert changed is False
+    assert fake_install.mock_calls[0].args[0].entry == (
+        "@edge https://example.com/edge/main"
+    )
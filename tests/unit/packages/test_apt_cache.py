@@ -19,7 +19,12 @@ from pathlib import Path
 from unittest import mock
 from unittest.mock import call
 
+import pytest
+
+from craft_parts import progress
+from craft_parts.packages import errors
 from craft_parts.packages.apt_cache import AptCache
+from craft_parts.sources.cache import FileCache
 
 # pylint: disable=too-few-public-methods
 
@@ -96,6 +101,7 @@ class TestMockedApt:
 
         assert fake_apt.mock_calls == [
             call.apt_pkg.config.set("Apt::Install-Recommends", "False"),
+            call.apt_pkg.config.set("Apt::Install-Suggests", "False"),
             call.apt_pkg.config.set("Acquire::AllowInsecureRepositories", "False"),
             call.apt_pkg.config.set("Dir::Etc::Trusted", "/etc/apt/trusted.gpg"),
             call.apt_pkg.config.set(
@@ -110,6 +116,121 @@ class TestMockedApt:
             call.cache.Cache().close(),
         ]
 
+    def test_stage_cache_recommends_suggests(self, tmpdir, mocker):
+        stage_cache = Path(tmpdir, "cache")
+        stage_cache.mkdir(exist_ok=True, parents=True)
+        fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
+
+        with AptCache(
+            stage_cache=stage_cache, install_recommends=True, install_suggests=True
+        ):
+            pass
+
+        assert fake_apt.mock_calls[:2] == [
+            call.apt_pkg.config.set("Apt::Install-Recommends", "True"),
+            call.apt_pkg.config.set("Apt::Install-Suggests", "True"),
+        ]
+
+    @pytest.mark.parametrize(
+        "installed,relation,version,result",
+        [
+            (None, ">=", "2", "3"),
+            (None, "<<", "3", "2"),
+            ("2", ">=", "2", "2"),
+            ("1", ">=", "2", "3"),
+        ],
+    )
+    def test_get_version_satisfying(
+        self, mocker, installed, relation, version, result
+    ):
+        fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
+        fake_apt.apt_pkg.check_dep.side_effect = _fake_check_dep
+        package = _fake_package(["1", "2", "3"], installed=installed)
+        fake_apt.cache.Cache.return_value = _fake_cache({"hello": package})
+
+        with AptCache() as apt_cache:
+            assert (
+                apt_cache.get_version_satisfying(
+                    "hello", relation=relation, version=version
+                )
+                == result
+            )
+
+    def test_get_version_satisfying_not_satisfied(self, mocker):
+        fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
+        fake_apt.apt_pkg.check_dep.side_effect = _fake_check_dep
+        package = _fake_package(["1", "2"], installed=None)
+        fake_apt.cache.Cache.return_value = _fake_cache({"hello": package})
+
+        with AptCache() as apt_cache, pytest.raises(
+            errors.PackageVersionNotSatisfied
+        ) as raised:
+            apt_cache.get_version_satisfying("hello", relation=">>", version="2")
+        assert raised.value.constraint == ">> 2"
+        assert raised.value.versions == ["2", "1"]
+
+    def test_get_version_satisfying_not_found(self, mocker):
+        fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
+        fake_apt.cache.Cache.return_value = _fake_cache({})
+
+        with AptCache() as apt_cache, pytest.raises(errors.PackageNotFound):
+            apt_cache.get_version_satisfying("hello", relation=">=", version="1")
+
+    def test_fetch_archives_file_cache(self, tmpdir, mocker):
+        fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
+        download_dir = Path(tmpdir, "download")
+        download_dir.mkdir()
+        file_cache = FileCache("test", cache_dir=Path(tmpdir, "cache"))
+
+        def fetch_binary(destdir):
+            deb_path = Path(destdir, "hello_2.10_amd64.deb")
+            if not deb_path.exists():
+                deb_path.write_text("downloaded")
+            return str(deb_path)
+
+        package = mock.Mock()
+        package.name = "hello"
+        package.candidate.version = "2.10"
+        package.candidate.sha256 = "1234"
+        package.candidate.filename = "pool/main/h/hello/hello_2.10_amd64.deb"
+        package.candidate.fetch_binary.side_effect = fetch_binary
+        fake_apt.cache.Cache.return_value.get_changes.return_value = [package]
+
+        with AptCache() as apt_cache:
+            fetched = apt_cache.fetch_archives(download_dir, file_cache=file_cache)
+
+        deb_path = download_dir / "hello_2.10_amd64.deb"
+        assert fetched == [("hello", "2.10", deb_path)]
+        assert Path(tmpdir, "cache/files/sha256/1234").read_text() == "downloaded"
+
+        # cached files are used instead of downloading them again
+        deb_path.unlink()
+        Path(tmpdir, "cache/files/sha256/1234").write_text("cached")
+
+        with AptCache() as apt_cache:
+            apt_cache.fetch_archives(download_dir, file_cache=file_cache)
+
+        assert deb_path.read_text() == "cached"
+
+    def test_fetch_archives_progress(self, tmpdir, mocker):
+        fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
+
+        package = mock.Mock()
+        package.name = "hello"
+        package.candidate.size = 1234
+        package.candidate.fetch_binary.return_value = "hello_2.10_amd64.deb"
+        fake_apt.cache.Cache.return_value.get_changes.return_value = [package]
+
+        reports = []
+        with progress.progress_handler(lambda *args: reports.append(args)):
+            with AptCache() as apt_cache:
+                apt_cache.fetch_archives(Path(tmpdir))
+
+        assert reports == [
+            (progress.DownloadKind.PACKAGE, "hello", 0, 1234),
+            (progress.DownloadKind.PACKAGE, "hello", 1234, 1234),
+        ]
+
     def test_stage_cache_in_snap(self, tmpdir, mocker):
         fake_apt = mocker.patch("craft_parts.packages.apt_cache.apt")
 
@@ -128,6 +249,7 @@ class TestMockedApt:
 
         assert fake_apt.mock_calls == [
             call.apt_pkg.config.set("Apt::Install-Recommends", "False"),
+            call.apt_pkg.config.set("Apt::Install-Suggests", "False"),
             call.apt_pkg.config.set("Acquire::AllowInsecureRepositories", "False"),
             call.apt_pkg.config.set("Dir", str(Path(snap, "usr/lib/apt"))),
             call.apt_pkg.config.set(
@@ -188,3 +310,36 @@ class TestAptReadonlyHostCache:
         with AptCache() as apt_cache:
             assert isinstance(apt_cache.get_installed_version("apt"), str)
             assert apt_cache.get_installed_version("fake-news-bears") is None
+
+
+class _FakeVersion:
+    def __init__(self, version: str):
+        self.version = version
+
+    def __lt__(self, other):
+        return int(self.version) < int(other.version)
+
+
+def _fake_package(versions, *, installed):
+    package = mock.Mock()
+    package.versions = [_FakeVersion(v) for v in versions]
+    package.installed = _FakeVersion(installed) if installed else None
+    return package
+
+
+def _fake_cache(packages):
+    cache = mock.MagicMock()
+    cache.__contains__.side_effect = packages.__contains__
+    cache.__getitem__.side_effect = packages.__getitem__
+    return cache
+
+
+def _fake_check_dep(pkg_version, relation, version):
+    relations = {
+        "<<": int.__lt__,
+        "<=": int.__le__,
+        "=": int.__eq__,
+        ">=": int.__ge__,
+        ">>": int.__gt__,
+    }
+    return relations[relation](int(pkg_version), int(version))
@@ -14,7 +14,11 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-from craft_parts.packages import base
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import base, errors
 from craft_parts.packages.base import BaseRepository, DummyRepository
 
 
@@ -34,6 +38,46 @@ class TestBaseRepository:
             "unpack_stage_packages",
         }
 
+    def test_resolve_build_packages(self, mocker):
+        fake_install = mocker.patch.object(
+            DummyRepository, "install_build_packages", return_value=["foo=1.0"]
+        )
+
+        assert DummyRepository.resolve_build_packages(["foo"]) == ["foo=1.0"]
+        fake_install.assert_called_once_with(["foo"], list_only=True)
+
+    def test_resolve_stage_packages(self, mocker):
+        fake_fetch = mocker.patch.object(
+            DummyRepository, "fetch_stage_packages", return_value=["foo=1.0"]
+        )
+
+        packages = DummyRepository.resolve_stage_packages(
+            application_name="test",
+            package_names=["foo"],
+            base="core20",
+            target_arch="amd64",
+        )
+
+        assert packages == ["foo=1.0"]
+        fake_fetch.assert_called_once_with(
+            application_name="test",
+            package_names=["foo"],
+            stage_packages_path=Path(),
+            base="core20",
+            target_arch="amd64",
+            list_only=True,
+        )
+
+    def test_install_package_repositories_not_supported(self):
+        with pytest.raises(errors.PackageRepositoriesNotSupported) as raised:
+            BaseRepository.install_package_repositories({}, keys_dir=Path("keys"))
+        assert raised.value.backend == "BaseRepository"
+
+    def test_get_overlay_install_commands_not_supported(self):
+        with pytest.raises(errors.OverlayPackagesNotSupported) as raised:
+            DummyRepository.get_overlay_install_commands(["hello"])
+        assert raised.value.backend == "DummyRepository"
+
 
 class TestDummyRepository:
     """Verify the dummy repository implementation."""
@@ -48,6 +92,10 @@ class TestDummyRepository:
         assert DummyRepository.is_package_installed("baz") is False
         assert DummyRepository.get_installed_packages() == []
         assert DummyRepository.fetch_stage_packages() == []
+        assert (
+            DummyRepository.install_package_repositories({}, keys_dir=Path("keys"))
+            is False
+        )
 
 
 class TestPkgNameParts:
@@ -0,0 +1,169 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import chisel, errors
+
+_COMMIT = "2514f9533ec9b45d07883e10a561b248497a8e3c"
+
+
+def test_get_slices():
+    slices = chisel.get_slices(["hello", "openssl_config", "libc6_libs"])
+    assert slices == ["libc6_libs", "openssl_config"]
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestResolveRelease:
+    """Verify the resolution of chisel releases."""
+
+    def test_default(self):
+        release_dir = chisel.resolve_release(
+            "", project_dir=Path(), cache_dir=Path("cache")
+        )
+        assert release_dir is None
+
+    def test_local_directory(self):
+        Path("slices").mkdir()
+        Path("slices/chisel.yaml").write_text("format: chisel-v1\n")
+
+        release_dir = chisel.resolve_release(
+            "slices", project_dir=Path(), cache_dir=Path("cache")
+        )
+
+        assert release_dir == Path("slices")
+
+    def test_local_directory_no_chisel_yaml(self):
+        Path("slices").mkdir()
+
+        with pytest.raises(errors.InvalidChiselRelease) as raised:
+            chisel.resolve_release(
+                "slices", project_dir=Path(), cache_dir=Path("cache")
+            )
+        assert raised.value.message == "chisel.yaml not found in the release directory"
+
+    def test_invalid_reference(self):
+        with pytest.raises(errors.InvalidChiselRelease) as raised:
+            chisel.resolve_release(
+                "not/a dir", project_dir=Path(), cache_dir=Path("cache")
+            )
+        assert raised.value.message == "not a directory or chisel-releases reference"
+
+    def test_branch(self, mocker):
+        def fake_git(command, **kwargs):
+            if "checkout" in command:
+                Path(command[2], "chisel.yaml").write_text("format: chisel-v1\n")
+            elif "init" in command:
+                Path(command[2], ".git").mkdir()
+
+        fake_run = mocker.patch("subprocess.run", side_effect=fake_git)
+
+        release_dir = chisel.resolve_release(
+            "ubuntu-22.04", project_dir=Path(), cache_dir=Path("cache")
+        )
+
+        git_dir = "cache/chisel-releases/ubuntu-22.04"
+        assert release_dir == Path(git_dir)
+        assert [c.args[0][3:] for c in fake_run.mock_calls] == [
+            ["init", "--quiet"],
+            ["remote", "add", "origin", chisel.CHISEL_RELEASES_URL],
+            ["fetch", "--quiet", "--depth=1", "origin", "ubuntu-22.04"],
+            ["checkout", "--quiet", "--force", "FETCH_HEAD"],
+        ]
+        assert fake_run.mock_calls[0].args[0][:3] == ["git", "-C", git_dir]
+
+    def test_branch_fetches_again(self, mocker):
+        Path("cache/chisel-releases/ubuntu-22.04/.git").mkdir(parents=True)
+        Path("cache/chisel-releases/ubuntu-22.04/chisel.yaml").touch()
+        fake_run = mocker.patch("subprocess.run")
+
+        chisel.resolve_release(
+            "ubuntu-22.04", project_dir=Path(), cache_dir=Path("cache")
+        )
+
+        assert [c.args[0][3] for c in fake_run.mock_calls] == ["fetch", "checkout"]
+
+    def test_commit_already_checked_out(self, mocker):
+        Path(f"cache/chisel-releases/{_COMMIT}/.git").mkdir(parents=True)
+        Path(f"cache/chisel-releases/{_COMMIT}/chisel.yaml").touch()
+        mocker.patch("subprocess.check_output", return_value=f"{_COMMIT}\n".encode())
+        fake_run = mocker.patch("subprocess.run")
+
+        release_dir = chisel.resolve_release(
+            _COMMIT, project_dir=Path(), cache_dir=Path("cache")
+        )
+
+        assert release_dir == Path(f"cache/chisel-releases/{_COMMIT}")
+        fake_run.assert_not_called()
+
+    def test_fetch_error(self, mocker):
+        mocker.patch(
+            "subprocess.run", side_effect=subprocess.CalledProcessError(128, [])
+        )
+
+        with pytest.raises(errors.InvalidChiselRelease) as raised:
+            chisel.resolve_release(
+                "ubuntu-99.04", project_dir=Path(), cache_dir=Path("cache")
+            )
+        assert raised.value.message == (
+            "failed to fetch from https://github.com/canonical/chisel-releases"
+        )
+
+
+class TestCutSlices:
+    """Verify that slices are cut with chisel."""
+
+    def test_cut(self, new_dir, mocker):
+        fake_run = mocker.patch("subprocess.run")
+
+        chisel.cut_slices(
+            ["libc6_libs", "openssl_config"],
+            install_path=Path("install"),
+            release_dir=Path("slices"),
+            target_arch="arm64",
+        )
+
+        fake_run.assert_called_once_with(
+            [
+                "chisel",
+                "cut",
+                "--root=install",
+                "--release=slices",
+                "--arch=arm64",
+                "libc6_libs",
+                "openssl_config",
+            ],
+            check=True,
+        )
+        assert Path("install").is_dir()
+
+    def test_cut_no_slices(self, mocker):
+        fake_run = mocker.patch("subprocess.run")
+
+        chisel.cut_slices([], install_path=Path("install"))
+
+        fake_run.assert_not_called()
+
+    def test_cut_error(self, new_dir, mocker):
+        mocker.patch("subprocess.run", side_effect=subprocess.CalledProcessError(1, []))
+
+        with pytest.raises(errors.ChiselError) as raised:
+            chisel.cut_slices(["libc6_libs"], install_path=Path("install"))
+        assert raised.value.slices == ["libc6_libs"]
+        assert raised.value.message == "chisel exited with code 1"
@@ -108,18 +108,32 @@ class TestPackages:
 
         fake_apt_cache.assert_has_calls(
             [
-                call(stage_cache=stage_cache_path, stage_cache_arch="amd64"),
+                call(
+                    stage_cache=stage_cache_path,
+                    stage_cache_arch="amd64",
+                    stage_cache_foreign_archs=set(),
+                    install_recommends=False,
+                    install_suggests=False,
+                ),
                 call().__enter__(),
                 call().__enter__().mark_packages({"fake-package"}),
                 call()
                 .__enter__()
                 .unmark_packages({"filtered-pkg-1", "filtered-pkg-2"}),
-                call().__enter__().fetch_archives(debs_path),
+                call().__enter__().fetch_archives(mock.ANY, file_cache=mock.ANY),
             ]
         )
 
         assert fetched_packages == ["fake-package=1.0"]
 
+        # packages are downloaded to a temporary directory, and kept in the
+        # shared deb file cache
+        fake_fetch = fake_apt_cache.return_value.__enter__.return_value.fetch_archives
+        assert fake_fetch.mock_calls[0].args[0] == Path(tmpdir, "deb-download")
+        file_cache = fake_fetch.mock_calls[0].kwargs["file_cache"]
+        assert file_cache.file_cache.endswith("test/craft-parts/stage-packages-debs")
+        assert file_cache.max_size == deb.DEB_FILE_CACHE_MAX_SIZE
+
     def test_fetch_virtual_stage_package(self, tmpdir, fake_apt_cache):
         _, debs_path = deb.get_cache_dirs("test")
         fake_package = debs_path / "fake-package_1.0_all.deb"
@@ -255,6 +269,30 @@ class TestBuildPackages:
             ]
         )
 
+    def test_install_build_packages_version_constraint(
+        self, fake_apt_cache, fake_run
+    ):
+        fake_cache = fake_apt_cache.return_value.__enter__.return_value
+        fake_cache.get_version_satisfying.return_value = "9.4.0-1"
+
+        deb.Ubuntu.install_build_packages(["gcc (>= 9.3)", "package"])
+
+        fake_cache.get_version_satisfying.assert_called_once_with(
+            "gcc", relation=">=", version="9.3"
+        )
+        fake_cache.mark_packages.assert_called_once_with({"gcc=9.4.0-1", "package"})
+
+    def test_install_build_packages_version_constraint_not_found(
+        self, fake_apt_cache, fake_run
+    ):
+        fake_cache = fake_apt_cache.return_value.__enter__.return_value
+        fake_cache.get_version_satisfying.side_effect = errors.PackageNotFound("gcc")
+
+        with pytest.raises(errors.BuildPackageNotFound) as raised:
+            deb.Ubuntu.install_build_packages(["gcc (>= 9.3)"])
+        assert raised.value.package == "gcc"
+        assert isinstance(raised.value.__cause__, errors.PackageNotFound)
+
     def test_install_build_packages_empty_list(self, fake_apt_cache, fake_run):
         fake_apt_cache.return_value.__enter__.return_value.get_packages_marked_for_installation.return_value = (
             []
@@ -471,6 +509,80 @@ def fake_dpkg_query(mocker):
     mocker.patch("subprocess.check_output", side_effect=dpkg_query)
 
 
+class TestGetForeignArchitectures:
+    @pytest.mark.parametrize(
+        "names,archs",
+        [
+            ([], set()),
+            (["foo", "bar=1.0"], set()),
+            (["foo:amd64", "bar:any", "baz:all", "qux:native"], set()),
+            (["foo:i386", "bar:armhf=1.0", "baz:i386"], {"i386", "armhf"}),
+        ],
+    )
+    def test_get_foreign_architectures(self, names, archs):
+        assert deb.get_foreign_architectures(names, target_arch="amd64") == archs
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestGetLocalDebFiles:
+    def test_get_local_deb_files(self):
+        Path("vendor").mkdir()
+        Path("vendor/foo_1.0_amd64.deb").touch()
+        Path("vendor/bar_2.0_amd64.deb").touch()
+        Path("baz_3.0_all.deb").touch()
+
+        names, deb_files = deb.get_local_deb_files(
+            ["hello", "vendor/", "baz_3.0_all.deb", "world=1.0"]
+        )
+
+        assert names == ["hello", "world=1.0"]
+        assert deb_files == [
+            Path("vendor/bar_2.0_amd64.deb"),
+            Path("vendor/foo_1.0_amd64.deb"),
+            Path("baz_3.0_all.deb"),
+        ]
+
+    def test_get_local_deb_files_not_found(self):
+        with pytest.raises(errors.LocalPackageNotFound) as raised:
+            deb.get_local_deb_files(["hello", "missing.deb"])
+        assert raised.value.path == "missing.deb"
+
+
+def test_get_deb_dependencies(mocker):
+    outputs = {
+        "foo.deb": b"foo\nlibc6 (>= 2.31), libfoo1 | libfoo2, bar (= 1.0)",
+        "bar.deb": b"bar\n, libbar:any",
+    }
+    mocker.patch(
+        "subprocess.check_output", side_effect=lambda cmd: outputs[str(cmd[-1])]
+    )
+
+    deps = deb.get_deb_dependencies([Path("foo.deb"), Path("bar.deb")])
+
+    assert deps == {"libc6", "libfoo1", "libbar:any"}
+
+
+@pytest.mark.parametrize(
+    "name,result",
+    [
+        ("gcc", ("gcc", None)),
+        ("gcc=9.3.0-1", ("gcc=9.3.0-1", None)),
+        ("gcc (>= 9.3)", ("gcc", (">=", "9.3"))),
+        ("gcc (= 4:9.3.0-1ubuntu2)", ("gcc", ("=", "4:9.3.0-1ubuntu2"))),
+        ("gcc(<<10)", ("gcc", ("<<", "10"))),
+    ],
+)
+def test_get_version_constraint(name, result):
+    assert deb.get_version_constraint(name) == result
+
+
+@pytest.mark.parametrize("name", ["gcc (~ 9)", "gcc (>= 9", "gcc (>=)"])
+def test_get_version_constraint_invalid(name):
+    with pytest.raises(errors.InvalidVersionConstraint) as raised:
+        deb.get_version_constraint(name)
+    assert raised.value.package == name
+
+
 class TestGetPackagesInBase:
     def test_hardcoded_bases(self):
         for base in ("core", "core16", "core18"):
@@ -578,3 +690,51 @@ def test_get_filtered_stage_package_empty_ignore_filter(mocker):
     )
 
     assert filtered_names == {"some-base-pkg", "some-other-base-pkg"}
+
+
+def test_install_package_repositories_cloud_archive(mocker):
+    mocker.patch(
+        "craft_parts.utils.os_utils.OsRelease.version_codename", return_value="jammy"
+    )
+    fake_install = mocker.patch(
+        "craft_parts.packages.deb.install_cloud_archive", return_value=True
+    )
+
+    changed = deb.Ubuntu.install_package_repositories(
+        {"openstack": {"cloud": "caracal"}}, keys_dir=Path("keys")
+    )
+
+    assert changed is True
+    cloud_archive = fake_install.mock_calls[0].args[0]
+    assert cloud_archive.cloud == "caracal"
+    assert fake_install.mock_calls[0].kwargs == {
+        "name": "openstack",
+        "keys_dir": Path("keys"),
+        "series": "jammy",
+    }
+
+
+def test_get_overlay_install_commands():
+    assert deb.Ubuntu.get_overlay_install_commands(["hello", "curl"]) == [
+        ["apt-get", "update"],
+        [
+            "env",
+            "DEBIAN_FRONTEND=noninteractive",
+            "apt-get",
+            "--no-install-recommends",
+            "-y",
+            "install",
+            "hello",
+            "curl",
+        ],
+    ]
+
+
+def test_prune_deb_file_cache(new_dir):
+    file_cache = deb.get_deb_file_cache("test")
+    Path("hello.deb").write_text("1234")
+    cached_path = file_cache.cache(filename="hello.deb", key="sha256/1234")
+
+    assert deb.prune_deb_file_cache("test") == 0
+    assert deb.prune_deb_file_cache("test", max_size=0) == 4
+    assert Path(cached_path).exists() is False
@@ -0,0 +1,253 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+from unittest.mock import call
+
+import pytest
+
+from craft_parts.packages import dnf, errors
+
+# pylint: disable=missing-class-docstring
+
+_STAGE_OPTIONS = ["--quiet", "--setopt=cachedir=/cache", "--forcearch=x86_64"]
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch(
+        "craft_parts.packages.dnf.BaseDirectory.save_cache_path",
+        return_value="/cache",
+    )
+
+
+@pytest.fixture
+def fake_check_call(mocker):
+    return mocker.patch("subprocess.check_call")
+
+
+@pytest.fixture
+def fake_rpm_query(mocker):
+    def rpm_query(cmd, **kwargs):
+        names = cmd[4:]
+        stdout = "\n".join(
+            f"{n}=1.0-1.fc34" if "installed" in n else f"package {n} is not installed"
+            for n in names
+        )
+        return subprocess.CompletedProcess(cmd, 0, stdout=stdout.encode())
+
+    return mocker.patch("subprocess.run", side_effect=rpm_query)
+
+
+class TestBuildPackages:
+    def test_refresh_build_packages_list(self, fake_check_call):
+        dnf.DNFRepository.refresh_build_packages_list()
+
+        fake_check_call.assert_called_once_with(
+            ["sudo", "--preserve-env", "dnf", "makecache", "--refresh"]
+        )
+
+    def test_refresh_build_packages_list_error(self, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageListRefreshError):
+            dnf.DNFRepository.refresh_build_packages_list()
+
+    def test_install_build_packages(self, fake_check_call, fake_rpm_query):
+        installed = dnf.DNFRepository.install_build_packages(
+            ["package", "installed=1.0-1.fc34", "other=2.0"]
+        )
+
+        fake_check_call.assert_called_once_with(
+            [
+                "sudo",
+                "--preserve-env",
+                "dnf",
+                "install",
+                "-y",
+                "installed-1.0-1.fc34",
+                "other-2.0",
+                "package",
+            ]
+        )
+        assert installed == ["installed=1.0-1.fc34"]
+
+    def test_install_build_packages_already_installed(
+        self, fake_check_call, fake_rpm_query
+    ):
+        installed = dnf.DNFRepository.install_build_packages(["installed"])
+
+        fake_check_call.assert_not_called()
+        assert installed == ["installed=1.0-1.fc34"]
+
+    def test_install_build_packages_list_only(self, mocker, fake_check_call):
+        fake_output = mocker.patch(
+            "subprocess.check_output", return_value=b"package=1.2-3.fc34\n"
+        )
+
+        installed = dnf.DNFRepository.install_build_packages(
+            ["package"], list_only=True
+        )
+
+        fake_check_call.assert_not_called()
+        assert fake_output.mock_calls[0].args[0][:2] == ["dnf", "repoquery"]
+        assert installed == ["package=1.2-3.fc34"]
+
+    def test_install_build_packages_error(self, fake_check_call, fake_rpm_query):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.BuildPackagesNotInstalled) as raised:
+            dnf.DNFRepository.install_build_packages(["package"])
+        assert raised.value.packages == ["package"]
+
+    def test_is_package_installed(self, fake_rpm_query):
+        assert dnf.DNFRepository.is_package_installed("installed") is True
+        assert dnf.DNFRepository.is_package_installed("package") is False
+
+    def test_get_installed_packages(self, mocker):
+        mocker.patch(
+            "subprocess.check_output", return_value=b"zlib=1.2-3\nbash=5.1-2\n"
+        )
+
+        assert dnf.DNFRepository.get_installed_packages() == [
+            "bash=5.1-2",
+            "zlib=1.2-3",
+        ]
+
+
+class TestStagePackages:
+    def test_refresh_stage_packages_list(self, fake_check_call):
+        dnf.DNFRepository.refresh_stage_packages_list(
+            application_name="test", target_arch="amd64"
+        )
+
+        fake_check_call.assert_called_once_with(
+            ["dnf", "makecache", "--refresh", *_STAGE_OPTIONS]
+        )
+
+    def test_fetch_stage_packages(self, new_dir, mocker):
+        def fake_download(cmd):
+            destdir = cmd[cmd.index("--destdir") + 1]
+            Path(destdir, "hello-2.10-5.fc34.x86_64.rpm").touch()
+            Path(destdir, "glibc-2.33-5.fc34.x86_64.rpm").touch()
+
+        fake_check_call = mocker.patch("subprocess.check_call")
+        fake_check_call.side_effect = fake_download
+
+        fetched = dnf.DNFRepository.fetch_stage_packages(
+            application_name="test",
+            package_names=["hello"],
+            stage_packages_path=Path("stage"),
+            base="fedora34",
+            target_arch="amd64",
+        )
+
+        assert fetched == ["glibc=2.33-5.fc34", "hello=2.10-5.fc34"]
+        assert sorted(p.name for p in Path("stage").iterdir()) == [
+            "glibc-2.33-5.fc34.x86_64.rpm",
+            "hello-2.10-5.fc34.x86_64.rpm",
+        ]
+        cmd = fake_check_call.mock_calls[0].args[0]
+        assert cmd[:4] == ["dnf", "download", "--resolve", "--alldeps"]
+        assert cmd[-1] == "hello"
+
+    def test_fetch_stage_packages_list_only(self, new_dir, mocker):
+        fake_output = mocker.patch(
+            "subprocess.check_output",
+            return_value=(
+                b"https://example.com/hello-2.10-5.fc34.x86_64.rpm\n"
+                b"https://example.com/glibc-common-2.33-5.fc34.x86_64.rpm\n"
+            ),
+        )
+
+        fetched = dnf.DNFRepository.fetch_stage_packages(
+            application_name="test",
+            package_names=["hello=2.10"],
+            stage_packages_path=Path("stage"),
+            base="fedora34",
+            target_arch="amd64",
+            list_only=True,
+        )
+
+        assert fetched == ["glibc-common=2.33-5.fc34", "hello=2.10-5.fc34"]
+        assert fake_output.mock_calls == [
+            call(
+                [
+                    "dnf",
+                    "download",
+                    "--resolve",
+                    "--alldeps",
+                    *_STAGE_OPTIONS,
+                    "--url",
+                    "hello-2.10",
+                ]
+            )
+        ]
+        assert Path("stage").exists() is False
+
+    def test_fetch_stage_packages_error(self, new_dir, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageFetchError):
+            dnf.DNFRepository.fetch_stage_packages(
+                application_name="test",
+                package_names=["hello"],
+                stage_packages_path=Path("stage"),
+                base="fedora34",
+                target_arch="amd64",
+            )
+
+    def test_unpack_stage_packages(self, new_dir, mocker):
+        def fake_cpio(cmd, *, stdin, cwd):
+            Path(cwd, "usr/bin").mkdir(parents=True)
+            Path(cwd, "usr/bin/hello").write_text("hello")
+
+        mocker.patch("subprocess.Popen").return_value.__enter__.return_value = (
+            mocker.Mock(stdout=None, returncode=0)
+        )
+        mocker.patch("subprocess.check_call", side_effect=fake_cpio)
+        fake_mark = mocker.patch(
+            "craft_parts.packages.package_manager.mark_origin_stage_package"
+        )
+        Path("stage").mkdir()
+        Path("stage/hello-2.10-5.fc34.x86_64.rpm").touch()
+        Path("install").mkdir()
+
+        dnf.DNFRepository.unpack_stage_packages(
+            stage_packages_path=Path("stage"), install_path=Path("install")
+        )
+
+        assert Path("install/usr/bin/hello").read_text() == "hello"
+        assert fake_mark.mock_calls[0].args[1] == "hello=2.10-5.fc34"
+
+
+def test_install_package_repositories(mocker):
+    fake_install = mocker.patch(
+        "craft_parts.packages.dnf.install_rpm_repository", side_effect=[False, True]
+    )
+    repositories = {
+        "tools": {"url": "https://rpm.example.com/tools", "key-id": "A" * 40},
+        "extras": {"url": "https://rpm.example.com/extras", "key-id": "B" * 40},
+    }
+
+    changed = dnf.DNFRepository.install_package_repositories(
+        repositories, keys_dir=Path("keys")
+    )
+
+    assert changed is True
+    assert [c.kwargs["name"] for c in fake_install.mock_calls] == ["tools", "extras"]
+    assert fake_install.mock_calls[0].args[0].url == "https://rpm.example.com/tools"
@@ -114,6 +114,14 @@ def test_snap_download_error():
     assert err.resolution is None
 
 
+def test_snap_unpack_error():
+    err = errors.SnapUnpackError(snap_file="word-salad_1.snap")
+    assert err.snap_file == "word-salad_1.snap"
+    assert err.brief == "Error extracting snap file 'word-salad_1.snap'."
+    assert err.details is None
+    assert err.resolution == "Make sure the unsquashfs command is installed."
+
+
 def test_snap_refresh_error():
     err = errors.SnapRefreshError(snap_name="word-salad", snap_channel="stable")
     assert err.snap_name == "word-salad"
@@ -148,3 +156,168 @@ def test_snapd_connection_error():
     )
     assert err.details is None
     assert err.resolution is None
+
+
+def test_package_repository_key_not_found():
+    err = errors.PackageRepositoryKeyNotFound(
+        key_id="78E1918602959B9C59103100F1831DDAFC42E99D", keys_dir="snap/keys"
+    )
+    assert err.key_id == "78E1918602959B9C59103100F1831DDAFC42E99D"
+    assert err.keys_dir == "snap/keys"
+    assert err.brief == (
+        "Failed to install repository: key "
+        "'78E1918602959B9C59103100F1831DDAFC42E99D' not found."
+    )
+    assert err.details is None
+    assert err.resolution == "Add the ASCII-armored key to 'snap/keys' as FC42E99D.asc."
+
+
+def test_package_repositories_not_supported():
+    err = errors.PackageRepositoriesNotSupported(backend="ArchLinux")
+    assert err.backend == "ArchLinux"
+    assert err.brief == "Package repositories are not supported by ArchLinux."
+    assert err.details is None
+    assert err.resolution == "Remove the package repositories from the project."
+
+
+def test_overlay_packages_not_supported():
+    err = errors.OverlayPackagesNotSupported(backend="DummyRepository")
+    assert err.backend == "DummyRepository"
+    assert err.brief == (
+        "Installing overlay packages is not supported by DummyRepository."
+    )
+    assert err.details is None
+    assert err.resolution == "Install the packages using an overlay script."
+
+
+def test_invalid_chisel_release():
+    err = errors.InvalidChiselRelease("slices", message="chisel.yaml not found")
+    assert err.release == "slices"
+    assert err.message == "chisel.yaml not found"
+    assert err.brief == "Invalid chisel release 'slices': chisel.yaml not found."
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure the release is a directory containing chisel.yaml, or "
+        "a branch or commit of chisel-releases."
+    )
+
+
+def test_chisel_error():
+    err = errors.ChiselError(["libc6_libs", "base-files_base"], message="failed")
+    assert err.slices == ["libc6_libs", "base-files_base"]
+    assert err.message == "failed"
+    assert err.brief == "Failed to cut slices libc6_libs, base-files_base: failed."
+    assert err.details is None
+    assert err.resolution is None
+
+
+def test_invalid_packages_lockfile():
+    err = errors.InvalidPackagesLockfile("packages.lock", message="bad data")
+    assert err.filename == "packages.lock"
+    assert err.message == "bad data"
+    assert err.brief == "Failed to read lockfile 'packages.lock': bad data"
+    assert err.details is None
+    assert err.resolution == "Make sure the lockfile is valid or record it again."
+
+
+def test_stage_packages_lock_mismatch():
+    err = errors.StagePackagesLockMismatch(message="checksum mismatch for hello=2.10")
+    assert err.message == "checksum mismatch for hello=2.10"
+    assert err.brief == (
+        "Stage packages don't match the lockfile: checksum mismatch for hello=2.10."
+    )
+    assert err.details is None
+    assert err.resolution == "Record the lockfile again to update the locked packages."
+
+
+def test_package_lock_not_supported():
+    err = errors.PackageLockNotSupported(backend="DummyRepository")
+    assert err.backend == "DummyRepository"
+    assert err.brief == "Locking stage packages is not supported by DummyRepository."
+    assert err.details is None
+    assert err.resolution is None
+
+
+def test_stage_packages_option_not_supported():
+    err = errors.StagePackagesOptionNotSupported(
+        "stage-packages-suggests", backend="OpenSUSE"
+    )
+    assert err.option == "stage-packages-suggests"
+    assert err.backend == "OpenSUSE"
+    assert err.brief == (
+        "Stage packages option 'stage-packages-suggests' is not supported by "
+        "OpenSUSE."
+    )
+    assert err.details is None
+    assert err.resolution == (
+        "Remove 'stage-packages-suggests' from the part properties."
+    )
+
+
+def test_version_constraint_not_supported():
+    err = errors.VersionConstraintNotSupported("gcc (>= 9.3)", backend="Alpine")
+    assert err.package == "gcc (>= 9.3)"
+    assert err.backend == "Alpine"
+    assert err.brief == "Version constraint 'gcc (>= 9.3)' is not supported by Alpine."
+    assert err.details is None
+    assert err.resolution == (
+        "Use the package name, or pin an exact version as 'name=version'."
+    )
+
+
+def test_local_package_not_found():
+    err = errors.LocalPackageNotFound("debs/foo.deb")
+    assert err.path == "debs/foo.deb"
+    assert err.brief == "Local package not found: debs/foo.deb."
+    assert err.details is None
+    assert err.resolution == "Make sure the package file path is correct."
+
+
+def test_invalid_version_constraint():
+    err = errors.InvalidVersionConstraint("gcc (~ 9)")
+    assert err.package == "gcc (~ 9)"
+    assert err.brief == "Invalid version constraint: 'gcc (~ 9)'."
+    assert err.details is None
+    assert err.resolution == (
+        "Use a constraint such as 'name (>= version)', with one of the "
+        "relations <<, <=, =, >= or >>."
+    )
+
+
+def test_package_version_not_satisfied():
+    err = errors.PackageVersionNotSatisfied(
+        "gcc", constraint=">= 10", versions=["9.3.0-1", "9.4.0-1"]
+    )
+    assert err.package_name == "gcc"
+    assert err.constraint == ">= 10"
+    assert err.versions == ["9.3.0-1", "9.4.0-1"]
+    assert err.brief == "No version of 'gcc' satisfies '>= 10'."
+    assert err.details == "Available versions: 9.3.0-1, 9.4.0-1."
+    assert err.resolution is None
+
+
+def test_package_version_not_satisfied_no_versions():
+    err = errors.PackageVersionNotSatisfied("gcc", constraint=">= 10", versions=[])
+    assert err.details == "No versions are available."
+
+
+def test_invalid_snap_revision():
+    err = errors.InvalidSnapRevision("go=latest")
+    assert err.snap == "go=latest"
+    assert err.brief == "Invalid snap revision: 'go=latest'."
+    assert err.details is None
+    assert err.resolution == (
+        "Use '<snap-name>=<revision>', where the revision is a number."
+    )
+
+
+def test_snap_assertion_error():
+    err = errors.SnapAssertionError(
+        snap_name="go", snap_revision="10030", message="digest mismatch"
+    )
+    assert err.snap_name == "go"
+    assert err.snap_revision == "10030"
+    assert err.message == "digest mismatch"
+    assert err.brief == "Failed to verify snap 'go' revision 10030: digest mismatch."
+    assert err.details is None
+    assert err.resolution is None
@@ -0,0 +1,235 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import hashlib
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import errors
+from craft_parts.packages.base import DummyRepository
+from craft_parts.packages.lockfile import (
+    LockedPackage,
+    PackagesLockfile,
+    get_part_packages,
+    lock_stage_packages,
+    record_part_packages,
+    replay_stage_packages,
+)
+
+
+def _sha256(data: str) -> str:
+    return hashlib.sha256(data.encode()).hexdigest()
+
+
+class FakeRepository(DummyRepository):
+    """A repository that fetches packages named after their versions."""
+
+    contents = {"hello=2.10-2": "hello", "libc6=2.31-0ubuntu9": "libc6"}
+    fetched = ["hello=2.10-2", "libc6=2.31-0ubuntu9"]
+
+    @classmethod
+    def fetch_stage_packages(cls, **kwargs):
+        for spec in cls.fetched:
+            Path(kwargs["stage_packages_path"], f"{spec}.pkg").write_text(
+                cls.contents[spec]
+            )
+        return cls.fetched
+
+    @classmethod
+    def get_package_file_name_version(cls, package_path):
+        return package_path.name[: -len(".pkg")]
+
+
+@pytest.fixture
+def locked():
+    return [
+        LockedPackage(name="hello", version="2.10-2", sha256=_sha256("hello")),
+        LockedPackage(name="libc6", version="2.31-0ubuntu9", sha256=_sha256("libc6")),
+    ]
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPackagesLockfile:
+    """Verify reading and writing lockfiles."""
+
+    def test_marshal_unmarshal(self):
+        data = {
+            "stage-packages": {
+                "foo": [{"name": "hello", "version": "2.10-2", "sha256": "1234"}]
+            }
+        }
+
+        lockfile = PackagesLockfile.unmarshal(data)
+        assert lockfile.marshal() == data
+
+    def test_unmarshal_not_dict(self):
+        with pytest.raises(TypeError) as raised:
+            PackagesLockfile.unmarshal(False)  # type: ignore
+        assert str(raised.value) == "lockfile data is not a dictionary"
+
+    def test_write_read(self, locked):
+        lockfile = PackagesLockfile.unmarshal({"stage-packages": {"foo": locked}})
+
+        lockfile.write(Path("lock/packages.lock"))
+        new_lockfile = PackagesLockfile.read(Path("lock/packages.lock"))
+
+        assert new_lockfile == lockfile
+
+    def test_read_invalid(self):
+        Path("packages.lock").write_text("stage-packages: [1]\n")
+
+        with pytest.raises(errors.InvalidPackagesLockfile) as raised:
+            PackagesLockfile.read(Path("packages.lock"))
+        assert raised.value.filename == "packages.lock"
+
+    def test_read_missing(self):
+        with pytest.raises(errors.InvalidPackagesLockfile):
+            PackagesLockfile.read(Path("packages.lock"))
+
+
+def test_lock_stage_packages(new_dir, locked):
+    Path("stage").mkdir()
+    Path("stage/libc6=2.31-0ubuntu9.pkg").write_text("libc6")
+    Path("stage/hello=2.10-2.pkg").write_text("hello")
+    Path("stage/partial").mkdir()
+
+    packages = lock_stage_packages(
+        repository=FakeRepository, stage_packages_path=Path("stage")
+    )
+
+    assert packages == locked
+
+
+def test_lock_stage_packages_not_supported(new_dir):
+    Path("stage").mkdir()
+    Path("stage/hello.pkg").touch()
+
+    with pytest.raises(errors.PackageLockNotSupported) as raised:
+        lock_stage_packages(
+            repository=DummyRepository, stage_packages_path=Path("stage")
+        )
+    assert raised.value.backend == "DummyRepository"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPartPackages:
+    """Verify recording and reading the locked packages of a part."""
+
+    def test_record(self, locked):
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked)
+        record_part_packages(Path("packages.lock"), part_name="p2", locked=locked[:1])
+
+        lockfile = PackagesLockfile.read(Path("packages.lock"))
+        assert lockfile.stage_packages == {"p1": locked, "p2": locked[:1]}
+
+    def test_record_replace(self, locked):
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked)
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked[1:])
+
+        lockfile = PackagesLockfile.read(Path("packages.lock"))
+        assert lockfile.stage_packages == {"p1": locked[1:]}
+
+    def test_record_empty(self, locked):
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked)
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=[])
+
+        lockfile = PackagesLockfile.read(Path("packages.lock"))
+        assert lockfile.stage_packages == {}
+
+    def test_get(self, locked):
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked)
+
+        packages = get_part_packages(
+            Path("packages.lock"), part_name="p1", package_names=["hello=2.10-2"]
+        )
+        assert packages == locked
+
+    def test_get_part_not_locked(self, locked):
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked)
+
+        with pytest.raises(errors.StagePackagesLockMismatch) as raised:
+            get_part_packages(
+                Path("packages.lock"), part_name="p2", package_names=["hello"]
+            )
+        assert raised.value.message == "part 'p2' is not in the lockfile"
+
+    def test_get_package_not_locked(self, locked):
+        record_part_packages(Path("packages.lock"), part_name="p1", locked=locked)
+
+        with pytest.raises(errors.StagePackagesLockMismatch) as raised:
+            get_part_packages(
+                Path("packages.lock"), part_name="p1", package_names=["hello", "make"]
+            )
+        assert raised.value.message == "packages not locked: make"
+
+
+class TestReplayStagePackages:
+    """Verify that replaying a lockfile fetches exactly the locked packages."""
+
+    @pytest.fixture(autouse=True)
+    def setup_method_fixture(self, new_dir, mocker):
+        Path("stage").mkdir()
+        mocker.patch.object(
+            FakeRepository, "fetched", ["hello=2.10-2", "libc6=2.31-0ubuntu9"]
+        )
+        mocker.patch.object(
+            FakeRepository,
+            "contents",
+            {"hello=2.10-2": "hello", "libc6=2.31-0ubuntu9": "libc6"},
+        )
+
+    def _replay(self, locked):
+        return replay_stage_packages(
+            locked,
+            repository=FakeRepository,
+            application_name="test",
+            stage_packages_path=Path("stage"),
+            base="core20",
+            target_arch="amd64",
+        )
+
+    def test_replay(self, locked):
+        assert self._replay(locked) == ["hello=2.10-2", "libc6=2.31-0ubuntu9"]
+
+    def test_replay_empty(self):
+        assert self._replay([]) == []
+
+    def test_replay_unlocked_dependency(self, locked, mocker):
+        mocker.patch.object(
+            FakeRepository, "fetched", ["hello=2.10-2", "libc6=2.31-0ubuntu9", "z=1"]
+        )
+        FakeRepository.contents["z=1"] = "z"
+
+        with pytest.raises(errors.StagePackagesLockMismatch) as raised:
+            self._replay(locked)
+        assert raised.value.message == "packages not in the lockfile: z=1"
+
+    def test_replay_missing_package(self, locked, mocker):
+        mocker.patch.object(FakeRepository, "fetched", ["hello=2.10-2"])
+
+        with pytest.raises(errors.StagePackagesLockMismatch) as raised:
+            self._replay(locked)
+        assert raised.value.message == (
+            "locked packages not fetched: libc6=2.31-0ubuntu9"
+        )
+
+    def test_replay_checksum_mismatch(self, locked):
+        FakeRepository.contents["hello=2.10-2"] = "modified"
+
+        with pytest.raises(errors.StagePackagesLockMismatch) as raised:
+            self._replay(locked)
+        assert raised.value.message == "checksum mismatch for hello=2.10-2"
@@ -0,0 +1,118 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import errors
+from craft_parts.packages.apk import Alpine
+from craft_parts.packages.dnf import DNFRepository
+from craft_parts.packages.package_manager import PackageManagerRepository
+from craft_parts.packages.pacman import ArchLinux
+from craft_parts.packages.zypper import OpenSUSE
+
+
+class TestPackageManagerRepository:
+    """Verify the package manager repository base class."""
+
+    def test_abstract_methods(self):
+        assert PackageManagerRepository.__abstractmethods__ == {  # type: ignore
+            "get_installed_packages",
+            "get_package_libraries",
+            "refresh_build_packages_list",
+            "refresh_stage_packages_list",
+            "_get_install_command",
+            "_list_build_packages",
+            "_query_installed_packages",
+            "_list_stage_packages",
+            "_get_download_command",
+            "_extract_package",
+        }
+
+    @pytest.mark.parametrize(
+        "repository,source_type,packages",
+        [
+            (DNFRepository, "hg", {"mercurial"}),
+            (DNFRepository, "rpm2cpio", {"rpm", "cpio"}),
+            (OpenSUSE, "svn", {"subversion"}),
+            (Alpine, "rpm2cpio", {"rpm2cpio", "cpio"}),
+            (ArchLinux, "bzr", {"breezy"}),
+            (ArchLinux, "rpm2cpio", {"rpmextract", "cpio"}),
+            (ArchLinux, "git", {"git"}),
+            (ArchLinux, "unknown", set()),
+        ],
+    )
+    def test_get_packages_for_source_type(self, repository, source_type, packages):
+        assert repository.get_packages_for_source_type(source_type) == packages
+
+    @pytest.mark.parametrize(
+        "repository,options,option",
+        [
+            (DNFRepository, {"recommends": True}, "stage-packages-recommends"),
+            (Alpine, {"suggests": True}, "stage-packages-suggests"),
+            (
+                OpenSUSE,
+                {"recommends": True, "suggests": True},
+                "stage-packages-suggests",
+            ),
+        ],
+    )
+    def test_fetch_stage_packages_option_not_supported(
+        self, new_dir, repository, options, option
+    ):
+        with pytest.raises(errors.StagePackagesOptionNotSupported) as raised:
+            repository.fetch_stage_packages(
+                application_name="test",
+                package_names=["hello"],
+                stage_packages_path=Path("stage"),
+                base="base",
+                target_arch="amd64",
+                **options,
+            )
+        assert raised.value.option == option
+        assert raised.value.backend == repository.__name__
+
+    @pytest.mark.parametrize("repository", [DNFRepository, Alpine, ArchLinux, OpenSUSE])
+    def test_fetch_stage_packages_version_constraint(self, new_dir, repository):
+        with pytest.raises(errors.VersionConstraintNotSupported) as raised:
+            repository.fetch_stage_packages(
+                application_name="test",
+                package_names=["hello", "libfoo (>= 1.2)"],
+                stage_packages_path=Path("stage"),
+                base="base",
+                target_arch="amd64",
+            )
+        assert raised.value.package == "libfoo (>= 1.2)"
+        assert raised.value.backend == repository.__name__
+
+    @pytest.mark.parametrize("repository", [DNFRepository, Alpine, ArchLinux, OpenSUSE])
+    def test_install_build_packages_version_constraint(self, repository):
+        with pytest.raises(errors.VersionConstraintNotSupported) as raised:
+            repository.install_build_packages(["gcc (>= 9.3)"], list_only=True)
+        assert raised.value.package == "gcc (>= 9.3)"
+        assert raised.value.backend == repository.__name__
+
+    def test_get_overlay_install_commands(self):
+        assert DNFRepository.get_overlay_install_commands(["hello", "curl"]) == [
+            ["dnf", "install", "-y", "curl", "hello"]
+        ]
+
+    @pytest.mark.parametrize("repository", [DNFRepository, Alpine, ArchLinux, OpenSUSE])
+    def test_get_overlay_install_commands_version_constraint(self, repository):
+        with pytest.raises(errors.VersionConstraintNotSupported) as raised:
+            repository.get_overlay_install_commands(["gcc (>= 9.3)"])
+        assert raised.value.package == "gcc (>= 9.3)"
@@ -0,0 +1,163 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts import packages
+from craft_parts.bases import Base
+from craft_parts.errors import UnsupportedBase
+from craft_parts.packages import get_repository_for_platform
+from craft_parts.packages.apk import Alpine
+from craft_parts.packages.base import DummyRepository
+from craft_parts.packages.deb import Ubuntu
+from craft_parts.packages.dnf import DNFRepository
+from craft_parts.packages.pacman import ArchLinux
+from craft_parts.packages.zypper import OpenSUSE
+from craft_parts.utils.os_utils import OsRelease
+
+
+@pytest.fixture(autouse=True)
+def teardown_backends():
+    yield
+    packages.unregister_all()
+
+
+class FakeBackend(DummyRepository):
+    """A package backend registered by an application."""
+
+
+@pytest.mark.parametrize(
+    "os_release,repository",
+    [
+        ('ID=ubuntu\nVERSION_ID="20.04"\n', Ubuntu),
+        ("ID=debian\n", Ubuntu),
+        ('ID=linuxmint\nID_LIKE="ubuntu debian"\n', Ubuntu),
+        ("ID=fedora\n", DNFRepository),
+        ('ID="centos"\nID_LIKE="rhel fedora"\n', DNFRepository),
+        ('ID="almalinux"\nID_LIKE="rhel centos fedora"\n', DNFRepository),
+        ("ID=alpine\nVERSION_ID=3.14.2\n", Alpine),
+        ("ID=arch\n", ArchLinux),
+        ("ID=manjaro\nID_LIKE=arch\n", ArchLinux),
+        ('ID="opensuse-leap"\nID_LIKE="suse opensuse"\n', OpenSUSE),
+        ('ID="sles"\nID_LIKE="suse"\n', OpenSUSE),
+        ("ID=gentoo\n", DummyRepository),
+        ("NAME=Unknown\n", DummyRepository),
+    ],
+)
+def test_get_repository_for_platform(new_dir, os_release, repository):
+    Path("os-release").write_text(os_release)
+
+    os_release_info = OsRelease(os_release_file="os-release")
+    assert get_repository_for_platform(os_release_info) is repository
+
+
+@pytest.mark.parametrize("sys_platform", ["darwin", "win32"])
+def test_get_repository_for_platform_non_linux(mocker, sys_platform):
+    mocker.patch("sys.platform", sys_platform)
+    mock_os_release = mocker.patch("craft_parts.bases.OsRelease")
+
+    assert get_repository_for_platform() is DummyRepository
+    mock_os_release.assert_not_called()
+
+
+@pytest.mark.parametrize(
+    "base,repository",
+    [
+        (Base("ubuntu", "22.04"), Ubuntu),
+        (Base("linuxmint", "21", like=("ubuntu", "debian")), Ubuntu),
+        (Base("fedora", "39"), DNFRepository),
+        (Base("alpine", "3.19"), Alpine),
+        (Base("arch"), ArchLinux),
+        (Base("opensuse-leap", "15.5", like=("suse", "opensuse")), OpenSUSE),
+        (None, DummyRepository),
+    ],
+)
+def test_get_repository_for_base(base, repository):
+    assert packages.get_repository_for_base(base) is repository
+
+
+def test_get_repository_for_base_unsupported():
+    with pytest.raises(UnsupportedBase) as raised:
+        packages.get_repository_for_base(Base("gentoo", "2.14"))
+    assert raised.value.base == "gentoo@2.14"
+
+
+def test_get_repository_for_base_registered():
+    packages.register({"gentoo": FakeBackend})
+
+    assert packages.get_repository_for_base(Base("gentoo")) is FakeBackend
+
+
+@pytest.mark.parametrize(
+    "repository,names",
+    [
+        (Ubuntu, ["g++", "python3-dev=3.10", "make"]),
+        (DNFRepository, ["gcc-c++", "python3-devel=3.10", "make"]),
+        (OpenSUSE, ["gcc-c++", "python3-devel=3.10", "make"]),
+        (Alpine, ["g++", "python3-dev=3.10", "make"]),
+        (ArchLinux, ["gcc", "python=3.10", "make"]),
+    ],
+)
+def test_get_package_name(repository, names):
+    assert [
+        repository.get_package_name(name)
+        for name in ["g++", "python3-dev=3.10", "make"]
+    ] == names
+
+
+class TestRegisterBackends:
+    """Verify the registration of package backends."""
+
+    def test_register(self):
+        packages.register({"gentoo": FakeBackend})
+
+        assert packages.get_registered_backends() == {"gentoo": FakeBackend}
+
+    def test_unregister_all(self):
+        packages.register({"gentoo": FakeBackend})
+        packages.unregister_all()
+
+        assert packages.get_registered_backends() == {}
+
+    @pytest.mark.parametrize(
+        "os_release",
+        [
+            "ID=gentoo\n",
+            'ID=funtoo\nID_LIKE="gentoo"\n',
+        ],
+    )
+    def test_registered_backend(self, new_dir, os_release):
+        Path("os-release").write_text(os_release)
+        packages.register({"gentoo": FakeBackend})
+
+        os_release_info = OsRelease(os_release_file="os-release")
+        assert get_repository_for_platform(os_release_info) is FakeBackend
+
+    def test_registered_backend_overrides_builtin(self, new_dir):
+        Path("os-release").write_text("ID=ubuntu\n")
+        packages.register({"ubuntu": FakeBackend})
+
+        os_release_info = OsRelease(os_release_file="os-release")
+        assert get_repository_for_platform(os_release_info) is FakeBackend
+
+    def test_distribution_id_precedence(self, new_dir):
+        Path("os-release").write_text('ID=pop\nID_LIKE="ubuntu debian"\n')
+        packages.register({"debian": DummyRepository, "pop": FakeBackend})
+
+        os_release_info = OsRelease(os_release_file="os-release")
+        assert get_repository_for_platform(os_release_info) is FakeBackend
@@ -0,0 +1,225 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import errors, pacman
+
+# pylint: disable=missing-class-docstring
+
+_STAGE_OPTIONS = ["--dbpath=/cache", "--arch=x86_64"]
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch(
+        "craft_parts.packages.pacman.BaseDirectory.save_cache_path",
+        return_value="/cache",
+    )
+
+
+@pytest.fixture
+def fake_check_call(mocker):
+    return mocker.patch("subprocess.check_call")
+
+
+@pytest.fixture
+def fake_pacman_query(mocker):
+    installed = {"bash": "5.1.008-1", "glibc": "2.33-5"}
+
+    def pacman_query(cmd, **kwargs):
+        names = cmd[2:] or sorted(installed)
+        # packages that are not installed are reported in the standard error
+        stdout = "\n".join(f"{n} {installed[n]}" for n in names if n in installed)
+        return subprocess.CompletedProcess(cmd, 0, stdout=stdout.encode())
+
+    return mocker.patch("subprocess.run", side_effect=pacman_query)
+
+
+class TestBuildPackages:
+    def test_refresh_build_packages_list(self, fake_check_call):
+        pacman.ArchLinux.refresh_build_packages_list()
+
+        fake_check_call.assert_called_once_with(
+            ["sudo", "--preserve-env", "pacman", "--sync", "--refresh"]
+        )
+
+    def test_refresh_build_packages_list_error(self, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageListRefreshError):
+            pacman.ArchLinux.refresh_build_packages_list()
+
+    def test_install_build_packages(self, fake_check_call, fake_pacman_query):
+        installed = pacman.ArchLinux.install_build_packages(["make", "bash"])
+
+        fake_check_call.assert_called_once_with(
+            [
+                "sudo",
+                "--preserve-env",
+                "pacman",
+                "--sync",
+                "--needed",
+                "--noconfirm",
+                "bash",
+                "make",
+            ]
+        )
+        assert installed == ["bash=5.1.008-1"]
+
+    def test_install_build_packages_already_installed(
+        self, fake_check_call, fake_pacman_query
+    ):
+        installed = pacman.ArchLinux.install_build_packages(
+            ["bash=5.1.008-1", "glibc"]
+        )
+
+        fake_check_call.assert_not_called()
+        assert installed == ["bash=5.1.008-1", "glibc=2.33-5"]
+
+    def test_install_build_packages_list_only(self, mocker, fake_check_call):
+        fake_output = mocker.patch(
+            "subprocess.check_output", return_value=b"make=4.3-3\nguile=2.2.7-1\n"
+        )
+
+        installed = pacman.ArchLinux.install_build_packages(["make"], list_only=True)
+
+        fake_check_call.assert_not_called()
+        assert fake_output.mock_calls[0].args[0] == [
+            "pacman",
+            "--sync",
+            "--print",
+            "--print-format=%n=%v",
+            "make",
+        ]
+        assert installed == ["guile=2.2.7-1", "make=4.3-3"]
+
+    def test_install_build_packages_error(self, fake_check_call, fake_pacman_query):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.BuildPackagesNotInstalled) as raised:
+            pacman.ArchLinux.install_build_packages(["make"])
+        assert raised.value.packages == ["make"]
+
+    def test_is_package_installed(self, fake_pacman_query):
+        assert pacman.ArchLinux.is_package_installed("bash") is True
+        assert pacman.ArchLinux.is_package_installed("make") is False
+
+    def test_get_installed_packages(self, fake_pacman_query):
+        assert pacman.ArchLinux.get_installed_packages() == [
+            "bash=5.1.008-1",
+            "glibc=2.33-5",
+        ]
+
+
+class TestStagePackages:
+    def test_refresh_stage_packages_list(self, fake_check_call):
+        pacman.ArchLinux.refresh_stage_packages_list(
+            application_name="test", target_arch="amd64"
+        )
+
+        fake_check_call.assert_called_once_with(
+            ["pacman", "--sync", "--refresh", *_STAGE_OPTIONS]
+        )
+
+    def test_fetch_stage_packages(self, new_dir, mocker):
+        def fake_download(cmd):
+            cache_dir = [c for c in cmd if c.startswith("--cachedir=")][0][11:]
+            Path(cache_dir, "zlib-1:1.2.11-4-x86_64.pkg.tar.zst").touch()
+            Path(cache_dir, "glibc-2.33-5-x86_64.pkg.tar.zst").touch()
+
+        fake_check_call = mocker.patch("subprocess.check_call")
+        fake_check_call.side_effect = fake_download
+
+        fetched = pacman.ArchLinux.fetch_stage_packages(
+            application_name="test",
+            package_names=["zlib"],
+            stage_packages_path=Path("stage"),
+            base="arch",
+            target_arch="amd64",
+        )
+
+        assert fetched == ["glibc=2.33-5", "zlib=1:1.2.11-4"]
+        assert sorted(p.name for p in Path("stage").iterdir()) == [
+            "glibc-2.33-5-x86_64.pkg.tar.zst",
+            "zlib-1:1.2.11-4-x86_64.pkg.tar.zst",
+        ]
+        cmd = fake_check_call.mock_calls[0].args[0]
+        assert cmd[:6] == [
+            "pacman",
+            "--sync",
+            "--downloadonly",
+            "--noconfirm",
+            *_STAGE_OPTIONS,
+        ]
+        assert cmd[-1] == "zlib"
+
+    def test_fetch_stage_packages_list_only(self, new_dir, mocker):
+        fake_output = mocker.patch(
+            "subprocess.check_output", return_value=b"zlib=1:1.2.11-4\n"
+        )
+
+        fetched = pacman.ArchLinux.fetch_stage_packages(
+            application_name="test",
+            package_names=["zlib"],
+            stage_packages_path=Path("stage"),
+            base="arch",
+            target_arch="amd64",
+            list_only=True,
+        )
+
+        assert fetched == ["zlib=1:1.2.11-4"]
+        assert fake_output.mock_calls[0].args[0][4:] == [*_STAGE_OPTIONS, "zlib"]
+        assert Path("stage").exists() is False
+
+    def test_fetch_stage_packages_error(self, new_dir, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageFetchError):
+            pacman.ArchLinux.fetch_stage_packages(
+                application_name="test",
+                package_names=["zlib"],
+                stage_packages_path=Path("stage"),
+                base="arch",
+                target_arch="amd64",
+            )
+
+    def test_unpack_stage_packages(self, new_dir, mocker):
+        def fake_tar(cmd):
+            directory = cmd[-1][len("--directory=") :]
+            Path(directory, "usr/lib").mkdir(parents=True)
+            Path(directory, "usr/lib/libz.so.1").write_text("libz")
+            Path(directory, ".PKGINFO").write_text("pkgname = zlib")
+            Path(directory, ".MTREE").write_text("mtree")
+
+        mocker.patch("subprocess.check_call", side_effect=fake_tar)
+        fake_mark = mocker.patch(
+            "craft_parts.packages.package_manager.mark_origin_stage_package"
+        )
+        Path("stage").mkdir()
+        Path("stage/zlib-1:1.2.11-4-x86_64.pkg.tar.zst").touch()
+        Path("install").mkdir()
+
+        pacman.ArchLinux.unpack_stage_packages(
+            stage_packages_path=Path("stage"), install_path=Path("install")
+        )
+
+        assert [p.name for p in Path("install").iterdir()] == ["usr"]
+        assert Path("install/usr/lib/libz.so.1").read_text() == "libz"
+        assert fake_mark.mock_calls[0].args[1] == "zlib=1:1.2.11-4"
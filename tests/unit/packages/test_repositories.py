@@ -0,0 +1,555 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pydantic
+import pytest
+
+from craft_parts.packages import errors
+from craft_parts.packages.repositories import (
+    ApkRepository,
+    AptCloudArchive,
+    AptRepository,
+    RpmRepository,
+    get_mirrorlist_content,
+    get_preferences_content,
+    get_repo_content,
+    get_sources_content,
+    install_apk_repository,
+    install_cloud_archive,
+    install_mirrorlist,
+    install_repository,
+    install_rpm_repository,
+)
+
+_KEY_ID = "78E1918602959B9C59103100F1831DDAFC42E99D"
+
+_KEY = """-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mDMEYJ2hHBYJKwYBBAHaRw8BAQdA
+=T3ZP
+-----END PGP PUBLIC KEY BLOCK-----
+"""
+
+_SOURCES = """Types: deb deb-src
+URIs: http://ppa.launchpad.net/snappy-dev/snapcraft-daily/ubuntu
+Suites: focal focal-updates
+Components: main
+Architectures: amd64 i386
+Signed-By:
+ -----BEGIN PGP PUBLIC KEY BLOCK-----
+ .
+ mDMEYJ2hHBYJKwYBBAHaRw8BAQdA
+ =T3ZP
+ -----END PGP PUBLIC KEY BLOCK-----
+"""
+
+
+@pytest.fixture
+def repository():
+    return AptRepository.unmarshal(
+        {
+            "formats": ["deb", "deb-src"],
+            "url": "http://ppa.launchpad.net/snappy-dev/snapcraft-daily/ubuntu",
+            "suites": ["focal", "focal-updates"],
+            "components": ["main"],
+            "architectures": ["amd64", "i386"],
+            "key-id": _KEY_ID,
+        }
+    )
+
+
+class TestAptRepository:
+    """Verify the apt repository definition."""
+
+    def test_marshal_unmarshal(self):
+        data = {
+            "formats": ["deb"],
+            "url": "http://archive.example.com/ubuntu",
+            "suites": ["focal"],
+            "components": ["main", "universe"],
+            "path": None,
+            "architectures": [],
+            "key-id": _KEY_ID,
+            "priority": None,
+        }
+
+        repository = AptRepository.unmarshal(data)
+        assert repository.marshal() == data
+
+    def test_unmarshal_not_dict(self):
+        with pytest.raises(TypeError) as raised:
+            AptRepository.unmarshal(False)  # type: ignore
+        assert str(raised.value) == "repository data is not a dictionary"
+
+    @pytest.mark.parametrize(
+        "data,message",
+        [
+            ({"formats": ["rpm"]}, "invalid format 'rpm', must be 'deb' or 'deb-src'"),
+            ({"suites": []}, "suites and components are required without path"),
+            ({"components": []}, "suites and components are required without path"),
+            ({"path": "/"}, "suites and components cannot be used with path"),
+            (
+                {"key-id": "FC42E99D"},
+                "key ID must be a 40-character uppercase fingerprint",
+            ),
+            ({"priority": 0}, "priority cannot be zero"),
+        ],
+    )
+    def test_unmarshal_invalid(self, data, message):
+        repository_data = {
+            "url": "http://archive.example.com/ubuntu",
+            "suites": ["focal"],
+            "components": ["main"],
+            "key-id": _KEY_ID,
+            **data,
+        }
+
+        with pytest.raises(pydantic.ValidationError) as raised:
+            AptRepository.unmarshal(repository_data)
+        assert raised.value.errors()[0]["msg"] == message
+
+
+def test_get_sources_content(repository):
+    assert get_sources_content(repository, key=_KEY) == _SOURCES
+
+
+def test_get_sources_content_default_architectures():
+    repository = AptRepository.unmarshal(
+        {
+            "url": "http://archive.example.com/ubuntu",
+            "suites": ["focal"],
+            "components": ["main"],
+            "key-id": _KEY_ID,
+        }
+    )
+
+    content = get_sources_content(repository, key=_KEY)
+
+    assert content.startswith(
+        "Types: deb\n"
+        "URIs: http://archive.example.com/ubuntu\n"
+        "Suites: focal\n"
+        "Components: main\n"
+        "Signed-By:\n"
+    )
+
+
+def test_get_sources_content_flat_repository():
+    repository = AptRepository.unmarshal(
+        {"url": "http://vendor.example.com/debs", "path": "stable", "key-id": _KEY_ID}
+    )
+
+    content = get_sources_content(repository, key=_KEY)
+
+    assert content.startswith(
+        "Types: deb\n"
+        "URIs: http://vendor.example.com/debs\n"
+        "Suites: stable/\n"
+        "Signed-By:\n"
+    )
+
+
+@pytest.mark.parametrize(
+    "url,origin",
+    [
+        ("http://archive.example.com/ubuntu", "archive.example.com"),
+        ("file:///srv/debs", ""),
+    ],
+)
+def test_get_preferences_content(url, origin):
+    repository = AptRepository.unmarshal(
+        {"url": url, "path": "/", "key-id": _KEY_ID, "priority": 990}
+    )
+
+    assert get_preferences_content(repository) == (
+        f'Package: *\nPin: origin "{origin}"\nPin-Priority: 990\n'
+    )
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestInstallRepository:
+    """Verify the installation of repository sources files."""
+
+    @pytest.mark.parametrize("key_name", [_KEY_ID, "FC42E99D"])
+    def test_install(self, repository, key_name):
+        Path("keys").mkdir()
+        Path("keys", f"{key_name}.asc").write_text(_KEY)
+
+        changed = install_repository(
+            repository, name="snapcraft", keys_dir=Path("keys"), sources_dir=Path("d")
+        )
+
+        assert changed is True
+        assert Path("d/craft-snapcraft.sources").read_text() == _SOURCES
+
+    def test_install_unchanged(self, repository):
+        Path("keys").mkdir()
+        Path("keys/FC42E99D.asc").write_text(_KEY)
+        Path("d").mkdir()
+        Path("d/craft-snapcraft.sources").write_text(_SOURCES)
+
+        changed = install_repository(
+            repository, name="snapcraft", keys_dir=Path("keys"), sources_dir=Path("d")
+        )
+
+        assert changed is False
+
+    def test_install_removes_legacy_list(self, repository):
+        Path("keys").mkdir()
+        Path("keys/FC42E99D.asc").write_text(_KEY)
+        Path("d").mkdir()
+        Path("d/craft-snapcraft.sources").write_text(_SOURCES)
+        Path("d/craft-snapcraft.list").write_text("deb http://example.com focal main")
+
+        changed = install_repository(
+            repository, name="snapcraft", keys_dir=Path("keys"), sources_dir=Path("d")
+        )
+
+        assert changed is True
+        assert Path("d/craft-snapcraft.list").exists() is False
+
+    def test_install_key_not_found(self, repository):
+        with pytest.raises(errors.PackageRepositoryKeyNotFound) as raised:
+            install_repository(
+                repository,
+                name="snapcraft",
+                keys_dir=Path("keys"),
+                sources_dir=Path("d"),
+            )
+
+        assert raised.value.key_id == _KEY_ID
+        assert raised.value.keys_dir == "keys"
+        assert Path("d").exists() is False
+
+    def test_install_priority(self, repository):
+        Path("keys").mkdir()
+        Path("keys/FC42E99D.asc").write_text(_KEY)
+        pinned = AptRepository.unmarshal({**repository.marshal(), "priority": 1000})
+
+        changed = install_repository(
+            pinned,
+            name="snapcraft",
+            keys_dir=Path("keys"),
+            sources_dir=Path("d"),
+            preferences_dir=Path("p"),
+        )
+
+        assert changed is True
+        assert Path("p/craft-snapcraft.pref").read_text() == (
+            'Package: *\nPin: origin "ppa.launchpad.net"\nPin-Priority: 1000\n'
+        )
+
+    def test_install_removes_priority(self, repository):
+        Path("keys").mkdir()
+        Path("keys/FC42E99D.asc").write_text(_KEY)
+        Path("d").mkdir()
+        Path("d/craft-snapcraft.sources").write_text(_SOURCES)
+        Path("p").mkdir()
+        Path("p/craft-snapcraft.pref").write_text("Pin-Priority: 1000\n")
+
+        changed = install_repository(
+            repository,
+            name="snapcraft",
+            keys_dir=Path("keys"),
+            sources_dir=Path("d"),
+            preferences_dir=Path("p"),
+        )
+
+        assert changed is True
+        assert Path("p/craft-snapcraft.pref").exists() is False
+
+
+class TestAptCloudArchive:
+    """Verify the Ubuntu Cloud Archive definition."""
+
+    def test_marshal_unmarshal(self):
+        data = {"cloud": "caracal", "pocket": "proposed", "priority": None}
+
+        cloud_archive = AptCloudArchive.unmarshal(data)
+        assert cloud_archive.marshal() == data
+
+    @pytest.mark.parametrize(
+        "data,message",
+        [
+            ({"cloud": "cloud-archive:yoga"}, "invalid cloud 'cloud-archive:yoga'"),
+            (
+                {"cloud": "caracal", "pocket": "security"},
+                "invalid pocket 'security', must be 'updates' or 'proposed'",
+            ),
+        ],
+    )
+    def test_unmarshal_invalid(self, data, message):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            AptCloudArchive.unmarshal(data)
+        assert raised.value.errors()[0]["msg"] == message
+
+    def test_get_repository(self):
+        cloud_archive = AptCloudArchive.unmarshal({"cloud": "caracal", "priority": 500})
+
+        repository = cloud_archive.get_repository(series="jammy")
+
+        assert repository.url == "http://ubuntu-cloud.archive.canonical.com/ubuntu"
+        assert repository.suites == ["jammy-updates/caracal"]
+        assert repository.components == ["main"]
+        assert repository.key_id == "391A9AA2147192839E9DB0315EDB1B62EC4926EA"
+        assert repository.priority == 500
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_install_cloud_archive():
+    Path("keys").mkdir()
+    Path("keys/EC4926EA.asc").write_text(_KEY)
+    cloud_archive = AptCloudArchive.unmarshal({"cloud": "caracal"})
+
+    changed = install_cloud_archive(
+        cloud_archive,
+        name="openstack",
+        keys_dir=Path("keys"),
+        series="jammy",
+        sources_dir=Path("d"),
+        preferences_dir=Path("p"),
+    )
+
+    assert changed is True
+    assert Path("d/craft-openstack.sources").read_text().startswith(
+        "Types: deb\n"
+        "URIs: http://ubuntu-cloud.archive.canonical.com/ubuntu\n"
+        "Suites: jammy-updates/caracal\n"
+        "Components: main\n"
+    )
+    assert Path("p").exists() is False
+
+
+_REPO = """[craft-tools]
+name=craft-tools
+baseurl=https://rpm.example.com/fedora/$releasever/$basearch
+enabled=1
+gpgcheck=1
+repo_gpgcheck=0
+gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-craft-tools
+"""
+
+
+@pytest.fixture
+def rpm_repository():
+    return RpmRepository.unmarshal(
+        {
+            "url": "https://rpm.example.com/fedora/$releasever/$basearch",
+            "key-id": _KEY_ID,
+        }
+    )
+
+
+class TestRpmRepository:
+    """Verify the rpm repository definition."""
+
+    def test_marshal_unmarshal(self):
+        data = {"url": "https://rpm.example.com/el/8", "key-id": _KEY_ID}
+
+        repository = RpmRepository.unmarshal(data)
+        assert repository.marshal() == data
+
+    def test_unmarshal_not_dict(self):
+        with pytest.raises(TypeError) as raised:
+            RpmRepository.unmarshal(False)  # type: ignore
+        assert str(raised.value) == "repository data is not a dictionary"
+
+    def test_unmarshal_invalid_key_id(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            RpmRepository.unmarshal(
+                {"url": "https://rpm.example.com/el/8", "key-id": "FC42E99D"}
+            )
+        assert raised.value.errors()[0]["msg"] == (
+            "key ID must be a 40-character uppercase fingerprint"
+        )
+
+
+def test_get_repo_content(rpm_repository):
+    key_file = Path("/etc/pki/rpm-gpg/RPM-GPG-KEY-craft-tools")
+    content = get_repo_content(rpm_repository, name="tools", key_file=key_file)
+
+    assert content == _REPO
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestInstallRpmRepository:
+    """Verify the installation of rpm repository files."""
+
+    def test_install(self, rpm_repository):
+        Path("keys").mkdir()
+        Path("keys/FC42E99D.asc").write_text(_KEY)
+
+        changed = install_rpm_repository(
+            rpm_repository,
+            name="tools",
+            keys_dir=Path("keys"),
+            repos_dir=Path("repos"),
+            gpg_dir=Path("gpg"),
+        )
+
+        assert changed is True
+        assert Path("gpg/RPM-GPG-KEY-craft-tools").read_text() == _KEY
+        assert Path("repos/craft-tools.repo").read_text() == (
+            _REPO.replace("file:///etc/pki/rpm-gpg/", "file://gpg/")
+        )
+
+    def test_install_unchanged(self, rpm_repository):
+        Path("keys").mkdir()
+        Path("keys/FC42E99D.asc").write_text(_KEY)
+        kwargs = {
+            "name": "tools",
+            "keys_dir": Path("keys"),
+            "repos_dir": Path("repos"),
+            "gpg_dir": Path("gpg"),
+        }
+        install_rpm_repository(rpm_repository, **kwargs)
+
+        changed = install_rpm_repository(rpm_repository, **kwargs)
+
+        assert changed is False
+
+    def test_install_key_not_found(self, rpm_repository):
+        with pytest.raises(errors.PackageRepositoryKeyNotFound) as raised:
+            install_rpm_repository(
+                rpm_repository,
+                name="tools",
+                keys_dir=Path("keys"),
+                repos_dir=Path("repos"),
+                gpg_dir=Path("gpg"),
+            )
+
+        assert raised.value.key_id == _KEY_ID
+        assert Path("repos").exists() is False
+
+
+class TestApkRepository:
+    """Verify the apk repository definition."""
+
+    def test_marshal_unmarshal(self):
+        data = {"url": "https://dl-cdn.alpinelinux.org/alpine/edge/main", "tag": None}
+
+        repository = ApkRepository.unmarshal(data)
+        assert repository.marshal() == data
+
+    def test_unmarshal_invalid_tag(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            ApkRepository.unmarshal({"url": "https://example.com", "tag": "Edge x"})
+        assert raised.value.errors()[0]["msg"] == "invalid repository tag 'Edge x'"
+
+    @pytest.mark.parametrize(
+        "tag,entry",
+        [
+            (None, "https://example.com/main"),
+            ("edge", "@edge https://example.com/main"),
+        ],
+    )
+    def test_entry(self, tag, entry):
+        repository = ApkRepository(url="https://example.com/main", tag=tag)
+        assert repository.entry == entry
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestInstallApkRepository:
+    """Verify the installation of apk repositories."""
+
+    def test_install(self):
+        Path("repositories").write_text("https://example.com/v3.14/main\n")
+        repository = ApkRepository(url="https://example.com/edge/main", tag="edge")
+
+        changed = install_apk_repository(
+            repository, repositories_file=Path("repositories")
+        )
+
+        assert changed is True
+        assert Path("repositories").read_text() == (
+            "https://example.com/v3.14/main\n@edge https://example.com/edge/main\n"
+        )
+
+    def test_install_new_file(self):
+        repository = ApkRepository(url="https://example.com/edge/main")
+
+        changed = install_apk_repository(
+            repository, repositories_file=Path("apk/repositories")
+        )
+
+        assert changed is True
+        assert Path("apk/repositories").read_text() == "https://example.com/edge/main\n"
+
+    def test_install_unchanged(self):
+        Path("repositories").write_text("@edge https://example.com/edge/main\n")
+        repository = ApkRepository(url="https://example.com/edge/main", tag="edge")
+
+        changed = install_apk_repository(
+            repository, repositories_file=Path("repositories")
+        )
+
+        assert changed is False
+
+    def test_install_replaces_tag(self):
+        Path("repositories").write_text("@edge https://old.example.com/edge/main\n")
+        repository = ApkRepository(url="https://example.com/edge/main", tag="edge")
+
+        changed = install_apk_repository(
+            repository, repositories_file=Path("repositories")
+        )
+
+        assert changed is True
+        assert Path("repositories").read_text() == (
+            "@edge https://example.com/edge/main\n"
+        )
+
+
+_MIRRORLIST = """Server = https://mirror.example.com/archlinux/$repo/os/$arch
+Server = https://geo.mirror.pkgbuild.com/$repo/os/$arch
+"""
+
+_SERVERS = [
+    "https://mirror.example.com/archlinux/$repo/os/$arch",
+    "https://geo.mirror.pkgbuild.com/$repo/os/$arch",
+]
+
+
+def test_get_mirrorlist_content():
+    assert get_mirrorlist_content(_SERVERS) == _MIRRORLIST
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestInstallMirrorlist:
+    """Verify the installation of the pacman mirrorlist."""
+
+    def test_install(self):
+        Path("mirrorlist").write_text("Server = https://old.example.com\n")
+
+        changed = install_mirrorlist(_SERVERS, mirrorlist_file=Path("mirrorlist"))
+
+        assert changed is True
+        assert Path("mirrorlist").read_text() == _MIRRORLIST
+
+    def test_install_unchanged(self):
+        Path("mirrorlist").write_text(_MIRRORLIST)
+
+        changed = install_mirrorlist(_SERVERS, mirrorlist_file=Path("mirrorlist"))
+
+        assert changed is False
+
+    def test_install_no_servers(self):
+        with pytest.raises(ValueError) as raised:
+            install_mirrorlist([], mirrorlist_file=Path("mirrorlist"))
+
+        assert str(raised.value) == "at least one mirror server is required"
+        assert Path("mirrorlist").exists() is False
@@ -0,0 +1,56 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import errors, rpm
+
+
+@pytest.mark.parametrize(
+    "rpm_file,name_version",
+    [
+        ("hello-2.10-5.fc34.x86_64.rpm", "hello=2.10-5.fc34"),
+        ("python3-libs-3.9.6-2.el8.noarch.rpm", "python3-libs=3.9.6-2.el8"),
+        ("libzypp-17.27.0-1.1.x86_64.rpm", "libzypp=17.27.0-1.1"),
+    ],
+)
+def test_get_rpm_name_version(rpm_file, name_version):
+    assert rpm.get_rpm_name_version(rpm_file) == name_version
+
+
+def test_run_rpm_query(mocker):
+    fake_run = mocker.patch(
+        "subprocess.run",
+        return_value=subprocess.CompletedProcess(
+            [], 0, stdout=b"bash=5.1.8-2.fc34\npackage make is not installed\n"
+        ),
+    )
+
+    assert rpm.run_rpm_query(["bash", "make"]) == ["bash=5.1.8-2.fc34"]
+    assert fake_run.mock_calls[0].args[0][-2:] == ["bash", "make"]
+
+
+def test_extract_rpm_error(new_dir, mocker):
+    mocker.patch("subprocess.Popen")
+    mocker.patch(
+        "subprocess.check_call", side_effect=subprocess.CalledProcessError(2, [])
+    )
+
+    with pytest.raises(errors.UnpackError):
+        rpm.extract_rpm(Path("hello-2.10-5.fc34.x86_64.rpm"), "extract")
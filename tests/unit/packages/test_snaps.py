@@ -14,8 +14,12 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+from pathlib import Path
+from subprocess import CalledProcessError
+
 import pytest
 
+from craft_parts import progress
 from craft_parts.packages import errors, snaps
 
 # pylint: disable=missing-class-docstring
@@ -245,6 +249,26 @@ class TestSnapPackageIsValid:
         )
 
 
+# The sha3-384 digest of b"snap data".
+_FAKE_SNAP_DIGEST = "AGqbbB8nAAuKBJNKG1PA-wOYvEgkF5zZcJdhb-yi46HmkjEpoYDfAEhMll2yM9B0"
+
+
+def _get_fake_assertion(*, revision: str, digest: str) -> str:
+    return (
+        "type: account-key\n"
+        "name: store\n"
+        "\n"
+        "SIGNATURE\n"
+        "\n"
+        "type: snap-revision\n"
+        "authority-id: canonical\n"
+        f"snap-sha3-384: {digest}\n"
+        f"snap-revision: {revision}\n"
+        "\n"
+        "SIGNATURE\n"
+    )
+
+
 @pytest.mark.usefixtures("new_dir")
 class TestSnapPackageLifecycle:
     def test_install_classic(self, fake_snapd, fake_snap_command):
@@ -425,6 +449,21 @@ class TestSnapPackageLifecycle:
             ],
         ]
 
+    def test_download_snaps_progress(self, mocker):
+        def download(self, *, directory):
+            Path(directory, f"{self.name}_12.snap").write_bytes(b"x" * 42)
+
+        mocker.patch("craft_parts.packages.snaps.SnapPackage.download", download)
+
+        reports = []
+        with progress.progress_handler(lambda *args: reports.append(args)):
+            snaps.download_snaps(snaps_list=["fake-snap"], directory="fakedir")
+
+        assert reports == [
+            (progress.DownloadKind.SNAP, "fake-snap", 0, None),
+            (progress.DownloadKind.SNAP, "fake-snap", 42, 42),
+        ]
+
     def test_download_snaps_with_invalid(self, fake_snapd, fake_snap_command):
         fake_snapd.find_result = [
             {"fake-snap": {"channels": {"latest/stable": {"confinement": "strict"}}}},
@@ -518,6 +557,94 @@ class TestSnapPackageLifecycle:
         installed_snaps = snaps.install_snaps(["fake-snap"])
         assert installed_snaps == ["fake-snap=test-fake-snap-revision"]
 
+    def test_install_revision(self, fake_snapd, fake_snap_command):
+        fake_snapd.find_result = [
+            {"fake-snap": {"channels": {"latest/stable": {"confinement": "strict"}}}}
+        ]
+        Path("fake-snap.snap").write_bytes(b"snap data")
+        fake_snap_command.fake_download = "fake-snap.snap"
+        fake_snap_command.fake_assertion = _get_fake_assertion(
+            revision="10", digest=_FAKE_SNAP_DIGEST
+        )
+
+        snap_pkg = snaps.SnapPackage("fake-snap=10")
+        snap_pkg.install()
+
+        assert fake_snap_command.calls[1] == [
+            "snap",
+            "download",
+            "fake-snap",
+            "--revision=10",
+        ]
+        assert [c[:3] for c in fake_snap_command.calls[2:]] == [
+            ["sudo", "snap", "ack"],
+            ["sudo", "snap", "install"],
+        ]
+        assert fake_snap_command.calls[2][3].endswith("/fake-snap_10.assert")
+        assert fake_snap_command.calls[3][3].endswith("/fake-snap_10.snap")
+
+    @pytest.mark.parametrize(
+        "revision,digest,message",
+        [
+            ("11", _FAKE_SNAP_DIGEST, "revision assertion not found"),
+            ("10", "bad-digest", "snap file digest mismatch"),
+        ],
+    )
+    def test_install_revision_verification_fails(
+        self, fake_snapd, fake_snap_command, revision, digest, message
+    ):
+        Path("fake-snap.snap").write_bytes(b"snap data")
+        fake_snap_command.fake_download = "fake-snap.snap"
+        fake_snap_command.fake_assertion = _get_fake_assertion(
+            revision=revision, digest=digest
+        )
+
+        snap_pkg = snaps.SnapPackage("fake-snap=10")
+        with pytest.raises(errors.SnapAssertionError) as raised:
+            snap_pkg.install()
+        assert raised.value.message == message
+        assert [c[1] for c in fake_snap_command.calls] == ["whoami", "download"]
+
+    def test_install_revision_ack_fails(self, fake_snapd, fake_snap_command):
+        Path("fake-snap.snap").write_bytes(b"snap data")
+        fake_snap_command.fake_download = "fake-snap.snap"
+        fake_snap_command.fake_assertion = _get_fake_assertion(
+            revision="10", digest=_FAKE_SNAP_DIGEST
+        )
+        fake_snap_command.ack_success = False
+
+        snap_pkg = snaps.SnapPackage("fake-snap=10")
+        with pytest.raises(errors.SnapAssertionError) as raised:
+            snap_pkg.install()
+        assert raised.value.message == "assertions not acknowledged"
+
+    def test_invalid_revision(self):
+        with pytest.raises(errors.InvalidSnapRevision) as raised:
+            snaps.SnapPackage("fake-snap/stable=latest")
+        assert raised.value.snap == "fake-snap/stable=latest"
+
+    def test_download_revision(self, new_dir, fake_snapd, fake_snap_command):
+        snap_pkg = snaps.SnapPackage("fake-snap/strict/stable=10")
+        snap_pkg.download()
+        assert fake_snap_command.calls == [
+            ["snap", "download", "fake-snap", "--revision=10"]
+        ]
+
+    def test_install_snaps_pinned_revision_installed(
+        self, fake_snapd, fake_snap_command
+    ):
+        fake_snapd.find_result = [
+            {"fake-snap": {"channel": "stable", "type": "app", "channels": {}}}
+        ]
+        fake_snapd.snaps_result = [
+            {"name": "fake-snap", "channel": "stable", "revision": "10"}
+        ]
+
+        installed_snaps = snaps.install_snaps(["fake-snap=10"])
+
+        assert installed_snaps == ["fake-snap=10"]
+        assert fake_snap_command.calls == []
+
     def test_install_snaps_non_stable_base(self, fake_snapd):
         fake_snapd.find_result = [
             {
@@ -553,6 +680,86 @@ class TestInstalledSnaps:
         ]
 
 
+class TestSnapRevisions:
+    @pytest.mark.parametrize(
+        "snap,revision",
+        [("fake-snap", "10"), ("fake-snap/edge", "12"), ("fake-snap=5", "5")],
+    )
+    def test_get_snap_revisions(self, fake_snapd, snap, revision):
+        fake_snapd.find_result = [
+            {
+                "fake-snap": {
+                    "id": "fake-snap-id",
+                    "channel": "stable",
+                    "type": "app",
+                    "channels": {
+                        "latest/stable": {"confinement": "strict", "revision": "10"},
+                        "latest/edge": {"confinement": "strict", "revision": "12"},
+                    },
+                }
+            }
+        ]
+
+        revisions = snaps.get_snap_revisions([snap])
+        assert revisions == {
+            "fake-snap": {"revision": revision, "snap-id": "fake-snap-id"}
+        }
+
+    def test_get_snap_revisions_channel_unavailable(self, fake_snapd):
+        fake_snapd.find_result = [{"fake-snap": {"id": "fake-snap-id", "channels": {}}}]
+
+        with pytest.raises(errors.SnapUnavailable) as raised:
+            snaps.get_snap_revisions(["fake-snap/beta"])
+        assert raised.value.snap_channel == "latest/beta"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestDownloadedSnaps:
+    def test_get_downloaded_snap_revisions(self):
+        Path("fake-snap_10.snap").touch()
+        Path("fake-snap_10.assert").write_text(
+            "type: account-key\n\n"
+            "type: snap-revision\nsnap-id: fake-snap-id\nsnap-revision: 10\n"
+        )
+        Path("other.txt").touch()
+
+        revisions = snaps.get_downloaded_snap_revisions(".")
+        assert revisions == {"fake-snap": {"revision": "10", "snap-id": "fake-snap-id"}}
+
+    def test_unpack_snaps(self, mocker):
+        def fake_unsquashfs(cmd):
+            Path(cmd[3], "bin").mkdir(parents=True)
+            Path(cmd[3], "bin/hello").write_text("hello")
+            Path(cmd[3], "meta").mkdir()
+            Path(cmd[3], "meta/snap.yaml").touch()
+
+        mock_run = mocker.patch(
+            "craft_parts.packages.snaps.check_output", side_effect=fake_unsquashfs
+        )
+        Path("snaps").mkdir()
+        Path("snaps/fake-snap_10.snap").touch()
+        Path("snaps/fake-snap_10.assert").touch()
+
+        snaps.unpack_snaps("snaps", install_dir="install")
+
+        assert mock_run.mock_calls[0].args[0][:3] == ["unsquashfs", "-force", "-dest"]
+        assert mock_run.mock_calls[0].args[0][4] == "snaps/fake-snap_10.snap"
+        assert Path("install/bin/hello").read_text() == "hello"
+        assert not Path("install/meta").exists()
+
+    def test_unpack_snaps_error(self, mocker):
+        mocker.patch(
+            "craft_parts.packages.snaps.check_output",
+            side_effect=CalledProcessError(1, "unsquashfs"),
+        )
+        Path("snaps").mkdir()
+        Path("snaps/fake-snap_10.snap").touch()
+
+        with pytest.raises(errors.SnapUnpackError) as raised:
+            snaps.unpack_snaps("snaps", install_dir="install")
+        assert raised.value.snap_file == "snaps/fake-snap_10.snap"
+
+
 class TestSnapdNotInstalled:
     def test_get_installed_snaps(self, mocker):
         mocker.patch(
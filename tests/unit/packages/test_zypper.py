@@ -0,0 +1,237 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.packages import errors, zypper
+
+# pylint: disable=missing-class-docstring
+
+_STAGE_OPTIONS = [
+    "--non-interactive",
+    "--gpg-auto-import-keys",
+    "--root=/cache/root",
+    "--reposd-dir=/etc/zypp/repos.d",
+    "--cache-dir=/cache/cache",
+]
+
+_DRY_RUN_OUTPUT = b"""<?xml version='1.0'?>
+<stream>
+<message type="info">Loading repository data...</message>
+<install-summary download-size="1024" space-usage-diff="4096" packages-to-change="2">
+<to-install>
+<solvable type="package" name="hello" edition="2.10-3.18" arch="x86_64"/>
+<solvable type="package" name="glibc" edition="2.31-9.3.2" arch="x86_64"/>
+<solvable type="pattern" name="base" edition="20170319-1.1" arch="x86_64"/>
+</to-install>
+</install-summary>
+</stream>
+"""
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch(
+        "craft_parts.packages.zypper.BaseDirectory.save_cache_path",
+        return_value="/cache",
+    )
+
+
+@pytest.fixture
+def fake_check_call(mocker):
+    return mocker.patch("subprocess.check_call")
+
+
+@pytest.fixture
+def fake_rpm_query(mocker):
+    def rpm_query(cmd, **kwargs):
+        names = cmd[4:]
+        stdout = "\n".join(
+            f"{n}=1.0-1.1" if "installed" in n else f"package {n} is not installed"
+            for n in names
+        )
+        return subprocess.CompletedProcess(cmd, 0, stdout=stdout.encode())
+
+    return mocker.patch("subprocess.run", side_effect=rpm_query)
+
+
+class TestBuildPackages:
+    def test_refresh_build_packages_list(self, fake_check_call):
+        zypper.OpenSUSE.refresh_build_packages_list()
+
+        fake_check_call.assert_called_once_with(
+            ["sudo", "--preserve-env", "zypper", "--non-interactive", "refresh"]
+        )
+
+    def test_refresh_build_packages_list_error(self, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageListRefreshError):
+            zypper.OpenSUSE.refresh_build_packages_list()
+
+    def test_install_build_packages(self, fake_check_call, fake_rpm_query):
+        installed = zypper.OpenSUSE.install_build_packages(
+            ["package", "installed=1.0-1.1"]
+        )
+
+        fake_check_call.assert_called_once_with(
+            [
+                "sudo",
+                "--preserve-env",
+                "zypper",
+                "--non-interactive",
+                "install",
+                "installed=1.0-1.1",
+                "package",
+            ]
+        )
+        assert installed == ["installed=1.0-1.1"]
+
+    def test_install_build_packages_already_installed(
+        self, fake_check_call, fake_rpm_query
+    ):
+        installed = zypper.OpenSUSE.install_build_packages(["installed"])
+
+        fake_check_call.assert_not_called()
+        assert installed == ["installed=1.0-1.1"]
+
+    def test_install_build_packages_list_only(self, mocker, fake_check_call):
+        fake_output = mocker.patch(
+            "subprocess.check_output", return_value=_DRY_RUN_OUTPUT
+        )
+
+        installed = zypper.OpenSUSE.install_build_packages(["hello"], list_only=True)
+
+        fake_check_call.assert_not_called()
+        assert fake_output.mock_calls[0].args[0] == [
+            "zypper",
+            "--non-interactive",
+            "--xmlout",
+            "install",
+            "--dry-run",
+            "--no-recommends",
+            "hello",
+        ]
+        assert installed == ["glibc=2.31-9.3.2", "hello=2.10-3.18"]
+
+    def test_install_build_packages_error(self, fake_check_call, fake_rpm_query):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.BuildPackagesNotInstalled) as raised:
+            zypper.OpenSUSE.install_build_packages(["package"])
+        assert raised.value.packages == ["package"]
+
+
+class TestStagePackages:
+    def test_refresh_stage_packages_list(self, fake_check_call):
+        zypper.OpenSUSE.refresh_stage_packages_list(
+            application_name="test", target_arch="amd64"
+        )
+
+        fake_check_call.assert_called_once_with(["zypper", *_STAGE_OPTIONS, "refresh"])
+
+    def test_fetch_stage_packages(self, new_dir, mocker):
+        def fake_download(cmd):
+            cache_dir = [c for c in cmd if c.startswith("--pkg-cache-dir=")][0][16:]
+            repo_dir = Path(cache_dir, "repo-oss/x86_64")
+            repo_dir.mkdir(parents=True)
+            Path(repo_dir, "hello-2.10-3.18.x86_64.rpm").touch()
+            Path(repo_dir, "glibc-2.31-9.3.2.x86_64.rpm").touch()
+
+        fake_check_call = mocker.patch("subprocess.check_call")
+        fake_check_call.side_effect = fake_download
+
+        fetched = zypper.OpenSUSE.fetch_stage_packages(
+            application_name="test",
+            package_names=["hello"],
+            stage_packages_path=Path("stage"),
+            base="opensuse-leap15.3",
+            target_arch="amd64",
+        )
+
+        assert fetched == ["glibc=2.31-9.3.2", "hello=2.10-3.18"]
+        assert sorted(p.name for p in Path("stage").iterdir()) == [
+            "glibc-2.31-9.3.2.x86_64.rpm",
+            "hello-2.10-3.18.x86_64.rpm",
+        ]
+        cmd = fake_check_call.mock_calls[0].args[0]
+        assert cmd[-4:] == ["install", "--download-only", "--no-recommends", "hello"]
+
+    def test_fetch_stage_packages_recommends(self, new_dir, fake_check_call):
+        zypper.OpenSUSE.fetch_stage_packages(
+            application_name="test",
+            package_names=["hello"],
+            stage_packages_path=Path("stage"),
+            base="opensuse-leap15.3",
+            target_arch="amd64",
+            recommends=True,
+        )
+
+        cmd = fake_check_call.mock_calls[0].args[0]
+        assert cmd[-4:] == ["install", "--download-only", "--recommends", "hello"]
+
+    def test_fetch_stage_packages_list_only(self, new_dir, mocker):
+        fake_output = mocker.patch(
+            "subprocess.check_output", return_value=_DRY_RUN_OUTPUT
+        )
+
+        fetched = zypper.OpenSUSE.fetch_stage_packages(
+            application_name="test",
+            package_names=["hello"],
+            stage_packages_path=Path("stage"),
+            base="opensuse-leap15.3",
+            target_arch="amd64",
+            list_only=True,
+        )
+
+        assert fetched == ["glibc=2.31-9.3.2", "hello=2.10-3.18"]
+        assert fake_output.mock_calls[0].args[0][1:6] == _STAGE_OPTIONS
+        assert Path("stage").exists() is False
+
+    def test_fetch_stage_packages_error(self, new_dir, fake_check_call):
+        fake_check_call.side_effect = subprocess.CalledProcessError(1, [])
+
+        with pytest.raises(errors.PackageFetchError):
+            zypper.OpenSUSE.fetch_stage_packages(
+                application_name="test",
+                package_names=["hello"],
+                stage_packages_path=Path("stage"),
+                base="opensuse-leap15.3",
+                target_arch="amd64",
+            )
+
+    def test_unpack_stage_packages(self, new_dir, mocker):
+        def fake_extract(rpm_path, extract_dir):
+            Path(extract_dir, "usr/bin").mkdir(parents=True)
+            Path(extract_dir, "usr/bin/hello").write_text("hello")
+
+        mocker.patch("craft_parts.packages.rpm.extract_rpm", side_effect=fake_extract)
+        fake_mark = mocker.patch(
+            "craft_parts.packages.package_manager.mark_origin_stage_package"
+        )
+        Path("stage").mkdir()
+        Path("stage/hello-2.10-3.18.x86_64.rpm").touch()
+        Path("install").mkdir()
+
+        zypper.OpenSUSE.unpack_stage_packages(
+            stage_packages_path=Path("stage"), install_path=Path("install")
+        )
+
+        assert Path("install/usr/bin/hello").read_text() == "hello"
+        assert fake_mark.mock_calls[0].args[1] == "hello=2.10-3.18"
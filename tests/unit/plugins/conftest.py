@@ -0,0 +1,70 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+from typing import Any, Dict, Optional, Type
+
+import pytest
+
+from craft_parts.infos import PartInfo, ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.plugins import Plugin
+
+
+@pytest.fixture
+def make_plugin():
+    """Return a function to create plugins for tests.
+
+    The plugin properties are unmarshaled from the given data. Part directory
+    arguments replace the default part directories, and other keyword
+    arguments are passed to ProjectInfo.
+    """
+
+    def _make_plugin(
+        plugin_class: Type[Plugin],
+        data: Dict[str, Any],
+        *,
+        part_name: str = "foo",
+        part_data: Optional[Dict[str, Any]] = None,
+        src_dir: Optional[str] = None,
+        src_subdir: Optional[str] = None,
+        build_dir: Optional[str] = None,
+        build_subdir: Optional[str] = None,
+        install_dir: str = "install/dir",
+        **project_options,
+    ) -> Any:
+        properties = plugin_class.properties_class.unmarshal(data)
+        part = Part(
+            part_name,
+            part_data or {},
+            project_dirs=project_options.get("project_dirs"),
+        )
+
+        project_info = ProjectInfo(**project_options)
+        part_info = PartInfo(project_info=project_info, part=part)
+        if src_dir:
+            part_info._part_src_dir = Path(src_dir)
+        if src_subdir:
+            part_info._part_src_subdir = Path(src_subdir)
+        if build_dir:
+            part_info._part_build_dir = Path(build_dir)
+        if build_subdir:
+            part_info._part_build_subdir = Path(build_subdir)
+        part_info._part_install_dir = Path(install_dir)
+
+        return plugin_class(properties=properties, part_info=part_info)
+
+    return _make_plugin
@@ -19,6 +19,7 @@ from pathlib import Path
 import pytest
 from pydantic import ValidationError
 
+from craft_parts.compiler_cache import CompilerCacheConfig
 from craft_parts.infos import PartInfo, ProjectInfo
 from craft_parts.parts import Part
 from craft_parts.plugins.autotools_plugin import AutotoolsPlugin
@@ -52,9 +53,39 @@ class TestPluginAutotools:
         }
         assert self._plugin.get_build_snaps() == set()
 
+    def test_get_toolchain_version_commands(self):
+        assert self._plugin.get_toolchain_version_commands() == {
+            "cc": '"${CC:-cc}" --version',
+            "make": "make --version",
+        }
+
     def test_get_build_environment(self):
         assert self._plugin.get_build_environment() == dict()
 
+    def test_compiler_cache(self, mocker):
+        mocker.patch("platform.machine", return_value="x86_64")
+        properties = AutotoolsPlugin.properties_class.unmarshal({})
+        part = Part("foo", {})
+
+        project_info = ProjectInfo(
+            arch="aarch64",
+            compiler_cache=CompilerCacheConfig(
+                cache_dir=Path("/cache"), max_size=1024 ** 3
+            ),
+        )
+        part_info = PartInfo(project_info=project_info, part=part)
+
+        plugin = AutotoolsPlugin(properties=properties, part_info=part_info)
+
+        assert "ccache" in plugin.get_build_packages()
+        assert plugin.get_build_environment() == {
+            "CCACHE_DIR": "/cache/ccache",
+            "CCACHE_MAXSIZE": "1048576Ki",
+            "CCACHE_BASEDIR": str(Path("parts").absolute()),
+            "CC": "ccache aarch64-linux-gnu-gcc",
+            "CXX": "ccache aarch64-linux-gnu-g++",
+        }
+
     def test_get_build_commands(self):
         assert self._plugin.get_build_commands() == [
             "[ ! -f ./configure ] && [ -f ./autogen.sh ] && env NOCONFIGURE=1 ./autogen.sh",
@@ -88,6 +119,64 @@ class TestPluginAutotools:
             'make install DESTDIR="/tmp"',
         ]
 
+    def test_get_build_commands_without_bootstrap(self, make_plugin):
+        plugin = make_plugin(
+            AutotoolsPlugin,
+            {
+                "autotools-bootstrap": False,
+                "autotools-autoreconf-parameters": ["-f", "-i"],
+            },
+            install_dir="/tmp",
+            parallel_build_count=8,
+        )
+
+        assert plugin.get_build_commands() == [
+            "[ ! -f ./configure ] && autoreconf -f -i",
+            "./configure",
+            "make -j8",
+            'make install DESTDIR="/tmp"',
+        ]
+
+    def test_get_build_commands_with_build_dir(self, make_plugin):
+        plugin = make_plugin(
+            AutotoolsPlugin,
+            {
+                "autotools-build-dir": "_build",
+                "autotools-configure-parameters": ["--prefix=/usr"],
+            },
+            build_subdir="/build",
+            install_dir="/tmp",
+            parallel_build_count=8,
+        )
+
+        assert plugin.get_build_commands()[3:] == [
+            'mkdir -p "_build"',
+            'cd "_build"',
+            '"/build/configure" --prefix=/usr',
+            "make -j8",
+            'make install DESTDIR="/tmp"',
+        ]
+
+    def test_get_build_commands_with_environment(self, make_plugin):
+        plugin = make_plugin(
+            AutotoolsPlugin,
+            {
+                "autotools-configure-environment": [
+                    {"CFLAGS": "-O2"},
+                    {"CC": "clang"},
+                ],
+                "autotools-make-environment": [{"V": "1"}],
+            },
+            install_dir="/tmp",
+            parallel_build_count=8,
+        )
+
+        assert plugin.get_build_commands()[3:] == [
+            'CFLAGS="-O2" CC="clang" ./configure',
+            'V="1" make -j8',
+            'V="1" make install DESTDIR="/tmp"',
+        ]
+
     def test_invalid_parameters(self):
         with pytest.raises(ValidationError) as raised:
             AutotoolsPlugin.properties_class.unmarshal({"autotools-invalid": True})
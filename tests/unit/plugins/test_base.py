@@ -14,7 +14,10 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+import hashlib
+import subprocess
 from dataclasses import dataclass
+from pathlib import Path
 from typing import Any, Dict, List, Set, cast
 
 import pytest
@@ -22,7 +25,11 @@ import pytest
 from craft_parts.infos import PartInfo, ProjectInfo
 from craft_parts.parts import Part
 from craft_parts.plugins import Plugin, PluginProperties
-from craft_parts.plugins.base import extract_plugin_properties
+from craft_parts.plugins.base import (
+    PluginModel,
+    extract_plugin_properties,
+    get_download_command,
+)
 
 
 @dataclass
@@ -64,6 +71,11 @@ def test_plugin():
     plugin = FooPlugin(properties=props, part_info=part_info)
 
     assert isinstance(plugin.properties_class, PluginProperties)
+    assert plugin.get_pull_commands() == []
+    assert plugin.get_pull_environment() == {}
+    assert plugin.get_pull_assets() == {}
+    assert plugin.get_build_assets() == {}
+    assert plugin.get_toolchain_version_commands() == {}
     assert plugin.get_build_snaps() == {"build_snap"}
     assert plugin.get_build_packages() == {"build_package"}
     assert plugin.get_build_environment() == {"ENV": "value"}
@@ -99,3 +111,136 @@ def test_extract_plugin_properties():
 
     plugin_data = extract_plugin_properties(data, plugin_name="test")
     assert plugin_data == {"test-one": 1, "test-two": 2}
+
+
+def test_plugin_properties_marshal():
+    props = PluginProperties()
+    assert props.marshal() == {}
+    assert PluginProperties.get_pull_properties() == []
+    assert PluginProperties.get_build_properties() == []
+
+
+def test_plugin_model_marshal():
+    class BarPluginProperties(PluginModel, PluginProperties):
+        """Test plugin properties using a pydantic model."""
+
+        bar_one: int = 1
+        bar_two: str = "two"
+
+    props = BarPluginProperties()
+    assert props.marshal() == {"bar-one": 1, "bar-two": "two"}
+    assert BarPluginProperties.get_pull_properties() == []
+    assert BarPluginProperties.get_build_properties() == ["bar-one", "bar-two"]
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestDownloadCommand:
+    """Verify the commands used to download plugin tools."""
+
+    def setup_method(self):
+        # pylint: disable=attribute-defined-outside-init
+        self._file = Path("tool.tar.gz").absolute()
+        self._file.write_text("tool")
+        self._url = f"file://{self._file}"
+        self._digest = hashlib.sha256(b"tool").hexdigest()
+        # pylint: enable=attribute-defined-outside-init
+
+    @staticmethod
+    def _run(command: str) -> subprocess.CompletedProcess:
+        return subprocess.run(
+            ["/bin/bash", "-e", "-c", command],
+            check=False,
+            stderr=subprocess.PIPE,
+            universal_newlines=True,
+        )
+
+    def test_sha256(self):
+        command = get_download_command(self._url, "out", sha256=self._digest)
+        assert self._run(command).returncode == 0
+        assert Path("out").read_text() == "tool"
+
+    def _write_checksums(self, content: str) -> str:
+        Path("SHA256SUMS").write_text(
+            content.format(digest=self._digest, other="0" * 64)
+        )
+        return f"file://{Path('SHA256SUMS').absolute()}"
+
+    @pytest.mark.parametrize(
+        "checksums",
+        [
+            "{digest}  tool.tar.gz\n",
+            "{other}  other.tar.gz\n{digest} *tool.tar.gz\n",
+            "{other}  tool.tar.gz.sig\n{digest}  ./dist/tool.tar.gz\n",
+        ],
+    )
+    def test_checksums_url(self, checksums):
+        command = get_download_command(
+            self._url, "out", checksums_url=self._write_checksums(checksums)
+        )
+        assert self._run(command).returncode == 0
+        assert Path("out").read_text() == "tool"
+
+    @pytest.mark.parametrize(
+        "checksums",
+        [
+            "{digest}\n",
+            "{digest}  mytool.tar.gz\n",
+            "{digest}  tool.tar.gz.sig\n",
+        ],
+    )
+    def test_checksums_url_name_not_found(self, checksums):
+        command = get_download_command(
+            self._url, "out", checksums_url=self._write_checksums(checksums)
+        )
+        proc = self._run(command)
+        assert proc.returncode == 1
+        assert proc.stderr == f"cannot download and verify {self._url}\n"
+        assert not Path("out").exists()
+
+    def test_sha256_url(self):
+        command = get_download_command(
+            self._url, "out", sha256_url=self._write_checksums("{digest}\n")
+        )
+        assert self._run(command).returncode == 0
+        assert Path("out").read_text() == "tool"
+
+    @pytest.mark.parametrize(
+        "index",
+        [
+            '{{\n  "tarball": "https://example.com/tool.tar.gz",\n'
+            '  "shasum": "{digest}"\n}}\n',
+            '[\n  {{"name": "tool.tar.gz.sig", "digest": "{other}"}},\n'
+            '  {{"name": "tool.tar.gz",\n   "digest": "sha256:{digest}"}}\n]\n',
+        ],
+    )
+    def test_index_url(self, index):
+        command = get_download_command(
+            self._url, "out", index_url=self._write_checksums(index)
+        )
+        assert self._run(command).returncode == 0
+        assert Path("out").read_text() == "tool"
+
+    def test_index_url_name_not_found(self):
+        index = '{{"tarball": "https://example.com/mytool.tar.gz", "sha": "{digest}"}}'
+        command = get_download_command(
+            self._url, "out", index_url=self._write_checksums(index)
+        )
+        assert self._run(command).returncode == 1
+        assert not Path("out").exists()
+
+    def test_digest_mismatch(self):
+        command = get_download_command(self._url, "out", sha256="0" * 64)
+        proc = self._run(command)
+        assert proc.returncode == 1
+        assert proc.stderr == f"cannot download and verify {self._url}\n"
+        assert sorted(p.name for p in Path().iterdir()) == ["tool.tar.gz"]
+
+    def test_existing_destination(self):
+        Path("out").write_text("cached")
+        command = get_download_command(self._url, "out", sha256="0" * 64)
+        assert self._run(command).returncode == 0
+        assert Path("out").read_text() == "cached"
+
+    def test_no_digest(self):
+        with pytest.raises(ValueError):
+            get_download_command(self._url, "out")
@@ -0,0 +1,85 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.bazel_plugin import BazelPlugin
+
+
+def _get_build_commands(*, build: str, query: str):
+    return [
+        'mkdir -p "/build/.bazel/sandbox"',
+        'grep -qsx ".bazel" .bazelignore || echo ".bazel" >> .bazelignore',
+        build,
+        f"{query} | while read -r output; do "
+        'dest="install/dir/${output#bazel-out/*/bin/}"; '
+        'mkdir -p "$(dirname "$dest")" && '
+        'cp --archive --dereference "$output" "$dest"; done',
+    ]
+
+
+class TestPluginBazel:
+    """Bazel plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(BazelPlugin, {})
+        assert plugin.get_build_packages() == {"g++", "gcc"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            BazelPlugin, {}, parallel_build_count=42, build_subdir="/build"
+        )
+        startup = 'bazel --output_user_root="/build/.bazel/root"'
+        dirs = (
+            '--disk_cache="/build/.bazel/cache" '
+            '--sandbox_base="/build/.bazel/sandbox"'
+        )
+        assert plugin.get_build_commands() == _get_build_commands(
+            build=f"{startup} build {dirs} --jobs=42 //...",
+            query=f"{startup} cquery {dirs} --output=files //...",
+        )
+
+    def test_get_build_commands_targets(self, make_plugin):
+        plugin = make_plugin(
+            BazelPlugin,
+            {
+                "bazel-targets": ["//cmd/server", "//cmd/client"],
+                "bazel-configs": ["release"],
+                "bazel-options": ["--stamp"],
+            },
+            parallel_build_count=42,
+            build_subdir="/build",
+        )
+        startup = 'bazel --output_user_root="/build/.bazel/root"'
+        dirs = (
+            '--config=release --disk_cache="/build/.bazel/cache" '
+            '--sandbox_base="/build/.bazel/sandbox"'
+        )
+        assert plugin.get_build_commands() == _get_build_commands(
+            build=f"{startup} build {dirs} --jobs=42 --stamp //cmd/server //cmd/client",
+            query=f"{startup} cquery {dirs} --output=files //cmd/server //cmd/client",
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            BazelPlugin.properties_class.unmarshal({"bazel-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("bazel-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,134 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.base import get_download_command
+from craft_parts.plugins.bun_plugin import BunPlugin
+
+
+@pytest.fixture(autouse=True)
+def host_arch(mocker):
+    mocker.patch("platform.machine", return_value="x86_64")
+
+
+class TestPluginBun:
+    """Bun plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {})
+        assert plugin.get_build_packages() == set()
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+        assert plugin.get_build_assets() == {}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {})
+        assert plugin.get_build_commands() == [
+            "bun install --frozen-lockfile",
+            'mkdir -p "install/dir/bin"',
+            'bun build --compile --outfile "install/dir/bin/foo" "index.ts"',
+        ]
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(
+            BunPlugin,
+            {
+                "bun-entrypoint": "src/cli.ts",
+                "bun-output": "hello",
+                "bun-parameters": ["--minify"],
+            },
+        )
+        assert plugin.get_build_commands() == [
+            "bun install --frozen-lockfile",
+            'mkdir -p "install/dir/bin"',
+            'bun build --compile --outfile "install/dir/bin/hello" --minify '
+            '"src/cli.ts"',
+        ]
+
+    def test_get_build_commands_cross(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {}, arch="aarch64")
+        assert plugin.get_build_commands()[-1] == (
+            'bun build --compile --outfile "install/dir/bin/foo" '
+            '--target=bun-linux-arm64 "index.ts"'
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            BunPlugin.properties_class.unmarshal({"bun-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("bun-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+
+class TestPluginBunProvisioning:
+    """Bun plugin tests provisioning bun."""
+
+    @pytest.fixture(autouse=True)
+    def cache_dir(self, mocker):
+        mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/bun")
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {"bun-version": "1.1.30"})
+        assert plugin.get_build_packages() == {"curl", "unzip"}
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {"bun-version": "1.1.30"})
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/bun/bun-v1.1.30-linux-x64:${PATH}",
+        }
+
+    def test_get_build_assets(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {"bun-version": "1.1.30"})
+        assert plugin.get_build_assets() == {"bun-version": "1.1.30"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(BunPlugin, {"bun-version": "1.1.30"})
+        bun_dir = "/cache/bun/bun-v1.1.30-linux-x64"
+        release_url = "https://github.com/oven-sh/bun/releases/download/bun-v1.1.30"
+        assert plugin.get_build_commands() == [
+            get_download_command(
+                f"{release_url}/bun-linux-x64.zip",
+                f"{bun_dir}.zip",
+                checksums_url=f"{release_url}/SHASUMS256.txt",
+            ),
+            f'if [ ! -x "{bun_dir}/bun" ]; then '
+            f'mkdir -p "{bun_dir}" && '
+            f'unzip -q -o -j "{bun_dir}.zip" -d "{bun_dir}"; fi',
+            "bun install --frozen-lockfile",
+            'mkdir -p "install/dir/bin"',
+            'bun build --compile --outfile "install/dir/bin/foo" "index.ts"',
+        ]
+
+    def test_get_build_commands_sha256(self, make_plugin):
+        plugin = make_plugin(
+            BunPlugin, {"bun-version": "1.1.30", "bun-version-sha256": "1234"}
+        )
+        assert plugin.get_build_commands()[0] == get_download_command(
+            "https://github.com/oven-sh/bun/releases/download/bun-v1.1.30/"
+            "bun-linux-x64.zip",
+            "/cache/bun/bun-v1.1.30-linux-x64.zip",
+            sha256="1234",
+        )
+
+    def test_get_build_commands_arm64(self, make_plugin, mocker):
+        mocker.patch("platform.machine", return_value="aarch64")
+        plugin = make_plugin(BunPlugin, {"bun-version": "1.1.30"}, arch="aarch64")
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/bun/bun-v1.1.30-linux-aarch64:${PATH}",
+        }
@@ -0,0 +1,109 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.compiler_cache import CompilerCacheConfig
+from craft_parts.plugins.cmake_plugin import CMakePlugin
+
+
+class TestPluginCMake:
+    """CMake plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(CMakePlugin, {})
+        assert plugin.get_build_packages() == {"cmake", "gcc", "make"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(CMakePlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "cmake": "cmake --version",
+            "cc": '"${CC:-cc}" --version',
+            "c++": '"${CXX:-c++}" --version',
+        }
+
+    def test_get_build_packages_ninja(self, make_plugin):
+        plugin = make_plugin(CMakePlugin, {"cmake-generator": "Ninja"})
+        assert plugin.get_build_packages() == {"cmake", "gcc", "ninja-build"}
+
+    def test_compiler_cache(self, make_plugin):
+        plugin = make_plugin(
+            CMakePlugin,
+            {},
+            compiler_cache=CompilerCacheConfig(
+                cache_dir=Path("/cache"), max_size=1024 ** 3
+            ),
+        )
+        assert plugin.get_build_packages() == {"ccache", "cmake", "gcc", "make"}
+        assert plugin.get_build_environment() == {
+            "CCACHE_DIR": "/cache/ccache",
+            "CCACHE_MAXSIZE": "1048576Ki",
+            "CCACHE_BASEDIR": str(Path("parts").absolute()),
+            "CMAKE_C_COMPILER_LAUNCHER": "ccache",
+            "CMAKE_CXX_COMPILER_LAUNCHER": "ccache",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            CMakePlugin,
+            {"cmake-parameters": ["-DCMAKE_BUILD_TYPE=Release"]},
+            parallel_build_count=42,
+            src_subdir="src/dir",
+        )
+        assert plugin.out_of_source_build is True
+        assert plugin.get_build_commands() == [
+            'cmake "src/dir" -G "Unix Makefiles" -DCMAKE_BUILD_TYPE=Release',
+            'cmake --build . -j "42"',
+            'DESTDIR="install/dir" cmake --build . --target install',
+        ]
+
+    def test_get_build_commands_preset(self, make_plugin):
+        plugin = make_plugin(
+            CMakePlugin,
+            {"cmake-preset": "release", "cmake-parameters": ["-DFOO=bar"]},
+            parallel_build_count=42,
+        )
+        assert plugin.out_of_source_build is False
+        assert plugin.get_build_commands() == [
+            'cmake --preset "release" -DFOO=bar',
+            'cmake --build --preset "release" -j "42"',
+            'DESTDIR="install/dir" cmake --build --preset "release" --target install',
+        ]
+
+    def test_get_build_commands_build_preset(self, make_plugin):
+        plugin = make_plugin(
+            CMakePlugin,
+            {"cmake-preset": "default", "cmake-build-preset": "release"},
+            parallel_build_count=42,
+        )
+        assert plugin.get_build_commands() == [
+            'cmake --preset "default"',
+            'cmake --build --preset "release" -j "42"',
+            'DESTDIR="install/dir" cmake --build --preset "release" --target install',
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            CMakePlugin.properties_class.unmarshal({"cmake-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("cmake-invalid",)
+        assert err[0]["type"] == "value_error.extra"
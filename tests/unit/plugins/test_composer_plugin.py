@@ -0,0 +1,77 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.composer_plugin import ComposerPlugin
+
+
+class TestPluginComposer:
+    """Composer plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(ComposerPlugin, {})
+        assert plugin.get_build_packages() == {"composer", "git", "php-cli", "unzip"}
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_packages_php_version(self, make_plugin):
+        plugin = make_plugin(ComposerPlugin, {"composer-php-version": "8.3"})
+        assert plugin.get_build_packages() == {
+            "composer",
+            "git",
+            "php8.3-cli",
+            "unzip",
+        }
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(ComposerPlugin, {}, build_dir="/work/parts/foo/build")
+        assert plugin.get_build_environment() == {
+            "COMPOSER_HOME": "/work/parts/foo/composer",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(ComposerPlugin, {})
+        assert plugin.get_build_commands() == [
+            "composer install --no-dev --optimize-autoloader "
+            "--no-interaction --no-progress",
+            'mkdir -p "install/dir/app"',
+            'cp --archive . "install/dir/app"',
+        ]
+
+    def test_get_build_commands_auth(self, make_plugin):
+        plugin = make_plugin(
+            ComposerPlugin,
+            {"composer-auth-env": "COMPOSER_AUTH_JSON", "composer-app-dir": "srv"},
+            build_dir="/work/parts/foo/build",
+        )
+        assert plugin.get_build_commands() == [
+            'mkdir -p "/work/parts/foo/composer"',
+            '(umask 077 && printf "%s" "${COMPOSER_AUTH_JSON}" '
+            '> "/work/parts/foo/composer/auth.json")',
+            "composer install --no-dev --optimize-autoloader "
+            "--no-interaction --no-progress",
+            'mkdir -p "install/dir/srv"',
+            'cp --archive . "install/dir/srv"',
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            ComposerPlugin.properties_class.unmarshal({"composer-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("composer-invalid",)
+        assert err[0]["type"] == "value_error.extra"
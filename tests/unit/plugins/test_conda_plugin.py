@@ -0,0 +1,110 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.conda_plugin import CondaPlugin
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/conda")
+
+
+class TestPluginConda:
+    """Conda plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(CondaPlugin, {})
+        assert plugin.get_build_packages() == set()
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(CondaPlugin, {})
+        assert plugin.get_build_environment() == {
+            "CONDA_PKGS_DIRS": "/cache/conda/pkgs",
+            "MAMBA_ROOT_PREFIX": "/cache/conda",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(CondaPlugin, {}, install_dir="/work/install")
+        assert plugin.get_build_commands() == [
+            'conda env create --prefix "/work/install" --file "environment.yml"',
+            'grep -rlIF --null "/work/install" "/work/install" | '
+            'xargs -0 -r sed -i "s|/work/install||g"',
+        ]
+
+    def test_get_build_commands_lockfile(self, make_plugin):
+        plugin = make_plugin(
+            CondaPlugin,
+            {
+                "conda-executable": "mamba",
+                "conda-lockfile": "conda-linux-64.lock",
+                "conda-prefix": "opt/env",
+            },
+            install_dir="/work/install",
+        )
+        assert plugin.get_build_commands() == [
+            'mamba create --yes --prefix "/work/install/opt/env" '
+            '--file "conda-linux-64.lock"',
+            'grep -rlIF --null "/work/install/opt/env" "/work/install/opt/env" | '
+            'xargs -0 -r sed -i "s|/work/install/opt/env|/opt/env|g"',
+        ]
+
+    @pytest.mark.parametrize(
+        "data,spec",
+        [
+            ({}, "environment.yml"),
+            ({"conda-lockfile": "conda-linux-64.lock"}, "conda-linux-64.lock"),
+        ],
+    )
+    def test_get_build_commands_micromamba(self, make_plugin, data, spec):
+        plugin = make_plugin(
+            CondaPlugin,
+            {"conda-executable": "micromamba", **data},
+            install_dir="/work/install",
+        )
+        assert plugin.get_build_commands()[0] == (
+            f'micromamba create --yes --prefix "/work/install" --file "{spec}"'
+        )
+
+    def test_get_build_commands_target_prefix(self, make_plugin):
+        plugin = make_plugin(
+            CondaPlugin,
+            {"conda-target-prefix": "/snap/foo/current/"},
+            install_dir="/work/install",
+        )
+        assert plugin.get_build_commands()[-1] == (
+            'grep -rlIF --null "/work/install" "/work/install" | '
+            'xargs -0 -r sed -i "s|/work/install|/snap/foo/current|g"'
+        )
+
+    def test_invalid_executable(self):
+        with pytest.raises(ValidationError) as raised:
+            CondaPlugin.properties_class.unmarshal({"conda-executable": "pip"})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("conda-executable",)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            CondaPlugin.properties_class.unmarshal({"conda-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("conda-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,144 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.base import get_download_command
+from craft_parts.plugins.crystal_plugin import CrystalPlugin
+
+_LINK_DEPENDENCIES = {
+    "gcc",
+    "git",
+    "libevent-dev",
+    "libpcre2-dev",
+    "libssl-dev",
+    "libyaml-dev",
+    "pkg-config",
+    "zlib1g-dev",
+}
+
+_BUILD_COMMANDS = [
+    "shards install --without-development",
+    "shards build --without-development --release",
+    'mkdir -p "install/dir/bin"',
+    'cp --archive bin/. "install/dir/bin"',
+]
+
+
+@pytest.fixture(autouse=True)
+def setup_host(mocker):
+    mocker.patch("platform.machine", return_value="x86_64")
+    mocker.patch("craft_parts.bases.get_host_base", return_value=None)
+    mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/crystal")
+
+
+class TestPluginCrystal:
+    """Crystal plugin tests using the packaged compiler."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {}, base="alpine@3.20")
+        assert plugin.get_build_packages() == {*_LINK_DEPENDENCIES, "crystal", "shards"}
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {}, base="arch")
+        assert plugin.get_build_environment() == {
+            "SHARDS_CACHE_PATH": "/cache/crystal/shards",
+        }
+
+    def test_get_build_assets(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {}, base="alpine@3.20")
+        assert plugin.get_build_assets() == {}
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "crystal": "crystal --version",
+            "shards": "shards --version",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {}, base="alpine@3.20")
+        assert plugin.get_build_commands() == _BUILD_COMMANDS
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(
+            CrystalPlugin,
+            {"crystal-build-options": ["foo", "--static"]},
+            base="alpine@3.20",
+        )
+        assert plugin.get_build_commands()[1] == (
+            "shards build --without-development --release foo --static"
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            CrystalPlugin.properties_class.unmarshal({"crystal-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("crystal-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+
+class TestPluginCrystalToolchain:
+    """Crystal plugin tests provisioning the crystal compiler."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {}, base="ubuntu@24.04")
+        assert plugin.get_build_packages() == {*_LINK_DEPENDENCIES, "curl"}
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(
+            CrystalPlugin, {"crystal-version": "1.13.3"}, base="alpine@3.20"
+        )
+        assert plugin.get_build_environment() == {
+            "SHARDS_CACHE_PATH": "/cache/crystal/shards",
+            "PATH": "/cache/crystal/crystal-1.13.3-1-linux-x86_64/bin:${PATH}",
+        }
+
+    def test_get_build_assets(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {})
+        assert plugin.get_build_assets() == {"crystal-version": "1.14.0"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(CrystalPlugin, {"crystal-version": "1.13.3"})
+        toolchain = "/cache/crystal/crystal-1.13.3-1-linux-x86_64"
+        assert plugin.get_build_commands() == [
+            get_download_command(
+                "https://github.com/crystal-lang/crystal/releases/download/1.13.3/"
+                "crystal-1.13.3-1-linux-x86_64.tar.gz",
+                f"{toolchain}.tar.gz",
+                index_url="https://api.github.com/repos/crystal-lang/crystal/"
+                "releases/tags/1.13.3",
+            ),
+            f'if [ ! -x "{toolchain}/bin/crystal" ]; then '
+            f'mkdir -p "{toolchain}" && '
+            f'tar -xzf "{toolchain}.tar.gz" -C "{toolchain}" --strip-components=1; fi',
+            *_BUILD_COMMANDS,
+        ]
+
+    def test_get_build_commands_sha256(self, make_plugin):
+        plugin = make_plugin(
+            CrystalPlugin,
+            {"crystal-version": "1.13.3", "crystal-version-sha256": "1234"},
+        )
+        assert plugin.get_build_commands()[0] == get_download_command(
+            "https://github.com/crystal-lang/crystal/releases/download/1.13.3/"
+            "crystal-1.13.3-1-linux-x86_64.tar.gz",
+            "/cache/crystal/crystal-1.13.3-1-linux-x86_64.tar.gz",
+            sha256="1234",
+        )
@@ -0,0 +1,87 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.deno_plugin import DenoPlugin
+
+
+class TestPluginDeno:
+    """Deno plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(DenoPlugin, {})
+        assert plugin.get_build_packages() == set()
+        assert plugin.get_build_snaps() == {"deno"}
+
+    def test_get_environment(self, make_plugin):
+        plugin = make_plugin(DenoPlugin, {}, src_dir="/work/parts/foo/src")
+        env = {"DENO_DIR": "/work/parts/foo/deno", "DENO_NO_UPDATE_CHECK": "1"}
+        assert plugin.get_pull_environment() == env
+        assert plugin.get_build_environment() == env
+
+    def test_get_commands(self, make_plugin):
+        plugin = make_plugin(DenoPlugin, {})
+        assert plugin.get_pull_commands() == [
+            'deno cache --lock="deno.lock" --frozen "main.ts"'
+        ]
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir/bin"',
+            'deno compile --lock="deno.lock" --frozen --cached-only '
+            '--output "install/dir/bin/foo" "main.ts"',
+        ]
+
+    def test_get_commands_compile_options(self, make_plugin):
+        plugin = make_plugin(
+            DenoPlugin,
+            {
+                "deno-entrypoint": "src/cli.ts",
+                "deno-output": "hello",
+                "deno-parameters": ["--allow-net", "--allow-read"],
+                "deno-lockfile": "locks/deno.lock",
+            },
+        )
+        assert plugin.get_pull_commands() == [
+            'deno cache --lock="locks/deno.lock" --frozen "src/cli.ts"'
+        ]
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir/bin"',
+            'deno compile --lock="locks/deno.lock" --frozen --cached-only '
+            '--output "install/dir/bin/hello" --allow-net --allow-read "src/cli.ts"',
+        ]
+
+    def test_get_commands_task(self, make_plugin):
+        plugin = make_plugin(DenoPlugin, {"deno-task": "build"})
+        assert plugin.get_pull_commands() == [
+            'deno install --lock="deno.lock" --frozen'
+        ]
+        assert plugin.get_build_commands() == ['deno task "build"']
+
+    def test_get_pull_properties(self):
+        assert DenoPlugin.properties_class.get_pull_properties() == [
+            "deno-entrypoint",
+            "deno-task",
+            "deno-lockfile",
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            DenoPlugin.properties_class.unmarshal({"deno-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("deno-invalid",)
+        assert err[0]["type"] == "value_error.extra"
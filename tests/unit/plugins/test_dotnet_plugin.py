@@ -0,0 +1,102 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.dotnet_plugin import DotnetPlugin
+
+
+class TestPluginDotnet:
+    """Dotnet plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(DotnetPlugin, {})
+        assert plugin.get_build_packages() == set()
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(DotnetPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "dotnet": "dotnet --version",
+        }
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(DotnetPlugin, {})
+        assert plugin.get_build_environment() == {
+            "DOTNET_CLI_TELEMETRY_OPTOUT": "1",
+            "DOTNET_NOLOGO": "1",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(DotnetPlugin, {})
+        assert plugin.get_build_commands() == [
+            'dotnet publish --configuration "Release" --output "install/dir"'
+        ]
+
+    def test_get_build_commands_project(self, make_plugin):
+        plugin = make_plugin(
+            DotnetPlugin,
+            {"dotnet-project": "src/app.csproj", "dotnet-configuration": "Debug"},
+        )
+        assert plugin.get_build_commands() == [
+            'dotnet publish "src/app.csproj" --configuration "Debug" '
+            '--output "install/dir"'
+        ]
+
+    def test_get_build_commands_self_contained(self, make_plugin):
+        plugin = make_plugin(
+            DotnetPlugin, {"dotnet-self-contained": True, "dotnet-trim": True}
+        )
+        assert plugin.get_build_commands() == [
+            'dotnet publish --configuration "Release" --output "install/dir" '
+            '--runtime "linux-x64" --self-contained true -p:PublishTrimmed=true'
+        ]
+
+    def test_get_build_commands_ready_to_run(self, make_plugin):
+        plugin = make_plugin(
+            DotnetPlugin, {"dotnet-ready-to-run": True}, arch="aarch64"
+        )
+        assert plugin.get_build_commands() == [
+            'dotnet publish --configuration "Release" --output "install/dir" '
+            '--runtime "linux-arm64" --self-contained false '
+            "-p:PublishReadyToRun=true"
+        ]
+
+    def test_get_build_commands_runtime_identifier(self, make_plugin):
+        plugin = make_plugin(
+            DotnetPlugin, {"dotnet-runtime-identifier": "linux-musl-x64"}
+        )
+        assert plugin.get_build_commands() == [
+            'dotnet publish --configuration "Release" --output "install/dir" '
+            '--runtime "linux-musl-x64" --self-contained false'
+        ]
+
+    def test_trim_requires_self_contained(self):
+        with pytest.raises(ValidationError) as raised:
+            DotnetPlugin.properties_class.unmarshal({"dotnet-trim": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("dotnet-trim",)
+        assert err[0]["msg"] == "trimming requires a self-contained build"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            DotnetPlugin.properties_class.unmarshal({"dotnet-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("dotnet-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -17,6 +17,7 @@
 from pathlib import Path
 
 import pytest
+from pydantic import ValidationError
 
 from craft_parts.infos import PartInfo, ProjectInfo
 from craft_parts.parts import Part
@@ -58,3 +59,74 @@ class TestPluginDump:
 
     def test_out_of_source_build(self):
         assert self._plugin.out_of_source_build is False
+
+
+class TestPluginDumpFilters:
+    """Check dump plugin source selection."""
+
+    def test_get_build_commands_include(self, make_plugin):
+        plugin = make_plugin(
+            DumpPlugin, {"source": "something", "dump-include": ["bin", "share/*"]}
+        )
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir"',
+            "tar --create --file=- -- bin share/* | "
+            "tar --extract --file=- --preserve-permissions "
+            '--directory="install/dir" --strip-components=0',
+        ]
+
+    def test_get_build_commands_exclude(self, make_plugin):
+        plugin = make_plugin(
+            DumpPlugin, {"source": "something", "dump-exclude": ["*.o", ".git"]}
+        )
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir"',
+            'tar --create --file=- --exclude="*.o" --exclude=".git" . | '
+            "tar --extract --file=- --preserve-permissions "
+            '--directory="install/dir" --strip-components=1',
+        ]
+
+    def test_get_build_commands_strip_components(self, make_plugin):
+        plugin = make_plugin(
+            DumpPlugin, {"source": "something", "dump-strip-components": 2}
+        )
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir"',
+            "tar --create --file=- . | "
+            "tar --extract --file=- --preserve-permissions "
+            '--directory="install/dir" --strip-components=3',
+        ]
+
+    def test_get_build_commands_include_strip_components(self, make_plugin):
+        plugin = make_plugin(
+            DumpPlugin,
+            {
+                "source": "something",
+                "dump-include": ["dist/app"],
+                "dump-strip-components": 2,
+            },
+        )
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir"',
+            "tar --create --file=- -- dist/app | "
+            "tar --extract --file=- --preserve-permissions "
+            '--directory="install/dir" --strip-components=2',
+        ]
+
+    def test_invalid_strip_components(self, make_plugin):
+        with pytest.raises(ValidationError) as raised:
+            make_plugin(
+                DumpPlugin, {"source": "something", "dump-strip-components": -1}
+            )
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("dump-strip-components",)
+        assert err[0]["type"] == "value_error.number.not_ge"
+
+    def test_invalid_parameters(self, make_plugin):
+        with pytest.raises(ValidationError) as raised:
+            make_plugin(DumpPlugin, {"source": "something", "dump-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("dump-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,76 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.dune_plugin import DunePlugin
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/opam")
+
+
+class TestPluginDune:
+    """Dune plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(DunePlugin, {})
+        assert "opam" in plugin.get_build_packages()
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(DunePlugin, {})
+        assert plugin.get_build_environment() == {
+            "OPAMROOT": "/cache/opam",
+            "OPAMYES": "1",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(DunePlugin, {}, parallel_build_count=42)
+        assert plugin.get_build_commands() == [
+            '[ -f "${OPAMROOT}/config" ] || opam init --bare --disable-sandboxing',
+            "opam install --deps-only .",
+            'opam exec -- dune build @install --profile "release" -j "42"',
+            'opam exec -- dune install --prefix "install/dir" --relocatable',
+        ]
+
+    def test_get_build_commands_switch(self, make_plugin):
+        plugin = make_plugin(
+            DunePlugin,
+            {"dune-ocaml-version": "5.1.1", "dune-packages": ["foo", "bar"]},
+            parallel_build_count=42,
+        )
+        assert plugin.get_build_environment()["OPAMSWITCH"] == "craft-parts-5.1.1"
+        assert plugin.get_build_commands() == [
+            '[ -f "${OPAMROOT}/config" ] || opam init --bare --disable-sandboxing',
+            'opam switch list --short | grep -qx "craft-parts-5.1.1" || '
+            'opam switch create "craft-parts-5.1.1" "5.1.1"',
+            "opam install --deps-only .",
+            'opam exec -- dune build @install --profile "release" '
+            '--only-packages "foo,bar" -j "42"',
+            'opam exec -- dune install --prefix "install/dir" --relocatable '
+            '"foo" "bar"',
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            DunePlugin.properties_class.unmarshal({"dune-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("dune-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,157 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.base import get_download_command
+from craft_parts.plugins.flutter_plugin import FlutterPlugin
+
+
+@pytest.fixture(autouse=True)
+def host_arch(mocker):
+    mocker.patch("platform.machine", return_value="x86_64")
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/flutter")
+
+
+class TestPluginFlutter:
+    """Flutter plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {})
+        assert plugin.get_build_packages() == {
+            "clang",
+            "cmake",
+            "git",
+            "libgtk-3-dev",
+            "liblzma-dev",
+            "ninja-build",
+            "pkg-config",
+            "unzip",
+        }
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_packages_web(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {"flutter-target": "web"})
+        assert plugin.get_build_packages() == {"git", "unzip"}
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PUB_CACHE": "/cache/flutter/pub-cache"
+        }
+        assert plugin.get_build_assets() == {}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {})
+        assert plugin.get_build_commands() == [
+            "flutter config --no-analytics",
+            "flutter pub get --enforce-lockfile",
+            "flutter build linux --release",
+            'mkdir -p "install/dir"',
+            'cp --archive "build/linux/x64/release/bundle/." "install/dir"',
+        ]
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(
+            FlutterPlugin,
+            {
+                "flutter-target": "web",
+                "flutter-build-mode": "profile",
+                "flutter-parameters": ["--base-href=/app/"],
+            },
+        )
+        assert plugin.get_build_commands() == [
+            "flutter config --no-analytics",
+            "flutter pub get --enforce-lockfile",
+            "flutter build web --profile --base-href=/app/",
+            'mkdir -p "install/dir"',
+            'cp --archive "build/web/." "install/dir"',
+        ]
+
+    def test_get_build_commands_cross(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {}, arch="aarch64")
+        assert plugin.get_build_commands()[2:] == [
+            "flutter build linux --release --target-platform=linux-arm64",
+            'mkdir -p "install/dir"',
+            'cp --archive "build/linux/arm64/release/bundle/." "install/dir"',
+        ]
+
+    @pytest.mark.parametrize(
+        "key,value", [("flutter-target", "apk"), ("flutter-build-mode", "fast")]
+    )
+    def test_invalid_choice(self, key, value):
+        with pytest.raises(ValidationError) as raised:
+            FlutterPlugin.properties_class.unmarshal({key: value})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == (key,)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            FlutterPlugin.properties_class.unmarshal({"flutter-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("flutter-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+
+class TestPluginFlutterSDK:
+    """Flutter plugin tests provisioning the flutter SDK."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {"flutter-version": "3.24.3"})
+        assert {"curl", "xz-utils"} <= plugin.get_build_packages()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {"flutter-version": "3.24.3"})
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/flutter/flutter-3.24.3/bin:${PATH}",
+            "PUB_CACHE": "/cache/flutter/pub-cache",
+        }
+        assert plugin.get_build_assets() == {"flutter-version": "3.24.3"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(FlutterPlugin, {"flutter-version": "3.24.3"})
+        sdk = "/cache/flutter/flutter-3.24.3"
+        releases = "https://storage.googleapis.com/flutter_infra_release/releases"
+        assert plugin.get_build_commands()[:2] == [
+            get_download_command(
+                f"{releases}/stable/linux/flutter_linux_3.24.3-stable.tar.xz",
+                f"{sdk}.tar.xz",
+                index_url=f"{releases}/releases_linux.json",
+            ),
+            f'if [ ! -x "{sdk}/bin/flutter" ]; then '
+            f'mkdir -p "{sdk}" && '
+            f'tar -xJf "{sdk}.tar.xz" -C "{sdk}" --strip-components=1; fi',
+        ]
+
+    def test_get_build_commands_sha256(self, make_plugin):
+        plugin = make_plugin(
+            FlutterPlugin,
+            {"flutter-version": "3.24.3", "flutter-version-sha256": "1234"},
+        )
+        assert plugin.get_build_commands()[0] == get_download_command(
+            "https://storage.googleapis.com/flutter_infra_release/releases/stable/"
+            "linux/flutter_linux_3.24.3-stable.tar.xz",
+            "/cache/flutter/flutter-3.24.3.tar.xz",
+            sha256="1234",
+        )
@@ -0,0 +1,122 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.go_use_plugin import GoUsePlugin, get_go_use_dir
+
+
+@pytest.fixture
+def monorepo(new_dir):
+    build_dir = Path("parts/mono/build")
+    for module in [
+        ".",
+        "api",
+        "cmd/tool",
+        "internal/testdata/fixture",
+        "vendor/example.com/dep",
+        ".github/actions/check",
+    ]:
+        module_dir = build_dir / module
+        module_dir.mkdir(parents=True, exist_ok=True)
+        (module_dir / "go.mod").write_text("module example.com/mono\n")
+    (build_dir / "docs").mkdir()
+
+
+class TestPluginGoUse:
+    """Go-use plugin tests."""
+
+    def test_unmarshal_error(self):
+        with pytest.raises(ValueError) as raised:
+            GoUsePlugin.properties_class.unmarshal({})
+        assert str(raised.value) == "'source' is required by the go-use plugin"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            GoUsePlugin.properties_class.unmarshal(
+                {"source": ".", "go-use-invalid": True}
+            )
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("go-use-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+    @pytest.mark.usefixtures("new_dir")
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(GoUsePlugin, {"source": "."})
+        assert plugin.get_build_packages() == set()
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    @pytest.mark.usefixtures("monorepo")
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(GoUsePlugin, {"source": "."}, part_name="mono")
+        go_use_dir = Path.cwd() / "parts/mono/go-use"
+        assert plugin.get_build_commands() == [
+            f'rm -rf "{go_use_dir}"',
+            f'mkdir -p "{go_use_dir}/src"',
+            f'cp --archive --link --no-dereference . "{go_use_dir}/src"',
+            f'printf "%s\\n" "." "api" "cmd/tool" > "{go_use_dir}/modules"',
+        ]
+
+    @pytest.mark.usefixtures("monorepo")
+    def test_get_build_commands_include(self, make_plugin):
+        plugin = make_plugin(
+            GoUsePlugin,
+            {"source": ".", "go-use-include": ["cmd/*", "api"]},
+            part_name="mono",
+        )
+        modules_file = Path.cwd() / "parts/mono/go-use/modules"
+        assert plugin.get_build_commands()[-1] == (
+            f'printf "%s\\n" "api" "cmd/tool" > "{modules_file}"'
+        )
+
+    @pytest.mark.usefixtures("monorepo")
+    def test_get_build_commands_exclude(self, make_plugin):
+        plugin = make_plugin(
+            GoUsePlugin, {"source": ".", "go-use-exclude": ["."]}, part_name="mono"
+        )
+        modules_file = Path.cwd() / "parts/mono/go-use/modules"
+        assert plugin.get_build_commands()[-1] == (
+            f'printf "%s\\n" "api" "cmd/tool" > "{modules_file}"'
+        )
+
+    @pytest.mark.usefixtures("monorepo")
+    def test_get_build_commands_include_exclude(self, make_plugin):
+        plugin = make_plugin(
+            GoUsePlugin,
+            {
+                "source": ".",
+                "go-use-include": [".", "cmd/*"],
+                "go-use-exclude": ["cmd/tool"],
+            },
+            part_name="mono",
+        )
+        modules_file = Path.cwd() / "parts/mono/go-use/modules"
+        assert plugin.get_build_commands()[-1] == (
+            f'printf "%s\\n" "." > "{modules_file}"'
+        )
+
+    def test_get_go_use_dir(self):
+        assert get_go_use_dir(Path("parts"), "mono") == Path("parts/mono/go-use")
+
+    @pytest.mark.usefixtures("new_dir")
+    def test_out_of_source_build(self, make_plugin):
+        plugin = make_plugin(GoUsePlugin, {"source": "."})
+        assert plugin.out_of_source_build is False
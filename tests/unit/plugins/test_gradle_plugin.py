@@ -0,0 +1,162 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts import errors
+from craft_parts.plugins.gradle_plugin import GradlePlugin
+from craft_parts.proxy import ProxyConfig
+
+_CHECKSUM = "a" * 64
+
+
+def _make_wrapper(properties: str = ""):
+    wrapper_dir = Path("parts/foo/build/gradle/wrapper")
+    wrapper_dir.mkdir(parents=True)
+    Path("parts/foo/build/gradlew").write_text("#!/bin/sh\n")
+    (wrapper_dir / "gradle-wrapper.properties").write_text(
+        "distributionUrl=https\\://services.gradle.org/distributions/"
+        "gradle-8.5-bin.zip\n" + properties
+    )
+
+
+_COPY_JARS = [
+    'mkdir -p "install/dir/jar"',
+    'find build/libs -maxdepth 1 -name "*.jar" -exec cp --archive {} '
+    '"install/dir/jar" \\;',
+]
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPluginGradle:
+    """Gradle plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(GradlePlugin, {})
+        assert plugin.get_build_packages() == {"default-jdk-headless"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_packages_no_wrapper(self, make_plugin):
+        plugin = make_plugin(GradlePlugin, {"gradle-use-wrapper": False})
+        assert plugin.get_build_packages() == {"default-jdk-headless", "gradle"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(GradlePlugin, {})
+        assert plugin.get_build_commands() == [
+            "gradle build --no-daemon",
+            *_COPY_JARS,
+        ]
+
+    def test_get_build_commands_proxy(self, make_plugin):
+        plugin = make_plugin(
+            GradlePlugin, {}, proxy=ProxyConfig(http_proxy="proxy:8080")
+        )
+        assert plugin.get_build_commands()[0] == (
+            "gradle build --no-daemon -Dhttp.proxyHost=proxy -Dhttp.proxyPort=8080"
+        )
+
+    def test_get_build_commands_parameters(self, make_plugin):
+        plugin = make_plugin(
+            GradlePlugin,
+            {
+                "gradle-task": "assemble",
+                "gradle-parameters": ["-x", "test"],
+                "gradle-init-scripts": ["mirror.gradle"],
+            },
+        )
+        assert plugin.get_build_commands()[0] == (
+            'gradle assemble --no-daemon --init-script "mirror.gradle" -x test'
+        )
+
+    def test_wrapper(self, make_plugin):
+        _make_wrapper(f"distributionSha256Sum={_CHECKSUM}\n")
+        plugin = make_plugin(GradlePlugin, {})
+        assert plugin.get_build_commands() == [
+            "./gradlew build --no-daemon",
+            *_COPY_JARS,
+        ]
+
+    def test_wrapper_disabled(self, make_plugin):
+        _make_wrapper()
+        plugin = make_plugin(GradlePlugin, {"gradle-use-wrapper": False})
+        assert plugin.get_build_commands()[0] == "gradle build --no-daemon"
+
+    def test_wrapper_checksum_option(self, make_plugin):
+        _make_wrapper()
+        plugin = make_plugin(GradlePlugin, {"gradle-wrapper-sha256": _CHECKSUM})
+        assert plugin.get_build_commands()[:2] == [
+            f"printf 'distributionSha256Sum=%s\\n' \"{_CHECKSUM}\" >> "
+            '"gradle/wrapper/gradle-wrapper.properties"',
+            "./gradlew build --no-daemon",
+        ]
+
+    def test_wrapper_checksum_matches(self, make_plugin):
+        _make_wrapper(f"distributionSha256Sum={_CHECKSUM}\n")
+        plugin = make_plugin(GradlePlugin, {"gradle-wrapper-sha256": _CHECKSUM})
+        assert plugin.get_build_commands()[0] == "./gradlew build --no-daemon"
+
+    def test_wrapper_checksum_mismatch(self, make_plugin):
+        _make_wrapper(f"distributionSha256Sum={_CHECKSUM}\n")
+        plugin = make_plugin(GradlePlugin, {"gradle-wrapper-sha256": "b" * 64})
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            plugin.get_build_commands()
+        assert raised.value.part_name == "foo"
+        assert raised.value.reason == (
+            f"gradle wrapper distribution checksum {_CHECKSUM!r} "
+            f"does not match {'b' * 64!r}"
+        )
+
+    def test_wrapper_checksum_missing(self, make_plugin):
+        _make_wrapper()
+        plugin = make_plugin(GradlePlugin, {})
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            plugin.get_build_commands()
+        assert raised.value.part_name == "foo"
+        assert raised.value.reason == (
+            "the gradle wrapper distribution checksum is not declared"
+        )
+
+    def test_gradle_properties(self, make_plugin, new_dir):
+        plugin = make_plugin(
+            GradlePlugin,
+            {
+                "gradle-properties": {
+                    "systemProp.https.proxyHost": "proxy.example.com",
+                    "systemProp.https.proxyPort": "3128",
+                }
+            },
+        )
+        gradle_home = Path(new_dir, "parts/foo/gradle")
+        assert plugin.get_build_environment() == {"GRADLE_USER_HOME": str(gradle_home)}
+        assert plugin.get_build_commands()[:3] == [
+            f'mkdir -p "{gradle_home}"',
+            'printf "%s\\n" systemProp.https.proxyHost=proxy.example.com '
+            "systemProp.https.proxyPort=3128 "
+            f'> "{gradle_home}/gradle.properties"',
+            "gradle build --no-daemon",
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            GradlePlugin.properties_class.unmarshal({"gradle-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("gradle-invalid",)
+        assert err[0]["type"] == "value_error.extra"
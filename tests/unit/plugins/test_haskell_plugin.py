@@ -0,0 +1,95 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.haskell_plugin import HaskellPlugin
+
+
+@pytest.fixture(autouse=True)
+def cache_dir(mocker):
+    mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/haskell")
+
+
+class TestPluginHaskell:
+    """Haskell plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(HaskellPlugin, {})
+        assert plugin.get_build_packages() == {
+            "cabal-install",
+            "ghc",
+            "libgmp-dev",
+            "zlib1g-dev",
+        }
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_packages_stack(self, make_plugin):
+        plugin = make_plugin(HaskellPlugin, {"haskell-build-tool": "stack"})
+        assert plugin.get_build_packages() == {
+            "haskell-stack",
+            "libgmp-dev",
+            "zlib1g-dev",
+        }
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(HaskellPlugin, {})
+        assert plugin.get_build_environment() == {
+            "CABAL_DIR": "/cache/haskell/cabal",
+            "STACK_ROOT": "/cache/haskell/stack",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            HaskellPlugin, {"haskell-targets": ["exe:hello"]}, parallel_build_count=42
+        )
+        assert plugin.get_build_commands() == [
+            "cabal update",
+            'mkdir -p "install/dir/bin"',
+            'cabal install -j"42" --installdir="install/dir/bin" '
+            "--install-method=copy --overwrite-policy=always exe:hello",
+        ]
+
+    def test_get_build_commands_stack(self, make_plugin):
+        plugin = make_plugin(
+            HaskellPlugin,
+            {
+                "haskell-build-tool": "stack",
+                "haskell-parameters": ["--flag", "hello:static"],
+            },
+            parallel_build_count=42,
+        )
+        assert plugin.get_build_commands() == [
+            'stack build -j "42" --copy-bins --local-bin-path "install/dir/bin" '
+            "--flag hello:static",
+        ]
+
+    def test_invalid_build_tool(self):
+        with pytest.raises(ValidationError) as raised:
+            HaskellPlugin.properties_class.unmarshal({"haskell-build-tool": "ghc"})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("haskell-build-tool",)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            HaskellPlugin.properties_class.unmarshal({"haskell-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("haskell-invalid",)
+        assert err[0]["type"] == "value_error.extra"
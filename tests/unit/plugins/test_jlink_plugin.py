@@ -0,0 +1,92 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.plugins.jlink_plugin import JLinkPlugin
+
+
+class TestPluginJLink:
+    """JLink plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(JLinkPlugin, {})
+        assert plugin.get_build_packages() == {"binutils", "default-jdk-headless"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_packages_java_version(self, make_plugin):
+        plugin = make_plugin(JLinkPlugin, {"jlink-java-version": "21"})
+        assert plugin.get_build_packages() == {"binutils", "openjdk-21-jdk-headless"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            JLinkPlugin, {}, project_dirs=ProjectDirs(work_dir="/work")
+        )
+        assert plugin.get_build_commands() == [
+            'JLINK_MODULES="$(jdeps --print-module-deps --ignore-missing-deps '
+            '--multi-release base "/work/stage"/jar/*.jar)"',
+            'rm -rf "install/dir/jre"',
+            'jlink --add-modules "${JLINK_MODULES}" --strip-debug --no-header-files '
+            '--no-man-pages --output "install/dir/jre"',
+        ]
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(
+            JLinkPlugin,
+            {
+                "jlink-jars": ["jar/app.jar", "lib/*.jar"],
+                "jlink-extra-modules": ["jdk.crypto.ec", "jdk.localedata"],
+                "jlink-java-version": "21",
+            },
+            project_dirs=ProjectDirs(work_dir="/work"),
+        )
+        assert plugin.get_build_commands() == [
+            'JLINK_MODULES="$(jdeps --print-module-deps --ignore-missing-deps '
+            '--multi-release 21 "/work/stage"/jar/app.jar "/work/stage"/lib/*.jar)"',
+            'rm -rf "install/dir/jre"',
+            'jlink --add-modules "${JLINK_MODULES},jdk.crypto.ec,jdk.localedata" '
+            "--strip-debug --no-header-files --no-man-pages "
+            '--output "install/dir/jre"',
+        ]
+
+    def test_get_build_commands_jpackage(self, make_plugin):
+        plugin = make_plugin(
+            JLinkPlugin,
+            {"jlink-jpackage-main-jar": "jar/app.jar"},
+            project_dirs=ProjectDirs(work_dir="/work"),
+        )
+        assert plugin.get_build_commands() == [
+            'JLINK_MODULES="$(jdeps --print-module-deps --ignore-missing-deps '
+            '--multi-release base "/work/stage"/jar/*.jar)"',
+            'rm -rf "/work/parts/foo/build/jre"',
+            'jlink --add-modules "${JLINK_MODULES}" --strip-debug --no-header-files '
+            '--no-man-pages --output "/work/parts/foo/build/jre"',
+            'rm -rf "install/dir/foo"',
+            'jpackage --type app-image --name "foo" --input "/work/stage/jar" '
+            '--main-jar "app.jar" --runtime-image "/work/parts/foo/build/jre" '
+            '--dest "install/dir"',
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            JLinkPlugin.properties_class.unmarshal({"jlink-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("jlink-invalid",)
+        assert err[0]["type"] == "value_error.extra"
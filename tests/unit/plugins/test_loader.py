@@ -0,0 +1,191 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import textwrap
+from pathlib import Path
+from typing import Dict, List, Set
+
+import pytest
+
+from craft_parts import errors, plugins
+from craft_parts.plugins import loader
+
+_PLUGIN_SOURCE = textwrap.dedent(
+    """
+    from typing import Any, Dict, List, Set
+
+    from craft_parts.plugins import Plugin, PluginProperties
+
+
+    class {name}Plugin(Plugin):
+        properties_class = PluginProperties
+
+        def get_build_snaps(self) -> Set[str]:
+            return set()
+
+        def get_build_packages(self) -> Set[str]:
+            return set()
+
+        def get_build_environment(self) -> Dict[str, str]:
+            return {{}}
+
+        def get_build_commands(self) -> List[str]:
+            return ["echo {name}"]
+    """
+)
+
+
+class BarPlugin(plugins.Plugin):
+    """A test plugin."""
+
+    properties_class = plugins.PluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        return []
+
+
+class IncompletePlugin(plugins.Plugin):
+    """A test plugin missing plugin methods."""
+
+    properties_class = plugins.PluginProperties
+
+
+class BadPropertiesPlugin(BarPlugin):
+    """A test plugin with invalid properties."""
+
+    properties_class = dict  # type: ignore
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestLoadPluginsFromDir:
+    """Verify loading plugins from project files."""
+
+    def test_load_plugins(self):
+        plugins_dir = Path("plugins")
+        plugins_dir.mkdir()
+        Path(plugins_dir, "foo.py").write_text(_PLUGIN_SOURCE.format(name="Foo"))
+        Path(plugins_dir, "my_tool.py").write_text(_PLUGIN_SOURCE.format(name="Tool"))
+        Path(plugins_dir, "_helper.py").write_text("raise RuntimeError")
+        Path(plugins_dir, "notes.txt").write_text("nothing")
+
+        loaded = plugins.load_plugins_from_dir(plugins_dir)
+
+        assert sorted(loaded) == ["foo", "my-tool"]
+        assert loaded["foo"].__name__ == "FooPlugin"
+        assert loaded["my-tool"].__name__ == "ToolPlugin"
+        assert issubclass(loaded["foo"], plugins.Plugin)
+
+    def test_load_plugins_missing_dir(self):
+        assert plugins.load_plugins_from_dir("plugins") == {}
+
+    @pytest.mark.parametrize(
+        "source,message",
+        [
+            ("X = 1", "'plugins/foo.py' must define exactly one plugin class, found 0"),
+            (
+                _PLUGIN_SOURCE.format(name="A") + _PLUGIN_SOURCE.format(name="B"),
+                "'plugins/foo.py' must define exactly one plugin class, found 2",
+            ),
+            ("import nonexistent", "cannot import 'plugins/foo.py'"),
+        ],
+    )
+    def test_load_plugins_error(self, source, message):
+        Path("plugins").mkdir()
+        Path("plugins/foo.py").write_text(source)
+
+        with pytest.raises(errors.PluginLoadError) as raised:
+            plugins.load_plugins_from_dir("plugins")
+        assert raised.value.plugin_name == "foo"
+        assert raised.value.message.startswith(message)
+
+
+class TestLoadPluginsFromEntryPoints:
+    """Verify loading plugins from package entry points."""
+
+    @pytest.fixture
+    def fake_entry_points(self, mocker):
+        def _fake_entry_points(**objects):
+            entry_points = []
+            for name, obj in objects.items():
+                entry_point = mocker.Mock(value=f"test:{name}")
+                entry_point.name = name
+                if isinstance(obj, Exception):
+                    entry_point.load.side_effect = obj
+                else:
+                    entry_point.load.return_value = obj
+                entry_points.append(entry_point)
+
+            fake = mocker.Mock(spec=["select"])
+            fake.select.return_value = entry_points
+            mocker.patch("importlib.metadata.entry_points", return_value=fake)
+            return fake
+
+        return _fake_entry_points
+
+    def test_load_plugins(self, fake_entry_points):
+        fake = fake_entry_points(bar=BarPlugin)
+
+        assert plugins.load_plugins_from_entry_points("test.plugins") == {
+            "bar": BarPlugin
+        }
+        fake.select.assert_called_once_with(group="test.plugins")
+
+    def test_load_plugins_groups_dict(self, mocker):
+        entry_point = mocker.Mock(value="test:BarPlugin")
+        entry_point.name = "bar"
+        entry_point.load.return_value = BarPlugin
+        mocker.patch(
+            "importlib.metadata.entry_points",
+            return_value={"test.plugins": [entry_point]},
+        )
+
+        assert plugins.load_plugins_from_entry_points("test.plugins") == {
+            "bar": BarPlugin
+        }
+        assert plugins.load_plugins_from_entry_points("other") == {}
+
+    @pytest.mark.parametrize(
+        "obj,message",
+        [
+            (ImportError("no module"), "cannot load entry point: no module"),
+            ("BarPlugin", "'BarPlugin' is not a subclass of Plugin"),
+            (
+                IncompletePlugin,
+                "'IncompletePlugin' does not implement all plugin methods",
+            ),
+            (
+                BadPropertiesPlugin,
+                "the properties class of 'BadPropertiesPlugin' is not a subclass "
+                "of PluginProperties",
+            ),
+        ],
+    )
+    def test_load_plugins_error(self, fake_entry_points, obj, message):
+        fake_entry_points(bar=obj)
+
+        with pytest.raises(errors.PluginLoadError) as raised:
+            loader.load_plugins_from_entry_points("test.plugins")
+        assert raised.value.plugin_name == "bar"
+        assert raised.value.message == message
@@ -19,6 +19,7 @@ from pathlib import Path
 import pytest
 from pydantic import ValidationError
 
+from craft_parts.compiler_cache import CompilerCacheConfig
 from craft_parts.infos import PartInfo, ProjectInfo
 from craft_parts.parts import Part
 from craft_parts.plugins.make_plugin import MakePlugin
@@ -48,9 +49,37 @@ class TestPluginMake:
         }
         assert self._plugin.get_build_snaps() == set()
 
+    def test_get_toolchain_version_commands(self):
+        assert self._plugin.get_toolchain_version_commands() == {
+            "cc": '"${CC:-cc}" --version',
+            "make": "make --version",
+        }
+
     def test_get_build_environment(self):
         assert self._plugin.get_build_environment() == dict()
 
+    def test_compiler_cache(self):
+        properties = MakePlugin.properties_class.unmarshal({})
+        part = Part("foo", {})
+
+        project_info = ProjectInfo(
+            compiler_cache=CompilerCacheConfig(
+                cache_dir=Path("/cache"), max_size=1024 ** 3
+            )
+        )
+        part_info = PartInfo(project_info=project_info, part=part)
+
+        plugin = MakePlugin(properties=properties, part_info=part_info)
+
+        assert plugin.get_build_packages() == {"ccache", "gcc", "make"}
+        assert plugin.get_build_environment() == {
+            "CCACHE_DIR": "/cache/ccache",
+            "CCACHE_MAXSIZE": "1048576Ki",
+            "CCACHE_BASEDIR": str(Path("parts").absolute()),
+            "CC": "ccache gcc",
+            "CXX": "ccache g++",
+        }
+
     def test_get_build_commands(self):
         assert self._plugin.get_build_commands() == [
             'make -j"42"',
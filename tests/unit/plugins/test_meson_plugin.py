@@ -0,0 +1,98 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import hashlib
+from pathlib import Path
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.meson_plugin import MesonPlugin
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPluginMeson:
+    """Meson plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(MesonPlugin, {})
+        assert plugin.get_build_packages() == {"gcc", "meson", "ninja-build"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+        assert plugin.out_of_source_build is True
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(MesonPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "meson": "meson --version",
+            "ninja": "ninja --version",
+            "cc": '"${CC:-cc}" --version',
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            MesonPlugin,
+            {"meson-parameters": ["--buildtype=release"]},
+            parallel_build_count=42,
+            src_subdir="src/dir",
+        )
+        assert plugin.get_build_commands() == [
+            'meson setup --buildtype=release . "src/dir"',
+            'ninja -j "42"',
+            'DESTDIR="install/dir" ninja install',
+        ]
+
+    def test_get_build_commands_machine_files(self, make_plugin):
+        plugin = make_plugin(
+            MesonPlugin,
+            {
+                "meson-cross-file": "cross/arm64.ini",
+                "meson-native-file": "native.ini",
+            },
+            src_subdir="src/dir",
+        )
+        assert plugin.get_build_commands()[0] == (
+            'meson setup --cross-file "src/dir/cross/arm64.ini" '
+            '--native-file "src/dir/native.ini" . "src/dir"'
+        )
+
+    def test_get_build_assets(self, make_plugin):
+        Path("src/dir/cross").mkdir(parents=True)
+        Path("src/dir/cross/arm64.ini").write_text("[host_machine]\n")
+
+        plugin = make_plugin(
+            MesonPlugin,
+            {
+                "meson-cross-file": "cross/arm64.ini",
+                "meson-native-file": "native.ini",
+            },
+            src_subdir="src/dir",
+        )
+        assert plugin.get_build_assets() == {
+            "meson-cross-file": hashlib.sha256(b"[host_machine]\n").hexdigest(),
+        }
+
+    def test_get_build_assets_no_files(self, make_plugin):
+        plugin = make_plugin(MesonPlugin, {})
+        assert plugin.get_build_assets() == {}
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            MesonPlugin.properties_class.unmarshal({"meson-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("meson-invalid",)
+        assert err[0]["type"] == "value_error.extra"
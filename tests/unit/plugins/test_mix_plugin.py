@@ -0,0 +1,60 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.mix_plugin import MixPlugin
+
+
+class TestPluginMix:
+    """Mix plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(MixPlugin, {})
+        assert plugin.get_build_packages() == {"elixir", "erlang-dev", "git"}
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(MixPlugin, {})
+        assert plugin.get_build_environment() == {"MIX_ENV": "prod"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(MixPlugin, {})
+        assert plugin.get_build_commands() == [
+            "mix local.hex --force --if-missing",
+            "mix local.rebar --force --if-missing",
+            'mix deps.get --only "prod"',
+            "mix compile",
+            'mix release --overwrite --path "install/dir"',
+        ]
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(MixPlugin, {"mix-env": "staging", "mix-release": "server"})
+        assert plugin.get_build_environment() == {"MIX_ENV": "staging"}
+        assert plugin.get_build_commands()[2:] == [
+            'mix deps.get --only "staging"',
+            "mix compile",
+            'mix release "server" --overwrite --path "install/dir"',
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            MixPlugin.properties_class.unmarshal({"mix-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("mix-invalid",)
+        assert err[0]["type"] == "value_error.extra"
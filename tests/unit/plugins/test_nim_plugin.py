@@ -0,0 +1,93 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.nim_plugin import NimPlugin
+
+_NIMBLE_DEPS = "if ls *.nimble >/dev/null 2>&1; then nimble install --depsOnly -y; fi"
+_NIMBLE_PATHS = '--nimblePath:"${NIMBLE_DIR}/pkgs2" --nimblePath:"${NIMBLE_DIR}/pkgs"'
+
+
+@pytest.fixture(autouse=True)
+def setup_host(mocker):
+    mocker.patch("platform.machine", return_value="x86_64")
+    mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/nimble")
+
+
+class TestPluginNim:
+    """Nim plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(NimPlugin, {})
+        assert plugin.get_build_packages() == {"gcc", "git", "nim"}
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(NimPlugin, {})
+        assert plugin.get_build_environment() == {"NIMBLE_DIR": "/cache/nimble"}
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(NimPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "nim": "nim --version",
+            "nimble": "nimble --version",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(NimPlugin, {})
+        assert plugin.get_build_commands() == [
+            _NIMBLE_DEPS,
+            'mkdir -p "install/dir/bin"',
+            "nimble build -y -d:release",
+            'nimble_bin_dir="$(nimble dump | '
+            "sed -n 's/^binDir: \"\\(.*\\)\"$/\\1/p')\"",
+            "for bin in $(nimble dump | sed -n 's/^bin: \"\\(.*\\)\"$/\\1/p' | "
+            "tr ',' ' '); do "
+            'install -m 755 "${nimble_bin_dir:-.}/$(basename "$bin")" '
+            '"install/dir/bin"; done',
+        ]
+
+    def test_get_build_commands_sources(self, make_plugin):
+        plugin = make_plugin(
+            NimPlugin,
+            {"nim-sources": ["src/foo.nim", "src/bar.nim"], "nim-flags": ["--mm:orc"]},
+        )
+        assert plugin.get_build_commands() == [
+            _NIMBLE_DEPS,
+            'mkdir -p "install/dir/bin"',
+            f"nim c -d:release --mm:orc {_NIMBLE_PATHS} "
+            '--outdir:"install/dir/bin" "src/foo.nim"',
+            f"nim c -d:release --mm:orc {_NIMBLE_PATHS} "
+            '--outdir:"install/dir/bin" "src/bar.nim"',
+        ]
+
+    def test_get_build_commands_cross_compiling(self, make_plugin):
+        plugin = make_plugin(NimPlugin, {"nim-sources": ["foo.nim"]}, arch="aarch64")
+        assert plugin.get_build_commands()[-1] == (
+            "nim c -d:release --cpu:arm64 --os:linux "
+            "--gcc.exe:aarch64-linux-gnu-gcc --gcc.linkerexe:aarch64-linux-gnu-gcc "
+            f'{_NIMBLE_PATHS} --outdir:"install/dir/bin" "foo.nim"'
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            NimPlugin.properties_class.unmarshal({"nim-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("nim-invalid",)
+        assert err[0]["type"] == "value_error.extra"
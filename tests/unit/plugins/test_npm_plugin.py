@@ -0,0 +1,197 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import json
+from pathlib import Path
+from typing import Any, Dict
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts import errors
+from craft_parts.plugins.base import get_download_command
+from craft_parts.plugins.npm_plugin import NpmPlugin
+
+
+def _write_package_json(content: Dict[str, Any]):
+    build_dir = Path("parts/foo/build")
+    build_dir.mkdir(parents=True, exist_ok=True)
+    (build_dir / "package.json").write_text(json.dumps(content))
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPluginNpm:
+    """Npm plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_build_packages() == {"curl", "xz-utils"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "node": "node --version",
+            "npm": "npm --version",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        _write_package_json({"name": "hello", "bin": {"hello": "bin/hello.js"}})
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_build_commands() == [
+            "npm ci --omit=dev",
+            'mkdir -p "install/dir/lib/node_modules/hello"',
+            'cp --archive . "install/dir/lib/node_modules/hello"',
+            'mkdir -p "install/dir/bin"',
+            'ln -sf "../lib/node_modules/hello/bin/hello.js" '
+            '"install/dir/bin/hello"',
+        ]
+
+    def test_get_build_commands_bin_string(self, make_plugin):
+        _write_package_json({"name": "@scope/hello", "bin": "cli.js"})
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_build_commands()[1:] == [
+            'mkdir -p "install/dir/lib/node_modules/@scope/hello"',
+            'cp --archive . "install/dir/lib/node_modules/@scope/hello"',
+            'mkdir -p "install/dir/bin"',
+            'ln -sf "../lib/node_modules/@scope/hello/cli.js" '
+            '"install/dir/bin/hello"',
+        ]
+
+    def test_get_build_commands_no_name_no_bin(self, make_plugin):
+        _write_package_json({})
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_build_commands() == [
+            "npm ci --omit=dev",
+            'mkdir -p "install/dir/lib/node_modules/foo"',
+            'cp --archive . "install/dir/lib/node_modules/foo"',
+        ]
+
+    def test_get_build_commands_pnpm(self, make_plugin):
+        _write_package_json({"name": "hello"})
+        plugin = make_plugin(NpmPlugin, {"node-package-manager": "pnpm"})
+        assert plugin.get_build_commands()[0] == (
+            "pnpm install --frozen-lockfile --prod"
+        )
+
+    def test_get_build_commands_yarn_classic(self, make_plugin):
+        _write_package_json({"name": "hello"})
+        plugin = make_plugin(NpmPlugin, {"node-package-manager": "yarn"})
+        assert plugin.get_build_commands()[0] == (
+            "yarn install --frozen-lockfile --production"
+        )
+
+    def test_get_build_commands_yarn_berry(self, make_plugin):
+        _write_package_json({"name": "hello"})
+        Path("parts/foo/build/.yarnrc.yml").write_text("nodeLinker: node-modules\n")
+        plugin = make_plugin(NpmPlugin, {"node-package-manager": "yarn"})
+        assert plugin.get_build_commands()[0] == "yarn install --immutable"
+
+    def test_get_build_commands_no_package_json(self, make_plugin):
+        plugin = make_plugin(NpmPlugin, {})
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            plugin.get_build_commands()
+        assert raised.value.part_name == "foo"
+        assert raised.value.reason.startswith("cannot read package.json:")
+
+    def test_invalid_package_manager(self):
+        with pytest.raises(ValidationError) as raised:
+            NpmPlugin.properties_class.unmarshal({"node-package-manager": "bun"})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("node-package-manager",)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            NpmPlugin.properties_class.unmarshal({"npm-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("npm-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPluginNpmNodeVersion:
+    """Npm plugin tests provisioning Node.js."""
+
+    @pytest.fixture(autouse=True)
+    def cache_dir(self, mocker):
+        mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/node")
+
+    def test_get_build_commands(self, make_plugin):
+        _write_package_json({"name": "hello"})
+        Path("parts/foo/build/.nvmrc").write_text("v20.11.1\n")
+        plugin = make_plugin(NpmPlugin, {})
+        node = "/cache/node/node-v20.11.1-linux-x64"
+        assert plugin.get_build_commands() == [
+            get_download_command(
+                "https://nodejs.org/dist/v20.11.1/node-v20.11.1-linux-x64.tar.xz",
+                f"{node}.tar.xz",
+                checksums_url="https://nodejs.org/dist/v20.11.1/SHASUMS256.txt",
+            ),
+            f'if [ ! -x "{node}/bin/node" ]; then '
+            f'mkdir -p "{node}" && '
+            f'tar -xJf "{node}.tar.xz" -C "{node}" --strip-components=1; fi',
+            'mkdir -p "install/dir"',
+            f'cp --archive "{node}/bin" "{node}/lib" "install/dir"',
+            "npm ci --omit=dev",
+            'mkdir -p "install/dir/lib/node_modules/hello"',
+            'cp --archive . "install/dir/lib/node_modules/hello"',
+        ]
+
+    def test_get_build_environment(self, make_plugin):
+        _write_package_json({"name": "hello", "engines": {"node": "20.11.1"}})
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/node/node-v20.11.1-linux-x64/bin:${PATH}",
+        }
+        assert plugin.get_build_assets() == {"node-version": "20.11.1"}
+
+    @pytest.mark.parametrize(
+        "nvmrc,engines,version",
+        [
+            ("20.11.1", "18.19.0", "20.11.1"),
+            (None, "=18.19.0", "18.19.0"),
+            (None, "v18.19.0", "18.19.0"),
+            (None, ">=18", None),
+            ("lts/iron", "18.19.0", None),
+            (None, None, None),
+        ],
+    )
+    def test_node_version(self, make_plugin, nvmrc, engines, version):
+        package: Dict[str, Any] = {"name": "hello"}
+        if engines:
+            package["engines"] = {"node": engines}
+        _write_package_json(package)
+        if nvmrc:
+            Path("parts/foo/build/.nvmrc").write_text(nvmrc)
+
+        plugin = make_plugin(NpmPlugin, {})
+        if version:
+            assert plugin.get_build_assets() == {"node-version": version}
+        else:
+            assert plugin.get_build_assets() == {}
+            assert plugin.get_build_environment() == {}
+
+    def test_get_build_commands_cross(self, make_plugin, mocker):
+        mocker.patch("platform.machine", return_value="aarch64")
+        _write_package_json({"name": "hello", "engines": {"node": "20.11.1"}})
+        plugin = make_plugin(NpmPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/node/node-v20.11.1-linux-arm64/bin:${PATH}",
+        }
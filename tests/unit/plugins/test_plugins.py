@@ -24,9 +24,39 @@ from craft_parts.parts import Part
 from craft_parts.plugins import nil_plugin
 from craft_parts.plugins.plugins import (
     AutotoolsPlugin,
+    BazelPlugin,
+    BunPlugin,
+    CMakePlugin,
+    ComposerPlugin,
+    CondaPlugin,
+    CrystalPlugin,
+    DenoPlugin,
+    DotnetPlugin,
     DumpPlugin,
+    DunePlugin,
+    FlutterPlugin,
+    GoPlugin,
+    GoUsePlugin,
+    GradlePlugin,
+    HaskellPlugin,
+    JLinkPlugin,
     MakePlugin,
+    MavenPlugin,
+    MesonPlugin,
+    MixPlugin,
     NilPlugin,
+    NimPlugin,
+    NpmPlugin,
+    PoetryPlugin,
+    PythonPlugin,
+    QmakePlugin,
+    Rebar3Plugin,
+    RubyPlugin,
+    RustPlugin,
+    SConsPlugin,
+    SwiftPlugin,
+    UvPlugin,
+    ZigPlugin,
 )
 
 
@@ -41,9 +71,39 @@ class TestGetPlugin:
         "name,plugin_class",
         [
             ("autotools", AutotoolsPlugin),
+            ("bazel", BazelPlugin),
+            ("bun", BunPlugin),
+            ("cmake", CMakePlugin),
+            ("composer", ComposerPlugin),
+            ("conda", CondaPlugin),
+            ("crystal", CrystalPlugin),
+            ("deno", DenoPlugin),
+            ("dotnet", DotnetPlugin),
             ("dump", DumpPlugin),
+            ("dune", DunePlugin),
+            ("flutter", FlutterPlugin),
+            ("go", GoPlugin),
+            ("go-use", GoUsePlugin),
+            ("gradle", GradlePlugin),
+            ("haskell", HaskellPlugin),
+            ("jlink", JLinkPlugin),
             ("make", MakePlugin),
+            ("maven", MavenPlugin),
+            ("meson", MesonPlugin),
+            ("mix", MixPlugin),
             ("nil", NilPlugin),
+            ("nim", NimPlugin),
+            ("npm", NpmPlugin),
+            ("poetry", PoetryPlugin),
+            ("python", PythonPlugin),
+            ("qmake", QmakePlugin),
+            ("rebar3", Rebar3Plugin),
+            ("ruby", RubyPlugin),
+            ("rust", RustPlugin),
+            ("scons", SConsPlugin),
+            ("swift", SwiftPlugin),
+            ("uv", UvPlugin),
+            ("zig", ZigPlugin),
         ],
     )
     def test_get_plugin(self, name, plugin_class):
@@ -133,6 +193,25 @@ class TestPluginRegistry:
         with pytest.raises(ValueError):
             plugins.get_plugin_class("foo")
 
+    def test_unregister(self):
+        plugins.register({"foo": FooPlugin, "dump": FooPlugin})
+
+        plugins.unregister("foo", "dump")
+        with pytest.raises(ValueError):
+            plugins.get_plugin_class("foo")
+        assert plugins.get_plugin_class("dump") == DumpPlugin
+
+    def test_get_registered_plugins(self):
+        registered = plugins.get_registered_plugins()
+        assert registered["dump"] == DumpPlugin
+        assert "foo" not in registered
+
+        plugins.register({"foo": FooPlugin})
+        assert plugins.get_registered_plugins()["foo"] == FooPlugin
+
+        plugins.unregister_all()
+        assert "foo" not in plugins.get_registered_plugins()
+
 
 class TestHelpers:
     """Verify plugin helper functions."""
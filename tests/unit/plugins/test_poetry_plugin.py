@@ -0,0 +1,102 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.poetry_plugin import PoetryPlugin
+
+
+def _get_install_commands(export_command: str) -> list:
+    return [
+        '"${PARTS_PYTHON_INTERPRETER}" -m venv ${PARTS_PYTHON_VENV_ARGS} '
+        '"install/dir"',
+        'PARTS_PYTHON_VENV_INTERP_PATH="install/dir/bin/${PARTS_PYTHON_INTERPRETER}"',
+        export_command,
+        'pip install --requirement="build/dir/requirements.txt"',
+        "pip install --no-deps .",
+        'find "install/dir" -type f -executable -print0 | xargs -0 '
+        'sed -i "1 s|^#\\!${PARTS_PYTHON_VENV_INTERP_PATH}.*$|'
+        '#!/usr/bin/env ${PARTS_PYTHON_INTERPRETER}|"',
+        'ln -sf "$(readlink -f "$(which "${PARTS_PYTHON_INTERPRETER}")")" '
+        '"install/dir/bin/${PARTS_PYTHON_INTERPRETER}"',
+    ]
+
+
+class TestPluginPoetry:
+    """Poetry plugin tests."""
+
+    @pytest.mark.parametrize(
+        "has_poetry,packages",
+        [
+            (True, {"findutils", "python3-dev", "python3-venv"}),
+            (False, {"findutils", "python3-dev", "python3-venv", "python3-poetry"}),
+        ],
+    )
+    def test_get_build_packages(self, make_plugin, mocker, has_poetry, packages):
+        mocker.patch(
+            "shutil.which", return_value="/usr/bin/poetry" if has_poetry else None
+        )
+        plugin = make_plugin(PoetryPlugin, {})
+        assert plugin.get_build_packages() == packages
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(PoetryPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PATH": "install/dir/bin:${PATH}",
+            "PARTS_PYTHON_INTERPRETER": "python3",
+            "PARTS_PYTHON_VENV_ARGS": "",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(PoetryPlugin, {}, build_dir="build/dir")
+        assert plugin.get_build_commands() == _get_install_commands(
+            "poetry export --format=requirements.txt "
+            '--output="build/dir/requirements.txt" --with-credentials'
+        )
+
+    def test_get_build_commands_groups(self, make_plugin):
+        plugin = make_plugin(
+            PoetryPlugin,
+            {"poetry-with": ["docs", "gui"], "poetry-without": ["dev"]},
+            build_dir="build/dir",
+        )
+        assert plugin.get_build_commands() == _get_install_commands(
+            "poetry export --format=requirements.txt "
+            '--output="build/dir/requirements.txt" --with-credentials '
+            "--with=docs,gui --without=dev"
+        )
+
+    def test_get_build_commands_strict_lock(self, make_plugin):
+        plugin = make_plugin(
+            PoetryPlugin, {"poetry-strict-lock": True}, build_dir="build/dir"
+        )
+        assert plugin.get_build_commands() == [
+            "poetry check --lock",
+            *_get_install_commands(
+                "poetry export --format=requirements.txt "
+                '--output="build/dir/requirements.txt" --with-credentials'
+            ),
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            PoetryPlugin.properties_class.unmarshal({"poetry-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("poetry-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,115 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.python_plugin import PythonPlugin
+
+
+def _get_build_commands(*, install_commands) -> list:
+    return [
+        '"${PARTS_PYTHON_INTERPRETER}" -m venv ${PARTS_PYTHON_VENV_ARGS} '
+        '"install/dir"',
+        'PARTS_PYTHON_VENV_INTERP_PATH="install/dir/bin/${PARTS_PYTHON_INTERPRETER}"',
+        *install_commands,
+        'find "install/dir" -type f -executable -print0 | xargs -0 '
+        'sed -i "1 s|^#\\!${PARTS_PYTHON_VENV_INTERP_PATH}.*$|'
+        '#!/usr/bin/env ${PARTS_PYTHON_INTERPRETER}|"',
+        'ln -sf "$(readlink -f "$(which "${PARTS_PYTHON_INTERPRETER}")")" '
+        '"install/dir/bin/${PARTS_PYTHON_INTERPRETER}"',
+    ]
+
+
+class TestPluginPython:
+    """Python plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(PythonPlugin, {})
+        assert plugin.get_build_packages() == {
+            "findutils",
+            "python3-dev",
+            "python3-venv",
+        }
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(PythonPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "python": '"${PARTS_PYTHON_INTERPRETER}" --version',
+        }
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(PythonPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PATH": "install/dir/bin:${PATH}",
+            "PARTS_PYTHON_INTERPRETER": "python3",
+            "PARTS_PYTHON_VENV_ARGS": "",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(PythonPlugin, {})
+        assert plugin.get_build_commands() == _get_build_commands(
+            install_commands=[
+                "pip install -U 'pip' 'setuptools' 'wheel'",
+                "if [ -f setup.py ] || [ -f pyproject.toml ]; then "
+                "pip install -U .; fi",
+            ]
+        )
+
+    def test_get_build_commands_requirements_constraints(self, make_plugin):
+        plugin = make_plugin(
+            PythonPlugin,
+            {
+                "python-packages": ["pytest"],
+                "python-requirements": ["requirements.txt", "dev.txt"],
+                "python-constraints": ["constraints.txt"],
+            },
+        )
+        assert plugin.get_build_commands() == _get_build_commands(
+            install_commands=[
+                "pip install -c 'constraints.txt' -U 'pytest'",
+                "pip install -c 'constraints.txt' -U "
+                "-r 'requirements.txt' -r 'dev.txt'",
+                "if [ -f setup.py ] || [ -f pyproject.toml ]; then "
+                "pip install -c 'constraints.txt' -U .; fi",
+            ]
+        )
+
+    def test_get_build_commands_require_hashes(self, make_plugin):
+        plugin = make_plugin(
+            PythonPlugin,
+            {
+                "python-packages": [],
+                "python-requirements": ["requirements.lock"],
+                "python-require-hashes": True,
+            },
+        )
+        assert plugin.get_build_commands() == _get_build_commands(
+            install_commands=[
+                "pip install --require-hashes -U -r 'requirements.lock'",
+                "if [ -f setup.py ] || [ -f pyproject.toml ]; then "
+                "pip install --no-deps -U .; fi",
+            ]
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            PythonPlugin.properties_class.unmarshal({"python-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("python-invalid",)
+        assert err[0]["type"] == "value_error.extra"
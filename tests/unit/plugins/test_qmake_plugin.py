@@ -0,0 +1,97 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.qmake_plugin import QmakePlugin
+
+_FLAGS = (
+    'QMAKE_CFLAGS+="${CFLAGS:-}" QMAKE_CXXFLAGS+="${CXXFLAGS:-}" '
+    'QMAKE_LFLAGS+="${LDFLAGS:-}"'
+)
+
+
+class TestPluginQmake:
+    """Qmake plugin tests."""
+
+    def test_out_of_source_build(self, make_plugin):
+        plugin = make_plugin(QmakePlugin, {})
+        assert plugin.out_of_source_build is True
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(QmakePlugin, {})
+        assert plugin.get_build_packages() == {
+            "g++",
+            "make",
+            "qt5-qmake",
+            "qtbase5-dev",
+        }
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {"QT_SELECT": "qt5"}
+
+    def test_get_build_packages_qt6(self, make_plugin):
+        plugin = make_plugin(QmakePlugin, {"qmake-qt-version": "6"})
+        assert plugin.get_build_packages() == {
+            "g++",
+            "make",
+            "qmake6",
+            "qt6-base-dev",
+        }
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            QmakePlugin, {}, parallel_build_count=42, src_subdir="src/dir"
+        )
+        assert plugin.get_build_commands() == [
+            f'qmake {_FLAGS} "src/dir"',
+            'env -u CFLAGS -u CXXFLAGS make -j"42"',
+            'make install INSTALL_ROOT="install/dir"',
+        ]
+
+    def test_get_build_commands_with_options(self, make_plugin):
+        plugin = make_plugin(
+            QmakePlugin,
+            {
+                "qmake-qt-version": 6,
+                "qmake-project-file": "app/app.pro",
+                "qmake-config": ["release", "c++17"],
+                "qmake-defines": ["APP_VERSION=1.0"],
+                "qmake-parameters": ["-after"],
+            },
+            src_subdir="src/dir",
+        )
+        assert plugin.get_build_commands()[0] == (
+            f'qmake6 {_FLAGS} CONFIG+="release" CONFIG+="c++17" '
+            'DEFINES+="APP_VERSION=1.0" -after "src/dir/app/app.pro"'
+        )
+
+    def test_invalid_qt_version(self):
+        with pytest.raises(ValidationError) as raised:
+            QmakePlugin.properties_class.unmarshal({"qmake-qt-version": "4"})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("qmake-qt-version",)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            QmakePlugin.properties_class.unmarshal({"qmake-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("qmake-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,72 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.rebar3_plugin import Rebar3Plugin
+
+
+class TestPluginRebar3:
+    """Rebar3 plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(Rebar3Plugin, {})
+        assert plugin.get_build_packages() == {"erlang", "git", "rebar3"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_environment_hex_mirror(self, make_plugin):
+        plugin = make_plugin(
+            Rebar3Plugin, {"rebar3-hex-mirror": "https://hex.example.com"}
+        )
+        assert plugin.get_build_environment() == {
+            "HEX_CDN": "https://hex.example.com",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(Rebar3Plugin, {})
+        assert plugin.get_build_commands() == [
+            'rebar3 as "prod" release',
+            'mkdir -p "install/dir"',
+            'cp --archive "_build/prod/rel/." "install/dir"',
+        ]
+
+    def test_get_build_commands_escriptize(self, make_plugin):
+        plugin = make_plugin(
+            Rebar3Plugin, {"rebar3-target": "escriptize", "rebar3-profile": "default"}
+        )
+        assert plugin.get_build_commands() == [
+            'rebar3 as "default" escriptize',
+            'mkdir -p "install/dir/bin"',
+            'cp --archive "_build/default/bin/." "install/dir/bin"',
+        ]
+
+    def test_invalid_target(self):
+        with pytest.raises(ValidationError) as raised:
+            Rebar3Plugin.properties_class.unmarshal({"rebar3-target": "compile"})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("rebar3-target",)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            Rebar3Plugin.properties_class.unmarshal({"rebar3-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("rebar3-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,63 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.ruby_plugin import RubyPlugin
+
+
+class TestPluginRuby:
+    """Ruby plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(RubyPlugin, {})
+        assert {"bundler", "gcc", "make", "ruby-dev"} <= plugin.get_build_packages()
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(RubyPlugin, {}, parallel_build_count=42)
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir/app"',
+            'cp --archive . "install/dir/app"',
+            'cd "install/dir/app"',
+            "bundle config set --local deployment true",
+            'bundle config set --local without "development:test"',
+            'bundle install -j "42"',
+        ]
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(
+            RubyPlugin,
+            {"ruby-without": [], "ruby-app-dir": "srv/app"},
+            parallel_build_count=42,
+        )
+        assert plugin.get_build_commands() == [
+            'mkdir -p "install/dir/srv/app"',
+            'cp --archive . "install/dir/srv/app"',
+            'cd "install/dir/srv/app"',
+            "bundle config set --local deployment true",
+            'bundle install -j "42"',
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            RubyPlugin.properties_class.unmarshal({"ruby-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("ruby-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,265 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import textwrap
+from pathlib import Path
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts import errors
+from craft_parts.compiler_cache import CompilerCacheConfig
+from craft_parts.plugins.base import get_download_command
+from craft_parts.plugins.rust_plugin import RustPlugin
+
+_RUSTUP_URL = (
+    "https://static.rust-lang.org/rustup/dist/x86_64-unknown-linux-gnu/rustup-init"
+)
+_RUSTUP_INIT = "/cache/rust/rustup-init-x86_64-unknown-linux-gnu"
+_RUSTUP_INSTALL = (
+    get_download_command(
+        _RUSTUP_URL, _RUSTUP_INIT, checksums_url=f"{_RUSTUP_URL}.sha256"
+    )
+    + f' && chmod +x "{_RUSTUP_INIT}" && '
+    f'"{_RUSTUP_INIT}" -y --no-modify-path --profile=minimal'
+)
+
+
+@pytest.fixture
+def workspace(new_dir):
+    build_dir = Path("parts/foo/build")
+    manifests = {
+        ".": '[workspace]\nmembers = ["crates/*"]\n',
+        "crates/server": '[package]\nname = "server"\nversion = "0.1.0"\n',
+        "crates/client": textwrap.dedent(
+            """\
+            [package]
+            version = "0.1.0"
+            name = 'client'
+
+            [dependencies]
+            name = "not-a-package"
+            """
+        ),
+        "target/package": '[package]\nname = "built"\n',
+    }
+    for path, content in manifests.items():
+        crate_dir = build_dir / path
+        crate_dir.mkdir(parents=True, exist_ok=True)
+        (crate_dir / "Cargo.toml").write_text(content)
+
+
+class TestPluginRust:
+    """Rust plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(RustPlugin, {})
+        assert plugin.get_build_packages() == {"curl", "gcc", "git", "pkg-config"}
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(RustPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "rustc": "rustc --version",
+            "cargo": "cargo --version",
+        }
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(RustPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PATH": "${HOME}/.cargo/bin:${PATH}"
+        }
+
+    def test_compiler_cache(self, make_plugin):
+        plugin = make_plugin(
+            RustPlugin,
+            {},
+            compiler_cache=CompilerCacheConfig(
+                cache_dir=Path("/cache"), max_size=1024 ** 3
+            ),
+        )
+        assert plugin.get_build_packages() == {
+            "curl",
+            "gcc",
+            "git",
+            "pkg-config",
+            "sccache",
+        }
+        assert plugin.get_build_environment() == {
+            "RUSTC_WRAPPER": "sccache",
+            "SCCACHE_DIR": "/cache/sccache",
+            "SCCACHE_CACHE_SIZE": "1048576K",
+            "PATH": "${HOME}/.cargo/bin:${PATH}",
+        }
+
+    def test_get_build_commands(self, make_plugin, mocker):
+        mocker.patch(
+            "xdg.BaseDirectory.save_cache_path", return_value="/cache/rust"
+        )
+        plugin = make_plugin(RustPlugin, {}, parallel_build_count=42)
+        assert plugin.get_build_commands() == [
+            "if ! command -v cargo > /dev/null 2>&1; then "
+            f"{_RUSTUP_INSTALL}; fi",
+            'cargo install --locked -j "42" --path "." --root "install/dir" --force',
+        ]
+
+    def test_get_build_commands_paths(self, make_plugin):
+        plugin = make_plugin(
+            RustPlugin, {"rust-path": ["a", "b"]}, parallel_build_count=42
+        )
+        assert plugin.get_build_commands()[1:] == [
+            'cargo install --locked -j "42" --path "a" --root "install/dir" --force',
+            'cargo install --locked -j "42" --path "b" --root "install/dir" --force',
+        ]
+
+    def test_get_build_commands_features(self, make_plugin):
+        plugin = make_plugin(
+            RustPlugin,
+            {"rust-features": ["tls", "metrics"], "rust-no-default-features": True},
+            parallel_build_count=42,
+        )
+        assert plugin.get_build_commands()[1:] == [
+            'cargo install --locked -j "42" --path "." --root "install/dir" --force '
+            '--no-default-features --features "tls,metrics"',
+        ]
+
+    @pytest.mark.usefixtures("workspace")
+    def test_get_build_commands_packages(self, make_plugin):
+        plugin = make_plugin(
+            RustPlugin, {"rust-packages": ["server", "client"]}, parallel_build_count=42
+        )
+        assert plugin.get_build_commands()[1:] == [
+            'cargo install --locked -j "42" --path "crates/server" '
+            '--root "install/dir" --force',
+            'cargo install --locked -j "42" --path "crates/client" '
+            '--root "install/dir" --force',
+        ]
+
+    @pytest.mark.usefixtures("workspace")
+    def test_get_build_commands_packages_not_found(self, make_plugin):
+        plugin = make_plugin(RustPlugin, {"rust-packages": ["server", "built"]})
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            plugin.get_build_commands()
+        assert raised.value.part_name == "foo"
+        assert raised.value.reason == (
+            "package 'built' not found in the cargo workspace"
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            RustPlugin.properties_class.unmarshal({"rust-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("rust-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+    def test_build_properties(self):
+        assert RustPlugin.properties_class.get_build_properties() == [
+            "rust-path",
+            "rust-packages",
+            "rust-features",
+            "rust-no-default-features",
+            "rust-channel",
+        ]
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPluginRustToolchain:
+    """Rust plugin tests installing pinned toolchains."""
+
+    @pytest.fixture(autouse=True)
+    def cache_dir(self, mocker):
+        mocker.patch(
+            "xdg.BaseDirectory.save_cache_path", return_value="/cache/rust"
+        )
+
+    def _write_build_file(self, name: str, content: str):
+        build_dir = Path("parts/foo/build")
+        build_dir.mkdir(parents=True, exist_ok=True)
+        (build_dir / name).write_text(content)
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(RustPlugin, {"rust-channel": "1.75.0"})
+        assert plugin.get_build_environment() == {
+            "RUSTUP_HOME": "/cache/rust/rustup",
+            "CARGO_HOME": "/cache/rust/cargo",
+            "RUSTUP_TOOLCHAIN": "1.75.0",
+            "PATH": "/cache/rust/cargo/bin:${PATH}",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(
+            RustPlugin, {"rust-channel": "1.75.0"}, parallel_build_count=42
+        )
+        assert plugin.get_build_commands() == [
+            'if [ ! -x "/cache/rust/cargo/bin/rustup" ]; then '
+            f"{_RUSTUP_INSTALL} --default-toolchain none; fi",
+            'rustup toolchain install "1.75.0" --profile minimal',
+            'cargo install --locked -j "42" --path "." --root "install/dir" --force',
+        ]
+
+    def test_channel_from_toolchain_file(self, make_plugin):
+        self._write_build_file(
+            "rust-toolchain.toml",
+            '[toolchain]\nchannel = "1.74.1"\ncomponents = ["rustfmt"]\n',
+        )
+        plugin = make_plugin(RustPlugin, {})
+        assert plugin.get_build_environment()["RUSTUP_TOOLCHAIN"] == "1.74.1"
+        assert plugin.get_build_commands()[1] == (
+            'rustup toolchain install "1.74.1" --profile minimal'
+        )
+
+    def test_channel_option_overrides_toolchain_file(self, make_plugin):
+        self._write_build_file(
+            "rust-toolchain.toml", '[toolchain]\nchannel = "1.74.1"\n'
+        )
+        plugin = make_plugin(RustPlugin, {"rust-channel": "nightly-2024-01-01"})
+        assert plugin.get_build_environment()["RUSTUP_TOOLCHAIN"] == (
+            "nightly-2024-01-01"
+        )
+
+    @pytest.mark.parametrize(
+        "content", ["1.70.0\n", '[toolchain]\nchannel = "1.70.0"\n']
+    )
+    def test_channel_from_legacy_toolchain_file(self, make_plugin, content):
+        self._write_build_file("rust-toolchain", content)
+        plugin = make_plugin(RustPlugin, {})
+        assert plugin.get_build_environment()["RUSTUP_TOOLCHAIN"] == "1.70.0"
+
+    def test_get_build_assets(self, make_plugin, mocker):
+        run = mocker.patch(
+            "subprocess.run",
+            return_value=mocker.Mock(stdout=b"rustc 1.75.0 (82e1608df 2023-12-21)\n"),
+        )
+        plugin = make_plugin(RustPlugin, {"rust-channel": "stable"})
+        assert plugin.get_build_assets() == {
+            "rust-channel": "stable",
+            "rust-version": "rustc 1.75.0 (82e1608df 2023-12-21)",
+        }
+        assert run.mock_calls[0].args[0] == [
+            "/cache/rust/cargo/bin/rustc",
+            "+stable",
+            "--version",
+        ]
+
+    def test_get_build_assets_no_rustc(self, make_plugin, mocker):
+        mocker.patch("subprocess.run", side_effect=FileNotFoundError())
+        plugin = make_plugin(RustPlugin, {"rust-channel": "stable"})
+        assert plugin.get_build_assets() == {"rust-channel": "stable"}
+
+    def test_get_build_assets_not_pinned(self, make_plugin):
+        plugin = make_plugin(RustPlugin, {})
+        assert plugin.get_build_assets() == {}
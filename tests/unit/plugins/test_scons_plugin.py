@@ -0,0 +1,71 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.scons_plugin import SConsPlugin
+
+
+class TestPluginSCons:
+    """SCons plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(SConsPlugin, {})
+        assert plugin.get_build_packages() == {"gcc", "g++", "scons"}
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(SConsPlugin, {})
+        assert plugin.get_build_environment() == dict()
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(SConsPlugin, {}, parallel_build_count=42)
+        assert plugin.get_build_commands() == [
+            'scons -j"42"',
+            'scons -j"42" DESTDIR="install/dir" install',
+        ]
+
+    def test_get_build_commands_with_options(self, make_plugin):
+        plugin = make_plugin(
+            SConsPlugin,
+            {
+                "scons-parameters": ["--warn=no-all"],
+                "scons-variables": {"PREFIX": "/usr", "target": "release"},
+                "scons-targets": ["app", "tools"],
+                "scons-install-target": "install-app",
+            },
+            parallel_build_count=42,
+        )
+        assert plugin.get_build_commands() == [
+            'scons -j"42" --warn=no-all PREFIX="/usr" target="release" app tools',
+            'scons -j"42" --warn=no-all PREFIX="/usr" target="release" '
+            'DESTDIR="install/dir" install-app',
+        ]
+
+    def test_get_build_commands_without_install(self, make_plugin):
+        plugin = make_plugin(
+            SConsPlugin, {"scons-install-target": ""}, parallel_build_count=42
+        )
+        assert plugin.get_build_commands() == ['scons -j"42"']
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            SConsPlugin.properties_class.unmarshal({"scons-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("scons-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,75 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.swift_plugin import SwiftPlugin
+
+_COPY_RESOURCES = (
+    'find "${SWIFT_BIN_PATH}" -maxdepth 1 -name "*.resources" '
+    '-exec cp --archive {} "install/dir/bin" \\;'
+)
+
+
+class TestPluginSwift:
+    """Swift plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(SwiftPlugin, {})
+        assert plugin.get_build_packages() == {"clang"}
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(SwiftPlugin, {}, parallel_build_count=42)
+        assert plugin.get_build_commands() == [
+            'swift build -c release -j "42"',
+            'SWIFT_BIN_PATH="$(swift build -c release --show-bin-path)"',
+            'mkdir -p "install/dir/bin"',
+            'find "${SWIFT_BIN_PATH}" -maxdepth 1 -type f -executable '
+            '-exec cp --archive {} "install/dir/bin" \\;',
+            _COPY_RESOURCES,
+        ]
+
+    def test_get_build_commands_products(self, make_plugin):
+        plugin = make_plugin(
+            SwiftPlugin,
+            {
+                "swift-products": ["server", "migrate"],
+                "swift-swiftc-flags": ["-static-stdlib"],
+                "swift-linker-flags": ["-s"],
+            },
+            parallel_build_count=42,
+        )
+        flags = '-Xswiftc "-static-stdlib" -Xlinker "-s"'
+        assert plugin.get_build_commands() == [
+            f'swift build -c release -j "42" --product "server" {flags}',
+            f'swift build -c release -j "42" --product "migrate" {flags}',
+            'SWIFT_BIN_PATH="$(swift build -c release --show-bin-path)"',
+            'mkdir -p "install/dir/bin"',
+            'cp --archive "${SWIFT_BIN_PATH}/server" "install/dir/bin"',
+            'cp --archive "${SWIFT_BIN_PATH}/migrate" "install/dir/bin"',
+            _COPY_RESOURCES,
+        ]
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            SwiftPlugin.properties_class.unmarshal({"swift-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("swift-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,78 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.uv_plugin import UvPlugin
+
+
+class TestPluginUv:
+    """Uv plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(UvPlugin, {})
+        assert plugin.get_build_packages() == {
+            "findutils",
+            "python3-dev",
+            "python3-venv",
+        }
+        assert plugin.get_build_snaps() == set()
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(UvPlugin, {})
+        assert plugin.get_build_environment() == {
+            "PATH": "install/dir/bin:${PATH}",
+            "PARTS_PYTHON_INTERPRETER": "python3",
+            "UV_PROJECT_ENVIRONMENT": "install/dir",
+            "UV_PYTHON_DOWNLOADS": "never",
+            "UV_COMPILE_BYTECODE": "1",
+            "UV_LINK_MODE": "copy",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(UvPlugin, {})
+        assert plugin.get_build_commands() == [
+            'uv venv --allow-existing --python "${PARTS_PYTHON_INTERPRETER}" '
+            '"install/dir"',
+            'PARTS_PYTHON_VENV_INTERP_PATH="install/dir/bin/'
+            '${PARTS_PYTHON_INTERPRETER}"',
+            "if [ -f uv.lock ]; then uv sync --locked --no-dev --no-editable; "
+            'else uv pip install --python "install/dir/bin/python" .; fi',
+            'find "install/dir" -type f -executable -print0 | xargs -0 '
+            'sed -i "1 s|^#\\!${PARTS_PYTHON_VENV_INTERP_PATH}.*$|'
+            '#!/usr/bin/env ${PARTS_PYTHON_INTERPRETER}|"',
+            'ln -sf "$(readlink -f "$(which "${PARTS_PYTHON_INTERPRETER}")")" '
+            '"install/dir/bin/${PARTS_PYTHON_INTERPRETER}"',
+        ]
+
+    def test_get_build_commands_extras_groups(self, make_plugin):
+        plugin = make_plugin(
+            UvPlugin, {"uv-extras": ["tls", "cli"], "uv-groups": ["docs"]}
+        )
+        assert plugin.get_build_commands()[2] == (
+            "if [ -f uv.lock ]; then uv sync --locked --no-dev --no-editable "
+            "--extra 'tls' --extra 'cli' --group 'docs'; "
+            "else uv pip install --python \"install/dir/bin/python\" '.[tls,cli]'; fi"
+        )
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            UvPlugin.properties_class.unmarshal({"uv-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("uv-invalid",)
+        assert err[0]["type"] == "value_error.extra"
@@ -0,0 +1,67 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+
+import pytest
+
+from craft_parts import errors
+from craft_parts.plugins import PluginEnvironmentValidator, PluginProperties
+
+_ENV = '#!/bin/sh\nset -e\nexport FOO="bar"\n'
+
+
+@pytest.fixture
+def validator():
+    return PluginEnvironmentValidator(
+        part_name="p1", env=_ENV, properties=PluginProperties()
+    )
+
+
+class TestPluginEnvironmentValidator:
+    """Verify the default plugin environment validator."""
+
+    def test_part_name(self, validator):
+        assert validator.part_name == "p1"
+
+    def test_validate_environment(self, validator):
+        validator.validate_environment(part_dependencies=["p2"])
+
+    def test_execute(self, validator):
+        assert validator.execute('echo "$FOO"') == "bar\n"
+
+    def test_execute_error(self, validator):
+        with pytest.raises(subprocess.CalledProcessError):
+            validator.execute("exit 3")
+
+    def test_validate_dependency(self, validator):
+        assert validator.validate_dependency("echo", argument="1.2.3") == "1.2.3"
+
+    def test_validate_dependency_not_found(self, validator):
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            validator.validate_dependency("craft-parts-missing-tool")
+
+        assert raised.value.part_name == "p1"
+        assert raised.value.reason == (
+            "'craft-parts-missing-tool' not found, add it to build-packages or "
+            "build-snaps, or build it in a part listed in 'after'"
+        )
+
+    def test_validate_dependency_failed(self, validator):
+        with pytest.raises(errors.PluginEnvironmentValidationError) as raised:
+            validator.validate_dependency("false", argument="")
+
+        assert raised.value.reason == "'false' failed with exit status 1"
@@ -0,0 +1,141 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+from pydantic import ValidationError
+
+from craft_parts.plugins.base import get_download_command
+from craft_parts.plugins.zig_plugin import ZigPlugin
+
+
+@pytest.fixture(autouse=True)
+def host_arch(mocker):
+    mocker.patch("platform.machine", return_value="x86_64")
+
+
+class TestPluginZig:
+    """Zig plugin tests."""
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {})
+        assert plugin.get_build_packages() == set()
+        assert plugin.get_build_snaps() == set()
+        assert plugin.get_build_environment() == {}
+        assert plugin.get_build_assets() == {}
+
+    def test_get_toolchain_version_commands(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {})
+        assert plugin.get_toolchain_version_commands() == {
+            "zig": "zig version",
+        }
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {})
+        assert plugin.get_build_commands() == [
+            'zig build install --prefix "install/dir" -Doptimize=ReleaseSafe'
+        ]
+
+    def test_get_build_commands_options(self, make_plugin):
+        plugin = make_plugin(
+            ZigPlugin,
+            {
+                "zig-optimize": "ReleaseSmall",
+                "zig-target": "x86_64-linux-musl",
+                "zig-prefix": "usr",
+                "zig-parameters": ["-Dstrip=true"],
+            },
+        )
+        assert plugin.get_build_commands() == [
+            'zig build install --prefix "install/dir/usr" -Doptimize=ReleaseSmall '
+            "-Dtarget=x86_64-linux-musl -Dstrip=true"
+        ]
+
+    def test_get_build_commands_cross(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {}, arch="aarch64")
+        assert plugin.get_build_commands() == [
+            'zig build install --prefix "install/dir" -Doptimize=ReleaseSafe '
+            "-Dtarget=aarch64-linux-gnu"
+        ]
+
+    def test_invalid_optimize(self):
+        with pytest.raises(ValidationError) as raised:
+            ZigPlugin.properties_class.unmarshal({"zig-optimize": "Fast"})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("zig-optimize",)
+        assert err[0]["type"] == "value_error.str.regex"
+
+    def test_invalid_parameters(self):
+        with pytest.raises(ValidationError) as raised:
+            ZigPlugin.properties_class.unmarshal({"zig-invalid": True})
+        err = raised.value.errors()
+        assert len(err) == 1
+        assert err[0]["loc"] == ("zig-invalid",)
+        assert err[0]["type"] == "value_error.extra"
+
+
+class TestPluginZigToolchain:
+    """Zig plugin tests provisioning the zig toolchain."""
+
+    @pytest.fixture(autouse=True)
+    def cache_dir(self, mocker):
+        mocker.patch("xdg.BaseDirectory.save_cache_path", return_value="/cache/zig")
+
+    def test_get_build_packages(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {"zig-version": "0.13.0"})
+        assert plugin.get_build_packages() == {"curl", "xz-utils"}
+
+    def test_get_build_environment(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {"zig-version": "0.13.0"})
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/zig/zig-linux-x86_64-0.13.0:${PATH}",
+        }
+
+    def test_get_build_assets(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {"zig-version": "0.13.0"})
+        assert plugin.get_build_assets() == {"zig-version": "0.13.0"}
+
+    def test_get_build_commands(self, make_plugin):
+        plugin = make_plugin(ZigPlugin, {"zig-version": "0.13.0"})
+        toolchain = "/cache/zig/zig-linux-x86_64-0.13.0"
+        assert plugin.get_build_commands() == [
+            get_download_command(
+                "https://ziglang.org/download/0.13.0/zig-linux-x86_64-0.13.0.tar.xz",
+                f"{toolchain}.tar.xz",
+                index_url="https://ziglang.org/download/index.json",
+            ),
+            f'if [ ! -x "{toolchain}/zig" ]; then '
+            f'mkdir -p "{toolchain}" && '
+            f'tar -xJf "{toolchain}.tar.xz" -C "{toolchain}" --strip-components=1; fi',
+            'zig build install --prefix "install/dir" -Doptimize=ReleaseSafe',
+        ]
+
+    def test_get_build_commands_sha256(self, make_plugin):
+        plugin = make_plugin(
+            ZigPlugin, {"zig-version": "0.13.0", "zig-version-sha256": "1234"}
+        )
+        assert plugin.get_build_commands()[0] == get_download_command(
+            "https://ziglang.org/download/0.13.0/zig-linux-x86_64-0.13.0.tar.xz",
+            "/cache/zig/zig-linux-x86_64-0.13.0.tar.xz",
+            sha256="1234",
+        )
+
+    def test_get_build_commands_arm(self, make_plugin, mocker):
+        mocker.patch("platform.machine", return_value="armv7l")
+        plugin = make_plugin(ZigPlugin, {"zig-version": "0.13.0"}, arch="armv7l")
+        assert plugin.get_build_environment() == {
+            "PATH": "/cache/zig/zig-linux-armv7a-0.13.0:${PATH}",
+        }
@@ -14,6 +14,7 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+import os
 from pathlib import Path
 
 import pytest
@@ -90,3 +91,105 @@ def test_file_cache_non_existent():
 
     result = x.cache(filename="test_file", key=digest)
     assert result is None
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_dir():
+    digest = "algo/12345678"
+    x = FileCache(name="test", cache_dir="shared")
+
+    Path("test_file").write_text("content")
+
+    result = x.cache(filename="test_file", key=digest)
+    assert result == "shared/files/algo/12345678"
+    assert Path(result).read_text() == "content"
+
+    # the same location is used by other applications
+    y = FileCache(name="other", cache_dir="shared")
+    assert y.get(key=digest) == result
+
+    # no partial files are left behind
+    assert [p.name for p in Path("shared/files/algo").iterdir()] == ["12345678"]
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_eviction():
+    x = FileCache(name="test", cache_dir="cache", max_size=10)
+
+    for name in ["a", "b", "c"]:
+        Path(name).write_text("1234")
+        x.cache(filename=name, key=f"algo/{name}")
+        # make access times distinguishable
+        os.utime(f"cache/files/algo/{name}", (0, {"a": 1, "b": 2, "c": 3}[name]))
+
+    # the least recently used file was removed
+    assert x.get(key="algo/a") is None
+    assert x.get(key="algo/b") is not None
+    assert x.get(key="algo/c") is not None
+
+    # b was used recently, so c is evicted next
+    os.utime("cache/files/algo/c", (0, 4))
+    Path("d").write_text("1234")
+    x.cache(filename="d", key="algo/d")
+
+    assert x.get(key="algo/b") is not None
+    assert x.get(key="algo/c") is None
+    assert x.get(key="algo/d") is not None
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_eviction_keeps_new_file():
+    x = FileCache(name="test", cache_dir="cache", max_size=2)
+
+    Path("big").write_text("12345")
+    result = x.cache(filename="big", key="algo/big")
+
+    assert result is not None
+    assert Path(result).is_file()
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_no_limit():
+    x = FileCache(name="test", cache_dir="cache")
+
+    for name in ["a", "b"]:
+        Path(name).write_text("1234" * 1000)
+        x.cache(filename=name, key=f"algo/{name}")
+
+    assert x.get(key="algo/a") is not None
+    assert x.get(key="algo/b") is not None
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_prune():
+    x = FileCache(name="test", cache_dir="cache")
+
+    for i, name in enumerate(["a", "b", "c"]):
+        Path(name).write_text("1234")
+        x.cache(filename=name, key=f"algo/{name}")
+        os.utime(f"cache/files/algo/{name}", (0, i))
+
+    assert x.prune(max_size=8) == 4
+    assert x.get(key="algo/a") is None
+    assert x.get(key="algo/b") is not None
+
+    # without a size limit all files are removed
+    assert x.prune() == 8
+    assert x.get(key="algo/c") is None
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_prune_size_limit():
+    x = FileCache(name="test", cache_dir="cache", max_size=4)
+    Path("a").write_text("1234")
+    x.cache(filename="a", key="algo/a")
+
+    assert x.prune() == 0
+    assert x.prune(max_size=0) == 4
+
+
+@pytest.mark.usefixtures("new_dir")
+def test_file_cache_prune_missing():
+    x = FileCache(name="test", cache_dir="cache")
+
+    assert x.prune() == 0
@@ -14,7 +14,9 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+import subprocess
 from pathlib import Path
+from unittest.mock import call
 
 import pytest
 
@@ -81,3 +83,111 @@ def test_verify_checksum_digest_error():
         checksum.verify_checksum("md5/digest", "checkfile")
     assert raised.value.expected == "digest"
     assert raised.value.obtained == "9a0364b9e99bb480dd25e1f0284c8555"
+
+
+@pytest.mark.parametrize(
+    "tc_checksum,tc_result",
+    [
+        ("sha256/SHA256SUMS", True),
+        ("sha512/https://example.com/release/SHA512SUMS", True),
+        ("md5/9a0364b9e99bb480dd25e1f0284c8555", False),
+        ("md5/9A0364B9E99BB480DD25E1F0284C8555", False),
+        ("md5/", False),
+        ("invalid", False),
+        ("", False),
+    ],
+)
+def test_is_checksum_file(tc_checksum, tc_result):
+    assert checksum.is_checksum_file(tc_checksum) is tc_result
+
+
+@pytest.mark.parametrize(
+    "tc_filename,tc_digest",
+    [
+        ("hello.tar.gz", "0123456789abcdef"),
+        ("https://example.com/downloads/hello-2.0.tar.gz", "fedcba9876543210"),
+        ("hello.zip", "00112233aabbccdd"),
+        ("hello-3.0.tar.gz", "aabbccddeeff0011"),
+        ("other.tar.gz", None),
+    ],
+)
+@pytest.mark.usefixtures("new_dir")
+def test_get_checksum_file_digest(tc_filename, tc_digest):
+    Path("SHA256SUMS").write_text(
+        "-----BEGIN PGP SIGNED MESSAGE-----\n"
+        "Hash: SHA256\n"
+        "\n"
+        "0123456789abcdef  hello.tar.gz\n"
+        "FEDCBA9876543210 *dist/hello-2.0.tar.gz\n"
+        "SHA256 (hello.zip) = 00112233aabbccdd\n"
+        "aabbccddeeff0011 ./hello-3.0.tar.gz\n"
+        "-----BEGIN PGP SIGNATURE-----\n"
+    )
+
+    assert checksum.get_checksum_file_digest("SHA256SUMS", tc_filename) == tc_digest
+
+
+@pytest.mark.parametrize(
+    "tc_path,tc_digest",
+    [
+        ("x86_64/hello.tar.gz", "0123456789abcdef"),
+        ("./arm64/hello.tar.gz", "fedcba9876543210"),
+        ("riscv64/hello.tar.gz", "00112233aabbccdd"),
+        ("x86_64/other.tar.gz", None),
+    ],
+)
+@pytest.mark.usefixtures("new_dir")
+def test_get_checksum_file_digest_relative_path(tc_path, tc_digest):
+    Path("SHA256SUMS").write_text(
+        "0123456789abcdef  x86_64/hello.tar.gz\n"
+        "fedcba9876543210  ./arm64/hello.tar.gz\n"
+        "00112233aabbccdd  hello.tar.gz\n"
+    )
+
+    digest = checksum.get_checksum_file_digest(
+        "SHA256SUMS", tc_path, relative_path=tc_path
+    )
+    assert digest == tc_digest
+
+
+def test_verify_checksum_file_signature(mocker, new_dir):
+    mock_run = mocker.patch("subprocess.run")
+
+    result = checksum.verify_checksum_file_signature(
+        "SHA256SUMS", keyring="keys.gpg", signature="SHA256SUMS.gpg"
+    )
+
+    assert result == "SHA256SUMS"
+    gnupg_home = mock_run.mock_calls[0].args[0][4]
+    gpg = ["gpg", "--batch", "--quiet", "--homedir", gnupg_home]
+    assert mock_run.mock_calls == [
+        call([*gpg, "--import", f"{new_dir}/keys.gpg"], check=True),
+        call([*gpg, "--verify", "SHA256SUMS.gpg", "SHA256SUMS"], check=True),
+    ]
+
+
+def test_verify_checksum_file_signature_clearsigned(mocker, new_dir):
+    mock_run = mocker.patch("subprocess.run")
+
+    result = checksum.verify_checksum_file_signature("SHA256SUMS", keyring="keys.gpg")
+
+    assert result == "SHA256SUMS.verified"
+    gnupg_home = mock_run.mock_calls[0].args[0][4]
+    gpg = ["gpg", "--batch", "--quiet", "--homedir", gnupg_home]
+    assert mock_run.mock_calls[1] == call(
+        [*gpg, "--output", "SHA256SUMS.verified", "--decrypt", "SHA256SUMS"],
+        check=True,
+    )
+
+
+def test_verify_checksum_file_signature_error(mocker):
+    mocker.patch(
+        "subprocess.run",
+        side_effect=[None, subprocess.CalledProcessError(returncode=1, cmd=["gpg"])],
+    )
+
+    result = checksum.verify_checksum_file_signature(
+        "SHA256SUMS", keyring="keys.gpg", signature="SHA256SUMS.gpg"
+    )
+
+    assert result is None
@@ -83,3 +83,90 @@ def test_source_not_found():
     assert err.brief == "Failed to pull source: 'some_source' not found."
     assert err.details is None
     assert err.resolution == "Make sure the source path is correct and accessible."
+
+
+def test_pull_error():
+    err = errors.PullError(command=["skopeo", "copy"], exit_code=1)
+    assert err.command == ["skopeo", "copy"]
+    assert err.exit_code == 1
+    assert err.brief == (
+        "Failed to pull source: command 'skopeo copy' exited with code 1."
+    )
+    assert err.details is None
+    assert err.resolution == "Make sure sources are correctly specified."
+
+
+def test_invalid_oci_image():
+    err = errors.InvalidOciImage("ubuntu:22.04", message="no manifest")
+    assert err.source == "ubuntu:22.04"
+    assert err.message == "no manifest"
+    assert err.brief == "Failed to unpack OCI image 'ubuntu:22.04': no manifest."
+    assert err.details is None
+    assert err.resolution == "Make sure the image reference or archive is correct."
+
+
+def test_source_credentials_not_found():
+    err = errors.SourceCredentialsNotFound("TOKEN")
+    assert err.variable == "TOKEN"
+    assert err.brief == (
+        "Failed to pull source: credentials variable 'TOKEN' is not set."
+    )
+    assert err.details is None
+    assert err.resolution == "Set the environment variable to the source credentials."
+
+
+def test_signature_verification_failed():
+    err = errors.SignatureVerificationFailed("repo.git", ref="v1")
+    assert err.source == "repo.git"
+    assert err.ref == "v1"
+    assert err.brief == "Failed to verify the signature of 'v1' in 'repo.git'."
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure the revision is signed by a key in the provided keyring "
+        "or allowed signers file."
+    )
+
+
+def test_patch_error():
+    err = errors.PatchError("fix.patch", message="patch exited with code 1")
+    assert err.patch == "fix.patch"
+    assert err.message == "patch exited with code 1"
+    assert err.brief == "Failed to apply patch 'fix.patch': patch exited with code 1."
+    assert err.details is None
+    assert err.resolution == "Make sure the patch applies cleanly to the pulled source."
+
+
+def test_invalid_mirror_rule():
+    err = errors.InvalidMirrorRule("https://example.com", message="missing mirror")
+    assert err.rule == "https://example.com"
+    assert err.message == "missing mirror"
+    assert err.brief == (
+        "Invalid source mirror rule 'https://example.com': missing mirror."
+    )
+    assert err.details is None
+    assert err.resolution == "Make sure source mirror rules are correctly specified."
+
+
+def test_checksum_file_error():
+    err = errors.ChecksumFileError("SHA256SUMS", message="'hello.tar' is not listed")
+    assert err.checksum_file == "SHA256SUMS"
+    assert err.message == "'hello.tar' is not listed"
+    assert err.brief == (
+        "Failed to use checksum file 'SHA256SUMS': 'hello.tar' is not listed."
+    )
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure the checksum file lists the source file and, if a keyring "
+        "is provided, is signed by one of its keys."
+    )
+
+
+def test_lfs_object_mismatch():
+    err = errors.LfsObjectMismatch("data/model.bin", oid="1234abcd")
+    assert err.path == "data/model.bin"
+    assert err.oid == "1234abcd"
+    assert err.brief == (
+        "Git LFS object for 'data/model.bin' doesn't match digest 1234abcd."
+    )
+    assert err.details is None
+    assert err.resolution == "Make sure the LFS server provides the correct objects."
@@ -0,0 +1,391 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import hashlib
+import os
+import subprocess
+from pathlib import Path
+from unittest.mock import ANY, call
+
+import pytest
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part, SubmoduleSpec
+from craft_parts.sources import errors, sources
+from craft_parts.sources.cache import FileCache
+from craft_parts.sources.git_source import GitSource
+
+_COMMIT = "2514f9533ec9b45d07883e10a561b248497a8e3c"
+
+
+@pytest.fixture
+def mock_run(mocker):
+    mocker.patch("subprocess.check_output", return_value=f"{_COMMIT}\n")
+    return mocker.patch("subprocess.run")
+
+
+def _call(command):
+    return call(command, check=True, env={**os.environ, "GIT_LFS_SKIP_SMUDGE": "1"})
+
+
+def _git(*args):
+    return _call(["git", "-C", "src", *args])
+
+
+_SUBMODULE_UPDATE = ["submodule", "update", "--init", "--recursive"]
+
+_LFS_CONTENT = b"model data"
+_LFS_OID = hashlib.sha256(_LFS_CONTENT).hexdigest()
+
+
+@pytest.fixture
+def lfs_repo(mocker):
+    """Create a source tree with a file tracked by Git LFS."""
+    Path("src").mkdir()
+    Path("src/.gitattributes").write_text(
+        "# models\n*.bin filter=lfs diff=lfs merge=lfs -text\n"
+    )
+
+    def fake_check_output(command, **_):
+        if "ls-files" in command:
+            return f"{_LFS_OID} - data/model.bin\n"
+        return f"{_COMMIT}\n"
+
+    mocker.patch("subprocess.check_output", side_effect=fake_check_output)
+    return Path("src/.git/lfs/objects", _LFS_OID[0:2], _LFS_OID[2:4], _LFS_OID)
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestGitSource:
+    """Tests for the git source handler."""
+
+    def test_get_source_handler(self):
+        part = Part(
+            "p1",
+            {
+                "source": "https://example.com/repo.git",
+                "source-submodules": ["lib1", {"path": "lib2", "depth": 1}],
+                "source-sparse-paths": ["docs"],
+                "source-lfs": False,
+            },
+        )
+        handler = sources.get_source_handler(
+            application_name="test", part=part, project_dirs=ProjectDirs()
+        )
+
+        assert isinstance(handler, GitSource)
+        assert handler.source_submodules == [
+            "lib1",
+            SubmoduleSpec(path="lib2", depth=1),
+        ]
+        assert handler.source_sparse_paths == ["docs"]
+        assert handler.source_lfs is False
+
+    def test_invalid_checksum(self):
+        with pytest.raises(errors.InvalidSourceOption) as raised:
+            GitSource("repo.git", "src", source_checksum="md5/1234")
+
+        assert raised.value.source_type == "git"
+        assert raised.value.option == "source-checksum"
+
+    def test_incompatible_options(self):
+        with pytest.raises(errors.IncompatibleSourceOptions) as raised:
+            GitSource("repo.git", "src", source_tag="v1", source_branch="main")
+
+        assert raised.value.options == ["source-tag", "source-branch"]
+
+    def test_pull(self, mock_run):
+        git_source = GitSource("repo.git", "src")
+        git_source.pull()
+
+        assert mock_run.mock_calls == [
+            _call(["git", "clone", "repo.git", "src"]),
+            _git(*_SUBMODULE_UPDATE),
+        ]
+        assert git_source.source_details == {"commit": _COMMIT}
+
+    def test_pull_branch_depth(self, mock_run):
+        GitSource("repo.git", "src", source_branch="dev", source_depth=2).pull()
+
+        assert mock_run.mock_calls == [
+            _call(
+                ["git", "clone", "--depth", "2", "--branch", "dev", "repo.git", "src"]
+            ),
+            _git(*_SUBMODULE_UPDATE, "--depth", "2"),
+        ]
+
+    def test_pull_tag(self, mock_run):
+        GitSource("repo.git", "src", source_tag="v1").pull()
+
+        assert mock_run.mock_calls[0] == _call(
+            ["git", "clone", "--branch", "v1", "repo.git", "src"]
+        )
+
+    def test_pull_commit(self, mock_run):
+        GitSource("repo.git", "src", source_commit=_COMMIT, source_depth=1).pull()
+
+        assert mock_run.mock_calls == [
+            _call(["git", "clone", "--depth", "1", "repo.git", "src"]),
+            _git("fetch", "--force", "--depth", "1", "origin", _COMMIT),
+            _git("checkout", _COMMIT),
+            _git(*_SUBMODULE_UPDATE, "--depth", "1"),
+        ]
+
+    def test_pull_sparse_paths(self, mock_run):
+        GitSource("repo.git", "src", source_sparse_paths=["docs", "lib/a"]).pull()
+
+        assert mock_run.mock_calls == [
+            _call(
+                [
+                    "git",
+                    "clone",
+                    "--filter=blob:none",
+                    "--sparse",
+                    "repo.git",
+                    "src",
+                ]
+            ),
+            _git("sparse-checkout", "init", "--cone"),
+            _git("sparse-checkout", "set", "--", "docs", "lib/a"),
+            _git(*_SUBMODULE_UPDATE),
+        ]
+
+    def test_pull_no_submodules(self, mock_run):
+        GitSource("repo.git", "src", source_submodules=[]).pull()
+
+        assert mock_run.mock_calls == [
+            _call(["git", "clone", "repo.git", "src"]),
+        ]
+
+    def test_pull_submodules(self, mock_run):
+        submodules = [
+            "lib1",
+            SubmoduleSpec(path="lib2", depth=1),
+            SubmoduleSpec(path="lib3", branch="stable"),
+        ]
+        GitSource("repo.git", "src", source_submodules=submodules).pull()
+
+        assert mock_run.mock_calls == [
+            _call(["git", "clone", "repo.git", "src"]),
+            _git(*_SUBMODULE_UPDATE, "--", "lib1"),
+            _git(*_SUBMODULE_UPDATE, "--depth", "1", "--", "lib2"),
+            _git(*_SUBMODULE_UPDATE, "--", "lib3"),
+            _call(["git", "-C", "src/lib3", "fetch", "origin", "stable"]),
+            _call(["git", "-C", "src/lib3", "checkout", "FETCH_HEAD"]),
+            _call(["git", "-C", "src/lib3", *_SUBMODULE_UPDATE]),
+        ]
+
+    def test_pull_submodule_branch_depth(self, mock_run):
+        submodules = [SubmoduleSpec(path="lib", branch="stable", depth=3)]
+        GitSource("repo.git", "src", source_submodules=submodules).pull()
+
+        assert mock_run.mock_calls[1:] == [
+            _git(*_SUBMODULE_UPDATE, "--depth", "3", "--", "lib"),
+            _call(
+                ["git", "-C", "src/lib", "fetch", "--depth", "3", "origin", "stable"]
+            ),
+            _call(["git", "-C", "src/lib", "checkout", "FETCH_HEAD"]),
+            _call(["git", "-C", "src/lib", *_SUBMODULE_UPDATE, "--depth", "3"]),
+        ]
+
+    @pytest.mark.parametrize(
+        "options,refspec",
+        [
+            ({}, "HEAD"),
+            ({"source_branch": "dev"}, "refs/heads/dev"),
+            ({"source_tag": "v1"}, "refs/tags/v1:refs/tags/v1"),
+            ({"source_commit": _COMMIT}, _COMMIT),
+        ],
+    )
+    def test_pull_existing(self, mock_run, options, refspec):
+        Path("src/.git").mkdir(parents=True)
+
+        GitSource("repo.git", "src", source_sparse_paths=["docs"], **options).pull()
+
+        assert mock_run.mock_calls == [
+            _git("sparse-checkout", "init", "--cone"),
+            _git("sparse-checkout", "set", "--", "docs"),
+            _git("fetch", "--force", "origin", refspec),
+            _git("reset", "--hard", "FETCH_HEAD"),
+            _git(*_SUBMODULE_UPDATE),
+        ]
+
+    def test_pull_verify_tag(self, mock_run, new_dir):
+        GitSource("repo.git", "src", source_tag="v1", source_keyring="keys.gpg").pull()
+
+        assert mock_run.mock_calls == [
+            _call(["git", "clone", "--branch", "v1", "repo.git", "src"]),
+            _call(
+                [
+                    "gpg",
+                    "--batch",
+                    "--quiet",
+                    "--homedir",
+                    ANY,
+                    "--import",
+                    f"{new_dir}/keys.gpg",
+                ]
+            ),
+            call(
+                ["git", "-C", "src", "-c", ANY, "verify-tag", "v1"],
+                check=True,
+                env=ANY,
+            ),
+            _git(*_SUBMODULE_UPDATE),
+        ]
+
+        gnupg_home = mock_run.mock_calls[1].args[0][4]
+        verify_args = mock_run.mock_calls[2]
+        assert verify_args.args[0][4] == (
+            f"gpg.ssh.allowedSignersFile={gnupg_home}/allowed_signers"
+        )
+        assert verify_args.kwargs["env"]["GNUPGHOME"] == gnupg_home
+        assert Path(gnupg_home).exists() is False
+
+    def test_pull_verify_commit(self, mock_run, new_dir):
+        GitSource(
+            "repo.git", "src", source_branch="dev", source_allowed_signers="signers"
+        ).pull()
+
+        assert mock_run.mock_calls[1] == call(
+            [
+                "git",
+                "-C",
+                "src",
+                "-c",
+                f"gpg.ssh.allowedSignersFile={new_dir}/signers",
+                "verify-commit",
+                "HEAD",
+            ],
+            check=True,
+            env=ANY,
+        )
+
+    def test_pull_verify_failed(self, mocker):
+        def fake_run(cmd, **_):
+            if "verify-commit" in cmd:
+                raise subprocess.CalledProcessError(returncode=1, cmd=cmd)
+
+        mock_run = mocker.patch("subprocess.run", side_effect=fake_run)
+
+        with pytest.raises(errors.SignatureVerificationFailed) as raised:
+            GitSource(
+                "repo.git", "src", source_commit=_COMMIT, source_keyring="keys.gpg"
+            ).pull()
+
+        assert raised.value.source == "repo.git"
+        assert raised.value.ref == _COMMIT
+        # submodules are not fetched
+        assert "submodule" not in mock_run.mock_calls[-1].args[0]
+
+    def test_get_source_date_epoch(self, mocker):
+        mock_output = mocker.patch(
+            "subprocess.check_output", return_value="1617181920\n"
+        )
+
+        assert GitSource("repo.git", "src").get_source_date_epoch() == 1617181920
+        mock_output.assert_called_once_with(
+            ["git", "-C", "src", "log", "-1", "--format=%ct", "HEAD"],
+            universal_newlines=True,
+        )
+
+    def test_get_source_date_epoch_error(self, mocker):
+        mocker.patch(
+            "subprocess.check_output",
+            side_effect=subprocess.CalledProcessError(returncode=128, cmd=["git"]),
+        )
+
+        with pytest.raises(errors.PullError) as raised:
+            GitSource("repo.git", "src").get_source_date_epoch()
+        assert raised.value.exit_code == 128
+
+    def test_pull_error(self, mocker):
+        mocker.patch(
+            "subprocess.run",
+            side_effect=subprocess.CalledProcessError(returncode=128, cmd=["git"]),
+        )
+
+        with pytest.raises(errors.PullError) as raised:
+            GitSource("repo.git", "src").pull()
+
+        assert raised.value.command == ["git", "clone", "repo.git", "src"]
+        assert raised.value.exit_code == 128
+
+    def test_pull_lfs(self, mocker, lfs_repo, new_dir):
+        mock_run = mocker.patch("subprocess.run")
+        lfs_repo.parent.mkdir(parents=True)
+        lfs_repo.write_bytes(_LFS_CONTENT)
+        cache = FileCache("test", cache_dir=new_dir / "cache")
+
+        GitSource("repo.git", "src", source_submodules=[], cache=cache).pull()
+
+        # the object directory makes it an existing repository
+        assert mock_run.mock_calls == [
+            _git("fetch", "--force", "origin", "HEAD"),
+            _git("reset", "--hard", "FETCH_HEAD"),
+            call(["git", "-C", "src", "lfs", "pull"], check=True, env=None),
+        ]
+        cached_file = cache.get(key=f"sha256/{_LFS_OID}")
+        assert cached_file is not None
+        assert Path(cached_file).read_bytes() == _LFS_CONTENT
+
+    def test_pull_lfs_cached(self, mocker, lfs_repo, new_dir):
+        mocker.patch("subprocess.run")
+        Path("model.bin").write_bytes(_LFS_CONTENT)
+        cache = FileCache("test", cache_dir=new_dir / "cache")
+        cache.cache(filename="model.bin", key=f"sha256/{_LFS_OID}")
+
+        GitSource("repo.git", "src", cache=cache).pull()
+
+        assert lfs_repo.read_bytes() == _LFS_CONTENT
+
+    def test_pull_lfs_cached_mismatch(self, mocker, lfs_repo, new_dir):
+        mocker.patch("subprocess.run")
+        Path("model.bin").write_bytes(b"corrupted")
+        cache = FileCache("test", cache_dir=new_dir / "cache")
+        cache.cache(filename="model.bin", key=f"sha256/{_LFS_OID}")
+
+        GitSource("repo.git", "src", cache=cache).pull()
+
+        assert lfs_repo.exists() is False
+
+    def test_pull_lfs_mismatch(self, mocker, lfs_repo, new_dir):
+        mocker.patch("subprocess.run")
+        lfs_repo.parent.mkdir(parents=True)
+        lfs_repo.write_bytes(b"corrupted")
+        cache = FileCache("test", cache_dir=new_dir / "cache")
+
+        with pytest.raises(errors.LfsObjectMismatch) as raised:
+            GitSource("repo.git", "src", cache=cache).pull()
+
+        assert raised.value.path == "data/model.bin"
+        assert raised.value.oid == _LFS_OID
+        assert cache.get(key=f"sha256/{_LFS_OID}") is None
+
+    def test_pull_lfs_disabled(self, mocker, lfs_repo):
+        mock_run = mocker.patch("subprocess.run")
+
+        GitSource("repo.git", "src", source_submodules=[], source_lfs=False).pull()
+
+        assert mock_run.mock_calls == [_call(["git", "clone", "repo.git", "src"])]
+
+    def test_pull_no_lfs_patterns(self, mock_run):
+        Path("src").mkdir()
+        Path("src/.gitattributes").write_text("*.sh text eol=lf\n")
+
+        GitSource("repo.git", "src", source_submodules=[]).pull()
+
+        assert mock_run.mock_calls == [_call(["git", "clone", "repo.git", "src"])]
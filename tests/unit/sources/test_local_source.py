@@ -158,6 +158,38 @@ class TestLocal:
         file_symlink = os.path.join("destination", "dir", "file_symlink")
         assert os.path.islink(file_symlink) and os.readlink(file_symlink) == "file"
 
+    def test_get_file_times(self):
+        os.makedirs(os.path.join("src", "dir"))
+        os.makedirs(os.path.join("src", "parts", "foo"))
+        open(os.path.join("src", "dir", "file"), "w").close()
+        open(os.path.join("src", "foo.snap"), "w").close()
+        os.utime(os.path.join("src", "dir", "file"), ns=(1000, 1000))
+
+        local = LocalSource("src", "destination")
+        file_times = local.get_file_times()
+
+        assert sorted(file_times) == ["dir", os.path.join("dir", "file")]
+        assert file_times[os.path.join("dir", "file")] == 1000
+
+    def test_get_file_times_removed_file(self, mocker):
+        os.makedirs("src")
+        open(os.path.join("src", "file"), "w").close()
+        open(os.path.join("src", "removed"), "w").close()
+
+        lstat = os.lstat
+
+        def fake_lstat(path):
+            if os.path.basename(path) == "removed":
+                raise FileNotFoundError(path)
+            return lstat(path)
+
+        mocker.patch("os.lstat", side_effect=fake_lstat)
+
+        local = LocalSource("src", "destination")
+        file_times = local.get_file_times()
+
+        assert sorted(file_times) == ["file"]
+
     def test_has_source_handler_entry(self):
         assert sources._source_handler["local"] is LocalSource
 
@@ -205,6 +237,7 @@ class TestLocalUpdate:
 
         # Expect update to be available
         assert local.check_if_outdated("reference")
+        assert local.get_outdated_files() == ["file"]
 
         local.update()
 
@@ -0,0 +1,132 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part
+from craft_parts.sources import errors, sources
+from craft_parts.sources.git_source import GitSource
+from craft_parts.sources.mirrors import (
+    MirrorRule,
+    get_environment_rules,
+    rewrite_source,
+)
+from craft_parts.sources.tar_source import TarSource
+
+_RULES = [
+    MirrorRule("https://github.com/", "https://mirror.internal/github/"),
+    MirrorRule(
+        r"https://files\.pythonhosted\.org/packages/(?P<path>[^/]+)/",
+        r"https://pypi.internal/\g<path>/",
+        regex=True,
+    ),
+    MirrorRule("https://", "https://proxy.internal/"),
+]
+
+
+@pytest.mark.parametrize(
+    "source,result",
+    [
+        (
+            "https://github.com/org/repo.git",
+            "https://mirror.internal/github/org/repo.git",
+        ),
+        (
+            "https://files.pythonhosted.org/packages/ab/cd/pkg.tar.gz",
+            "https://pypi.internal/ab/cd/pkg.tar.gz",
+        ),
+        ("https://example.com/file.tar", "https://proxy.internal/example.com/file.tar"),
+        ("http://example.com/file.tar", "http://example.com/file.tar"),
+        ("src/dir", "src/dir"),
+    ],
+)
+def test_rewrite_source(source, result):
+    assert rewrite_source(source, _RULES) == result
+
+
+def test_rewrite_source_no_rules():
+    assert rewrite_source("https://github.com/a", []) == "https://github.com/a"
+
+
+@pytest.mark.parametrize(
+    "pattern,regex,message",
+    [
+        ("", False, "empty pattern"),
+        ("https://(", True, "missing ), unterminated subpattern at position 8"),
+    ],
+)
+def test_invalid_rule(pattern, regex, message):
+    with pytest.raises(errors.InvalidMirrorRule) as raised:
+        MirrorRule(pattern, "https://mirror.internal/", regex=regex)
+
+    assert raised.value.rule == pattern
+    assert raised.value.message == message
+
+
+def test_get_environment_rules():
+    env = {
+        "CRAFT_SOURCE_MIRRORS": (
+            "https://github.com/=https://mirror.internal/github/\n"
+            "  re:https://(?=pypi)[^/]+/=https://pypi.internal/"
+        )
+    }
+
+    assert get_environment_rules(env) == [
+        MirrorRule("https://github.com/", "https://mirror.internal/github/"),
+        MirrorRule("https://(?=pypi)[^/]+/", "https://pypi.internal/", regex=True),
+    ]
+
+
+def test_get_environment_rules_unset(monkeypatch):
+    monkeypatch.delenv("CRAFT_SOURCE_MIRRORS", raising=False)
+    assert get_environment_rules() == []
+
+    monkeypatch.setenv("CRAFT_SOURCE_MIRRORS", "https://a/=https://b/")
+    assert get_environment_rules() == [MirrorRule("https://a/", "https://b/")]
+
+
+@pytest.mark.parametrize("entry", ["https://github.com/", "https://github.com/="])
+def test_get_environment_rules_malformed(entry):
+    with pytest.raises(errors.InvalidMirrorRule) as raised:
+        get_environment_rules({"CRAFT_SOURCE_MIRRORS": entry})
+
+    assert raised.value.rule == entry
+    assert raised.value.message == "missing mirror"
+
+
+def test_get_source_handler_with_mirrors():
+    part = Part("p1", {"source": "https://github.com/org/repo", "source-type": "git"})
+    handler = sources.get_source_handler(
+        application_name="test", part=part, project_dirs=ProjectDirs(), mirrors=_RULES
+    )
+
+    assert isinstance(handler, GitSource)
+    assert handler.source == "https://mirror.internal/github/org/repo"
+
+
+def test_get_source_handler_with_mirrors_keeps_source_type():
+    rules = [MirrorRule("https://example.com/hello.tar.gz", "https://mirror/get?id=1")]
+    part = Part(
+        "p1",
+        {"source": [{"source": "https://example.com/hello.tar.gz", "target": "a"}]},
+    )
+    handler = sources.get_source_handler(
+        application_name="test", part=part, project_dirs=ProjectDirs(), mirrors=rules
+    )
+
+    assert [h.source for h in handler.handlers] == ["https://mirror/get?id=1"]
+    assert isinstance(handler.handlers[0], TarSource)
@@ -0,0 +1,211 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+from typing import List
+
+import pytest
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part
+from craft_parts.sources import errors, sources
+from craft_parts.sources.base import SourceHandler
+from craft_parts.sources.git_source import GitSource
+from craft_parts.sources.local_source import LocalSource
+from craft_parts.sources.multi_source import MultiSource
+from craft_parts.sources.tar_source import TarSource
+
+
+class FakeSource(SourceHandler):
+    """A source handler that records its calls."""
+
+    def __init__(self, source, part_src_dir, *, calls: List[str], outdated=None):
+        super().__init__(source, part_src_dir)
+        self._calls = calls
+        self._outdated = outdated
+
+    def pull(self) -> None:
+        self._calls.append(f"pull {self.source} {self.previous_details}")
+        Path(self.part_src_dir, self.source).write_text(self.source)
+        self.source_details = {"name": self.source}
+
+    def check_if_outdated(self, target, *, ignore_files=None) -> bool:
+        if self._outdated is None:
+            return super().check_if_outdated(target, ignore_files=ignore_files)
+        self._calls.append(f"check {self.source}")
+        return self._outdated
+
+    def update(self):
+        if self._outdated is None:
+            super().update()
+        self._calls.append(f"update {self.source}")
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestMultiSource:
+    """Tests for the handler of parts with more than one source."""
+
+    def test_get_source_handler(self):
+        part = Part(
+            "p1",
+            {
+                "source": [
+                    {"source": "hello.tar.gz", "source-checksum": "md5/1234"},
+                    {"source": ".", "source-type": "local", "target": "assets"},
+                    {"source": "repo.git", "source-depth": 1, "target": "a/b/"},
+                ],
+                "source-subdir": "hello",
+            },
+        )
+        handler = sources.get_source_handler(
+            application_name="test", part=part, project_dirs=ProjectDirs()
+        )
+
+        assert isinstance(handler, MultiSource)
+        assert handler.source == "hello.tar.gz, ., repo.git"
+        assert [type(h) for h in handler.handlers] == [
+            TarSource,
+            LocalSource,
+            GitSource,
+        ]
+        assert [h.part_src_dir for h in handler.handlers] == [
+            str(part.part_src_dir),
+            str(part.part_src_dir / "assets"),
+            str(part.part_src_dir / "a/b"),
+        ]
+        assert handler.handlers[0].source_checksum == "md5/1234"
+        assert handler.handlers[2].source_depth == 1
+
+    def test_get_source_handler_invalid_option(self):
+        part = Part("p1", {"source": [{"source": "hello.tar.gz", "source-depth": 1}]})
+
+        with pytest.raises(errors.InvalidSourceOption) as raised:
+            sources.get_source_handler(
+                application_name="test", part=part, project_dirs=ProjectDirs()
+            )
+        assert raised.value.source_type == "tar"
+        assert raised.value.option == "source-depth"
+
+    def test_pull(self):
+        calls: List[str] = []
+        handler = MultiSource(
+            "src",
+            handlers=[
+                FakeSource("first", "src", calls=calls),
+                FakeSource("second", "src/a/b", calls=calls),
+            ],
+        )
+
+        handler.pull()
+
+        assert calls == ["pull first None", "pull second None"]
+        assert Path("src/first").read_text() == "first"
+        assert Path("src/a/b/second").read_text() == "second"
+        assert handler.source_details == {
+            "first": {"name": "first"},
+            "second": {"name": "second"},
+        }
+
+    def test_pull_previous_details(self):
+        calls: List[str] = []
+        handler = MultiSource(
+            "src",
+            handlers=[
+                FakeSource("first", "src", calls=calls),
+                FakeSource("second", "src", calls=calls),
+            ],
+        )
+        handler.previous_details = {"second": {"name": "second"}}
+
+        handler.pull()
+
+        assert calls == ["pull first None", "pull second {'name': 'second'}"]
+
+    def test_pull_no_source_details(self, mocker):
+        mocker.patch.object(LocalSource, "pull")
+        handler = MultiSource("src", handlers=[LocalSource(".", "src")])
+
+        handler.pull()
+
+        assert handler.source_details is None
+
+    @pytest.mark.parametrize(
+        "outdated,result", [([False, None, False], False), ([False, None, True], True)]
+    )
+    def test_check_if_outdated(self, outdated, result):
+        calls: List[str] = []
+        handler = MultiSource(
+            "src",
+            handlers=[
+                FakeSource(f"s{i}", "src", calls=calls, outdated=value)
+                for i, value in enumerate(outdated)
+            ],
+        )
+
+        assert handler.check_if_outdated("target") is result
+        # all sources that can check for changes are verified
+        assert calls == ["check s0", "check s2"]
+
+    def test_check_if_outdated_unsupported(self):
+        handler = MultiSource(
+            "src", handlers=[FakeSource("s0", "src", calls=[], outdated=None)]
+        )
+
+        with pytest.raises(errors.SourceUpdateUnsupported) as raised:
+            handler.check_if_outdated("target")
+        assert raised.value.name == "MultiSource"
+
+    def test_get_source_date_epoch(self, mocker):
+        handlers = [
+            FakeSource("s0", "src", calls=[]),
+            FakeSource("s1", "src", calls=[]),
+            FakeSource("s2", "src", calls=[]),
+        ]
+        for handler, epoch in zip(handlers, [1000, None, 3000]):
+            mocker.patch.object(handler, "get_source_date_epoch", return_value=epoch)
+        handler = MultiSource("src", handlers=handlers)
+
+        assert handler.get_source_date_epoch() == 3000
+
+    def test_get_source_date_epoch_none(self, mocker):
+        source = FakeSource("s0", "src", calls=[])
+        mocker.patch.object(source, "get_source_date_epoch", return_value=None)
+        handler = MultiSource("src", handlers=[source])
+
+        assert handler.get_source_date_epoch() is None
+
+    def test_update(self):
+        calls: List[str] = []
+        handler = MultiSource(
+            "src",
+            handlers=[
+                FakeSource("s0", "src", calls=calls, outdated=True),
+                FakeSource("s1", "src", calls=calls, outdated=None),
+            ],
+        )
+
+        handler.update()
+
+        assert calls == ["update s0"]
+
+    def test_update_unsupported(self):
+        handler = MultiSource(
+            "src", handlers=[FakeSource("s0", "src", calls=[], outdated=None)]
+        )
+
+        with pytest.raises(errors.SourceUpdateUnsupported) as raised:
+            handler.update()
+        assert raised.value.name == "MultiSource"
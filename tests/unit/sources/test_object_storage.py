@@ -0,0 +1,108 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+
+import pytest
+
+from craft_parts.sources import errors, object_storage
+
+
+@pytest.mark.parametrize(
+    "url,result",
+    [
+        ("s3://bucket/file.tar.gz", True),
+        ("gs://bucket/file.tar.gz", True),
+        ("https://example.com/file.tar.gz", False),
+        ("file.tar.gz", False),
+    ],
+)
+def test_is_object_storage_url(url, result):
+    assert object_storage.is_object_storage_url(url) is result
+
+
+@pytest.mark.parametrize(
+    "url,options,command",
+    [
+        (
+            "s3://bucket/file",
+            {},
+            ["aws", "s3", "cp", "--only-show-errors", "s3://bucket/file", "dest"],
+        ),
+        (
+            "s3://bucket/file",
+            {"region": "eu-west-1", "profile": "release"},
+            [
+                "aws",
+                "s3",
+                "cp",
+                "--only-show-errors",
+                "--region",
+                "eu-west-1",
+                "--profile",
+                "release",
+                "s3://bucket/file",
+                "dest",
+            ],
+        ),
+        (
+            "gs://bucket/file",
+            {},
+            ["gcloud", "storage", "cp", "gs://bucket/file", "dest"],
+        ),
+        (
+            "gs://bucket/file",
+            {"profile": "release"},
+            [
+                "gcloud",
+                "storage",
+                "cp",
+                "--configuration",
+                "release",
+                "gs://bucket/file",
+                "dest",
+            ],
+        ),
+    ],
+)
+def test_download_object(mocker, url, options, command):
+    mock_run = mocker.patch("subprocess.run")
+
+    object_storage.download_object(url, "dest", **options)
+
+    mock_run.assert_called_once_with(command, check=True)
+
+
+def test_download_object_error(mocker):
+    mocker.patch(
+        "subprocess.run",
+        side_effect=subprocess.CalledProcessError(returncode=1, cmd=["aws"]),
+    )
+
+    with pytest.raises(errors.PullError) as raised:
+        object_storage.download_object("s3://bucket/file", "dest")
+
+    assert raised.value.command[:3] == ["aws", "s3", "cp"]
+    assert raised.value.exit_code == 1
+
+
+def test_download_object_invalid_url():
+    with pytest.raises(ValueError) as raised:
+        object_storage.download_object("https://example.com/file", "dest")
+
+    assert str(raised.value) == (
+        "'https://example.com/file' is not an object storage URL"
+    )
@@ -0,0 +1,271 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import hashlib
+import io
+import json
+import os
+import subprocess
+import tarfile
+from pathlib import Path
+from typing import Dict, List, Optional
+
+import pytest
+
+from craft_parts.sources import errors, sources
+from craft_parts.sources.oci_source import OciSource
+
+
+def _add_blob(layout_dir: Path, data: bytes) -> str:
+    digest = hashlib.sha256(data).hexdigest()
+    blob_dir = layout_dir / "blobs" / "sha256"
+    blob_dir.mkdir(parents=True, exist_ok=True)
+    (blob_dir / digest).write_bytes(data)
+    return f"sha256:{digest}"
+
+
+def _make_layer(entries: Dict[str, Optional[str]], links: Dict[str, str] = None):
+    """Create a gzipped layer with files (or directories if None) and symlinks."""
+    data = io.BytesIO()
+    with tarfile.open(fileobj=data, mode="w:gz") as tar:
+        for name, content in entries.items():
+            info = tarfile.TarInfo(name)
+            if content is None:
+                info.type = tarfile.DIRTYPE
+                info.mode = 0o755
+                tar.addfile(info)
+            else:
+                info.size = len(content)
+                info.mode = 0o644
+                tar.addfile(info, io.BytesIO(content.encode()))
+        for name, target in (links or {}).items():
+            info = tarfile.TarInfo(name)
+            info.type = tarfile.SYMTYPE
+            info.linkname = target
+            tar.addfile(info)
+    return data.getvalue()
+
+
+def _fake_skopeo(mocker, layers: List[bytes]):
+    """Make skopeo create an OCI layout containing the given layers."""
+    digests = {}
+
+    def fake_run(cmd, **_):
+        layout_dir = Path(cmd[-1].split(":")[1])
+        layer_digests = [_add_blob(layout_dir, layer) for layer in layers]
+        manifest = {
+            "schemaVersion": 2,
+            "layers": [
+                {
+                    "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+                    "digest": d,
+                }
+                for d in layer_digests
+            ],
+        }
+        digests["manifest"] = _add_blob(layout_dir, json.dumps(manifest).encode())
+        index = {
+            "schemaVersion": 2,
+            "manifests": [
+                {
+                    "digest": digests["manifest"],
+                    "annotations": {"org.opencontainers.image.ref.name": "craft-parts"},
+                }
+            ],
+        }
+        (layout_dir / "index.json").write_text(json.dumps(index))
+
+    return mocker.patch("subprocess.run", side_effect=fake_run), digests
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestOciSource:
+    """Tests for the OCI image source handler."""
+
+    def test_get_source_handler_class(self):
+        handler_class = sources._get_source_handler_class(
+            "ubuntu:22.04", source_type="oci"
+        )
+        assert handler_class == OciSource
+
+    @pytest.mark.parametrize(
+        "option,value",
+        [
+            ("source_tag", "v1"),
+            ("source_commit", "abc"),
+            ("source_branch", "main"),
+            ("source_depth", 1),
+        ],
+    )
+    def test_invalid_options(self, option, value):
+        with pytest.raises(errors.InvalidSourceOption) as raised:
+            OciSource("ubuntu:22.04", "parts/p1/src", **{option: value})
+
+        assert raised.value.source_type == "oci"
+        assert raised.value.option == option.replace("_", "-")
+
+    @pytest.mark.parametrize(
+        "source,image",
+        [
+            ("ubuntu:22.04", "docker://ubuntu:22.04"),
+            ("docker://ubuntu:22.04", "docker://ubuntu:22.04"),
+            ("quay.io/org/app@sha256:1234", "docker://quay.io/org/app@sha256:1234"),
+        ],
+    )
+    def test_pull_registry_image(self, mocker, source, image):
+        mock_run, digests = _fake_skopeo(mocker, [_make_layer({"etc/os": "jammy"})])
+        Path("parts/p1/src").mkdir(parents=True)
+
+        oci_source = OciSource(source, "parts/p1/src")
+        oci_source.pull()
+
+        mock_run.assert_called_once_with(
+            ["skopeo", "copy", "--quiet", image, "oci:parts/p1/oci:craft-parts"],
+            check=True,
+        )
+        assert Path("parts/p1/src/etc/os").read_text() == "jammy"
+        assert oci_source.source_details == {"digest": digests["manifest"]}
+
+    def test_pull_oci_archive(self, mocker, new_dir):
+        mock_run, _ = _fake_skopeo(mocker, [_make_layer({"hello": "world"})])
+        Path("image.tar").touch()
+        Path("parts/p1/src").mkdir(parents=True)
+
+        oci_source = OciSource("image.tar", "parts/p1/src")
+        oci_source.pull()
+
+        assert mock_run.mock_calls[0].args[0][3] == f"oci-archive:{new_dir}/image.tar"
+        assert Path("parts/p1/src/hello").read_text() == "world"
+
+    def test_pull_layers(self, mocker):
+        _fake_skopeo(
+            mocker,
+            [
+                _make_layer(
+                    {
+                        "usr": None,
+                        "usr/bin": None,
+                        "usr/bin/ls": "ls",
+                        "etc": None,
+                        "etc/passwd": "root",
+                        "etc/removed": "x",
+                        "opt": None,
+                        "opt/old": "old",
+                    },
+                    links={"bin": "usr/bin", "etc/alternatives": "/usr/bin/ls"},
+                ),
+                _make_layer(
+                    {
+                        "etc/.wh.removed": "",
+                        "etc/passwd": "root\nuser",
+                        "opt/new": "new",
+                        "opt/.wh..wh..opq": "",
+                    }
+                ),
+            ],
+        )
+        Path("parts/p1/src").mkdir(parents=True)
+
+        OciSource("ubuntu:22.04", "parts/p1/src").pull()
+
+        src = Path("parts/p1/src")
+        assert (src / "usr/bin/ls").read_text() == "ls"
+        assert (src / "bin").is_symlink()
+        assert (src / "bin/ls").read_text() == "ls"
+        assert os.readlink(src / "etc/alternatives") == "/usr/bin/ls"
+        assert (src / "etc/passwd").read_text() == "root\nuser"
+        assert (src / "etc/removed").exists() is False
+        assert sorted(p.name for p in (src / "opt").iterdir()) == ["new"]
+
+    def test_pull_unsafe_entries(self, mocker, new_dir):
+        _fake_skopeo(
+            mocker,
+            [
+                _make_layer(
+                    {"../escape": "x", "/abs": "y"}, links={"link": str(new_dir)}
+                ),
+                _make_layer({"link/file": "z"}),
+            ],
+        )
+        Path("parts/p1/src").mkdir(parents=True)
+
+        with pytest.raises(errors.InvalidOciImage) as raised:
+            OciSource("ubuntu:22.04", "parts/p1/src").pull()
+
+        assert raised.value.message == (
+            "layer entry 'link/file' is under a symbolic link"
+        )
+        assert Path("parts/p1/src/abs").read_text() == "y"
+        assert Path("parts/p1/escape").exists() is False
+        assert Path("file").exists() is False
+
+    def test_pull_checksum(self, mocker):
+        layer = _make_layer({"hello": "world"})
+        _, digests = _fake_skopeo(mocker, [layer])
+        Path("parts/p1/src").mkdir(parents=True)
+
+        # find the manifest digest
+        OciSource("ubuntu:22.04", "parts/p1/src").pull()
+        checksum = digests["manifest"].replace(":", "/")
+
+        oci_source = OciSource(
+            "ubuntu:22.04", "parts/p1/src", source_checksum=checksum
+        )
+        oci_source.pull()
+
+        assert oci_source.source_details == {"digest": digests["manifest"]}
+
+    def test_pull_checksum_mismatch(self, mocker):
+        _, digests = _fake_skopeo(mocker, [_make_layer({"hello": "world"})])
+        Path("parts/p1/src").mkdir(parents=True)
+
+        oci_source = OciSource(
+            "ubuntu:22.04", "parts/p1/src", source_checksum="sha256/1234"
+        )
+        with pytest.raises(errors.ChecksumMismatch) as raised:
+            oci_source.pull()
+
+        assert raised.value.expected == "sha256:1234"
+        assert raised.value.obtained == digests["manifest"]
+        assert Path("parts/p1/src/hello").exists() is False
+
+    def test_pull_error(self, mocker):
+        mocker.patch(
+            "subprocess.run",
+            side_effect=subprocess.CalledProcessError(returncode=1, cmd=["skopeo"]),
+        )
+        Path("parts/p1/src").mkdir(parents=True)
+
+        with pytest.raises(errors.PullError) as raised:
+            OciSource("ubuntu:22.04", "parts/p1/src").pull()
+
+        assert raised.value.command[:2] == ["skopeo", "copy"]
+        assert raised.value.exit_code == 1
+
+    def test_pull_invalid_layer(self, mocker):
+        _fake_skopeo(mocker, [b"not a tarball"])
+        Path("parts/p1/src").mkdir(parents=True)
+
+        with pytest.raises(errors.InvalidOciImage) as raised:
+            OciSource("ubuntu:22.04", "parts/p1/src").pull()
+
+        assert raised.value.message.startswith("cannot unpack layer")
+
+    def test_update_unsupported(self):
+        oci_source = OciSource("ubuntu:22.04", "parts/p1/src")
+
+        with pytest.raises(errors.SourceUpdateUnsupported):
+            oci_source.update()
@@ -0,0 +1,94 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import subprocess
+from pathlib import Path
+from unittest.mock import call
+
+import pytest
+
+from craft_parts.sources import errors, patches
+
+
+@pytest.fixture
+def patch_files():
+    Path("patches").mkdir()
+    Path("patches/02-second.patch").write_text("second")
+    Path("patches/01-first.patch").write_text("first")
+    Path("patches/subdir").mkdir()
+    Path("extra.diff").write_text("extra")
+
+
+@pytest.mark.usefixtures("new_dir", "patch_files")
+class TestPatches:
+    """Verify patch application to pulled sources."""
+
+    def test_get_patch_files(self):
+        assert patches.get_patch_files(["extra.diff", "patches"]) == [
+            Path("extra.diff"),
+            Path("patches/01-first.patch"),
+            Path("patches/02-second.patch"),
+        ]
+
+    def test_get_digests(self):
+        assert patches.get_digests(["patches", "missing.patch"]) == {
+            "patches/01-first.patch": (
+                "a7937b64b8caa58f03721bb6bacf5c78cb235febe0e70b1b84cd99541461a08e"
+            ),
+            "patches/02-second.patch": (
+                "16367aacb67a4a017c8da8ab95682ccb390863780f7114dda0a0e0c55644c7c4"
+            ),
+        }
+
+    def test_apply_patches(self, mocker, new_dir):
+        mock_run = mocker.patch("subprocess.run")
+
+        patches.apply_patches(["patches", "extra.diff"], src_dir="src", strip=0)
+
+        options = ["--batch", "--forward", "--strip=0", "--directory=src"]
+        assert mock_run.mock_calls == [
+            call(
+                ["patch", *options, f"--input={new_dir}/patches/01-first.patch"],
+                check=True,
+            ),
+            call(
+                ["patch", *options, f"--input={new_dir}/patches/02-second.patch"],
+                check=True,
+            ),
+            call(["patch", *options, f"--input={new_dir}/extra.diff"], check=True),
+        ]
+
+    def test_apply_patches_missing(self, mocker):
+        mock_run = mocker.patch("subprocess.run")
+
+        with pytest.raises(errors.PatchError) as raised:
+            patches.apply_patches(["missing.patch"], src_dir="src")
+
+        assert raised.value.patch == "missing.patch"
+        assert raised.value.message == "file not found"
+        mock_run.assert_not_called()
+
+    def test_apply_patches_error(self, mocker):
+        mocker.patch(
+            "subprocess.run",
+            side_effect=subprocess.CalledProcessError(returncode=1, cmd=["patch"]),
+        )
+
+        with pytest.raises(errors.PatchError) as raised:
+            patches.apply_patches(["extra.diff"], src_dir="src")
+
+        assert raised.value.patch == "extra.diff"
+        assert raised.value.message == "patch exited with code 1"
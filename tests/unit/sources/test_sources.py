@@ -19,6 +19,8 @@ import pytest
 from craft_parts.dirs import ProjectDirs
 from craft_parts.parts import Part
 from craft_parts.sources import LocalSource, errors, sources
+from craft_parts.sources.git_source import GitSource
+from craft_parts.sources.oci_source import OciSource
 from craft_parts.sources.tar_source import TarSource
 
 
@@ -31,6 +33,7 @@ from craft_parts.sources.tar_source import TarSource
         (".tar.bz2", TarSource),
         (".tgz", TarSource),
         (".tar", TarSource),
+        ("https://example.com/repo.git", GitSource),
     ],
 )
 def test_get_source_handler_class(tc_url, tc_handler):
@@ -96,3 +99,100 @@ def test_sources_with_branch_errors(
         )
     assert err.value.source_type == source_type
     assert err.value.option == error
+
+
+@pytest.mark.parametrize(
+    "source_type,option,value",
+    [
+        (source_type, option, value)
+        for source_type in ["tar", "oci"]
+        for option, value in [
+            ("source-submodules", ["lib"]),
+            ("source-sparse-paths", ["docs"]),
+            ("source-lfs", False),
+            ("source-keyring", "keys.gpg"),
+            ("source-allowed-signers", "signers"),
+        ]
+    ]
+    + [
+        ("oci", "source-auth", {"token-env": "TOKEN"}),
+        ("git", "source-auth", {"token-env": "TOKEN"}),
+        ("oci", "source-region", "eu-west-1"),
+        ("oci", "source-profile", "release"),
+        ("git", "source-region", "eu-west-1"),
+        ("git", "source-profile", "release"),
+        ("oci", "source-checksum", "sha256/SHA256SUMS"),
+    ],
+)
+def test_sources_with_invalid_options_errors(source_type, option, value):
+    p1 = Part(
+        "p1",
+        {"source": "https://source.com", "source-type": source_type, option: value},
+    )
+
+    with pytest.raises(errors.InvalidSourceOption) as err:
+        sources.get_source_handler(
+            application_name="test", part=p1, project_dirs=ProjectDirs()
+        )
+    assert err.value.source_type == source_type
+    assert err.value.option == option
+
+
+@pytest.mark.parametrize(
+    "source_type,handler_class", [("oci", OciSource), ("git", GitSource)]
+)
+def test_sources_with_checksum_signature_errors(source_type, handler_class):
+    with pytest.raises(errors.InvalidSourceOption) as err:
+        handler_class(
+            "https://source.com", "src", source_checksum_signature="SHA256SUMS.gpg"
+        )
+    assert err.value.source_type == source_type
+    assert err.value.option == "source-checksum-signature"
+
+
+def test_get_source_options():
+    options = sources.get_source_options()
+    assert sorted(options) == ["git", "local", "oci", "tar"]
+    assert options["local"] == []
+    assert options["oci"] == ["source-checksum"]
+    assert "source-branch" in options["git"]
+    assert "source-checksum" not in options["git"]
+    assert "source-region" in options["tar"]
+
+
+@pytest.mark.parametrize(
+    "source_type,option",
+    [
+        (source_type, option)
+        for source_type, handler in sources._source_handler.items()
+        for option in [
+            "source-tag",
+            "source-commit",
+            "source-branch",
+            "source-depth",
+            "source-submodules",
+            "source-sparse-paths",
+            "source-allowed-signers",
+        ]
+        if option not in handler.source_options and source_type != "local"
+    ],
+)
+def test_get_source_options_rejected(source_type, option):
+    values = {
+        "source-depth": 1,
+        "source-submodules": ["lib"],
+        "source-sparse-paths": ["docs"],
+    }
+    p1 = Part(
+        "p1",
+        {
+            "source": "https://source.com",
+            "source-type": source_type,
+            option: values.get(option, "value"),
+        },
+    )
+
+    with pytest.raises(errors.InvalidSourceOption):
+        sources.get_source_handler(
+            application_name="test", part=p1, project_dirs=ProjectDirs()
+        )
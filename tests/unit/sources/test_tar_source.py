@@ -20,7 +20,8 @@ import tarfile
 import pytest
 import requests
 
-from craft_parts.sources import sources
+from craft_parts.sources import errors, sources
+from craft_parts.sources.cache import FileCache
 
 
 @pytest.mark.usefixtures("new_dir")
@@ -69,6 +70,243 @@ class TestTarSource:
         tar_source.pull()
         assert download_spy.call_count == 0
 
+    def test_pull_shared_cache(self, mocker, http_server):
+        mocker.patch("craft_parts.sources.tar_source.TarSource.provision")
+
+        source = "http://{}:{}/{file_name}".format(
+            *http_server.server_address, file_name="test.tar"
+        )
+        expected_checksum = (
+            "sha384/d9da1f5d54432edc8963cd817ceced83f7c6d61d3"
+            "50ad76d1c2f50c4935d11d50211945ca0ecb980c04c98099"
+            "085b0c3"
+        )
+        os.makedirs("src1")
+        tar_source = sources.TarSource(
+            source,
+            "src1",
+            source_checksum=expected_checksum,
+            cache=FileCache("app1", cache_dir="shared"),
+        )
+        tar_source.pull()
+
+        assert os.path.isfile(os.path.join("shared", "files", expected_checksum))
+
+        # another application using the same cache doesn't download again
+        download_spy = mocker.spy(requests, "get")
+        os.makedirs("src2")
+        tar_source = sources.TarSource(
+            source,
+            "src2",
+            source_checksum=expected_checksum,
+            cache=FileCache("app2", cache_dir="shared"),
+        )
+        tar_source.pull()
+
+        assert download_spy.call_count == 0
+        with open(os.path.join("src2", "test.tar"), "r") as tar_file:
+            assert tar_file.read() == "Test fake file"
+
+    @pytest.mark.parametrize("source", ["s3://bucket/test.tar", "gs://bucket/test.tar"])
+    def test_pull_object_storage(self, mocker, source):
+        mocker.patch("craft_parts.sources.tar_source.TarSource.provision")
+
+        def fake_copy(cmd, **_):
+            with open(cmd[-1], "w") as dest:
+                dest.write("Test fake file")
+
+        mock_run = mocker.patch("subprocess.run", side_effect=fake_copy)
+        expected_checksum = (
+            "sha384/d9da1f5d54432edc8963cd817ceced83f7c6d61d3"
+            "50ad76d1c2f50c4935d11d50211945ca0ecb980c04c98099"
+            "085b0c3"
+        )
+        tar_source = sources.TarSource(
+            source,
+            ".",
+            source_checksum=expected_checksum,
+            source_profile="release",
+            cache=FileCache("app", cache_dir="cache"),
+        )
+        tar_source.pull()
+
+        assert mock_run.call_count == 1
+        assert mock_run.mock_calls[0].args[0][-2:] == [source, "./test.tar"]
+
+        # the checksum is verified and the file is cached
+        tar_source.pull()
+        assert mock_run.call_count == 1
+
+    def test_pull_object_storage_checksum_mismatch(self, mocker):
+        def fake_copy(cmd, **_):
+            with open(cmd[-1], "w") as dest:
+                dest.write("Other file")
+
+        mocker.patch("subprocess.run", side_effect=fake_copy)
+        tar_source = sources.TarSource(
+            "s3://bucket/test.tar", ".", source_checksum="md5/1234"
+        )
+
+        with pytest.raises(errors.ChecksumMismatch):
+            tar_source.pull()
+
+    @pytest.mark.parametrize(
+        "source,option",
+        [
+            ("https://example.com/test.tar", "source_region"),
+            ("gs://bucket/test.tar", "source_region"),
+            ("https://example.com/test.tar", "source_profile"),
+        ],
+    )
+    def test_invalid_object_storage_options(self, source, option):
+        with pytest.raises(errors.InvalidSourceOption) as raised:
+            sources.TarSource(source, ".", **{option: "value"})
+
+        assert raised.value.source_type == "tar"
+        assert raised.value.option == option.replace("_", "-")
+
+    def test_pull_checksum_file(self, mocker):
+        mock_prov = mocker.patch("craft_parts.sources.tar_source.TarSource.provision")
+        os.makedirs("src")
+        with open("test.tar", "w") as tar_file:
+            tar_file.write("Test fake file")
+        with open("SHA256SUMS", "w") as sums_file:
+            sums_file.write(
+                "0123456789abcdef  other.tar\n"
+                "1eaacf5d02554283dca5ff3488c6a9fc6fa07e16b8282901d39245f8614d9063"
+                " *test.tar\n"
+            )
+
+        tar_source = sources.TarSource(
+            "test.tar", "src", source_checksum="sha256/SHA256SUMS"
+        )
+        tar_source.pull()
+
+        mock_prov.assert_called_once_with("src", src="src/test.tar", clean_target=False)
+        assert tar_source.source_checksum == (
+            "sha256/1eaacf5d02554283dca5ff3488c6a9fc6fa07e16b8282901d39245f8614d9063"
+        )
+
+    def test_pull_checksum_file_not_listed(self):
+        os.makedirs("src")
+        with open("test.tar", "w") as tar_file:
+            tar_file.write("Test fake file")
+        with open("SHA256SUMS", "w") as sums_file:
+            sums_file.write("0123456789abcdef  other.tar\n")
+
+        tar_source = sources.TarSource(
+            "test.tar", "src", source_checksum="sha256/SHA256SUMS"
+        )
+        with pytest.raises(errors.ChecksumFileError) as raised:
+            tar_source.pull()
+
+        assert raised.value.checksum_file == "SHA256SUMS"
+        assert raised.value.message == "'test.tar' is not listed"
+
+    def test_pull_checksum_file_relative_path(self, mocker):
+        mocker.patch("craft_parts.sources.tar_source.TarSource.provision")
+        os.makedirs("src")
+        os.makedirs("arm64")
+        with open("arm64/test.tar", "w") as tar_file:
+            tar_file.write("Test fake file")
+        with open("SHA256SUMS", "w") as sums_file:
+            sums_file.write(
+                "0123456789abcdef  x86_64/test.tar\n"
+                "1eaacf5d02554283dca5ff3488c6a9fc6fa07e16b8282901d39245f8614d9063"
+                "  arm64/test.tar\n"
+            )
+
+        tar_source = sources.TarSource(
+            "arm64/test.tar", "src", source_checksum="sha256/SHA256SUMS"
+        )
+        tar_source.pull()
+
+        assert tar_source.source_checksum == (
+            "sha256/1eaacf5d02554283dca5ff3488c6a9fc6fa07e16b8282901d39245f8614d9063"
+        )
+
+    @pytest.mark.parametrize("signature", [None, "SHA256SUMS.gpg"])
+    def test_pull_checksum_file_signature(self, mocker, signature):
+        mocker.patch("craft_parts.sources.tar_source.TarSource.provision")
+        mock_run = mocker.patch("subprocess.run")
+        for name in ["test.tar", "SHA256SUMS", "SHA256SUMS.gpg"]:
+            with open(name, "w") as test_file:
+                test_file.write(name)
+        os.makedirs("src")
+
+        def fake_verify(sums_file, **_):
+            with open("verified", "w") as verified_file:
+                verified_file.write(f"{'0' * 64}  test.tar\n")
+            return "verified"
+
+        mock_verify = mocker.patch(
+            "craft_parts.sources.checksum.verify_checksum_file_signature",
+            side_effect=fake_verify,
+        )
+        mocker.patch("craft_parts.sources.base.verify_checksum")
+
+        tar_source = sources.TarSource(
+            "test.tar",
+            "src",
+            source_checksum="sha256/SHA256SUMS",
+            source_keyring="keys.gpg",
+            source_checksum_signature=signature,
+        )
+        tar_source.pull()
+
+        assert mock_verify.call_count == 1
+        assert mock_verify.mock_calls[0].kwargs["keyring"] == "keys.gpg"
+        assert (mock_verify.mock_calls[0].kwargs["signature"] is None) is (
+            signature is None
+        )
+        assert tar_source.source_checksum == f"sha256/{'0' * 64}"
+        mock_run.assert_not_called()
+
+    def test_pull_checksum_file_signature_error(self, mocker):
+        for name in ["test.tar", "SHA256SUMS"]:
+            with open(name, "w") as test_file:
+                test_file.write(name)
+        mocker.patch(
+            "craft_parts.sources.checksum.verify_checksum_file_signature",
+            return_value=None,
+        )
+
+        tar_source = sources.TarSource(
+            "test.tar",
+            "src",
+            source_checksum="sha256/SHA256SUMS",
+            source_keyring="keys.gpg",
+        )
+        with pytest.raises(errors.ChecksumFileError) as raised:
+            tar_source.pull()
+
+        assert raised.value.checksum_file == "SHA256SUMS"
+        assert raised.value.message == "signature verification failed"
+
+    @pytest.mark.parametrize(
+        "options,option",
+        [
+            ({"source_keyring": "keys.gpg"}, "source-keyring"),
+            (
+                {"source_checksum": "md5/1234", "source_keyring": "keys.gpg"},
+                "source-keyring",
+            ),
+            (
+                {
+                    "source_checksum": "sha256/SHA256SUMS",
+                    "source_checksum_signature": "SHA256SUMS.gpg",
+                },
+                "source-checksum-signature",
+            ),
+        ],
+    )
+    def test_invalid_checksum_file_options(self, options, option):
+        with pytest.raises(errors.InvalidSourceOption) as raised:
+            sources.TarSource("test.tar", ".", **options)
+
+        assert raised.value.source_type == "tar"
+        assert raised.value.option == option
+
     def test_strip_common_prefix(self):
         # Create tar file for testing
         os.makedirs(os.path.join("src", "test_prefix"))
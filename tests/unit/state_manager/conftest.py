@@ -42,6 +42,12 @@ def properties() -> Dict[str, Any]:
         "organize": {"src1": "dest1", "src2": "dest2"},
         "stage": ["-usr/docs"],
         "prime": ["*"],
+        "overlay-packages": ["overlay-pkg1", "overlay-pkg2"],
+        "overlay": ["-usr/share"],
+        "overlay-script": "overlay-script",
+        "permissions": [{"path": "bin/*", "mode": "755"}],
+        "strip": {"split-debug": True, "exclude": []},
+        "elf-patch": {"interpreter": "/lib/ld.so", "exclude": []},
         "override-pull": "override-pull",
         "override-build": "override-build",
         "override-stage": "override-stage",
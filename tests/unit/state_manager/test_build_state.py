@@ -28,20 +28,24 @@ class TestBuildState:
     def test_marshal_empty(self):
         state = BuildState()
         assert state.marshal() == {
+            "schema-version": 2,
             "assets": {},
             "part-properties": {},
             "project-options": {},
             "files": set(),
             "directories": set(),
+            "values": {},
         }
 
     def test_marshal_unmarshal(self):
         state_data = {
+            "schema-version": 2,
             "assets": {"build-packages": ["foo"]},
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
 
         state = BuildState.unmarshal(state_data)
@@ -86,6 +90,7 @@ class TestBuildStateChanges:
             "after",
             "build-attributes",
             "build-packages",
+            "build-snaps",
             "disable-parallel",
             "organize",
             "override-build",
@@ -105,3 +110,32 @@ class TestBuildStateChanges:
     def test_project_option_changes(self, project_options):
         state = BuildState(project_options=project_options)
         assert state.diff_project_options_of_interest({}) == set()
+
+    def test_target_arch_changes(self):
+        state = BuildState(project_options={"target_arch": "amd64"})
+        assert (
+            state.diff_project_options_of_interest(
+                {"target_arch": "amd64", "arch_triplet": "x86_64-linux-gnu"}
+            )
+            == set()
+        )
+        assert state.diff_project_options_of_interest({"target_arch": "arm64"}) == {
+            "target_arch"
+        }
+
+    def test_project_vars_changes(self):
+        state = BuildState(project_options={"project_vars": {"version": "1.0"}})
+        assert (
+            state.diff_project_options_of_interest({"project_vars": {"version": "1.0"}})
+            == set()
+        )
+        assert state.diff_project_options_of_interest(
+            {"project_vars": {"version": "1.1"}}
+        ) == {"project_vars"}
+
+    def test_extra_property_changes(self, properties):
+        state = BuildState(part_properties={**properties, "go-arch": "arm64"})
+
+        other = {**properties, "go-arch": "riscv64"}
+        assert state.diff_properties_of_interest(other) == set()
+        assert state.diff_properties_of_interest(other, ["go-arch"]) == {"go-arch"}
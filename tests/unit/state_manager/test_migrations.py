@@ -0,0 +1,67 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import pytest
+
+from craft_parts import errors
+from craft_parts.parts import PartSpec
+from craft_parts.state_manager import migrations
+
+
+class TestMigrateState:
+    """Verify the migration of state data between schema versions."""
+
+    def test_migrate_current(self):
+        data = {"schema-version": 2, "part-properties": {"plugin": "nil"}}
+        state_data = migrations.migrate_state(data, part_name="foo", step_name="pull")
+        assert state_data == data
+
+    def test_migrate_unversioned(self):
+        data = {"part-properties": {"plugin": "nil", "stage": ["bin"]}, "files": []}
+        state_data = migrations.migrate_state(data, part_name="foo", step_name="pull")
+
+        assert state_data["schema-version"] == migrations.STATE_SCHEMA_VERSION
+        assert state_data["files"] == []
+        assert state_data["part-properties"] == {
+            **PartSpec.unmarshal({}).marshal(),
+            "plugin": "nil",
+            "stage": ["bin"],
+        }
+
+    def test_migrate_version_1(self):
+        data = {
+            "schema-version": 1,
+            "part-properties": {"source": "/work/src"},
+            "files": ["a"],
+        }
+        state_data = migrations.migrate_state(data, part_name="foo", step_name="pull")
+        assert state_data == {**data, "schema-version": 2}
+
+    def test_migrate_unversioned_no_properties(self):
+        state_data = migrations.migrate_state({}, part_name="foo", step_name="pull")
+        assert state_data["part-properties"] == PartSpec.unmarshal({}).marshal()
+
+    @pytest.mark.parametrize("version", [3, "2"])
+    def test_migrate_unsupported_version(self, version):
+        with pytest.raises(errors.StateVersionError) as raised:
+            migrations.migrate_state(
+                {"schema-version": version}, part_name="foo", step_name="build"
+            )
+        assert raised.value.part_name == "foo"
+        assert raised.value.step_name == "build"
+        assert raised.value.version == version
+        assert raised.value.supported_version == 2
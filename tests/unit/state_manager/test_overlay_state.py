@@ -0,0 +1,136 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+import yaml
+
+from craft_parts.state_manager.overlay_state import OverlayState
+
+
+class TestOverlayState:
+    """Verify OverlayState initialization and marshaling."""
+
+    def test_marshal_empty(self):
+        state = OverlayState()
+        assert state.marshal() == {
+            "schema-version": 2,
+            "assets": {},
+            "part-properties": {},
+            "project-options": {},
+            "files": set(),
+            "directories": set(),
+            "values": {},
+        }
+
+    def test_marshal_unmarshal(self):
+        state_data = {
+            "schema-version": 2,
+            "assets": {"layer-key": "abcd"},
+            "part-properties": {"plugin": "nil"},
+            "project-options": {"target_arch": "amd64"},
+            "files": {"a"},
+            "directories": {"b"},
+            "values": {"version": "1.0"},
+        }
+
+        state = OverlayState.unmarshal(state_data)
+        assert state.marshal() == state_data
+
+    def test_unmarshal_invalid(self):
+        with pytest.raises(TypeError) as raised:
+            OverlayState.unmarshal(False)  # type: ignore
+        assert str(raised.value) == "state data is not a dictionary"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestOverlayStatePersist:
+    """Verify writing StepState to file."""
+
+    def test_write(self, properties):
+        state = OverlayState(
+            assets={"layer-key": "abcd"},
+            part_properties=properties,
+            project_options={
+                "target_arch": "amd64",
+            },
+            files={"a"},
+            directories={"b"},
+        )
+
+        state.write(Path("state"))
+        with open("state") as f:
+            content = f.read()
+
+        new_state = yaml.safe_load(content)
+        assert new_state == state.marshal()
+
+
+class TestOverlayStateChanges:
+    """Verify state comparison methods."""
+
+    def test_property_changes(self, properties):
+        state = OverlayState(part_properties=properties)
+
+        relevant_properties = [
+            "overlay-packages",
+            "overlay-script",
+        ]
+
+        for prop in properties.keys():
+            other = properties.copy()
+            other[prop] = "new value"
+
+            if prop in relevant_properties:
+                # relevant project options changed
+                assert state.diff_properties_of_interest(other) == {prop}
+            else:
+                # relevant properties didn't change
+                assert state.diff_properties_of_interest(other) == set()
+
+    def test_project_option_changes(self, project_options):
+        state = OverlayState(project_options=project_options)
+        assert state.diff_project_options_of_interest({}) == set()
+
+    def test_target_arch_changes(self):
+        state = OverlayState(project_options={"target_arch": "amd64"})
+        assert (
+            state.diff_project_options_of_interest(
+                {"target_arch": "amd64", "arch_triplet": "x86_64-linux-gnu"}
+            )
+            == set()
+        )
+        assert state.diff_project_options_of_interest({"target_arch": "arm64"}) == {
+            "target_arch"
+        }
+
+    def test_project_vars_changes(self):
+        state = OverlayState(project_options={"project_vars": {"version": "1.0"}})
+        assert (
+            state.diff_project_options_of_interest({"project_vars": {"version": "1.0"}})
+            == set()
+        )
+        assert state.diff_project_options_of_interest(
+            {"project_vars": {"version": "1.1"}}
+        ) == {"project_vars"}
+
+    def test_extra_property_changes(self, properties):
+        state = OverlayState(part_properties={**properties, "go-arch": "arm64"})
+
+        other = {**properties, "go-arch": "riscv64"}
+        assert state.diff_properties_of_interest(other) == set()
+        assert state.diff_properties_of_interest(other, ["go-arch"]) == {"go-arch"}
@@ -28,22 +28,30 @@ class TestPrimeState:
     def test_marshal_empty(self):
         state = PrimeState()
         assert state.marshal() == {
+            "schema-version": 2,
             "part-properties": {},
             "project-options": {},
             "files": set(),
             "directories": set(),
+            "values": {},
             "dependency-paths": set(),
             "primed-stage-packages": set(),
+            "permissions": {},
+            "elf-patches": {},
         }
 
     def test_marshal_unmarshal(self):
         state_data = {
+            "schema-version": 2,
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
             "dependency-paths": {"c"},
             "primed-stage-packages": {"d"},
+            "permissions": {"e": {"mode": "755"}},
+            "elf-patches": {"f": {"rpath": "$ORIGIN", "digest": "1234"}},
         }
 
         state = PrimeState.unmarshal(state_data)
@@ -69,6 +77,8 @@ class TestPrimeStatePersist:
             directories={"b"},
             dependency_paths={"c"},
             primed_stage_packages={"d"},
+            permissions={"e": {"mode": "755"}},
+            elf_patches={"f": {"rpath": "$ORIGIN", "digest": "1234"}},
         )
 
         state.write(Path("state"))
@@ -86,8 +96,12 @@ class TestPrimeStateChanges:
         state = PrimeState(part_properties=properties)
 
         relevant_properties = [
+            "overlay",
             "override-prime",
             "prime",
+            "permissions",
+            "strip",
+            "elf-patch",
         ]
 
         for prop in properties.keys():
@@ -28,20 +28,24 @@ class TestPullState:
     def test_marshal_empty(self):
         state = PullState()
         assert state.marshal() == {
+            "schema-version": 2,
             "assets": {},
             "part-properties": {},
             "project-options": {},
             "files": set(),
             "directories": set(),
+            "values": {},
         }
 
     def test_marshal_unmarshal(self):
         state_data = {
+            "schema-version": 2,
             "assets": {"stage-packages": ["foo"]},
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
 
         state = PullState.unmarshal(state_data)
@@ -93,6 +97,8 @@ class TestPullStateChanges:
             "source-subdir",
             "override-pull",
             "stage-packages",
+            "stage-snaps",
+            "stage-snaps-track-channel",
         ]
 
         for prop in properties.keys():
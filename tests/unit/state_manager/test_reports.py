@@ -37,6 +37,24 @@ def test_outdated_report(step, source_modified, result):
     assert report.reason() == result
 
 
+def test_outdated_report_details():
+    report = reports.OutdatedReport(
+        previous_step_modified=Step.PULL,
+        source_modified=True,
+        modified_source_files=["b", "a"],
+    )
+    assert report.details() == [
+        "The 'PULL' step ran more recently.",
+        "Source file 'a' was modified.",
+        "Source file 'b' was modified.",
+    ]
+
+
+def test_outdated_report_details_unknown_files():
+    report = reports.OutdatedReport(source_modified=True)
+    assert report.details() == ["The source was modified."]
+
+
 @pytest.mark.parametrize(
     "props,opts,deps,result",
     [
@@ -78,3 +96,21 @@ def test_dirty_report(props, opts, deps, result):
         dirty_properties=props, dirty_project_options=opts, changed_dependencies=deps
     )
     assert report.reason() == result
+
+
+def test_dirty_report_details():
+    report = reports.DirtyReport(
+        dirty_properties=["source", "build-packages"],
+        dirty_project_options=["target_arch"],
+        changed_dependencies=[Dependency("e", Step.STAGE)],
+    )
+    assert report.details() == [
+        "Property 'build-packages' changed.",
+        "Property 'source' changed.",
+        "Project option 'target_arch' changed.",
+        "The 'STAGE' step of dependency 'e' changed.",
+    ]
+
+
+def test_dirty_report_details_empty():
+    assert reports.DirtyReport().details() == []
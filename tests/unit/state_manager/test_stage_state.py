@@ -28,18 +28,22 @@ class TestStageState:
     def test_marshal_empty(self):
         state = StageState()
         assert state.marshal() == {
+            "schema-version": 2,
             "part-properties": {},
             "project-options": {},
             "files": set(),
             "directories": set(),
+            "values": {},
         }
 
     def test_marshal_unmarshal(self):
         state_data = {
+            "schema-version": 2,
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
 
         state = StageState.unmarshal(state_data)
@@ -81,6 +85,7 @@ class TestStageStateChanges:
 
         relevant_properties = [
             "filesets",
+            "overlay",
             "override-stage",
             "stage",
         ]
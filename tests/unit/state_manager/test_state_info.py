@@ -0,0 +1,113 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts.parts import Part
+from craft_parts.state_manager import state_info, states
+from craft_parts.steps import Step
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestPartStateInfo:
+    """Verify obtaining the recorded states of a part."""
+
+    def test_no_states(self):
+        part = Part("foo", {})
+        info = state_info.get_part_state_info(part)
+
+        assert info.part_name == "foo"
+        assert info.steps == {}
+        assert info.stage_packages == {}
+        assert info.source_details is None
+        assert info.build_environment is None
+
+    def test_step_states(self, properties):
+        part = Part("foo", {})
+        details = {"commit": "1234"}
+        states.PullState(
+            part_properties=properties,
+            project_options={"target_arch": "amd64"},
+            assets={"source-details": details},
+        ).write(states.state_file_path(part, Step.PULL))
+        states.StageState(
+            part_properties=properties,
+            project_options={"target_arch": "amd64"},
+            files={"a"},
+            directories={"b"},
+        ).write(states.state_file_path(part, Step.STAGE))
+
+        info = state_info.get_part_state_info(part)
+
+        assert list(info.steps) == [Step.PULL, Step.STAGE]
+        assert info.source_details == details
+
+        pull = info.steps[Step.PULL]
+        assert pull.part_name == "foo"
+        assert pull.step == Step.PULL
+        assert pull.part_properties == properties
+        assert pull.project_options == {"target_arch": "amd64"}
+        assert pull.assets == {"source-details": details}
+        assert pull.properties_fingerprint.startswith("sha256/")
+
+        stage = info.steps[Step.STAGE]
+        assert stage.assets == {}
+        assert stage.files == {"a"}
+        assert stage.directories == {"b"}
+
+    def test_properties_fingerprint(self, properties):
+        part = Part("foo", {})
+
+        def get_fingerprint(part_properties):
+            state = states.StageState(part_properties=part_properties)
+            state.write(states.state_file_path(part, Step.STAGE))
+            info = state_info.get_part_state_info(part)
+            return info.steps[Step.STAGE].properties_fingerprint
+
+        fingerprint = get_fingerprint(properties)
+
+        # Properties not relevant to the step don't change the fingerprint.
+        other = {**properties, "override-build": "other"}
+        assert get_fingerprint(other) == fingerprint
+
+        other = {**properties, "stage": ["other"]}
+        assert get_fingerprint(other) != fingerprint
+
+    def test_stage_packages(self):
+        part = Part("foo", {"stage-packages": ["hello"]})
+        states.PullState().write(states.state_file_path(part, Step.PULL))
+        part.part_packages_dir.mkdir(parents=True)
+        Path(part.part_packages_dir, "hello_2.10-2_amd64.deb").touch()
+        Path(part.part_packages_dir, "libfoo_1%3a1.0_amd64.deb").touch()
+
+        info = state_info.get_part_state_info(part)
+
+        assert info.stage_packages == {"hello": "2.10-2", "libfoo": "1:1.0"}
+
+    def test_build_environment(self, properties):
+        part = Part("foo", {})
+        snapshot = {"toolchain": {"go": "go version go1.22.1 linux/amd64"}}
+        states.BuildState(
+            part_properties=properties,
+            assets={"build-environment": snapshot},
+        ).write(states.state_file_path(part, Step.BUILD))
+
+        info = state_info.get_part_state_info(part)
+
+        assert info.build_environment == snapshot
@@ -21,6 +21,7 @@ import pytest
 
 from craft_parts.infos import ProjectInfo
 from craft_parts.parts import Part
+from craft_parts.plugins.go_plugin import GoPluginProperties
 from craft_parts.state_manager import StateManager, state_manager, states
 from craft_parts.steps import Step
 from craft_parts.utils import os_utils
@@ -254,6 +255,7 @@ class TestStateManager:
 
         # add states for all steps
         sm.set_state(p1, Step.PULL, state=states.PullState())
+        sm.set_state(p1, Step.OVERLAY, state=states.OverlayState())
         sm.set_state(p1, Step.BUILD, state=states.BuildState())
         sm.set_state(p1, Step.STAGE, state=states.StageState())
         sm.set_state(p1, Step.PRIME, state=states.PrimeState())
@@ -298,7 +300,9 @@ class TestStateManager:
         part_properties = p1.spec.marshal()
 
         # p1 build already ran
-        s1 = states.BuildState(part_properties=part_properties)
+        s1 = states.BuildState(
+            part_properties=part_properties, project_options=info.project_options
+        )
         s1.write(Path("parts/p1/state/build"))
 
         # but p1 pull ran more recently
@@ -307,14 +311,17 @@ class TestStateManager:
 
         sm = StateManager(project_info=info, part_list=[p1])
 
+        # the overlay step didn't run
         for step in list(Step):
-            assert sm.should_step_run(p1, step) == (step >= Step.BUILD)
+            assert sm.should_step_run(p1, step) == (step >= Step.OVERLAY)
 
         # and we updated it!
         sm._state_db.rewrap(part_name="p1", step=Step.BUILD, step_updated=True)
 
         for step in list(Step):
-            assert sm.should_step_run(p1, step) == (step >= Step.STAGE)
+            assert sm.should_step_run(p1, step) == (
+                step >= Step.STAGE or step == Step.OVERLAY
+            )
 
     def test_should_step_run_dirty(self):
         info = ProjectInfo()
@@ -324,14 +331,18 @@ class TestStateManager:
         # p1 pull and build already ran
         s1 = states.PullState(part_properties=part_properties)
         s1.write(Path("parts/p1/state/pull"))
-        s2 = states.BuildState(part_properties=part_properties)
+        s2 = states.BuildState(
+            part_properties=part_properties, project_options=info.project_options
+        )
         s2.write(Path("parts/p1/state/build"))
 
         sm = StateManager(project_info=info, part_list=[p1])
 
-        # we're clean, steps STAGE and PRIME should run
+        # we're clean, steps OVERLAY, STAGE and PRIME should run
         for step in list(Step):
-            assert sm.should_step_run(p1, step) == (step > Step.BUILD)
+            assert sm.should_step_run(p1, step) == (
+                step > Step.BUILD or step == Step.OVERLAY
+            )
 
         # make the build step dirty
         stw = sm._state_db.get(part_name="p1", step=Step.BUILD)
@@ -339,7 +350,7 @@ class TestStateManager:
 
         # now build should run again
         for step in list(Step):
-            assert sm.should_step_run(p1, step) == (step >= Step.BUILD)
+            assert sm.should_step_run(p1, step) == (step >= Step.OVERLAY)
 
 
 @pytest.mark.usefixtures("new_dir")
@@ -383,6 +394,27 @@ class TestStepOutdated:
         for step in list(Step):
             assert sm.check_if_outdated(p1, step) is None
 
+    def test_outdated_overlay(self):
+        info = ProjectInfo(features={"overlay": True})
+        p1 = Part("p1", {})
+
+        # p1 overlay and build already ran
+        states.OverlayState().write(Path("parts/p1/state/overlay"))
+        states.BuildState().write(Path("parts/p1/state/build"))
+
+        # but p1 pull ran more recently
+        states.PullState().write(Path("parts/p1/state/pull"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+
+        for step in list(Step):
+            report = sm.check_if_outdated(p1, step)
+            if step in (Step.OVERLAY, Step.BUILD):
+                assert report is not None
+                assert report.reason() == "'PULL' step changed"
+            else:
+                assert report is None
+
     def test_source_outdated(self):
         info = ProjectInfo()
         p1 = Part("p1", {"source": "subdir"})  # source is local
@@ -401,6 +433,7 @@ class TestStepOutdated:
             if step == Step.PULL:
                 assert report is not None
                 assert report.reason() == "source changed"
+                assert report.modified_source_files == ["foo"]
             else:
                 assert report is None
 
@@ -432,7 +465,9 @@ class TestStepDirty:
         # p1 pull and build already ran
         s1 = states.PullState(part_properties=part_properties)
         s1.write(Path("parts/p1/state/pull"))
-        s2 = states.BuildState(part_properties=part_properties)
+        s2 = states.BuildState(
+            part_properties=part_properties, project_options=info.project_options
+        )
         s2.write(Path("parts/p1/state/build"))
 
         sm = StateManager(project_info=info, part_list=[p1])
@@ -454,6 +489,191 @@ class TestStepDirty:
             else:
                 assert report is None
 
+    def test_dirty_patches(self):
+        info = ProjectInfo()
+        Path("fix.patch").write_text("fix")
+        p1 = Part("p1", {"source-patches": ["fix.patch"]})
+        part_properties = p1.spec.marshal()
+        digests = {
+            "fix.patch": (
+                "1c6e6c4c02e55178e85890fc9bbed4ce046415ec8122bf38f711b779184ae2a0"
+            )
+        }
+
+        # p1 pull already ran
+        s1 = states.PullState(
+            part_properties=part_properties, assets={"source-patches": digests}
+        )
+        s1.write(Path("parts/p1/state/pull"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+        assert sm.check_if_dirty(p1, Step.PULL) is None
+
+        # change the patch contents
+        Path("fix.patch").write_text("other fix")
+
+        report = sm.check_if_dirty(p1, Step.PULL)
+        assert report is not None
+        assert report.reason() == "'source-patches' property changed"
+
+    def test_dirty_plugin_pull_assets(self, mocker):
+        info = ProjectInfo()
+        p1 = Part("p1", {"plugin": "nil"})
+        part_properties = p1.spec.marshal()
+        mock_assets = mocker.patch(
+            "craft_parts.plugins.nil_plugin.NilPlugin.get_pull_assets",
+            return_value={"vendor": "sha256/1234"},
+        )
+
+        # p1 pull already ran
+        s1 = states.PullState(
+            part_properties=part_properties, assets={"vendor": "sha256/1234"}
+        )
+        s1.write(Path("parts/p1/state/pull"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+        assert sm.check_if_dirty(p1, Step.PULL) is None
+
+        # the vendored dependencies changed
+        mock_assets.return_value = {"vendor": "sha256/5678"}
+
+        report = sm.check_if_dirty(p1, Step.PULL)
+        assert report is not None
+        assert report.reason() == "'vendor' property changed"
+
+    def test_dirty_plugin_pull_commands(self, mocker):
+        info = ProjectInfo()
+        p1 = Part("p1", {"plugin": "nil"})
+        part_properties = p1.spec.marshal()
+        mock_commands = mocker.patch(
+            "craft_parts.plugins.nil_plugin.NilPlugin.get_pull_commands",
+            return_value=["go mod download"],
+        )
+
+        # p1 pull already ran
+        s1 = states.PullState(
+            part_properties=part_properties,
+            assets={"pull-commands": ["go mod download"]},
+        )
+        s1.write(Path("parts/p1/state/pull"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+        assert sm.check_if_dirty(p1, Step.PULL) is None
+
+        # the plugin pull commands changed
+        mock_commands.return_value = ["go mod download", "go mod vendor"]
+
+        report = sm.check_if_dirty(p1, Step.PULL)
+        assert report is not None
+        assert report.reason() == "'pull-commands' property changed"
+
+    @pytest.mark.parametrize("track_channel", [True, False])
+    def test_dirty_stage_snaps(self, mocker, track_channel):
+        info = ProjectInfo()
+        p1 = Part(
+            "p1",
+            {"stage-snaps": ["snap1"], "stage-snaps-track-channel": track_channel},
+        )
+        part_properties = p1.spec.marshal()
+        revisions = {"snap1": {"revision": "10", "snap-id": "snap1-id"}}
+        mock_revisions = mocker.patch(
+            "craft_parts.packages.snaps.get_snap_revisions", return_value=revisions
+        )
+
+        # p1 pull already ran
+        s1 = states.PullState(
+            part_properties=part_properties, assets={"stage-snaps": revisions}
+        )
+        s1.write(Path("parts/p1/state/pull"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+        assert sm.check_if_dirty(p1, Step.PULL) is None
+
+        # the channel moved to a new revision
+        mock_revisions.return_value = {
+            "snap1": {"revision": "11", "snap-id": "snap1-id"}
+        }
+
+        report = sm.check_if_dirty(p1, Step.PULL)
+        if track_channel:
+            assert report is not None
+            assert report.reason() == "'stage-snaps' property changed"
+        else:
+            assert report is None
+            mock_revisions.assert_not_called()
+
+    def test_dirty_project_option(self):
+        info = ProjectInfo()
+        p1 = Part("p1", {})
+        part_properties = p1.spec.marshal()
+
+        # p1 build already ran for a different target architecture
+        s1 = states.BuildState(
+            part_properties=part_properties,
+            project_options={**info.project_options, "target_arch": "other"},
+        )
+        s1.write(Path("parts/p1/state/build"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+
+        report = sm.check_if_dirty(p1, Step.BUILD)
+        assert report is not None
+        assert report.reason() == "'target_arch' option changed"
+
+    def test_dirty_project_vars(self):
+        info = ProjectInfo(project_vars={"version": "1.1"})
+        p1 = Part("p1", {})
+        part_properties = p1.spec.marshal()
+
+        # p1 build already ran for a different project version
+        s1 = states.BuildState(
+            part_properties=part_properties,
+            project_options={
+                **info.project_options,
+                "project_vars": {"version": "1.0"},
+            },
+        )
+        s1.write(Path("parts/p1/state/build"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+
+        report = sm.check_if_dirty(p1, Step.BUILD)
+        assert report is not None
+        assert report.reason() == "'project_vars' option changed"
+
+    def test_dirty_plugin_property(self):
+        info = ProjectInfo()
+        plugin_properties = GoPluginProperties.unmarshal({"go-arch": "arm64"})
+        p1 = Part("p1", {"plugin": "go"}, plugin_properties=plugin_properties)
+        part_properties = {**p1.spec.marshal(), **plugin_properties.marshal()}
+
+        # p1 pull and build already ran
+        s1 = states.PullState(part_properties=part_properties)
+        s1.write(Path("parts/p1/state/pull"))
+        s2 = states.BuildState(
+            part_properties=part_properties, project_options=info.project_options
+        )
+        s2.write(Path("parts/p1/state/build"))
+
+        sm = StateManager(project_info=info, part_list=[p1])
+
+        # check if dirty, all steps are clean
+        for step in list(Step):
+            assert sm.check_if_dirty(p1, step) is None
+
+        # change the target architecture
+        stw = sm._state_db.get(part_name="p1", step=Step.BUILD)
+        stw.state.part_properties["go-arch"] = "riscv64"
+
+        # now check again if dirty
+        for step in list(Step):
+            report = sm.check_if_dirty(p1, step)
+            if step == Step.BUILD:
+                assert report is not None
+                assert report.reason() == "'go-arch' property changed"
+            else:
+                assert report is None
+
     def test_dirty_dependency(self):
         info = ProjectInfo()
         p1 = Part("p1", {"after": ["p2"]})
@@ -464,7 +684,9 @@ class TestStepDirty:
         # p2 pull/build/stage already ran
         s2 = states.PullState(part_properties=p2_properties)
         s2.write(Path("parts/p2/state/pull"))
-        s2 = states.BuildState(part_properties=p2_properties)
+        s2 = states.BuildState(
+            part_properties=p2_properties, project_options=info.project_options
+        )
         s2.write(Path("parts/p2/state/build"))
         s2 = states.StageState(part_properties=p2_properties)
         s2.write(Path("parts/p2/state/stage"))
@@ -472,7 +694,9 @@ class TestStepDirty:
         # p1 pull/build already ran
         s1 = states.PullState(part_properties=p1_properties)
         s1.write(Path("parts/p1/state/pull"))
-        s1 = states.BuildState(part_properties=p1_properties)
+        s1 = states.BuildState(
+            part_properties=p1_properties, project_options=info.project_options
+        )
         s1.write(Path("parts/p1/state/build"))
 
         sm = StateManager(project_info=info, part_list=[p1, p2])
@@ -504,6 +728,43 @@ class TestStepDirty:
             else:
                 assert report is None
 
+    def test_dirty_dependency_pull(self):
+        info = ProjectInfo()
+        p1 = Part("p1", {"after": ["p2:pull"]})
+        p1_properties = p1.spec.marshal()
+        p2 = Part("p2", {})
+        p2_properties = p2.spec.marshal()
+
+        # p2 pull already ran
+        s2 = states.PullState(part_properties=p2_properties)
+        s2.write(Path("parts/p2/state/pull"))
+
+        # p1 pull/build already ran
+        s1 = states.PullState(part_properties=p1_properties)
+        s1.write(Path("parts/p1/state/pull"))
+        s1 = states.BuildState(
+            part_properties=p1_properties, project_options=info.project_options
+        )
+        s1.write(Path("parts/p1/state/build"))
+
+        sm = StateManager(project_info=info, part_list=[p1, p2])
+
+        # p2 doesn't need to be staged
+        for step in list(Step):
+            assert sm.check_if_dirty(p1, step) is None
+
+        # make p2 pull step dirty
+        stw = sm._state_db.get(part_name="p2", step=Step.PULL)
+        stw.state.part_properties["source"] = "new_source"
+
+        for step in list(Step):
+            report = sm.check_if_dirty(p1, step)
+            if step == Step.BUILD:
+                assert report is not None
+                assert report.reason() == "'p2' changed"
+            else:
+                assert report is None
+
     def test_dirty_dependency_didnt_run(self):
         info = ProjectInfo()
         p1 = Part("p1", {"after": ["p2"]})
@@ -513,7 +774,9 @@ class TestStepDirty:
         # p1 pull/build already ran
         s1 = states.PullState(part_properties=p1_properties)
         s1.write(Path("parts/p1/state/pull"))
-        s1 = states.BuildState(part_properties=p1_properties)
+        s1 = states.BuildState(
+            part_properties=p1_properties, project_options=info.project_options
+        )
         s1.write(Path("parts/p1/state/build"))
 
         sm = StateManager(project_info=info, part_list=[p1, p2])
@@ -528,6 +791,43 @@ class TestStepDirty:
                 assert report is None
 
 
+    def test_dirty_lower_layer(self):
+        info = ProjectInfo(features={"overlay": True})
+        p1 = Part("p1", {})
+        p1_properties = p1.spec.marshal()
+        p2 = Part("p2", {"overlay-packages": ["hello"]})
+        p2_properties = p2.spec.marshal()
+
+        # p1 and p2 pull/overlay already ran
+        for name, properties in [("p1", p1_properties), ("p2", p2_properties)]:
+            state = states.PullState(part_properties=properties)
+            state.write(Path(f"parts/{name}/state/pull"))
+            state = states.OverlayState(
+                part_properties=properties, project_options=info.project_options
+            )
+            state.write(Path(f"parts/{name}/state/overlay"))
+
+        sm = StateManager(project_info=info, part_list=[p1, p2])
+
+        assert sm.check_if_dirty(p1, Step.OVERLAY) is None
+        assert sm.check_if_dirty(p2, Step.OVERLAY) is None
+
+        # make the p1 layer dirty
+        stw = sm._state_db.get(part_name="p1", step=Step.OVERLAY)
+        stw.state.part_properties["overlay-script"] = "true"
+
+        # the p2 layer is on top of it
+        report = sm.check_if_dirty(p2, Step.OVERLAY)
+        assert report is not None
+        assert report.reason() == "'p1' changed"
+
+        # but changes in the p2 layer don't affect the layers below it
+        stw.state.part_properties["overlay-script"] = None
+        stw = sm._state_db.get(part_name="p2", step=Step.OVERLAY)
+        stw.state.part_properties["overlay-script"] = "true"
+        assert sm.check_if_dirty(p1, Step.OVERLAY) is None
+
+
 @pytest.mark.usefixtures("new_dir")
 class TestHelpers:
     """Verify State Manager helper functions."""
@@ -19,7 +19,9 @@ from pathlib import Path
 import pytest
 import yaml
 
-from craft_parts.parts import Part
+from craft_parts import errors
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part, PartSpec
 from craft_parts.state_manager import states
 from craft_parts.steps import Step
 
@@ -35,11 +37,13 @@ class TestStates:
 
     def test_load_pull_state(self):
         state_data = {
+            "schema-version": 2,
             "assets": {"stage-packages": ["foo"]},
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
         state_file = Path("parts/foo/state/pull")
         state_file.parent.mkdir(parents=True, exist_ok=True)
@@ -52,11 +56,13 @@ class TestStates:
 
     def test_load_build_state(self):
         state_data = {
+            "schema-version": 2,
             "assets": {"build-packages": ["foo"]},
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
         state_file = Path("parts/foo/state/build")
         state_file.parent.mkdir(parents=True, exist_ok=True)
@@ -69,10 +75,12 @@ class TestStates:
 
     def test_load_stage_state(self):
         state_data = {
+            "schema-version": 2,
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
         state_file = Path("parts/foo/state/stage")
         state_file.parent.mkdir(parents=True, exist_ok=True)
@@ -85,12 +93,16 @@ class TestStates:
 
     def test_load_prime_state(self):
         state_data = {
+            "schema-version": 2,
             "part-properties": {"plugin": "nil"},
             "project-options": {"target_arch": "amd64"},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
             "dependency-paths": {"c"},
             "primed-stage-packages": {"d"},
+            "permissions": {"e": {"mode": "755"}},
+            "elf-patches": {"f": {"rpath": "$ORIGIN", "digest": "1234"}},
         }
         state_file = Path("parts/foo/state/prime")
         state_file.parent.mkdir(parents=True, exist_ok=True)
@@ -100,3 +112,53 @@ class TestStates:
 
         assert isinstance(state, states.PrimeState)
         assert state.marshal() == state_data
+
+    def test_load_unversioned_state(self):
+        state_data = {
+            "part-properties": {"plugin": "nil", "source": "foo"},
+            "files": ["a"],
+        }
+        state_file = Path("parts/foo/state/pull")
+        state_file.parent.mkdir(parents=True, exist_ok=True)
+        state_file.write_text(yaml.dump(state_data))
+
+        state = states.load_state(Part("foo", {}), Step.PULL)
+
+        assert isinstance(state, states.PullState)
+        assert state.schema_version == 2
+        assert state.files == {"a"}
+        assert state.part_properties == {
+            **PartSpec.unmarshal({}).marshal(),
+            "plugin": "nil",
+            "source": "foo",
+        }
+
+        # properties added after the state was written don't make it dirty
+        properties = PartSpec.unmarshal({"plugin": "nil", "source": "foo"}).marshal()
+        assert state.diff_properties_of_interest(properties) == set()
+
+    def test_load_newer_state(self):
+        state_file = Path("parts/foo/state/stage")
+        state_file.parent.mkdir(parents=True, exist_ok=True)
+        state_file.write_text(yaml.dump({"schema-version": 99}))
+
+        with pytest.raises(errors.StateVersionError) as raised:
+            states.load_state(Part("foo", {}), Step.STAGE)
+        assert raised.value.part_name == "foo"
+        assert raised.value.step_name == "stage"
+        assert raised.value.version == 99
+
+    def test_load_relocated_state(self, new_dir):
+        state_data = {
+            "schema-version": 2,
+            "part-properties": {"override-build": "ls ${CRAFT_WORK_DIR}/stage"},
+        }
+        state_file = Path("new/parts/foo/state/build")
+        state_file.parent.mkdir(parents=True, exist_ok=True)
+        state_file.write_text(yaml.dump(state_data))
+
+        part = Part("foo", {}, project_dirs=ProjectDirs(work_dir="new"))
+        state = states.load_state(part, Step.BUILD)
+
+        assert state is not None
+        assert state.part_properties == {"override-build": f"ls {new_dir}/new/stage"}
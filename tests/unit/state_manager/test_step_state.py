@@ -15,7 +15,7 @@
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 from pathlib import Path
-from typing import Any, Dict
+from typing import Any, Dict, List, Optional
 
 import pytest
 import yaml
@@ -26,8 +26,16 @@ from craft_parts.state_manager import step_state
 class SomeStepState(step_state.StepState):
     """A concrete step state implementing abstract methods."""
 
-    def properties_of_interest(self, part_properties: Dict[str, Any]) -> Dict[str, Any]:
-        return {"name": part_properties.get("name")}
+    def properties_of_interest(
+        self,
+        part_properties: Dict[str, Any],
+        *,
+        extra_properties: Optional[List[str]] = None,
+    ) -> Dict[str, Any]:
+        properties = {"name": part_properties.get("name")}
+        for name in extra_properties or []:
+            properties[name] = part_properties.get(name)
+        return properties
 
     def project_options_of_interest(
         self, project_options: Dict[str, Any]
@@ -41,10 +49,12 @@ class TestStepState:
     def test_marshal_empty(self):
         state = SomeStepState()
         assert state.marshal() == {
+            "schema-version": 2,
             "part-properties": {},
             "project-options": {},
             "files": set(),
             "directories": set(),
+            "values": {},
         }
 
     def test_marshal_data(self):
@@ -57,21 +67,26 @@ class TestStepState:
             },
             files={"a"},
             directories={"b"},
+            values={"version": "1.0"},
         )
         assert state.marshal() == {
+            "schema-version": 2,
             "part-properties": {"name": "foo"},
             "project-options": {"number": 42},
             "files": {"a"},
             "directories": {"b"},
+            "values": {"version": "1.0"},
         }
 
     def test_ignore_additional_data(self):
         state = SomeStepState(extra="something")
         assert state.marshal() == {
+            "schema-version": 2,
             "part-properties": {},
             "project-options": {},
             "files": set(),
             "directories": set(),
+            "values": {},
         }
 
 
@@ -98,6 +113,49 @@ class TestStepStatePersist:
         new_state = yaml.safe_load(content)
         assert new_state == state.marshal()
 
+    def test_write_relative_to_work_dir(self, new_dir):
+        state = SomeStepState(
+            part_properties={"source": f"{new_dir}/src", "other": f"{new_dir}x"},
+            files={f"{new_dir}/file"},
+        )
+
+        state.write(Path("state"), work_dir=new_dir)
+        new_state = yaml.safe_load(Path("state").read_text())
+
+        assert new_state["part-properties"] == {
+            "source": "${CRAFT_WORK_DIR}/src",
+            "other": f"{new_dir}x",
+        }
+
+
+class TestRelocatePaths:
+    """Verify the replacement of path prefixes in state data."""
+
+    @pytest.mark.parametrize(
+        "data,result",
+        [
+            ("/work", "/new"),
+            ("/work/a", "/new/a"),
+            ("cd /work/a && ls /work", "cd /new/a && ls /new"),
+            ("/workspace", "/workspace"),
+            ("/work.d", "/work.d"),
+            ("/other/work", "/other/work"),
+            (42, 42),
+            (None, None),
+            (["/work/a", "b"], ["/new/a", "b"]),
+            ({"/work/a", "b"}, {"/new/a", "b"}),
+            ({"/work/a": {"x": ["/work"]}}, {"/new/a": {"x": ["/new"]}}),
+        ],
+    )
+    def test_relocate_paths(self, data, result):
+        assert step_state.relocate_paths(data, old="/work", new="/new") == result
+
+    def test_relocate_paths_escaped(self):
+        data = step_state.relocate_paths(
+            "${CRAFT_WORK_DIR}/a", old="${CRAFT_WORK_DIR}", new="/x\\1"
+        )
+        assert data == "/x\\1/a"
+
 
 class TestStateChanges:
     """Verify state comparison methods."""
@@ -118,6 +176,14 @@ class TestStateChanges:
         # relevant properties changed
         assert state.diff_properties_of_interest({"name": "bob"}) == {"name"}
 
+    def test_extra_property_changes(self):
+        state = SomeStepState(part_properties={"name": "alice", "pet": "cat"})
+
+        # extra properties are also compared
+        assert state.diff_properties_of_interest(
+            {"name": "alice", "pet": "eel"}, ["pet"]
+        ) == {"pet"}
+
     def test_project_options_changes(self):
         state = SomeStepState(
             project_options={
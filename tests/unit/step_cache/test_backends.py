@@ -0,0 +1,169 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import subprocess
+from pathlib import Path
+
+import pytest
+
+from craft_parts.step_cache import (
+    HttpCacheBackend,
+    LocalCacheBackend,
+    ObjectStorageCacheBackend,
+)
+from craft_parts.step_cache import errors
+
+_KEY = "sha256/1234"
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestLocalCacheBackend:
+    """Verify the local file cache backend."""
+
+    def test_put_get(self):
+        Path("data").write_text("content")
+        backend = LocalCacheBackend("test", cache_dir="cache")
+
+        backend.put(key=_KEY, filename=Path("data"))
+
+        assert Path("cache/steps/sha256/1234").read_text() == "content"
+        assert backend.get(key=_KEY, destination=Path("restored")) is True
+        assert Path("restored").read_text() == "content"
+
+    def test_get_missing(self):
+        backend = LocalCacheBackend("test", cache_dir="cache")
+
+        assert backend.get(key=_KEY, destination=Path("restored")) is False
+        assert Path("restored").exists() is False
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestHttpCacheBackend:
+    """Verify the HTTP cache backend."""
+
+    def test_get(self, requests_mock):
+        requests_mock.get("https://cache/steps/sha256/1234", text="content")
+        backend = HttpCacheBackend("https://cache/steps/")
+
+        assert backend.get(key=_KEY, destination=Path("restored")) is True
+        assert Path("restored").read_text() == "content"
+
+    def test_get_headers(self, requests_mock):
+        requests_mock.get("https://cache/sha256/1234", text="content")
+        backend = HttpCacheBackend("https://cache", headers={"Authorization": "t"})
+
+        backend.get(key=_KEY, destination=Path("restored"))
+
+        assert requests_mock.last_request.headers["Authorization"] == "t"
+
+    def test_get_missing(self, requests_mock):
+        requests_mock.get("https://cache/sha256/1234", status_code=404)
+        backend = HttpCacheBackend("https://cache")
+
+        assert backend.get(key=_KEY, destination=Path("restored")) is False
+        assert Path("restored").exists() is False
+
+    def test_get_error(self, requests_mock):
+        requests_mock.get("https://cache/sha256/1234", status_code=500)
+        backend = HttpCacheBackend("https://cache")
+
+        with pytest.raises(errors.StepCacheBackendError) as raised:
+            backend.get(key=_KEY, destination=Path("restored"))
+        assert raised.value.location == "https://cache"
+
+    def test_put(self, requests_mock):
+        Path("data").write_text("content")
+        requests_mock.put("https://cache/sha256/1234")
+        backend = HttpCacheBackend("https://cache")
+
+        backend.put(key=_KEY, filename=Path("data"))
+
+        assert requests_mock.call_count == 1
+        assert requests_mock.last_request.method == "PUT"
+
+    def test_put_error(self, requests_mock):
+        Path("data").write_text("content")
+        requests_mock.put("https://cache/sha256/1234", status_code=403)
+        backend = HttpCacheBackend("https://cache")
+
+        with pytest.raises(errors.StepCacheBackendError):
+            backend.put(key=_KEY, filename=Path("data"))
+
+
+class TestObjectStorageCacheBackend:
+    """Verify the object storage cache backend."""
+
+    def test_invalid_url(self):
+        with pytest.raises(ValueError):
+            ObjectStorageCacheBackend("https://cache")
+
+    def test_get(self, mocker):
+        mock_run = mocker.patch("subprocess.run")
+        backend = ObjectStorageCacheBackend("s3://bucket/steps", region="eu-west-1")
+
+        assert backend.get(key=_KEY, destination=Path("restored")) is True
+        mock_run.assert_called_once_with(
+            [
+                "aws",
+                "s3",
+                "cp",
+                "--only-show-errors",
+                "--region",
+                "eu-west-1",
+                "s3://bucket/steps/sha256/1234",
+                "restored",
+            ],
+            check=True,
+            stdout=subprocess.DEVNULL,
+        )
+
+    def test_get_missing(self, mocker):
+        mocker.patch(
+            "subprocess.run", side_effect=subprocess.CalledProcessError(1, ["aws"])
+        )
+        backend = ObjectStorageCacheBackend("s3://bucket/steps")
+
+        assert backend.get(key=_KEY, destination=Path("restored")) is False
+
+    def test_put(self, mocker):
+        mock_run = mocker.patch("subprocess.run")
+        backend = ObjectStorageCacheBackend("gs://bucket/steps", profile="ci")
+
+        backend.put(key=_KEY, filename=Path("data"))
+        mock_run.assert_called_once_with(
+            [
+                "gcloud",
+                "storage",
+                "cp",
+                "--configuration",
+                "ci",
+                "data",
+                "gs://bucket/steps/sha256/1234",
+            ],
+            check=True,
+            stdout=subprocess.DEVNULL,
+        )
+
+    def test_put_error(self, mocker):
+        mocker.patch(
+            "subprocess.run", side_effect=subprocess.CalledProcessError(1, ["gcloud"])
+        )
+        backend = ObjectStorageCacheBackend("gs://bucket/steps")
+
+        with pytest.raises(errors.StepCacheBackendError) as raised:
+            backend.put(key=_KEY, filename=Path("data"))
+        assert raised.value.message == "command exited with code 1"
@@ -0,0 +1,41 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from craft_parts.step_cache import errors
+
+
+def test_step_cache_backend_error():
+    err = errors.StepCacheBackendError(
+        "https://cache.example.com", message="something is wrong"
+    )
+    assert err.location == "https://cache.example.com"
+    assert err.message == "something is wrong"
+    assert err.brief == (
+        "Failed to access step cache at https://cache.example.com: "
+        "something is wrong."
+    )
+    assert err.details is None
+    assert err.resolution is None
+
+
+def test_invalid_step_archive():
+    err = errors.InvalidStepArchive("sha256/1234", message="something is wrong")
+    assert err.key == "sha256/1234"
+    assert err.message == "something is wrong"
+    assert err.brief == "Invalid cached step output sha256/1234: something is wrong."
+    assert err.details is None
+    assert err.resolution is None
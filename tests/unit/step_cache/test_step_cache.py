@@ -0,0 +1,243 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import io
+import os
+import tarfile
+from pathlib import Path
+
+import pytest
+
+from craft_parts.step_cache import (
+    LocalCacheBackend,
+    StepCache,
+    get_directory_digest,
+    get_step_fingerprint,
+)
+from craft_parts.step_cache import errors
+
+_FINGERPRINT_ARGS = {
+    "step_name": "BUILD",
+    "source_digest": "sha256/1234",
+    "part_properties": {"plugin": "nil"},
+    "project_options": {"target_arch": "amd64"},
+    "environment": "export FOO=bar",
+    "package_versions": ["gcc=10", "make=4"],
+}
+
+
+def test_get_step_fingerprint():
+    key = get_step_fingerprint(**_FINGERPRINT_ARGS)
+
+    assert key.startswith("sha256/")
+    assert key == get_step_fingerprint(
+        **{**_FINGERPRINT_ARGS, "package_versions": ["make=4", "gcc=10"]}
+    )
+
+
+@pytest.mark.parametrize(
+    "name,value",
+    [
+        ("step_name", "PULL"),
+        ("source_digest", "sha256/5678"),
+        ("part_properties", {"plugin": "make"}),
+        ("project_options", {"target_arch": "arm64"}),
+        ("environment", "export FOO=baz"),
+        ("package_versions", ["gcc=11", "make=4"]),
+    ],
+)
+def test_get_step_fingerprint_changes(name, value):
+    key = get_step_fingerprint(**_FINGERPRINT_ARGS)
+
+    assert key != get_step_fingerprint(**{**_FINGERPRINT_ARGS, name: value})
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestDirectoryDigest:
+    """Verify directory tree digests."""
+
+    def setup_method(self):
+        Path("dir/sub").mkdir(parents=True)
+        Path("dir/file").write_text("content")
+        Path("dir/sub/file").write_text("other content")
+        Path("dir/link").symlink_to("file")
+
+    def test_same_contents(self):
+        digest = get_directory_digest(Path("dir"))
+
+        Path("dir/file").touch()
+        assert get_directory_digest(Path("dir")) == digest
+
+    def test_file_contents(self):
+        digest = get_directory_digest(Path("dir"))
+
+        Path("dir/sub/file").write_text("changed")
+        assert get_directory_digest(Path("dir")) != digest
+
+    def test_file_mode(self):
+        digest = get_directory_digest(Path("dir"))
+
+        Path("dir/file").chmod(0o755)
+        assert get_directory_digest(Path("dir")) != digest
+
+    def test_symlink_target(self):
+        digest = get_directory_digest(Path("dir"))
+
+        Path("dir/link").unlink()
+        Path("dir/link").symlink_to("sub/file")
+        assert get_directory_digest(Path("dir")) != digest
+
+    def test_file_name(self):
+        digest = get_directory_digest(Path("dir"))
+
+        Path("dir/sub/file").rename("dir/sub/renamed")
+        assert get_directory_digest(Path("dir")) != digest
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestStepCache:
+    """Verify saving and restoring step outputs."""
+
+    def setup_method(self):
+        # pylint: disable=attribute-defined-outside-init
+        Path("part/build/sub").mkdir(parents=True)
+        Path("part/build/sub/file").write_text("built")
+        Path("part/install").mkdir()
+        Path("part/install/bin").write_text("installed")
+        Path("part/install/link").symlink_to("/usr/bin/bin")
+        self._cache = StepCache(LocalCacheBackend("test", cache_dir="cache"))
+        # pylint: enable=attribute-defined-outside-init
+
+    def test_save_restore(self):
+        assert self._cache.save(
+            key="sha256/1234",
+            root=Path("part"),
+            dirs=["build", "install"],
+            assets={"foo": "bar"},
+        )
+
+        Path("part/build/sub/file").write_text("changed")
+        Path("part/install/bin").unlink()
+        Path("part/install/extra").write_text("extra")
+
+        assets = self._cache.restore(
+            key="sha256/1234", root=Path("part"), dirs=["build", "install"]
+        )
+
+        assert assets == {"foo": "bar"}
+        assert Path("part/build/sub/file").read_text() == "built"
+        assert Path("part/install/bin").read_text() == "installed"
+        assert os.readlink("part/install/link") == "/usr/bin/bin"
+        assert Path("part/install/extra").exists() is False
+
+    def test_restore_missing_dirs(self):
+        self._cache.save(
+            key="sha256/1234", root=Path("part"), dirs=["build"], assets={}
+        )
+        Path("other").mkdir()
+
+        assets = self._cache.restore(
+            key="sha256/1234", root=Path("other"), dirs=["build"]
+        )
+
+        assert assets == {}
+        assert Path("other/build/sub/file").read_text() == "built"
+
+    def test_restore_miss(self):
+        assets = self._cache.restore(
+            key="sha256/1234", root=Path("part"), dirs=["build"]
+        )
+
+        assert assets is None
+        assert Path("part/build/sub/file").read_text() == "built"
+
+    @pytest.mark.parametrize(
+        "entries",
+        [
+            [("dir", "dirs/build"), ("file", "/etc/passwd")],
+            [("dir", "dirs/build"), ("file", "dirs/build/../../escaped")],
+            [("dir", "dirs/build"), ("file", "other")],
+            [("symlink", "dirs/build/sub", "/tmp"), ("file", "dirs/build/sub/file")],
+            [("dir", "dirs/build"), ("hardlink", "dirs/build/file", "/etc/passwd")],
+            [("dir", "dirs/build"), ("fifo", "dirs/build/fifo")],
+            [("file", "dirs/build/file")],
+            [],
+        ],
+    )
+    def test_restore_invalid_archive(self, entries):
+        archive = Path("step.tar.gz")
+        with tarfile.open(archive, "w:gz") as tar:
+            for kind, name, *target in [("file", "assets.json")] + entries:
+                info = tarfile.TarInfo(name)
+                data = b"{}"
+                if kind == "dir":
+                    info.type = tarfile.DIRTYPE
+                elif kind == "symlink":
+                    info.type = tarfile.SYMTYPE
+                    info.linkname = target[0]
+                elif kind == "hardlink":
+                    info.type = tarfile.LNKTYPE
+                    info.linkname = target[0]
+                elif kind == "fifo":
+                    info.type = tarfile.FIFOTYPE
+                else:
+                    info.size = len(data)
+                tar.addfile(info, io.BytesIO(data) if info.isfile() else None)
+        self._cache.backend.put(key="sha256/1234", filename=archive)
+
+        assets = self._cache.restore(
+            key="sha256/1234", root=Path("part"), dirs=["build"]
+        )
+
+        assert assets is None
+        assert Path("part/build/sub/file").read_text() == "built"
+        assert Path("/tmp/file").exists() is False
+        assert Path("../escaped").exists() is False
+
+    def test_restore_truncated_archive(self):
+        self._cache.save(
+            key="sha256/1234", root=Path("part"), dirs=["build"], assets={}
+        )
+        archive = Path("step.tar.gz")
+        self._cache.backend.get(key="sha256/1234", destination=archive)
+        archive.write_bytes(archive.read_bytes()[:100])
+        self._cache.backend.put(key="sha256/5678", filename=archive)
+        Path("part/build/sub/file").write_text("changed")
+
+        assets = self._cache.restore(
+            key="sha256/5678", root=Path("part"), dirs=["build"]
+        )
+
+        assert assets is None
+        assert Path("part/build/sub/file").read_text() == "changed"
+
+    def test_backend_errors(self, mocker):
+        backend = self._cache.backend
+        error = errors.StepCacheBackendError("cache", message="offline")
+        mocker.patch.object(backend, "put", side_effect=error)
+        mocker.patch.object(backend, "get", side_effect=error)
+
+        assert (
+            self._cache.save(
+                key="sha256/1234", root=Path("part"), dirs=["build"], assets={}
+            )
+            is False
+        )
+        assert (
+            self._cache.restore(key="sha256/1234", root=Path("part"), dirs=["build"])
+            is None
+        )
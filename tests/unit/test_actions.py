@@ -60,3 +60,48 @@ def test_action_comparison():
     assert action != a3
     assert action != a4
     assert action != a5
+
+
+def test_action_dirty_properties():
+    action = Action(
+        "foo",
+        Step.PULL,
+        action_type=ActionType.RERUN,
+        reason="properties changed",
+        dirty_properties=["source", "plugin"],
+        dirty_project_options=["target_arch"],
+    )
+    assert action.dirty_properties == ["source", "plugin"]
+    assert action.dirty_project_options == ["target_arch"]
+    assert action == Action(
+        "foo", Step.PULL, action_type=ActionType.RERUN, reason="properties changed"
+    )
+
+
+def test_action_marshal():
+    action = Action("foo", Step.PULL, action_type=ActionType.SKIP, reason="is tired")
+    assert action.marshal() == {
+        "part": "foo",
+        "step": "pull",
+        "type": "skip",
+        "reason": "is tired",
+    }
+
+
+def test_action_marshal_dirty():
+    action = Action(
+        "foo",
+        Step.BUILD,
+        action_type=ActionType.RERUN,
+        reason="properties changed",
+        dirty_properties=["source", "plugin"],
+        dirty_project_options=["target_arch"],
+    )
+    assert action.marshal() == {
+        "part": "foo",
+        "step": "build",
+        "type": "rerun",
+        "reason": "properties changed",
+        "dirty-properties": ["plugin", "source"],
+        "dirty-project-options": ["target_arch"],
+    }
@@ -0,0 +1,95 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts import bases, errors
+from craft_parts.bases import Base
+from craft_parts.utils.os_utils import OsRelease
+
+
+@pytest.fixture(autouse=True)
+def host_base(mocker):
+    return mocker.patch(
+        "craft_parts.bases.get_host_base",
+        return_value=Base("linuxmint", "21", like=("ubuntu", "debian")),
+    )
+
+
+@pytest.mark.parametrize(
+    "name,base",
+    [
+        ("ubuntu@22.04", Base("ubuntu", "22.04")),
+        ("fedora@39", Base("fedora", "39")),
+        ("alpine", Base("alpine")),
+        ("core", Base("ubuntu", "16.04")),
+        ("core22", Base("ubuntu", "22.04")),
+        ("core24", Base("ubuntu", "24.04")),
+        ("linuxmint@21", Base("linuxmint", "21", like=("ubuntu", "debian"))),
+    ],
+)
+def test_parse_base(name, base):
+    assert bases.parse_base(name) == base
+
+
+@pytest.mark.parametrize("name", ["", "ubuntu@", "Ubuntu@22.04", "a@b@c", "a b"])
+def test_parse_base_invalid(name):
+    with pytest.raises(errors.InvalidBase) as raised:
+        bases.parse_base(name)
+    assert raised.value.base == name
+
+
+def test_base_name():
+    base = Base("ubuntu", "22.04", like=("debian",))
+    assert base.name == "ubuntu@22.04"
+    assert base.distribution_ids == ("ubuntu", "debian")
+    assert Base("arch").name == "arch"
+
+
+class TestHostBase:
+    """Host bases are detected from os-release."""
+
+    @pytest.fixture(autouse=True)
+    def host_base(self):
+        pass
+
+    @pytest.mark.parametrize(
+        "os_release,base",
+        [
+            ('ID=ubuntu\nVERSION_ID="22.04"\n', Base("ubuntu", "22.04")),
+            (
+                'ID=linuxmint\nVERSION_ID="21"\nID_LIKE="ubuntu debian"\n',
+                Base("linuxmint", "21", like=("ubuntu", "debian")),
+            ),
+            ("ID=arch\n", Base("arch")),
+            ("NAME=Unknown\n", None),
+        ],
+    )
+    def test_get_host_base(self, new_dir, os_release, base):
+        Path("os-release").write_text(os_release)
+
+        os_release_info = OsRelease(os_release_file="os-release")
+        assert bases.get_host_base(os_release_info) == base
+
+    def test_get_host_base_non_linux(self, mocker):
+        mocker.patch("sys.platform", "darwin")
+        mock_os_release = mocker.patch("craft_parts.bases.OsRelease")
+
+        assert bases.get_host_base() is None
+        mock_os_release.assert_not_called()
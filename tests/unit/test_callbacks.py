@@ -14,13 +14,15 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-from typing import List
+from typing import Dict, List, Optional
 
 import pytest
 
-from craft_parts import callbacks, errors
+from craft_parts import callbacks, errors, events
+from craft_parts.actions import Action
 from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
 from craft_parts.parts import Part
+from craft_parts.plugins import PluginEnvironmentValidator, PluginProperties
 from craft_parts.steps import Step
 
 
@@ -48,6 +50,50 @@ def _callback_4(info: ProjectInfo, part_list: List[Part]) -> None:
     print(f"{greet} callback 4 ({names})")
 
 
+def _callback_5(info: StepInfo, validator: PluginEnvironmentValidator) -> None:
+    greet = getattr(info, "greet")
+    print(f"{greet} callback 5 ({validator.part_name})")
+
+
+def _callback_6(info: StepInfo, validator: PluginEnvironmentValidator) -> None:
+    greet = getattr(info, "greet")
+    print(f"{greet} callback 6 ({validator.part_name})")
+
+
+def _callback_7(url: str) -> Dict[str, str]:
+    return {"Authorization": f"Bearer token-7 for {url}", "X-Seven": "7"}
+
+
+def _callback_8(url: str) -> Dict[str, str]:
+    return {"Authorization": "Bearer token-8", "X-Eight": "8"}
+
+
+def _callback_9(event: events.Event) -> None:
+    print(f"{event.name} callback 9 ({event.action.part_name})")
+
+
+def _callback_10(event: events.Event) -> None:
+    print(f"{event.name} callback 10 ({event.action.part_name})")
+
+
+def _callback_11(name: str) -> Optional[str]:
+    return "value-11" if name == "eleven" else None
+
+
+def _callback_12(name: str) -> Optional[str]:
+    return f"value-12-{name}"
+
+
+def _callback_13(info: StepInfo, error: Exception) -> None:
+    greet = getattr(info, "greet")
+    print(f"{greet} callback 13 ({error})")
+
+
+def _callback_14(info: StepInfo, error: Exception) -> None:
+    greet = getattr(info, "greet")
+    print(f"{greet} callback 14 ({error})")
+
+
 class TestCallbackRegistration:
     """Test different scenarios of callback function registration."""
 
@@ -80,6 +126,19 @@ class TestCallbackRegistration:
         # But we can register a different one
         callbacks.register_post_step(_callback_2)
 
+    def test_register_step_failure(self):
+        callbacks.register_step_failure(_callback_13)
+
+        # A callback function shouldn't be registered again
+        with pytest.raises(errors.CallbackRegistrationError) as raised:
+            callbacks.register_step_failure(_callback_13)
+        assert raised.value.message == (
+            "callback function '_callback_13' is already registered."
+        )
+
+        # But we can register a different one
+        callbacks.register_step_failure(_callback_14)
+
     def test_register_prologue(self):
         callbacks.register_prologue(_callback_3)
 
@@ -106,6 +165,58 @@ class TestCallbackRegistration:
         # But we can register a different one
         callbacks.register_epilogue(_callback_4)
 
+    def test_register_environment_validation(self):
+        callbacks.register_environment_validation(_callback_5)
+
+        # A callback function shouldn't be registered again
+        with pytest.raises(errors.CallbackRegistrationError) as raised:
+            callbacks.register_environment_validation(_callback_5)
+        assert raised.value.message == (
+            "callback function '_callback_5' is already registered."
+        )
+
+        # But we can register a different one
+        callbacks.register_environment_validation(_callback_6)
+
+    def test_register_credentials_provider(self):
+        callbacks.register_credentials_provider(_callback_7)
+
+        # A callback function shouldn't be registered again
+        with pytest.raises(errors.CallbackRegistrationError) as raised:
+            callbacks.register_credentials_provider(_callback_7)
+        assert raised.value.message == (
+            "callback function '_callback_7' is already registered."
+        )
+
+        # But we can register a different one
+        callbacks.register_credentials_provider(_callback_8)
+
+    def test_register_secret_provider(self):
+        callbacks.register_secret_provider(_callback_11)
+
+        # A callback function shouldn't be registered again
+        with pytest.raises(errors.CallbackRegistrationError) as raised:
+            callbacks.register_secret_provider(_callback_11)
+        assert raised.value.message == (
+            "callback function '_callback_11' is already registered."
+        )
+
+        # But we can register a different one
+        callbacks.register_secret_provider(_callback_12)
+
+    def test_register_event_handler(self):
+        callbacks.register_event_handler(_callback_9)
+
+        # A callback function shouldn't be registered again
+        with pytest.raises(errors.CallbackRegistrationError) as raised:
+            callbacks.register_event_handler(_callback_9)
+        assert raised.value.message == (
+            "callback function '_callback_9' is already registered."
+        )
+
+        # But we can register a different one
+        callbacks.register_event_handler(_callback_10)
+
     def test_register_both_pre_and_post(self):
         callbacks.register_pre_step(_callback_1)
         callbacks.register_post_step(_callback_1)
@@ -117,13 +228,21 @@ class TestCallbackRegistration:
     def test_clear(self):
         callbacks.register_pre_step(_callback_1)
         callbacks.register_post_step(_callback_1)
+        callbacks.register_step_failure(_callback_13)
         callbacks.register_prologue(_callback_3)
         callbacks.register_epilogue(_callback_3)
+        callbacks.register_environment_validation(_callback_5)
+        callbacks.register_credentials_provider(_callback_7)
+        callbacks.register_event_handler(_callback_9)
         callbacks.clear()
         callbacks.register_pre_step(_callback_1)
         callbacks.register_post_step(_callback_1)
+        callbacks.register_step_failure(_callback_13)
         callbacks.register_prologue(_callback_3)
         callbacks.register_epilogue(_callback_3)
+        callbacks.register_environment_validation(_callback_5)
+        callbacks.register_credentials_provider(_callback_7)
+        callbacks.register_event_handler(_callback_9)
 
     def test_register_steps(self):
         callbacks.register_pre_step(_callback_1, step_list=[Step.PULL, Step.BUILD])
@@ -169,6 +288,20 @@ class TestCallbackExecution:
         assert not err
         assert out == "hello callback 1\nhello callback 2\n"
 
+    def test_run_step_failure(self, capfd):
+        callbacks.register_step_failure(_callback_13)
+        callbacks.register_step_failure(_callback_14, step_list=[Step.PULL])
+        callbacks.run_step_failure(self._step_info, error=RuntimeError("oops"))
+        out, err = capfd.readouterr()
+        assert not err
+        assert out == "hello callback 13 (oops)\n"
+
+    def test_step_info_action(self):
+        action = Action("foo", Step.BUILD)
+        step_info = StepInfo(part_info=self._part_info, step=Step.BUILD, action=action)
+        assert step_info.action == action
+        assert self._step_info.action is None
+
     def test_run_prologue(self, capfd):
         part1 = Part("p1", {})
         part2 = Part("p2", {})
@@ -188,3 +321,52 @@ class TestCallbackExecution:
         out, err = capfd.readouterr()
         assert not err
         assert out == "hello callback 3 (p1 p2)\nhello callback 4 (p1 p2)\n"
+
+    def test_run_environment_validation(self, capfd):
+        validator = PluginEnvironmentValidator(
+            part_name="p1", env="", properties=PluginProperties()
+        )
+        callbacks.register_environment_validation(_callback_5)
+        callbacks.register_environment_validation(_callback_6)
+        callbacks.run_environment_validation(self._step_info, validator=validator)
+        out, err = capfd.readouterr()
+        assert not err
+        assert out == "hello callback 5 (p1)\nhello callback 6 (p1)\n"
+
+    def test_get_source_credentials(self):
+        callbacks.register_credentials_provider(_callback_7)
+        callbacks.register_credentials_provider(_callback_8)
+        headers = callbacks.get_source_credentials("http://test.com/file")
+        assert headers == {
+            "Authorization": "Bearer token-7 for http://test.com/file",
+            "X-Seven": "7",
+            "X-Eight": "8",
+        }
+
+    def test_get_source_credentials_no_providers(self):
+        assert callbacks.get_source_credentials("http://test.com/file") == {}
+
+    def test_get_secret(self):
+        callbacks.register_secret_provider(_callback_11)
+        callbacks.register_secret_provider(_callback_12)
+        assert callbacks.get_secret("eleven") == "value-11"
+        assert callbacks.get_secret("twelve") == "value-12-twelve"
+
+    def test_get_secret_from_environment(self, monkeypatch):
+        monkeypatch.setenv("TEST_SECRET", "value")
+        monkeypatch.delenv("TEST_UNDEFINED_SECRET", raising=False)
+        assert callbacks.get_secret("TEST_SECRET") == "value"
+        assert callbacks.get_secret("TEST_UNDEFINED_SECRET") is None
+
+    def test_run_event_handlers(self, capfd):
+        assert callbacks.has_event_handlers() is False
+        callbacks.register_event_handler(_callback_9)
+        callbacks.register_event_handler(_callback_10)
+        assert callbacks.has_event_handlers()
+
+        callbacks.run_event_handlers(events.ActionStarted(Action("p1", Step.PULL)))
+        out, err = capfd.readouterr()
+        assert not err
+        assert out == (
+            "action-started callback 9 (p1)\naction-started callback 10 (p1)\n"
+        )
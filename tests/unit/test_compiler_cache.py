@@ -0,0 +1,97 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+from xdg import BaseDirectory  # type: ignore
+
+from craft_parts import compiler_cache
+from craft_parts.compiler_cache import CompilerCacheConfig
+from craft_parts.infos import PartInfo, ProjectInfo
+from craft_parts.parts import Part
+
+
+def _part_info(config, **kwargs) -> PartInfo:
+    project_info = ProjectInfo(application_name="test", compiler_cache=config, **kwargs)
+    return PartInfo(project_info=project_info, part=Part("foo", {}))
+
+
+@pytest.fixture(autouse=True)
+def native_arch(mocker):
+    mocker.patch("platform.machine", return_value="x86_64")
+
+
+class TestCompilerCacheConfig:
+    """Verify the compiler cache configuration."""
+
+    def test_default_cache_dir(self):
+        config = CompilerCacheConfig()
+        assert config.get_cache_dir("test") == Path(
+            BaseDirectory.xdg_cache_home, "test", "craft-parts", "compiler-cache"
+        )
+        assert config.max_size == 5 * 1024 ** 3
+
+    def test_cache_dir(self):
+        config = CompilerCacheConfig(cache_dir=Path("/cache"))
+        assert config.get_cache_dir("test") == Path("/cache")
+
+
+class TestCompilerCacheEnvironment:
+    """Verify the environment used to configure compiler caches."""
+
+    def test_disabled(self):
+        part_info = _part_info(None)
+        assert compiler_cache.is_enabled(part_info) is False
+        assert compiler_cache.get_ccache_environment(part_info) == {}
+        assert compiler_cache.get_sccache_environment(part_info) == {}
+        assert compiler_cache.get_compiler_environment(part_info) == {}
+
+    def test_ccache_environment(self):
+        part_info = _part_info(
+            CompilerCacheConfig(cache_dir=Path("/cache"), max_size=2 * 1024 ** 2)
+        )
+        assert compiler_cache.is_enabled(part_info) is True
+        assert compiler_cache.get_ccache_environment(part_info) == {
+            "CCACHE_DIR": "/cache/ccache",
+            "CCACHE_MAXSIZE": "2048Ki",
+            "CCACHE_BASEDIR": str(part_info.parts_dir),
+        }
+
+    def test_sccache_environment(self):
+        part_info = _part_info(
+            CompilerCacheConfig(cache_dir=Path("/cache"), max_size=2 * 1024 ** 2)
+        )
+        assert compiler_cache.get_sccache_environment(part_info) == {
+            "RUSTC_WRAPPER": "sccache",
+            "SCCACHE_DIR": "/cache/sccache",
+            "SCCACHE_CACHE_SIZE": "2048K",
+        }
+
+    def test_compiler_environment(self):
+        part_info = _part_info(CompilerCacheConfig(cache_dir=Path("/cache")))
+        env = compiler_cache.get_compiler_environment(part_info)
+        assert env["CC"] == "ccache gcc"
+        assert env["CXX"] == "ccache g++"
+        assert env["CCACHE_DIR"] == "/cache/ccache"
+
+    def test_compiler_environment_cross_compiling(self):
+        part_info = _part_info(
+            CompilerCacheConfig(cache_dir=Path("/cache")), arch="aarch64"
+        )
+        env = compiler_cache.get_compiler_environment(part_info, cxx=None)
+        assert env["CC"] == "ccache aarch64-linux-gnu-gcc"
+        assert "CXX" not in env
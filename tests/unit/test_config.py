@@ -0,0 +1,165 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import textwrap
+from pathlib import Path
+
+import pytest
+
+from craft_parts import config, errors
+from craft_parts.config import PartsConfig
+from craft_parts.proxy import ProxyConfig
+from craft_parts.sources.mirrors import MirrorRule
+
+
+class TestPartsConfig:
+    """Verify the configuration model."""
+
+    def test_unmarshal(self):
+        data = {
+            "cache-dir": "/cache",
+            "cache-size-limit": 1000,
+            "parallel-build-count": 4,
+            "max-parallel-parts": 2,
+            "proxy": {
+                "http-proxy": "http://proxy:3128",
+                "no-proxy": ["localhost", ".internal"],
+            },
+            "source-mirrors": [
+                {"pattern": "https://github.com/", "mirror": "https://mirror/"},
+                {"pattern": "^http://(.*)", "mirror": "https://\\\\1", "regex": True},
+            ],
+            "features": {"foo": True},
+        }
+        parts_config = PartsConfig.unmarshal(data)
+
+        assert parts_config.marshal() == data
+        assert parts_config.get_proxy_config() == ProxyConfig(
+            http_proxy="http://proxy:3128", no_proxy=("localhost", ".internal")
+        )
+        assert parts_config.get_mirror_rules() == [
+            MirrorRule("https://github.com/", "https://mirror/"),
+            MirrorRule("^http://(.*)", "https://\\\\1", regex=True),
+        ]
+
+    def test_unmarshal_empty(self):
+        parts_config = PartsConfig.unmarshal({})
+        assert parts_config.marshal() == {}
+        assert parts_config.parallel_build_count is None
+        assert parts_config.get_proxy_config() is None
+        assert parts_config.get_mirror_rules() == []
+        assert parts_config.features == {}
+
+    def test_unmarshal_invalid(self):
+        with pytest.raises(TypeError) as raised:
+            PartsConfig.unmarshal([])  # type: ignore
+        assert str(raised.value) == "configuration data is not a dictionary"
+
+    def test_merge(self):
+        system = PartsConfig.unmarshal(
+            {
+                "cache-dir": "/cache",
+                "parallel-build-count": 4,
+                "source-mirrors": [{"pattern": "a", "mirror": "b"}],
+                "features": {"foo": True, "bar": True},
+            }
+        )
+        user = PartsConfig.unmarshal(
+            {
+                "parallel-build-count": 8,
+                "source-mirrors": [{"pattern": "c", "mirror": "d"}],
+                "features": {"bar": False},
+            }
+        )
+
+        merged = system.merge(user)
+        assert merged.cache_dir == "/cache"
+        assert merged.parallel_build_count == 8
+        assert merged.get_mirror_rules() == [MirrorRule("c", "d"), MirrorRule("a", "b")]
+        assert merged.features == {"foo": True, "bar": False}
+
+
+class TestConfigFiles:
+    """Verify reading configuration files."""
+
+    def test_get_config_files(self, mocker):
+        mocker.patch("xdg.BaseDirectory.xdg_config_home", "/home/user/.config")
+        assert config.get_config_files({}) == [
+            Path("/etc/craft-parts/config.yaml"),
+            Path("/home/user/.config/craft-parts/config.yaml"),
+        ]
+
+    def test_get_config_files_environment(self, mocker):
+        mocker.patch("xdg.BaseDirectory.xdg_config_home", "/home/user/.config")
+        env = {"CRAFT_PARTS_CONFIG": "/tmp/config.yaml"}
+        assert config.get_config_files(env) == [
+            Path("/etc/craft-parts/config.yaml"),
+            Path("/home/user/.config/craft-parts/config.yaml"),
+            Path("/tmp/config.yaml"),
+        ]
+
+    def test_load_config(self, new_dir):
+        Path("system.yaml").write_text(
+            textwrap.dedent(
+                """\
+                cache-dir: /cache
+                parallel-build-count: 4
+                """
+            )
+        )
+        Path("user.yaml").write_text("parallel-build-count: 8\n")
+        Path("empty.yaml").write_text("")
+
+        parts_config = config.load_config(
+            ["system.yaml", "missing.yaml", "empty.yaml", Path("user.yaml")]
+        )
+        assert parts_config.cache_dir == "/cache"
+        assert parts_config.parallel_build_count == 8
+
+    def test_load_config_default_files(self, mocker, new_dir):
+        Path("config.yaml").write_text("max-parallel-parts: 3\n")
+        mocker.patch(
+            "craft_parts.config.get_config_files",
+            return_value=[Path("missing.yaml"), Path("config.yaml")],
+        )
+
+        assert config.load_config().max_parallel_parts == 3
+
+    def test_load_config_invalid(self, new_dir):
+        Path("config.yaml").write_text("parallel-build-count: many\nfoo: bar\n")
+
+        with pytest.raises(errors.ConfigurationError) as raised:
+            config.load_config(["config.yaml"])
+        assert raised.value.path == "config.yaml"
+        assert "'parallel-build-count'" in raised.value.message
+        assert "'foo'" in raised.value.message
+
+    @pytest.mark.parametrize("content", ["- foo\n", "foo: [\n"])
+    def test_load_config_malformed(self, new_dir, content):
+        Path("config.yaml").write_text(content)
+
+        with pytest.raises(errors.ConfigurationError) as raised:
+            config.load_config(["config.yaml"])
+        assert raised.value.path == "config.yaml"
+
+    def test_load_config_malformed_line(self, new_dir):
+        Path("config.yaml").write_text("cache-dir: /cache\nfoo: [\n")
+
+        with pytest.raises(errors.ConfigurationError) as raised:
+            config.load_config(["config.yaml"])
+        assert raised.value.line == 3
+        assert raised.value.marshal()["line"] == 3
@@ -0,0 +1,95 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import sys
+from pathlib import Path
+
+import pytest
+
+from craft_parts import ctl
+
+
+@pytest.fixture
+def fifos(new_dir, monkeypatch):
+    """Use regular files in place of the control API FIFOs."""
+    call = Path(new_dir, "call")
+    feedback = Path(new_dir, "feedback")
+    monkeypatch.setenv("PARTS_CALL_FIFO", str(call))
+    monkeypatch.setenv("PARTS_FEEDBACK_FIFO", str(feedback))
+    return call, feedback
+
+
+class TestClient:
+    """Verify the control API client."""
+
+    def test_client_call(self, fifos):
+        call, feedback = fifos
+        feedback.write_text("\n")
+
+        assert ctl.client("build", []) is None
+        assert call.read_text() == '{"function": "build", "args": []}'
+
+    def test_client_result(self, fifos):
+        call, feedback = fifos
+        feedback.write_text("OK 1.0\n")
+
+        assert ctl.client("get", ["version"]) == "1.0"
+        assert call.read_text() == '{"function": "get", "args": ["version"]}'
+
+    def test_client_error(self, fifos):
+        _, feedback = fifos
+        feedback.write_text("something failed\n")
+
+        with pytest.raises(RuntimeError) as raised:
+            ctl.client("get", ["version"])
+        assert str(raised.value) == "something failed"
+
+    def test_client_not_in_scriptlet(self, monkeypatch):
+        monkeypatch.delenv("PARTS_CALL_FIFO", raising=False)
+
+        with pytest.raises(RuntimeError) as raised:
+            ctl.client("get", ["version"])
+        assert str(raised.value) == "not running in a craft-parts scriptlet"
+
+
+class TestMain:
+    """Verify the craftctl command line."""
+
+    def test_main(self, fifos, capsys, monkeypatch):
+        _, feedback = fifos
+        feedback.write_text("OK stable\n")
+        monkeypatch.setattr(sys, "argv", ["craftctl", "get", "grade"])
+
+        ctl.main()
+        assert capsys.readouterr().out == "stable\n"
+
+    def test_main_usage(self, capsys, monkeypatch):
+        monkeypatch.setattr(sys, "argv", ["craftctl"])
+
+        with pytest.raises(SystemExit) as raised:
+            ctl.main()
+        assert raised.value.code == 2
+        assert capsys.readouterr().err == "usage: craftctl <function> [arguments]\n"
+
+    def test_main_error(self, fifos, capsys, monkeypatch):
+        _, feedback = fifos
+        feedback.write_text("'grade' is not defined\n")
+        monkeypatch.setattr(sys, "argv", ["craftctl", "get", "grade"])
+
+        with pytest.raises(SystemExit) as raised:
+            ctl.main()
+        assert raised.value.code == 1
+        assert capsys.readouterr().err == "craftctl: 'grade' is not defined\n"
@@ -23,6 +23,7 @@ def test_dirs(new_dir):
     assert dirs.parts_dir == new_dir / "parts"
     assert dirs.stage_dir == new_dir / "stage"
     assert dirs.prime_dir == new_dir / "prime"
+    assert dirs.debug_dir == new_dir / "debug"
 
 
 def test_dirs_work_dir(new_dir):
@@ -31,3 +32,4 @@ def test_dirs_work_dir(new_dir):
     assert dirs.parts_dir == new_dir / "foobar/parts"
     assert dirs.stage_dir == new_dir / "foobar/stage"
     assert dirs.prime_dir == new_dir / "foobar/prime"
+    assert dirs.debug_dir == new_dir / "foobar/debug"
@@ -14,7 +14,41 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+import json
+import re
+from pathlib import Path
+from typing import List, Type
+
+import pytest
+
 from craft_parts import errors
+from craft_parts.elf import errors as elf_errors
+from craft_parts.overlays import errors as overlay_errors
+from craft_parts.packages import errors as package_errors
+from craft_parts.sources import errors as source_errors
+from craft_parts.step_cache import errors as step_cache_errors
+
+_ERROR_MODULES = [
+    errors,
+    elf_errors,
+    overlay_errors,
+    package_errors,
+    source_errors,
+    step_cache_errors,
+]
+
+
+def _get_error_classes() -> List[Type[errors.PartsError]]:
+    classes = []
+    for module in _ERROR_MODULES:
+        for value in vars(module).values():
+            if (
+                isinstance(value, type)
+                and issubclass(value, errors.PartsError)
+                and value.__module__ == module.__name__
+            ):
+                classes.append(value)
+    return classes
 
 
 def test_parts_error_brief():
@@ -41,6 +75,90 @@ def test_parts_error_full():
     assert err.resolution == "Resolution"
 
 
+@pytest.mark.parametrize("error_class", _get_error_classes())
+def test_error_code(error_class):
+    assert "code" in vars(error_class)
+    assert re.match(r"^[a-z]+(-[a-z]+)*$", error_class.code)
+
+
+def test_error_codes_unique():
+    codes = [error_class.code for error_class in _get_error_classes()]
+    assert len(codes) > 80
+    assert len(codes) == len(set(codes))
+
+
+def test_parts_error_marshal():
+    err = errors.PartsError(brief="Brief", details="Details", resolution="Resolution")
+    assert err.marshal() == {
+        "code": "parts-error",
+        "brief": "Brief",
+        "details": "Details",
+        "resolution": "Resolution",
+        "part": None,
+        "step": None,
+        "file": None,
+        "line": None,
+        "attributes": {},
+    }
+
+
+def test_parts_error_marshal_location():
+    err = errors.StepTimeoutError(part_name="foo", step_name="build", timeout=10)
+    data = err.marshal()
+    assert data["code"] == "step-timeout-error"
+    assert data["part"] == "foo"
+    assert data["step"] == "build"
+    assert data["file"] is None
+    assert data["attributes"] == {
+        "part_name": "foo",
+        "step_name": "build",
+        "timeout": 10,
+    }
+
+
+def test_parts_error_marshal_scriptlet_step():
+    err = errors.ScriptletRunError(
+        part_name="foo", scriptlet_name="override-stage", exit_code=2
+    )
+    data = err.marshal()
+    assert data["part"] == "foo"
+    assert data["step"] == "stage"
+
+    err = errors.ScriptletRunError(
+        part_name="foo", scriptlet_name="overlay-script", exit_code=2
+    )
+    assert err.marshal()["step"] is None
+
+
+def test_parts_error_marshal_file():
+    err = errors.ConfigurationError("config.yaml", message="bad", line=3)
+    data = err.marshal()
+    assert data["file"] == "config.yaml"
+    assert data["line"] == 3
+
+    err = errors.FilesetConflict({"b", "a"})
+    assert err.marshal()["attributes"] == {"conflicting_files": ["a", "b"]}
+
+
+def test_parts_error_marshal_values():
+    err = errors.PartsError(brief="Brief")
+    err.path = Path("/some/path")  # type: ignore
+    err.items = ({"a": (1, 2)}, object)  # type: ignore
+    data = err.marshal()
+    assert data["file"] == "/some/path"
+    assert data["attributes"] == {
+        "path": "/some/path",
+        "items": [{"a": [1, 2]}, "<class 'object'>"],
+    }
+
+
+def test_parts_error_to_json():
+    err = errors.PluginBuildError(part_name="foo")
+    assert json.loads(err.to_json()) == err.marshal()
+    assert err.marshal()["step"] == "build"
+    assert err.to_json(indent=2).startswith('{\n  "code": "plugin-build-error"')
+
+
 def test_part_dependency_cycle():
     err = errors.PartDependencyCycle()
     assert err.brief == "A circular dependency chain was detected."
@@ -86,6 +204,26 @@ def test_part_specification_error_from_validation_error():
     assert err.resolution == "Review part 'foo' and make sure it's correct."
 
 
+def test_part_specification_error_suggestion():
+    error_list = [
+        {"loc": ("overide-build",), "msg": "extra", "type": "value_error.extra"},
+        {"loc": ("foo",), "msg": "extra", "type": "value_error.extra"},
+        {"loc": ("overide-stage",), "msg": "wrong", "type": "type_error"},
+        {"loc": ("x", "overide-prime"), "msg": "extra", "type": "value_error.extra"},
+    ]
+    err = errors.PartSpecificationError.from_validation_error(
+        part_name="foo",
+        error_list=error_list,
+        valid_names=["override-build", "override-stage", "override-prime"],
+    )
+    assert err.details.splitlines() == [
+        "'overide-build': extra (did you mean 'override-build'?)",
+        "'foo': extra",
+        "'overide-stage': wrong",
+        "'x','overide-prime': extra",
+    ]
+
+
 def test_part_specification_error_from_bad_validation_error():
     error_list = [
         {"loc": "field-1", "msg": "something is wrong"},
@@ -102,6 +240,48 @@ def test_part_specification_error_from_bad_validation_error():
     assert err.resolution == "Review part 'foo' and make sure it's correct."
 
 
+def test_part_template_error():
+    err = errors.PartTemplateError(template_name="foo", message="something is wrong")
+    assert err.template_name == "foo"
+    assert err.message == "something is wrong"
+    assert err.brief == "Part template 'foo' validation failed."
+    assert err.details == "something is wrong"
+    assert err.resolution == "Review part template 'foo' and make sure it's correct."
+
+
+def test_parts_file_error():
+    err = errors.PartsFileError("parts.yaml", message="something is wrong", line=2)
+    assert err.path == "parts.yaml"
+    assert err.message == "something is wrong"
+    assert err.line == 2
+    assert err.brief == "Cannot load parts file 'parts.yaml'."
+    assert err.details == "something is wrong"
+    assert err.resolution == (
+        "Make sure the file exists and contains a parts definition."
+    )
+
+
+def test_configuration_error():
+    err = errors.ConfigurationError("config.yaml", message="something is wrong")
+    assert err.path == "config.yaml"
+    assert err.message == "something is wrong"
+    assert err.brief == "Invalid configuration file 'config.yaml'."
+    assert err.details == "something is wrong"
+    assert err.resolution == "Review the configuration file and make sure it's correct."
+
+
+def test_configuration_error_from_validation_error():
+    error_list = [
+        {"loc": ("field-1",), "msg": "something is wrong"},
+        {"loc": "field-2", "msg": "something is wrong"},
+    ]
+    err = errors.ConfigurationError.from_validation_error(
+        "config.yaml", error_list=error_list
+    )
+    assert err.path == "config.yaml"
+    assert err.details == "'field-1': something is wrong"
+
+
 def test_copy_tree_error():
     err = errors.CopyTreeError("something bad happened")
     assert err.message == "something bad happened"
@@ -155,6 +335,15 @@ def test_invalid_plugin():
     assert err.resolution == "Review part 'foo' and make sure it's correct."
 
 
+def test_plugin_load_error():
+    err = errors.PluginLoadError("name", message="something is wrong")
+    assert err.plugin_name == "name"
+    assert err.message == "something is wrong"
+    assert err.brief == "Failed to load plugin 'name': something is wrong."
+    assert err.details is None
+    assert err.resolution == "Make sure the plugin is correct."
+
+
 def test_os_release_id_error():
     err = errors.OsReleaseIdError()
     assert err.brief == "Unable to determine the host operating system ID."
@@ -183,6 +372,46 @@ def test_os_release_codename_error():
     assert err.resolution is None
 
 
+def test_invalid_base():
+    err = errors.InvalidBase("ubuntu@")
+    assert err.base == "ubuntu@"
+    assert err.brief == "Invalid base 'ubuntu@'."
+    assert err.details is None
+    assert err.resolution == (
+        "Name the base as '<distribution>@<version>', e.g. 'ubuntu@22.04'."
+    )
+
+
+def test_unsupported_base():
+    err = errors.UnsupportedBase("gentoo@2.14")
+    assert err.base == "gentoo@2.14"
+    assert err.brief == "Base 'gentoo@2.14' is not supported."
+    assert err.details == "System packages can't be installed or staged on this base."
+    assert err.resolution == (
+        "Use a base supported by craft-parts, or register a package backend "
+        "for its distribution."
+    )
+
+
+def test_host_feature_not_supported():
+    err = errors.HostFeatureNotSupported("scriptlets", host="Windows")
+    assert err.feature == "scriptlets"
+    assert err.host == "Windows"
+    assert err.brief == "Cannot use scriptlets on Windows hosts."
+    assert err.details is None
+    assert err.resolution == "Process the project on a Linux host."
+
+
+def test_feature_not_enabled():
+    err = errors.FeatureNotEnabled("overlay", details="Part 'foo' uses overlays.")
+    assert err.feature == "overlay"
+    assert err.brief == "Feature 'overlay' is not enabled."
+    assert err.details == "Part 'foo' uses overlays."
+    assert err.resolution == (
+        "Enable the 'overlay' feature flag in the configuration."
+    )
+
+
 def test_fileset_error():
     err = errors.FilesetError(name="stage", message="something is wrong")
     assert err.name == "stage"
@@ -216,6 +445,37 @@ def test_file_organize_error():
     assert err.resolution is None
 
 
+def test_file_permissions_error():
+    err = errors.FilePermissionsError(
+        part_name="foo", path="usr/bin/bar", message="Operation not permitted"
+    )
+    assert err.part_name == "foo"
+    assert err.path == "usr/bin/bar"
+    assert err.message == "Operation not permitted"
+    assert err.brief == (
+        "Failed to set permissions of 'usr/bin/bar' in part 'foo': "
+        "Operation not permitted."
+    )
+    assert err.details is None
+    assert err.resolution == "Make sure the permissions defined in the part are valid."
+
+
+def test_strip_error():
+    err = errors.StripError(
+        part_name="foo", path="usr/bin/bar", message="'strip' is not installed"
+    )
+    assert err.part_name == "foo"
+    assert err.path == "usr/bin/bar"
+    assert err.message == "'strip' is not installed"
+    assert err.brief == (
+        "Failed to strip 'usr/bin/bar' in part 'foo': 'strip' is not installed."
+    )
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure binutils is installed, or exclude the file from stripping."
+    )
+
+
 def test_part_files_conflict():
     err = errors.PartFilesConflict(
         part_name="foo", other_part_name="bar", conflicting_files=["file1", "file2"]
@@ -252,6 +512,23 @@ def test_stage_files_conflict():
     assert err.resolution is None
 
 
+def test_plugin_environment_validation_error():
+    err = errors.PluginEnvironmentValidationError(part_name="foo", reason="bar")
+    assert err.part_name == "foo"
+    assert err.reason == "bar"
+    assert err.brief == "Environment validation failed for part 'foo': bar."
+    assert err.details is None
+    assert err.resolution is None
+
+
+def test_plugin_pull_error():
+    err = errors.PluginPullError(part_name="foo")
+    assert err.part_name == "foo"
+    assert err.brief == "Failed to run the pull script for part 'foo'."
+    assert err.details is None
+    assert err.resolution is None
+
+
 def test_plugin_build_error():
     err = errors.PluginBuildError(part_name="foo")
     assert err.part_name == "foo"
@@ -287,6 +564,81 @@ def test_scriptlet_run_error():
     assert err.resolution == "Review the scriptlet and make sure it's correct."
 
 
+def test_step_timeout_error():
+    err = errors.StepTimeoutError(part_name="foo", step_name="pull", timeout=600)
+    assert err.part_name == "foo"
+    assert err.step_name == "pull"
+    assert err.timeout == 600
+    assert err.brief == "Step 'pull' of part 'foo' timed out after 600 seconds."
+    assert err.details is None
+    assert err.resolution == "Increase the step timeout or check for stalled commands."
+
+
+def test_network_isolation_error():
+    err = errors.NetworkIsolationError(part_name="foo", step_name="build")
+    assert err.part_name == "foo"
+    assert err.step_name == "build"
+    assert err.brief == "Cannot disable network access for step 'build' of part 'foo'."
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure 'unshare' is installed and that network namespaces "
+        "can be created in this host."
+    )
+
+
+def test_build_isolation_error():
+    err = errors.BuildIsolationError(part_name="foo", message="something failed")
+    assert err.part_name == "foo"
+    assert err.message == "something failed"
+    assert err.brief == "Cannot isolate the build of part 'foo': something failed."
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure the base layer directory is set and that the isolation "
+        "method is supported in this host."
+    )
+
+
+def test_emulation_error():
+    err = errors.EmulationError("arm64", message="something is wrong")
+    assert err.arch == "arm64"
+    assert err.message == "something is wrong"
+    assert err.brief == "Cannot emulate architecture 'arm64': something is wrong."
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure qemu-user-static and binfmt-support are installed in this host."
+    )
+
+
+def test_secret_not_found():
+    err = errors.SecretNotFound(part_name="foo", secret_name="token")
+    assert err.part_name == "foo"
+    assert err.secret_name == "token"
+    assert err.brief == "Secret 'token' used by part 'foo' is not available."
+    assert err.details is None
+    assert err.resolution == (
+        "Make sure the secret is set in the host environment or provided "
+        "by the application."
+    )
+
+
+def test_state_version_error():
+    err = errors.StateVersionError(
+        part_name="foo", step_name="pull", version=3, supported_version=1
+    )
+    assert err.part_name == "foo"
+    assert err.step_name == "pull"
+    assert err.version == 3
+    assert err.supported_version == 1
+    assert err.brief == (
+        "State of step 'pull' of part 'foo' has schema version 3, newer than "
+        "the supported version 1."
+    )
+    assert err.details is None
+    assert err.resolution == (
+        "Upgrade the application, or clean the part to discard its state."
+    )
+
+
 def test_callback_registration_error():
     err = errors.CallbackRegistrationError("General failure reading drive A")
     assert err.message == "General failure reading drive A"
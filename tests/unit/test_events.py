@@ -0,0 +1,126 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from craft_parts import events, progress
+from craft_parts.actions import Action, ActionType
+from craft_parts.executor.metrics import ActionMetrics
+from craft_parts.steps import Step
+
+
+class TestEvents:
+    """Verify execution event serialization."""
+
+    def test_action_started(self):
+        event = events.ActionStarted(Action("foo", Step.PULL), index=2, total=5)
+
+        assert event.marshal() == {
+            "event": "action-started",
+            "timestamp": event.timestamp,
+            "part": "foo",
+            "step": "pull",
+            "type": "run",
+            "reason": None,
+            "index": 2,
+            "total": 5,
+        }
+
+    def test_action_output(self):
+        event = events.ActionOutput(
+            Action("foo", Step.BUILD),
+            stream=events.OutputStream.STDERR,
+            data="warning\n",
+        )
+
+        assert event.marshal() == {
+            "event": "action-output",
+            "timestamp": event.timestamp,
+            "part": "foo",
+            "step": "build",
+            "type": "run",
+            "reason": None,
+            "stream": "stderr",
+            "data": "warning\n",
+        }
+
+    def test_download_progress(self):
+        event = events.DownloadProgress(
+            Action("foo", Step.PULL),
+            kind=progress.DownloadKind.PACKAGE,
+            item="hello",
+            downloaded=250,
+            total=1000,
+        )
+
+        assert event.percentage == 25.0
+        assert event.marshal() == {
+            "event": "download-progress",
+            "timestamp": event.timestamp,
+            "part": "foo",
+            "step": "pull",
+            "type": "run",
+            "reason": None,
+            "kind": "package",
+            "item": "hello",
+            "downloaded": 250,
+            "total": 1000,
+            "percentage": 25.0,
+        }
+
+    def test_download_progress_unknown_total(self):
+        event = events.DownloadProgress(
+            Action("foo", Step.PULL), item="http://test.com/file", downloaded=250
+        )
+
+        assert event.kind == progress.DownloadKind.SOURCE
+        assert event.percentage is None
+        assert event.marshal()["total"] is None
+
+    def test_action_finished(self):
+        action = Action("foo", Step.BUILD, ActionType.RERUN, reason="dirty")
+        event = events.ActionFinished(
+            action,
+            status=events.ActionStatus.SUCCEEDED,
+            metrics=ActionMetrics(action, 1.5, 0.5, 4096),
+        )
+
+        assert event.marshal() == {
+            "event": "action-finished",
+            "timestamp": event.timestamp,
+            "part": "foo",
+            "step": "build",
+            "type": "rerun",
+            "reason": "dirty",
+            "status": "succeeded",
+            "duration": 1.5,
+            "cpu-time": 0.5,
+            "peak-disk-usage": 4096,
+        }
+
+    def test_action_finished_failed(self):
+        event = events.ActionFinished(
+            Action("foo", Step.PULL),
+            status=events.ActionStatus.FAILED,
+            error="network unreachable",
+        )
+
+        data = event.marshal()
+        assert data["status"] == "failed"
+        assert data["error"] == "network unreachable"
+        assert "duration" not in data
+
+    def test_compare_ignores_timestamp(self):
+        action = Action("foo", Step.PULL)
+        assert events.ActionStarted(action) == events.ActionStarted(action)
@@ -0,0 +1,164 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import gzip
+import hashlib
+import os
+import stat
+import tarfile
+from pathlib import Path
+
+import pytest
+
+from craft_parts import export
+from craft_parts.export import OciLayer
+
+_needs_root = pytest.mark.skipif(os.geteuid() != 0, reason="requires root")
+
+
+@pytest.fixture
+def root(new_dir):
+    root = Path(new_dir, "prime")
+    Path(root, "usr/bin").mkdir(parents=True)
+    Path(root, "usr/bin/hello").write_text("hello")
+    Path(root, "usr/bin/hello").chmod(0o755)
+    Path(root, "etc").mkdir()
+    Path(root, "etc/config").write_text("config")
+    Path(root, "link").symlink_to("usr/bin/hello")
+    for path in root.rglob("*"):
+        os.utime(path, (2000000000, 2000000000), follow_symlinks=False)
+    return root
+
+
+def _members(tar: tarfile.TarFile):
+    return [(x.name, x.type, x.mtime, x.uid, x.uname) for x in tar.getmembers()]
+
+
+class TestCreateTarball:
+    """Verify the creation of deterministic tarballs."""
+
+    def test_create_tarball(self, root):
+        export.create_tarball(root, Path("prime.tar"))
+
+        assert tarfile.is_tarfile("prime.tar")
+        with tarfile.open("prime.tar") as tar:
+            assert _members(tar) == [
+                ("etc", tarfile.DIRTYPE, 2000000000, 0, ""),
+                ("etc/config", tarfile.REGTYPE, 2000000000, 0, ""),
+                ("link", tarfile.SYMTYPE, 2000000000, 0, ""),
+                ("usr", tarfile.DIRTYPE, 2000000000, 0, ""),
+                ("usr/bin", tarfile.DIRTYPE, 2000000000, 0, ""),
+                ("usr/bin/hello", tarfile.REGTYPE, 2000000000, 0, ""),
+            ]
+            assert tar.getmember("link").linkname == "usr/bin/hello"
+            assert stat.S_IMODE(tar.getmember("usr/bin/hello").mode) == 0o755
+            content = tar.extractfile("usr/bin/hello")
+            assert content is not None
+            assert content.read() == b"hello"
+
+    def test_create_tarball_mtime(self, root):
+        export.create_tarball(root, Path("prime.tar"), mtime=1000)
+
+        with tarfile.open("prime.tar") as tar:
+            assert {x.mtime for x in tar.getmembers()} == {1000}
+
+    def test_create_tarball_deterministic(self, root):
+        export.create_tarball(root, Path("prime1.tar.gz"), compress=True)
+        Path(root, "etc/config").touch()
+        os.utime(Path(root, "etc/config"), (1, 1))
+        export.create_tarball(root, Path("prime2.tar.gz"), mtime=0, compress=True)
+        export.create_tarball(root, Path("prime3.tar.gz"), mtime=0, compress=True)
+
+        data = [Path(f"prime{x}.tar.gz").read_bytes() for x in range(1, 4)]
+        assert data[1] == data[2]
+        assert data[0] != data[1]
+
+        with tarfile.open("prime1.tar.gz", "r:gz") as tar:
+            assert len(tar.getmembers()) == 6
+
+    def test_create_tarball_opaque_directory(self, mocker, root):
+        def fake_getxattr(path, key, *, follow_symlinks=True):
+            if path.endswith("/etc") and key == "trusted.overlay.opaque":
+                return b"y"
+            raise OSError(61, "No data available")
+
+        mocker.patch("os.getxattr", side_effect=fake_getxattr)
+
+        export.create_tarball(root, Path("prime.tar"))
+
+        with tarfile.open("prime.tar") as tar:
+            assert tar.getnames()[:3] == ["etc", "etc/.wh..wh..opq", "etc/config"]
+            assert tar.getmember("etc/.wh..wh..opq").isreg()
+            assert tar.getmember("etc/.wh..wh..opq").size == 0
+
+    @_needs_root
+    def test_create_tarball_whiteout_device(self, root):
+        os.mknod(Path(root, "usr/bin/removed"), stat.S_IFCHR | 0o600, 0)
+        os.mknod(Path(root, "removed"), stat.S_IFCHR | 0o600, 0)
+
+        export.create_tarball(root, Path("prime.tar"))
+
+        with tarfile.open("prime.tar") as tar:
+            names = tar.getnames()
+            assert ".wh.removed" in names
+            assert "usr/bin/.wh.removed" in names
+            assert "removed" not in names
+            assert tar.getmember("usr/bin/.wh.removed").isreg()
+
+    def test_create_tarball_oci_whiteout(self, root):
+        Path(root, "etc/.wh.removed").touch()
+
+        export.create_tarball(root, Path("prime.tar"))
+
+        with tarfile.open("prime.tar") as tar:
+            assert tar.getmember("etc/.wh.removed").isreg()
+
+
+class TestCreateOciLayer:
+    """Verify the creation of OCI image layers."""
+
+    def test_create_oci_layer(self, root):
+        layer = export.create_oci_layer(root, Path("blobs/sha256"))
+
+        blob = next(Path("blobs/sha256").iterdir())
+        data = blob.read_bytes()
+        digest = hashlib.sha256(data).hexdigest()
+        diff_id = hashlib.sha256(gzip.decompress(data)).hexdigest()
+
+        assert layer == OciLayer(
+            digest=f"sha256:{digest}",
+            size=len(data),
+            diff_id=f"sha256:{diff_id}",
+            path=Path("blobs/sha256", digest),
+        )
+        assert blob.name == digest
+        assert layer.marshal() == {
+            "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+            "digest": f"sha256:{digest}",
+            "size": len(data),
+        }
+
+        with tarfile.open(blob, "r:gz") as tar:
+            assert "usr/bin/hello" in tar.getnames()
+
+    def test_create_oci_layer_deterministic(self, root):
+        layer1 = export.create_oci_layer(root, Path("blobs1"), mtime=0)
+        layer2 = export.create_oci_layer(root, Path("blobs2"), mtime=0)
+
+        assert layer1.digest == layer2.digest
+        assert layer1.diff_id == layer2.diff_id
+        assert len(list(Path("blobs1").iterdir())) == 1
@@ -0,0 +1,110 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts import errors, hosts
+
+
+@pytest.mark.parametrize(
+    "sys_platform,host_class,name",
+    [
+        ("linux", hosts.LinuxHost, "Linux"),
+        ("darwin", hosts.MacOSHost, "macOS"),
+        ("win32", hosts.WindowsHost, "Windows"),
+        ("freebsd13", hosts.HostPlatform, hosts.HostPlatform.name),
+    ],
+)
+def test_get_host_platform(mocker, sys_platform, host_class, name):
+    mocker.patch("sys.platform", sys_platform)
+
+    host = hosts.get_host_platform()
+    assert type(host) is host_class  # pylint: disable=unidiomatic-typecheck
+    assert host.name == name
+
+
+@pytest.mark.parametrize(
+    "host,features",
+    [
+        (hosts.LinuxHost(), [True, True, True, True, True]),
+        (hosts.MacOSHost(), [False, False, False, False, True]),
+        (hosts.WindowsHost(), [False, False, False, False, False]),
+    ],
+)
+def test_host_features(host, features):
+    assert [
+        host.supports_packages,
+        host.supports_overlays,
+        host.supports_isolation,
+        host.supports_xattrs,
+        host.supports_fifos,
+    ] == features
+
+
+@pytest.mark.parametrize(
+    "machine,result",
+    [
+        ("x86_64", "x86_64"),
+        ("aarch64", "aarch64"),
+        ("armv7l", "armv7l"),
+        ("arm64", "aarch64"),
+        ("AMD64", "x86_64"),
+        ("ARM64", "aarch64"),
+    ],
+)
+def test_get_machine(mocker, machine, result):
+    mocker.patch("platform.machine", return_value=machine)
+
+    assert hosts.MacOSHost().get_machine() == result
+
+
+@pytest.mark.parametrize("euid,result", [(0, True), (1000, False)])
+def test_is_root(mocker, euid, result):
+    mocker.patch("os.geteuid", return_value=euid)
+
+    assert hosts.LinuxHost().is_root() is result
+
+
+def test_posix_script_command():
+    host = hosts.MacOSHost()
+
+    assert host.get_shell() == "/bin/sh"
+    assert host.get_script_command(Path("build.sh")) == [Path("build.sh")]
+
+
+class TestWindowsHost:
+    """Step scripts are executed by a shell found in the host."""
+
+    def test_script_command(self, mocker):
+        mocker.patch("shutil.which", return_value="C:/Git/bin/sh.exe")
+        host = hosts.WindowsHost()
+
+        assert host.is_root() is False
+        assert host.get_script_command(Path("build.sh")) == [
+            "C:/Git/bin/sh.exe",
+            Path("build.sh"),
+        ]
+
+    def test_no_shell(self, mocker):
+        mocker.patch("shutil.which", return_value=None)
+
+        with pytest.raises(errors.HostFeatureNotSupported) as raised:
+            hosts.WindowsHost().get_shell()
+        assert raised.value.feature == "step scripts"
+        assert raised.value.host == "Windows"
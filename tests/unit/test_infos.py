@@ -14,12 +14,19 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+from pathlib import Path
+
 import pytest
 
-from craft_parts import errors
+from craft_parts import bases, errors
+from craft_parts.compiler_cache import CompilerCacheConfig
 from craft_parts.dirs import ProjectDirs
 from craft_parts.infos import PartInfo, ProjectInfo, StepInfo
+from craft_parts.packages.lockfile import LockfileMode
 from craft_parts.parts import Part
+from craft_parts.proxy import ProxyConfig
+from craft_parts.sources.mirrors import MirrorRule
+from craft_parts.step_cache import LocalCacheBackend
 from craft_parts.steps import Step
 
 _MOCK_NATIVE_ARCH = "aarch64"
@@ -51,13 +58,19 @@ def test_project_info(mocker, new_dir, tc_arch, tc_target_arch, tc_triplet, tc_c
 
     assert x.application_name == "test"
     assert x.arch_triplet == tc_triplet
+    assert x.host_arch == "arm64"
     assert x.is_cross_compiling == tc_cross
     assert x.parallel_build_count == 16
     assert x.target_arch == tc_target_arch
+    assert x.project_name is None
+    assert x.project_vars == {}
+    assert x.project_environment == {}
     assert x.project_options == {
         "application_name": "test",
         "arch_triplet": tc_triplet,
         "target_arch": tc_target_arch,
+        "project_name": None,
+        "project_vars": {},
     }
 
     assert x.parts_dir == new_dir / "parts"
@@ -65,6 +78,27 @@ def test_project_info(mocker, new_dir, tc_arch, tc_target_arch, tc_triplet, tc_c
     assert x.prime_dir == new_dir / "prime"
 
 
+def test_project_info_emulation(mocker):
+    mocker.patch("platform.machine", return_value=_MOCK_NATIVE_ARCH)
+
+    info = ProjectInfo(arch="x86_64", emulation=True)
+    assert info.is_emulated
+    assert info.is_cross_compiling is False
+    assert info.target_arch == "amd64"
+    assert info.host_arch == "amd64"
+    assert info.arch_triplet == "x86_64-linux-gnu"
+
+
+def test_project_info_emulation_native(mocker):
+    mocker.patch("platform.machine", return_value=_MOCK_NATIVE_ARCH)
+
+    info = ProjectInfo(arch="aarch64", emulation=True)
+    assert info.is_emulated is False
+    assert info.is_cross_compiling is False
+    assert info.host_arch == "arm64"
+    assert ProjectInfo().is_emulated is False
+
+
 def test_project_info_work_dir(new_dir):
     info = ProjectInfo(project_dirs=ProjectDirs(work_dir="work_dir"))
 
@@ -73,6 +107,182 @@ def test_project_info_work_dir(new_dir):
     assert info.prime_dir == new_dir / "work_dir/prime"
 
 
+def test_project_info_project_vars():
+    info = ProjectInfo(project_name="hello", project_vars={"version": "1.0"})
+
+    assert info.project_name == "hello"
+    assert info.project_vars == {"version": "1.0"}
+    assert info.project_environment == {
+        "CRAFT_PROJECT_NAME": "hello",
+        "CRAFT_PROJECT_VERSION": "1.0",
+    }
+    assert info.project_options["project_name"] == "hello"
+    assert info.project_options["project_vars"] == {"version": "1.0"}
+
+
+def test_project_info_set_project_var():
+    project_vars = {"version": "1.0"}
+    info = ProjectInfo(project_vars=project_vars)
+    info.set_project_var("version", "1.1")
+
+    assert info.project_vars == {"version": "1.1"}
+    assert info.project_environment == {"CRAFT_PROJECT_VERSION": "1.1"}
+    assert project_vars == {"version": "1.0"}
+
+
+def test_project_info_cache():
+    info = ProjectInfo(cache_dir=Path("/some/cache"), cache_size_limit=1000)
+
+    assert info.cache_dir == Path("/some/cache")
+    assert info.cache_size_limit == 1000
+
+
+def test_project_info_source_mirrors():
+    rules = [MirrorRule("https://github.com/", "https://mirror/")]
+    info = ProjectInfo(source_mirrors=rules)
+
+    assert info.source_mirrors == rules
+
+
+def test_project_info_step_cache_backend():
+    backend = LocalCacheBackend("test", cache_dir="cache")
+    info = ProjectInfo(step_cache_backend=backend)
+
+    assert info.step_cache_backend == backend
+    assert ProjectInfo().step_cache_backend is None
+
+
+def test_project_info_step_policies():
+    info = ProjectInfo(
+        step_timeouts={Step.PULL: 600},
+        step_retries={Step.PULL: 3},
+        retry_delay=5,
+    )
+
+    assert info.step_timeouts == {Step.PULL: 600}
+    assert info.step_retries == {Step.PULL: 3}
+    assert info.retry_delay == 5
+
+
+def test_project_info_step_policies_default():
+    info = ProjectInfo()
+
+    assert info.step_timeouts == {}
+    assert info.step_retries == {}
+    assert info.retry_delay == 1.0
+
+
+def test_project_info_reproducibility():
+    info = ProjectInfo(source_date_epoch=1000, normalize_prime=True)
+
+    assert info.source_date_epoch == 1000
+    assert info.normalize_prime is True
+    assert ProjectInfo().source_date_epoch is None
+    assert ProjectInfo().normalize_prime is False
+
+
+def test_project_info_proxy():
+    proxy = ProxyConfig(http_proxy="http://proxy:3128")
+    info = ProjectInfo(proxy=proxy)
+
+    assert info.proxy == proxy
+    assert ProjectInfo().proxy is None
+
+
+def test_project_info_compiler_cache():
+    config = CompilerCacheConfig(cache_dir=Path("/cache"))
+    info = ProjectInfo(compiler_cache=config)
+
+    assert info.compiler_cache == config
+    assert ProjectInfo().compiler_cache is None
+
+
+def test_project_info_values():
+    info = ProjectInfo(project_vars={"version": "1.0"})
+    assert info.get_value("version") == "1.0"
+    assert info.get_value("revision") is None
+
+    info.set_value("version", "2.0")
+    info.set_value("revision", "42")
+    assert info.project_vars == {"version": "2.0"}
+    assert info.run_values == {"revision": "42"}
+    assert info.get_value("revision") == "42"
+
+
+def test_step_info_values():
+    info = ProjectInfo(project_vars={"version": "1.0"})
+    part = Part("foo", {})
+    step_info = StepInfo(PartInfo(project_info=info, part=part), Step.BUILD)
+    other_info = StepInfo(PartInfo(project_info=info, part=part), Step.PULL)
+
+    step_info.set_value("version", "2.0")
+    step_info.set_value("revision", "42")
+    other_info.set_value("grade", "stable")
+    assert step_info.values == {"revision": "42"}
+    assert other_info.values == {"grade": "stable"}
+    assert info.run_values == {"revision": "42", "grade": "stable"}
+    assert info.project_vars == {"version": "2.0"}
+
+
+@pytest.mark.parametrize("name", ["", "1st", "a.b", "a b"])
+def test_project_info_set_value_invalid(name):
+    info = ProjectInfo()
+    with pytest.raises(ValueError) as raised:
+        info.set_value(name, "value")
+    assert str(raised.value) == f"invalid name {name!r}"
+
+
+def test_project_info_base_layer_dir():
+    info = ProjectInfo(base_layer_dir=Path("/base"))
+
+    assert info.base_layer_dir == Path("/base")
+    assert ProjectInfo().base_layer_dir is None
+
+
+def test_project_info_packages_lockfile():
+    info = ProjectInfo(
+        packages_lockfile=Path("packages.lock"),
+        packages_lockfile_mode=LockfileMode.REPLAY,
+    )
+
+    assert info.packages_lockfile == Path("packages.lock")
+    assert info.packages_lockfile_mode == LockfileMode.REPLAY
+    assert ProjectInfo().packages_lockfile is None
+    assert ProjectInfo().packages_lockfile_mode == LockfileMode.RECORD
+
+
+def test_project_info_base(mocker):
+    mocker.patch("craft_parts.bases.get_host_base", return_value=None)
+
+    info = ProjectInfo(base="core24")
+    assert info.base == "core24"
+    assert info.build_base == bases.Base("ubuntu", "24.04")
+
+
+def test_project_info_build_base_host(mocker):
+    host_base = bases.Base("fedora", "39")
+    mocker.patch("craft_parts.bases.get_host_base", return_value=host_base)
+
+    info = ProjectInfo()
+    assert info.base is None
+    assert info.build_base == host_base
+
+
+def test_project_info_invalid_base():
+    with pytest.raises(errors.InvalidBase):
+        ProjectInfo(base="foo@")
+
+
+def test_project_info_features():
+    info = ProjectInfo(features={"foo": True, "bar": False})
+    assert info.features == {"foo": True, "bar": False}
+    assert info.is_feature_enabled("foo")
+    assert info.is_feature_enabled("bar") is False
+    assert info.is_feature_enabled("baz") is False
+    assert ProjectInfo().features == {}
+    assert ProjectInfo().base is None
+
+
 def test_project_info_custom_args():
     info = ProjectInfo(custom1="foobar", custom2=[1, 2])
 
@@ -94,6 +304,9 @@ def test_project_info_default():
 
     assert x.application_name == "craft_parts"
     assert x.parallel_build_count == 1
+    assert x.cache_dir is None
+    assert x.cache_size_limit is None
+    assert x.source_mirrors == []
 
 
 def test_invalid_arch():
@@ -117,6 +330,7 @@ def test_part_info(new_dir):
     assert x.part_build_subdir == new_dir / "parts/foo/build"
     assert x.part_install_dir == new_dir / "parts/foo/install"
     assert x.part_state_dir == new_dir / "parts/foo/state"
+    assert x.part_dependencies == []
     assert x.stage_dir == new_dir / "stage"
     assert x.prime_dir == new_dir / "prime"
 
@@ -125,6 +339,13 @@ def test_part_info(new_dir):
     assert x.custom2 == [1, 2]
 
 
+def test_part_info_dependencies():
+    part = Part("foo", {"after": ["bar", "baz"]})
+    x = PartInfo(project_info=ProjectInfo(), part=part)
+
+    assert x.part_dependencies == ["bar", "baz"]
+
+
 def test_part_info_invalid_custom_args():
     info = ProjectInfo()
     part = Part("foo", {})
@@ -16,14 +16,27 @@
 
 """Unit tests for the lifecycle manager."""
 
+import json
+import os
+import tarfile
 import textwrap
+from pathlib import Path
 
 import pytest
 import yaml
 
-from craft_parts import errors
+from craft_parts import bases, callbacks, errors, plugins
+from craft_parts.actions import Action, ActionType
+from craft_parts.config import PartsConfig, load_config
 from craft_parts.lifecycle_manager import LifecycleManager
-from craft_parts.plugins import nil_plugin
+from craft_parts.packages.lockfile import LockfileMode
+from craft_parts.plugins import dump_plugin, nil_plugin
+from craft_parts.proxy import ProxyConfig
+from craft_parts.sbom import SbomFormat
+from craft_parts.sources.mirrors import MirrorRule
+from craft_parts.state_manager.reports import DirtyReport, OutdatedReport
+from craft_parts.steps import Step
+from craft_parts.utils import os_utils
 
 
 class TestLifecycleManager:
@@ -70,6 +83,98 @@ class TestLifecycleManager:
         assert info.dirs.prime_dir == self._dir / "work_dir" / "prime"
         assert info.custom_args == ["custom"]
         assert info.custom == "foo"
+        assert info.cache_dir is None
+        assert info.cache_size_limit is None
+
+    def test_cache(self):
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            cache_dir="/some/cache",
+            cache_size_limit=1000,
+        )
+        info = lf.project_info
+
+        assert info.cache_dir == Path("/some/cache")
+        assert info.cache_size_limit == 1000
+
+    def test_packages_lockfile(self):
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            packages_lockfile="packages.lock",
+            packages_lockfile_mode=LockfileMode.REPLAY,
+        )
+        info = lf.project_info
+
+        assert info.packages_lockfile == Path("packages.lock")
+        assert info.packages_lockfile_mode == LockfileMode.REPLAY
+
+    def test_source_mirrors(self, monkeypatch):
+        monkeypatch.setenv("CRAFT_SOURCE_MIRRORS", "https://=https://proxy/")
+        rule = MirrorRule("https://github.com/", "https://mirror/")
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", source_mirrors=[rule]
+        )
+
+        assert lf.project_info.source_mirrors == [
+            rule,
+            MirrorRule("https://", "https://proxy/"),
+        ]
+
+    def test_config(self, monkeypatch):
+        monkeypatch.setenv("CRAFT_SOURCE_MIRRORS", "https://=https://proxy/")
+        config = PartsConfig.unmarshal(
+            {
+                "cache-dir": "/config/cache",
+                "cache-size-limit": 1000,
+                "parallel-build-count": 8,
+                "max-parallel-parts": 2,
+                "proxy": {"https-proxy": "http://proxy:3128"},
+                "source-mirrors": [{"pattern": "http://", "mirror": "https://"}],
+                "features": {"foo": True},
+            }
+        )
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            cache_dir="/some/cache",
+            config=config,
+        )
+        info = lf.project_info
+
+        assert info.cache_dir == Path("/some/cache")
+        assert info.cache_size_limit == 1000
+        assert info.parallel_build_count == 8
+        assert info.proxy == ProxyConfig(https_proxy="http://proxy:3128")
+        assert info.source_mirrors == [
+            MirrorRule("https://", "https://proxy/"),
+            MirrorRule("http://", "https://"),
+        ]
+        assert info.is_feature_enabled("foo")
+        assert lf._executor._max_parallel_parts == 2
+
+    def test_config_files(self, mocker, new_dir):
+        Path("config.yaml").write_text("parallel-build-count: 4\n")
+        mocker.patch(
+            "craft_parts.config.get_config_files", return_value=[Path("config.yaml")]
+        )
+
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        assert lf.project_info.parallel_build_count == 1
+
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", config=load_config()
+        )
+        assert lf.project_info.parallel_build_count == 4
+
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            parallel_build_count=2,
+            config=load_config(),
+        )
+        assert lf.project_info.parallel_build_count == 2
 
     def test_part_initialization(self, mocker):
         mock_seq = mocker.patch("craft_parts.sequencer.Sequencer")
@@ -91,6 +196,564 @@ class TestLifecycleManager:
             project_info=lf.project_info,
         )
 
+    def test_action_executor(self):
+        callbacks.clear()
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        actions = lf.plan(Step.PULL)
+        with lf.action_executor() as ctx:
+            ctx.execute(actions)
+
+        assert Path("parts/foo/state/pull").is_file()
+
+    def test_action_executor_metrics_report(self):
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        actions = lf.plan(Step.PULL)
+
+        with lf.action_executor(metrics_report="metrics.json") as ctx:
+            metrics = ctx.execute(actions)
+
+        assert [x.action for x in metrics] == actions
+        report = json.loads(Path("metrics.json").read_text())
+        assert [x["part"] for x in report["actions"]] == ["foo"]
+        assert report["actions"][0]["duration"] == metrics[0].duration
+
+    def test_generate_sbom(self):
+        callbacks.clear()
+        Path("subdir").mkdir()
+        self._data["parts"]["foo"]["source"] = "subdir"
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", project_name="proj"
+        )
+        assert lf.generate_sbom()["packages"][1:] == []
+
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PRIME))
+
+        doc = lf.generate_sbom(SbomFormat.CYCLONEDX, ["foo"])
+        assert doc["metadata"]["component"]["name"] == "proj"
+        assert [x["name"] for x in doc["components"]] == ["foo"]
+
+        with pytest.raises(errors.InvalidPartName):
+            lf.generate_sbom(part_names=["bar"])
+
+    def test_get_part_states(self):
+        callbacks.clear()
+        Path("subdir").mkdir()
+        Path("subdir/bar").write_text("bar")
+        self._data["parts"]["foo"]["plugin"] = "dump"
+        self._data["parts"]["foo"]["source"] = "subdir"
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        part_states = lf.get_part_states()
+        assert list(part_states) == ["foo"]
+        assert part_states["foo"].steps == {}
+
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.STAGE))
+
+        part_state = lf.get_part_states(["foo"])["foo"]
+        assert list(part_state.steps) == [Step.PULL, Step.BUILD, Step.STAGE]
+        assert part_state.steps[Step.STAGE].files == {"bar"}
+        assert part_state.stage_packages == {}
+
+        with pytest.raises(errors.InvalidPartName):
+            lf.get_part_states(["bar"])
+
+    def test_export_prime(self):
+        callbacks.clear()
+        Path("subdir").mkdir()
+        Path("subdir/bar").write_text("bar")
+        self._data["parts"]["foo"]["plugin"] = "dump"
+        self._data["parts"]["foo"]["source"] = "subdir"
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", source_date_epoch=1000
+        )
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PRIME))
+
+        lf.export_prime("prime.tar.gz", compress=True)
+        with tarfile.open("prime.tar.gz", "r:gz") as tar:
+            assert tar.getnames() == ["bar"]
+            assert tar.getmember("bar").mtime == 1000
+
+        layer = lf.export_prime_layer("blobs")
+        assert layer.path.parent == Path("blobs")
+        assert layer.digest == f"sha256:{layer.path.name}"
+
+    def test_watch(self):
+        callbacks.clear()
+        Path("subdir").mkdir()
+        Path("subdir/bar").write_text("bar")
+        self._data["parts"]["foo"]["plugin"] = "dump"
+        self._data["parts"]["foo"]["source"] = "subdir"
+        self._data["parts"]["baz"] = {"plugin": "nil", "after": ["foo"]}
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        plans = lf.watch(Step.PRIME, ["baz"], interval=0.01)
+        actions = next(plans)
+        assert [
+            (a.part_name, a.step) for a in actions if a.action_type != ActionType.SKIP
+        ] == [
+            ("baz", Step.PULL),
+            ("foo", Step.PULL),
+            ("foo", Step.BUILD),
+            ("foo", Step.STAGE),
+            ("baz", Step.BUILD),
+            ("baz", Step.STAGE),
+            ("foo", Step.PRIME),
+            ("baz", Step.PRIME),
+        ]
+        with lf.action_executor() as ctx:
+            ctx.execute(actions)
+
+        Path("subdir/bar").write_text("new bar")
+        mtime_ns = Path("subdir/bar").stat().st_mtime_ns + 10 ** 9
+        os.utime("subdir/bar", ns=(mtime_ns, mtime_ns))
+
+        actions = [a for a in next(plans) if a.action_type != ActionType.SKIP]
+        assert [(a.part_name, a.step, a.action_type) for a in actions] == [
+            ("foo", Step.PULL, ActionType.UPDATE),
+            ("foo", Step.BUILD, ActionType.UPDATE),
+            ("foo", Step.STAGE, ActionType.RERUN),
+            ("baz", Step.BUILD, ActionType.RERUN),
+            ("baz", Step.STAGE, ActionType.RUN),
+            ("foo", Step.PRIME, ActionType.RUN),
+            ("baz", Step.PRIME, ActionType.RUN),
+        ]
+        plans.close()
+
+    def test_watch_no_local_sources(self):
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        plans = lf.watch(Step.PULL)
+        assert [a.part_name for a in next(plans)] == ["foo"]
+        with pytest.raises(StopIteration):
+            next(plans)
+
+    def test_watch_invalid_part(self):
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        with pytest.raises(errors.InvalidPartName):
+            next(lf.watch(Step.PULL, ["bar"]))
+
+    def test_prune_removed_parts(self):
+        callbacks.clear()
+        self._data["parts"]["bar"] = {"plugin": "nil"}
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+
+        del self._data["parts"]["bar"]
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        assert lf.get_removed_parts() == ["bar"]
+        assert lf.prune_removed_parts() == ["bar"]
+        assert lf.get_removed_parts() == []
+        assert Path("parts/bar").exists() is False
+        assert Path("parts/foo/state/pull").is_file()
+
+    def test_prune_removed_parts_automatically(self):
+        callbacks.clear()
+        self._data["parts"]["bar"] = {"plugin": "nil"}
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+
+        del self._data["parts"]["bar"]
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", prune_removed_parts=True
+        )
+        assert Path("parts/bar").is_dir()
+
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+
+        assert Path("parts/bar").exists() is False
+
+    def test_describe_step(self):
+        self._data["parts"]["foo"]["override-build"] = "echo building"
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        description = lf.describe_step("foo", Step.BUILD)
+        assert description.part_name == "foo"
+        assert description.properties["plugin"] == "nil"
+        assert description.scriptlet == "echo building"
+
+        with pytest.raises(errors.InvalidPartName):
+            lf.describe_step("bar", Step.BUILD)
+
+    def test_get_build_packages(self, mocker):
+        mocker.patch("craft_parts.bases.get_host_base", return_value=None)
+        self._data["parts"]["foo"]["build-packages"] = ["python3-dev", "g++"]
+        self._data["parts"]["bar"] = {
+            "plugin": "make",
+            "source": "https://example.com/bar.git",
+        }
+
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", base="fedora@39"
+        )
+        assert lf.get_build_packages() == [
+            "gcc",
+            "gcc-c++",
+            "git",
+            "make",
+            "python3-devel",
+        ]
+
+    def test_unsupported_base(self, mocker):
+        mocker.patch("craft_parts.bases.get_host_base", return_value=None)
+        self._data["parts"]["foo"]["stage-packages"] = ["hello"]
+
+        with pytest.raises(errors.UnsupportedBase) as raised:
+            LifecycleManager(
+                self._data, application_name="test_manager", base="gentoo@2.14"
+            )
+        assert raised.value.base == "gentoo@2.14"
+
+        del self._data["parts"]["foo"]["stage-packages"]
+        LifecycleManager(
+            self._data, application_name="test_manager", base="gentoo@2.14"
+        )
+
+    def test_base_image(self, mocker):
+        mock_unpack = mocker.patch(
+            "craft_parts.overlays.unpack_base_image",
+            return_value=Path("/cache/rootfs"),
+        )
+
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            cache_dir="cache",
+            base_image="docker://ubuntu:22.04",
+            base_image_digest="sha256:aaaa",
+        )
+
+        assert lf.project_info.base_layer_dir == Path("/cache/rootfs")
+        mock_unpack.assert_called_once_with(
+            "docker://ubuntu:22.04", cache_dir=Path("cache"), digest="sha256:aaaa"
+        )
+
+    def test_base_image_with_base_layer_dir(self, mocker):
+        mock_unpack = mocker.patch("craft_parts.overlays.unpack_base_image")
+
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            base_layer_dir="/base",
+            base_image="docker://ubuntu:22.04",
+        )
+
+        assert lf.project_info.base_layer_dir == Path("/base")
+        mock_unpack.assert_not_called()
+
+    def test_overlay_not_enabled(self):
+        self._data["parts"]["foo"]["overlay-script"] = "echo hello"
+
+        with pytest.raises(errors.FeatureNotEnabled) as raised:
+            LifecycleManager(self._data, application_name="test_manager")
+        assert raised.value.feature == "overlay"
+        assert raised.value.details == "Part 'foo' sets overlay properties."
+
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            config=PartsConfig(features={"overlay": True}),
+        )
+        assert lf.project_info.overlay_enabled
+
+    def test_get_step_log(self, capfd):
+        callbacks.clear()
+        self._data["parts"]["foo"]["override-pull"] = "echo pulling"
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", prefix_output=True
+        )
+        log = lf.get_step_log("foo", Step.PULL)
+        assert log == Path("parts/foo/state/logs/pull.log").absolute()
+        assert log.exists() is False
+
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+
+        assert "pulling" in log.read_text()
+        out, _ = capfd.readouterr()
+        assert "[foo:pull] pulling\n" in out
+
+        with pytest.raises(errors.InvalidPartName):
+            lf.get_step_log("bar", Step.PULL)
+
+    def test_generate_provenance(self):
+        callbacks.clear()
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PRIME))
+
+        statement = lf.generate_provenance({"foo.snap": "sha256/123"})
+        assert statement["subject"] == [
+            {"name": "foo.snap", "digest": {"sha256": "123"}}
+        ]
+        byproducts = statement["predicate"]["runDetails"]["byproducts"]
+        assert [x["name"] for x in byproducts] == [
+            "foo:pull",
+            "foo:build",
+            "foo:stage",
+            "foo:prime",
+        ]
+
+    def test_plan_json(self):
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        plan = json.loads(lf.plan_json(Step.PULL))
+        assert plan == {
+            "target-step": "pull",
+            "actions": [
+                {"part": "foo", "step": "pull", "type": "run", "reason": None},
+            ],
+        }
+
+    def test_explain_step(self):
+        callbacks.clear()
+        Path("subdir").mkdir()
+        self._data["parts"]["foo"]["source"] = "subdir"
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.BUILD))
+
+        assert lf.explain_step("foo", Step.PULL) is None
+        assert lf.explain_step("foo", Step.STAGE) is None
+
+        os_utils.TimedWriter.write_text(Path("subdir/bar"), "content")
+        self._data["parts"]["foo"]["override-build"] = "true"
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        report = lf.explain_step("foo", Step.PULL)
+        assert isinstance(report, OutdatedReport)
+        assert report.modified_source_files == ["bar"]
+
+        report = lf.explain_step("foo", Step.BUILD)
+        assert isinstance(report, DirtyReport)
+        assert report.dirty_properties == ["override-build"]
+
+    def test_plan_resume(self):
+        callbacks.clear()
+        self._data["parts"]["foo"]["override-build"] = "touch built; exit 1"
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with pytest.raises(errors.ScriptletRunError):
+            with lf.action_executor() as ctx:
+                ctx.execute(lf.plan(Step.BUILD))
+
+        assert Path("parts/foo/build/built").exists()
+
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        actions = lf.plan(Step.BUILD)
+        assert actions == [
+            Action("foo", Step.PULL, action_type=ActionType.SKIP, reason="already ran"),
+            Action("foo", Step.BUILD),
+        ]
+
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        actions = lf.plan(Step.BUILD, resume=True)
+        assert actions == [
+            Action("foo", Step.PULL, action_type=ActionType.SKIP, reason="already ran"),
+            Action(
+                "foo",
+                Step.BUILD,
+                action_type=ActionType.UPDATE,
+                reason="resume failed step",
+            ),
+        ]
+
+        self._data["parts"]["foo"]["override-build"] = "test -f built"
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.BUILD, resume=True))
+
+        assert Path("parts/foo/state/build").is_file()
+        assert Path("parts/foo/state/build.failed").exists() is False
+
+    def test_plan_resume_pull_changed(self):
+        callbacks.clear()
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+        Path("parts/foo/state/build.failed").write_text("error\n")
+
+        self._data["parts"]["foo"]["source"] = "."
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        actions = lf.plan(Step.BUILD, resume=True)
+        assert actions[1] == Action("foo", Step.BUILD)
+        assert actions[1].action_type == ActionType.RUN
+
+    def test_explain_step_invalid_part(self):
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        with pytest.raises(errors.InvalidPartName) as raised:
+            lf.explain_step("bar", Step.PULL)
+        assert raised.value.part_name == "bar"
+
+    def test_spec_vars(self):
+        self._data["parts"]["foo"]["source"] = "https://example.com/foo-$VERSION.tgz"
+        self._data["parts"]["foo"]["organize"] = {"bin": "opt/$CRAFT_PROJECT_NAME"}
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            project_name="hello",
+            spec_vars={"VERSION": "1.0"},
+        )
+
+        part = lf._part_list[0]
+        assert part.spec.source == "https://example.com/foo-1.0.tgz"
+        assert part.spec.organize_files == {"bin": "opt/hello"}
+
+    def test_conditional_properties(self, mocker):
+        self._data["parts"]["foo"]["when"] = [
+            {"arch": "arm64", "base": "core24", "source": "https://example.com/$A"}
+        ]
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            arch="aarch64",
+            base="core24",
+            spec_vars={"A": "arm64.tgz"},
+        )
+
+        assert lf.project_info.base == "core24"
+        assert lf._part_list[0].spec.source == "https://example.com/arm64.tgz"
+
+        mocker.patch("craft_parts.bases.get_host_base", return_value=None)
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", arch="aarch64"
+        )
+        assert lf._part_list[0].spec.source is None
+
+    def test_conditional_properties_host_base(self, mocker):
+        self._data["parts"]["foo"]["when"] = [
+            {"base": "core24", "source": "https://example.com/noble.tgz"}
+        ]
+        mocker.patch(
+            "craft_parts.bases.get_host_base",
+            return_value=bases.Base("ubuntu", "24.04", like=("debian",)),
+        )
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        assert lf.project_info.base is None
+        assert lf._part_list[0].spec.source == "https://example.com/noble.tgz"
+
+    def test_part_templates(self):
+        data = {
+            "templates": {
+                "common": {"plugin": "make", "make-parameters": ["PREFIX=/usr"]},
+                "src": {
+                    "extends": "common",
+                    "source": ".",
+                    "build-packages": ["gcc"],
+                },
+            },
+            "parts": {
+                "foo": {"extends": "src"},
+                "bar": {"extends": "src", "make-parameters": []},
+            },
+        }
+        lf = LifecycleManager(data, application_name="test_manager")
+
+        foo, bar = lf._part_list
+        assert foo.plugin == "make"
+        assert foo.spec.source == "."
+        assert foo.spec.build_packages == ["gcc"]
+        assert foo.plugin_properties.make_parameters == ["PREFIX=/usr"]
+        assert bar.plugin_properties.make_parameters == []
+
+    @pytest.mark.parametrize(
+        "template,message",
+        [
+            ({"build-packages": "gcc"}, "'build-packages': value is not a valid list"),
+            (
+                {"plugin": "make", "make-parameters": "-j1"},
+                "'make-parameters': value is not a valid list",
+            ),
+            ({"extends": "missing"}, "template 'missing' is not defined"),
+        ],
+    )
+    def test_part_templates_invalid(self, template, message):
+        data = {"templates": {"common": template}, "parts": {}}
+
+        with pytest.raises(errors.PartTemplateError) as raised:
+            LifecycleManager(data, application_name="test_manager")
+        assert raised.value.template_name == "common"
+        assert raised.value.message == message
+
+    def test_spec_vars_dirty(self):
+        callbacks.clear()
+        self._data["parts"]["foo"]["source"] = "$SRC"
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", spec_vars={"SRC": "."}
+        )
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+
+        lf = LifecycleManager(
+            self._data, application_name="test_manager", spec_vars={"SRC": "src"}
+        )
+
+        actions = lf.plan(Step.PULL)
+        assert actions[0].action_type == ActionType.RERUN
+        assert actions[0].reason == "'source' property changed"
+
+    def test_relocated_work_dir(self, new_dir):
+        callbacks.clear()
+        Path("src").mkdir()
+        self._data["parts"]["foo"]["plugin"] = "dump"
+        self._data["parts"]["foo"]["source"] = "$WORK/../src"
+        self._data["parts"]["foo"]["source-type"] = "local"
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            work_dir="work",
+            spec_vars={"WORK": f"{new_dir}/work"},
+        )
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.BUILD))
+
+        Path("work").rename("moved")
+
+        lf = LifecycleManager(
+            self._data,
+            application_name="test_manager",
+            work_dir="moved",
+            spec_vars={"WORK": f"{new_dir}/moved"},
+        )
+
+        actions = lf.plan(Step.BUILD)
+        assert [action.action_type for action in actions] == [
+            ActionType.SKIP,
+            ActionType.SKIP,
+        ]
+
+    def test_plan_json_dirty(self):
+        callbacks.clear()
+        lf = LifecycleManager(self._data, application_name="test_manager")
+        with lf.action_executor() as ctx:
+            ctx.execute(lf.plan(Step.PULL))
+
+        self._data["parts"]["foo"]["source"] = "."
+        lf = LifecycleManager(self._data, application_name="test_manager")
+
+        plan = json.loads(lf.plan_json(Step.PULL, indent=2))
+        assert plan == {
+            "target-step": "pull",
+            "actions": [
+                {
+                    "part": "foo",
+                    "step": "pull",
+                    "type": "rerun",
+                    "reason": "'source' property changed",
+                    "dirty-properties": ["source"],
+                },
+            ],
+        }
+
 
 class TestPluginProperties:
     """Verify if plugin properties are correctly handled."""
@@ -161,3 +824,180 @@ class TestPluginProperties:
                 application_name="test_manager",
             )
         assert raised.value.part_name == "bar"
+
+    def test_unknown_property_suggestion(self):
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            LifecycleManager(
+                {
+                    "parts": {
+                        "bar": {
+                            "plugin": "make",
+                            "overide-build": "make",
+                            "cmake-parameters": ["-DTEST_PARAMETER"],
+                            "foo": "bar",
+                        }
+                    }
+                },
+                application_name="test_manager",
+            )
+        assert raised.value.message.splitlines() == [
+            "'cmake-parameters': extra fields not permitted "
+            "(did you mean 'make-parameters'?)",
+            "'foo': extra fields not permitted",
+            "'overide-build': extra fields not permitted "
+            "(did you mean 'override-build'?)",
+        ]
+
+    def test_unknown_plugin_option_suggestion(self):
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            LifecycleManager(
+                {"parts": {"bar": {"plugin": "make", "make-paramters": ["-j1"]}}},
+                application_name="test_manager",
+            )
+        assert raised.value.message == (
+            "'make-paramters': extra fields not permitted "
+            "(did you mean 'make-parameters'?)"
+        )
+
+    def test_unused_plugin_option(self, mocker):
+        mocker.patch("craft_parts.sequencer.Sequencer")
+        data = {"parts": {"bar": {"plugin": "nil", "nil-flavor": "spicy"}}}
+
+        lf = LifecycleManager(data, application_name="test_manager")
+        assert lf._part_list[0].plugin == "nil"
+
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            LifecycleManager(
+                data, application_name="test_manager", strict_validation=True
+            )
+        assert raised.value.part_name == "bar"
+        assert raised.value.message == "'nil-flavor': option not used by plugin 'nil'"
+
+    def test_strict_validation(self, mocker):
+        mocker.patch("craft_parts.sequencer.Sequencer")
+
+        lf = LifecycleManager(
+            {"parts": {"bar": {"plugin": "make", "make-parameters": ["-j1"]}}},
+            application_name="test_manager",
+            strict_validation=True,
+        )
+        assert lf._part_list[0].plugin_properties.make_parameters == ["-j1"]
+
+    def test_strict_validation_template(self):
+        with pytest.raises(errors.PartTemplateError) as raised:
+            LifecycleManager(
+                {
+                    "templates": {"base": {"plugin": "nil", "nil-flavor": "spicy"}},
+                    "parts": {},
+                },
+                application_name="test_manager",
+                strict_validation=True,
+            )
+        assert raised.value.template_name == "base"
+
+
+_PROJECT_PLUGIN = textwrap.dedent(
+    """
+    from typing import Any, Dict, List, Set
+
+    from craft_parts.plugins import Plugin, PluginProperties
+    from craft_parts.plugins.base import PluginModel, extract_plugin_properties
+
+
+    class MyToolPluginProperties(PluginModel, PluginProperties):
+        my_tool_flavor: str = "plain"
+
+        @classmethod
+        def unmarshal(cls, data: Dict[str, Any]):
+            return cls(**extract_plugin_properties(data, plugin_name="my-tool"))
+
+
+    class MyToolPlugin(Plugin):
+        properties_class = MyToolPluginProperties
+
+        def get_build_snaps(self) -> Set[str]:
+            return set()
+
+        def get_build_packages(self) -> Set[str]:
+            return set()
+
+        def get_build_environment(self) -> Dict[str, str]:
+            return {}
+
+        def get_build_commands(self) -> List[str]:
+            return []
+    """
+)
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestCustomPlugins:
+    """Verify loading of plugins provided by projects and packages."""
+
+    @pytest.fixture(autouse=True)
+    def unregister_plugins(self):
+        yield
+        plugins.unregister_all()
+
+    def test_plugins_dir(self):
+        Path("plugins").mkdir()
+        Path("plugins/my_tool.py").write_text(_PROJECT_PLUGIN)
+
+        lf = LifecycleManager(
+            {"parts": {"foo": {"plugin": "my-tool", "my-tool-flavor": "spicy"}}},
+            application_name="test_manager",
+            plugins_dir="plugins",
+        )
+
+        part = lf._part_list[0]
+        assert part.plugin == "my-tool"
+        assert type(part.plugin_properties).__name__ == "MyToolPluginProperties"
+        assert part.plugin_properties.marshal() == {"my-tool-flavor": "spicy"}
+
+    def test_plugins_close(self, mocker):
+        plugins.register({"bar": dump_plugin.DumpPlugin})
+        mocker.patch(
+            "craft_parts.plugins.load_plugins_from_entry_points",
+            return_value={"foo": nil_plugin.NilPlugin, "bar": nil_plugin.NilPlugin},
+        )
+
+        with LifecycleManager(
+            {"parts": {"foo": {"plugin": "foo"}}},
+            application_name="test_manager",
+            plugin_entry_point_group="test_manager.plugins",
+        ):
+            assert plugins.get_plugin_class("foo") is nil_plugin.NilPlugin
+            assert plugins.get_plugin_class("bar") is nil_plugin.NilPlugin
+
+        assert "foo" not in plugins.get_registered_plugins()
+        assert plugins.get_plugin_class("bar") is dump_plugin.DumpPlugin
+
+    def test_plugins_dir_invalid_property(self):
+        Path("plugins").mkdir()
+        Path("plugins/my_tool.py").write_text(_PROJECT_PLUGIN)
+
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            LifecycleManager(
+                {"parts": {"foo": {"plugin": "my-tool", "my-tool-invalid": True}}},
+                application_name="test_manager",
+                plugins_dir="plugins",
+            )
+        assert raised.value.part_name == "foo"
+        assert raised.value.message == "'my-tool-invalid': extra fields not permitted"
+        assert "my-tool" not in plugins.get_registered_plugins()
+
+    def test_plugin_entry_points(self, mocker):
+        mock_load = mocker.patch(
+            "craft_parts.plugins.load_plugins_from_entry_points",
+            return_value={"bar": nil_plugin.NilPlugin},
+        )
+
+        lf = LifecycleManager(
+            {"parts": {"foo": {"plugin": "bar"}}},
+            application_name="test_manager",
+            plugin_entry_point_group="test_manager.plugins",
+        )
+
+        mock_load.assert_called_once_with("test_manager.plugins")
+        part = lf._part_list[0]
+        assert isinstance(part.plugin_properties, nil_plugin.NilPluginProperties)
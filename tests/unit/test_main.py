@@ -0,0 +1,299 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import json
+import textwrap
+from pathlib import Path
+
+import pytest
+
+from craft_parts import main
+from craft_parts.actions import Action, ActionType
+from craft_parts.lifecycle_manager import LifecycleManager
+from craft_parts.steps import Step
+
+_PARTS_YAML = textwrap.dedent(
+    """\
+    parts:
+      foo:
+        plugin: nil
+        override-build: echo building foo
+      bar:
+        plugin: nil
+        after: [foo]
+    """
+)
+
+
+@pytest.fixture(autouse=True)
+def parts_file(new_dir):
+    Path("parts.yaml").write_text(_PARTS_YAML)
+
+
+@pytest.mark.parametrize(
+    "action,result",
+    [
+        (Action("foo", Step.PULL), "Pull foo"),
+        (Action("foo", Step.BUILD, ActionType.RERUN), "Rebuild foo"),
+        (Action("foo", Step.PULL, ActionType.UPDATE), "Update pull of foo"),
+        (
+            Action("foo", Step.STAGE, ActionType.SKIP, reason="already ran"),
+            "Skip stage foo (already ran)",
+        ),
+    ],
+)
+def test_format_action(action, result):
+    assert main.format_action(action) == result
+
+
+def test_plan(capsys):
+    main.main(["plan", "build", "bar"])
+
+    out, _ = capsys.readouterr()
+    assert out.splitlines() == [
+        "Pull bar",
+        "Pull foo (required to build 'bar')",
+        "Build foo (required to build 'bar')",
+        "Stage foo (required to build 'bar')",
+        "Build bar",
+    ]
+
+
+def test_plan_show_skipped(capsys):
+    main.main(["pull"])
+    capsys.readouterr()
+
+    main.main(["plan", "pull"])
+    out, _ = capsys.readouterr()
+    assert out == "No actions to execute.\n"
+
+    main.main(["plan", "pull", "--show-skipped"])
+    out, _ = capsys.readouterr()
+    assert out.splitlines() == [
+        "Skip pull foo (already ran)",
+        "Skip pull bar (already ran)",
+    ]
+
+
+def test_plan_json(capsys):
+    main.main(["--json", "plan", "pull", "foo"])
+
+    out, _ = capsys.readouterr()
+    assert json.loads(out) == {
+        "target-step": "pull",
+        "actions": [
+            {"part": "foo", "step": "pull", "type": "run", "reason": None},
+        ],
+    }
+
+
+def test_run_step(capfd):
+    main.main(["build", "foo"])
+
+    out, _ = capfd.readouterr()
+    assert "Pull foo\nBuild foo\n" in out
+    assert "building foo" in out
+    assert Path("parts/foo/state/build").is_file()
+    assert Path("parts/bar/state").exists() is False
+
+
+def test_run_step_config_files(mocker):
+    Path("config.yaml").write_text("parallel-build-count: 4\n")
+    mocker.patch(
+        "craft_parts.config.get_config_files", return_value=[Path("config.yaml")]
+    )
+    mock_lcm = mocker.patch(
+        "craft_parts.main.LifecycleManager", wraps=LifecycleManager
+    )
+
+    main.main(["pull", "--dry-run"])
+
+    assert mock_lcm.call_args.kwargs["config"].parallel_build_count == 4
+
+
+def test_run_step_dry_run(capsys):
+    main.main(["prime", "--dry-run", "foo"])
+
+    out, _ = capsys.readouterr()
+    assert out.splitlines() == ["Pull foo", "Build foo", "Stage foo", "Prime foo"]
+    assert Path("parts").exists() is False
+
+
+def test_run_step_watch(capfd, mocker):
+    def fake_watch(_, target_step, part_names):
+        assert target_step == Step.BUILD
+        assert part_names == ["foo"]
+        yield [Action("foo", Step.PULL), Action("foo", Step.BUILD)]
+        yield [Action("foo", Step.BUILD, ActionType.RERUN)]
+        raise KeyboardInterrupt
+
+    Path("parts.yaml").write_text(
+        "parts:\n  foo:\n    plugin: nil\n    override-build: exit 1\n"
+    )
+    mocker.patch.object(LifecycleManager, "watch", fake_watch)
+    main.main(["build", "--watch", "foo"])
+
+    out, err = capfd.readouterr()
+    assert "Pull foo\nBuild foo\nRebuild foo\n" in out
+    assert "No local sources to watch." not in out
+    assert err.count("Error: 'override-build' in part 'foo' failed with code 1.") == 2
+
+
+def test_run_step_watch_no_local_sources(capfd):
+    main.main(["pull", "--watch"])
+
+    out, _ = capfd.readouterr()
+    assert out.endswith("Pull foo\nPull bar\nNo local sources to watch.\n")
+
+
+def test_run_step_watch_dry_run(capsys):
+    with pytest.raises(SystemExit) as raised:
+        main.main(["pull", "--watch", "--dry-run"])
+    assert raised.value.code == 2
+
+
+def test_explain(capsys):
+    main.main(["explain", "foo", "build"])
+
+    out, _ = capsys.readouterr()
+    lines = out.splitlines()
+    assert lines[:3] == ["Part: foo", "Step: build", "Properties:"]
+    assert '  plugin: "nil"' in lines
+    assert '  override-build: "echo building foo"' in lines
+    assert "Environment:" in lines
+    assert "  set -e" in lines
+    assert lines[-3:] == [
+        "Commands:",
+        "Scriptlet (override-build):",
+        "  echo building foo",
+    ]
+
+
+def test_explain_status(capsys):
+    main.main(["pull", "foo"])
+    Path("parts.yaml").write_text(
+        _PARTS_YAML.replace("plugin: nil\n", "plugin: nil\n    source: .\n", 1)
+    )
+    capsys.readouterr()
+
+    main.main(["explain", "foo", "pull"])
+
+    out, _ = capsys.readouterr()
+    assert "Status: 'source' property changed" in out.splitlines()
+
+
+def test_explain_json(capsys):
+    main.main(["--json", "explain", "bar", "stage"])
+
+    out, _ = capsys.readouterr()
+    data = json.loads(out)
+    assert data["part"] == "bar"
+    assert data["step"] == "stage"
+    assert data["commands"] == []
+    assert data["scriptlet"] is None
+    assert data["status"] is None
+
+
+def test_error(capsys):
+    with pytest.raises(SystemExit) as raised:
+        main.main(["explain", "baz", "pull"])
+    assert raised.value.code == 1
+
+    _, err = capsys.readouterr()
+    assert err.startswith("Error: A part named 'baz' is not defined")
+
+
+def test_error_json(capsys):
+    with pytest.raises(SystemExit):
+        main.main(["--json", "explain", "baz", "pull"])
+
+    _, err = capsys.readouterr()
+    assert json.loads(err)["code"] == "invalid-part-name"
+
+
+def test_parts_file_not_found(capsys):
+    with pytest.raises(SystemExit):
+        main.main(["-f", "missing.yaml", "plan", "pull"])
+
+    _, err = capsys.readouterr()
+    assert err.startswith("Error: Cannot load parts file 'missing.yaml'.")
+
+
+def test_parts_file_not_mapping(capsys):
+    Path("parts.yaml").write_text("- foo\n")
+    with pytest.raises(SystemExit):
+        main.main(["--json", "plan", "pull"])
+
+    _, err = capsys.readouterr()
+    data = json.loads(err)
+    assert data["code"] == "parts-file-error"
+    assert data["file"] == "parts.yaml"
+
+
+def test_list_plugins(capsys):
+    Path("parts.yaml").unlink()
+    main.main(["list-plugins"])
+
+    out, _ = capsys.readouterr()
+    lines = out.splitlines()
+    assert "nil" in lines
+    assert "make" in lines
+    assert lines[lines.index("make") + 1] == "  make-parameters"
+
+
+def test_list_plugins_json(capsys):
+    main.main(["--json", "list-plugins"])
+
+    out, _ = capsys.readouterr()
+    data = json.loads(out)
+    assert data["nil"] == []
+    assert data["make"] == ["make-parameters"]
+    assert list(data) == sorted(data)
+
+
+def test_list_sources(capsys):
+    Path("parts.yaml").unlink()
+    main.main(["list-sources"])
+
+    out, _ = capsys.readouterr()
+    lines = out.splitlines()
+    assert lines[:2] == ["git", "  source-tag"]
+    assert lines[lines.index("oci") :] == [
+        "oci",
+        "  source-checksum",
+        "tar",
+        "  source-checksum",
+        "  source-keyring",
+        "  source-checksum-signature",
+        "  source-auth",
+        "  source-region",
+        "  source-profile",
+    ]
+
+
+def test_list_sources_json(capsys):
+    main.main(["--json", "list-sources"])
+
+    out, _ = capsys.readouterr()
+    data = json.loads(out)
+    assert sorted(data) == ["git", "local", "oci", "tar"]
+    assert data["local"] == []
+
+
+def test_no_command(capsys):
+    with pytest.raises(SystemExit) as raised:
+        main.main([])
+    assert raised.value.code == 2
@@ -16,11 +16,12 @@
 
 from copy import deepcopy
 
+import pydantic
 import pytest
 
-from craft_parts import errors, parts
+from craft_parts import bases, errors, parts
 from craft_parts.dirs import ProjectDirs
-from craft_parts.parts import Part, PartSpec
+from craft_parts.parts import ElfPatchSpec, OrganizeSpec, Part, PartSpec, StripSpec
 from craft_parts.steps import Step
 
 
@@ -38,21 +39,67 @@ class TestPartSpecs:
             "source-subdir": "src",
             "source-tag": "v2.3",
             "source-type": "tar",
+            "source-submodules": [
+                "lib1",
+                {"path": "lib2", "branch": "main", "depth": 1},
+            ],
+            "source-sparse-paths": ["docs"],
+            "source-lfs": False,
+            "source-keyring": "keys.gpg",
+            "source-allowed-signers": "allowed_signers",
+            "source-checksum-signature": "SHA256SUMS.gpg",
+            "source-auth": {
+                "token-env": "TOKEN",
+                "username-env": "",
+                "password-env": "",
+                "headers-env": {"X-Api-Key": "API_KEY"},
+            },
+            "source-region": "eu-west-1",
+            "source-profile": "release",
+            "source-patches": ["patches"],
+            "source-patches-strip": 0,
             "disable-parallel": True,
             "after": ["bar"],
             "stage-snaps": ["stage-snap1", "stage-snap2"],
-            "stage-packages": ["stage-pkg1", "stage-pkg2"],
+            "stage-snaps-track-channel": True,
+            "stage-packages": ["stage-pkg1", "stage-pkg2", "libc6_libs"],
+            "stage-packages-recommends": True,
+            "stage-packages-suggests": False,
+            "chisel-release": "slices",
             "build-snaps": ["build-snap1", "build-snap2"],
             "build-packages": ["build-pkg1", "build-pkg2"],
             "build-environment": [{"ENV1": "on"}, {"ENV2": "off"}],
             "build-attributes": ["attr1", "attr2"],
             "organize": {"src1": "dest1", "src2": "dest2"},
+            "filesets": {"docs": ["usr/share/doc"]},
             "stage": ["-usr/docs"],
+            "stage-conflict-policy": "first-wins",
+            "stage-conflicts": {"usr/lib/*": "prefer-part"},
             "prime": ["*"],
+            "permissions": [
+                {
+                    "path": "bin/*",
+                    "owner": 0,
+                    "group": 0,
+                    "mode": "755",
+                    "capabilities": "cap_net_bind_service=+ep",
+                    "acls": ["u:daemon:rx"],
+                }
+            ],
+            "strip": {"split-debug": True, "exclude": ["usr/lib/debug/*"]},
+            "elf-patch": {"interpreter": "/lib/ld.so", "exclude": ["usr/bin/*"]},
             "override-pull": "override-pull",
             "override-build": "override-build",
             "override-stage": "override-stage",
             "override-prime": "override-prime",
+            "overlay-packages": ["overlay-pkg1"],
+            "overlay-script": "overlay-script",
+            "overlay": ["-usr/share/doc"],
+            "step-timeouts": {"pull": 600.0, "build": 3600.0},
+            "step-retries": {"pull": 3},
+            "build-network": False,
+            "step-network": {"stage": False},
+            "build-isolation": "chroot",
         }
 
         data_copy = deepcopy(data)
@@ -63,6 +110,226 @@ class TestPartSpecs:
         new_data = spec.marshal()
         assert new_data == data_copy
 
+    def test_unmarshal_submodules(self):
+        spec = PartSpec.unmarshal(
+            {"source-submodules": ["lib1", {"path": "lib2", "depth": 1}]}
+        )
+        assert spec.source_submodules == [
+            "lib1",
+            parts.SubmoduleSpec(path="lib2", depth=1),
+        ]
+
+    def test_unmarshal_submodules_invalid(self):
+        with pytest.raises(pydantic.ValidationError):
+            PartSpec.unmarshal({"source-submodules": [{"path": "lib", "tag": "v1"}]})
+
+    def test_unmarshal_source_list(self):
+        spec = PartSpec.unmarshal(
+            {
+                "source": [
+                    {"source": "hello.tar.gz", "source-type": "tar"},
+                    {"source": "assets", "target": "share/./assets/"},
+                ],
+                "source-subdir": "hello",
+            }
+        )
+        assert spec.source == [
+            parts.SourceSpec.parse_obj(
+                {"source": "hello.tar.gz", "source-type": "tar"}
+            ),
+            parts.SourceSpec(source="assets", target="share/assets"),
+        ]
+        assert spec.source_subdir == "hello"
+
+    @pytest.mark.parametrize(
+        "data",
+        [
+            {"source": "hello.tar.gz", "source-checksum-signature": "SUMS.gpg"},
+            {"source": [{"source": "a.tar.gz", "source-checksum-signature": "S.gpg"}]},
+        ],
+    )
+    def test_unmarshal_checksum_signature_without_keyring(self, data):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal(data)
+        assert "'source-checksum-signature' requires 'source-keyring'" in str(
+            raised.value
+        )
+
+    def test_unmarshal_source_list_top_level_options(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal(
+                {"source": [{"source": "hello.tar.gz"}], "source-checksum": "md5/1"}
+            )
+        assert raised.value.errors()[0]["msg"] == (
+            "'source-checksum' must be set in each source entry when "
+            "'source' is a list"
+        )
+
+    @pytest.mark.parametrize("target", ["/abs", "..", "../other", "a/../../b"])
+    def test_unmarshal_source_list_invalid_target(self, target):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"source": [{"source": "a", "target": target}]})
+        assert raised.value.errors()[-1]["loc"] == ("source", 0, "target")
+        assert raised.value.errors()[-1]["msg"] == (
+            "target must be a subdirectory of the part source"
+        )
+
+    def test_unmarshal_organize_invalid_regex(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"organize": {"^lib/(.*": "usr/lib/"}})
+        assert raised.value.errors()[0]["msg"] == (
+            "invalid organize pattern '^lib/(.*': missing ), unterminated subpattern "
+            "at position 5"
+        )
+
+    def test_unmarshal_filesets(self):
+        spec = PartSpec.unmarshal(
+            {
+                "filesets": {
+                    "headers": ["usr/include", "-usr/include/private"],
+                    "binaries": ["usr/bin/*"],
+                },
+                "stage": ["$headers", "$binaries", "usr/lib"],
+                "prime": ["$binaries"],
+            }
+        )
+        assert spec.stage_files == [
+            "usr/include",
+            "-usr/include/private",
+            "usr/bin/*",
+            "usr/lib",
+        ]
+        assert spec.prime_files == ["usr/bin/*"]
+
+    def test_unmarshal_filesets_undefined(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"filesets": {"headers": []}, "prime": ["$binaries"]})
+        assert raised.value.errors()[0]["loc"] == ("prime",)
+        assert raised.value.errors()[0]["msg"] == "fileset 'binaries' is not defined"
+
+    def test_get_stage_conflict_policy(self):
+        spec = PartSpec.unmarshal(
+            {
+                "stage-conflict-policy": "last-wins",
+                "stage-conflicts": {
+                    "usr/share/doc/*": "first-wins",
+                    "usr/*": "prefer-part",
+                },
+            }
+        )
+        assert spec.get_stage_conflict_policy("usr/share/doc/foo") == "first-wins"
+        assert spec.get_stage_conflict_policy("usr/lib/libfoo.so") == "prefer-part"
+        assert spec.get_stage_conflict_policy("bin/foo") == "last-wins"
+
+    def test_get_stage_conflict_policy_default(self):
+        spec = PartSpec.unmarshal({})
+        assert spec.get_stage_conflict_policy("bin/foo") == "error"
+
+    @pytest.mark.parametrize(
+        "data,loc",
+        [
+            ({"stage-conflict-policy": "any"}, ("stage-conflict-policy",)),
+            ({"stage-conflicts": {"bin/*": "any"}}, ("stage-conflicts",)),
+        ],
+    )
+    def test_unmarshal_stage_conflicts_invalid(self, data, loc):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal(data)
+        assert raised.value.errors()[0]["loc"] == loc
+        assert raised.value.errors()[0]["msg"] == (
+            "'any' is not a valid stage conflict policy"
+        )
+
+    @pytest.mark.parametrize(
+        "strip,expected",
+        [
+            (True, StripSpec()),
+            (False, None),
+            ({"split-debug": True}, StripSpec.parse_obj({"split-debug": True})),
+            ({"exclude": ["lib/*"]}, StripSpec(exclude=["lib/*"])),
+        ],
+    )
+    def test_unmarshal_strip(self, strip, expected):
+        spec = PartSpec.unmarshal({"strip": strip})
+        assert spec.strip == expected
+
+    @pytest.mark.parametrize(
+        "elf_patch,expected",
+        [
+            (True, ElfPatchSpec()),
+            (False, None),
+            ({"interpreter": "/lib/ld.so"}, ElfPatchSpec(interpreter="/lib/ld.so")),
+        ],
+    )
+    def test_unmarshal_elf_patch(self, elf_patch, expected):
+        spec = PartSpec.unmarshal({"elf-patch": elf_patch})
+        assert spec.elf_patch == expected
+
+    def test_unmarshal_strip_default(self):
+        spec = PartSpec.unmarshal({})
+        assert spec.strip is None
+        assert spec.elf_patch is None
+
+    def test_unmarshal_organize_options(self):
+        spec = PartSpec.unmarshal(
+            {
+                "organize": {
+                    "foo": "bar",
+                    "lib/libfoo.so.1": {"path": "lib/libfoo.so", "symlink": True},
+                    "baz": {"path": "bin/baz", "owner": "root", "mode": "0755"},
+                }
+            }
+        )
+        assert spec.organize_files == {
+            "foo": "bar",
+            "lib/libfoo.so.1": OrganizeSpec(path="lib/libfoo.so", symlink=True),
+            "baz": OrganizeSpec(path="bin/baz", owner="root", mode="0755"),
+        }
+
+    @pytest.mark.parametrize(
+        "options,message",
+        [
+            ({"mode": "755x"}, "mode '755x' must be an octal file mode"),
+            ({"mode": "0999"}, "mode '0999' must be an octal file mode"),
+            (
+                {"mode": "0755", "symlink": True},
+                "mode cannot be set when creating a symlink",
+            ),
+        ],
+    )
+    def test_unmarshal_organize_invalid_options(self, options, message):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"organize": {"foo": {"path": "bar", **options}}})
+        assert raised.value.errors()[-1]["msg"] == message
+
+    @pytest.mark.parametrize("field", ["step-timeouts", "step-retries", "step-network"])
+    def test_unmarshal_step_policy_invalid_step(self, field):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({field: {"fetch": 1}})
+        assert raised.value.errors()[0]["msg"] == "'fetch' is not a valid step name"
+
+    @pytest.mark.parametrize("timeout", [0, -1])
+    def test_unmarshal_step_timeouts_not_positive(self, timeout):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"step-timeouts": {"pull": timeout}})
+        assert raised.value.errors()[0]["msg"] == (
+            "timeout for step 'pull' must be positive"
+        )
+
+    def test_unmarshal_step_retries_negative(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"step-retries": {"pull": -1}})
+        assert raised.value.errors()[0]["msg"] == (
+            "retries for step 'pull' must not be negative"
+        )
+
+    def test_unmarshal_build_isolation_invalid(self):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            PartSpec.unmarshal({"build-isolation": "docker"})
+        assert raised.value.errors()[0]["msg"] == (
+            "build isolation 'docker' must be 'chroot' or 'bubblewrap'"
+        )
+
     def test_unmarshal_not_dict(self):
         with pytest.raises(TypeError) as raised:
             PartSpec.unmarshal(False)  # type: ignore
@@ -83,11 +350,15 @@ class TestPartData:
         assert p.part_build_subdir == new_dir / "parts/foo/build"
         assert p.part_install_dir == new_dir / "parts/foo/install"
         assert p.part_state_dir == new_dir / "parts/foo/state"
+        assert p.part_log_dir == new_dir / "parts/foo/state/logs"
         assert p.part_packages_dir == new_dir / "parts/foo/stage_packages"
         assert p.part_snaps_dir == new_dir / "parts/foo/stage_snaps"
+        assert p.part_slices_dir == new_dir / "parts/foo/stage_slices"
+        assert p.part_layer_dir == new_dir / "parts/foo/layer"
         assert p.part_run_dir == new_dir / "parts/foo/run"
         assert p.stage_dir == new_dir / "stage"
         assert p.prime_dir == new_dir / "prime"
+        assert p.debug_dir == new_dir / "debug"
 
     def test_part_work_dir(self, new_dir):
         p = Part("foo", {}, project_dirs=ProjectDirs(work_dir="foobar"))
@@ -98,11 +369,14 @@ class TestPartData:
         assert p.part_build_subdir == new_dir / "foobar/parts/foo/build"
         assert p.part_install_dir == new_dir / "foobar/parts/foo/install"
         assert p.part_state_dir == new_dir / "foobar/parts/foo/state"
+        assert p.part_log_dir == new_dir / "foobar/parts/foo/state/logs"
         assert p.part_packages_dir == new_dir / "foobar/parts/foo/stage_packages"
         assert p.part_snaps_dir == new_dir / "foobar/parts/foo/stage_snaps"
+        assert p.part_slices_dir == new_dir / "foobar/parts/foo/stage_slices"
         assert p.part_run_dir == new_dir / "foobar/parts/foo/run"
         assert p.stage_dir == new_dir / "foobar/stage"
         assert p.prime_dir == new_dir / "foobar/prime"
+        assert p.debug_dir == new_dir / "foobar/debug"
 
     def test_part_subdirs(self, new_dir):
         p = Part("foo", {"source-subdir": "foobar"})
@@ -134,6 +408,18 @@ class TestPartData:
         p = Part("foo", {"after": ["bar"]})
         assert p.dependencies == ["bar"]
 
+    def test_part_dependency_steps(self):
+        p = Part("foo", {"after": ["bar:pull", "baz", "qux:prime"]})
+        assert p.dependencies == ["bar", "baz", "qux"]
+        assert p.get_dependency_step("bar") == Step.PULL
+        assert p.get_dependency_step("baz") == Step.STAGE
+        assert p.get_dependency_step("qux") == Step.PRIME
+
+    def test_part_dependency_invalid_step(self):
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            Part("foo", {"after": ["bar:fetch"]})
+        assert raised.value.message == "'after',0: 'fetch' is not a valid step name"
+
     def test_part_plugin(self):
         p = Part("foo", {"plugin": "nil"})
         assert p.spec.plugin == "nil"
@@ -158,6 +444,11 @@ class TestPartData:
         p = Part("foo", tc_spec)
         assert p.spec.stage_packages == tc_result
 
+    def test_part_stage_packages_recommends(self):
+        p = Part("foo", {"stage-packages-recommends": True})
+        assert p.spec.stage_packages_recommends is True
+        assert p.spec.stage_packages_suggests is False
+
     @pytest.mark.parametrize(
         "tc_spec,tc_result",
         [
@@ -198,6 +489,7 @@ class TestPartData:
         "tc_step,tc_content",
         [
             (Step.PULL, "pull"),
+            (Step.OVERLAY, "overlay"),
             (Step.BUILD, "build"),
             (Step.STAGE, "stage"),
             (Step.PRIME, "prime"),
@@ -208,6 +500,7 @@ class TestPartData:
             "foo",
             {
                 "override-pull": "pull",
+                "overlay-script": "overlay",
                 "override-build": "build",
                 "override-stage": "stage",
                 "override-prime": "prime",
@@ -217,12 +510,53 @@ class TestPartData:
 
     @pytest.mark.parametrize(
         "step",
-        [Step.PULL, Step.BUILD, Step.STAGE, Step.PRIME],
+        list(Step),
     )
     def test_part_get_scriptlet_none(self, step):
         p = Part("foo", {})
         assert p.spec.get_scriptlet(step) is None
 
+    @pytest.mark.parametrize(
+        "data,result",
+        [
+            ({}, False),
+            ({"overlay-packages": ["hello"]}, True),
+            ({"overlay-script": "echo hello"}, True),
+            ({"overlay": ["-usr"]}, True),
+            ({"overlay": ["*"]}, False),
+        ],
+    )
+    def test_part_has_overlay(self, data, result):
+        p = Part("foo", data)
+        assert p.spec.has_overlay is result
+
+    def test_part_overlay_defaults(self):
+        p = Part("foo", {})
+        assert p.spec.overlay_packages == []
+        assert p.spec.overlay_files == ["*"]
+        assert p.spec.overlay_script is None
+
+    @pytest.mark.parametrize(
+        "data,result",
+        [
+            ({}, [True, True, True, True]),
+            ({"build-network": False}, [True, False, True, True]),
+            ({"step-network": {"pull": False}}, [False, True, True, True]),
+            (
+                {"build-network": False, "step-network": {"build": True}},
+                [True, True, True, True],
+            ),
+            (
+                {"step-network": {"build": False, "prime": False}},
+                [True, False, True, False],
+            ),
+        ],
+    )
+    def test_part_has_network_access(self, data, result):
+        p = Part("foo", data)
+        steps = [Step.PULL, Step.BUILD, Step.STAGE, Step.PRIME]
+        assert [p.spec.has_network_access(step) for step in steps] == result
+
 
 class TestPartOrdering:
     """Test part ordering.
@@ -334,3 +668,178 @@ class TestPartHelpers:
         with pytest.raises(errors.InvalidPartName) as raised:
             parts.part_dependencies("invalid", part_list=[p1, p2, p3, p4])
         assert raised.value.part_name == "invalid"
+
+    @pytest.mark.parametrize(
+        "step,result",
+        [
+            (Step.PULL, {}),
+            (Step.BUILD, {"bar": Step.PULL, "baz": Step.STAGE, "qux": Step.STAGE}),
+            (Step.PRIME, {"bar": Step.PULL, "baz": Step.PRIME, "qux": Step.PRIME}),
+        ],
+    )
+    def test_part_dependency_steps(self, step, result):
+        p1 = Part("foo", {"after": ["bar:pull", "baz"]})
+        p2 = Part("bar", {"after": ["quux"]})
+        p3 = Part("baz", {"after": ["qux"]})
+        p4 = Part("qux", {})
+        p5 = Part("quux", {})
+
+        x = parts.part_dependency_steps("foo", step, part_list=[p1, p2, p3, p4, p5])
+        assert x == result
+
+    def test_part_lower_layers(self):
+        p1 = Part("foo", {"after": ["bar"]})
+        p2 = Part("bar", {})
+        p3 = Part("baz", {})
+
+        x = parts.part_lower_layers("foo", part_list=[p1, p2, p3])
+        assert x == [p2, p3]
+
+        x = parts.part_lower_layers("bar", part_list=[p1, p2, p3])
+        assert x == []
+
+        with pytest.raises(errors.InvalidPartName) as raised:
+            parts.part_lower_layers("invalid", part_list=[p1, p2, p3])
+        assert raised.value.part_name == "invalid"
+
+    def test_part_dependency_steps_invalid(self):
+        with pytest.raises(errors.InvalidPartName) as raised:
+            parts.part_dependency_steps("invalid", Step.BUILD, part_list=[])
+        assert raised.value.part_name == "invalid"
+
+    def test_expand_variables(self):
+        data = {
+            "source": "https://example.com/hello-$VERSION.tar.gz",
+            "go-ldflags": ["-X main.version=${VERSION}", "-X main.name=$NAME"],
+            "organize": {"bin/hello": "opt/$CRAFT_PROJECT_NAME/bin/hello"},
+            "build-environment": [{"PATH": "$PATH:/opt/${VERSION}/bin"}],
+            "override-build": "echo $VERSION",
+            "disable-parallel": True,
+        }
+        variables = {"VERSION": "1.0", "CRAFT_PROJECT_NAME": "hello"}
+
+        assert parts.expand_variables(data, variables) == {
+            "source": "https://example.com/hello-1.0.tar.gz",
+            "go-ldflags": ["-X main.version=1.0", "-X main.name=$NAME"],
+            "organize": {"bin/hello": "opt/hello/bin/hello"},
+            "build-environment": [{"PATH": "$PATH:/opt/1.0/bin"}],
+            "override-build": "echo $VERSION",
+            "disable-parallel": True,
+        }
+
+    def test_expand_variables_keys(self):
+        data = {"organize": {"share/$NAME": "usr/share/$NAME", "$$VERSION": "x"}}
+
+        assert parts.expand_variables(data, {"NAME": "foo", "VERSION": "1"}) == {
+            "organize": {"share/foo": "usr/share/foo", "$1": "x"}
+        }
+
+    def test_resolve_conditional_properties(self):
+        data = {
+            "plugin": "make",
+            "source": "hello-amd64.tar.gz",
+            "make-parameters": ["ARCH=amd64"],
+            "when": [
+                {"arch": "arm64", "source": "hello-arm64.tar.gz"},
+                {
+                    "arch": ["arm64", "armhf"],
+                    "base": "core24",
+                    "make-parameters": ["ARCH=arm"],
+                },
+                {"base": ["core22"], "stage-packages": ["libfoo1"]},
+            ],
+        }
+
+        assert parts.resolve_conditional_properties(
+            "foo", data, arch="arm64", base=bases.parse_base("core24")
+        ) == {
+            "plugin": "make",
+            "source": "hello-arm64.tar.gz",
+            "make-parameters": ["ARCH=arm"],
+        }
+
+        assert parts.resolve_conditional_properties(
+            "foo", data, arch="amd64", base=bases.Base("ubuntu", "22.04")
+        ) == {
+            "plugin": "make",
+            "source": "hello-amd64.tar.gz",
+            "make-parameters": ["ARCH=amd64"],
+            "stage-packages": ["libfoo1"],
+        }
+
+        assert parts.resolve_conditional_properties(
+            "foo", data, arch="arm64", base=None
+        ) == {
+            "plugin": "make",
+            "source": "hello-arm64.tar.gz",
+            "make-parameters": ["ARCH=amd64"],
+        }
+
+    def test_resolve_conditional_properties_invalid_base(self):
+        data = {"when": [{"base": "not a base", "source": "."}]}
+        with pytest.raises(errors.InvalidBase):
+            parts.resolve_conditional_properties(
+                "foo", data, arch="amd64", base=bases.parse_base("core24")
+            )
+
+    def test_resolve_conditional_properties_none(self):
+        data = {"plugin": "nil"}
+        assert (
+            parts.resolve_conditional_properties("foo", data, arch="amd64", base=None)
+            == data
+        )
+
+    @pytest.mark.parametrize(
+        "when,message",
+        [
+            ({"arch": "arm64"}, "'when' must be a list of conditional blocks"),
+            (["arm64"], "conditional blocks must set 'arch' or 'base'"),
+            ([{"source": "."}], "conditional blocks must set 'arch' or 'base'"),
+            ([{"arch": {"arm64": 1}}], "conditions must be a name or a list of names"),
+            ([{"base": [22]}], "conditions must be a name or a list of names"),
+        ],
+    )
+    def test_resolve_conditional_properties_invalid(self, when, message):
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            parts.resolve_conditional_properties(
+                "foo", {"when": when}, arch="amd64", base=bases.parse_base("core24")
+            )
+        assert raised.value.part_name == "foo"
+        assert raised.value.message == message
+
+    def test_apply_template(self):
+        templates = {
+            "base": {"build-packages": ["gcc"], "organize": {"bin": "usr/bin"}},
+            "make": {"extends": "base", "plugin": "make", "source": "."},
+        }
+        data = {"extends": "make", "source": "src", "after": ["bar"]}
+
+        assert parts.apply_template("foo", data, templates) == {
+            "build-packages": ["gcc"],
+            "organize": {"bin": "usr/bin"},
+            "plugin": "make",
+            "source": "src",
+            "after": ["bar"],
+        }
+
+    def test_apply_template_none(self):
+        data = {"plugin": "nil"}
+        assert parts.apply_template("foo", data, {}) == data
+
+    @pytest.mark.parametrize(
+        "templates,message",
+        [
+            ({}, "template 'tmpl' is not defined"),
+            ({"tmpl": {"extends": "other"}}, "template 'other' is not defined"),
+            (
+                {"tmpl": {"extends": "other"}, "other": {"extends": "tmpl"}},
+                "template 'tmpl' extends itself",
+            ),
+            ({"tmpl": ["plugin"]}, "template 'tmpl' is malformed"),
+        ],
+    )
+    def test_apply_template_invalid(self, templates, message):
+        with pytest.raises(errors.PartSpecificationError) as raised:
+            parts.apply_template("foo", {"extends": "tmpl"}, templates)
+        assert raised.value.part_name == "foo"
+        assert raised.value.message == message
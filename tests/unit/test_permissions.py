@@ -0,0 +1,194 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import os
+import stat
+import subprocess
+from pathlib import Path
+
+import pydantic
+import pytest
+
+from craft_parts import errors
+from craft_parts.permissions import Permissions, apply_permissions
+
+
+class TestPermissions:
+    """Verify the permissions model."""
+
+    def test_defaults(self):
+        perm = Permissions()
+        assert perm.path == "*"
+        assert perm.owner is None
+        assert perm.group is None
+        assert perm.mode is None
+        assert perm.capabilities is None
+        assert perm.acls == []
+
+    @pytest.mark.parametrize("mode", ["755x", "0999", "7"])
+    def test_invalid_mode(self, mode):
+        with pytest.raises(pydantic.ValidationError) as raised:
+            Permissions(mode=mode)
+        assert raised.value.errors()[0]["msg"] == (
+            f"mode {mode!r} must be an octal file mode"
+        )
+
+    @pytest.mark.parametrize(
+        "pattern,path,result",
+        [
+            ("*", "usr/bin/foo", True),
+            ("usr/bin/*", "usr/bin/foo", True),
+            ("usr/bin/foo", "usr/bin/foo", True),
+            ("usr/bin/bar", "usr/bin/foo", False),
+            ("usr/lib/*", "usr/bin/foo", False),
+        ],
+    )
+    def test_applies_to(self, pattern, path, result):
+        assert Permissions(path=pattern).applies_to(path) == result
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestApplyPermissions:
+    """Verify setting permissions of primed files."""
+
+    # pylint: disable=attribute-defined-outside-init
+    def setup_method(self):
+        Path("prime/bin").mkdir(parents=True)
+        Path("prime/bin/foo").write_text("foo")
+        Path("prime/bin/bar").write_text("bar")
+        Path("prime/bin/baz").symlink_to("foo")
+        self._paths = {"bin", "bin/foo", "bin/bar", "bin/baz"}
+
+    # pylint: enable=attribute-defined-outside-init
+
+    def test_mode(self):
+        applied = apply_permissions(
+            part_name="p1",
+            root=Path("prime"),
+            paths=self._paths,
+            permissions=[
+                Permissions(path="bin/*", mode="700"),
+                Permissions(path="bin/bar", mode="600"),
+            ],
+        )
+
+        assert applied == {"bin/foo": {"mode": "700"}, "bin/bar": {"mode": "600"}}
+        assert stat.S_IMODE(os.stat("prime/bin/foo").st_mode) == 0o700
+        assert stat.S_IMODE(os.stat("prime/bin/bar").st_mode) == 0o600
+
+    def test_no_permissions(self, mocker):
+        mock_run = mocker.patch("subprocess.run")
+        applied = apply_permissions(
+            part_name="p1", root=Path("prime"), paths=self._paths, permissions=[]
+        )
+        assert applied == {}
+        mock_run.assert_not_called()
+
+    def test_ownership_capabilities_and_acls(self, mocker):
+        calls = []
+        mocker.patch("os.chown", side_effect=lambda *args: calls.append(args))
+        mocker.patch(
+            "subprocess.run", side_effect=lambda args, **kwargs: calls.append(args)
+        )
+
+        applied = apply_permissions(
+            part_name="p1",
+            root=Path("prime"),
+            paths=self._paths,
+            permissions=[
+                Permissions(
+                    path="bin/foo",
+                    owner=0,
+                    group=0,
+                    capabilities="cap_net_bind_service=+ep",
+                    acls=["u:daemon:rx", "g:daemon:rx"],
+                ),
+            ],
+        )
+
+        assert applied == {
+            "bin/foo": {
+                "owner": 0,
+                "group": 0,
+                "capabilities": "cap_net_bind_service=+ep",
+                "acls": ["u:daemon:rx", "g:daemon:rx"],
+            }
+        }
+        assert calls == [
+            (Path("prime/bin/foo"), 0, 0),
+            ["setcap", "cap_net_bind_service=+ep", "prime/bin/foo"],
+            ["setfacl", "--modify", "u:daemon:rx,g:daemon:rx", "prime/bin/foo"],
+        ]
+
+    def test_hard_links(self):
+        os.link("prime/bin/foo", "stage-foo")
+
+        apply_permissions(
+            part_name="p1",
+            root=Path("prime"),
+            paths=self._paths,
+            permissions=[Permissions(path="bin/foo", mode="700")],
+        )
+
+        assert os.stat("prime/bin/foo").st_nlink == 1
+        assert Path("prime/bin/foo").read_text() == "foo"
+        assert stat.S_IMODE(os.stat("prime/bin/foo").st_mode) == 0o700
+        assert stat.S_IMODE(os.stat("stage-foo").st_mode) != 0o700
+        assert not Path("prime/bin/.foo.partial").exists()
+
+    def test_command_not_installed(self, mocker):
+        mocker.patch("subprocess.run", side_effect=FileNotFoundError())
+
+        with pytest.raises(errors.FilePermissionsError) as raised:
+            apply_permissions(
+                part_name="p1",
+                root=Path("prime"),
+                paths=self._paths,
+                permissions=[Permissions(path="bin/foo", capabilities="cap_chown+ep")],
+            )
+        assert raised.value.part_name == "p1"
+        assert raised.value.path == "bin/foo"
+        assert raised.value.message == "'setcap' is not installed"
+
+    def test_command_error(self, mocker):
+        mocker.patch(
+            "subprocess.run",
+            side_effect=subprocess.CalledProcessError(
+                1, ["setfacl"], stderr="setfacl: invalid argument\n"
+            ),
+        )
+
+        with pytest.raises(errors.FilePermissionsError) as raised:
+            apply_permissions(
+                part_name="p1",
+                root=Path("prime"),
+                paths=self._paths,
+                permissions=[Permissions(path="bin/foo", acls=["x"])],
+            )
+        assert raised.value.message == "setfacl: invalid argument"
+
+    def test_ownership_error(self, mocker):
+        mocker.patch("os.chown", side_effect=PermissionError(1, "Not permitted"))
+
+        with pytest.raises(errors.FilePermissionsError) as raised:
+            apply_permissions(
+                part_name="p1",
+                root=Path("prime"),
+                paths=self._paths,
+                permissions=[Permissions(path="bin/foo", owner=1000)],
+            )
+        assert raised.value.message == "Not permitted"
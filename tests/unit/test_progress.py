@@ -0,0 +1,86 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from craft_parts import progress
+from craft_parts.progress import DownloadKind, ProgressReporter
+
+
+class TestProgressReporter:
+    """Verify the download progress reporter."""
+
+    def test_report(self):
+        reports = []
+        with progress.progress_handler(lambda *args: reports.append(args)):
+            reporter = ProgressReporter(DownloadKind.SOURCE, "file", total=100)
+
+        reporter.start()
+        reporter.advance(30)
+        reporter.update(60)
+        reporter.finish()
+
+        assert reporter.downloaded == 60
+        assert reports == [
+            (DownloadKind.SOURCE, "file", 0, 100),
+            (DownloadKind.SOURCE, "file", 60, 100),
+        ]
+
+    def test_report_interval(self, mocker):
+        mocker.patch("time.monotonic", side_effect=[0, 0.2, 0.6, 0.7, 1.0])
+        reports = []
+        with progress.progress_handler(lambda *args: reports.append(args)):
+            reporter = ProgressReporter(DownloadKind.PACKAGE, "hello", interval=0.5)
+            reporter.start()
+            reporter.advance(10)
+            reporter.advance(10)
+            reporter.advance(10)
+            reporter.finish()
+
+        assert reports == [
+            (DownloadKind.PACKAGE, "hello", 0, None),
+            (DownloadKind.PACKAGE, "hello", 20, None),
+            (DownloadKind.PACKAGE, "hello", 30, 30),
+        ]
+
+    def test_update_total(self):
+        reports = []
+        with progress.progress_handler(lambda *args: reports.append(args)):
+            reporter = ProgressReporter(DownloadKind.SOURCE, "file")
+            reporter.update(10, 50)
+            reporter.finish()
+
+        assert reports == [
+            (DownloadKind.SOURCE, "file", 10, 50),
+            (DownloadKind.SOURCE, "file", 10, 50),
+        ]
+
+    def test_no_handler(self):
+        reporter = ProgressReporter(DownloadKind.SNAP, "hello")
+        reporter.start()
+        reporter.advance(10)
+        reporter.finish()
+
+        assert reporter.downloaded == 10
+
+    def test_nested_handlers(self):
+        outer = []
+        inner = []
+        with progress.progress_handler(lambda *args: outer.append(args)):
+            with progress.progress_handler(lambda *args: inner.append(args)):
+                ProgressReporter(DownloadKind.SNAP, "inner").start()
+            ProgressReporter(DownloadKind.SNAP, "outer").start()
+
+        assert inner == [(DownloadKind.SNAP, "inner", 0, None)]
+        assert outer == [(DownloadKind.SNAP, "outer", 0, None)]
@@ -0,0 +1,135 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts import provenance
+from craft_parts.infos import ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.state_manager import states
+from craft_parts.step_cache import get_directory_digest
+from craft_parts.steps import Step
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestGenerateProvenance:
+    """Verify the generated provenance statements."""
+
+    def test_statement(self):
+        info = ProjectInfo(
+            application_name="app",
+            project_name="proj",
+            project_vars={"version": "1.0"},
+            arch="x86_64",
+        )
+        p1 = Part(
+            "p1",
+            {
+                "source": [
+                    {"source": "https://example.com/a.git"},
+                    {"source": "https://example.com/b.tar.gz", "target": "b"},
+                ],
+                "stage-snaps": ["hello"],
+            },
+        )
+        p2 = Part("p2", {})
+        pull_state = states.PullState(
+            assets={
+                "source-details": {
+                    "https://example.com/a.git": {"commit": "abc123"},
+                    "https://example.com/b.tar.gz": {"digest": "sha384/def456"},
+                },
+                "stage-snaps": {"hello": {"revision": "42", "snap-id": "x"}},
+            }
+        )
+        pull_state.write(states.state_file_path(p1, Step.PULL))
+        Path("prime").mkdir()
+        Path("prime/file").write_text("content")
+
+        statement = provenance.generate_provenance(
+            [p1, p2], project_info=info, builder_id="https://example.com/builder"
+        )
+
+        assert statement["_type"] == "https://in-toto.io/Statement/v1"
+        assert statement["predicateType"] == "https://slsa.dev/provenance/v1"
+        digest = get_directory_digest(Path("prime")).split("/")[1]
+        assert statement["subject"] == [{"name": "proj", "digest": {"sha256": digest}}]
+
+        definition = statement["predicate"]["buildDefinition"]
+        assert list(definition["externalParameters"]["parts"]) == ["p1", "p2"]
+        assert definition["externalParameters"]["parts"]["p1"]["stage-snaps"] == [
+            "hello"
+        ]
+        assert definition["internalParameters"] == {
+            "application": "app",
+            "arch": "amd64",
+            "project-vars": {"version": "1.0"},
+        }
+        assert definition["resolvedDependencies"] == [
+            {
+                "uri": "https://example.com/a.git",
+                "digest": {"gitCommit": "abc123"},
+                "annotations": {"part": "p1"},
+            },
+            {
+                "uri": "https://example.com/b.tar.gz",
+                "digest": {"sha384": "def456"},
+                "annotations": {"part": "p1"},
+            },
+            {
+                "uri": "https://snapcraft.io/hello",
+                "annotations": {"part": "p1", "revision": "42", "snap-id": "x"},
+            },
+        ]
+
+        run_details = statement["predicate"]["runDetails"]
+        assert run_details["builder"]["id"] == "https://example.com/builder"
+        fingerprint = provenance.get_state_fingerprint(pull_state).split("/")[1]
+        assert run_details["byproducts"] == [
+            {"name": "p1:pull", "digest": {"sha256": fingerprint}},
+        ]
+
+    def test_subjects(self):
+        info = ProjectInfo(application_name="app")
+
+        statement = provenance.generate_provenance(
+            [],
+            project_info=info,
+            subjects={"b.snap": "sha256:222", "a.snap": "sha256/111"},
+        )
+        assert statement["subject"] == [
+            {"name": "a.snap", "digest": {"sha256": "111"}},
+            {"name": "b.snap", "digest": {"sha256": "222"}},
+        ]
+        assert statement["predicate"]["runDetails"]["builder"]["id"] == (
+            provenance.DEFAULT_BUILDER_ID
+        )
+
+
+class TestStateFingerprint:
+    """Verify step fingerprints computed from states."""
+
+    def test_fingerprint(self):
+        state1 = states.BuildState(files={"a", "b"}, assets={"go-toolchain": "1.20"})
+        state2 = states.BuildState(files={"b", "a"}, assets={"go-toolchain": "1.20"})
+        state3 = states.BuildState(files={"a", "b"}, assets={"go-toolchain": "1.21"})
+
+        fingerprint = provenance.get_state_fingerprint(state1)
+        assert fingerprint.startswith("sha256/")
+        assert fingerprint == provenance.get_state_fingerprint(state2)
+        assert fingerprint != provenance.get_state_fingerprint(state3)
@@ -0,0 +1,110 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts import prune
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part
+from craft_parts.state_manager.prime_state import PrimeState
+from craft_parts.state_manager.stage_state import StageState
+
+
+def _write_state(part: Part, step: str, state) -> None:
+    part.part_state_dir.mkdir(parents=True, exist_ok=True)
+    state.write(part.part_state_dir / step)
+
+
+@pytest.fixture
+def dirs(new_dir):
+    return ProjectDirs(work_dir=new_dir)
+
+
+class TestGetRemovedParts:
+    """Verify the detection of removed parts."""
+
+    def test_no_parts_dir(self, dirs):
+        assert prune.get_removed_parts([], project_dirs=dirs) == []
+
+    def test_removed_parts(self, dirs):
+        p1 = Part("p1", {}, project_dirs=dirs)
+        for name in ["p1", "p3", "p2"]:
+            Path(dirs.parts_dir, name, "state").mkdir(parents=True)
+        Path(dirs.parts_dir, "other").mkdir()
+        Path(dirs.parts_dir, "file").touch()
+
+        assert prune.get_removed_parts([p1], project_dirs=dirs) == ["p2", "p3"]
+
+
+class TestPruneRemovedParts:
+    """Verify the removal of work files of removed parts."""
+
+    def test_prune(self, dirs):
+        p1 = Part("p1", {}, project_dirs=dirs)
+        p2 = Part("p2", {}, project_dirs=dirs)
+        p1.part_state_dir.mkdir(parents=True)
+
+        for migration_dir in [dirs.stage_dir, dirs.prime_dir]:
+            Path(migration_dir, "dir1/dir2").mkdir(parents=True)
+            Path(migration_dir, "dir3").mkdir()
+            Path(migration_dir, "dir1/dir2/file1").touch()
+            Path(migration_dir, "dir3/file2").touch()
+            Path(migration_dir, "file3").touch()
+
+        for step, state_class in [("stage", StageState), ("prime", PrimeState)]:
+            _write_state(
+                p1, step, state_class(files={"dir3/file2"}, directories={"dir3"})
+            )
+            _write_state(
+                p2,
+                step,
+                state_class(
+                    files={"dir1/dir2/file1", "dir3/file2", "file3"},
+                    directories={"dir1", "dir1/dir2", "dir3"},
+                ),
+            )
+
+        assert prune.prune_removed_parts([p1], project_dirs=dirs) == ["p2"]
+
+        assert p1.part_state_dir.is_dir()
+        assert Path(dirs.parts_dir, "p2").exists() is False
+        for migration_dir in [dirs.stage_dir, dirs.prime_dir]:
+            assert sorted(
+                str(x.relative_to(migration_dir)) for x in migration_dir.rglob("*")
+            ) == ["dir3", "dir3/file2"]
+
+    def test_prune_keeps_modified_directories(self, dirs):
+        p1 = Part("p1", {}, project_dirs=dirs)
+        Path(dirs.stage_dir, "dir1").mkdir(parents=True)
+        Path(dirs.stage_dir, "dir1/file1").touch()
+        Path(dirs.stage_dir, "dir1/other").touch()
+        state = StageState(files={"dir1/file1"}, directories={"dir1"})
+        _write_state(p1, "stage", state)
+
+        assert prune.prune_removed_parts([], project_dirs=dirs) == ["p1"]
+
+        assert Path(dirs.stage_dir, "dir1/file1").exists() is False
+        assert Path(dirs.stage_dir, "dir1/other").exists()
+
+    def test_prune_nothing(self, dirs):
+        p1 = Part("p1", {}, project_dirs=dirs)
+        p1.part_state_dir.mkdir(parents=True)
+
+        assert prune.prune_removed_parts([p1], project_dirs=dirs) == []
+        assert p1.part_state_dir.is_dir()
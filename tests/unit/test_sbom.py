@@ -0,0 +1,254 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+from pathlib import Path
+
+import pytest
+
+from craft_parts import sbom
+from craft_parts.infos import ProjectInfo
+from craft_parts.parts import Part
+from craft_parts.sbom import Component, SbomFormat
+from craft_parts.state_manager import states
+from craft_parts.steps import Step
+
+
+def _prime(part: Part, **pull_assets) -> None:
+    states.PullState(assets=pull_assets).write(states.state_file_path(part, Step.PULL))
+    states.PrimeState().write(states.state_file_path(part, Step.PRIME))
+
+
+@pytest.mark.usefixtures("new_dir")
+class TestGetComponents:
+    """Verify the components obtained from a part."""
+
+    def test_not_primed(self):
+        p1 = Part("p1", {"source": "https://example.com/p1.git"})
+        states.PullState().write(states.state_file_path(p1, Step.PULL))
+
+        assert sbom.get_components(p1) == []
+
+    def test_source(self):
+        p1 = Part("p1", {"source": "https://example.com/p1.git"})
+        _prime(p1, **{"source-details": {"commit": "abc123"}})
+
+        assert sbom.get_components(p1) == [
+            Component(
+                name="p1",
+                version="abc123",
+                kind="source",
+                part_name="p1",
+                download_location="https://example.com/p1.git",
+            )
+        ]
+
+    def test_source_list(self):
+        p1 = Part(
+            "p1",
+            {
+                "source": [
+                    {"source": "https://example.com/a.tar.gz"},
+                    {"source": "https://example.com/b.git", "target": "b"},
+                ]
+            },
+        )
+        details = {"https://example.com/a.tar.gz": {"digest": "sha384/123"}}
+        _prime(p1, **{"source-details": details})
+
+        components = sbom.get_components(p1)
+        assert [(x.name, x.version) for x in components] == [
+            ("p1", "sha384/123"),
+            ("p1/b", None),
+        ]
+
+    def test_stage_packages(self, mocker):
+        mocker.patch("craft_parts.utils.os_utils.OsRelease.id", return_value="ubuntu")
+        p1 = Part("p1", {"stage-packages": ["hello", "libfoo:amd64", "bar=1.0"]})
+        p1.part_packages_dir.mkdir(parents=True)
+        Path(p1.part_packages_dir, "hello_1%3a2.10-2_amd64.deb").touch()
+        _prime(p1)
+
+        components = sbom.get_components(p1)
+        assert [(x.name, x.version, x.purl) for x in components] == [
+            ("hello", "1:2.10-2", "pkg:deb/ubuntu/hello@1:2.10-2"),
+            ("libfoo", None, "pkg:deb/ubuntu/libfoo"),
+            ("bar", "1.0", "pkg:deb/ubuntu/bar@1.0"),
+        ]
+
+    def test_stage_snaps(self):
+        p1 = Part("p1", {"stage-snaps": ["hello/latest/stable"]})
+        _prime(p1, **{"stage-snaps": {"hello": {"revision": "42", "snap-id": "x"}}})
+
+        assert sbom.get_components(p1) == [
+            Component(
+                name="hello",
+                version="42",
+                kind="snap",
+                part_name="p1",
+                download_location="https://snapcraft.io/hello",
+            )
+        ]
+
+    def test_go_sum(self):
+        p1 = Part("p1", {})
+        p1.part_src_subdir.mkdir(parents=True)
+        Path(p1.part_src_subdir, "go.sum").write_text(
+            "example.com/mod v1.2.0 h1:aaa=\n"
+            "example.com/mod v1.2.0/go.mod h1:bbb=\n"
+        )
+        _prime(p1)
+
+        components = sbom.get_components(p1)
+        assert [(x.name, x.version, x.purl) for x in components] == [
+            ("example.com/mod", "v1.2.0", "pkg:golang/example.com/mod@v1.2.0"),
+        ]
+
+    def test_go_vendor_modules(self):
+        p1 = Part("p1", {})
+        Path(p1.part_src_subdir, "vendor").mkdir(parents=True)
+        Path(p1.part_src_subdir, "go.sum").write_text("other v1.0.0 h1:aaa=\n")
+        Path(p1.part_src_subdir, "vendor/modules.txt").write_text(
+            "# example.com/mod v1.3.0\n## explicit\nexample.com/mod/pkg\n"
+        )
+        _prime(p1)
+
+        components = sbom.get_components(p1)
+        assert [(x.name, x.version) for x in components] == [
+            ("example.com/mod", "v1.3.0"),
+        ]
+
+    def test_python_packages(self):
+        p1 = Part("p1", {})
+        dist_info = Path(p1.part_install_dir, "lib/site-packages/Foo_Bar-1.0.dist-info")
+        dist_info.mkdir(parents=True)
+        Path(dist_info, "METADATA").write_text(
+            "Metadata-Version: 2.1\nName: Foo_Bar\nVersion: 1.0\n\nName: other\n"
+        )
+        _prime(p1)
+
+        components = sbom.get_components(p1)
+        assert [(x.name, x.version, x.purl) for x in components] == [
+            ("Foo_Bar", "1.0", "pkg:pypi/foo-bar@1.0"),
+        ]
+
+    def test_crates(self):
+        p1 = Part("p1", {})
+        p1.part_src_subdir.mkdir(parents=True)
+        Path(p1.part_src_subdir, "Cargo.lock").write_text(
+            "version = 3\n\n"
+            '[[package]]\nname = "app"\nversion = "0.1.0"\n\n'
+            "[[package]]\n"
+            'name = "libc"\n'
+            'version = "0.2.150"\n'
+            'source = "registry+https://github.com/rust-lang/crates.io-index"\n'
+        )
+        _prime(p1)
+
+        components = sbom.get_components(p1)
+        assert [(x.name, x.version, x.purl) for x in components] == [
+            ("libc", "0.2.150", "pkg:cargo/libc@0.2.150"),
+        ]
+
+
+class TestGenerateSbom:
+    """Verify the generated documents."""
+
+    _components = [
+        Component(
+            name="p1",
+            version="abc123",
+            kind="source",
+            part_name="p1",
+            download_location="https://example.com/p1.git",
+        ),
+        Component(
+            name="libc",
+            version="0.2.150",
+            kind="crate",
+            part_name="p1",
+            purl="pkg:cargo/libc@0.2.150",
+        ),
+    ]
+
+    def test_spdx(self):
+        info = ProjectInfo(project_name="proj", project_vars={"version": "1.0"})
+        doc = sbom.generate_sbom(
+            self._components, sbom_format=SbomFormat.SPDX, project_info=info
+        )
+
+        assert doc["spdxVersion"] == "SPDX-2.3"
+        assert doc["name"] == "proj"
+        assert doc["documentNamespace"].startswith("https://spdx.org/spdxdocs/proj-")
+        assert doc["packages"][0]["name"] == "proj"
+        assert doc["packages"][0]["versionInfo"] == "1.0"
+        assert doc["packages"][1] == {
+            "SPDXID": "SPDXRef-Package-1",
+            "name": "p1",
+            "versionInfo": "abc123",
+            "downloadLocation": "https://example.com/p1.git",
+            "filesAnalyzed": False,
+            "comment": "source provided by part 'p1'",
+        }
+        assert doc["packages"][2]["downloadLocation"] == "NOASSERTION"
+        assert doc["packages"][2]["externalRefs"] == [
+            {
+                "referenceCategory": "PACKAGE-MANAGER",
+                "referenceType": "purl",
+                "referenceLocator": "pkg:cargo/libc@0.2.150",
+            }
+        ]
+        assert [x["relatedSpdxElement"] for x in doc["relationships"]] == [
+            "SPDXRef-Artifact",
+            "SPDXRef-Package-1",
+            "SPDXRef-Package-2",
+        ]
+
+    def test_cyclonedx(self):
+        info = ProjectInfo(application_name="app")
+        doc = sbom.generate_sbom(
+            self._components, sbom_format=SbomFormat.CYCLONEDX, project_info=info
+        )
+
+        assert doc["bomFormat"] == "CycloneDX"
+        assert doc["specVersion"] == "1.4"
+        assert doc["metadata"]["component"] == {"type": "application", "name": "app"}
+        assert doc["components"][1] == {
+            "type": "library",
+            "bom-ref": "component-2",
+            "name": "libc",
+            "version": "0.2.150",
+            "purl": "pkg:cargo/libc@0.2.150",
+            "properties": [
+                {"name": "craft-parts:kind", "value": "crate"},
+                {"name": "craft-parts:part", "value": "p1"},
+            ],
+        }
+        assert doc["components"][0]["externalReferences"] == [
+            {"type": "distribution", "url": "https://example.com/p1.git"}
+        ]
+
+    @pytest.mark.parametrize("sbom_format", list(SbomFormat))
+    def test_reproducible(self, sbom_format):
+        info = ProjectInfo(project_name="proj", source_date_epoch=1000)
+        doc1 = sbom.generate_sbom(
+            self._components, sbom_format=sbom_format, project_info=info
+        )
+        doc2 = sbom.generate_sbom(
+            self._components, sbom_format=sbom_format, project_info=info
+        )
+
+        assert doc1 == doc2
+        assert "1970-01-01T00:16:40Z" in str(doc1)
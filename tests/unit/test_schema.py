@@ -0,0 +1,113 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import json
+from typing import Any, Dict, List, Set
+
+import pytest
+
+from craft_parts import plugins, schema
+from craft_parts.plugins.base import PluginModel, extract_plugin_properties
+
+
+class FooPluginProperties(PluginModel, plugins.PluginProperties):
+    """Test plugin properties."""
+
+    foo_name: str
+    foo_parameters: List[str] = []
+
+    @classmethod
+    def unmarshal(cls, data: Dict[str, Any]):
+        plugin_data = extract_plugin_properties(data, plugin_name="foo")
+        return cls(**plugin_data)
+
+
+class FooPlugin(plugins.Plugin):
+    """A test plugin."""
+
+    properties_class = FooPluginProperties
+
+    def get_build_snaps(self) -> Set[str]:
+        return set()
+
+    def get_build_packages(self) -> Set[str]:
+        return set()
+
+    def get_build_environment(self) -> Dict[str, str]:
+        return {}
+
+    def get_build_commands(self) -> List[str]:
+        return []
+
+
+@pytest.fixture(autouse=True)
+def setup_fixture():
+    yield
+    plugins.unregister_all()
+
+
+class TestSchemaExport:
+    """Verify the part specification schema export."""
+
+    def test_export(self):
+        part_schema = schema.export()
+
+        assert part_schema["$schema"] == "http://json-schema.org/draft-07/schema#"
+        assert part_schema["type"] == "object"
+        assert part_schema["required"] == ["plugin"]
+        assert {"$ref": "#/definitions/dump-part"} in part_schema["oneOf"]
+        assert len(part_schema["oneOf"]) == len(plugins.get_registered_plugins())
+
+        # the schema can be serialized
+        json.dumps(part_schema)
+
+    def test_export_plugin_part(self):
+        dump_part = schema.export()["definitions"]["dump-part"]
+
+        assert dump_part["description"] == "Copy the content from the part source."
+        assert dump_part["required"] == ["plugin"]
+        assert dump_part["additionalProperties"] is False
+
+        properties = dump_part["properties"]
+        assert properties["plugin"] == {"title": "Plugin", "const": "dump"}
+        assert properties["stage-packages"]["type"] == "array"
+        assert properties["organize"]["type"] == "object"
+        assert properties["dump-strip-components"]["minimum"] == 0
+        assert "make-parameters" not in properties
+
+    def test_export_plugin_without_model(self):
+        nil_part = schema.export()["definitions"]["nil-part"]
+
+        assert nil_part["properties"]["plugin"]["const"] == "nil"
+        assert [p for p in nil_part["properties"] if p.startswith("nil-")] == []
+
+    def test_export_registered_plugin(self):
+        plugins.register({"foo": FooPlugin})
+        part_schema = schema.export()
+
+        assert {"$ref": "#/definitions/foo-part"} in part_schema["oneOf"]
+
+        foo_part = part_schema["definitions"]["foo-part"]
+        assert foo_part["description"] == "A test plugin."
+        assert foo_part["required"] == ["plugin", "foo-name"]
+        assert foo_part["properties"]["foo-name"]["type"] == "string"
+        assert foo_part["properties"]["foo-parameters"]["default"] == []
+
+    def test_export_unregistered_plugin(self):
+        plugins.register({"foo": FooPlugin})
+        plugins.unregister_all()
+
+        assert "foo-part" not in schema.export()["definitions"]
@@ -0,0 +1,90 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+import pytest
+
+from craft_parts import callbacks, errors, secrets
+from craft_parts.parts import Part
+
+
+@pytest.fixture(autouse=True)
+def clear_callbacks():
+    callbacks.clear()
+    yield
+    callbacks.clear()
+
+
+def _provider(name):
+    return {"token": "s3cr3t", "user": "me"}.get(name)
+
+
+def test_get_secret_names():
+    part = Part(
+        "p1",
+        {
+            "build-environment": [
+                {"TOKEN": "$(HOST_SECRET:token)"},
+                {"AUTH": "$(HOST_SECRET:user):$(HOST_SECRET:token)"},
+                {"PLAIN": "$(echo value)"},
+            ]
+        },
+    )
+    assert secrets.get_secret_names(part) == ["token", "user"]
+
+
+def test_get_secret_names_no_environment():
+    assert secrets.get_secret_names(Part("p1", {})) == []
+
+
+def test_resolve_secrets():
+    callbacks.register_secret_provider(_provider)
+    part = Part("p1", {"build-environment": [{"TOKEN": "$(HOST_SECRET:token)"}]})
+
+    assert secrets.resolve_secrets(part) == {"token": "s3cr3t"}
+
+
+def test_resolve_secrets_not_found(monkeypatch):
+    monkeypatch.delenv("missing", raising=False)
+    part = Part("p1", {"build-environment": [{"TOKEN": "$(HOST_SECRET:missing)"}]})
+
+    with pytest.raises(errors.SecretNotFound) as raised:
+        secrets.resolve_secrets(part)
+    assert raised.value.part_name == "p1"
+    assert raised.value.secret_name == "missing"
+
+
+def test_get_secret_environment():
+    assert secrets.get_secret_environment({"npm-token": "value"}) == {
+        "CRAFT_SECRET_NPM_TOKEN": "value"
+    }
+
+
+def test_replace_references():
+    assert secrets.replace_references("$(HOST_SECRET:user):$(HOST_SECRET:a.b)") == (
+        "${CRAFT_SECRET_USER}:${CRAFT_SECRET_A_B}"
+    )
+
+
+@pytest.mark.parametrize(
+    "text,result",
+    [
+        ("no secrets", "no secrets"),
+        ("token=s3cr3t user=me", "token=***** user=*****"),
+        ("s3cr3t-long", "*****"),
+    ],
+)
+def test_redact(text, result):
+    assert secrets.redact(text, ["me", "s3cr3t", "s3cr3t-long", ""]) == result
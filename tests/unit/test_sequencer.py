@@ -18,6 +18,7 @@ from pathlib import Path
 
 import pytest
 
+from craft_parts import errors
 from craft_parts.actions import Action, ActionType
 from craft_parts.infos import ProjectInfo
 from craft_parts.parts import Part, PartSpec
@@ -49,6 +50,7 @@ def test_sequencer_add_actions():
     "step,state_class",
     [
         (Step.PULL, states.PullState),
+        (Step.OVERLAY, states.OverlayState),
         (Step.BUILD, states.BuildState),
         (Step.STAGE, states.StageState),
         (Step.PRIME, states.PrimeState),
@@ -77,6 +79,8 @@ def test_sequencer_run_step(step, state_class):
         "application_name": "test",
         "arch_triplet": "aarch64-linux-gnu",
         "target_arch": "arm64",
+        "project_name": None,
+        "project_vars": {},
     }
 
 
@@ -94,6 +98,7 @@ def test_sequencer_run_step_invalid():
     "step,state_class",
     [
         (Step.PULL, states.PullState),
+        (Step.OVERLAY, states.OverlayState),
         (Step.BUILD, states.BuildState),
         (Step.STAGE, states.StageState),
         (Step.PRIME, states.PrimeState),
@@ -127,6 +132,8 @@ def test_sequencer_rerun_step(mocker, step, state_class):
         "application_name": "test",
         "arch_triplet": "aarch64-linux-gnu",
         "target_arch": "arm64",
+        "project_name": None,
+        "project_vars": {},
     }
 
 
@@ -135,6 +142,7 @@ def test_sequencer_rerun_step(mocker, step, state_class):
     "step,state_class",
     [
         (Step.PULL, states.PullState),
+        (Step.OVERLAY, states.OverlayState),
         (Step.BUILD, states.BuildState),
         (Step.STAGE, states.StageState),
         (Step.PRIME, states.PrimeState),
@@ -179,3 +187,91 @@ def test_sequencer_process_dependencies(mocker):
     mock_add_all_actions.assert_called_once_with(
         target_step=Step.STAGE, part_names=["p2"], reason="required to build 'p1'"
     )
+
+
+@pytest.mark.parametrize(
+    "dependency,target_step",
+    [
+        ("p2:pull", Step.PULL),
+        ("p2:build", Step.BUILD),
+        ("p2:stage", Step.STAGE),
+        ("p2:prime", Step.PRIME),
+    ],
+)
+def test_sequencer_process_dependencies_step(mocker, dependency, target_step):
+    info = ProjectInfo(arch="aarch64", application_name="test")
+    p1 = Part("p1", {"after": [dependency]})
+    p2 = Part("p2", {})
+
+    seq = Sequencer(part_list=[p1, p2], project_info=info)
+
+    mock_add_all_actions = mocker.patch.object(seq, "_add_all_actions")
+
+    # process p1 dependencies
+    seq._process_dependencies(p1, Step.BUILD)
+    mock_add_all_actions.assert_called_once_with(
+        target_step=target_step, part_names=["p2"], reason="required to build 'p1'"
+    )
+
+
+def test_sequencer_plan_pull_dependency():
+    info = ProjectInfo(arch="aarch64", application_name="test")
+    p1 = Part("p1", {"after": ["p2:pull"]})
+    p2 = Part("p2", {})
+
+    seq = Sequencer(part_list=[p1, p2], project_info=info)
+    actions = seq.plan(Step.BUILD, ["p1"])
+
+    assert actions == [
+        Action("p1", Step.PULL),
+        Action("p2", Step.PULL, reason="required to build 'p1'"),
+        Action("p1", Step.BUILD),
+    ]
+
+
+def test_sequencer_plan_overlay():
+    info = ProjectInfo(
+        arch="aarch64", application_name="test", features={"overlay": True}
+    )
+    p1 = Part("p1", {})
+    p2 = Part("p2", {})
+
+    seq = Sequencer(part_list=[p1, p2], project_info=info)
+    actions = seq.plan(Step.BUILD)
+
+    assert actions == [
+        Action("p1", Step.PULL),
+        Action("p2", Step.PULL),
+        Action("p1", Step.OVERLAY),
+        Action("p2", Step.OVERLAY),
+        Action("p1", Step.BUILD),
+        Action("p2", Step.BUILD),
+    ]
+
+
+def test_sequencer_plan_overlay_lower_layers():
+    info = ProjectInfo(
+        arch="aarch64", application_name="test", features={"overlay": True}
+    )
+    p1 = Part("p1", {})
+    p2 = Part("p2", {})
+
+    seq = Sequencer(part_list=[p1, p2], project_info=info)
+    actions = seq.plan(Step.OVERLAY, ["p2"])
+
+    assert actions == [
+        Action("p2", Step.PULL),
+        Action("p1", Step.PULL, reason="required to overlay 'p2'"),
+        Action("p1", Step.OVERLAY, reason="required to overlay 'p2'"),
+        Action("p2", Step.OVERLAY),
+    ]
+
+
+def test_sequencer_plan_overlay_not_enabled():
+    info = ProjectInfo(arch="aarch64", application_name="test")
+    p1 = Part("p1", {})
+
+    seq = Sequencer(part_list=[p1], project_info=info)
+    with pytest.raises(errors.FeatureNotEnabled) as raised:
+        seq.plan(Step.OVERLAY)
+    assert raised.value.feature == "overlay"
@@ -22,6 +22,7 @@ from craft_parts.steps import Step
 
 def test_step():
     assert f"{Step.PULL!r}" == "Step.PULL"
+    assert f"{Step.OVERLAY!r}" == "Step.OVERLAY"
     assert f"{Step.BUILD!r}" == "Step.BUILD"
     assert f"{Step.STAGE!r}" == "Step.STAGE"
     assert f"{Step.PRIME!r}" == "Step.PRIME"
@@ -29,13 +30,20 @@ def test_step():
 
 def test_ordering():
     slist = list(Step)
-    assert sorted(slist) == [Step.PULL, Step.BUILD, Step.STAGE, Step.PRIME]
+    assert sorted(slist) == [
+        Step.PULL,
+        Step.OVERLAY,
+        Step.BUILD,
+        Step.STAGE,
+        Step.PRIME,
+    ]
 
 
 @pytest.mark.parametrize(
     "tc_step,tc_result",
     [
         (Step.PULL, []),
+        (Step.OVERLAY, [Step.PULL]),
         (Step.BUILD, [Step.PULL]),
         (Step.STAGE, [Step.PULL, Step.BUILD]),
         (Step.PRIME, [Step.PULL, Step.BUILD, Step.STAGE]),
@@ -45,10 +53,25 @@ def test_previous_steps(tc_step, tc_result):
     assert tc_step.previous_steps() == tc_result
 
 
+@pytest.mark.parametrize(
+    "tc_step,tc_result",
+    [
+        (Step.PULL, []),
+        (Step.OVERLAY, [Step.PULL]),
+        (Step.BUILD, [Step.PULL, Step.OVERLAY]),
+        (Step.STAGE, [Step.PULL, Step.OVERLAY, Step.BUILD]),
+        (Step.PRIME, [Step.PULL, Step.OVERLAY, Step.BUILD, Step.STAGE]),
+    ],
+)
+def test_previous_steps_overlay(tc_step, tc_result):
+    assert tc_step.previous_steps(overlay=True) == tc_result
+
+
 @pytest.mark.parametrize(
     "tc_step,tc_result",
     [
         (Step.PULL, [Step.BUILD, Step.STAGE, Step.PRIME]),
+        (Step.OVERLAY, [Step.BUILD, Step.STAGE, Step.PRIME]),
         (Step.BUILD, [Step.STAGE, Step.PRIME]),
         (Step.STAGE, [Step.PRIME]),
         (Step.PRIME, []),
@@ -58,10 +81,25 @@ def test_next_steps(tc_step, tc_result):
     assert tc_step.next_steps() == tc_result
 
 
+@pytest.mark.parametrize(
+    "tc_step,tc_result",
+    [
+        (Step.PULL, [Step.OVERLAY, Step.BUILD, Step.STAGE, Step.PRIME]),
+        (Step.OVERLAY, [Step.BUILD, Step.STAGE, Step.PRIME]),
+        (Step.BUILD, [Step.STAGE, Step.PRIME]),
+        (Step.STAGE, [Step.PRIME]),
+        (Step.PRIME, []),
+    ],
+)
+def test_next_steps_overlay(tc_step, tc_result):
+    assert tc_step.next_steps(overlay=True) == tc_result
+
+
 @pytest.mark.parametrize(
     "tc_step,tc_result",
     [
         (Step.PULL, None),
+        (Step.OVERLAY, None),
         (Step.BUILD, Step.STAGE),
         (Step.STAGE, Step.STAGE),
         (Step.PRIME, Step.PRIME),
@@ -69,3 +107,22 @@ def test_next_steps(tc_step, tc_result):
 )
 def test_prerequisite_step(tc_step, tc_result):
     assert steps.dependency_prerequisite_step(tc_step) == tc_result
+
+
+@pytest.mark.parametrize(
+    "tc_step,tc_dependency_step,tc_result",
+    [
+        (Step.PULL, Step.PULL, None),
+        (Step.BUILD, Step.PULL, Step.PULL),
+        (Step.PRIME, Step.PULL, Step.PULL),
+        (Step.BUILD, Step.BUILD, Step.BUILD),
+        (Step.PRIME, Step.BUILD, Step.BUILD),
+        (Step.BUILD, Step.STAGE, Step.STAGE),
+        (Step.PRIME, Step.STAGE, Step.PRIME),
+        (Step.BUILD, Step.PRIME, Step.PRIME),
+        (Step.STAGE, Step.PRIME, Step.PRIME),
+    ],
+)
+def test_prerequisite_step_dependency_step(tc_step, tc_dependency_step, tc_result):
+    result = steps.dependency_prerequisite_step(tc_step, tc_dependency_step)
+    assert result == tc_result
@@ -0,0 +1,85 @@
+# -*- Mode:Python; indent-tabs-mode:nil; tab-width:4 -*-
+#
+# Copyright 2021 Canonical Ltd.
+#
+# This program is free software: you can redistribute it and/or modify
+# it under the terms of the GNU General Public License version 3 as
+# published by the Free Software Foundation.
+#
+# This program is distributed in the hope that it will be useful,
+# but WITHOUT ANY WARRANTY; without even the implied warranty of
+# MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+# GNU General Public License for more details.
+#
+# You should have received a copy of the GNU General Public License
+# along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+
+import os
+from pathlib import Path
+
+import pytest
+
+from craft_parts.dirs import ProjectDirs
+from craft_parts.parts import Part
+from craft_parts.watch import SourceWatcher
+
+
+def _touch(path: str) -> None:
+    """Write a file with a modification time that differs from earlier ones."""
+    Path(path).write_text("data")
+    mtime_ns = Path(path).stat().st_mtime_ns + 10 ** 9
+    os.utime(path, ns=(mtime_ns, mtime_ns))
+
+
+@pytest.fixture
+def parts(new_dir):
+    Path("src1").mkdir()
+    Path("src2").mkdir()
+    Path("src1/file").write_text("data")
+    return [
+        Part("p1", {"source": "src1"}),
+        Part("p2", {"source": [{"source": "src2", "target": "two"}]}),
+        Part("p3", {"source": "https://example.com/repo.git"}),
+        Part("p4", {}),
+    ]
+
+
+class TestSourceWatcher:
+    """Verify the detection of local source modifications."""
+
+    def test_part_names(self, parts):
+        watcher = SourceWatcher(
+            parts, application_name="test", project_dirs=ProjectDirs()
+        )
+        assert watcher.part_names == ["p1", "p2"]
+
+    def test_wait_timeout(self, parts):
+        watcher = SourceWatcher(
+            parts, application_name="test", project_dirs=ProjectDirs()
+        )
+        assert watcher.wait(interval=0.01, timeout=0.05) == []
+
+    def test_wait_modified(self, parts):
+        watcher = SourceWatcher(
+            parts, application_name="test", project_dirs=ProjectDirs()
+        )
+        _touch("src1/file")
+        assert watcher.wait(interval=0.01, timeout=1) == ["p1"]
+
+        # modifications are reported once
+        assert watcher.wait(interval=0.01, timeout=0.05) == []
+
+        _touch("src2/new")
+        Path("src1/file").unlink()
+        assert watcher.wait(interval=0.01, timeout=1) == ["p1", "p2"]
+
+    def test_wait_ignores_work_dirs(self, parts):
+        parts.append(Part("p5", {"source": "."}))
+        watcher = SourceWatcher(
+            parts, application_name="test", project_dirs=ProjectDirs()
+        )
+        Path("parts").mkdir()
+        Path("stage").mkdir()
+        _touch("parts/file")
+        assert watcher.wait(interval=0.01, timeout=0.05) == []
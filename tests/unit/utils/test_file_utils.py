@@ -14,6 +14,7 @@
 # You should have received a copy of the GNU General Public License
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
+import errno
 import os
 from pathlib import Path
 
@@ -144,6 +145,78 @@ class TestLinkOrCopy:
         assert os.stat("foo/2").st_ino == os.stat("qux/2").st_ino
 
 
+class TestReflinkOrLinkOrCopy:
+    """Verify func:`reflink_or_link_or_copy` usage scenarios."""
+
+    def setup_method(self):
+        file_utils._reflink_unsupported.clear()
+        Path("1").write_text("content")
+
+    def teardown_method(self):
+        file_utils._reflink_unsupported.clear()
+
+    def test_reflink(self, mocker):
+        mock_reflink = mocker.patch("craft_parts.utils.file_utils.reflink")
+        mock_link = mocker.patch("craft_parts.utils.file_utils.link_or_copy")
+
+        file_utils.reflink_or_link_or_copy("1", "2")
+
+        mock_reflink.assert_called_once_with("1", "2", follow_symlinks=False)
+        mock_link.assert_not_called()
+
+    def test_reflink_unsupported(self, mocker):
+        mock_reflink = mocker.patch(
+            "craft_parts.utils.file_utils.reflink",
+            side_effect=OSError(errno.EOPNOTSUPP, "not supported"),
+        )
+        mock_link = mocker.patch("craft_parts.utils.file_utils.link_or_copy")
+
+        file_utils.reflink_or_link_or_copy("1", "2")
+        file_utils.reflink_or_link_or_copy("1", "3")
+
+        # Files are linked, and cloning is not attempted again.
+        mock_reflink.assert_called_once_with("1", "2", follow_symlinks=False)
+        assert mock_link.mock_calls == [
+            mocker.call("1", "2", follow_symlinks=False),
+            mocker.call("1", "3", follow_symlinks=False),
+        ]
+
+    def test_reflink_error(self, mocker):
+        mock_reflink = mocker.patch(
+            "craft_parts.utils.file_utils.reflink",
+            side_effect=OSError(errno.EIO, "error"),
+        )
+
+        file_utils.reflink_or_link_or_copy("1", "2")
+        file_utils.reflink_or_link_or_copy("1", "3")
+
+        assert mock_reflink.call_count == 2
+        assert Path("2").read_text() == "content"
+
+    def test_symlink_not_cloned(self, mocker):
+        mock_reflink = mocker.patch("craft_parts.utils.file_utils.reflink")
+        os.symlink("1", "link")
+
+        file_utils.reflink_or_link_or_copy("link", "2")
+
+        mock_reflink.assert_not_called()
+        assert os.readlink("2") == "1"
+
+    def test_reflink_file(self):
+        try:
+            file_utils.reflink("1", "foo/2")
+        except OSError:
+            pytest.skip("file cloning is not supported")
+
+        assert Path("foo/2").read_text() == "content"
+        assert os.stat("1").st_ino != os.stat("foo/2").st_ino
+
+    def test_reflink_file_not_found(self):
+        with pytest.raises(errors.CopyFileNotFound) as raised:
+            file_utils.reflink("2", "3")
+        assert raised.value.name == "2"
+
+
 class TestCopy:
     """Verify func:`copy` usage scenarios."""
 
@@ -46,3 +46,17 @@ def test_humanize_list(items, result):
 def test_humanize_list_item_format(items, item_format, result):
     hl = formatting_utils.humanize_list(iter(items), "&", item_format)
     assert hl == result
+
+
+@pytest.mark.parametrize(
+    "name,result",
+    [
+        ["overide-build", "override-build"],
+        ["build-package", "build-packages"],
+        ["sourc", "source"],
+        ["foo", None],
+    ],
+)
+def test_get_close_match(name, result):
+    candidates = ["override-build", "build-packages", "source", "source-tag"]
+    assert formatting_utils.get_close_match(name, candidates) == result
@@ -15,6 +15,7 @@
 # along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 import os
+import subprocess
 import textwrap
 from pathlib import Path
 
@@ -228,6 +229,69 @@ class TestTerminal:
         assert os_utils.is_dumb_terminal() == result
 
 
+class TestNetworkIsolation:
+    """Tests for the network isolation command prefix."""
+
+    def setup_method(self):
+        os_utils.get_network_isolation_prefix.cache_clear()
+
+    def teardown_method(self):
+        os_utils.get_network_isolation_prefix.cache_clear()
+
+    @pytest.mark.parametrize(
+        "euid,prefix",
+        [
+            (0, ("unshare", "--net")),
+            (1000, ("unshare", "--net", "--map-root-user")),
+        ],
+    )
+    def test_get_network_isolation_prefix(self, mocker, euid, prefix):
+        mocker.patch("shutil.which", return_value="/usr/bin/unshare")
+        mocker.patch("os.geteuid", return_value=euid)
+        mock_run = mocker.patch("subprocess.run")
+
+        assert os_utils.get_network_isolation_prefix() == prefix
+        mock_run.assert_called_once_with(
+            [*prefix, "true"],
+            check=True,
+            stdout=subprocess.DEVNULL,
+            stderr=subprocess.DEVNULL,
+        )
+
+    def test_get_network_isolation_prefix_no_unshare(self, mocker):
+        mocker.patch("shutil.which", return_value=None)
+        mock_run = mocker.patch("subprocess.run")
+
+        assert os_utils.get_network_isolation_prefix() is None
+        mock_run.assert_not_called()
+
+    def test_get_network_isolation_prefix_non_linux(self, mocker):
+        mocker.patch("sys.platform", "darwin")
+        mocker.patch("shutil.which", return_value="/usr/bin/unshare")
+        mock_run = mocker.patch("subprocess.run")
+
+        assert os_utils.get_network_isolation_prefix() is None
+        mock_run.assert_not_called()
+
+    def test_get_network_isolation_prefix_unsupported(self, mocker):
+        mocker.patch("shutil.which", return_value="/usr/bin/unshare")
+        mocker.patch(
+            "subprocess.run",
+            side_effect=subprocess.CalledProcessError(1, ["unshare"]),
+        )
+
+        assert os_utils.get_network_isolation_prefix() is None
+
+    def test_get_network_isolation_prefix_cached(self, mocker):
+        mocker.patch("shutil.which", return_value="/usr/bin/unshare")
+        mock_run = mocker.patch("subprocess.run")
+
+        os_utils.get_network_isolation_prefix()
+        os_utils.get_network_isolation_prefix()
+
+        mock_run.assert_called_once()
+
+
 @pytest.mark.usefixtures("new_dir")
 class TestOsRelease:
     """Verify os-release data retrieval."""
@@ -257,6 +321,7 @@ class TestOsRelease:
         )
 
         assert release.id() == "arch"
+        assert release.id_like() == ["archlinux"]
         assert release.name() == "Arch Linux"
         assert release.version_id() == "foo"
         assert release.version_codename() == "bar"
@@ -279,6 +344,27 @@ class TestOsRelease:
         with pytest.raises(errors.OsReleaseIdError):
             release.id()
 
+    def test_id_like(self):
+        release = os_utils.OsRelease(
+            os_release_file=self._write_os_release(
+                textwrap.dedent(
+                    """\
+                ID="almalinux"
+                ID_LIKE="rhel centos fedora"
+            """
+                )
+            )
+        )
+
+        assert release.id_like() == ["rhel", "centos", "fedora"]
+
+    def test_no_id_like(self):
+        release = os_utils.OsRelease(
+            os_release_file=self._write_os_release("ID=fedora\n")
+        )
+
+        assert release.id_like() == []
+
     def test_no_name(self):
         release = os_utils.OsRelease(
             os_release_file=self._write_os_release(